@@ -0,0 +1,40 @@
+// Command go-jf-watch is the go-jf-watch binary. Subcommands are picked by
+// the first argument; "top" watches a running server from the terminal.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// version is set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
+// commands maps subcommand names to their entry points, which get the
+// arguments after the name.
+var commands = map[string]func(args []string) error{
+	"top": runTop,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "go-jf-watch: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err := run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "go-jf-watch %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "go-jf-watch %s\n\nUsage:\n", version)
+	fmt.Fprintln(os.Stderr, "  go-jf-watch top [--config FILE] [--url URL] [--interval DURATION]")
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/monitor"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// runTop runs the terminal dashboard against the server named in the
+// config file, or the one given with --url.
+func runTop(args []string) error {
+	fs := flag.NewFlagSet("top", flag.ContinueOnError)
+	configPath := fs.String("config", "config.yaml", "config file naming the server to watch")
+	serverURL := fs.String("url", "", "server to watch, instead of the one in the config file")
+	interval := fs.Duration("interval", 2*time.Second, "how often to poll the queue and cache")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	baseURL := *serverURL
+	if baseURL == "" {
+		cfg, err := config.Load(*configPath)
+		if err != nil {
+			return err
+		}
+		baseURL = monitor.URLFromConfig(&cfg.Server)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Log lines would tear the full-screen frame, so the dashboard shows
+	// errors itself
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return monitor.New(baseURL, *interval, logger).Run(ctx, os.Stdin, os.Stdout)
+}
@@ -0,0 +1,172 @@
+// Package chaos injects faults into downloads, storage and Jellyfin requests
+// so retry, backoff and crash recovery can be exercised on purpose.
+//
+// Injection is doubly gated: the binary must be built with -tags chaos and
+// the chaos section of the config must be enabled. Otherwise New returns a
+// nil *Injector, whose methods all pass calls through untouched, so call
+// sites can wrap unconditionally.
+package chaos
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// ErrInjected is wrapped by every error the injector produces.
+var ErrInjected = errors.New("injected fault")
+
+// Injector decides, at the configured rates, which operations fail.
+type Injector struct {
+	config *config.ChaosConfig
+	logger *slog.Logger
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// New returns an injector for cfg, or nil when fault injection is disabled
+// in the config or not compiled into this binary.
+func New(cfg *config.ChaosConfig, logger *slog.Logger) *Injector {
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+	if !compiledIn {
+		logger.Warn("Chaos config ignored: fault injection requires a build with -tags chaos")
+		return nil
+	}
+
+	logger.Warn("Fault injection enabled",
+		"download_failure_rate", cfg.DownloadFailureRate,
+		"slow_read_delay", cfg.SlowReadDelay,
+		"storage_error_rate", cfg.StorageErrorRate,
+		"jellyfin_error_rate", cfg.JellyfinErrorRate)
+	return newInjector(cfg, logger)
+}
+
+// newInjector builds an injector regardless of build tags.
+func newInjector(cfg *config.ChaosConfig, logger *slog.Logger) *Injector {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Injector{
+		config: cfg,
+		logger: logger,
+		rng:    rand.New(rand.NewSource(seed)),
+	}
+}
+
+// roll reports whether an event with probability rate happens.
+func (i *Injector) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rng.Float64() < rate
+}
+
+// StorageError returns an injected error for the named storage operation at
+// the configured storage error rate, and nil otherwise.
+func (i *Injector) StorageError(op string) error {
+	if i == nil || !i.roll(i.config.StorageErrorRate) {
+		return nil
+	}
+	i.logger.Debug("Injecting storage error", "operation", op)
+	return fmt.Errorf("%s: %w", op, ErrInjected)
+}
+
+// Transport wraps next so Jellyfin requests fail with HTTP 500 at the
+// configured rate. A nil next means http.DefaultTransport.
+func (i *Injector) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if i == nil || i.config.JellyfinErrorRate <= 0 {
+		return next
+	}
+	return &faultyTransport{next: next, injector: i}
+}
+
+type faultyTransport struct {
+	next     http.RoundTripper
+	injector *Injector
+}
+
+func (t *faultyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.injector.roll(t.injector.config.JellyfinErrorRate) {
+		return t.next.RoundTrip(req)
+	}
+	t.injector.logger.Debug("Injecting HTTP 500", "url", req.URL.Redacted())
+	body := "injected fault\n"
+	return &http.Response{
+		Status:        "500 Internal Server Error",
+		StatusCode:    http.StatusInternalServerError,
+		Proto:         req.Proto,
+		ProtoMajor:    req.ProtoMajor,
+		ProtoMinor:    req.ProtoMinor,
+		Header:        http.Header{"Content-Type": {"text/plain"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// Reader wraps a download body so every read is delayed by the configured
+// slow read delay and, at the download failure rate, the download fails
+// part-way through. The decision to fail is made once per download.
+func (i *Injector) Reader(r io.Reader) io.Reader {
+	if i == nil || (i.config.DownloadFailureRate <= 0 && i.config.SlowReadDelay <= 0) {
+		return r
+	}
+
+	reader := &faultyReader{r: r, delay: i.config.SlowReadDelay, failAfter: -1}
+	if i.roll(i.config.DownloadFailureRate) {
+		i.mu.Lock()
+		// Fail somewhere in the first 4 MiB; shorter bodies fail at the end
+		reader.failAfter = i.rng.Int63n(4 << 20)
+		i.mu.Unlock()
+		i.logger.Debug("Injecting download failure", "after_bytes", reader.failAfter)
+	}
+	return reader
+}
+
+type faultyReader struct {
+	r         io.Reader
+	delay     time.Duration
+	failAfter int64 // bytes to pass through before failing; -1 never fails
+	read      int64
+}
+
+func (f *faultyReader) Read(p []byte) (int, error) {
+	if f.delay > 0 {
+		time.Sleep(f.delay)
+	}
+	if f.failAfter >= 0 {
+		remaining := f.failAfter - f.read
+		if remaining <= 0 {
+			return 0, fmt.Errorf("download interrupted: %w", ErrInjected)
+		}
+		if int64(len(p)) > remaining {
+			p = p[:remaining]
+		}
+	}
+	n, err := f.r.Read(p)
+	f.read += int64(n)
+	if err == io.EOF && f.failAfter >= 0 {
+		// Bodies shorter than the failure point still fail, losing the
+		// final chunk as a dropped connection would
+		f.read -= int64(n)
+		return 0, fmt.Errorf("download interrupted: %w", ErrInjected)
+	}
+	return n, err
+}
@@ -0,0 +1,97 @@
+package chaos
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestNewGating(t *testing.T) {
+	assert.Nil(t, New(nil, testLogger()))
+	assert.Nil(t, New(&config.ChaosConfig{StorageErrorRate: 1}, testLogger()), "disabled in config")
+
+	inj := New(&config.ChaosConfig{Enabled: true, StorageErrorRate: 1}, testLogger())
+	if compiledIn {
+		assert.NotNil(t, inj)
+	} else {
+		assert.Nil(t, inj, "faults are only injected in builds with -tags chaos")
+	}
+}
+
+func TestNilInjectorPassesThrough(t *testing.T) {
+	var inj *Injector
+
+	assert.NoError(t, inj.StorageError("write"))
+	assert.Equal(t, http.DefaultTransport, inj.Transport(nil))
+
+	r := strings.NewReader("content")
+	assert.Equal(t, io.Reader(r), inj.Reader(r))
+}
+
+func TestStorageError(t *testing.T) {
+	never := newInjector(&config.ChaosConfig{Seed: 1}, testLogger())
+	assert.NoError(t, never.StorageError("write"))
+
+	always := newInjector(&config.ChaosConfig{Seed: 1, StorageErrorRate: 1}, testLogger())
+	err := always.StorageError("write")
+	assert.ErrorIs(t, err, ErrInjected)
+	assert.Contains(t, err.Error(), "write")
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	always := newInjector(&config.ChaosConfig{Seed: 1, JellyfinErrorRate: 1}, testLogger())
+	client := &http.Client{Transport: always.Transport(nil)}
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	// Roughly half of requests fail at a 0.5 rate, reproducibly for a seed
+	half := newInjector(&config.ChaosConfig{Seed: 42, JellyfinErrorRate: 0.5}, testLogger())
+	client = &http.Client{Transport: half.Transport(nil)}
+	failures := 0
+	for i := 0; i < 200; i++ {
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusInternalServerError {
+			failures++
+		}
+	}
+	assert.InDelta(t, 100, failures, 30)
+}
+
+func TestReader(t *testing.T) {
+	content := strings.Repeat("x", 1000)
+
+	always := newInjector(&config.ChaosConfig{Seed: 1, DownloadFailureRate: 1}, testLogger())
+	data, err := io.ReadAll(always.Reader(strings.NewReader(content)))
+	assert.True(t, errors.Is(err, ErrInjected), "expected injected failure, got %v", err)
+	assert.LessOrEqual(t, len(data), len(content))
+
+	slow := newInjector(&config.ChaosConfig{Seed: 1, SlowReadDelay: 5 * time.Millisecond}, testLogger())
+	start := time.Now()
+	data, err = io.ReadAll(slow.Reader(strings.NewReader(content)))
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+	assert.GreaterOrEqual(t, time.Since(start), 5*time.Millisecond)
+}
@@ -0,0 +1,7 @@
+//go:build !chaos
+
+package chaos
+
+// compiledIn is false in regular builds, so a chaos section left in a
+// production config can never inject faults.
+const compiledIn = false
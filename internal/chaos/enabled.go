@@ -0,0 +1,7 @@
+//go:build chaos
+
+package chaos
+
+// compiledIn is true in binaries built with -tags chaos, the only builds in
+// which faults are ever injected.
+const compiledIn = true
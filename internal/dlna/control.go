@@ -0,0 +1,520 @@
+package dlna
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// upnpError is an error returned to a control request as a SOAP fault.
+type upnpError struct {
+	code        int
+	description string
+}
+
+var (
+	errInvalidAction = &upnpError{401, "Invalid Action"}
+	errInvalidArgs   = &upnpError{402, "Invalid Args"}
+	errActionFailed  = &upnpError{501, "Action Failed"}
+	errNoSuchObject  = &upnpError{701, "No such object"}
+)
+
+// soapArg is an output argument of an action, in the order the service
+// description lists them.
+type soapArg struct {
+	name, value string
+}
+
+// handleDescription serves the device description.
+func (s *Server) handleDescription(w http.ResponseWriter, r *http.Request) {
+	desc := deviceDescription{
+		Xmlns:       "urn:schemas-upnp-org:device-1-0",
+		XmlnsDLNA:   "urn:schemas-dlna-org:device-1-0",
+		SpecVersion: specVersion{Major: 1, Minor: 0},
+		Device: device{
+			DeviceType:   deviceType,
+			FriendlyName: s.config.FriendlyName,
+			Manufacturer: "go-jf-watch",
+			ModelName:    "go-jf-watch",
+			UDN:          "uuid:" + s.uuid,
+			DLNADoc:      "DMS-1.50",
+			Services: []service{
+				{
+					ServiceType: contentDirectoryType,
+					ServiceID:   "urn:upnp-org:serviceId:ContentDirectory",
+					SCPDURL:     "/ContentDirectory.xml",
+					ControlURL:  "/control/ContentDirectory",
+					EventSubURL: "/event/ContentDirectory",
+				},
+				{
+					ServiceType: connectionManagerType,
+					ServiceID:   "urn:upnp-org:serviceId:ConnectionManager",
+					SCPDURL:     "/ConnectionManager.xml",
+					ControlURL:  "/control/ConnectionManager",
+					EventSubURL: "/event/ConnectionManager",
+				},
+			},
+		},
+	}
+	writeXML(w, desc)
+}
+
+type deviceDescription struct {
+	XMLName     xml.Name    `xml:"root"`
+	Xmlns       string      `xml:"xmlns,attr"`
+	XmlnsDLNA   string      `xml:"xmlns:dlna,attr"`
+	SpecVersion specVersion `xml:"specVersion"`
+	Device      device      `xml:"device"`
+}
+
+type specVersion struct {
+	Major int `xml:"major"`
+	Minor int `xml:"minor"`
+}
+
+type device struct {
+	DeviceType   string    `xml:"deviceType"`
+	FriendlyName string    `xml:"friendlyName"`
+	Manufacturer string    `xml:"manufacturer"`
+	ModelName    string    `xml:"modelName"`
+	UDN          string    `xml:"UDN"`
+	DLNADoc      string    `xml:"dlna:X_DLNADOC"`
+	Services     []service `xml:"serviceList>service"`
+}
+
+type service struct {
+	ServiceType string `xml:"serviceType"`
+	ServiceID   string `xml:"serviceId"`
+	SCPDURL     string `xml:"SCPDURL"`
+	ControlURL  string `xml:"controlURL"`
+	EventSubURL string `xml:"eventSubURL"`
+}
+
+// scpd is a service description: its actions and state variables.
+type scpd struct {
+	XMLName     xml.Name       `xml:"scpd"`
+	Xmlns       string         `xml:"xmlns,attr"`
+	SpecVersion specVersion    `xml:"specVersion"`
+	Actions     []scpdAction   `xml:"actionList>action"`
+	Variables   []scpdVariable `xml:"serviceStateTable>stateVariable"`
+}
+
+type scpdAction struct {
+	Name      string    `xml:"name"`
+	Arguments []scpdArg `xml:"argumentList>argument"`
+}
+
+type scpdArg struct {
+	Name      string `xml:"name"`
+	Direction string `xml:"direction"`
+	Variable  string `xml:"relatedStateVariable"`
+}
+
+type scpdVariable struct {
+	SendEvents string   `xml:"sendEvents,attr"`
+	Name       string   `xml:"name"`
+	DataType   string   `xml:"dataType"`
+	Allowed    []string `xml:"allowedValueList>allowedValue,omitempty"`
+}
+
+// in and out build the arguments of a service description.
+func in(name, variable string) scpdArg  { return scpdArg{name, "in", variable} }
+func out(name, variable string) scpdArg { return scpdArg{name, "out", variable} }
+
+var contentDirectorySCPD = scpd{
+	Xmlns:       "urn:schemas-upnp-org:service-1-0",
+	SpecVersion: specVersion{Major: 1, Minor: 0},
+	Actions: []scpdAction{
+		{Name: "Browse", Arguments: []scpdArg{
+			in("ObjectID", "A_ARG_TYPE_ObjectID"),
+			in("BrowseFlag", "A_ARG_TYPE_BrowseFlag"),
+			in("Filter", "A_ARG_TYPE_Filter"),
+			in("StartingIndex", "A_ARG_TYPE_Index"),
+			in("RequestedCount", "A_ARG_TYPE_Count"),
+			in("SortCriteria", "A_ARG_TYPE_SortCriteria"),
+			out("Result", "A_ARG_TYPE_Result"),
+			out("NumberReturned", "A_ARG_TYPE_Count"),
+			out("TotalMatches", "A_ARG_TYPE_Count"),
+			out("UpdateID", "A_ARG_TYPE_UpdateID"),
+		}},
+		{Name: "GetSystemUpdateID", Arguments: []scpdArg{out("Id", "SystemUpdateID")}},
+		{Name: "GetSearchCapabilities", Arguments: []scpdArg{out("SearchCaps", "SearchCapabilities")}},
+		{Name: "GetSortCapabilities", Arguments: []scpdArg{out("SortCaps", "SortCapabilities")}},
+	},
+	Variables: []scpdVariable{
+		{SendEvents: "no", Name: "A_ARG_TYPE_ObjectID", DataType: "string"},
+		{SendEvents: "no", Name: "A_ARG_TYPE_BrowseFlag", DataType: "string", Allowed: []string{"BrowseMetadata", "BrowseDirectChildren"}},
+		{SendEvents: "no", Name: "A_ARG_TYPE_Filter", DataType: "string"},
+		{SendEvents: "no", Name: "A_ARG_TYPE_Index", DataType: "ui4"},
+		{SendEvents: "no", Name: "A_ARG_TYPE_Count", DataType: "ui4"},
+		{SendEvents: "no", Name: "A_ARG_TYPE_SortCriteria", DataType: "string"},
+		{SendEvents: "no", Name: "A_ARG_TYPE_Result", DataType: "string"},
+		{SendEvents: "no", Name: "A_ARG_TYPE_UpdateID", DataType: "ui4"},
+		{SendEvents: "yes", Name: "SystemUpdateID", DataType: "ui4"},
+		{SendEvents: "no", Name: "SearchCapabilities", DataType: "string"},
+		{SendEvents: "no", Name: "SortCapabilities", DataType: "string"},
+	},
+}
+
+var connectionManagerSCPD = scpd{
+	Xmlns:       "urn:schemas-upnp-org:service-1-0",
+	SpecVersion: specVersion{Major: 1, Minor: 0},
+	Actions: []scpdAction{
+		{Name: "GetProtocolInfo", Arguments: []scpdArg{
+			out("Source", "SourceProtocolInfo"),
+			out("Sink", "SinkProtocolInfo"),
+		}},
+		{Name: "GetCurrentConnectionIDs", Arguments: []scpdArg{out("ConnectionIDs", "CurrentConnectionIDs")}},
+		{Name: "GetCurrentConnectionInfo", Arguments: []scpdArg{
+			in("ConnectionID", "A_ARG_TYPE_ConnectionID"),
+			out("RcsID", "A_ARG_TYPE_RcsID"),
+			out("AVTransportID", "A_ARG_TYPE_AVTransportID"),
+			out("ProtocolInfo", "A_ARG_TYPE_ProtocolInfo"),
+			out("PeerConnectionManager", "A_ARG_TYPE_ConnectionManager"),
+			out("PeerConnectionID", "A_ARG_TYPE_ConnectionID"),
+			out("Direction", "A_ARG_TYPE_Direction"),
+			out("Status", "A_ARG_TYPE_ConnectionStatus"),
+		}},
+	},
+	Variables: []scpdVariable{
+		{SendEvents: "yes", Name: "SourceProtocolInfo", DataType: "string"},
+		{SendEvents: "yes", Name: "SinkProtocolInfo", DataType: "string"},
+		{SendEvents: "yes", Name: "CurrentConnectionIDs", DataType: "string"},
+		{SendEvents: "no", Name: "A_ARG_TYPE_ConnectionStatus", DataType: "string", Allowed: []string{"OK", "ContentFormatMismatch", "InsufficientBandwidth", "UnreliableChannel", "Unknown"}},
+		{SendEvents: "no", Name: "A_ARG_TYPE_ConnectionManager", DataType: "string"},
+		{SendEvents: "no", Name: "A_ARG_TYPE_Direction", DataType: "string", Allowed: []string{"Input", "Output"}},
+		{SendEvents: "no", Name: "A_ARG_TYPE_ProtocolInfo", DataType: "string"},
+		{SendEvents: "no", Name: "A_ARG_TYPE_ConnectionID", DataType: "i4"},
+		{SendEvents: "no", Name: "A_ARG_TYPE_AVTransportID", DataType: "i4"},
+		{SendEvents: "no", Name: "A_ARG_TYPE_RcsID", DataType: "i4"},
+	},
+}
+
+// serveSCPD returns a handler serving a service description.
+func serveSCPD(desc scpd) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeXML(w, desc)
+	}
+}
+
+// writeXML writes v as an XML document.
+func writeXML(w http.ResponseWriter, v interface{}) {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		http.Error(w, "Failed to encode description", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	io.WriteString(w, xml.Header)
+	w.Write(body)
+}
+
+// handleContentDirectory answers ContentDirectory control requests.
+func (s *Server) handleContentDirectory(w http.ResponseWriter, r *http.Request) {
+	action, args, err := readSOAP(r)
+	if err != nil {
+		writeSOAPFault(w, errInvalidAction)
+		return
+	}
+
+	var result []soapArg
+	var upnpErr *upnpError
+	switch action {
+	case "Browse":
+		result, upnpErr = s.browse(args, "http://"+r.Host)
+	case "GetSystemUpdateID":
+		_, updateID, err := s.currentTree()
+		if err != nil {
+			upnpErr = errActionFailed
+			break
+		}
+		result = []soapArg{{"Id", strconv.FormatUint(uint64(updateID), 10)}}
+	case "GetSearchCapabilities":
+		result = []soapArg{{"SearchCaps", ""}}
+	case "GetSortCapabilities":
+		result = []soapArg{{"SortCaps", ""}}
+	default:
+		upnpErr = errInvalidAction
+	}
+	if upnpErr != nil {
+		s.logger.Debug("DLNA action failed", "action", action, "code", upnpErr.code)
+		writeSOAPFault(w, upnpErr)
+		return
+	}
+	writeSOAP(w, contentDirectoryType, action, result)
+}
+
+// handleConnectionManager answers ConnectionManager control requests. The
+// server only ever serves over HTTP, so there is a single connection, 0.
+func (s *Server) handleConnectionManager(w http.ResponseWriter, r *http.Request) {
+	action, _, err := readSOAP(r)
+	if err != nil {
+		writeSOAPFault(w, errInvalidAction)
+		return
+	}
+
+	var result []soapArg
+	switch action {
+	case "GetProtocolInfo":
+		result = []soapArg{{"Source", sourceProtocolInfo()}, {"Sink", ""}}
+	case "GetCurrentConnectionIDs":
+		result = []soapArg{{"ConnectionIDs", "0"}}
+	case "GetCurrentConnectionInfo":
+		result = []soapArg{
+			{"RcsID", "-1"},
+			{"AVTransportID", "-1"},
+			{"ProtocolInfo", ""},
+			{"PeerConnectionManager", ""},
+			{"PeerConnectionID", "-1"},
+			{"Direction", "Output"},
+			{"Status", "OK"},
+		}
+	default:
+		writeSOAPFault(w, errInvalidAction)
+		return
+	}
+	writeSOAP(w, connectionManagerType, action, result)
+}
+
+// sourceProtocolInfo lists the protocol info of every MIME type the
+// server can serve.
+func sourceProtocolInfo() string {
+	seen := make(map[string]bool)
+	var infos []string
+	for _, mime := range mimeTypes {
+		if !seen[mime] {
+			seen[mime] = true
+			infos = append(infos, protocolInfo(mime))
+		}
+	}
+	sort.Strings(infos)
+	return strings.Join(infos, ",")
+}
+
+// browse answers a Browse action: the object itself or a page of its
+// children as DIDL-Lite.
+func (s *Server) browse(args map[string]string, baseURL string) ([]soapArg, *upnpError) {
+	tree, updateID, err := s.currentTree()
+	if err != nil {
+		s.logger.Warn("Failed to build DLNA content directory", "error", err)
+		return nil, errActionFailed
+	}
+
+	obj, ok := tree.objects[args["ObjectID"]]
+	if !ok {
+		return nil, errNoSuchObject
+	}
+	start, err := parseCount(args["StartingIndex"])
+	if err != nil {
+		return nil, errInvalidArgs
+	}
+	count, err := parseCount(args["RequestedCount"])
+	if err != nil {
+		return nil, errInvalidArgs
+	}
+
+	var objects []*object
+	var total int
+	switch args["BrowseFlag"] {
+	case "BrowseMetadata":
+		objects, total = []*object{obj}, 1
+	case "BrowseDirectChildren":
+		total = len(obj.children)
+		objects = obj.children[min(start, total):]
+		if count > 0 && count < len(objects) {
+			objects = objects[:count]
+		}
+	default:
+		return nil, errInvalidArgs
+	}
+
+	result, err := didl(objects, baseURL)
+	if err != nil {
+		return nil, errActionFailed
+	}
+	return []soapArg{
+		{"Result", result},
+		{"NumberReturned", strconv.Itoa(len(objects))},
+		{"TotalMatches", strconv.Itoa(total)},
+		{"UpdateID", strconv.FormatUint(uint64(updateID), 10)},
+	}, nil
+}
+
+// parseCount parses an unsigned Browse argument; empty means 0.
+func parseCount(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(value, 10, 31)
+	return int(n), err
+}
+
+type didlLite struct {
+	XMLName    xml.Name        `xml:"DIDL-Lite"`
+	Xmlns      string          `xml:"xmlns,attr"`
+	XmlnsDC    string          `xml:"xmlns:dc,attr"`
+	XmlnsUPnP  string          `xml:"xmlns:upnp,attr"`
+	XmlnsDLNA  string          `xml:"xmlns:dlna,attr"`
+	Containers []didlContainer `xml:"container"`
+	Items      []didlItem      `xml:"item"`
+}
+
+type didlContainer struct {
+	ID         string `xml:"id,attr"`
+	ParentID   string `xml:"parentID,attr"`
+	Restricted string `xml:"restricted,attr"`
+	ChildCount int    `xml:"childCount,attr"`
+	Title      string `xml:"dc:title"`
+	Class      string `xml:"upnp:class"`
+}
+
+type didlItem struct {
+	ID         string  `xml:"id,attr"`
+	ParentID   string  `xml:"parentID,attr"`
+	Restricted string  `xml:"restricted,attr"`
+	Title      string  `xml:"dc:title"`
+	Class      string  `xml:"upnp:class"`
+	Res        didlRes `xml:"res"`
+}
+
+type didlRes struct {
+	ProtocolInfo string `xml:"protocolInfo,attr"`
+	Size         int64  `xml:"size,attr,omitempty"`
+	Duration     string `xml:"duration,attr,omitempty"`
+	URL          string `xml:",chardata"`
+}
+
+// didl renders objects as a DIDL-Lite document. Items link to their
+// media under baseURL.
+func didl(objects []*object, baseURL string) (string, error) {
+	doc := didlLite{
+		Xmlns:     "urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/",
+		XmlnsDC:   "http://purl.org/dc/elements/1.1/",
+		XmlnsUPnP: "urn:schemas-upnp-org:metadata-1-0/upnp/",
+		XmlnsDLNA: "urn:schemas-dlna-org:metadata-1-0/",
+	}
+	for _, obj := range objects {
+		if obj.record == nil {
+			doc.Containers = append(doc.Containers, didlContainer{
+				ID:         obj.id,
+				ParentID:   obj.parentID,
+				Restricted: "1",
+				ChildCount: len(obj.children),
+				Title:      obj.title,
+				Class:      obj.class,
+			})
+			continue
+		}
+
+		record := obj.record
+		res := didlRes{
+			ProtocolInfo: protocolInfo(mimeType(record)),
+			Size:         record.Size,
+			URL:          baseURL + "/media/" + url.PathEscape(record.JellyfinID) + strings.ToLower(filepath.Ext(record.LocalPath)),
+		}
+		if obj.duration > 0 {
+			res.Duration = formatDuration(obj.duration)
+		}
+		doc.Items = append(doc.Items, didlItem{
+			ID:         obj.id,
+			ParentID:   obj.parentID,
+			Restricted: "1",
+			Title:      obj.title,
+			Class:      obj.class,
+			Res:        res,
+		})
+	}
+
+	body, err := xml.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// formatDuration formats a duration as DIDL-Lite expects: H:MM:SS.mmm.
+func formatDuration(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%d:%02d:%02d.%03d", ms/3_600_000, ms/60_000%60, ms/1000%60, ms%1000)
+}
+
+// readSOAP returns the action a control request invokes and its arguments.
+func readSOAP(r *http.Request) (string, map[string]string, error) {
+	if r.Method != http.MethodPost {
+		return "", nil, fmt.Errorf("control requests must be POST")
+	}
+
+	decoder := xml.NewDecoder(io.LimitReader(r.Body, 64<<10))
+	var action string
+	args := make(map[string]string)
+	depth, bodyDepth := 0, 0
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case bodyDepth == 0 && t.Name.Local == "Body":
+				bodyDepth = depth
+			case bodyDepth > 0 && depth == bodyDepth+1:
+				action = t.Name.Local
+			case bodyDepth > 0 && depth == bodyDepth+2:
+				var value string
+				if err := decoder.DecodeElement(&value, &t); err != nil {
+					return "", nil, err
+				}
+				args[t.Name.Local] = value
+				depth--
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+
+	if action == "" {
+		return "", nil, fmt.Errorf("no action in request")
+	}
+	return action, args, nil
+}
+
+// writeSOAP writes the response to an action.
+func writeSOAP(w http.ResponseWriter, serviceType, action string, args []soapArg) {
+	var body bytes.Buffer
+	body.WriteString(xml.Header)
+	body.WriteString(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	fmt.Fprintf(&body, `<u:%sResponse xmlns:u="%s">`, action, serviceType)
+	for _, arg := range args {
+		fmt.Fprintf(&body, "<%s>", arg.name)
+		xml.EscapeText(&body, []byte(arg.value))
+		fmt.Fprintf(&body, "</%s>", arg.name)
+	}
+	fmt.Fprintf(&body, `</u:%sResponse></s:Body></s:Envelope>`, action)
+
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.Header().Set("EXT", "")
+	w.Write(body.Bytes())
+}
+
+// writeSOAPFault writes a UPnP error as a SOAP fault.
+func writeSOAPFault(w http.ResponseWriter, upnpErr *upnpError) {
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.WriteHeader(http.StatusInternalServerError)
+	fmt.Fprintf(w, `%s<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body><s:Fault><faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring><detail><UPnPError xmlns="urn:schemas-upnp-org:control-1-0"><errorCode>%d</errorCode><errorDescription>%s</errorDescription></UPnPError></detail></s:Fault></s:Body></s:Envelope>`,
+		xml.Header, upnpErr.code, upnpErr.description)
+}
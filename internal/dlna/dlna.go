@@ -0,0 +1,490 @@
+// Package dlna serves the cache as a UPnP AV media server, so smart TVs and
+// other DLNA players on the LAN can browse and play cached items without
+// the web UI.
+//
+// Design Philosophy:
+//   - Only completed downloads are advertised; partial and evicted items never appear
+//   - The content directory is rebuilt from download records, and clients are
+//     told through SystemUpdateID whenever items are cached or evicted
+//   - Media is served from the cache with Range support, decrypted on the fly
+//     when the cache is encrypted
+//   - The server is read-only and unauthenticated, like the LAN it runs on
+package dlna
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// UPnP types of the device and its services.
+const (
+	deviceType            = "urn:schemas-upnp-org:device:MediaServer:1"
+	contentDirectoryType  = "urn:schemas-upnp-org:service:ContentDirectory:1"
+	connectionManagerType = "urn:schemas-upnp-org:service:ConnectionManager:1"
+)
+
+// refreshInterval is how often download records are checked for items that
+// were cached or evicted.
+const refreshInterval = 10 * time.Second
+
+// rootID is the object ID of the top of the content directory.
+const rootID = "0"
+
+// Server is a DLNA media server for the cache.
+type Server struct {
+	config *config.DLNAConfig
+	host   string
+	store  storage.MediaStore
+	cipher *storage.Cipher
+	logger *slog.Logger
+	uuid   string
+	events *http.Client
+
+	mu       sync.Mutex
+	tree     *contentTree
+	updateID uint32
+	subs     map[string]*subscription
+
+	httpServer *http.Server
+	ssdp       *ssdpServer
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+// New creates a DLNA server for the cache in store. host is the address
+// the server listens on, empty for all interfaces; cipher decrypts an
+// encrypted cache and is nil otherwise.
+func New(cfg *config.DLNAConfig, host string, store storage.MediaStore, cipher *storage.Cipher, logger *slog.Logger) *Server {
+	return &Server{
+		config:   cfg,
+		host:     host,
+		store:    store,
+		cipher:   cipher,
+		logger:   logger,
+		uuid:     deviceUUID(cfg.FriendlyName, cfg.Port),
+		events:   &http.Client{Timeout: 5 * time.Second},
+		updateID: 1,
+		subs:     make(map[string]*subscription),
+	}
+}
+
+// deviceUUID derives a stable device UUID from the host name and the
+// server's name and port, so TVs recognize the server across restarts.
+func deviceUUID(name string, port int) string {
+	hostname, _ := os.Hostname()
+	sum := sha1.Sum([]byte(hostname + "\x00" + name + "\x00" + strconv.Itoa(port)))
+	sum[6] = sum[6]&0x0f | 0x50 // version 5, name based
+	sum[8] = sum[8]&0x3f | 0x80 // RFC 4122 variant
+	return formatUUID(sum[:16])
+}
+
+// formatUUID formats 16 bytes as a UUID.
+func formatUUID(b []byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// Handler returns the HTTP handler for the device description, the
+// service control and eventing URLs and the media files.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/description.xml", s.handleDescription)
+	mux.HandleFunc("/ContentDirectory.xml", serveSCPD(contentDirectorySCPD))
+	mux.HandleFunc("/ConnectionManager.xml", serveSCPD(connectionManagerSCPD))
+	mux.HandleFunc("/control/ContentDirectory", s.handleContentDirectory)
+	mux.HandleFunc("/control/ConnectionManager", s.handleConnectionManager)
+	mux.HandleFunc("/event/ContentDirectory", s.handleEvent(contentDirectoryType))
+	mux.HandleFunc("/event/ConnectionManager", s.handleEvent(connectionManagerType))
+	mux.HandleFunc("/media/", s.handleMedia)
+	return mux
+}
+
+// Start serves the device over HTTP, announces it over SSDP and keeps the
+// content directory up to date until ctx is cancelled or Stop is called.
+// A failure to announce is logged rather than returned, since players
+// that were given the description URL can still use the server.
+func (s *Server) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", net.JoinHostPort(s.host, strconv.Itoa(s.config.Port)))
+	if err != nil {
+		return fmt.Errorf("failed to listen for DLNA: %w", err)
+	}
+	if err := s.refresh(); err != nil {
+		s.logger.Warn("Failed to build DLNA content directory", "error", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.httpServer = &http.Server{
+		Handler:     s.Handler(),
+		ReadTimeout: 30 * time.Second,
+		IdleTimeout: 60 * time.Second,
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("DLNA server error", "error", err)
+		}
+	}()
+
+	s.ssdp = newSSDPServer(s.uuid, s.host, s.config.Port, s.logger)
+	if err := s.ssdp.start(ctx, &s.wg); err != nil {
+		s.logger.Warn("Failed to announce DLNA server, TVs will not discover it", "error", err)
+		s.ssdp = nil
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.refreshLoop(ctx)
+	}()
+
+	s.logger.Info("Started DLNA server",
+		"address", listener.Addr().String(),
+		"name", s.config.FriendlyName,
+		"uuid", s.uuid)
+	return nil
+}
+
+// Stop says goodbye over SSDP and shuts the server down.
+func (s *Server) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	if s.ssdp != nil {
+		s.ssdp.stop()
+	}
+	s.cancel()
+	err := s.httpServer.Shutdown(ctx)
+	s.wg.Wait()
+	return err
+}
+
+// refreshLoop rebuilds the content directory every refreshInterval.
+func (s *Server) refreshLoop(ctx context.Context) {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.refresh(); err != nil {
+				s.logger.Warn("Failed to refresh DLNA content directory", "error", err)
+			}
+		}
+	}
+}
+
+// refresh rebuilds the content directory and, when items were cached or
+// evicted since the last build, bumps SystemUpdateID and tells subscribers.
+func (s *Server) refresh() error {
+	tree, err := s.buildTree()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	changed := s.tree != nil && s.tree.fingerprint != tree.fingerprint
+	if changed {
+		s.updateID++
+	}
+	s.tree = tree
+	s.mu.Unlock()
+
+	if changed {
+		s.notifySubscribers()
+	}
+	return nil
+}
+
+// currentTree returns the content directory, building it on first use.
+func (s *Server) currentTree() (*contentTree, uint32, error) {
+	s.mu.Lock()
+	tree, updateID := s.tree, s.updateID
+	s.mu.Unlock()
+	if tree != nil {
+		return tree, updateID, nil
+	}
+
+	if err := s.refresh(); err != nil {
+		return nil, 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tree, s.updateID, nil
+}
+
+// contentTree is the browsable content directory:
+//
+//	Movies/{title}
+//	Shows/{series}/Season {N}/{episode}
+//	Music/{album}/{track}
+//	Audiobooks/{book}/{chapter}
+//	Other/{title}
+type contentTree struct {
+	objects map[string]*object
+	// fingerprint changes whenever the set of advertised items does
+	fingerprint string
+}
+
+// object is a container or an item of the content directory.
+type object struct {
+	id       string
+	parentID string
+	title    string
+	class    string
+	sortKey  string
+	children []*object               // containers only
+	record   *storage.DownloadRecord // items only
+	duration time.Duration
+}
+
+// buildTree builds the content directory from completed download records.
+func (s *Server) buildTree() (*contentTree, error) {
+	records, err := s.store.ListDownloadRecords("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cached downloads: %w", err)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+
+	tree := &contentTree{objects: make(map[string]*object)}
+	root := &object{id: rootID, parentID: "-1", title: s.config.FriendlyName, class: "object.container.storageFolder"}
+	tree.objects[rootID] = root
+
+	fingerprint := sha1.New()
+	for _, record := range records {
+		if record.Status != "" && record.Status != "completed" {
+			continue
+		}
+		fmt.Fprintf(fingerprint, "%s\x00%s\x00%d\x00%s\n", record.ID, record.Title, record.Size, record.LocalPath)
+
+		metadata, _ := s.store.GetMediaMetadata(record.JellyfinID)
+		parent := root
+		for _, c := range s.containersFor(record, metadata) {
+			parent = tree.container(parent, c)
+		}
+
+		item := &object{
+			id:       "item/" + record.JellyfinID,
+			parentID: parent.id,
+			title:    record.Title,
+			class:    itemClass(record),
+			sortKey:  strings.ToLower(record.Title),
+			record:   record,
+		}
+		if item.title == "" {
+			item.title = record.JellyfinID
+		}
+		if metadata != nil {
+			switch {
+			case metadata.EpisodeNumber > 0:
+				item.sortKey = fmt.Sprintf("%04d", metadata.EpisodeNumber)
+			case metadata.TrackNumber > 0:
+				item.sortKey = fmt.Sprintf("%03d%04d", metadata.DiscNumber, metadata.TrackNumber)
+			}
+			item.duration = time.Duration(metadata.RunTimeTicks * 100)
+		}
+		if _, exists := tree.objects[item.id]; exists {
+			continue
+		}
+		tree.objects[item.id] = item
+		parent.children = append(parent.children, item)
+	}
+
+	for _, obj := range tree.objects {
+		sort.SliceStable(obj.children, func(i, j int) bool {
+			a, b := obj.children[i], obj.children[j]
+			if a.sortKey != b.sortKey {
+				return a.sortKey < b.sortKey
+			}
+			return a.title < b.title
+		})
+	}
+
+	tree.fingerprint = fmt.Sprintf("%x", fingerprint.Sum(nil))
+	return tree, nil
+}
+
+// containerSpec describes a container an item is filed under.
+type containerSpec struct {
+	id, title, class, sortKey string
+}
+
+// container returns the child container of parent described by c,
+// creating it if needed.
+func (t *contentTree) container(parent *object, c containerSpec) *object {
+	if obj, ok := t.objects[c.id]; ok {
+		return obj
+	}
+	class := c.class
+	if class == "" {
+		class = "object.container.storageFolder"
+	}
+	obj := &object{id: c.id, parentID: parent.id, title: c.title, class: class, sortKey: c.sortKey}
+	t.objects[c.id] = obj
+	parent.children = append(parent.children, obj)
+	return obj
+}
+
+// containersFor returns the containers a download is filed under, using
+// series and album metadata when it is available.
+func (s *Server) containersFor(record *storage.DownloadRecord, metadata *storage.MediaMetadata) []containerSpec {
+	switch record.MediaType {
+	case "movie":
+		return []containerSpec{{id: "movies", title: "Movies", sortKey: "0"}}
+	case "episode":
+		seriesID, series, season := "unknown", "Unknown Series", 0
+		if metadata != nil && metadata.SeriesID != "" {
+			seriesID, series, season = metadata.SeriesID, metadata.SeriesID, metadata.SeasonNumber
+			if seriesMeta, err := s.store.GetMediaMetadata(metadata.SeriesID); err == nil && seriesMeta != nil && seriesMeta.Name != "" {
+				series = seriesMeta.Name
+			}
+		}
+		return []containerSpec{
+			{id: "shows", title: "Shows", sortKey: "1"},
+			{id: "shows/" + seriesID, title: series, sortKey: strings.ToLower(series)},
+			{id: fmt.Sprintf("shows/%s/%d", seriesID, season), title: fmt.Sprintf("Season %d", season), sortKey: fmt.Sprintf("%04d", season)},
+		}
+	case "audio", "audiobook":
+		top := containerSpec{id: "music", title: "Music", sortKey: "2"}
+		class := "object.container.album.musicAlbum"
+		if record.MediaType == "audiobook" {
+			top = containerSpec{id: "audiobooks", title: "Audiobooks", sortKey: "3"}
+			class = "object.container.storageFolder"
+		}
+		albumID, album := "unknown", "Unknown Album"
+		if metadata != nil && metadata.AlbumID != "" {
+			albumID, album = metadata.AlbumID, metadata.AlbumID
+			if albumMeta, err := s.store.GetMediaMetadata(metadata.AlbumID); err == nil && albumMeta != nil && albumMeta.Name != "" {
+				album = albumMeta.Name
+			}
+		}
+		return []containerSpec{top, {id: top.id + "/" + albumID, title: album, class: class, sortKey: strings.ToLower(album)}}
+	default:
+		return []containerSpec{{id: "other", title: "Other", sortKey: "4"}}
+	}
+}
+
+// itemClass returns the UPnP class of a cached item.
+func itemClass(record *storage.DownloadRecord) string {
+	switch record.MediaType {
+	case "movie":
+		return "object.item.videoItem.movie"
+	case "episode":
+		return "object.item.videoItem"
+	case "audio":
+		return "object.item.audioItem.musicTrack"
+	case "audiobook":
+		return "object.item.audioItem.audioBook"
+	}
+	if strings.HasPrefix(mimeType(record), "audio/") {
+		return "object.item.audioItem"
+	}
+	return "object.item.videoItem"
+}
+
+// mimeTypes maps file extensions to the MIME types players expect.
+var mimeTypes = map[string]string{
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".mkv":  "video/x-matroska",
+	".avi":  "video/x-msvideo",
+	".mov":  "video/quicktime",
+	".wmv":  "video/x-ms-wmv",
+	".webm": "video/webm",
+	".ts":   "video/mp2t",
+	".mp3":  "audio/mpeg",
+	".flac": "audio/flac",
+	".m4a":  "audio/mp4",
+	".m4b":  "audio/mp4",
+	".aac":  "audio/aac",
+	".ogg":  "audio/ogg",
+	".opus": "audio/ogg",
+	".wav":  "audio/wav",
+}
+
+// mimeType returns the MIME type a cached item is served as.
+func mimeType(record *storage.DownloadRecord) string {
+	if record.ContentType != "" {
+		return record.ContentType
+	}
+	if t, ok := mimeTypes[strings.ToLower(filepath.Ext(record.LocalPath))]; ok {
+		return t
+	}
+	if record.MediaType == "audio" || record.MediaType == "audiobook" {
+		return "audio/mpeg"
+	}
+	return "video/mp4"
+}
+
+// protocolInfo returns the DLNA protocol info of a MIME type: plain HTTP
+// with byte range seeking.
+func protocolInfo(mime string) string {
+	return "http-get:*:" + mime + ":" + contentFeatures
+}
+
+// contentFeatures advertises byte seeking, streaming transfer and DLNA 1.5.
+const contentFeatures = "DLNA.ORG_OP=01;DLNA.ORG_CI=0;DLNA.ORG_FLAGS=01700000000000000000000000000000"
+
+// handleMedia serves a cached item with Range support.
+func (s *Server) handleMedia(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/media/")
+	mediaID := strings.TrimSuffix(name, filepath.Ext(name))
+
+	tree, _, err := s.currentTree()
+	if err != nil {
+		http.Error(w, "Content directory unavailable", http.StatusInternalServerError)
+		return
+	}
+	item, ok := tree.objects["item/"+mediaID]
+	if !ok || item.record == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	file, err := storage.OpenCached(item.record.LocalPath, s.cipher)
+	if err != nil {
+		s.logger.Warn("Failed to open cached file for DLNA", "media_id", mediaID, "error", err)
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return
+	}
+
+	if rangeHeader := r.Header.Get("Range"); r.Method == http.MethodGet && (rangeHeader == "" || rangeHeader == "bytes=0-") {
+		if err := s.store.RecordAccess(mediaID, time.Now()); err != nil {
+			s.logger.Debug("Failed to record cache access", "media_id", mediaID, "error", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", mimeType(item.record))
+	w.Header().Set("transferMode.dlna.org", "Streaming")
+	w.Header().Set("contentFeatures.dlna.org", contentFeatures)
+	http.ServeContent(w, r, filepath.Base(item.record.LocalPath), info.ModTime(), file)
+}
@@ -0,0 +1,236 @@
+package dlna
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func newTestServer(t *testing.T) (*Server, *storagetest.MemStore, string) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	dir := t.TempDir()
+
+	for _, metadata := range []*storage.MediaMetadata{
+		{ID: "s1", JellyfinID: "s1", Name: "Bluey", Type: "series"},
+		{ID: "e1", JellyfinID: "e1", Name: "Camping", Type: "episode", SeriesID: "s1", SeasonNumber: 1, EpisodeNumber: 2, RunTimeTicks: int64(7*time.Minute) / 100},
+		{ID: "e2", JellyfinID: "e2", Name: "Magic Xylophone", Type: "episode", SeriesID: "s1", SeasonNumber: 1, EpisodeNumber: 1},
+	} {
+		if err := store.AddMediaMetadata(metadata); err != nil {
+			t.Fatalf("Failed to add metadata: %v", err)
+		}
+	}
+	for _, record := range []*storage.DownloadRecord{
+		{ID: "movie:m1", JellyfinID: "m1", MediaType: "movie", Title: "Heat", Status: "completed"},
+		{ID: "movie:m2", JellyfinID: "m2", MediaType: "movie", Title: "Ronin", Status: "evicted"},
+		{ID: "episode:e1", JellyfinID: "e1", MediaType: "episode", Title: "Camping", Status: "completed"},
+		{ID: "episode:e2", JellyfinID: "e2", MediaType: "episode", Title: "Magic Xylophone", Status: "completed"},
+	} {
+		record.LocalPath = filepath.Join(dir, record.JellyfinID+".mkv")
+		if err := os.WriteFile(record.LocalPath, []byte("video of "+record.Title), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		record.Size = int64(len("video of " + record.Title))
+		if err := store.AddDownloadRecord(record); err != nil {
+			t.Fatalf("Failed to add record: %v", err)
+		}
+	}
+
+	cfg := &config.DLNAConfig{Enabled: true, Port: 8200, FriendlyName: "Living Room Cache"}
+	return New(cfg, "", store, nil, logger), store, dir
+}
+
+// soapRequest invokes a ContentDirectory action.
+func soapRequest(t *testing.T, handler http.Handler, action string, args map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	var body strings.Builder
+	body.WriteString(`<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>`)
+	body.WriteString(`<u:` + action + ` xmlns:u="` + contentDirectoryType + `">`)
+	for name, value := range args {
+		body.WriteString("<" + name + ">" + value + "</" + name + ">")
+	}
+	body.WriteString(`</u:` + action + `></s:Body></s:Envelope>`)
+
+	req := httptest.NewRequest(http.MethodPost, "http://192.168.1.10:8200/control/ContentDirectory", strings.NewReader(body.String()))
+	req.Header.Set("SOAPACTION", `"`+contentDirectoryType+`#`+action+`"`)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func browse(t *testing.T, handler http.Handler, objectID string) string {
+	t.Helper()
+	w := soapRequest(t, handler, "Browse", map[string]string{
+		"ObjectID":       objectID,
+		"BrowseFlag":     "BrowseDirectChildren",
+		"StartingIndex":  "0",
+		"RequestedCount": "0",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 browsing %s, got %d: %s", objectID, w.Code, w.Body.String())
+	}
+	return w.Body.String()
+}
+
+func TestDescription(t *testing.T) {
+	server, _, _ := newTestServer(t)
+
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/description.xml", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	for _, want := range []string{"<friendlyName>Living Room Cache</friendlyName>", "<UDN>uuid:" + server.uuid + "</UDN>", contentDirectoryType, "/control/ContentDirectory"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Expected description to contain %q, got %s", want, w.Body.String())
+		}
+	}
+}
+
+func TestBrowse(t *testing.T) {
+	server, _, _ := newTestServer(t)
+	handler := server.Handler()
+
+	root := browse(t, handler, rootID)
+	if !strings.Contains(root, "&lt;dc:title&gt;Movies&lt;/dc:title&gt;") || !strings.Contains(root, "&lt;dc:title&gt;Shows&lt;/dc:title&gt;") {
+		t.Errorf("Expected Movies and Shows at the root, got %s", root)
+	}
+	if !strings.Contains(root, "<TotalMatches>2</TotalMatches>") {
+		t.Errorf("Expected two containers at the root, got %s", root)
+	}
+
+	movies := browse(t, handler, "movies")
+	if !strings.Contains(movies, "Heat") || strings.Contains(movies, "Ronin") {
+		t.Errorf("Expected only the completed movie, got %s", movies)
+	}
+	if !strings.Contains(movies, "http://192.168.1.10:8200/media/m1.mkv") {
+		t.Errorf("Expected a media URL on the requested host, got %s", movies)
+	}
+
+	season := browse(t, handler, "shows/s1/1")
+	first, second := strings.Index(season, "Magic Xylophone"), strings.Index(season, "Camping")
+	if first < 0 || second < 0 || first > second {
+		t.Errorf("Expected episodes in episode order, got %s", season)
+	}
+	if !strings.Contains(season, `duration=&#34;0:07:00.000&#34;`) {
+		t.Errorf("Expected the episode duration, got %s", season)
+	}
+
+	if w := soapRequest(t, handler, "Browse", map[string]string{"ObjectID": "missing", "BrowseFlag": "BrowseDirectChildren"}); w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "<errorCode>701</errorCode>") {
+		t.Errorf("Expected error 701 for an unknown object, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRefreshTracksCacheChanges(t *testing.T) {
+	server, store, dir := newTestServer(t)
+	handler := server.Handler()
+
+	var mu sync.Mutex
+	var notified []string
+	events := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		notified = append(notified, r.Header.Get("SEQ")+":"+string(body))
+		mu.Unlock()
+	}))
+	defer events.Close()
+
+	req := httptest.NewRequest("SUBSCRIBE", "/event/ContentDirectory", nil)
+	req.Header.Set("CALLBACK", "<"+events.URL+"/events>")
+	req.Header.Set("NT", "upnp:event")
+	req.Header.Set("TIMEOUT", "Second-1800")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("SID"), "uuid:") {
+		t.Fatalf("Expected a subscription, got %d %v", w.Code, w.Header())
+	}
+
+	w = soapRequest(t, handler, "GetSystemUpdateID", nil)
+	if !strings.Contains(w.Body.String(), "<Id>1</Id>") {
+		t.Errorf("Expected update ID 1, got %s", w.Body.String())
+	}
+
+	// Rebuilding without changes keeps the update ID
+	if err := server.refresh(); err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+	w = soapRequest(t, handler, "GetSystemUpdateID", nil)
+	if !strings.Contains(w.Body.String(), "<Id>1</Id>") {
+		t.Errorf("Expected update ID 1 after an unchanged refresh, got %s", w.Body.String())
+	}
+
+	path := filepath.Join(dir, "m3.mp4")
+	if err := os.WriteFile(path, []byte("new"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := store.AddDownloadRecord(&storage.DownloadRecord{ID: "movie:m3", JellyfinID: "m3", MediaType: "movie", Title: "Thief", LocalPath: path, Status: "completed"}); err != nil {
+		t.Fatalf("Failed to add record: %v", err)
+	}
+	if err := server.refresh(); err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+	w = soapRequest(t, handler, "GetSystemUpdateID", nil)
+	if !strings.Contains(w.Body.String(), "<Id>2</Id>") {
+		t.Errorf("Expected update ID 2 after an item was cached, got %s", w.Body.String())
+	}
+	if movies := browse(t, handler, "movies"); !strings.Contains(movies, "Thief") {
+		t.Errorf("Expected the new movie to be listed, got %s", movies)
+	}
+
+	found := false
+	for deadline := time.Now().Add(2 * time.Second); !found && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		mu.Lock()
+		for _, event := range notified {
+			if strings.HasPrefix(event, "1:") && strings.Contains(event, "<SystemUpdateID>2</SystemUpdateID>") {
+				found = true
+			}
+		}
+		mu.Unlock()
+	}
+	if !found {
+		t.Error("Expected subscribers to be told about update 2")
+	}
+}
+
+func TestHandleMedia(t *testing.T) {
+	server, store, _ := newTestServer(t)
+	handler := server.Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/media/m1.mkv", nil)
+	req.Header.Set("Range", "bytes=0-4")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "video" {
+		t.Errorf("Expected the first bytes of the file, got %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "video/x-matroska" || w.Header().Get("contentFeatures.dlna.org") == "" {
+		t.Errorf("Expected DLNA headers, got %v", w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/media/m1.mkv", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	if record, err := store.GetDownload("m1"); err != nil || record.AccessCount != 1 {
+		t.Errorf("Expected playback to be recorded, got %+v (%v)", record, err)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/media/m2.mkv", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected evicted items to be unavailable, got %d", w.Code)
+	}
+}
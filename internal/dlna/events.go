@@ -0,0 +1,210 @@
+package dlna
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bounds on how long an event subscription lasts before it must be
+// renewed.
+const (
+	defaultSubscriptionTimeout = 30 * time.Minute
+	minSubscriptionTimeout     = time.Minute
+)
+
+// subscription is a control point subscribed to a service's events.
+type subscription struct {
+	sid      string
+	service  string
+	callback string
+	expires  time.Time
+	seq      uint32
+}
+
+// handleEvent handles SUBSCRIBE and UNSUBSCRIBE requests for a service's
+// events. ContentDirectory subscribers are told the new SystemUpdateID
+// whenever items are cached or evicted.
+func (s *Server) handleEvent(serviceType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "SUBSCRIBE":
+			s.subscribe(w, r, serviceType)
+		case "UNSUBSCRIBE":
+			s.mu.Lock()
+			_, ok := s.subs[r.Header.Get("SID")]
+			delete(s.subs, r.Header.Get("SID"))
+			s.mu.Unlock()
+			if !ok {
+				http.Error(w, "Unknown subscription", http.StatusPreconditionFailed)
+			}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// subscribe creates or renews a subscription and, for a new one, sends the
+// initial event.
+func (s *Server) subscribe(w http.ResponseWriter, r *http.Request, serviceType string) {
+	timeout := parseTimeout(r.Header.Get("TIMEOUT"))
+
+	if sid := r.Header.Get("SID"); sid != "" {
+		if r.Header.Get("CALLBACK") != "" || r.Header.Get("NT") != "" {
+			http.Error(w, "Renewals take only SID", http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		sub, ok := s.subs[sid]
+		if ok {
+			sub.expires = time.Now().Add(timeout)
+		}
+		s.mu.Unlock()
+		if !ok {
+			http.Error(w, "Unknown subscription", http.StatusPreconditionFailed)
+			return
+		}
+		writeSubscription(w, sid, timeout)
+		return
+	}
+
+	callback := parseCallback(r.Header.Get("CALLBACK"))
+	if r.Header.Get("NT") != "upnp:event" || callback == "" {
+		http.Error(w, "NT and CALLBACK are required", http.StatusPreconditionFailed)
+		return
+	}
+
+	sub := &subscription{
+		sid:      "uuid:" + newUUID(),
+		service:  serviceType,
+		callback: callback,
+		expires:  time.Now().Add(timeout),
+	}
+	s.mu.Lock()
+	s.subs[sub.sid] = sub
+	s.mu.Unlock()
+	writeSubscription(w, sub.sid, timeout)
+
+	props, err := s.eventedState(serviceType)
+	if err != nil {
+		s.logger.Debug("Failed to build initial DLNA event", "error", err)
+		return
+	}
+	go s.sendEvent(sub.callback, sub.sid, 0, props)
+}
+
+// writeSubscription answers a successful SUBSCRIBE.
+func writeSubscription(w http.ResponseWriter, sid string, timeout time.Duration) {
+	w.Header().Set("SID", sid)
+	w.Header().Set("TIMEOUT", fmt.Sprintf("Second-%d", int(timeout.Seconds())))
+	w.WriteHeader(http.StatusOK)
+}
+
+// parseTimeout parses a TIMEOUT header such as "Second-1800", falling back
+// to the default for missing, infinite or unreasonably short requests.
+func parseTimeout(header string) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimPrefix(header, "Second-"))
+	if err != nil {
+		return defaultSubscriptionTimeout
+	}
+	timeout := time.Duration(seconds) * time.Second
+	if timeout < minSubscriptionTimeout {
+		return minSubscriptionTimeout
+	}
+	return min(timeout, defaultSubscriptionTimeout)
+}
+
+// parseCallback returns the first HTTP URL of a CALLBACK header, which
+// lists them in angle brackets.
+func parseCallback(header string) string {
+	for _, part := range strings.Split(header, ">") {
+		url := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(part), "<"))
+		if strings.HasPrefix(url, "http://") {
+			return url
+		}
+	}
+	return ""
+}
+
+// newUUID returns a random UUID.
+func newUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return formatUUID(b)
+}
+
+// eventedState returns the evented state variables of a service.
+func (s *Server) eventedState(serviceType string) ([]soapArg, error) {
+	if serviceType == connectionManagerType {
+		return []soapArg{
+			{"SourceProtocolInfo", sourceProtocolInfo()},
+			{"SinkProtocolInfo", ""},
+			{"CurrentConnectionIDs", "0"},
+		}, nil
+	}
+
+	_, updateID, err := s.currentTree()
+	if err != nil {
+		return nil, err
+	}
+	return []soapArg{{"SystemUpdateID", strconv.FormatUint(uint64(updateID), 10)}}, nil
+}
+
+// notifySubscribers sends the current SystemUpdateID to ContentDirectory
+// subscribers and drops expired subscriptions.
+func (s *Server) notifySubscribers() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	props := []soapArg{{"SystemUpdateID", strconv.FormatUint(uint64(s.updateID), 10)}}
+	now := time.Now()
+	for sid, sub := range s.subs {
+		if now.After(sub.expires) {
+			delete(s.subs, sid)
+			continue
+		}
+		if sub.service != contentDirectoryType {
+			continue
+		}
+		sub.seq++
+		go s.sendEvent(sub.callback, sub.sid, sub.seq, props)
+	}
+}
+
+// sendEvent delivers an event to a subscriber's callback URL.
+func (s *Server) sendEvent(callback, sid string, seq uint32, props []soapArg) {
+	var body bytes.Buffer
+	body.WriteString(xml.Header)
+	body.WriteString(`<e:propertyset xmlns:e="urn:schemas-upnp-org:event-1-0">`)
+	for _, prop := range props {
+		fmt.Fprintf(&body, "<e:property><%s>", prop.name)
+		xml.EscapeText(&body, []byte(prop.value))
+		fmt.Fprintf(&body, "</%s></e:property>", prop.name)
+	}
+	body.WriteString(`</e:propertyset>`)
+
+	req, err := http.NewRequest("NOTIFY", callback, &body)
+	if err != nil {
+		s.logger.Debug("Invalid DLNA event callback", "callback", callback, "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("NT", "upnp:event")
+	req.Header.Set("NTS", "upnp:propchange")
+	req.Header.Set("SID", sid)
+	req.Header.Set("SEQ", strconv.FormatUint(uint64(seq), 10))
+
+	resp, err := s.events.Do(req)
+	if err != nil {
+		s.logger.Debug("Failed to send DLNA event", "callback", callback, "error", err)
+		return
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,328 @@
+package dlna
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/ipv4"
+)
+
+// SSDP discovery constants.
+const (
+	ssdpAddress = "239.255.255.250:1900"
+	// ssdpMaxAge is how long an announcement is valid for
+	ssdpMaxAge = 1800
+	// announceInterval re-announces well within ssdpMaxAge, since
+	// multicast is lossy
+	announceInterval = 10 * time.Minute
+	// maxSearchDelay caps the random delay before answering a search
+	maxSearchDelay = 3 * time.Second
+)
+
+// ssdpServerHeader identifies the server in SSDP messages.
+var ssdpServerHeader = fmt.Sprintf("%s/1.0 UPnP/1.0 go-jf-watch/1.0", runtime.GOOS)
+
+// ssdpServer announces the device on the LAN and answers searches for it.
+type ssdpServer struct {
+	uuid   string
+	host   string // address to advertise when listening on one, else each interface's own
+	port   int
+	logger *slog.Logger
+
+	group *net.UDPAddr
+	recv  *net.UDPConn
+	send  *ipv4.PacketConn
+}
+
+func newSSDPServer(uuid, host string, port int, logger *slog.Logger) *ssdpServer {
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		host = ""
+	}
+	return &ssdpServer{uuid: uuid, host: host, port: port, logger: logger}
+}
+
+// start joins the SSDP multicast group on every interface, announces the
+// device and answers searches until ctx is cancelled.
+func (s *ssdpServer) start(ctx context.Context, wg *sync.WaitGroup) error {
+	group, err := net.ResolveUDPAddr("udp4", ssdpAddress)
+	if err != nil {
+		return err
+	}
+	s.group = group
+
+	recv, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return fmt.Errorf("failed to join SSDP group: %w", err)
+	}
+	joined := ipv4.NewPacketConn(recv)
+	for _, iface := range multicastInterfaces() {
+		// The default interface was joined already
+		joined.JoinGroup(&iface, group)
+	}
+
+	sendConn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		recv.Close()
+		return fmt.Errorf("failed to open SSDP socket: %w", err)
+	}
+	s.recv = recv
+	s.send = ipv4.NewPacketConn(sendConn)
+	s.send.SetMulticastTTL(2)
+
+	s.announce(true)
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		s.serve(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(announceInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.announce(true)
+			}
+		}
+	}()
+	return nil
+}
+
+// stop says goodbye and closes the sockets.
+func (s *ssdpServer) stop() {
+	s.announce(false)
+	s.recv.Close()
+	s.send.Close()
+}
+
+// serve answers M-SEARCH requests until the socket is closed.
+func (s *ssdpServer) serve(ctx context.Context) {
+	buf := make([]byte, 2048)
+	for {
+		n, src, err := s.recv.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil || strings.Contains(err.Error(), "use of closed") {
+				return
+			}
+			s.logger.Debug("Failed to read SSDP message", "error", err)
+			continue
+		}
+
+		st, mx, ok := parseSearch(buf[:n])
+		if !ok {
+			continue
+		}
+		targets := s.searchTargets(st)
+		if len(targets) == 0 {
+			continue
+		}
+		go s.answerSearch(ctx, src, targets, mx)
+	}
+}
+
+// answerSearch replies to a search after the random delay it allows.
+func (s *ssdpServer) answerSearch(ctx context.Context, src *net.UDPAddr, targets []string, mx int) {
+	delay := min(time.Duration(mx)*time.Second, maxSearchDelay)
+	if delay > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(rand.N(delay)):
+		}
+	}
+
+	ip := s.host
+	if ip == "" {
+		ip = localAddressFor(src)
+	}
+	if ip == "" {
+		return
+	}
+	for _, target := range targets {
+		if _, err := s.send.WriteTo(s.searchResponse(target, s.location(ip)), nil, src); err != nil {
+			s.logger.Debug("Failed to answer SSDP search", "to", src.String(), "error", err)
+			return
+		}
+	}
+}
+
+// announce sends ssdp:alive, or ssdp:byebye, for every notification type
+// on every interface.
+func (s *ssdpServer) announce(alive bool) {
+	for _, iface := range multicastInterfaces() {
+		ip := s.host
+		if ip == "" {
+			ip = interfaceIPv4(iface).String()
+		}
+		if err := s.send.SetMulticastInterface(&iface); err != nil {
+			continue
+		}
+		for _, nt := range s.notificationTypes() {
+			msg := s.byebyeMessage(nt)
+			if alive {
+				msg = s.aliveMessage(nt, s.location(ip))
+			}
+			if _, err := s.send.WriteTo(msg, nil, s.group); err != nil {
+				s.logger.Debug("Failed to send SSDP announcement", "interface", iface.Name, "error", err)
+				break
+			}
+		}
+	}
+}
+
+// notificationTypes lists what the device announces itself as.
+func (s *ssdpServer) notificationTypes() []string {
+	return []string{
+		"upnp:rootdevice",
+		"uuid:" + s.uuid,
+		deviceType,
+		contentDirectoryType,
+		connectionManagerType,
+	}
+}
+
+// usn returns the unique service name of a notification type.
+func (s *ssdpServer) usn(nt string) string {
+	if nt == "uuid:"+s.uuid {
+		return nt
+	}
+	return "uuid:" + s.uuid + "::" + nt
+}
+
+// searchTargets returns the notification types that answer a search.
+func (s *ssdpServer) searchTargets(st string) []string {
+	if st == "ssdp:all" {
+		return s.notificationTypes()
+	}
+	for _, nt := range s.notificationTypes() {
+		if nt == st {
+			return []string{nt}
+		}
+	}
+	return nil
+}
+
+// location returns the device description URL on an address.
+func (s *ssdpServer) location(ip string) string {
+	return "http://" + net.JoinHostPort(ip, strconv.Itoa(s.port)) + "/description.xml"
+}
+
+func (s *ssdpServer) aliveMessage(nt, location string) []byte {
+	return ssdpMessage("NOTIFY * HTTP/1.1",
+		"HOST", ssdpAddress,
+		"CACHE-CONTROL", fmt.Sprintf("max-age=%d", ssdpMaxAge),
+		"LOCATION", location,
+		"NT", nt,
+		"NTS", "ssdp:alive",
+		"SERVER", ssdpServerHeader,
+		"USN", s.usn(nt))
+}
+
+func (s *ssdpServer) byebyeMessage(nt string) []byte {
+	return ssdpMessage("NOTIFY * HTTP/1.1",
+		"HOST", ssdpAddress,
+		"NT", nt,
+		"NTS", "ssdp:byebye",
+		"USN", s.usn(nt))
+}
+
+func (s *ssdpServer) searchResponse(st, location string) []byte {
+	return ssdpMessage("HTTP/1.1 200 OK",
+		"CACHE-CONTROL", fmt.Sprintf("max-age=%d", ssdpMaxAge),
+		"DATE", time.Now().UTC().Format(http.TimeFormat),
+		"EXT", "",
+		"LOCATION", location,
+		"SERVER", ssdpServerHeader,
+		"ST", st,
+		"USN", s.usn(st))
+}
+
+// ssdpMessage formats an SSDP message from a start line and header pairs.
+func ssdpMessage(startLine string, headers ...string) []byte {
+	var b bytes.Buffer
+	b.WriteString(startLine + "\r\n")
+	for i := 0; i+1 < len(headers); i += 2 {
+		b.WriteString(headers[i] + ": " + headers[i+1] + "\r\n")
+	}
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+// parseSearch parses an M-SEARCH request and returns its search target and
+// the most seconds it allows the answer to be delayed.
+func parseSearch(data []byte) (string, int, bool) {
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil || req.Method != "M-SEARCH" {
+		return "", 0, false
+	}
+	if strings.Trim(req.Header.Get("MAN"), `"`) != "ssdp:discover" {
+		return "", 0, false
+	}
+	st := req.Header.Get("ST")
+	if st == "" {
+		return "", 0, false
+	}
+	mx, _ := strconv.Atoi(req.Header.Get("MX"))
+	return st, max(mx, 0), true
+}
+
+// multicastInterfaces returns the interfaces the device is announced on:
+// those up, multicast capable, not loopback and with an IPv4 address.
+func multicastInterfaces() []net.Interface {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var usable []net.Interface
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		if interfaceIPv4(iface) != nil {
+			usable = append(usable, iface)
+		}
+	}
+	return usable
+}
+
+// interfaceIPv4 returns the first IPv4 address of an interface.
+func interfaceIPv4(iface net.Interface) net.IP {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			if ip := ipNet.IP.To4(); ip != nil {
+				return ip
+			}
+		}
+	}
+	return nil
+}
+
+// localAddressFor returns the local address packets to addr leave from,
+// which is where a searcher on that network can reach the device.
+func localAddressFor(addr *net.UDPAddr) string {
+	conn, err := net.DialUDP("udp4", nil, addr)
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String()
+}
@@ -0,0 +1,65 @@
+package dlna
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseSearch(t *testing.T) {
+	tests := []struct {
+		name   string
+		msg    string
+		wantST string
+		wantMX int
+		ok     bool
+	}{
+		{"search", "M-SEARCH * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nMAN: \"ssdp:discover\"\r\nMX: 2\r\nST: ssdp:all\r\n\r\n", "ssdp:all", 2, true},
+		{"not a discovery", "M-SEARCH * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nST: ssdp:all\r\n\r\n", "", 0, false},
+		{"announcement", "NOTIFY * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nNT: upnp:rootdevice\r\nNTS: ssdp:alive\r\n\r\n", "", 0, false},
+		{"garbage", "hello", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, mx, ok := parseSearch([]byte(tt.msg))
+			if st != tt.wantST || mx != tt.wantMX || ok != tt.ok {
+				t.Errorf("Expected (%q, %d, %v), got (%q, %d, %v)", tt.wantST, tt.wantMX, tt.ok, st, mx, ok)
+			}
+		})
+	}
+}
+
+func TestSSDPMessages(t *testing.T) {
+	s := newSSDPServer("1234", "0.0.0.0", 8200, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if got := s.searchTargets("ssdp:all"); len(got) != 5 {
+		t.Errorf("Expected every notification type for ssdp:all, got %v", got)
+	}
+	if got := s.searchTargets(contentDirectoryType); len(got) != 1 {
+		t.Errorf("Expected the content directory to answer its own type, got %v", got)
+	}
+	if got := s.searchTargets("urn:schemas-upnp-org:device:MediaRenderer:1"); got != nil {
+		t.Errorf("Expected no answer for renderers, got %v", got)
+	}
+
+	resp := string(s.searchResponse(deviceType, s.location("192.168.1.10")))
+	for _, want := range []string{
+		"HTTP/1.1 200 OK\r\n",
+		"LOCATION: http://192.168.1.10:8200/description.xml\r\n",
+		"ST: " + deviceType + "\r\n",
+		"USN: uuid:1234::" + deviceType + "\r\n",
+	} {
+		if !strings.Contains(resp, want) {
+			t.Errorf("Expected search response to contain %q, got %q", want, resp)
+		}
+	}
+
+	alive := string(s.aliveMessage("uuid:1234", s.location("192.168.1.10")))
+	if !strings.Contains(alive, "NTS: ssdp:alive\r\n") || !strings.Contains(alive, "USN: uuid:1234\r\n") {
+		t.Errorf("Unexpected alive message %q", alive)
+	}
+	if byebye := string(s.byebyeMessage("upnp:rootdevice")); !strings.Contains(byebye, "NTS: ssdp:byebye\r\n") {
+		t.Errorf("Unexpected byebye message %q", byebye)
+	}
+}
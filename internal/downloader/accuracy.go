@@ -0,0 +1,139 @@
+package downloader
+
+import (
+	"sort"
+	"time"
+)
+
+// minAccuracySamples is how many predicted downloads of a priority must be
+// settled as hit or wasted before its hit rate adjusts confidence.
+const minAccuracySamples = 5
+
+// accuracyTargetRate is the hit rate at or above which predictions keep
+// their full confidence. Below it confidence is scaled down linearly, to
+// half at a hit rate of zero.
+const accuracyTargetRate = 0.5
+
+// PriorityAccuracy reports how predicted downloads of one priority turned
+// out: watched within prediction.accuracy_days of finishing (hits), not
+// watched in time (wasted), or still inside the window (pending).
+type PriorityAccuracy struct {
+	Priority  int     `json:"priority"`
+	Hits      int     `json:"hits"`
+	Wasted    int     `json:"wasted"`
+	Pending   int     `json:"pending"`
+	HitRate   float64 `json:"hit_rate"`   // Hits over settled downloads; 0 when none are settled
+	WasteRate float64 `json:"waste_rate"` // Wasted over settled downloads
+	// Weight is the factor applied to the confidence of new predictions
+	// at this priority
+	Weight float64 `json:"weight"`
+}
+
+// Accuracy returns the prediction accuracy per priority computed by the
+// last prediction cycle, most urgent priority first.
+func (p *Predictor) Accuracy() []PriorityAccuracy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result := make([]PriorityAccuracy, 0, len(p.accuracy))
+	for _, acc := range p.accuracy {
+		result = append(result, acc)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Priority < result[j].Priority
+	})
+	return result
+}
+
+// updateAccuracy joins the download records of predicted items against the
+// viewing history of users and recomputes per-priority hit and waste
+// rates. Only downloads finished within the history window are judged, so
+// viewings that have aged out of the history are not mistaken for waste.
+// Callers must hold p.mu.
+func (p *Predictor) updateAccuracy(users []string, now time.Time) error {
+	records, err := p.storage.ListDownloadRecords("")
+	if err != nil {
+		return err
+	}
+
+	// When any of the users started watching each item, keyed by media ID
+	watched := make(map[string][]time.Time)
+	for _, userID := range users {
+		sessions, err := p.storage.GetViewingHistory(userID, p.config.HistoryDays)
+		if err != nil {
+			return err
+		}
+		for _, session := range sessions {
+			watched[session.MediaID] = append(watched[session.MediaID], session.StartTime)
+		}
+	}
+
+	window := time.Duration(p.config.AccuracyDays) * 24 * time.Hour
+	horizon := now.AddDate(0, 0, -p.config.HistoryDays)
+
+	accuracy := make(map[int]PriorityAccuracy)
+	for _, record := range records {
+		if record.Source != SourcePrediction || record.DownloadedAt.Before(horizon) {
+			continue
+		}
+
+		acc := accuracy[record.Priority]
+		acc.Priority = record.Priority
+
+		deadline := record.DownloadedAt.Add(window)
+		switch {
+		case watchedBetween(watched[record.JellyfinID], record.DownloadedAt, deadline):
+			acc.Hits++
+		case now.After(deadline):
+			acc.Wasted++
+		default:
+			acc.Pending++
+		}
+		accuracy[record.Priority] = acc
+	}
+
+	for priority, acc := range accuracy {
+		if settled := acc.Hits + acc.Wasted; settled > 0 {
+			acc.HitRate = float64(acc.Hits) / float64(settled)
+			acc.WasteRate = float64(acc.Wasted) / float64(settled)
+		}
+		acc.Weight = accuracyWeight(acc)
+		accuracy[priority] = acc
+	}
+
+	p.accuracy = accuracy
+	return nil
+}
+
+// watchedBetween reports whether any of the viewing start times falls in
+// [from, to].
+func watchedBetween(starts []time.Time, from, to time.Time) bool {
+	for _, start := range starts {
+		if !start.Before(from) && !start.After(to) {
+			return true
+		}
+	}
+	return false
+}
+
+// accuracyWeight returns the confidence factor for a priority's track
+// record: 1 until enough downloads are settled or while the hit rate meets
+// accuracyTargetRate, falling to 0.5 as the hit rate drops to zero.
+func accuracyWeight(acc PriorityAccuracy) float64 {
+	if acc.Hits+acc.Wasted < minAccuracySamples || acc.HitRate >= accuracyTargetRate {
+		return 1.0
+	}
+	return 0.5 + 0.5*acc.HitRate/accuracyTargetRate
+}
+
+// applyAccuracyWeights scales the confidence of each prediction by the hit
+// rate of earlier predictions at its priority, so that priorities whose
+// downloads go unwatched fall below min_confidence more often.
+func (p *Predictor) applyAccuracyWeights(predictions []PredictionResult) []PredictionResult {
+	for i := range predictions {
+		if acc, ok := p.accuracy[predictions[i].Priority]; ok {
+			predictions[i].Confidence *= acc.Weight
+		}
+	}
+	return predictions
+}
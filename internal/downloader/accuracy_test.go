@@ -0,0 +1,93 @@
+package downloader
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestPredictionAccuracy(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	cfg := &config.PredictionConfig{HistoryDays: 30, AccuracyDays: 7, MinConfidence: 0.7}
+	predictor := NewPredictor(store, cfg, logger)
+
+	now := time.Now()
+	day := 24 * time.Hour
+	download := func(id string, priority int, source string, at time.Time) {
+		require.NoError(t, store.AddDownloadRecord(&storage.DownloadRecord{
+			ID: id, JellyfinID: id, MediaType: "episode", Status: "completed",
+			Priority: priority, Source: source, DownloadedAt: at,
+		}))
+	}
+	watch := func(userID, id string, at time.Time) {
+		require.NoError(t, store.StoreViewingSession(userID, storage.ViewingSession{MediaID: id, StartTime: at}))
+	}
+
+	// Priority 1: six settled predictions, five watched in time
+	for i := 0; i < 6; i++ {
+		id := fmt.Sprintf("p1-%d", i)
+		download(id, 1, SourcePrediction, now.Add(-10*day))
+		if i < 5 {
+			watch("alice", id, now.Add(-9*day))
+		}
+	}
+
+	// Priority 3: six settled predictions, one watched in time and one too
+	// late; another still inside the window, and older ones out of history
+	for i := 0; i < 6; i++ {
+		download(fmt.Sprintf("p3-%d", i), 3, SourcePrediction, now.Add(-20*day))
+	}
+	watch("bob", "p3-0", now.Add(-19*day))
+	watch("bob", "p3-1", now.Add(-5*day))
+	download("p3-new", 3, SourcePrediction, now.Add(-day))
+	download("p3-ancient", 3, SourcePrediction, now.Add(-60*day))
+
+	// Downloads that were not predicted are not judged
+	download("manual", 3, SourceManual, now.Add(-20*day))
+
+	require.NoError(t, predictor.updateAccuracy([]string{"alice", "bob"}, now))
+
+	accuracy := predictor.Accuracy()
+	require.Len(t, accuracy, 2)
+
+	p1 := accuracy[0]
+	assert.Equal(t, 1, p1.Priority)
+	assert.Equal(t, 5, p1.Hits)
+	assert.Equal(t, 1, p1.Wasted)
+	assert.InDelta(t, 5.0/6, p1.HitRate, 1e-9)
+	assert.Equal(t, 1.0, p1.Weight, "a good track record keeps full confidence")
+
+	p3 := accuracy[1]
+	assert.Equal(t, 3, p3.Priority)
+	assert.Equal(t, 1, p3.Hits)
+	assert.Equal(t, 5, p3.Wasted)
+	assert.Equal(t, 1, p3.Pending)
+	assert.InDelta(t, 5.0/6, p3.WasteRate, 1e-9)
+	assert.InDelta(t, 0.5+0.5*(1.0/6)/accuracyTargetRate, p3.Weight, 1e-9)
+
+	// A wasteful priority's predictions drop below the confidence threshold
+	predictions := predictor.applyAccuracyWeights([]PredictionResult{
+		{MediaID: "a", Priority: 1, Confidence: 0.8},
+		{MediaID: "b", Priority: 3, Confidence: 0.8},
+		{MediaID: "c", Priority: 4, Confidence: 0.8},
+	})
+	filtered := predictor.filterPredictions(predictions)
+	require.Len(t, filtered, 2)
+	assert.Equal(t, "a", filtered[0].MediaID)
+	assert.Equal(t, "c", filtered[1].MediaID, "priorities without a track record are left alone")
+}
+
+func TestAccuracyWeightNeedsSamples(t *testing.T) {
+	assert.Equal(t, 1.0, accuracyWeight(PriorityAccuracy{Wasted: minAccuracySamples - 1}))
+	assert.Equal(t, 0.5, accuracyWeight(PriorityAccuracy{Wasted: minAccuracySamples}))
+}
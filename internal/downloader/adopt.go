@@ -0,0 +1,602 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/media"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// Ways an adopted file is brought into the cache.
+const (
+	AdoptLink = "link" // Hardlink, leaving the original in place; the default
+	AdoptMove = "move" // Move, falling back to copy and delete across filesystems
+	AdoptCopy = "copy" // Copy, leaving the original in place
+)
+
+// adoptPriority is the priority adopted items are recorded with, the same
+// as manual download requests.
+const adoptPriority = 3
+
+// adoptCandidates bounds how many search results are considered per file.
+const adoptCandidates = 10
+
+var (
+	// adoptEpisodePattern finds SxxEyy or NxNN episode numbering and what
+	// comes before it.
+	adoptEpisodePattern = regexp.MustCompile(`(?i)^(.*?)[\s._-]*(?:s(\d{1,2})[\s._-]*e(\d{1,3})|\b(\d{1,2})x(\d{2,3})\b)`)
+	// adoptSeasonDir matches season folders, which never name the series.
+	adoptSeasonDir = regexp.MustCompile(`(?i)^(season|series|staffel)[\s._-]*\d+$|^specials$`)
+	// adoptTagPattern matches release tags that end the title of a file.
+	adoptTagPattern = regexp.MustCompile(`(?i)^(\d{3,4}p|4k|uhd|hdr\d*|bluray|blu-ray|bdrip|brrip|dvdrip|webrip|web-?dl|web|hdtv|remux|x26[45]|h\.?26[45]|hevc|avc|xvid|aac\d*|ac3|dts|proper|repack|extended|unrated)$`)
+)
+
+// AdoptSource confirms matches against the Jellyfin library (implemented
+// by jellyfin.Client).
+type AdoptSource interface {
+	GetItemsByID(ctx context.Context, ids []string) ([]jellyfin.MediaItem, error)
+}
+
+// AdoptStore is the storage adopted files are matched against and recorded
+// in.
+type AdoptStore interface {
+	SearchMetadata(query, mediaType string, limit int) ([]*storage.MediaMetadata, error)
+	FindMediaMetadata(mediaID string) (*storage.MediaMetadata, error)
+	GetSeriesMetadata(seriesID string) ([]*storage.MediaMetadata, error)
+	AddMediaMetadata(metadata *storage.MediaMetadata) error
+	GetDownload(mediaID string) (*storage.DownloadRecord, error)
+	ListDownloadRecords(mediaType string) ([]*storage.DownloadRecord, error)
+	AddDownloadRecord(record *storage.DownloadRecord) error
+	ChecksumAlgorithm() string
+}
+
+// AdoptOptions controls an adoption run.
+type AdoptOptions struct {
+	Mode   string // One of the Adopt constants; empty for AdoptLink
+	DryRun bool   // Match and report without touching files or the database
+}
+
+// AdoptReport describes what an adoption run did, or would do on a dry run.
+type AdoptReport struct {
+	Directory string        `json:"directory"`
+	Mode      string        `json:"mode"`
+	DryRun    bool          `json:"dry_run"`
+	Scanned   int           `json:"scanned"`
+	Adopted   []AdoptedFile `json:"adopted"`
+	Skipped   []SkippedFile `json:"skipped"`
+}
+
+// AdoptedFile is a file that was matched to a library item and cached.
+type AdoptedFile struct {
+	Path      string `json:"path"`
+	MediaID   string `json:"media_id"`
+	Name      string `json:"name"`
+	LocalPath string `json:"local_path"`
+	// Match is what confirmed the item: "hash" for the checksum of an
+	// evicted copy, "size" for the size of the server's file and "name"
+	// for its file name
+	Match string `json:"match"`
+}
+
+// SkippedFile is a file that was left alone, and why.
+type SkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// Adopter imports media that was downloaded by hand into the cache, so
+// users moving to go-jf-watch keep what they already have instead of
+// fetching it again. Files are matched to library items by their name,
+// then confirmed by the size or file name of the item on the server; a
+// file whose checksum matches an evicted item is that item. Matched files
+// are linked, moved or copied into the cache layout and recorded as if
+// they had been downloaded.
+type Adopter struct {
+	source AdoptSource
+	store  AdoptStore
+	cache  *storage.CacheManager
+	files  *storage.FileManager
+	config *config.CacheConfig
+	logger *slog.Logger
+
+	// mu allows one adoption at a time, so two runs cannot claim the same
+	// item
+	mu sync.Mutex
+}
+
+// NewAdopter creates an adopter that places files where cache keeps media.
+func NewAdopter(source AdoptSource, store AdoptStore, cache *storage.CacheManager, cfg *config.CacheConfig, logger *slog.Logger) *Adopter {
+	return &Adopter{
+		source: source,
+		store:  store,
+		cache:  cache,
+		files:  storage.NewFileManager(cfg.TempDirectory, logger),
+		config: cfg,
+		logger: logger,
+	}
+}
+
+// ValidAdoptMode reports whether mode is one of the Adopt constants or
+// empty.
+func ValidAdoptMode(mode string) bool {
+	switch mode {
+	case "", AdoptLink, AdoptMove, AdoptCopy:
+		return true
+	}
+	return false
+}
+
+// Adopt scans dir for video files and adopts those it can match. Files
+// that cannot be matched or placed are reported as skipped; an error is
+// only returned when the scan itself fails or Jellyfin cannot be asked to
+// confirm matches.
+func (a *Adopter) Adopt(ctx context.Context, dir string, opts AdoptOptions) (*AdoptReport, error) {
+	if !ValidAdoptMode(opts.Mode) {
+		return nil, fmt.Errorf("mode must be %q, %q or %q", AdoptLink, AdoptMove, AdoptCopy)
+	}
+	if opts.Mode == "" {
+		opts.Mode = AdoptLink
+	}
+
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	if info, err := os.Stat(root); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+	if cacheDir, err := filepath.Abs(a.config.Directory); err == nil && withinDir(root, cacheDir) {
+		return nil, fmt.Errorf("%s is inside the cache directory", root)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	paths, err := adoptableFiles(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	byChecksum, err := a.checksumIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &AdoptReport{
+		Directory: root,
+		Mode:      opts.Mode,
+		DryRun:    opts.DryRun,
+		Scanned:   len(paths),
+		Adopted:   []AdoptedFile{},
+		Skipped:   []SkippedFile{},
+	}
+	// claimed keeps two files from being adopted as the same item, which
+	// a dry run would otherwise not notice
+	claimed := make(map[string]bool)
+
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		adopted, reason, err := a.adoptFile(ctx, root, path, opts, byChecksum, claimed)
+		if err != nil {
+			return report, err
+		}
+		if adopted == nil {
+			report.Skipped = append(report.Skipped, SkippedFile{Path: path, Reason: reason})
+			continue
+		}
+		claimed[adopted.MediaID] = true
+		report.Adopted = append(report.Adopted, *adopted)
+	}
+
+	if !opts.DryRun {
+		a.logger.Info("Adopted existing media",
+			"directory", root,
+			"mode", opts.Mode,
+			"adopted", len(report.Adopted),
+			"skipped", len(report.Skipped))
+	}
+	return report, nil
+}
+
+// adoptFile matches and adopts one file. It returns the adopted file, or
+// why the file was skipped.
+func (a *Adopter) adoptFile(ctx context.Context, root, path string, opts AdoptOptions, byChecksum map[string]*storage.DownloadRecord, claimed map[string]bool) (*AdoptedFile, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err.Error(), nil
+	}
+
+	algorithm := a.store.ChecksumAlgorithm()
+	var checksum string
+	if algorithm != storage.ChecksumOff {
+		if checksum, err = a.files.CalculateChecksumWith(path, algorithm); err != nil {
+			return nil, fmt.Sprintf("failed to checksum: %v", err), nil
+		}
+	}
+
+	var metadata *storage.MediaMetadata
+	var match string
+	if record := byChecksum[checksumKey(algorithm, checksum)]; checksum != "" && record != nil {
+		if record.Status != "evicted" {
+			return nil, fmt.Sprintf("same content as cached item %s", record.JellyfinID), nil
+		}
+		if metadata, err = a.store.FindMediaMetadata(record.JellyfinID); err != nil {
+			return nil, "", err
+		}
+		if metadata == nil {
+			return nil, fmt.Sprintf("no metadata for evicted item %s", record.JellyfinID), nil
+		}
+		match = "hash"
+	} else {
+		var reason string
+		if metadata, match, reason, err = a.match(ctx, root, path, info.Size()); metadata == nil {
+			return nil, reason, err
+		}
+	}
+
+	if claimed[metadata.JellyfinID] {
+		return nil, fmt.Sprintf("%s was matched by another file", metadata.JellyfinID), nil
+	}
+	existing, err := a.store.GetDownload(metadata.JellyfinID)
+	if err == nil && existing.Status != "evicted" {
+		return nil, fmt.Sprintf("%s is already cached", metadata.JellyfinID), nil
+	}
+
+	localPath := a.localPath(metadata, filepath.Base(path))
+	if _, err := os.Stat(localPath); err == nil {
+		return nil, fmt.Sprintf("%s already exists", localPath), nil
+	}
+
+	adopted := &AdoptedFile{
+		Path:      path,
+		MediaID:   metadata.JellyfinID,
+		Name:      metadata.Name,
+		LocalPath: localPath,
+		Match:     match,
+	}
+	if opts.DryRun {
+		return adopted, "", nil
+	}
+
+	if err := a.place(opts.Mode, path, localPath); err != nil {
+		return nil, err.Error(), nil
+	}
+	if err := a.record(metadata, existing, localPath, filepath.Base(path), info.Size(), algorithm, checksum); err != nil {
+		return nil, "", err
+	}
+	return adopted, "", nil
+}
+
+// match finds the library item a file holds from its name and confirms it
+// with the item's file on the server. It returns the item and what
+// confirmed it, or why there is no match.
+func (a *Adopter) match(ctx context.Context, root, path string, size int64) (*storage.MediaMetadata, string, string, error) {
+	name := parseAdoptName(root, path)
+	if name.title == "" {
+		return nil, "", "no title in file name", nil
+	}
+
+	var candidates []*storage.MediaMetadata
+	if name.episode > 0 {
+		series, err := a.store.SearchMetadata(name.title, "series", adoptCandidates)
+		if err != nil {
+			return nil, "", "", err
+		}
+		for _, show := range series {
+			if !sameTitle(show.Name, name.title) {
+				continue
+			}
+			episodes, err := a.store.GetSeriesMetadata(show.JellyfinID)
+			if err != nil {
+				return nil, "", "", err
+			}
+			for _, episode := range episodes {
+				if episode.SeasonNumber == name.season && episode.EpisodeNumber == name.episode {
+					candidates = append(candidates, episode)
+				}
+			}
+		}
+	} else {
+		movies, err := a.store.SearchMetadata(name.title, "movie", adoptCandidates)
+		if err != nil {
+			return nil, "", "", err
+		}
+		for _, movie := range movies {
+			if sameTitle(movie.Name, name.title) {
+				candidates = append(candidates, movie)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, "", fmt.Sprintf("no library item matches %q", name), nil
+	}
+
+	ids := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		ids = append(ids, candidate.JellyfinID)
+	}
+	items, err := a.source.GetItemsByID(ctx, ids)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to confirm matches with Jellyfin: %w", err)
+	}
+	byID := make(map[string]jellyfin.MediaItem, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+
+	// A file of the same size is the same release; failing that, the
+	// server holding a file of the same name is good enough
+	for _, candidate := range candidates {
+		if item, ok := byID[candidate.JellyfinID]; ok && item.Size > 0 && item.Size == size {
+			return candidate, "size", "", nil
+		}
+	}
+	for _, candidate := range candidates {
+		item, ok := byID[candidate.JellyfinID]
+		if ok && item.Path != "" && strings.EqualFold(serverFileName(item.Path), filepath.Base(path)) {
+			return candidate, "name", "", nil
+		}
+	}
+	return nil, "", fmt.Sprintf("%q matches %s by name only; size and file name differ from the server's", name, candidates[0].JellyfinID), nil
+}
+
+// localPath returns where an adopted file of an item goes in the cache.
+func (a *Adopter) localPath(metadata *storage.MediaMetadata, filename string) string {
+	if metadata.Type == "episode" && metadata.SeriesID != "" {
+		return a.cache.GetMediaPath("episode", metadata.SeriesID, metadata.SeasonNumber, metadata.EpisodeNumber, filename)
+	}
+	return a.cache.GetMediaPath(metadata.Type, metadata.JellyfinID, metadata.SeasonNumber, metadata.EpisodeNumber, filename)
+}
+
+// place brings src into the cache at dst as mode asks.
+func (a *Adopter) place(mode, src, dst string) error {
+	switch mode {
+	case AdoptMove:
+		return a.files.MoveFileAtomic(src, dst)
+	case AdoptCopy:
+		_, err := a.files.CopyFileAtomic(src, dst)
+		return err
+	default:
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.Link(src, dst); err != nil {
+			return fmt.Errorf("failed to hardlink, the file may be on another filesystem than the cache: %w", err)
+		}
+		return nil
+	}
+}
+
+// record stores a download record and updated metadata for an adopted
+// file, replacing the record of an evicted copy, and writes its sidecar.
+func (a *Adopter) record(metadata *storage.MediaMetadata, existing *storage.DownloadRecord, localPath, originalName string, size int64, algorithm, checksum string) error {
+	now := time.Now()
+	container := strings.TrimPrefix(strings.ToLower(filepath.Ext(localPath)), ".")
+
+	record := &storage.DownloadRecord{
+		ID:           fmt.Sprintf("%s-%d", metadata.JellyfinID, now.Unix()),
+		MediaType:    metadata.Type,
+		JellyfinID:   metadata.JellyfinID,
+		Title:        metadata.Name,
+		LocalPath:    localPath,
+		Size:         size,
+		Status:       "completed",
+		DownloadedAt: now,
+		LastAccessed: now,
+		Priority:     adoptPriority,
+		Source:       SourceAdopted,
+		Container:    container,
+		Checksum:     checksum,
+	}
+	if checksum != "" {
+		record.ChecksumAlgorithm = algorithm
+	}
+	// Records are keyed by media type, so keep the evicted record's type
+	// to replace it rather than add a second one
+	if existing != nil && existing.MediaType != "" {
+		record.MediaType = existing.MediaType
+	}
+	if err := a.store.AddDownloadRecord(record); err != nil {
+		return fmt.Errorf("failed to record adopted file: %w", err)
+	}
+
+	metadata.Size = size
+	metadata.Container = container
+	if err := a.store.AddMediaMetadata(metadata); err != nil {
+		return fmt.Errorf("failed to update metadata: %w", err)
+	}
+
+	if err := a.files.WriteMetadata(localPath, &storage.FileMetadata{
+		JellyfinID:   metadata.JellyfinID,
+		OriginalName: originalName,
+		Size:         size,
+		Checksum:     checksum,
+		DownloadedAt: now,
+		Name:         metadata.Name,
+		Overview:     metadata.Overview,
+		Genres:       metadata.Genres,
+		SyncedAt:     metadata.LastSynced,
+	}); err != nil {
+		a.logger.Warn("Failed to write metadata sidecar", "path", localPath, "error", err)
+	}
+	return nil
+}
+
+// checksumIndex maps the checksums of all download records, evicted ones
+// included, to their records.
+func (a *Adopter) checksumIndex() (map[string]*storage.DownloadRecord, error) {
+	records, err := a.store.ListDownloadRecords("")
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]*storage.DownloadRecord, len(records))
+	for _, record := range records {
+		if record.Checksum == "" {
+			continue
+		}
+		algorithm := record.ChecksumAlgorithm
+		if algorithm == "" {
+			algorithm = storage.ChecksumSHA256
+		}
+		key := checksumKey(algorithm, record.Checksum)
+		// A cached copy outranks an evicted one
+		if current := index[key]; current == nil || current.Status == "evicted" {
+			index[key] = record
+		}
+	}
+	return index, nil
+}
+
+func checksumKey(algorithm, checksum string) string {
+	return algorithm + ":" + checksum
+}
+
+// adoptableFiles returns the video files under root, leaving out sidecars.
+func adoptableFiles(ctx context.Context, root string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() && path != root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if path != root && storage.IsSidecar(d.Name()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && media.IsVideoFile(d.Name()) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+	return paths, nil
+}
+
+// adoptName is what a file name says about the item it holds.
+type adoptName struct {
+	title   string
+	season  int
+	episode int // Zero for movies
+}
+
+func (n adoptName) String() string {
+	if n.episode > 0 {
+		return fmt.Sprintf("%s S%02dE%02d", n.title, n.season, n.episode)
+	}
+	return n.title
+}
+
+// parseAdoptName reads the title, and for episodes the season and episode
+// numbers, from the name of a file under root. Episodes named only by
+// their numbers take the series title from the nearest folder that is not
+// a season folder.
+func parseAdoptName(root, path string) adoptName {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	m := adoptEpisodePattern.FindStringSubmatch(base)
+	if m == nil {
+		return adoptName{title: cleanTitle(base)}
+	}
+
+	name := adoptName{title: cleanTitle(m[1])}
+	if m[2] != "" {
+		name.season, _ = strconv.Atoi(m[2])
+		name.episode, _ = strconv.Atoi(m[3])
+	} else {
+		name.season, _ = strconv.Atoi(m[4])
+		name.episode, _ = strconv.Atoi(m[5])
+	}
+
+	for dir := filepath.Dir(path); name.title == "" && withinDir(dir, root); dir = filepath.Dir(dir) {
+		if folder := filepath.Base(dir); !adoptSeasonDir.MatchString(folder) {
+			name.title = cleanTitle(folder)
+		}
+		if dir == root {
+			break
+		}
+	}
+	return name
+}
+
+// cleanTitle turns the start of a release name into a title: separators
+// become spaces, and release tags, the year and anything after them are
+// dropped.
+func cleanTitle(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '.' || r == '_' || r == '(' || r == ')' || r == '[' || r == ']' || unicode.IsSpace(r)
+	})
+
+	var title []string
+	for _, word := range words {
+		if adoptTagPattern.MatchString(word) {
+			break
+		}
+		title = append(title, strings.Trim(word, "-"))
+	}
+	// The year ends the title, but a title can be a year-like number
+	// itself, as in Blade Runner 2049 (2017)
+	if n := len(title); n > 1 && isYear(title[n-1]) {
+		title = title[:n-1]
+	}
+	return strings.TrimSpace(strings.Join(title, " "))
+}
+
+func isYear(word string) bool {
+	year, err := strconv.Atoi(word)
+	return err == nil && len(word) == 4 && year >= 1900 && year <= 2099
+}
+
+// sameTitle reports whether two titles match ignoring case, punctuation
+// and spacing.
+func sameTitle(a, b string) bool {
+	normalize := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, s)
+	}
+	na := normalize(a)
+	return na != "" && na == normalize(b)
+}
+
+// serverFileName returns the file name of a path on the Jellyfin server,
+// which may use either separator.
+func serverFileName(p string) string {
+	return path.Base(strings.ReplaceAll(p, `\`, "/"))
+}
+
+// withinDir reports whether path is dir or inside it.
+func withinDir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
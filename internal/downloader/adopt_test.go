@@ -0,0 +1,137 @@
+package downloader
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestAdopterAdopt(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	source := jellyfintest.New("http://jellyfin.test")
+	downloads := t.TempDir()
+	cfg := &config.CacheConfig{Directory: t.TempDir(), TempDirectory: t.TempDir()}
+
+	files := map[string]string{
+		"Heat (1995) 1080p BluRay.mkv": "heat",
+		"Bluey/Season 1/S01E02.mkv":    "camping",
+		"Unknown.Film.2020.mkv":        "unknown",
+		"ronin-backup.mkv":             "ronin",
+		"notes.txt":                    "not a video",
+	}
+	for name, content := range files {
+		path := filepath.Join(downloads, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	for _, metadata := range []*storage.MediaMetadata{
+		{ID: "m1", JellyfinID: "m1", Name: "Heat", Type: "movie"},
+		{ID: "m2", JellyfinID: "m2", Name: "Ronin", Type: "movie"},
+		{ID: "s1", JellyfinID: "s1", Name: "Bluey", Type: "series"},
+		{ID: "e1", JellyfinID: "e1", Name: "Camping", Type: "episode", SeriesID: "s1", SeasonNumber: 1, EpisodeNumber: 2},
+	} {
+		require.NoError(t, store.AddMediaMetadata(metadata))
+	}
+	// Ronin was cached and evicted; its checksum identifies the backup
+	sum := sha256.Sum256([]byte("ronin"))
+	require.NoError(t, store.AddDownloadRecord(&storage.DownloadRecord{
+		ID: "m2-1", JellyfinID: "m2", MediaType: "movie", Status: "evicted",
+		Checksum: hex.EncodeToString(sum[:]),
+	}))
+	source.Items = map[string]jellyfin.MediaItem{
+		"m1": {ID: "m1", Size: int64(len("heat"))},
+		"e1": {ID: "e1", Path: `D:\TV\Bluey\Season 1\S01E02.mkv`, Size: 1 << 30},
+	}
+
+	adopter := NewAdopter(source, store, storage.NewCacheManager(cfg, nil, logger), cfg, logger)
+
+	report, err := adopter.Adopt(context.Background(), downloads, AdoptOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Scanned)
+	assert.Equal(t, AdoptLink, report.Mode)
+	require.Len(t, report.Adopted, 3)
+	require.Len(t, report.Skipped, 1)
+	assert.Equal(t, filepath.Join(downloads, "Unknown.Film.2020.mkv"), report.Skipped[0].Path)
+	_, err = store.GetDownload("m1")
+	assert.Error(t, err, "a dry run records nothing")
+
+	report, err = adopter.Adopt(context.Background(), downloads, AdoptOptions{})
+	require.NoError(t, err)
+	matches := make(map[string]AdoptedFile)
+	for _, adopted := range report.Adopted {
+		matches[adopted.MediaID] = adopted
+	}
+	require.Len(t, matches, 3)
+	assert.Equal(t, "size", matches["m1"].Match)
+	assert.Equal(t, "name", matches["e1"].Match)
+	assert.Equal(t, "hash", matches["m2"].Match)
+	assert.Equal(t, filepath.Join(cfg.Directory, "series", "s1", "S01E02", "S01E02.mkv"), matches["e1"].LocalPath)
+
+	record, err := store.GetDownload("m1")
+	require.NoError(t, err)
+	assert.Equal(t, "completed", record.Status)
+	assert.Equal(t, SourceAdopted, record.Source)
+	assert.Equal(t, filepath.Join(cfg.Directory, "movies", "m1", "Heat (1995) 1080p BluRay.mkv"), record.LocalPath)
+	assert.NotEmpty(t, record.Checksum)
+	data, err := os.ReadFile(record.LocalPath)
+	require.NoError(t, err)
+	assert.Equal(t, "heat", string(data))
+	assert.FileExists(t, filepath.Join(downloads, "Heat (1995) 1080p BluRay.mkv"), "links leave the original in place")
+	assert.FileExists(t, filepath.Join(filepath.Dir(record.LocalPath), ".meta.json"))
+
+	records, err := store.ListDownloadRecords("movie")
+	require.NoError(t, err)
+	assert.Len(t, records, 2, "the evicted Ronin record is replaced")
+
+	metadata, err := store.FindMediaMetadata("e1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("camping")), metadata.Size)
+	assert.Equal(t, "mkv", metadata.Container)
+
+	// Adopted files are cached now
+	report, err = adopter.Adopt(context.Background(), downloads, AdoptOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, report.Adopted)
+	assert.Len(t, report.Skipped, 4)
+
+	_, err = adopter.Adopt(context.Background(), downloads, AdoptOptions{Mode: "symlink"})
+	assert.Error(t, err)
+	_, err = adopter.Adopt(context.Background(), cfg.Directory, AdoptOptions{})
+	assert.Error(t, err, "the cache cannot adopt itself")
+}
+
+func TestParseAdoptName(t *testing.T) {
+	root := filepath.FromSlash("/downloads")
+	tests := []struct {
+		path string
+		want adoptName
+	}{
+		{"Heat.1995.1080p.BluRay.x264.mkv", adoptName{title: "Heat"}},
+		{"Blade Runner 2049 (2017).mkv", adoptName{title: "Blade Runner 2049"}},
+		{"1917.mkv", adoptName{title: "1917"}},
+		{"Bluey.S01E02.Camping.720p.mkv", adoptName{title: "Bluey", season: 1, episode: 2}},
+		{"The Office - 2x05 - Halloween.mkv", adoptName{title: "The Office", season: 2, episode: 5}},
+		{"Bluey/Season 2/S02E10.mkv", adoptName{title: "Bluey", season: 2, episode: 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseAdoptName(root, filepath.Join(root, filepath.FromSlash(tt.path))))
+		})
+	}
+}
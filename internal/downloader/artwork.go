@@ -0,0 +1,104 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// artworkSources maps the cached artwork types to the Jellyfin image type
+// fetched for them and the widest it is kept.
+var artworkSources = map[string]struct {
+	imageType string
+	maxWidth  int
+}{
+	"primary":  {"Primary", 600},
+	"backdrop": {"Backdrop", 1920},
+}
+
+// ArtworkSource downloads item images (implemented by jellyfin.Client).
+type ArtworkSource interface {
+	GetItemImage(ctx context.Context, itemID, imageType string, maxWidth int) ([]byte, error)
+}
+
+// ArtworkStore is the storage the artwork cache looks cached items up in.
+type ArtworkStore interface {
+	GetDownload(mediaID string) (*storage.DownloadRecord, error)
+}
+
+// ArtworkCache keeps the poster and backdrop of every cached item next to
+// its media file, so the web UI can show them while Jellyfin is
+// unreachable and never has to ask the server for them. Images are fetched
+// when an item finishes downloading and again when library sync sees its
+// metadata change.
+type ArtworkCache struct {
+	source ArtworkSource
+	store  ArtworkStore
+	files  *storage.FileManager
+	logger *slog.Logger
+}
+
+// NewArtworkCache creates an artwork cache that downloads from source.
+func NewArtworkCache(source ArtworkSource, store ArtworkStore, cfg *config.CacheConfig, logger *slog.Logger) *ArtworkCache {
+	return &ArtworkCache{
+		source: source,
+		store:  store,
+		files:  storage.NewFileManager(cfg.TempDirectory, logger),
+		logger: logger,
+	}
+}
+
+// CacheArtwork stores the images of a cached item, returning how many it
+// wrote. Images already on disk are kept unless refresh is set. Items that
+// are not cached are skipped, and types the item has no image of are
+// removed.
+func (a *ArtworkCache) CacheArtwork(ctx context.Context, mediaID string, refresh bool) (int, error) {
+	record, err := a.store.GetDownload(mediaID)
+	if err != nil || record.LocalPath == "" || record.Status == "evicted" {
+		return 0, nil
+	}
+
+	written := 0
+	for _, artworkType := range storage.ArtworkTypes {
+		path := storage.ArtworkPath(record.LocalPath, artworkType)
+		if !refresh && a.files.FileExists(path) {
+			continue
+		}
+
+		source := artworkSources[artworkType]
+		data, err := a.source.GetItemImage(ctx, mediaID, source.imageType, source.maxWidth)
+		if errors.Is(err, jellyfin.ErrNoImage) {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				a.logger.Debug("Failed to remove outdated artwork", "path", path, "error", err)
+			}
+			continue
+		}
+		if err != nil {
+			return written, err
+		}
+
+		if err := a.files.WriteFileAtomic(path, data); err != nil {
+			return written, err
+		}
+		written++
+	}
+	return written, nil
+}
+
+// LibraryChanged refreshes the artwork of cached items whose metadata
+// library sync found changed. Register it with jellyfin.LibrarySync.OnChange.
+func (a *ArtworkCache) LibraryChanged(change jellyfin.LibraryChange) {
+	for _, items := range [][]jellyfin.MediaItem{change.Added, change.Updated} {
+		for _, item := range items {
+			// Listeners run on the syncing goroutine without its context
+			if _, err := a.CacheArtwork(context.Background(), item.ID, true); err != nil {
+				a.logger.Warn("Failed to cache artwork", "media_id", item.ID, "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+var _ ArtworkSource = (*jellyfin.Client)(nil)
+var _ ArtworkSource = (*jellyfintest.Mock)(nil)
+
+func TestArtworkCache(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+
+	moviePath := filepath.Join(t.TempDir(), "movies", "m1", "movie.mkv")
+	require.NoError(t, os.MkdirAll(filepath.Dir(moviePath), 0755))
+	require.NoError(t, os.WriteFile(moviePath, []byte("video"), 0644))
+	require.NoError(t, store.AddDownloadRecord(&storage.DownloadRecord{
+		ID: "m1", JellyfinID: "m1", MediaType: "movie", LocalPath: moviePath, Status: "completed",
+	}))
+
+	source := jellyfintest.New("http://jellyfin.local")
+	source.Images = map[string][]byte{
+		"m1/Primary":  []byte("poster"),
+		"m1/Backdrop": []byte("backdrop"),
+		"m2/Primary":  []byte("uncached"),
+	}
+	artwork := NewArtworkCache(source, store, &config.CacheConfig{TempDirectory: t.TempDir()}, logger)
+
+	written, err := artwork.CacheArtwork(context.Background(), "m1", false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, written)
+	poster, err := os.ReadFile(storage.ArtworkPath(moviePath, "primary"))
+	require.NoError(t, err)
+	assert.Equal(t, "poster", string(poster))
+
+	// Cached images are kept unless refreshed
+	source.Images["m1/Primary"] = []byte("new poster")
+	written, err = artwork.CacheArtwork(context.Background(), "m1", false)
+	require.NoError(t, err)
+	assert.Zero(t, written)
+
+	// A library change refreshes them, dropping images the server lost
+	delete(source.Images, "m1/Backdrop")
+	artwork.LibraryChanged(jellyfin.LibraryChange{Updated: []jellyfin.MediaItem{{ID: "m1"}, {ID: "m2"}}})
+	poster, err = os.ReadFile(storage.ArtworkPath(moviePath, "primary"))
+	require.NoError(t, err)
+	assert.Equal(t, "new poster", string(poster))
+	assert.NoFileExists(t, storage.ArtworkPath(moviePath, "backdrop"))
+
+	// Items that are not cached have nowhere to keep artwork
+	written, err = artwork.CacheArtwork(context.Background(), "m2", true)
+	require.NoError(t, err)
+	assert.Zero(t, written)
+}
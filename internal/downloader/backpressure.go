@@ -0,0 +1,102 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// ErrQueueFull is returned when queueing an item would exceed the queue
+// limit of its priority class (see config.DownloadConfig.QueueLimits).
+var ErrQueueFull = errors.New("download queue is full")
+
+// QueueFullError reports which queue limit refused an item. It wraps
+// ErrQueueFull.
+type QueueFullError struct {
+	Priority   int   // Priority the item was queued at
+	Priorities []int // Priority class the limit covers
+	Items      int   // Items already queued in the class
+	Bytes      int64 // Known size of the items already queued in the class
+	MaxItems   int
+	MaxBytes   int64
+}
+
+func (e *QueueFullError) Error() string {
+	if e.MaxItems > 0 && e.Items >= e.MaxItems {
+		return fmt.Sprintf("%v: %d of %d items queued at priorities %v", ErrQueueFull, e.Items, e.MaxItems, e.Priorities)
+	}
+	return fmt.Sprintf("%v: %d of %d bytes queued at priorities %v", ErrQueueFull, e.Bytes, e.MaxBytes, e.Priorities)
+}
+
+func (e *QueueFullError) Unwrap() error {
+	return ErrQueueFull
+}
+
+// queueLimitFor returns the queue limit covering priority, or nil when the
+// priority is not limited.
+func (m *Manager) queueLimitFor(priority int) *config.QueueLimitConfig {
+	for i := range m.config.QueueLimits {
+		if containsPriority(m.config.QueueLimits[i].Priorities, priority) {
+			return &m.config.QueueLimits[i]
+		}
+	}
+	return nil
+}
+
+// checkQueueLimit returns a *QueueFullError when adding an item of size
+// bytes at priority would exceed its class's limit. Only items still
+// waiting count; downloads in flight are bounded by the worker count.
+// Callers must hold m.queueMu.
+func (m *Manager) checkQueueLimit(priority int, size int64) error {
+	limit := m.queueLimitFor(priority)
+	if limit == nil {
+		return nil
+	}
+
+	queued, err := m.storage.GetQueueItems("queued")
+	if err != nil {
+		return fmt.Errorf("failed to check queue limit: %w", err)
+	}
+
+	full := &QueueFullError{
+		Priority:   priority,
+		Priorities: limit.Priorities,
+		MaxItems:   limit.MaxItems,
+		MaxBytes:   int64(limit.MaxGB * 1024 * 1024 * 1024),
+	}
+	for _, item := range queued {
+		if !containsPriority(limit.Priorities, item.Priority) {
+			continue
+		}
+		full.Items++
+		full.Bytes += m.queuedSize(item)
+	}
+
+	if full.MaxItems > 0 && full.Items >= full.MaxItems {
+		return full
+	}
+	if full.MaxBytes > 0 && full.Bytes+size > full.MaxBytes {
+		return full
+	}
+	return nil
+}
+
+// queuedSize returns the size of a queued item, from the stored metadata
+// when the queue entry does not record it. Unknown sizes count as 0.
+func (m *Manager) queuedSize(item *storage.QueueItem) int64 {
+	if item.Size > 0 {
+		return item.Size
+	}
+	return m.mediaSize(item.MediaID)
+}
+
+// mediaSize returns the size of mediaID from its stored metadata, or 0.
+func (m *Manager) mediaSize(mediaID string) int64 {
+	metadata, err := m.storage.GetMediaMetadata(mediaID)
+	if err != nil || metadata == nil {
+		return 0
+	}
+	return metadata.Size
+}
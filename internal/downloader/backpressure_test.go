@@ -0,0 +1,93 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+const gib = 1024 * 1024 * 1024
+
+func newLimitedManager(t *testing.T, limits ...config.QueueLimitConfig) (*Manager, *storagetest.MemStore) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	manager := New(&config.DownloadConfig{Workers: 1, QueueLimits: limits}, store, logger)
+	manager.running = true
+	return manager, store
+}
+
+func TestQueueLimitItems(t *testing.T) {
+	manager, _ := newLimitedManager(t, config.QueueLimitConfig{Priorities: []int{3, 4}, MaxItems: 2})
+	ctx := context.Background()
+
+	_, err := manager.QueueDownload(ctx, "a", 3)
+	require.NoError(t, err)
+	_, err = manager.QueueDownload(ctx, "b", 4)
+	require.NoError(t, err)
+
+	_, err = manager.QueueDownload(ctx, "c", 4)
+	require.ErrorIs(t, err, ErrQueueFull)
+	var full *QueueFullError
+	require.True(t, errors.As(err, &full))
+	assert.Equal(t, 2, full.Items)
+	assert.Equal(t, []int{3, 4}, full.Priorities)
+
+	// Items already queued are returned as before, and other classes are
+	// not limited
+	_, err = manager.QueueDownload(ctx, "a", 3)
+	assert.NoError(t, err)
+	_, err = manager.QueueDownload(ctx, "c", 1)
+	assert.NoError(t, err)
+}
+
+func TestQueueLimitBytes(t *testing.T) {
+	manager, store := newLimitedManager(t, config.QueueLimitConfig{Priorities: []int{4}, MaxGB: 10})
+	ctx := context.Background()
+	for id, size := range map[string]int64{"big": 6 * gib, "bigger": 6 * gib, "small": gib} {
+		require.NoError(t, store.AddMediaMetadata(&storage.MediaMetadata{ID: id, JellyfinID: id, Type: "movie", Size: size}))
+	}
+
+	_, err := manager.QueueDownload(ctx, "big", 4)
+	require.NoError(t, err)
+	_, err = manager.QueueDownload(ctx, "bigger", 4)
+	assert.ErrorIs(t, err, ErrQueueFull)
+	_, err = manager.QueueDownload(ctx, "small", 4)
+	assert.NoError(t, err)
+	_, err = manager.QueueDownload(ctx, "unknown-size", 4)
+	assert.NoError(t, err, "items of unknown size only count against max_items")
+}
+
+func TestReconcileQueueTrimsToQueueLimit(t *testing.T) {
+	manager, store := newLimitedManager(t, config.QueueLimitConfig{Priorities: []int{3, 4}, MaxItems: 2})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	predictor := NewPredictor(store, &config.PredictionConfig{SyncInterval: time.Hour, HistoryDays: 30}, logger)
+	predictor.SetDownloadManager(manager)
+
+	summary, err := predictor.ReconcileQueue(context.Background(), []PredictionResult{
+		{MediaID: "unlikely", Priority: 4, Confidence: 0.5},
+		{MediaID: "likely", Priority: 4, Confidence: 0.9},
+		{MediaID: "soon", Priority: 3, Confidence: 0.6},
+		{MediaID: "next", Priority: 1, Confidence: 0.7},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, summary.Added)
+	assert.Equal(t, 1, summary.Trimmed)
+
+	queued, err := store.GetQueueItems("")
+	require.NoError(t, err)
+	var media []string
+	for _, item := range queued {
+		media = append(media, item.MediaID)
+	}
+	assert.ElementsMatch(t, []string{"next", "soon", "likely"}, media, "the least confident prediction is trimmed")
+}
@@ -0,0 +1,225 @@
+package downloader
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// playbackShare is the fraction of the configured bandwidth left to
+// rate-limited downloads while a Priority 0 download, which is never
+// throttled, is in flight.
+const playbackShare = 0.25
+
+// minBurst keeps per-job limiters usable when a job's share is tiny.
+const minBurst = 32 * 1024
+
+// priorityWeight returns a job's relative bandwidth weight: each step
+// towards Priority 0 doubles the share.
+func priorityWeight(priority int) float64 {
+	if priority < 0 {
+		priority = 0
+	}
+	if priority > 4 {
+		priority = 4
+	}
+	return float64(int(1) << (4 - priority))
+}
+
+// bandwidthAllocator splits the download rate limit between in-flight
+// jobs in proportion to their priority weights. Shares are recomputed
+// whenever a job starts, finishes or changes priority.
+type bandwidthAllocator struct {
+	mu    sync.Mutex
+	total func() rate.Limit
+	jobs  map[string]*jobShare
+
+	// owed is token debt carried over from before a restart, charged to
+	// the next rate-limited job to start
+	owed float64
+}
+
+// jobShare is one job's slice of the bandwidth.
+type jobShare struct {
+	priority int
+	limiter  *rate.Limiter
+}
+
+func newBandwidthAllocator(total func() rate.Limit) *bandwidthAllocator {
+	return &bandwidthAllocator{
+		total: total,
+		jobs:  make(map[string]*jobShare),
+	}
+}
+
+// register adds an in-flight job and returns the limiter its reads must
+// wait on.
+func (a *bandwidthAllocator) register(jobID string, priority int) *rate.Limiter {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	share := &jobShare{priority: priority, limiter: rate.NewLimiter(rate.Inf, minBurst)}
+	a.jobs[jobID] = share
+	a.rebalanceLocked()
+	a.chargeOwedLocked(share.limiter)
+	return share.limiter
+}
+
+// debt returns how many bytes in-flight jobs have read beyond what their
+// limiters have allowed so far, i.e. the wait they still owe.
+func (a *bandwidthAllocator) debt() float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var debt float64
+	for _, share := range a.jobs {
+		if share.limiter.Limit() == rate.Inf {
+			continue
+		}
+		if tokens := share.limiter.Tokens(); tokens < 0 {
+			debt -= tokens
+		}
+	}
+	return debt + a.owed
+}
+
+// carryDebt adds bytes of token debt from a previous run, so a restart
+// does not hand out a fresh burst the link has already spent.
+func (a *bandwidthAllocator) carryDebt(bytes float64) {
+	if bytes <= 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.owed += bytes
+}
+
+// chargeOwedLocked moves any carried debt onto limiter. Unlimited limiters
+// cannot carry debt, so it waits for the next limited job. Callers must
+// hold a.mu.
+func (a *bandwidthAllocator) chargeOwedLocked(limiter *rate.Limiter) {
+	if a.owed <= 0 || limiter.Limit() == rate.Inf {
+		return
+	}
+
+	// Empty the fresh bucket too: the restarted job should not get a burst
+	// the link already spent. A reservation may not exceed the burst, so
+	// charge in burst-sized steps
+	now := time.Now()
+	burst := limiter.Burst()
+	owed := a.owed + max(limiter.TokensAt(now), 0)
+	for remaining := int(owed); remaining > 0; remaining -= burst {
+		limiter.ReserveN(now, min(remaining, burst))
+	}
+	a.owed = 0
+}
+
+// unregister removes a finished job and hands its share to the others.
+func (a *bandwidthAllocator) unregister(jobID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.jobs, jobID)
+	a.rebalanceLocked()
+}
+
+// setPriority changes an in-flight job's priority. It reports false if the
+// job is not in flight.
+func (a *bandwidthAllocator) setPriority(jobID string, priority int) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	share, ok := a.jobs[jobID]
+	if !ok {
+		return false
+	}
+	share.priority = priority
+	a.rebalanceLocked()
+	return true
+}
+
+// rebalance recomputes every share, e.g. when the total rate changes at
+// the start or end of peak hours.
+func (a *bandwidthAllocator) rebalance() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rebalanceLocked()
+}
+
+// rebalanceLocked recomputes every share. Callers must hold a.mu.
+func (a *bandwidthAllocator) rebalanceLocked() {
+	total := a.total()
+	playing := false
+	var weights float64
+	for _, share := range a.jobs {
+		if share.priority == 0 {
+			playing = true
+			continue
+		}
+		weights += priorityWeight(share.priority)
+	}
+
+	// Whatever is playing gets the link; the rest make do with what is left
+	if playing && total != rate.Inf {
+		total *= playbackShare
+	}
+
+	for _, share := range a.jobs {
+		limit := rate.Inf
+		if share.priority != 0 && total != rate.Inf {
+			limit = total * rate.Limit(priorityWeight(share.priority)/weights)
+		}
+
+		burst := minBurst
+		if limit != rate.Inf && int(limit) > burst {
+			burst = int(limit)
+		}
+		share.limiter.SetLimit(limit)
+		share.limiter.SetBurst(burst)
+	}
+}
+
+// shares returns the current rate of each in-flight job, for diagnostics.
+func (a *bandwidthAllocator) shares() map[string]rate.Limit {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	shares := make(map[string]rate.Limit, len(a.jobs))
+	for id, share := range a.jobs {
+		shares[id] = share.limiter.Limit()
+	}
+	return shares
+}
+
+// SetJobPriority changes the priority of a queued or in-flight download.
+// In-flight downloads get their bandwidth share rebalanced immediately.
+func (m *Manager) SetJobPriority(jobID string, priority int) error {
+	if priority < 0 || priority > 4 {
+		return fmt.Errorf("priority must be between 0 and 4")
+	}
+
+	if err := m.storage.UpdateQueueItemPriority(jobID, priority); err != nil {
+		return err
+	}
+
+	// A job still waiting for a worker moves within the queue, and one
+	// raised to playback may pause a less urgent transfer
+	if m.jobs.setPriority(jobID, priority) && priority == 0 {
+		m.preemptFor(jobID)
+	}
+
+	m.activeMu.Lock()
+	if tracker, ok := m.active[jobID]; ok {
+		tracker.info.Priority = priority
+	}
+	m.activeMu.Unlock()
+
+	if m.bandwidth.setPriority(jobID, priority) {
+		m.logger.Info("Rebalanced download bandwidth after priority change",
+			"job_id", jobID, "priority", priority)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package downloader
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestBandwidthAllocatorSharesByPriority(t *testing.T) {
+	const total = rate.Limit(1000)
+	a := newBandwidthAllocator(func() rate.Limit { return total })
+
+	a.register("a", 3)
+	a.register("b", 3)
+	a.register("c", 3)
+	for id, limit := range a.shares() {
+		assert.InDelta(t, float64(total)/3, float64(limit), 0.01, "job %s", id)
+	}
+
+	// A priority 1 job gets four times the share of each priority 3 job
+	a.register("d", 1)
+	shares := a.shares()
+	assert.InDelta(t, 4*float64(shares["a"]), float64(shares["d"]), 0.01)
+	assert.InDelta(t, float64(total), float64(shares["a"]+shares["b"]+shares["c"]+shares["d"]), 0.01)
+
+	// Finishing a job hands its share back to the rest
+	a.unregister("d")
+	assert.InDelta(t, float64(total)/3, float64(a.shares()["a"]), 0.01)
+}
+
+func TestBandwidthAllocatorPlaybackPriority(t *testing.T) {
+	const total = rate.Limit(1000)
+	a := newBandwidthAllocator(func() rate.Limit { return total })
+
+	a.register("a", 3)
+	a.register("b", 3)
+	a.register("c", 3)
+
+	require.True(t, a.setPriority("a", 0))
+	shares := a.shares()
+	assert.Equal(t, rate.Inf, shares["a"], "currently playing downloads are never throttled")
+	assert.InDelta(t, float64(total)*playbackShare/2, float64(shares["b"]), 0.01)
+	assert.InDelta(t, float64(total)*playbackShare/2, float64(shares["c"]), 0.01)
+
+	require.True(t, a.setPriority("a", 3))
+	assert.InDelta(t, float64(total)/3, float64(a.shares()["b"]), 0.01)
+
+	assert.False(t, a.setPriority("missing", 0))
+}
+
+func TestBandwidthAllocatorFollowsTotal(t *testing.T) {
+	total := rate.Limit(1000)
+	a := newBandwidthAllocator(func() rate.Limit { return total })
+	limiter := a.register("a", 2)
+	assert.Equal(t, total, limiter.Limit())
+
+	// Peak hours start
+	total = 250
+	a.rebalance()
+	assert.Equal(t, rate.Limit(250), limiter.Limit())
+	assert.GreaterOrEqual(t, limiter.Burst(), minBurst)
+}
+
+var _ Store = (*storagetest.MemStore)(nil)
+
+func TestSetJobPriority(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sm := storagetest.New()
+	manager := New(&config.DownloadConfig{Workers: 1, RateLimitMbps: 8}, sm, logger)
+
+	for _, id := range []string{"a", "b"} {
+		require.NoError(t, manager.AddJob(&DownloadJob{ID: id, MediaID: "m-" + id, Priority: 3, CreatedAt: time.Now()}))
+	}
+
+	// "a" is in flight, "b" is still queued
+	manager.bandwidth.register("a", 3)
+	manager.trackDownload(&DownloadJob{ID: "a", MediaID: "m-a", Priority: 3}, 0, 100)
+
+	require.NoError(t, manager.SetJobPriority("a", 0))
+	assert.Equal(t, rate.Inf, manager.bandwidth.shares()["a"])
+	assert.Equal(t, 0, manager.active["a"].info.Priority)
+
+	require.NoError(t, manager.SetJobPriority("b", 1))
+	item, err := sm.FindActiveQueueItem("m-b")
+	require.NoError(t, err)
+	assert.Equal(t, 1, item.Priority)
+
+	assert.Error(t, manager.SetJobPriority("b", 5))
+	assert.Error(t, manager.SetJobPriority("missing", 1))
+}
+
+func TestSetJobPriorityThenAttemptFails(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sm := storagetest.New()
+	manager := New(&config.DownloadConfig{Workers: 1, RetryAttempts: 2, RetryDelay: time.Second}, sm, logger)
+	manager.running = true
+
+	job := &DownloadJob{ID: "a", MediaID: "m-a", Priority: 3, CreatedAt: time.Now()}
+	require.NoError(t, sm.AddQueueItem(&storage.QueueItem{ID: "a", MediaID: "m-a", Priority: 3, Status: "downloading", CreatedAt: job.CreatedAt}))
+
+	require.NoError(t, manager.SetJobPriority("a", 1))
+	manager.handleResult(&DownloadResult{Job: job, Error: errors.New("connection reset by peer")})
+
+	item, err := sm.FindActiveQueueItem("m-a")
+	require.NoError(t, err)
+	assert.Equal(t, "queued", item.Status)
+	assert.Equal(t, 1, item.Priority, "the retry keeps the priority set during the attempt")
+}
@@ -0,0 +1,92 @@
+package downloader
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// httpClientFor returns the HTTP client used for a download of the given
+// priority. Priorities with an interface binding dial from that interface's
+// address; everything else uses the default route. Interface addresses are
+// resolved per download so a VPN reconnecting with a new address is picked
+// up, and each address gets its own shared transport.
+func (m *Manager) httpClientFor(priority int) (*http.Client, error) {
+	var localIP net.IP
+	for _, binding := range m.config.InterfaceBindings {
+		if !containsPriority(binding.Priorities, priority) {
+			continue
+		}
+
+		ip, err := resolveBindingIP(binding.Interface, binding.SourceIP)
+		if err != nil {
+			// Fail rather than fall back, so traffic meant for a VPN never
+			// leaks onto the primary interface
+			return nil, err
+		}
+		localIP = ip
+		break
+	}
+
+	transport, err := m.transportFor(localIP)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Timeout:   30 * time.Minute, // Long timeout for large files
+		Transport: transport,
+	}, nil
+}
+
+// resolveBindingIP returns the local address to bind for an interface
+// binding, preferring IPv4 when an interface has several addresses.
+func resolveBindingIP(ifaceName, sourceIP string) (net.IP, error) {
+	if sourceIP != "" {
+		ip := net.ParseIP(sourceIP)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid source IP %q", sourceIP)
+		}
+		return ip, nil
+	}
+
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find interface %s: %w", ifaceName, err)
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list addresses for interface %s: %w", ifaceName, err)
+	}
+
+	var fallback net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			return ipNet.IP, nil
+		}
+		if fallback == nil {
+			fallback = ipNet.IP
+		}
+	}
+
+	if fallback == nil {
+		return nil, fmt.Errorf("interface %s has no usable address", ifaceName)
+	}
+	return fallback, nil
+}
+
+// containsPriority reports whether priority is in the list.
+func containsPriority(priorities []int, priority int) bool {
+	for _, p := range priorities {
+		if p == priority {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package downloader
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestHTTPClientForBinding(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var remoteAddr string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteAddr = r.RemoteAddr
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	cfg := &config.DownloadConfig{
+		Workers:       1,
+		RateLimitMbps: 10,
+		InterfaceBindings: []config.InterfaceBindingConfig{
+			{Priorities: []int{3, 4}, SourceIP: "127.0.0.1"},
+			{Priorities: []int{2}, Interface: "does-not-exist0"},
+		},
+	}
+	manager := New(cfg, nil, logger)
+
+	t.Run("bound priority dials from source IP", func(t *testing.T) {
+		client, err := manager.httpClientFor(3)
+		require.NoError(t, err)
+		require.NotNil(t, client.Transport)
+
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.True(t, strings.HasPrefix(remoteAddr, "127.0.0.1:"))
+	})
+
+	t.Run("unbound priority uses the shared default transport", func(t *testing.T) {
+		client, err := manager.httpClientFor(0)
+		require.NoError(t, err)
+		require.NotNil(t, client.Transport)
+
+		again, err := manager.httpClientFor(0)
+		require.NoError(t, err)
+		assert.Same(t, client.Transport, again.Transport)
+	})
+
+	t.Run("missing interface fails instead of falling back", func(t *testing.T) {
+		_, err := manager.httpClientFor(2)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,142 @@
+package downloader
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// defaultBingeRateThreshold and defaultBingeEpisodes apply when
+// prediction.binge_rate_threshold or prediction.binge_episodes is unset.
+const (
+	defaultBingeRateThreshold = 2.0
+	defaultBingeEpisodes      = 5
+)
+
+// bingeActiveDays is how recently a series must have been watched for its
+// upcoming episodes to be prefetched.
+const bingeActiveDays = 7
+
+// predictUpNext predicts following episodes in sequence (Priority 2).
+// When the user watches more episodes per day than
+// prediction.binge_rate_threshold, the next prediction.binge_episodes
+// episodes of each recently watched series are predicted, crossing into the
+// next season, so a binge night never waits on the network. Episodes that
+// are already cached count towards the N but are not predicted again.
+// During a trip, at least the trip's episodes are predicted whatever the
+// binge rate.
+func (p *Predictor) predictUpNext() []PredictionResult {
+	threshold := p.config.BingeRateThreshold
+	if threshold <= 0 {
+		threshold = defaultBingeRateThreshold
+	}
+	if p.preferences.SeriesBingeRate < threshold && p.tripEpisodes == 0 {
+		return nil
+	}
+
+	count := p.config.BingeEpisodes
+	if count <= 0 {
+		count = defaultBingeEpisodes
+	}
+	if p.tripEpisodes > count {
+		count = p.tripEpisodes
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -bingeActiveDays)
+	return p.upcomingEpisodes(cutoff, count, "Binge prefetch")
+}
+
+// upcomingEpisodes predicts the count episodes following the last one
+// watched of every series watched since cutoff, at Priority 2. reason
+// starts the reason of each prediction.
+func (p *Predictor) upcomingEpisodes(cutoff time.Time, count int, reason string) []PredictionResult {
+	var predictions []PredictionResult
+
+	for _, progress := range p.seriesProgress() {
+		if !progress.LastWatched.After(cutoff) {
+			continue
+		}
+
+		episodes, err := p.storage.GetSeriesMetadata(progress.SeriesID)
+		if err != nil {
+			p.logger.Warn("Failed to get series episodes for prefetch",
+				"series_id", progress.SeriesID, "error", err)
+			continue
+		}
+
+		upcoming := episodesAfter(episodes, progress.LastSeason, progress.LastEpisode, count)
+		averageSize := averageEpisodeSize(episodes)
+		confidence := p.calculateContinueConfidence(progress)
+
+		var totalSize int64
+		for i, episode := range upcoming {
+			if cached, err := p.storage.IsMediaCached(episode.ID); err != nil || cached {
+				continue
+			}
+
+			size := episode.Size
+			if size == 0 {
+				size = averageSize
+			}
+			totalSize += size
+
+			// Confidence falls slightly with each episode ahead, so the
+			// queue fills in watching order and a full cache defers the
+			// furthest episodes
+			predictions = append(predictions, PredictionResult{
+				MediaID:       episode.ID,
+				Priority:      2,
+				Confidence:    confidence - 0.01*float64(i),
+				Reason:        fmt.Sprintf("%s, %d episodes ahead", reason, i+1),
+				SeriesID:      progress.SeriesID,
+				Season:        episode.SeasonNumber,
+				Episode:       episode.EpisodeNumber,
+				MediaType:     "episode",
+				EstimatedSize: size,
+			})
+		}
+
+		p.logger.Debug("Prefetching upcoming episodes",
+			"series_id", progress.SeriesID,
+			"reason", reason,
+			"episodes", len(upcoming),
+			"estimated_bytes", totalSize,
+			"binge_rate", p.preferences.SeriesBingeRate)
+	}
+
+	return predictions
+}
+
+// episodesAfter returns up to count episodes that follow season and
+// episode, in watching order. episodes must be sorted by season and
+// episode number.
+func episodesAfter(episodes []*storage.MediaMetadata, season, episode, count int) []*storage.MediaMetadata {
+	var upcoming []*storage.MediaMetadata
+	for _, candidate := range episodes {
+		if len(upcoming) == count {
+			break
+		}
+		if candidate.SeasonNumber > season ||
+			(candidate.SeasonNumber == season && candidate.EpisodeNumber > episode) {
+			upcoming = append(upcoming, candidate)
+		}
+	}
+	return upcoming
+}
+
+// averageEpisodeSize is the mean size of the episodes whose size is known,
+// used as the estimate for those whose size is not; 0 when none are known.
+func averageEpisodeSize(episodes []*storage.MediaMetadata) int64 {
+	var total, known int64
+	for _, episode := range episodes {
+		if episode.Size > 0 {
+			total += episode.Size
+			known++
+		}
+	}
+	if known == 0 {
+		return 0
+	}
+	return total / known
+}
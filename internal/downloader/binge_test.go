@@ -0,0 +1,63 @@
+package downloader
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestPredictUpNextBingePrefetch(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	cfg := &config.PredictionConfig{HistoryDays: 30, MinConfidence: 0.7, BingeRateThreshold: 2, BingeEpisodes: 4}
+	predictor := NewPredictor(store, cfg, logger)
+
+	for _, ep := range []struct {
+		id              string
+		season, episode int
+		size            int64
+	}{
+		{"s1e1", 1, 1, 1000}, {"s1e2", 1, 2, 1000}, {"s1e3", 1, 3, 0},
+		{"s2e1", 2, 1, 3000}, {"s2e2", 2, 2, 2000}, {"s2e3", 2, 3, 2000}, {"s2e4", 2, 4, 2000},
+	} {
+		require.NoError(t, store.AddMediaMetadata(&storage.MediaMetadata{
+			ID: ep.id, JellyfinID: ep.id, Type: "episode", SeriesID: "show",
+			SeasonNumber: ep.season, EpisodeNumber: ep.episode, Size: ep.size,
+		}))
+	}
+	require.NoError(t, store.AddDownloadRecord(&storage.DownloadRecord{
+		ID: "s2e1", JellyfinID: "s2e1", MediaType: "episode", Status: "completed",
+	}))
+
+	now := time.Now()
+	predictor.viewingHistory = []ViewingSession{
+		{MediaID: "s1e1", MediaType: "episode", SeriesID: "show", Season: 1, Episode: 1, StartTime: now.Add(-2 * time.Hour), Completed: true},
+		{MediaID: "s1e2", MediaType: "episode", SeriesID: "show", Season: 1, Episode: 2, StartTime: now.Add(-time.Hour), Completed: true},
+	}
+
+	// Below the threshold nothing is prefetched
+	predictor.preferences.SeriesBingeRate = 1.5
+	assert.Empty(t, predictor.predictUpNext())
+
+	predictor.preferences.SeriesBingeRate = 2.5
+	predictions := predictor.predictUpNext()
+	require.Len(t, predictions, 3, "the cached s2e1 counts towards the four episodes")
+
+	assert.Equal(t, "s1e3", predictions[0].MediaID)
+	assert.Equal(t, int64(11000/6), predictions[0].EstimatedSize, "unknown sizes use the series average")
+	assert.Equal(t, "s2e2", predictions[1].MediaID)
+	assert.Equal(t, 2, predictions[1].Season)
+	assert.Equal(t, "s2e3", predictions[2].MediaID)
+	for _, pred := range predictions {
+		assert.Equal(t, 2, pred.Priority)
+	}
+	assert.Greater(t, predictions[0].Confidence, predictions[2].Confidence)
+}
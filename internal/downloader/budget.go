@@ -0,0 +1,75 @@
+package downloader
+
+import "fmt"
+
+// speculativeBudget tracks how much of prediction.max_speculative_gb is in
+// use by speculative (Priority 3-4) predicted downloads.
+type speculativeBudget struct {
+	limit int64 // bytes; 0 means no cap
+	used  int64
+}
+
+// speculativeBudget adds up the size of speculative predicted items that
+// are cached, queued or downloading. Sizes the queue does not know yet
+// come from stored metadata. Callers must hold p.mu.
+func (p *Predictor) speculativeBudget() (*speculativeBudget, error) {
+	budget := &speculativeBudget{limit: int64(p.config.MaxSpeculativeGB) * 1024 * 1024 * 1024}
+	if budget.limit == 0 {
+		return budget, nil
+	}
+
+	records, err := p.storage.ListDownloadRecords("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list download records: %w", err)
+	}
+	for _, record := range records {
+		if record.Status == "completed" && record.Source == SourcePrediction && record.Priority >= 3 {
+			budget.used += record.Size
+		}
+	}
+
+	items, err := p.storage.GetQueueItems("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queue: %w", err)
+	}
+	for _, item := range items {
+		if item.Source != SourcePrediction || item.Priority < 3 {
+			continue
+		}
+		if item.Status != "queued" && item.Status != "downloading" {
+			continue
+		}
+		size := item.Size
+		if size == 0 {
+			size = p.predictedSize(PredictionResult{MediaID: item.MediaID})
+		}
+		budget.used += size
+	}
+
+	return budget, nil
+}
+
+// fits reports whether a prediction of size bytes at priority fits the
+// budget. Urgent priorities are not capped.
+func (b *speculativeBudget) fits(priority int, size int64) bool {
+	return b.limit == 0 || priority < 3 || b.used+size <= b.limit
+}
+
+// reserve counts a queued speculative prediction against the budget.
+func (b *speculativeBudget) reserve(priority int, size int64) {
+	if priority >= 3 {
+		b.used += size
+	}
+}
+
+// predictedSize returns the estimated download size of a prediction, from
+// the prediction itself or stored metadata; 0 when unknown.
+func (p *Predictor) predictedSize(pred PredictionResult) int64 {
+	if pred.EstimatedSize > 0 {
+		return pred.EstimatedSize
+	}
+	if metadata, err := p.storage.GetMediaMetadata(pred.MediaID); err == nil {
+		return metadata.Size
+	}
+	return 0
+}
@@ -0,0 +1,135 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// ErrInsufficientSpace is returned when an item cannot be queued because
+// the cache has no room for it, even after eviction.
+var ErrInsufficientSpace = errors.New("not enough cache space")
+
+// maxEvictPriority is the least urgent priority allowed to evict cached
+// items to make room. Speculative downloads never push out cached content;
+// they are refused until space frees up and predicted again later.
+const maxEvictPriority = 2
+
+// InsufficientSpaceError reports how far an item is from fitting in the
+// cache. It wraps ErrInsufficientSpace.
+type InsufficientSpaceError struct {
+	MediaID   string
+	Size      int64 // Estimated size of the item
+	Used      int64 // Cache usage, including downloads already queued
+	Max       int64 // Cache size limit
+	Evictable int64 // Space eviction could free, if it was attempted
+}
+
+func (e *InsufficientSpaceError) Error() string {
+	return fmt.Sprintf("%v: %s needs %d bytes, %d of %d bytes used", ErrInsufficientSpace, e.MediaID, e.Size, e.Used, e.Max)
+}
+
+func (e *InsufficientSpaceError) Unwrap() error {
+	return ErrInsufficientSpace
+}
+
+// CapacityManager is the cache space management the download manager
+// consults before queueing. *storage.CacheManager implements it.
+type CapacityManager interface {
+	GetCacheSize() (int64, error)
+	MaxSize() int64
+	GetEvictionCandidates(targetSize int64) ([]*storage.EvictionCandidate, error)
+	EvictItems(candidates []*storage.EvictionCandidate) error
+}
+
+var _ CapacityManager = (*storage.CacheManager)(nil)
+
+// SetCapacityManager enables the capacity gate: items whose estimated size
+// does not fit in the cache are refused with an *InsufficientSpaceError.
+func (m *Manager) SetCapacityManager(cache CapacityManager) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cache = cache
+}
+
+// checkCapacity returns an *InsufficientSpaceError when an item of size
+// bytes at priority does not fit in the cache next to what is cached and
+// queued. With download.evict_to_fit set, urgent items first evict cached
+// items to make room. Items of unknown size are always let through.
+// Callers must hold m.queueMu.
+func (m *Manager) checkCapacity(mediaID string, priority int, size int64) error {
+	m.mu.RLock()
+	cache := m.cache
+	m.mu.RUnlock()
+
+	if cache == nil || size <= 0 {
+		return nil
+	}
+
+	cached, err := cache.GetCacheSize()
+	if err != nil {
+		return fmt.Errorf("failed to check cache size: %w", err)
+	}
+	pending, err := m.pendingBytes()
+	if err != nil {
+		return err
+	}
+
+	short := &InsufficientSpaceError{
+		MediaID: mediaID,
+		Size:    size,
+		Used:    cached + pending,
+		Max:     cache.MaxSize(),
+	}
+	needed := short.Used + size - short.Max
+	if needed <= 0 {
+		return nil
+	}
+	if !m.config.EvictToFit || priority > maxEvictPriority {
+		return short
+	}
+
+	candidates, err := cache.GetEvictionCandidates(needed)
+	if err != nil {
+		return fmt.Errorf("failed to find eviction candidates: %w", err)
+	}
+	for _, candidate := range candidates {
+		// Removing one link to a hardlinked file frees nothing
+		if candidate.Links <= 1 {
+			short.Evictable += candidate.Size
+		}
+	}
+	if short.Evictable < needed {
+		return short
+	}
+
+	m.logger.Info("Evicting cached items to make room for download",
+		"media_id", mediaID,
+		"priority", priority,
+		"needed_bytes", needed,
+		"candidates", len(candidates))
+	if err := cache.EvictItems(candidates); err != nil {
+		return fmt.Errorf("failed to evict cached items: %w", err)
+	}
+	return nil
+}
+
+// pendingBytes is the known size of everything queued, paused or
+// downloading, which will need cache space on top of what is cached.
+func (m *Manager) pendingBytes() (int64, error) {
+	items, err := m.storage.GetQueueItems("")
+	if err != nil {
+		return 0, fmt.Errorf("failed to check queued downloads: %w", err)
+	}
+
+	var total int64
+	for _, item := range items {
+		// Partial files live in the temp directory, outside the cache size
+		switch item.Status {
+		case "queued", "paused", "downloading":
+			total += m.queuedSize(item)
+		}
+	}
+	return total, nil
+}
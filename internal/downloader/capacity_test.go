@@ -0,0 +1,112 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+)
+
+// fakeCapacity is a cache of a fixed size with a list of items it can evict.
+type fakeCapacity struct {
+	used       int64
+	max        int64
+	candidates []*storage.EvictionCandidate
+	evicted    []string
+}
+
+func (f *fakeCapacity) GetCacheSize() (int64, error) { return f.used, nil }
+
+func (f *fakeCapacity) MaxSize() int64 { return f.max }
+
+func (f *fakeCapacity) GetEvictionCandidates(targetSize int64) ([]*storage.EvictionCandidate, error) {
+	var result []*storage.EvictionCandidate
+	var total int64
+	for _, candidate := range f.candidates {
+		if total >= targetSize {
+			break
+		}
+		result = append(result, candidate)
+		total += candidate.Size
+	}
+	return result, nil
+}
+
+func (f *fakeCapacity) EvictItems(candidates []*storage.EvictionCandidate) error {
+	for _, candidate := range candidates {
+		f.evicted = append(f.evicted, candidate.JellyfinID)
+		f.used -= candidate.Size
+	}
+	return nil
+}
+
+func addSizedMedia(t *testing.T, store *storagetest.MemStore, id string, size int64) {
+	t.Helper()
+	require.NoError(t, store.AddMediaMetadata(&storage.MediaMetadata{ID: id, JellyfinID: id, Type: "movie", Size: size}))
+}
+
+func TestQueueDownloadCapacity(t *testing.T) {
+	manager, store := newLimitedManager(t)
+	ctx := context.Background()
+	cache := &fakeCapacity{used: 900, max: 1000}
+	manager.SetCapacityManager(cache)
+
+	addSizedMedia(t, store, "small", 50)
+	addSizedMedia(t, store, "medium", 60)
+
+	_, err := manager.QueueDownload(ctx, "small", 3)
+	require.NoError(t, err)
+
+	// small is queued, so 950 of 1000 bytes are spoken for
+	_, err = manager.QueueDownload(ctx, "medium", 1)
+	require.ErrorIs(t, err, ErrInsufficientSpace)
+	var short *InsufficientSpaceError
+	require.True(t, errors.As(err, &short))
+	assert.Equal(t, int64(950), short.Used)
+	assert.Equal(t, int64(60), short.Size)
+	assert.Empty(t, cache.evicted, "eviction is off by default")
+
+	// Items of unknown size cannot be checked and are let through
+	_, err = manager.QueueDownload(ctx, "unknown", 3)
+	require.NoError(t, err)
+}
+
+func TestQueueDownloadEvictToFit(t *testing.T) {
+	manager, store := newLimitedManager(t)
+	manager.config.EvictToFit = true
+	ctx := context.Background()
+	cache := &fakeCapacity{
+		used: 1000,
+		max:  1000,
+		candidates: []*storage.EvictionCandidate{
+			{CacheEntry: storage.CacheEntry{JellyfinID: "old", Size: 40, Links: 1}},
+			{CacheEntry: storage.CacheEntry{JellyfinID: "older", Size: 40, Links: 1}},
+		},
+	}
+	manager.SetCapacityManager(cache)
+
+	addSizedMedia(t, store, "speculative", 50)
+	addSizedMedia(t, store, "next", 50)
+	addSizedMedia(t, store, "huge", 500)
+
+	// Speculative downloads never evict
+	_, err := manager.QueueDownload(ctx, "speculative", 3)
+	require.ErrorIs(t, err, ErrInsufficientSpace)
+	assert.Empty(t, cache.evicted)
+
+	// Nothing is evicted when eviction cannot free enough
+	_, err = manager.QueueDownload(ctx, "huge", 1)
+	var short *InsufficientSpaceError
+	require.True(t, errors.As(err, &short))
+	assert.Equal(t, int64(80), short.Evictable)
+	assert.Empty(t, cache.evicted)
+
+	_, err = manager.QueueDownload(ctx, "next", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"old", "older"}, cache.evicted)
+}
@@ -0,0 +1,451 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// ErrCollectionNotFound is returned for a collection or playlist the last
+// sync did not find.
+var ErrCollectionNotFound = errors.New("collection not found")
+
+// defaultCollectionPriority is the priority collections are cached at when
+// no other is asked for, the documented default for manual requests.
+const defaultCollectionPriority = 3
+
+// Statuses of a collection item.
+const (
+	CollectionItemCached      = "cached"
+	CollectionItemDownloading = "downloading"
+	CollectionItemQueued      = "queued"
+	CollectionItemMissing     = "missing" // Neither cached nor queued
+)
+
+// CollectionSource lists the collections and playlists to sync and their
+// items (implemented by jellyfin.Client).
+type CollectionSource interface {
+	GetCollections(ctx context.Context) ([]jellyfin.MediaItem, error)
+	GetCollectionItems(ctx context.Context, collectionID string) ([]jellyfin.MediaItem, error)
+	GetPlaylistItems(ctx context.Context, playlistID string) ([]jellyfin.MediaItem, error)
+}
+
+// CollectionStore holds the synced collections and tells what of them is
+// cached or queued (implemented by storage.Manager).
+type CollectionStore interface {
+	storage.QueueStore
+	storage.MediaStore
+	SaveCollection(collection *storage.Collection) error
+	GetCollection(id string) (*storage.Collection, error)
+	ListCollections() ([]*storage.Collection, error)
+	RemoveCollection(id string) error
+}
+
+// CollectionCacheResult describes what queueing a collection did.
+type CollectionCacheResult struct {
+	Queued   int `json:"queued"`   // Items queued, or already in the queue
+	Excluded int `json:"excluded"` // Items in excluded libraries
+	Failed   int `json:"failed"`
+	// QueueFull and CacheFull are set when queueing stopped part way;
+	// caching the collection again queues the rest once there is room
+	QueueFull bool `json:"queue_full"`
+	CacheFull bool `json:"cache_full"`
+}
+
+// CollectionProgress reports how much of a collection is cached.
+type CollectionProgress struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Kind        string    `json:"kind"`
+	Cached      bool      `json:"cached"` // Queued for caching, following additions
+	Priority    int       `json:"priority,omitempty"`
+	SyncedAt    time.Time `json:"synced_at"`
+	Total       int       `json:"total"`
+	CachedItems int       `json:"cached_items"`
+	Downloading int       `json:"downloading"`
+	QueuedItems int       `json:"queued_items"`
+	Missing     int       `json:"missing"`
+	TotalBytes  int64     `json:"total_bytes"` // Sizes reported by Jellyfin; unknown sizes count as 0
+	CachedBytes int64     `json:"cached_bytes"`
+	// Progress is the share of items cached, counting partial downloads,
+	// 0.0 to 1.0
+	Progress float64 `json:"progress"`
+	// Items are only filled in for a single collection
+	Items []CollectionItemStatus `json:"items,omitempty"`
+}
+
+// CollectionItemStatus is where an item of a collection stands.
+type CollectionItemStatus struct {
+	Position int     `json:"position"` // 1-based, in collection or playlist order
+	MediaID  string  `json:"media_id"`
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	Size     int64   `json:"size,omitempty"`
+	Status   string  `json:"status"`             // One of the CollectionItem constants
+	Progress float64 `json:"progress,omitempty"` // Of a download, 0.0 to 1.0
+	JobID    string  `json:"job_id,omitempty"`
+}
+
+// Collections mirrors the Jellyfin collections and playlists and caches
+// the ones asked for, in order: items are queued one after another at the
+// same priority, so they download in collection or playlist order. Items
+// later added to a cached collection are queued by the next sync.
+type Collections struct {
+	source   CollectionSource
+	store    CollectionStore
+	queuer   DownloadQueuer
+	interval time.Duration
+	logger   *slog.Logger
+
+	// now is stubbed by tests
+	now func() time.Time
+
+	// mu serializes syncs and caching, so an item is not queued twice
+	mu sync.Mutex
+}
+
+// NewCollections creates collections synced every interval that queue
+// through queuer. An interval of 0 disables Run.
+func NewCollections(source CollectionSource, store CollectionStore, queuer DownloadQueuer, interval time.Duration, logger *slog.Logger) *Collections {
+	return &Collections{
+		source:   source,
+		store:    store,
+		queuer:   queuer,
+		interval: interval,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Run syncs collections straight away and then every interval until ctx
+// is cancelled.
+func (c *Collections) Run(ctx context.Context) {
+	if c.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Sync(ctx); err != nil && ctx.Err() == nil {
+			c.logger.Warn("Collection sync failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync stores every collection and playlist on the server with its items,
+// forgets those deleted there and queues items added to cached ones.
+func (c *Collections) Sync(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	remote, err := c.source.GetCollections(ctx)
+	if err != nil {
+		return err
+	}
+	stored, err := c.store.ListCollections()
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]*storage.Collection, len(stored))
+	for _, collection := range stored {
+		existing[collection.ID] = collection
+	}
+
+	seen := make(map[string]bool, len(remote))
+	for _, item := range remote {
+		kind := collectionKind(item.Type)
+		if kind == "" {
+			continue
+		}
+		seen[item.ID] = true
+
+		items, err := c.fetchItems(ctx, item.ID, kind)
+		if err != nil {
+			c.logger.Warn("Failed to sync collection", "collection_id", item.ID, "error", err)
+			continue
+		}
+
+		collection := existing[item.ID]
+		if collection == nil {
+			collection = &storage.Collection{ID: item.ID}
+		}
+		known := make(map[string]bool, len(collection.Items))
+		for _, old := range collection.Items {
+			known[old.MediaID] = true
+		}
+		collection.Name = item.Name
+		collection.Kind = kind
+		collection.Items = items
+		collection.SyncedAt = c.now()
+
+		if err := c.store.SaveCollection(collection); err != nil {
+			return fmt.Errorf("failed to store collection: %w", err)
+		}
+
+		if !collection.Cached {
+			continue
+		}
+		var added []storage.CollectionItem
+		for _, item := range items {
+			if !known[item.MediaID] {
+				added = append(added, item)
+			}
+		}
+		if len(added) > 0 {
+			result := c.queue(ctx, collection, added)
+			c.logger.Info("Queued items added to cached collection",
+				"collection_id", collection.ID,
+				"name", collection.Name,
+				"queued", result.Queued)
+		}
+	}
+
+	for id := range existing {
+		if seen[id] {
+			continue
+		}
+		if err := c.store.RemoveCollection(id); err != nil {
+			return fmt.Errorf("failed to remove collection: %w", err)
+		}
+	}
+
+	c.logger.Debug("Collections synced", "collections", len(seen))
+	return nil
+}
+
+// Cache queues every item of a collection that is not cached yet, in
+// order, at priority (0 for the default), and queues items added to it
+// later too.
+func (c *Collections) Cache(ctx context.Context, id string, priority int) (*CollectionCacheResult, error) {
+	if priority < 0 || priority > 4 {
+		return nil, fmt.Errorf("priority must be between 1 and 4")
+	}
+	if priority == 0 {
+		priority = defaultCollectionPriority
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	collection, err := c.store.GetCollection(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+	if collection == nil {
+		return nil, ErrCollectionNotFound
+	}
+
+	collection.Cached = true
+	collection.Priority = priority
+	collection.CachedAt = c.now()
+	if err := c.store.SaveCollection(collection); err != nil {
+		return nil, fmt.Errorf("failed to store collection: %w", err)
+	}
+
+	result := c.queue(ctx, collection, collection.Items)
+	c.logger.Info("Queued collection for caching",
+		"collection_id", collection.ID,
+		"name", collection.Name,
+		"kind", collection.Kind,
+		"priority", priority,
+		"queued", result.Queued,
+		"excluded", result.Excluded)
+	return result, nil
+}
+
+// Uncache stops queueing items added to a collection. Items already cached
+// or queued stay.
+func (c *Collections) Uncache(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	collection, err := c.store.GetCollection(id)
+	if err != nil {
+		return fmt.Errorf("failed to get collection: %w", err)
+	}
+	if collection == nil {
+		return ErrCollectionNotFound
+	}
+
+	collection.Cached = false
+	collection.Priority = 0
+	collection.CachedAt = time.Time{}
+	return c.store.SaveCollection(collection)
+}
+
+// List returns the progress of every collection, without their items.
+func (c *Collections) List() ([]*CollectionProgress, error) {
+	collections, err := c.store.ListCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	progress := make([]*CollectionProgress, 0, len(collections))
+	for _, collection := range collections {
+		p := c.progress(collection)
+		p.Items = nil
+		progress = append(progress, p)
+	}
+	return progress, nil
+}
+
+// Progress returns how much of a collection is cached, item by item.
+func (c *Collections) Progress(id string) (*CollectionProgress, error) {
+	collection, err := c.store.GetCollection(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+	if collection == nil {
+		return nil, ErrCollectionNotFound
+	}
+	return c.progress(collection), nil
+}
+
+// progress looks up where each item of collection stands.
+func (c *Collections) progress(collection *storage.Collection) *CollectionProgress {
+	p := &CollectionProgress{
+		ID:       collection.ID,
+		Name:     collection.Name,
+		Kind:     collection.Kind,
+		Cached:   collection.Cached,
+		Priority: collection.Priority,
+		SyncedAt: collection.SyncedAt,
+		Total:    len(collection.Items),
+		Items:    make([]CollectionItemStatus, 0, len(collection.Items)),
+	}
+
+	var done float64
+	for i, item := range collection.Items {
+		status := CollectionItemStatus{
+			Position: i + 1,
+			MediaID:  item.MediaID,
+			Name:     item.Name,
+			Type:     item.Type,
+			Size:     item.Size,
+			Status:   CollectionItemMissing,
+		}
+		p.TotalBytes += item.Size
+
+		if cached, err := c.store.IsMediaCached(item.MediaID); err == nil && cached {
+			status.Status = CollectionItemCached
+			status.Progress = 1
+			p.CachedItems++
+			if record, err := c.store.GetDownload(item.MediaID); err == nil {
+				p.CachedBytes += record.Size
+			}
+			done++
+		} else if queued, err := c.store.FindActiveQueueItem(item.MediaID); err == nil && queued != nil {
+			status.JobID = queued.ID
+			status.Progress = queued.Progress
+			if queued.Status == "downloading" {
+				status.Status = CollectionItemDownloading
+				p.Downloading++
+				done += queued.Progress
+			} else {
+				status.Status = CollectionItemQueued
+				p.QueuedItems++
+			}
+		} else {
+			p.Missing++
+		}
+		p.Items = append(p.Items, status)
+	}
+
+	if p.Total > 0 {
+		p.Progress = done / float64(p.Total)
+	}
+	return p
+}
+
+// queue queues items of collection in order, skipping those already
+// cached, until the queue or the cache is full. Callers must hold c.mu.
+func (c *Collections) queue(ctx context.Context, collection *storage.Collection, items []storage.CollectionItem) *CollectionCacheResult {
+	result := &CollectionCacheResult{}
+	for _, item := range items {
+		if cached, err := c.store.IsMediaCached(item.MediaID); err == nil && cached {
+			continue
+		}
+
+		_, err := c.queuer.QueueDownloadWithSource(ctx, item.MediaID, collection.Priority, SourceCollection)
+		switch {
+		case errors.Is(err, ErrLibraryExcluded):
+			result.Excluded++
+			continue
+		case errors.Is(err, ErrQueueFull):
+			result.QueueFull = true
+		case errors.Is(err, ErrInsufficientSpace):
+			// Later items are no smaller, so stop at the first that does not fit
+			result.CacheFull = true
+		case err != nil:
+			c.logger.Warn("Failed to queue collection item",
+				"collection_id", collection.ID,
+				"media_id", item.MediaID,
+				"error", err)
+			result.Failed++
+			continue
+		default:
+			result.Queued++
+			continue
+		}
+		break
+	}
+	return result
+}
+
+// fetchItems returns the cacheable items of a collection or playlist, in
+// order, once each.
+func (c *Collections) fetchItems(ctx context.Context, id, kind string) ([]storage.CollectionItem, error) {
+	var remote []jellyfin.MediaItem
+	var err error
+	if kind == storage.CollectionKindPlaylist {
+		remote, err = c.source.GetPlaylistItems(ctx, id)
+	} else {
+		remote, err = c.source.GetCollectionItems(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]storage.CollectionItem, 0, len(remote))
+	seen := make(map[string]bool, len(remote))
+	for _, item := range remote {
+		mediaType := jellyfin.CacheMediaType(item.Type)
+		if mediaType == "" || seen[item.ID] {
+			continue
+		}
+		seen[item.ID] = true
+		items = append(items, storage.CollectionItem{
+			MediaID: item.ID,
+			Name:    item.Name,
+			Type:    mediaType,
+			Size:    item.Size,
+		})
+	}
+	return items, nil
+}
+
+// collectionKind maps a Jellyfin item type to a collection kind, or ""
+// for other types.
+func collectionKind(itemType string) string {
+	switch itemType {
+	case "BoxSet":
+		return storage.CollectionKindCollection
+	case "Playlist":
+		return storage.CollectionKindPlaylist
+	}
+	return ""
+}
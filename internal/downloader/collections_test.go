@@ -0,0 +1,112 @@
+package downloader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+var _ CollectionSource = (*jellyfin.Client)(nil)
+var _ CollectionSource = (*jellyfintest.Mock)(nil)
+var _ CollectionStore = (*storage.Manager)(nil)
+
+func queuedOrder(t *testing.T, store *storage.Manager) []string {
+	items, err := store.GetQueueItems("queued")
+	require.NoError(t, err)
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.MediaID)
+	}
+	return ids
+}
+
+func TestCollections(t *testing.T) {
+	predictor, store, queuer := newReconcileTestPredictor(t)
+	ctx := context.Background()
+
+	source := jellyfintest.New("http://jellyfin.local")
+	source.Collections = []jellyfin.MediaItem{
+		{ID: "c1", Name: "Alien Collection", Type: "BoxSet"},
+		{ID: "p1", Name: "Road Trip", Type: "Playlist"},
+		{ID: "f1", Name: "Folder", Type: "Folder"},
+	}
+	source.CollectionItems = map[string][]jellyfin.MediaItem{
+		"c1": {
+			{ID: "m1", Name: "Alien", Type: "Movie", Size: 4000},
+			{ID: "s1", Name: "Series", Type: "Series"}, // Not cacheable
+			{ID: "m2", Name: "Aliens", Type: "Movie", Size: 6000},
+		},
+		"p1": {
+			{ID: "t9", Name: "Last Added, Played First", Type: "Audio"},
+			{ID: "t1", Name: "Opener", Type: "Audio"},
+			{ID: "t5", Name: "Middle", Type: "Audio"},
+		},
+	}
+	collections := NewCollections(source, store, queuer, 0, predictor.logger)
+
+	require.NoError(t, collections.Sync(ctx))
+	list, err := collections.List()
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "Alien Collection", list[0].Name)
+	assert.Equal(t, storage.CollectionKindCollection, list[0].Kind)
+	assert.Equal(t, 2, list[0].Total)
+	assert.Nil(t, list[0].Items)
+	assert.Equal(t, storage.CollectionKindPlaylist, list[1].Kind)
+
+	// A playlist downloads in playlist order
+	result, err := collections.Cache(ctx, "p1", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Queued)
+	assert.Equal(t, []string{"t9", "t1", "t5"}, queuedOrder(t, store))
+	for _, item := range queuedByMedia(t, store) {
+		assert.Equal(t, defaultCollectionPriority, item.Priority)
+		assert.Equal(t, SourceCollection, item.Source)
+	}
+
+	// Cached items are not queued again and count towards progress
+	require.NoError(t, store.AddDownloadRecord(&storage.DownloadRecord{ID: "m1", JellyfinID: "m1", MediaType: "movie", Status: "completed", Size: 4000}))
+	result, err = collections.Cache(ctx, "c1", 2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Queued)
+
+	progress, err := collections.Progress("c1")
+	require.NoError(t, err)
+	assert.True(t, progress.Cached)
+	assert.Equal(t, 2, progress.Priority)
+	assert.Equal(t, 1, progress.CachedItems)
+	assert.Equal(t, 1, progress.QueuedItems)
+	assert.Equal(t, int64(10000), progress.TotalBytes)
+	assert.Equal(t, int64(4000), progress.CachedBytes)
+	assert.InDelta(t, 0.5, progress.Progress, 0.001)
+	require.Len(t, progress.Items, 2)
+	assert.Equal(t, CollectionItemCached, progress.Items[0].Status)
+	assert.Equal(t, CollectionItemQueued, progress.Items[1].Status)
+	assert.Equal(t, 2, progress.Items[1].Position)
+
+	// Items added to a cached playlist are queued by the next sync, and
+	// collections deleted on the server are forgotten
+	source.CollectionItems["p1"] = append(source.CollectionItems["p1"], jellyfin.MediaItem{ID: "t7", Type: "Audio"})
+	source.Collections = source.Collections[1:]
+	require.NoError(t, collections.Sync(ctx))
+	assert.Contains(t, queuedOrder(t, store), "t7")
+	_, err = collections.Progress("c1")
+	assert.ErrorIs(t, err, ErrCollectionNotFound)
+
+	// Once uncached, additions are left alone
+	require.NoError(t, collections.Uncache("p1"))
+	source.CollectionItems["p1"] = append(source.CollectionItems["p1"], jellyfin.MediaItem{ID: "t8", Type: "Audio"})
+	require.NoError(t, collections.Sync(ctx))
+	assert.NotContains(t, queuedOrder(t, store), "t8")
+
+	_, err = collections.Cache(ctx, "gone", 0)
+	assert.ErrorIs(t, err, ErrCollectionNotFound)
+	_, err = collections.Cache(ctx, "p1", 7)
+	assert.Error(t, err)
+}
@@ -0,0 +1,286 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+var (
+	// ErrQueueItemNotFound is returned when a queue ID matches no item.
+	ErrQueueItemNotFound = errors.New("queue item not found")
+	// ErrQueueItemState is returned when a queue item cannot be paused or
+	// resumed because it already failed or finished.
+	ErrQueueItemState = errors.New("queue item cannot be changed in its current status")
+)
+
+// Causes for stopping an in-flight download on request. None is a
+// failure: a paused download keeps its partial file for later, a cancelled
+// one is thrown away, and one stopped by shutdown resumes on the next start.
+var (
+	errPaused    = errors.New("download paused")
+	errCancelled = errors.New("download cancelled")
+	errShutdown  = errors.New("download manager shutting down")
+)
+
+// PauseJob stops a queued or in-flight download until ResumeJob is called.
+// An in-flight transfer is aborted at once and keeps its partial file, so
+// it resumes where it left off. Pausing a paused item does nothing.
+func (m *Manager) PauseJob(jobID string) error {
+	item, err := m.findQueueItem(jobID)
+	if err != nil {
+		return err
+	}
+	switch item.Status {
+	case "paused":
+		return nil
+	case "queued", "downloading":
+	default:
+		return fmt.Errorf("%w: %s is %s", ErrQueueItemState, jobID, item.Status)
+	}
+
+	item.Status = "paused"
+	if err := m.storage.UpdateQueueItem(item); err != nil {
+		return fmt.Errorf("failed to pause queue item: %w", err)
+	}
+
+	m.jobs.remove(jobID)
+	inFlight := m.stopJob(jobID, errPaused)
+
+	m.logger.Info("Paused download", "job_id", jobID, "media_id", item.MediaID, "in_flight", inFlight)
+	m.reportProgress(item.MediaID, item.Progress, "paused", "Download paused")
+	return nil
+}
+
+// ResumeJob returns a paused download to the queue at its priority.
+// Resuming an item that is queued or downloading does nothing; one that ran
+// out of retries is retried as by RetryFailedJob.
+func (m *Manager) ResumeJob(jobID string) error {
+	item, err := m.findQueueItem(jobID)
+	if err != nil {
+		return err
+	}
+	switch item.Status {
+	case "queued", "downloading":
+		return nil
+	case "paused":
+	case "dead_letter":
+		return m.retryFailed(item)
+	default:
+		return fmt.Errorf("%w: %s is %s", ErrQueueItemState, jobID, item.Status)
+	}
+
+	item.Status = "queued"
+	if err := m.storage.UpdateQueueItem(item); err != nil {
+		return fmt.Errorf("failed to resume queue item: %w", err)
+	}
+
+	m.logger.Info("Resumed download", "job_id", jobID, "media_id", item.MediaID)
+	m.reportProgress(item.MediaID, item.Progress, "queued", "Download resumed")
+	return nil
+}
+
+// FailedJobs returns the downloads that gave up, most urgent first: those
+// out of retries and those that failed with an error retrying would not
+// fix.
+func (m *Manager) FailedJobs() ([]*storage.QueueItem, error) {
+	items, err := m.storage.GetQueueItems("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue: %w", err)
+	}
+	failed := make([]*storage.QueueItem, 0)
+	for _, item := range items {
+		if isFailed(item) {
+			failed = append(failed, item)
+		}
+	}
+	return failed, nil
+}
+
+// RetryFailedJob returns a failed download to the queue with a fresh set
+// of retries. Its error history is kept.
+func (m *Manager) RetryFailedJob(jobID string) error {
+	item, err := m.findQueueItem(jobID)
+	if err != nil {
+		return err
+	}
+	if !isFailed(item) {
+		return fmt.Errorf("%w: %s is %s", ErrQueueItemState, jobID, item.Status)
+	}
+	return m.retryFailed(item)
+}
+
+// RetryFailedJobs retries every failed download, reporting how many were
+// returned to the queue.
+func (m *Manager) RetryFailedJobs() (int, error) {
+	items, err := m.FailedJobs()
+	if err != nil {
+		return 0, err
+	}
+	for i, item := range items {
+		if err := m.retryFailed(item); err != nil {
+			return i, err
+		}
+	}
+	return len(items), nil
+}
+
+// retryFailed requeues a failed item. If the media was queued again since,
+// the failed entry is dropped instead of downloading it twice.
+func (m *Manager) retryFailed(item *storage.QueueItem) error {
+	if active, err := m.storage.FindActiveQueueItem(item.MediaID); err == nil && active != nil {
+		if err := m.storage.RemoveQueueItem(item.ID); err != nil {
+			return fmt.Errorf("failed to remove failed queue item: %w", err)
+		}
+		m.logger.Info("Dropped failed download queued again since",
+			"job_id", item.ID, "media_id", item.MediaID, "queued_as", active.ID)
+		return nil
+	}
+
+	item.Status = "queued"
+	item.RetryCount = 0
+	item.NextAttemptAt = time.Time{}
+	item.CompletedAt = time.Time{}
+	if err := m.storage.UpdateQueueItem(item); err != nil {
+		return fmt.Errorf("failed to retry queue item: %w", err)
+	}
+
+	m.logger.Info("Retrying failed download", "job_id", item.ID, "media_id", item.MediaID)
+	m.reportProgress(item.MediaID, item.Progress, "queued", "Download retried")
+	return nil
+}
+
+// isFailed reports whether a queue item is a download that gave up.
+func isFailed(item *storage.QueueItem) bool {
+	return item.Status == "failed" || item.Status == "dead_letter"
+}
+
+// RemoveFromQueue removes an item from the download queue. If the item is
+// downloading, the transfer is aborted; its partial file is deleted either
+// way.
+func (m *Manager) RemoveFromQueue(ctx context.Context, queueID string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.running {
+		return fmt.Errorf("download manager not running")
+	}
+
+	item, err := m.findQueueItem(queueID)
+	if err != nil {
+		return err
+	}
+
+	// Remove from storage queue
+	if err := m.storage.RemoveQueueItem(queueID); err != nil {
+		return fmt.Errorf("failed to remove from storage queue: %w", err)
+	}
+
+	m.jobs.remove(queueID)
+	if m.stopJob(queueID, errCancelled) {
+		// The worker deletes the partial file once the transfer has stopped
+		m.logger.Info("Cancelled in-flight download", "queue_id", queueID, "media_id", item.MediaID)
+	} else if item.LocalPath != "" {
+		m.removePartial(queueID, item.LocalPath)
+	}
+
+	m.logger.Info("Removed item from download queue", "queue_id", queueID)
+	return nil
+}
+
+// handleStopped settles a download that was stopped on purpose.
+func (m *Manager) handleStopped(job *DownloadJob, result *DownloadResult) {
+	switch {
+	case errors.Is(result.Stopped, errPreempted):
+		m.requeuePreempted(job, result.BytesDownloaded)
+	case errors.Is(result.Stopped, errPaused):
+		item, err := m.storage.FindActiveQueueItem(job.MediaID)
+		if err != nil || item == nil || item.ID != job.ID {
+			return
+		}
+		item.BytesDownloaded = result.BytesDownloaded
+		if err := m.storage.UpdateQueueItem(item); err != nil {
+			m.logger.Error("Failed to record paused download progress",
+				"job_id", job.ID, "error", err)
+		}
+	case errors.Is(result.Stopped, errCancelled):
+		m.removePartial(job.ID, job.LocalPath)
+	}
+}
+
+// stopJob cancels the in-flight download of jobID with cause. It reports
+// whether the job was in flight.
+func (m *Manager) stopJob(jobID string, cause error) bool {
+	m.activeMu.Lock()
+	defer m.activeMu.Unlock()
+
+	cancel := m.cancels[jobID]
+	if cancel == nil {
+		return false
+	}
+	cancel(cause)
+	return true
+}
+
+// stopAll cancels every in-flight download with cause.
+func (m *Manager) stopAll(cause error) {
+	m.activeMu.Lock()
+	defer m.activeMu.Unlock()
+
+	for _, cancel := range m.cancels {
+		cancel(cause)
+	}
+}
+
+// attachCancel records how to stop the in-flight download of jobID.
+func (m *Manager) attachCancel(jobID string, cancel context.CancelCauseFunc) {
+	m.activeMu.Lock()
+	defer m.activeMu.Unlock()
+	if m.cancels == nil {
+		m.cancels = make(map[string]context.CancelCauseFunc)
+	}
+	m.cancels[jobID] = cancel
+}
+
+func (m *Manager) detachCancel(jobID string) {
+	m.activeMu.Lock()
+	defer m.activeMu.Unlock()
+	delete(m.cancels, jobID)
+}
+
+// stopCause returns why a download's context was cancelled on purpose, or
+// nil if it was not.
+func stopCause(ctx context.Context) error {
+	cause := context.Cause(ctx)
+	if errors.Is(cause, errPreempted) || errors.Is(cause, errPaused) ||
+		errors.Is(cause, errCancelled) || errors.Is(cause, errShutdown) {
+		return cause
+	}
+	return nil
+}
+
+// findQueueItem returns the queue item with the given ID.
+func (m *Manager) findQueueItem(queueID string) (*storage.QueueItem, error) {
+	items, err := m.storage.GetQueueItems("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue: %w", err)
+	}
+	for _, item := range items {
+		if item.ID == queueID {
+			return item, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrQueueItemNotFound, queueID)
+}
+
+// removePartial deletes what a cancelled download left behind.
+func (m *Manager) removePartial(jobID, localPath string) {
+	if err := os.Remove(storage.PartialPath(localPath)); err != nil && !os.IsNotExist(err) {
+		m.logger.Warn("Failed to remove partial download",
+			"job_id", jobID, "error", err)
+	}
+}
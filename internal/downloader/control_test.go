@@ -0,0 +1,161 @@
+package downloader
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+func TestPauseAndResumeQueuedJob(t *testing.T) {
+	manager, store := newLimitedManager(t)
+
+	jobID, err := manager.QueueDownload(context.Background(), "m1", 2)
+	require.NoError(t, err)
+	require.Equal(t, 1, manager.jobs.len())
+
+	require.NoError(t, manager.PauseJob(jobID))
+	require.NoError(t, manager.PauseJob(jobID), "pausing twice is harmless")
+	item, err := store.FindActiveQueueItem("m1")
+	require.NoError(t, err)
+	assert.Equal(t, "paused", item.Status)
+	assert.Zero(t, manager.jobs.len(), "a paused job is not handed to workers")
+
+	again, err := manager.QueueDownload(context.Background(), "m1", 2)
+	require.NoError(t, err)
+	assert.Equal(t, jobID, again, "a paused item is not queued twice")
+
+	require.NoError(t, manager.ResumeJob(jobID))
+	item, err = store.FindActiveQueueItem("m1")
+	require.NoError(t, err)
+	assert.Equal(t, "queued", item.Status)
+}
+
+func TestPauseJobErrors(t *testing.T) {
+	manager, store := newLimitedManager(t)
+	require.NoError(t, store.AddQueueItem(&storage.QueueItem{ID: "done", MediaID: "m1", Status: "failed", CreatedAt: time.Now()}))
+
+	assert.ErrorIs(t, manager.PauseJob("missing"), ErrQueueItemNotFound)
+	assert.ErrorIs(t, manager.ResumeJob("missing"), ErrQueueItemNotFound)
+	assert.ErrorIs(t, manager.PauseJob("done"), ErrQueueItemState)
+	assert.ErrorIs(t, manager.ResumeJob("done"), ErrQueueItemState)
+	assert.ErrorIs(t, manager.RemoveFromQueue(context.Background(), "missing"), ErrQueueItemNotFound)
+}
+
+// startBlockedDownload runs job against a server that sends 1000 of 5000
+// bytes and then hangs, and waits until those bytes are on disk.
+func startBlockedDownload(t *testing.T) (*Manager, *DownloadJob, <-chan *DownloadResult, func() *storage.QueueItem) {
+	manager, store, job := newResumeTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "5000")
+		w.Write(make([]byte, 1000))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	manager.running = true
+
+	results := make(chan *DownloadResult, 1)
+	go func() { results <- manager.processJob(job) }()
+
+	require.Eventually(t, func() bool {
+		active := manager.ActiveDownloads()
+		return len(active) == 1 && active[0].Downloaded == 1000
+	}, 2*time.Second, 10*time.Millisecond)
+
+	item := func() *storage.QueueItem {
+		item, err := store.FindActiveQueueItem(job.MediaID)
+		require.NoError(t, err)
+		return item
+	}
+	return manager, job, results, item
+}
+
+func waitResult(t *testing.T, results <-chan *DownloadResult) *DownloadResult {
+	select {
+	case result := <-results:
+		return result
+	case <-time.After(2 * time.Second):
+		t.Fatal("download did not stop")
+		return nil
+	}
+}
+
+func TestPauseInFlightDownload(t *testing.T) {
+	manager, job, results, item := startBlockedDownload(t)
+
+	require.NoError(t, manager.PauseJob(job.ID))
+	result := waitResult(t, results)
+	assert.ErrorIs(t, result.Stopped, errPaused)
+	assert.NoError(t, result.Error)
+
+	manager.handleResult(result)
+	paused := item()
+	require.NotNil(t, paused)
+	assert.Equal(t, "paused", paused.Status)
+	assert.Equal(t, int64(1000), paused.BytesDownloaded)
+	assert.FileExists(t, storage.PartialPath(job.LocalPath), "the partial file is kept to resume from")
+}
+
+func TestCancelInFlightDownload(t *testing.T) {
+	manager, job, results, item := startBlockedDownload(t)
+
+	require.NoError(t, manager.RemoveFromQueue(context.Background(), job.ID))
+	result := waitResult(t, results)
+	assert.ErrorIs(t, result.Stopped, errCancelled)
+
+	manager.handleResult(result)
+	assert.Nil(t, item())
+	_, err := os.Stat(storage.PartialPath(job.LocalPath))
+	assert.True(t, os.IsNotExist(err), "the partial file is deleted")
+}
+
+func TestRetryFailedJobs(t *testing.T) {
+	manager, store := newLimitedManager(t)
+	now := time.Now()
+	for _, item := range []*storage.QueueItem{
+		{ID: "dead", MediaID: "m1", Priority: 2, Status: "dead_letter", RetryCount: 6, CreatedAt: now,
+			ErrorHistory: []storage.AttemptError{{At: now, Message: "connection reset"}}},
+		{ID: "gone", MediaID: "m2", Priority: 1, Status: "failed", CreatedAt: now},
+		{ID: "stale", MediaID: "m3", Priority: 3, Status: "dead_letter", CreatedAt: now},
+		{ID: "requeued", MediaID: "m3", Priority: 3, Status: "queued", CreatedAt: now},
+		{ID: "busy", MediaID: "m4", Priority: 3, Status: "downloading", CreatedAt: now},
+	} {
+		require.NoError(t, store.AddQueueItem(item))
+	}
+
+	failed, err := manager.FailedJobs()
+	require.NoError(t, err)
+	var ids []string
+	for _, item := range failed {
+		ids = append(ids, item.ID)
+	}
+	assert.ElementsMatch(t, []string{"dead", "gone", "stale"}, ids)
+
+	assert.ErrorIs(t, manager.RetryFailedJob("busy"), ErrQueueItemState)
+	assert.ErrorIs(t, manager.RetryFailedJob("missing"), ErrQueueItemNotFound)
+
+	require.NoError(t, manager.RetryFailedJob("dead"))
+	item, err := manager.findQueueItem("dead")
+	require.NoError(t, err)
+	assert.Equal(t, "queued", item.Status)
+	assert.Zero(t, item.RetryCount)
+	assert.Len(t, item.ErrorHistory, 1, "the error history is kept")
+
+	retried, err := manager.RetryFailedJobs()
+	require.NoError(t, err)
+	assert.Equal(t, 2, retried)
+	item, err = manager.findQueueItem("gone")
+	require.NoError(t, err)
+	assert.Equal(t, "queued", item.Status)
+	_, err = manager.findQueueItem("stale")
+	assert.ErrorIs(t, err, ErrQueueItemNotFound, "media queued again since is not downloaded twice")
+
+	failed, err = manager.FailedJobs()
+	require.NoError(t, err)
+	assert.Empty(t, failed)
+}
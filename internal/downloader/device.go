@@ -0,0 +1,103 @@
+package downloader
+
+import (
+	"strings"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// Device classes recorded for playback starts and used to pick the quality
+// variant of predicted downloads.
+const (
+	DevicePhone   = "phone"
+	DeviceTablet  = "tablet"
+	DeviceTV      = "tv"
+	DeviceDesktop = "desktop"
+)
+
+// minDevicePlays is how many playback starts an hour of day needs before
+// its dominant device is trusted as a prediction.
+const minDevicePlays = 3
+
+// ClassifyUserAgent maps a client User-Agent to a device class. It returns
+// an empty string when the device cannot be identified.
+func ClassifyUserAgent(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	switch {
+	case ua == "":
+		return ""
+	case containsAny(ua, "smart-tv", "smarttv", "tizen", "webos", "web0s", "roku",
+		"appletv", "tvos", "android tv", "googletv", "crkey", "bravia", " aft"):
+		return DeviceTV
+	case containsAny(ua, "ipad", "tablet"),
+		strings.Contains(ua, "android") && !strings.Contains(ua, "mobile"):
+		return DeviceTablet
+	case containsAny(ua, "iphone", "ipod", "windows phone", "mobile"):
+		return DevicePhone
+	case containsAny(ua, "windows", "macintosh", "x11", "linux", "cros"):
+		return DeviceDesktop
+	}
+
+	return ""
+}
+
+// containsAny reports whether s contains any of the substrings.
+func containsAny(s string, substrs ...string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// PredictDevice returns the device class the user is most likely to watch
+// on next: the dominant device of the first hour of day, starting from now,
+// with enough recorded playback. It returns an empty string when there is
+// not enough history.
+func PredictDevice(usage []*storage.DeviceUsage, now time.Time) string {
+	for offset := 0; offset < 24; offset++ {
+		hour := (now.Hour() + offset) % 24
+
+		total := 0
+		best, bestPlays := "", 0
+		for _, device := range usage {
+			plays := device.Hours[hour]
+			total += plays
+			if plays > bestPlays || (plays == bestPlays && plays > 0 && device.Class < best) {
+				best, bestPlays = device.Class, plays
+			}
+		}
+
+		if total >= minDevicePlays {
+			return best
+		}
+	}
+
+	return ""
+}
+
+// PredictedQuality returns the quality variant to cache predicted items in
+// and the device it was chosen for. An empty quality means the original,
+// which is always used unless adaptive quality is enabled.
+func (p *Predictor) PredictedQuality(now time.Time) (string, string) {
+	if !p.config.AdaptiveQuality || p.storage == nil {
+		return "", ""
+	}
+
+	usage, err := p.storage.GetDeviceUsage()
+	if err != nil {
+		p.logger.Warn("Failed to load device usage", "error", err)
+		return "", ""
+	}
+
+	device := PredictDevice(usage, now)
+	quality := p.config.DeviceQuality[device]
+	if quality == "original" {
+		quality = ""
+	}
+
+	return quality, device
+}
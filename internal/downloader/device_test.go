@@ -0,0 +1,90 @@
+package downloader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+func TestClassifyUserAgent(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      string
+	}{
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148", DevicePhone},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36", DevicePhone},
+		{"Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X) AppleWebKit/605.1.15", DeviceTablet},
+		{"Mozilla/5.0 (Linux; Android 13; SM-X200) AppleWebKit/537.36 Chrome/120.0 Safari/537.36", DeviceTablet},
+		{"Mozilla/5.0 (SMART-TV; Linux; Tizen 6.0) AppleWebKit/537.36", DeviceTV},
+		{"Mozilla/5.0 (Web0S; Linux/SmartTV) AppleWebKit/537.36", DeviceTV},
+		{"Mozilla/5.0 (Linux; Android 9; AFTMM Build/PS7233) AppleWebKit/537.36", DeviceTV},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0", DeviceDesktop},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15", DeviceDesktop},
+		{"curl/8.4.0", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ClassifyUserAgent(tt.userAgent), tt.userAgent)
+	}
+}
+
+func TestPredictDevice(t *testing.T) {
+	phone := &storage.DeviceUsage{Class: DevicePhone}
+	phone.Hours[7] = 5
+	tv := &storage.DeviceUsage{Class: DeviceTV}
+	tv.Hours[20] = 4
+	tv.Hours[7] = 1
+	usage := []*storage.DeviceUsage{phone, tv}
+
+	at := func(hour int) time.Time { return time.Date(2024, 3, 1, hour, 30, 0, 0, time.Local) }
+
+	assert.Equal(t, DevicePhone, PredictDevice(usage, at(2)), "next active hour is the morning")
+	assert.Equal(t, DeviceTV, PredictDevice(usage, at(12)), "next active hour is the evening")
+	assert.Equal(t, DevicePhone, PredictDevice(usage, at(22)), "wraps past midnight")
+	assert.Empty(t, PredictDevice(nil, at(12)))
+
+	// Too little history at any hour to trust
+	sparse := &storage.DeviceUsage{Class: DeviceTablet}
+	sparse.Hours[9] = minDevicePlays - 1
+	assert.Empty(t, PredictDevice([]*storage.DeviceUsage{sparse}, at(8)))
+}
+
+func TestReconcileQueueUsesDeviceQuality(t *testing.T) {
+	predictor, sm, _ := newReconcileTestPredictor(t)
+	ctx := context.Background()
+
+	predictor.config.AdaptiveQuality = true
+	predictor.config.DeviceQuality = map[string]string{DevicePhone: "720p", DeviceTV: "original"}
+
+	// Every playback so far started on a phone in the current hour
+	for i := 0; i < minDevicePlays; i++ {
+		require.NoError(t, sm.RecordDevicePlayback(DevicePhone, time.Now()))
+	}
+
+	quality, device := predictor.PredictedQuality(time.Now())
+	assert.Equal(t, "720p", quality)
+	assert.Equal(t, DevicePhone, device)
+
+	_, err := predictor.ReconcileQueue(ctx, []PredictionResult{{MediaID: "next", Priority: 3}})
+	require.NoError(t, err)
+	assert.Equal(t, "720p", queuedByMedia(t, sm)["next"].Quality)
+
+	// Watching on the TV instead caches the original
+	for i := 0; i < 2*minDevicePlays; i++ {
+		require.NoError(t, sm.RecordDevicePlayback(DeviceTV, time.Now()))
+	}
+	_, err = predictor.ReconcileQueue(ctx, []PredictionResult{{MediaID: "next", Priority: 3}, {MediaID: "later", Priority: 4}})
+	require.NoError(t, err)
+	assert.Empty(t, queuedByMedia(t, sm)["later"].Quality)
+
+	// Disabled adaptive quality always caches the original
+	predictor.config.AdaptiveQuality = false
+	quality, _ = predictor.PredictedQuality(time.Now())
+	assert.Empty(t, quality)
+}
@@ -0,0 +1,101 @@
+package downloader
+
+import (
+	"context"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/notify"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// diskCheckInterval limits how often disk health is re-measured while
+// dispatching speculative downloads.
+const diskCheckInterval = time.Minute
+
+// diskCheckTimeout bounds a disk health check, smartctl included.
+const diskCheckTimeout = 30 * time.Second
+
+// notifyTimeout bounds delivery of a single disk health alert.
+const notifyTimeout = 30 * time.Second
+
+// DiskHealth returns the most recent cache disk health, refreshing it if the
+// cached result is stale. Returns nil if disk health cannot be measured.
+// The check, which may run smartctl, happens without diskMu held: while
+// one is under way other callers get the previous result.
+func (m *Manager) DiskHealth() *storage.DiskHealth {
+	m.diskMu.Lock()
+	if m.diskChecking || (m.diskHealth != nil && time.Since(m.diskHealth.CheckedAt) < diskCheckInterval) {
+		defer m.diskMu.Unlock()
+		return m.diskHealth
+	}
+	m.diskChecking = true
+	m.diskMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(m.ctx, diskCheckTimeout)
+	health, err := m.storage.CheckDiskHealth(ctx)
+	cancel()
+
+	m.diskMu.Lock()
+	defer m.diskMu.Unlock()
+	m.diskChecking = false
+
+	if err != nil {
+		m.logger.Debug("Failed to check disk health", "error", err)
+		return m.diskHealth
+	}
+
+	previous := m.diskHealth
+	m.diskHealth = health
+
+	// Notify only on state transitions to avoid repeating the same warning
+	if previous == nil || previous.Status != health.Status {
+		if health.Status != storage.DiskStatusOK {
+			m.logger.Warn("Cache disk unhealthy, pausing speculative downloads",
+				"status", health.Status,
+				"message", health.Message,
+				"free_bytes", health.FreeBytes,
+				"smart_status", health.SMARTStatus)
+			m.reportProgress("", 0, "disk_warning", health.Message)
+			// A full or failing disk needs attention even during quiet hours
+			m.sendNotification(&notify.Notification{
+				Title:    "Cache disk unhealthy: " + string(health.Status),
+				Body:     health.Message + "\nSpeculative downloads are paused.",
+				Severity: notify.SeverityCritical,
+			})
+		} else if previous != nil {
+			m.logger.Info("Cache disk healthy again, resuming speculative downloads",
+				"free_bytes", health.FreeBytes)
+			m.sendNotification(&notify.Notification{
+				Title:    "Cache disk healthy again",
+				Body:     "Speculative downloads have resumed.",
+				Severity: notify.SeverityInfo,
+			})
+		}
+	}
+
+	return health
+}
+
+// speculativePaused reports whether speculative (Priority 3-4) downloads
+// should be held back because the cache disk is failing or nearly full.
+func (m *Manager) speculativePaused() bool {
+	health := m.DiskHealth()
+	return health != nil && health.Status != storage.DiskStatusOK
+}
+
+// sendNotification delivers n in the background so slow sinks never block
+// download dispatch. Callers must hold diskMu.
+func (m *Manager) sendNotification(n *notify.Notification) {
+	notifier := m.notifier
+	if notifier == nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+		if err := notifier.Notify(ctx, n); err != nil {
+			m.logger.Warn("Failed to send disk health notification", "error", err)
+		}
+	}()
+}
@@ -0,0 +1,80 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/notify"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestSpeculativeDownloadsPauseOnLowDisk(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cacheCfg := &config.CacheConfig{
+		Directory:     t.TempDir(),
+		MaxSizeGB:     1,
+		MetadataStore: "boltdb",
+		MinFreeGB:     1 << 30, // more than any disk has free
+	}
+	sm, err := storage.NewManager(cacheCfg, logger)
+	require.NoError(t, err)
+	defer sm.Close()
+
+	manager := New(&config.DownloadConfig{Workers: 1, RateLimitMbps: 10}, sm, logger)
+	notifications := make(chan *notify.Notification, 1)
+	manager.SetNotifier(notifierFunc(func(ctx context.Context, n *notify.Notification) error {
+		notifications <- n
+		return nil
+	}))
+
+	health := manager.DiskHealth()
+	if health == nil {
+		t.Skip("disk usage not supported on this platform")
+	}
+	assert.Equal(t, storage.DiskStatusLowSpace, health.Status)
+
+	select {
+	case n := <-notifications:
+		assert.Equal(t, notify.SeverityCritical, n.Severity, "disk alerts bypass quiet hours")
+	case <-time.After(time.Second):
+		t.Fatal("expected a disk health notification")
+	}
+
+	speculative := &DownloadJob{ID: "spec", MediaID: "m1", Priority: 4, CreatedAt: time.Now()}
+	require.NoError(t, manager.AddJob(speculative))
+	manager.loadJobsFromQueue()
+	assert.Zero(t, manager.jobs.len(), "speculative job should stay queued")
+
+	item, err := sm.FindActiveQueueItem("m1")
+	require.NoError(t, err)
+	assert.Equal(t, "queued", item.Status)
+
+	urgent := &DownloadJob{ID: "next", MediaID: "m2", Priority: 1, CreatedAt: time.Now()}
+	require.NoError(t, manager.AddJob(urgent))
+	assert.Equal(t, 1, manager.jobs.len(), "non-speculative jobs are not paused")
+}
+
+type notifierFunc func(ctx context.Context, n *notify.Notification) error
+
+func (f notifierFunc) Notify(ctx context.Context, n *notify.Notification) error {
+	return f(ctx, n)
+}
+
+func TestDiskHealthDuringCheck(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	manager := New(&config.DownloadConfig{Workers: 1}, nil, logger)
+
+	// While another caller runs a check, possibly a slow smartctl, the
+	// previous result is returned rather than waiting for it
+	stale := &storage.DiskHealth{Status: storage.DiskStatusOK, CheckedAt: time.Now().Add(-time.Hour)}
+	manager.diskHealth = stale
+	manager.diskChecking = true
+	assert.Same(t, stale, manager.DiskHealth())
+}
@@ -0,0 +1,35 @@
+package downloader
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// recordEvent adds event to the event log. The log is only there to
+// explain what happened, so failing to write it does not fail the action.
+func recordEvent(store storage.EventStore, logger *slog.Logger, event *storage.Event) {
+	if err := store.RecordEvent(event); err != nil {
+		logger.Debug("Failed to record event", "kind", event.Kind, "media_id", event.MediaID, "error", err)
+	}
+}
+
+// recordFailure logs a download that gave up in the event log.
+func (m *Manager) recordFailure(item *storage.QueueItem, httpStatus int) {
+	details := map[string]interface{}{
+		"status":   item.Status,
+		"attempts": item.RetryCount + 1,
+		"priority": item.Priority,
+		"source":   item.Source,
+	}
+	if httpStatus != 0 {
+		details["http_status"] = httpStatus
+	}
+	recordEvent(m.storage, m.logger, &storage.Event{
+		Kind:    storage.EventDownloadFailed,
+		MediaID: item.MediaID,
+		Message: fmt.Sprintf("Download gave up after %d attempts: %s", item.RetryCount+1, item.ErrorMessage),
+		Details: details,
+	})
+}
@@ -0,0 +1,69 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestDownloadEvents(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	manager := New(&config.DownloadConfig{Workers: 1, RetryAttempts: 3, RetryDelay: time.Second}, store, logger)
+
+	now := time.Now()
+	manager.handleResult(&DownloadResult{
+		Job:         &DownloadJob{ID: "j1", MediaID: "m1", Priority: 3, Source: SourcePrediction, CreatedAt: now},
+		Success:     true,
+		Size:        1 << 20,
+		CompletedAt: now,
+	})
+	// Retried failures are not logged; giving up is
+	manager.handleResult(&DownloadResult{
+		Job:   &DownloadJob{ID: "j2", MediaID: "m2", Priority: 2, CreatedAt: now},
+		Error: errors.New("connection reset by peer"),
+	})
+	manager.handleResult(&DownloadResult{
+		Job:        &DownloadJob{ID: "j3", MediaID: "m3", Priority: 2, CreatedAt: now},
+		Error:      errors.New("HTTP error: 404"),
+		HTTPStatus: http.StatusNotFound,
+	})
+
+	events, err := store.GetEvents(storage.EventFilter{})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	failed, completed := events[0], events[1]
+	assert.Equal(t, storage.EventDownloadCompleted, completed.Kind)
+	assert.Equal(t, "m1", completed.MediaID)
+	assert.Equal(t, SourcePrediction, completed.Details["source"])
+
+	assert.Equal(t, storage.EventDownloadFailed, failed.Kind)
+	assert.Equal(t, "m3", failed.MediaID)
+	assert.EqualValues(t, http.StatusNotFound, failed.Details["http_status"])
+	assert.Contains(t, failed.Message, "HTTP error: 404")
+}
+
+func TestPredictionCycleEvent(t *testing.T) {
+	predictor, sm, _ := newReconcileTestPredictor(t)
+
+	_, err := predictor.RunPredictionCycle(context.Background(), "alice")
+	require.NoError(t, err)
+
+	events, err := sm.GetEvents(storage.EventFilter{Kinds: []string{storage.EventSync}})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "alice", events[0].Details["user_id"])
+	assert.EqualValues(t, 0, events[0].Details["added"])
+}
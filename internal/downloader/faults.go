@@ -0,0 +1,62 @@
+package downloader
+
+import (
+	"github.com/opd-ai/go-jf-watch/internal/chaos"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// SetFaultInjector routes downloads and queue storage through inj, so
+// retries and recovery can be exercised against injected failures. Call it
+// before Start; a nil injector leaves the manager untouched.
+func (m *Manager) SetFaultInjector(inj *chaos.Injector) {
+	if inj == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.faults = inj
+	m.storage = &faultyStore{Store: m.storage, faults: inj}
+}
+
+// faultyStore fails queue and download record operations at the injector's
+// storage error rate; everything else passes through.
+type faultyStore struct {
+	Store
+	faults *chaos.Injector
+}
+
+func (s *faultyStore) AddQueueItem(item *storage.QueueItem) error {
+	if err := s.faults.StorageError("add queue item"); err != nil {
+		return err
+	}
+	return s.Store.AddQueueItem(item)
+}
+
+func (s *faultyStore) GetNextQueueItem() (*storage.QueueItem, error) {
+	if err := s.faults.StorageError("get next queue item"); err != nil {
+		return nil, err
+	}
+	return s.Store.GetNextQueueItem()
+}
+
+func (s *faultyStore) UpdateQueueItem(item *storage.QueueItem) error {
+	if err := s.faults.StorageError("update queue item"); err != nil {
+		return err
+	}
+	return s.Store.UpdateQueueItem(item)
+}
+
+func (s *faultyStore) RemoveQueueItem(itemID string) error {
+	if err := s.faults.StorageError("remove queue item"); err != nil {
+		return err
+	}
+	return s.Store.RemoveQueueItem(itemID)
+}
+
+func (s *faultyStore) AddDownloadRecord(record *storage.DownloadRecord) error {
+	if err := s.faults.StorageError("add download record"); err != nil {
+		return err
+	}
+	return s.Store.AddDownloadRecord(record)
+}
@@ -0,0 +1,77 @@
+package downloader
+
+import (
+	"context"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// SetMetadataSource sets where the predictor looks up metadata of watched
+// items that are not in storage, such as items watched before library sync
+// was enabled.
+func (p *Predictor) SetMetadataSource(source MetadataSource) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.metadata = source
+}
+
+// backfillGenreMetadata fetches and stores the metadata of items in the
+// viewing history that storage has none for. Failures are logged and the
+// items are left out of genre analysis until the next cycle. Callers must
+// hold p.mu.
+func (p *Predictor) backfillGenreMetadata(ctx context.Context) {
+	if p.metadata == nil {
+		return
+	}
+
+	seen := make(map[string]bool)
+	var missing []string
+	for _, session := range p.viewingHistory {
+		if seen[session.MediaID] {
+			continue
+		}
+		seen[session.MediaID] = true
+		if _, err := p.storage.GetMediaMetadata(session.MediaID); err != nil {
+			missing = append(missing, session.MediaID)
+		}
+	}
+
+	now := time.Now()
+	for start := 0; start < len(missing); start += metadataRefreshBatch {
+		end := min(start+metadataRefreshBatch, len(missing))
+
+		items, err := p.metadata.GetItemsByID(ctx, missing[start:end])
+		if err != nil {
+			p.logger.Warn("Failed to fetch metadata of watched items", "error", err)
+			return
+		}
+
+		for _, item := range items {
+			if err := p.storeItemMetadata(item, now); err != nil {
+				p.logger.Warn("Failed to store metadata of watched item",
+					"media_id", item.ID, "error", err)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		p.logger.Debug("Backfilled metadata of watched items", "requested", len(missing))
+	}
+}
+
+// storeItemMetadata stores the metadata of an item fetched from Jellyfin,
+// so that later pipeline steps and library views can look it up.
+func (p *Predictor) storeItemMetadata(item jellyfin.MediaItem, now time.Time) error {
+	metadata := &storage.MediaMetadata{
+		ID:         item.ID,
+		JellyfinID: item.ID,
+		Type:       jellyfin.CacheMediaType(item.Type),
+		Size:       item.Size,
+		Container:  item.Container,
+		LastSynced: now,
+	}
+	jellyfin.ApplyMetadata(metadata, item)
+	return p.storage.AddMediaMetadata(metadata)
+}
@@ -0,0 +1,68 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestGenrePreferencesFromMetadata(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	cfg := &config.PredictionConfig{HistoryDays: 30, SyncInterval: time.Hour, MinConfidence: 0.7}
+	predictor := NewPredictor(store, cfg, logger)
+
+	for _, metadata := range []*storage.MediaMetadata{
+		{ID: "m1", JellyfinID: "m1", Type: "movie", Genres: []string{"Sci-Fi", "Drama"}},
+		{ID: "m2", JellyfinID: "m2", Type: "movie", Genres: []string{"Sci-Fi"}},
+		{ID: "m3", JellyfinID: "m3", Type: "movie", Genres: []string{"Horror"}},
+	} {
+		require.NoError(t, store.AddMediaMetadata(metadata))
+	}
+
+	// m4 was watched before library sync stored it
+	source := jellyfintest.New("http://jellyfin.local")
+	source.Items = map[string]jellyfin.MediaItem{
+		"m4": {ID: "m4", Name: "Alien", Type: "Movie", Genres: []string{"Horror", "Sci-Fi"}},
+	}
+	predictor.SetMetadataSource(source)
+
+	predictor.viewingHistory = []ViewingSession{
+		{MediaID: "m1", Completed: true},
+		{MediaID: "m2", Completed: true},
+		{MediaID: "m3"}, // abandoned, counts half
+		{MediaID: "m4", Completed: true},
+		{MediaID: "unknown", Completed: true},
+	}
+
+	predictor.backfillGenreMetadata(context.Background())
+	stored, err := store.GetMediaMetadata("m4")
+	require.NoError(t, err)
+	assert.Equal(t, "movie", stored.Type)
+	assert.Equal(t, []string{"Horror", "Sci-Fi"}, stored.Genres)
+
+	predictor.analyzeGenrePreferences()
+	assert.Equal(t, []string{"Sci-Fi", "Horror", "Drama"}, predictor.preferences.PreferredGenres)
+}
+
+func TestGenrePreferencesWithoutSource(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	predictor := NewPredictor(store, &config.PredictionConfig{HistoryDays: 30}, logger)
+	predictor.viewingHistory = []ViewingSession{{MediaID: "unknown", Completed: true}}
+
+	predictor.backfillGenreMetadata(context.Background())
+	predictor.analyzeGenrePreferences()
+	assert.Empty(t, predictor.preferences.PreferredGenres)
+}
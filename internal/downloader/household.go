@@ -0,0 +1,66 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// completionThreshold is the share of an item that must be watched for the
+// viewing to count as completed.
+const completionThreshold = 0.85
+
+// HouseholdUsers returns the configured household user IDs.
+func (p *Predictor) HouseholdUsers() []string {
+	return append([]string(nil), p.config.HouseholdUsers...)
+}
+
+// RecordWatchTogether records playback progress of mediaID in the local
+// player for every user watching together, so each of their predictors
+// sees the same episode as watched. Repeated reports for the same playback
+// (same startedAt) update a single viewing session per user.
+func (p *Predictor) RecordWatchTogether(ctx context.Context, mediaID string, userIDs []string, startedAt time.Time, position, duration time.Duration) error {
+	if len(userIDs) == 0 {
+		return fmt.Errorf("at least one user is required")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	session := storage.ViewingSession{
+		MediaID:     mediaID,
+		StartTime:   startedAt,
+		EndTime:     time.Now(),
+		Duration:    int64(duration.Seconds()),
+		WatchedTime: int64(position.Seconds()),
+		Completed:   duration > 0 && float64(position) >= completionThreshold*float64(duration),
+	}
+
+	// Series details let the predictor move each viewer to the next episode
+	if metadata, err := p.storage.GetMediaMetadata(mediaID); err == nil {
+		session.MediaType = metadata.Type
+		session.SeriesID = metadata.SeriesID
+		session.Season = metadata.SeasonNumber
+		session.Episode = metadata.EpisodeNumber
+	}
+
+	for _, userID := range userIDs {
+		if err := p.storage.UpsertViewingSession(userID, session); err != nil {
+			return fmt.Errorf("failed to record progress for user %s: %w", userID, err)
+		}
+
+		// The cached history is stale once its user's progress changes
+		if userID == p.historyUser {
+			p.lastSync = time.Time{}
+		}
+	}
+
+	p.logger.Debug("Recorded shared playback progress",
+		"media_id", mediaID,
+		"users", userIDs,
+		"completed", session.Completed)
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package downloader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcileQueueSharedAcrossUsers(t *testing.T) {
+	predictor, sm, queuer := newReconcileTestPredictor(t)
+	ctx := context.Background()
+
+	// Alice's predictions queue the shared show and a show of her own
+	summary, err := predictor.reconcileQueue(ctx, "alice", []PredictionResult{
+		{MediaID: "shared", Priority: 2},
+		{MediaID: "alice-only", Priority: 3},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.Added)
+
+	// Bob is predicted to watch the shared show too, less urgently
+	summary, err = predictor.reconcileQueue(ctx, "bob", []PredictionResult{{MediaID: "shared", Priority: 4}})
+	require.NoError(t, err)
+	assert.Equal(t, 0, summary.Added, "one copy serves both users")
+	assert.Equal(t, 1, summary.Shared)
+	assert.Equal(t, 0, summary.Cancelled, "alice's own prediction is left alone")
+	assert.Equal(t, 2, queuer.calls)
+
+	queued := queuedByMedia(t, sm)
+	require.Contains(t, queued, "alice-only")
+	assert.ElementsMatch(t, []string{"alice", "bob"}, queued["shared"].Users)
+	assert.Equal(t, 2, queued["shared"].Priority, "shared items are never made less urgent")
+
+	// Alice moves on; bob still wants the shared show
+	summary, err = predictor.reconcileQueue(ctx, "alice", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Cancelled)
+
+	queued = queuedByMedia(t, sm)
+	assert.NotContains(t, queued, "alice-only")
+	require.Contains(t, queued, "shared")
+	assert.Equal(t, []string{"bob"}, queued["shared"].Users)
+
+	// Once nobody wants it, it is cancelled
+	_, err = predictor.reconcileQueue(ctx, "bob", nil)
+	require.NoError(t, err)
+	assert.NotContains(t, queuedByMedia(t, sm), "shared")
+}
+
+func TestRunHouseholdCycle(t *testing.T) {
+	predictor, _, _ := newReconcileTestPredictor(t)
+	predictor.config.HouseholdUsers = []string{"alice", "bob"}
+
+	summary, err := predictor.RunHouseholdCycle(context.Background())
+	require.NoError(t, err)
+	assert.NotNil(t, summary)
+	assert.Equal(t, "bob", predictor.historyUser, "each user's history is loaded in turn")
+	assert.Equal(t, []string{"alice", "bob"}, predictor.HouseholdUsers())
+}
+
+func TestRecordWatchTogether(t *testing.T) {
+	predictor, sm, _ := newReconcileTestPredictor(t)
+	ctx := context.Background()
+	startedAt := time.Now().Add(-40 * time.Minute).Truncate(time.Second)
+
+	require.Error(t, predictor.RecordWatchTogether(ctx, "ep1", nil, startedAt, 0, time.Hour))
+
+	// Progress is reported during playback and again at the end
+	require.NoError(t, predictor.RecordWatchTogether(ctx, "ep1", []string{"alice", "bob"}, startedAt, 20*time.Minute, 45*time.Minute))
+	require.NoError(t, predictor.RecordWatchTogether(ctx, "ep1", []string{"alice", "bob"}, startedAt, 40*time.Minute, 45*time.Minute))
+
+	for _, user := range []string{"alice", "bob"} {
+		history, err := sm.GetViewingHistory(user, 30)
+		require.NoError(t, err)
+		require.Len(t, history, 1, "one session per playback for %s", user)
+		assert.True(t, history[0].Completed, "%s finished the episode", user)
+		assert.Equal(t, int64(40*60), history[0].WatchedTime)
+	}
+
+	history, err := sm.GetViewingHistory("carol", 30)
+	require.NoError(t, err)
+	assert.Empty(t, history, "users not watching are not updated")
+}
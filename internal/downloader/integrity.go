@@ -0,0 +1,165 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// integrityRequeuePriority is the priority lost items are downloaded again
+// at. They were cached before, but nobody is waiting on them right now.
+const integrityRequeuePriority = 3
+
+// orphanGracePeriod is how old a file without a download record must be
+// before a scan deletes it, leaving downloads that finish mid-scan alone.
+const orphanGracePeriod = time.Hour
+
+// IntegrityStore is the storage an integrity scan checks and repairs
+// (implemented by storage.Manager).
+type IntegrityStore interface {
+	VerifyCache(ctx context.Context) (*storage.VerifyResult, error)
+	RemoveOrphanedFiles(ctx context.Context, before time.Time) (*storage.OrphanResult, error)
+}
+
+// IntegrityReport summarizes an integrity scan.
+type IntegrityReport struct {
+	StartedAt     time.Time `json:"started_at"`
+	FinishedAt    time.Time `json:"finished_at"`
+	Checked       int       `json:"checked"`
+	Missing       int       `json:"missing"`        // Records whose file was gone
+	Corrupt       int       `json:"corrupt"`        // Files that failed size or checksum checks
+	Requeued      int       `json:"requeued"`       // Missing and corrupt items queued for download again
+	Orphaned      int       `json:"orphaned"`       // Files without a download record that were deleted
+	OrphanedBytes int64     `json:"orphaned_bytes"` // Space freed by deleting them
+	Errors        []string  `json:"errors,omitempty"`
+}
+
+// IntegrityScanner periodically checks the cache against its download
+// records and repairs what it finds: records whose file is missing or
+// corrupt are dropped and the item queued for download again, and files no
+// record points to are deleted.
+type IntegrityScanner struct {
+	store    IntegrityStore
+	queuer   DownloadQueuer
+	interval time.Duration
+	logger   *slog.Logger
+
+	// now is stubbed by tests
+	now func() time.Time
+
+	// scanMu serializes scans
+	scanMu sync.Mutex
+
+	mu   sync.RWMutex
+	last *IntegrityReport
+}
+
+// NewIntegrityScanner creates a scanner that scans every interval once Run
+// is called. An interval of 0 disables periodic scans; Scan still works.
+func NewIntegrityScanner(store IntegrityStore, queuer DownloadQueuer, interval time.Duration, logger *slog.Logger) *IntegrityScanner {
+	return &IntegrityScanner{
+		store:    store,
+		queuer:   queuer,
+		interval: interval,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Enabled reports whether periodic scans are turned on.
+func (s *IntegrityScanner) Enabled() bool {
+	return s.interval > 0
+}
+
+// LastReport returns the report of the most recent scan, or nil if none
+// has run yet.
+func (s *IntegrityScanner) LastReport() *IntegrityReport {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.last
+}
+
+// Scan verifies every cached file, re-queues the items that were lost and
+// deletes orphaned files. Items in excluded libraries are not re-queued.
+// Problems with single items are reported in the result rather than
+// failing the scan.
+func (s *IntegrityScanner) Scan(ctx context.Context) (*IntegrityReport, error) {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+
+	report := &IntegrityReport{StartedAt: s.now()}
+
+	verified, err := s.store.VerifyCache(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify cache: %w", err)
+	}
+	report.Checked = verified.Checked
+	report.Missing = verified.Missing
+	report.Corrupt = verified.Corrupt
+	report.Errors = append(report.Errors, verified.Errors...)
+
+	for _, mediaID := range verified.Lost {
+		_, err := s.queuer.QueueDownloadWithSource(ctx, mediaID, integrityRequeuePriority, SourceIntegrity)
+		if errors.Is(err, ErrLibraryExcluded) {
+			continue
+		}
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: failed to re-queue: %v", mediaID, err))
+			continue
+		}
+		report.Requeued++
+	}
+
+	orphans, err := s.store.RemoveOrphanedFiles(ctx, report.StartedAt.Add(-orphanGracePeriod))
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove orphaned files: %w", err)
+	}
+	report.Orphaned = orphans.Removed
+	report.OrphanedBytes = orphans.Bytes
+	report.Errors = append(report.Errors, orphans.Errors...)
+
+	report.FinishedAt = s.now()
+
+	s.mu.Lock()
+	s.last = report
+	s.mu.Unlock()
+
+	s.logger.Info("Cache integrity scan complete",
+		"checked", report.Checked,
+		"missing", report.Missing,
+		"corrupt", report.Corrupt,
+		"requeued", report.Requeued,
+		"orphaned", report.Orphaned,
+		"errors", len(report.Errors))
+
+	return report, nil
+}
+
+// Run scans the cache every interval until ctx is cancelled. The first scan
+// waits a full interval, keeping startup quiet. It is a no-op when periodic
+// scans are disabled.
+func (s *IntegrityScanner) Run(ctx context.Context) {
+	if !s.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := s.Scan(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("Cache integrity scan failed", "error", err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// fakeIntegrityStore reports a fixed verification result and records the
+// orphan cutoff it was asked for.
+type fakeIntegrityStore struct {
+	verified *storage.VerifyResult
+	orphans  *storage.OrphanResult
+	before   time.Time
+}
+
+func (f *fakeIntegrityStore) VerifyCache(ctx context.Context) (*storage.VerifyResult, error) {
+	return f.verified, nil
+}
+
+func (f *fakeIntegrityStore) RemoveOrphanedFiles(ctx context.Context, before time.Time) (*storage.OrphanResult, error) {
+	f.before = before
+	return f.orphans, nil
+}
+
+// integrityQueuer records re-queued items and rejects a few.
+type integrityQueuer struct {
+	queued  map[string]string
+	reject  map[string]error
+	lastPri int
+}
+
+func (q *integrityQueuer) QueueDownload(ctx context.Context, mediaID string, priority int) (string, error) {
+	return q.QueueDownloadWithSource(ctx, mediaID, priority, SourceManual)
+}
+
+func (q *integrityQueuer) QueueDownloadWithSource(ctx context.Context, mediaID string, priority int, source string) (string, error) {
+	if err := q.reject[mediaID]; err != nil {
+		return "", err
+	}
+	q.queued[mediaID] = source
+	q.lastPri = priority
+	return mediaID, nil
+}
+
+func TestIntegrityScan(t *testing.T) {
+	store := &fakeIntegrityStore{
+		verified: &storage.VerifyResult{
+			Checked: 10, Missing: 2, Corrupt: 2,
+			Lost:   []string{"gone", "excluded", "broken", "full"},
+			Errors: []string{"unreadable: permission denied"},
+		},
+		orphans: &storage.OrphanResult{Removed: 3, Bytes: 4096},
+	}
+	queuer := &integrityQueuer{
+		queued: make(map[string]string),
+		reject: map[string]error{
+			"excluded": fmt.Errorf("%w: excluded", ErrLibraryExcluded),
+			"full":     ErrQueueFull,
+		},
+	}
+	scanner := NewIntegrityScanner(store, queuer, 0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	scanner.now = func() time.Time { return now }
+
+	assert.False(t, scanner.Enabled())
+	assert.Nil(t, scanner.LastReport())
+
+	report, err := scanner.Scan(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"gone": SourceIntegrity, "broken": SourceIntegrity}, queuer.queued)
+	assert.Equal(t, integrityRequeuePriority, queuer.lastPri)
+	assert.Equal(t, now.Add(-orphanGracePeriod), store.before)
+
+	assert.Equal(t, 10, report.Checked)
+	assert.Equal(t, 2, report.Missing)
+	assert.Equal(t, 2, report.Corrupt)
+	assert.Equal(t, 2, report.Requeued)
+	assert.Equal(t, 3, report.Orphaned)
+	assert.Equal(t, int64(4096), report.OrphanedBytes)
+	// Excluded libraries are skipped silently; other failures are reported
+	assert.Len(t, report.Errors, 2)
+	assert.Contains(t, report.Errors[1], "full")
+
+	assert.Same(t, report, scanner.LastReport())
+}
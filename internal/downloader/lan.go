@@ -0,0 +1,75 @@
+package downloader
+
+import (
+	"context"
+	"net"
+	"net/url"
+)
+
+// parseExemptNetworks parses the configured exempt CIDRs. Invalid entries
+// are skipped, since config validation has already rejected them.
+func parseExemptNetworks(cidrs []string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+// isRateLimitExempt reports whether downloads from rawURL should bypass
+// bandwidth limiting. The host must resolve exclusively to exempt addresses,
+// so a hostname that also resolves to a public address is still throttled.
+func (m *Manager) isRateLimitExempt(ctx context.Context, rawURL string) bool {
+	if !m.config.RateLimitExemption.AutoDetectLAN && len(m.exemptNets) == 0 {
+		return false
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return false
+	}
+
+	var ips []net.IP
+	if ip := net.ParseIP(parsed.Hostname()); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, parsed.Hostname())
+		if err != nil {
+			m.logger.Debug("Failed to resolve download host for rate limit exemption",
+				"host", parsed.Hostname(), "error", err)
+			return false
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+
+	if len(ips) == 0 {
+		return false
+	}
+
+	for _, ip := range ips {
+		if !m.isExemptIP(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// isExemptIP reports whether ip is a LAN address (when auto-detection is
+// enabled) or falls within one of the configured exempt networks.
+func (m *Manager) isExemptIP(ip net.IP) bool {
+	if m.config.RateLimitExemption.AutoDetectLAN &&
+		(ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast()) {
+		return true
+	}
+
+	for _, network := range m.exemptNets {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
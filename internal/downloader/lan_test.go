@@ -0,0 +1,41 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestIsRateLimitExempt(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name   string
+		cfg    config.RateLimitExemptionConfig
+		url    string
+		exempt bool
+	}{
+		{"disabled", config.RateLimitExemptionConfig{}, "http://192.168.1.10:8096/Items/1/Download", false},
+		{"auto-detect private", config.RateLimitExemptionConfig{AutoDetectLAN: true}, "http://192.168.1.10:8096/Items/1/Download", true},
+		{"auto-detect loopback", config.RateLimitExemptionConfig{AutoDetectLAN: true}, "http://127.0.0.1:8096/Items/1/Download", true},
+		{"auto-detect ipv6 ula", config.RateLimitExemptionConfig{AutoDetectLAN: true}, "http://[fd00::10]:8096/Items/1/Download", true},
+		{"auto-detect public", config.RateLimitExemptionConfig{AutoDetectLAN: true}, "https://203.0.113.5/Items/1/Download", false},
+		{"configured cidr", config.RateLimitExemptionConfig{CIDRs: []string{"203.0.113.0/24"}}, "https://203.0.113.5/Items/1/Download", true},
+		{"outside configured cidr", config.RateLimitExemptionConfig{CIDRs: []string{"203.0.113.0/24"}}, "https://198.51.100.5/Items/1/Download", false},
+		{"invalid url", config.RateLimitExemptionConfig{AutoDetectLAN: true}, "://bad", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.DownloadConfig{Workers: 1, RateLimitMbps: 10, RateLimitExemption: tt.cfg}
+			manager := New(cfg, nil, logger)
+
+			assert.Equal(t, tt.exempt, manager.isRateLimitExempt(context.Background(), tt.url))
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package downloader
+
+import (
+	"sort"
+	"strings"
+)
+
+// Confidence adjustments applied by language preference matching.
+const (
+	languageMatchBoost     = 0.1
+	languageMismatchDamper = 0.2
+)
+
+// analyzeLanguagePreferences derives preferred audio languages from the
+// metadata of completed items in the viewing history. Audio tracks count
+// fully, subtitle tracks count half, since subtitles are often incidental.
+// A configured override list always wins over derived preferences.
+func (p *Predictor) analyzeLanguagePreferences() {
+	if len(p.config.PreferredLanguages) > 0 {
+		p.preferences.PreferredLanguages = normalizeLanguages(p.config.PreferredLanguages)
+		return
+	}
+
+	scores := make(map[string]float64)
+	for _, session := range p.viewingHistory {
+		if !session.Completed {
+			continue
+		}
+
+		metadata, err := p.storage.GetMediaMetadata(session.MediaID)
+		if err != nil {
+			continue
+		}
+
+		for _, lang := range normalizeLanguages(metadata.AudioLanguages) {
+			scores[lang] += 1.0
+		}
+		for _, lang := range normalizeLanguages(metadata.SubtitleLanguages) {
+			scores[lang] += 0.5
+		}
+	}
+
+	languages := make([]string, 0, len(scores))
+	for lang := range scores {
+		languages = append(languages, lang)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if scores[languages[i]] == scores[languages[j]] {
+			return languages[i] < languages[j]
+		}
+		return scores[languages[i]] > scores[languages[j]]
+	})
+
+	// Keep the top 3 languages
+	if len(languages) > 3 {
+		languages = languages[:3]
+	}
+	p.preferences.PreferredLanguages = languages
+}
+
+// applyLanguagePreferences boosts predictions whose audio matches a preferred
+// language and dampens those that offer none of them. Speculative items
+// (Priority 3-4) without a preferred audio track are dropped entirely.
+// Predictions without known audio metadata are passed through untouched.
+func (p *Predictor) applyLanguagePreferences(predictions []PredictionResult) []PredictionResult {
+	languages := p.currentLanguages()
+	if len(languages) == 0 {
+		return predictions
+	}
+
+	preferred := make(map[string]bool, len(languages))
+	for _, lang := range languages {
+		preferred[lang] = true
+	}
+
+	result := predictions[:0]
+	for _, pred := range predictions {
+		metadata, err := p.storage.GetMediaMetadata(pred.MediaID)
+		if err != nil || len(metadata.AudioLanguages) == 0 {
+			result = append(result, pred)
+			continue
+		}
+
+		matched := false
+		for _, lang := range normalizeLanguages(metadata.AudioLanguages) {
+			if preferred[lang] {
+				matched = true
+				break
+			}
+		}
+
+		switch {
+		case matched:
+			pred.Confidence += languageMatchBoost
+			if pred.Confidence > 1.0 {
+				pred.Confidence = 1.0
+			}
+		case pred.Priority >= 3:
+			p.logger.Debug("Dropping speculative prediction without preferred audio language",
+				"media_id", pred.MediaID,
+				"audio_languages", metadata.AudioLanguages)
+			continue
+		default:
+			pred.Confidence -= languageMismatchDamper
+		}
+
+		result = append(result, pred)
+	}
+
+	return result
+}
+
+// PreferredLanguages returns the current preferred audio languages,
+// most preferred first. Used by the web UI to pick default audio tracks.
+func (p *Predictor) PreferredLanguages() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	languages := p.currentLanguages()
+	result := make([]string, len(languages))
+	copy(result, languages)
+	return result
+}
+
+// currentLanguages returns derived language preferences, falling back to the
+// configured override before any history has been analyzed.
+func (p *Predictor) currentLanguages() []string {
+	if len(p.preferences.PreferredLanguages) == 0 && len(p.config.PreferredLanguages) > 0 {
+		return normalizeLanguages(p.config.PreferredLanguages)
+	}
+	return p.preferences.PreferredLanguages
+}
+
+// normalizeLanguages lower-cases and de-duplicates language codes,
+// dropping empty and "und" (undetermined) entries.
+func normalizeLanguages(languages []string) []string {
+	seen := make(map[string]bool, len(languages))
+	normalized := make([]string, 0, len(languages))
+
+	for _, lang := range languages {
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if lang == "" || lang == "und" || seen[lang] {
+			continue
+		}
+		seen[lang] = true
+		normalized = append(normalized, lang)
+	}
+
+	return normalized
+}
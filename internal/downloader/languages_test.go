@@ -0,0 +1,63 @@
+package downloader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+func TestAnalyzeLanguagePreferences(t *testing.T) {
+	predictor, sm, _ := newReconcileTestPredictor(t)
+
+	require.NoError(t, sm.AddMediaMetadata(&storage.MediaMetadata{ID: "a", JellyfinID: "a", AudioLanguages: []string{"JPN"}, SubtitleLanguages: []string{"eng"}}))
+	require.NoError(t, sm.AddMediaMetadata(&storage.MediaMetadata{ID: "b", JellyfinID: "b", AudioLanguages: []string{"jpn", "und"}}))
+	require.NoError(t, sm.AddMediaMetadata(&storage.MediaMetadata{ID: "c", JellyfinID: "c", AudioLanguages: []string{"fra"}}))
+
+	predictor.viewingHistory = []ViewingSession{
+		{MediaID: "a", Completed: true},
+		{MediaID: "b", Completed: true},
+		{MediaID: "c", Completed: false}, // abandoned items don't count
+	}
+
+	predictor.analyzeLanguagePreferences()
+	assert.Equal(t, []string{"jpn", "eng"}, predictor.preferences.PreferredLanguages)
+
+	// Configured override wins over derived preferences
+	predictor.config.PreferredLanguages = []string{"FRA", "fra", ""}
+	predictor.analyzeLanguagePreferences()
+	assert.Equal(t, []string{"fra"}, predictor.preferences.PreferredLanguages)
+}
+
+func TestApplyLanguagePreferences(t *testing.T) {
+	predictor, sm, _ := newReconcileTestPredictor(t)
+	predictor.preferences.PreferredLanguages = []string{"eng"}
+
+	require.NoError(t, sm.AddMediaMetadata(&storage.MediaMetadata{ID: "match", JellyfinID: "match", AudioLanguages: []string{"eng", "deu"}}))
+	require.NoError(t, sm.AddMediaMetadata(&storage.MediaMetadata{ID: "next", JellyfinID: "next", AudioLanguages: []string{"deu"}}))
+	require.NoError(t, sm.AddMediaMetadata(&storage.MediaMetadata{ID: "spec", JellyfinID: "spec", AudioLanguages: []string{"deu"}}))
+
+	result := predictor.applyLanguagePreferences([]PredictionResult{
+		{MediaID: "match", Priority: 3, Confidence: 0.7},
+		{MediaID: "next", Priority: 1, Confidence: 0.9},
+		{MediaID: "spec", Priority: 4, Confidence: 0.9},
+		{MediaID: "unknown", Priority: 4, Confidence: 0.8},
+	})
+
+	require.Len(t, result, 3)
+	assert.Equal(t, "match", result[0].MediaID)
+	assert.InDelta(t, 0.8, result[0].Confidence, 0.001)
+	assert.Equal(t, "next", result[1].MediaID)
+	assert.InDelta(t, 0.7, result[1].Confidence, 0.001)
+	assert.Equal(t, "unknown", result[2].MediaID)
+	assert.InDelta(t, 0.8, result[2].Confidence, 0.001)
+}
+
+func TestPreferredLanguagesFallsBackToConfig(t *testing.T) {
+	predictor, _, _ := newReconcileTestPredictor(t)
+	predictor.config.PreferredLanguages = []string{"ENG", "jpn"}
+
+	assert.Equal(t, []string{"eng", "jpn"}, predictor.PreferredLanguages())
+}
@@ -0,0 +1,49 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// ErrLibraryExcluded is returned when queueing an item from a Jellyfin
+// library that the library filter excludes from caching.
+var ErrLibraryExcluded = errors.New("library is excluded from caching")
+
+// checkLibrary returns ErrLibraryExcluded when the item's library is not
+// allowed by filter. Items without stored metadata are allowed, since their
+// library is unknown.
+func checkLibrary(store storage.MediaStore, filter *config.LibraryFilterConfig, mediaID string) error {
+	if filter == nil {
+		return nil
+	}
+	metadata, err := store.GetMediaMetadata(mediaID)
+	if err != nil || filter.Allows(metadata.Library) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s belongs to library %q", ErrLibraryExcluded, mediaID, metadata.Library)
+}
+
+// SetLibraryFilter restricts queueing to items from libraries the filter
+// allows. Requests for other items fail with ErrLibraryExcluded.
+func (m *Manager) SetLibraryFilter(filter *config.LibraryFilterConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.libraries = filter
+}
+
+// SetLibraryFilter makes the predictor ignore viewing history and playback
+// of items from libraries the filter excludes.
+func (p *Predictor) SetLibraryFilter(filter *config.LibraryFilterConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.libraries = filter
+}
+
+// libraryAllowed reports whether predictions may draw on mediaID. Callers
+// must hold p.mu.
+func (p *Predictor) libraryAllowed(mediaID string) bool {
+	return checkLibrary(p.storage, p.libraries, mediaID) == nil
+}
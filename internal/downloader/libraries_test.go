@@ -0,0 +1,81 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// recordingQueuer records the media IDs a predictor asks to download.
+type recordingQueuer struct {
+	queued []string
+}
+
+func (q *recordingQueuer) QueueDownload(ctx context.Context, mediaID string, priority int) (string, error) {
+	return q.QueueDownloadWithSource(ctx, mediaID, priority, SourceManual)
+}
+
+func (q *recordingQueuer) QueueDownloadWithSource(ctx context.Context, mediaID string, priority int, source string) (string, error) {
+	q.queued = append(q.queued, mediaID)
+	return mediaID, nil
+}
+
+func newLibraryTestStore(t *testing.T) *storagetest.MemStore {
+	store := storagetest.New()
+	for _, metadata := range []*storage.MediaMetadata{
+		{ID: "movie", JellyfinID: "movie", Type: "movie", Library: "Movies"},
+		{ID: "birthday", JellyfinID: "birthday", Type: "movie", Library: "Home Videos"},
+		{ID: "unknown", JellyfinID: "unknown", Type: "movie"},
+	} {
+		require.NoError(t, store.AddMediaMetadata(metadata))
+	}
+	return store
+}
+
+func TestQueueDownloadRejectsExcludedLibrary(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	manager := New(&config.DownloadConfig{Workers: 1}, newLibraryTestStore(t), logger)
+	manager.running = true
+	manager.SetLibraryFilter(&config.LibraryFilterConfig{Exclude: []string{"Home Videos"}})
+
+	_, err := manager.QueueDownload(context.Background(), "birthday", 2)
+	assert.ErrorIs(t, err, ErrLibraryExcluded)
+	assert.Contains(t, err.Error(), `"Home Videos"`)
+
+	for _, id := range []string{"movie", "unknown", "not-synced"} {
+		_, err := manager.QueueDownload(context.Background(), id, 2)
+		assert.NoError(t, err, "media %s", id)
+	}
+}
+
+func TestPredictorIgnoresExcludedLibraries(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := newLibraryTestStore(t)
+	now := time.Now()
+	for _, id := range []string{"movie", "birthday"} {
+		require.NoError(t, store.StoreViewingSession("alice", storage.ViewingSession{MediaID: id, StartTime: now}))
+	}
+
+	predictor := NewPredictor(store, &config.PredictionConfig{HistoryDays: 30, SyncInterval: time.Hour}, logger)
+	queuer := &recordingQueuer{}
+	predictor.SetDownloadManager(queuer)
+	predictor.SetLibraryFilter(&config.LibraryFilterConfig{Include: []string{"Movies"}})
+
+	require.NoError(t, predictor.refreshViewingHistory(context.Background(), "alice"))
+	require.Len(t, predictor.viewingHistory, 1)
+	assert.Equal(t, "movie", predictor.viewingHistory[0].MediaID)
+
+	require.NoError(t, predictor.OnPlaybackStart(context.Background(), "birthday"))
+	require.NoError(t, predictor.OnPlaybackStart(context.Background(), "movie"))
+	assert.Equal(t, []string{"movie"}, queuer.queued)
+	assert.Len(t, predictor.viewingHistory, 2, "only the allowed playback is recorded")
+}
@@ -0,0 +1,170 @@
+package downloader
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// LocalSource finds the server's own copy of an item on this host, so a
+// download can be a reflink, hardlink or local copy instead of a transfer
+// over HTTP. It applies when go-jf-watch runs next to Jellyfin or has its
+// media folders mounted.
+type LocalSource struct {
+	source MetadataSource
+	config *config.LocalSourceConfig
+	logger *slog.Logger
+}
+
+// NewLocalSource creates a local source that asks source where items'
+// files are.
+func NewLocalSource(source MetadataSource, cfg *config.LocalSourceConfig, logger *slog.Logger) *LocalSource {
+	return &LocalSource{
+		source: source,
+		config: cfg,
+		logger: logger,
+	}
+}
+
+// Enabled reports whether files are taken from the local source.
+func (l *LocalSource) Enabled() bool {
+	return l.config.Enabled
+}
+
+// Resolve returns where the server's file of an item is on this host and
+// its size according to the server, or "" if it cannot be read from here.
+func (l *LocalSource) Resolve(ctx context.Context, mediaID string) (string, int64, error) {
+	items, err := l.source.GetItemsByID(ctx, []string{mediaID})
+	if err != nil {
+		return "", 0, err
+	}
+	if len(items) == 0 || items[0].Path == "" {
+		return "", 0, nil
+	}
+
+	path := l.LocalPath(items[0].Path)
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", 0, nil
+	}
+	return path, items[0].Size, nil
+}
+
+// LocalPath translates a path Jellyfin reports into one on this host using
+// the longest matching path mapping.
+func (l *LocalSource) LocalPath(serverPath string) string {
+	best := -1
+	for i, mapping := range l.config.PathMappings {
+		if !hasPathPrefix(serverPath, mapping.From) {
+			continue
+		}
+		if best < 0 || len(mapping.From) > len(l.config.PathMappings[best].From) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return filepath.FromSlash(serverPath)
+	}
+
+	mapping := l.config.PathMappings[best]
+	rest := strings.TrimLeft(serverPath[len(mapping.From):], `/\`)
+	// Windows servers report backslashes
+	return filepath.Join(mapping.To, filepath.FromSlash(strings.ReplaceAll(rest, `\`, "/")))
+}
+
+// hasPathPrefix reports whether path is prefix or lies under it.
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimRight(prefix, `/\`)
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	rest := path[len(prefix):]
+	return rest == "" || rest[0] == '/' || rest[0] == '\\'
+}
+
+// SetLocalSource makes downloads take the server's file from l when it is
+// reachable on this host. A nil source downloads everything over HTTP.
+func (m *Manager) SetLocalSource(l *LocalSource) {
+	m.local = l
+}
+
+// takeLocal completes job from the local source and reports whether it
+// did. Transcodes, and files that an encrypted cache has to rewrite, are
+// left to HTTP, as is anything the local source cannot provide intact.
+func (m *Manager) takeLocal(ctx context.Context, job *DownloadJob, result *DownloadResult, start time.Time) bool {
+	if m.local == nil || !m.local.Enabled() || job.Quality != "" || m.cipher != nil {
+		return false
+	}
+
+	src, size, err := m.local.Resolve(ctx, job.MediaID)
+	if err != nil || src == "" {
+		m.logger.Debug("Server file not reachable locally, downloading",
+			"job_id", job.ID, "media_id", job.MediaID, "error", err)
+		return false
+	}
+
+	method, err := storage.LinkFile(src, job.LocalPath, m.local.config.Mode)
+	if err != nil {
+		m.logger.Warn("Failed to take file from local source, downloading",
+			"job_id", job.ID, "src", src, "error", err)
+		return false
+	}
+
+	// What was linked has to be what the server would have sent
+	if err := m.verifyLocal(job, size, result); err != nil {
+		os.Remove(job.LocalPath)
+		m.logger.Warn("File from local source failed verification, downloading",
+			"job_id", job.ID, "src", src, "error", err)
+		result.Checksum, result.ChecksumAlgorithm, result.Size = "", "", 0
+		return false
+	}
+	os.Remove(storage.PartialPath(job.LocalPath))
+
+	result.Success = true
+	result.Duration = time.Since(start)
+	result.BytesDownloaded = result.Size
+
+	m.logger.Info("Took file from local source",
+		"job_id", job.ID,
+		"media_id", job.MediaID,
+		"method", method,
+		"bytes", result.Size)
+	m.reportProgress(job.MediaID, 100, "completed", "Taken from local source")
+	return true
+}
+
+// verifyLocal checks the size and checksum of a file taken from the local
+// source and records them in result.
+func (m *Manager) verifyLocal(job *DownloadJob, size int64, result *DownloadResult) error {
+	info, err := os.Stat(job.LocalPath)
+	if err != nil {
+		return err
+	}
+	if size > 0 && info.Size() != size {
+		return fmt.Errorf("file is %d bytes, the server reports %d", info.Size(), size)
+	}
+	result.Size = info.Size()
+
+	hasher, algorithm, err := m.downloadHasher(job)
+	if err != nil || hasher == nil {
+		return err
+	}
+	if err := hashPrefix(hasher, job.LocalPath, info.Size()); err != nil {
+		return err
+	}
+	checksum := hex.EncodeToString(hasher.Sum(nil))
+	if job.Checksum != "" && checksum != job.Checksum {
+		return fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, job.Checksum, checksum)
+	}
+	result.Checksum = checksum
+	result.ChecksumAlgorithm = algorithm
+	return nil
+}
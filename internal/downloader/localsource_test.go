@@ -0,0 +1,96 @@
+package downloader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestTakeLocal(t *testing.T) {
+	content := bytes.Repeat([]byte("library copy "), 1000)
+	var requests atomic.Int32
+	manager, _, job := newResumeTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.ServeContent(w, r, "movie.mkv", time.Time{}, bytes.NewReader(content))
+	})
+
+	// Jellyfin keeps its media under /media; this host mounts it elsewhere
+	library := t.TempDir()
+	src := filepath.Join(library, "Movies", "Heat (1995)", "Heat.mkv")
+	require.NoError(t, os.MkdirAll(filepath.Dir(src), 0755))
+	require.NoError(t, os.WriteFile(src, content, 0644))
+
+	source := jellyfintest.New("http://jellyfin.test")
+	source.Items = map[string]jellyfin.MediaItem{
+		"m1": {ID: "m1", Path: "/media/Movies/Heat (1995)/Heat.mkv", Size: int64(len(content))},
+	}
+	cfg := &config.LocalSourceConfig{
+		Enabled: true,
+		Mode:    "hardlink",
+		PathMappings: []config.PathMappingConfig{
+			{From: "/", To: t.TempDir()},
+			{From: "/media", To: library},
+		},
+	}
+	local := NewLocalSource(source, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	manager.SetLocalSource(local)
+
+	result := manager.processJob(job)
+	require.True(t, result.Success, "error: %v", result.Error)
+	assert.Zero(t, requests.Load(), "nothing is downloaded")
+	assert.Zero(t, result.BytesRead)
+	assert.Equal(t, int64(len(content)), result.Size)
+	sum := sha256.Sum256(content)
+	assert.Equal(t, hex.EncodeToString(sum[:]), result.Checksum)
+
+	srcInfo, err := os.Stat(src)
+	require.NoError(t, err)
+	dstInfo, err := os.Stat(job.LocalPath)
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(srcInfo, dstInfo), "the cached file is a hardlink")
+
+	// A checksum that does not match falls back to downloading
+	require.NoError(t, os.Remove(job.LocalPath))
+	job.Checksum = "0000"
+	result = manager.processJob(job)
+	assert.Equal(t, int32(1), requests.Load())
+	assert.ErrorIs(t, result.Error, ErrChecksumMismatch)
+
+	// So does a file the server reports at another size
+	job.Checksum = ""
+	source.Items["m1"] = jellyfin.MediaItem{ID: "m1", Path: "/media/Movies/Heat (1995)/Heat.mkv", Size: 1}
+	result = manager.processJob(job)
+	require.True(t, result.Success, "error: %v", result.Error)
+	assert.Equal(t, int32(2), requests.Load())
+	dstInfo, err = os.Stat(job.LocalPath)
+	require.NoError(t, err)
+	assert.False(t, os.SameFile(srcInfo, dstInfo))
+}
+
+func TestLocalSourcePath(t *testing.T) {
+	local := NewLocalSource(nil, &config.LocalSourceConfig{
+		PathMappings: []config.PathMappingConfig{
+			{From: "/media", To: "/mnt/jellyfin"},
+			{From: `D:\Media`, To: "/mnt/d"},
+		},
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	assert.Equal(t, filepath.FromSlash("/mnt/jellyfin/Movies/Heat.mkv"), local.LocalPath("/media/Movies/Heat.mkv"))
+	assert.Equal(t, filepath.FromSlash("/mnt/d/TV/Bluey/S01E02.mkv"), local.LocalPath(`D:\Media\TV\Bluey\S01E02.mkv`))
+	assert.Equal(t, filepath.FromSlash("/mediaserver/a.mkv"), local.LocalPath("/mediaserver/a.mkv"), "only whole folders match")
+}
@@ -4,27 +4,32 @@
 // Architecture follows the worker pool pattern specified in PLAN.md:
 // - Manager orchestrates workers and manages queue state
 // - Workers handle concurrent download execution (3-5 goroutines)
-// - Priority-based download scheduling using channels
+// - Priority-based download scheduling with preemption for playback
 // - Rate limiting using golang.org/x/time/rate
 package downloader
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"math/rand"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
-	"strconv"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/schollz/progressbar/v3"
 	"golang.org/x/time/rate"
 
+	"github.com/opd-ai/go-jf-watch/internal/chaos"
+	"github.com/opd-ai/go-jf-watch/internal/media"
+	"github.com/opd-ai/go-jf-watch/internal/notify"
 	"github.com/opd-ai/go-jf-watch/internal/storage"
 	"github.com/opd-ai/go-jf-watch/pkg/config"
 )
@@ -32,21 +37,96 @@ import (
 // Manager orchestrates workers and manages download queue state.
 // It implements the worker pool pattern with priority-based scheduling.
 type Manager struct {
-	workers          int
-	jobs             chan *DownloadJob
+	workers          int // Written under both mu and activeMu
+	jobs             *jobQueue
 	results          chan *DownloadResult
-	limiter          *rate.Limiter
-	storage          *storage.Manager
+	bandwidth        *bandwidthAllocator
+	exemptNets       []*net.IPNet
+	storage          Store
 	logger           *slog.Logger
 	config           *config.DownloadConfig
 	progressReporter ProgressReporter
+	libraries        *config.LibraryFilterConfig
+	faults           *chaos.Injector // nil unless fault injection is enabled
+	cache            CapacityManager // nil disables the capacity gate
+	cipher           *storage.Cipher // nil stores downloads unencrypted
+	artwork          *ArtworkCache   // nil leaves artwork uncached
+	trickplay        *TrickplayCache // nil leaves seek previews uncached
+	nfo              *NFOExporter    // nil writes no .nfo files
+	inspector        *MediaInspector // nil leaves downloads uninspected
+	local            *LocalSource    // nil downloads everything over HTTP
+
+	// What to download for each item: the original or a transcode at
+	// qualityPreference. nil queues jobs without a URL
+	variants          VariantSelector
+	qualityPreference string
+
+	// queueMu serializes the check-then-add in QueueDownloadWithSource so
+	// concurrent callers cannot enqueue the same media twice.
+	queueMu sync.Mutex
+
+	// Cached cache-disk health; speculative downloads pause while unhealthy
+	// and transitions are sent to the notifier. diskChecking is set while
+	// a check runs outside diskMu
+	diskHealth   *storage.DiskHealth
+	diskChecking bool
+	notifier     Notifier
+	diskMu       sync.Mutex
+
+	// Byte-level progress of in-flight downloads and how to stop them,
+	// keyed by job ID
+	active   map[string]*downloadTracker
+	cancels  map[string]context.CancelCauseFunc
+	activeMu sync.Mutex
+
+	// Opening segments to fetch ahead of the rest, keyed by media ID
+	heads  map[string]headRequest
+	headMu sync.Mutex
+
+	// How download attempts started: resumed or from scratch
+	resumes resumeStats
+
+	// Bytes downloaded per hour, for accounting and the daily cap, and
+	// whether the cap was reached when last checked
+	usage      *usageMeter
+	throughput *throughputRing
+	capReached atomic.Bool
+
+	// Shared HTTP transports keyed by local address ("" for the default
+	// route), and download slots per server host
+	transports   map[string]*http.Transport
+	hostSlots    map[string]chan struct{}
+	transportsMu sync.Mutex
+
+	// When each household user usually watches, keyed by user ID
+	windows   map[string][]TimeWindow
+	windowsMu sync.Mutex
+
+	// Total download rate in megabits per second, changeable while running
+	rateLimitMbps atomic.Int64
+
+	// Worker management. workerStops stops each running worker, in the
+	// order they were started
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+	workerStops []context.CancelFunc
+	running     bool
+	mu          sync.RWMutex
+}
 
-	// Worker management
-	ctx     context.Context
-	cancel  context.CancelFunc
-	wg      sync.WaitGroup
-	running bool
-	mu      sync.RWMutex
+// Store is the storage the download manager works against. *storage.Manager
+// implements it; tests can substitute an in-memory fake.
+type Store interface {
+	storage.QueueStore
+	storage.MediaStore
+	storage.StateStore
+	storage.EventStore
+	RecordDownloadCompleted(bytes int64) error
+	RecordBandwidth(at time.Time, bytes int64) error
+	GetBandwidthUsage(from, to time.Time) ([]*storage.BandwidthUsage, error)
+	CheckDiskHealth(ctx context.Context) (*storage.DiskHealth, error)
+	ChecksumAlgorithm() string
 }
 
 // DownloadJob represents a download task with priority and metadata.
@@ -59,17 +139,69 @@ type DownloadJob struct {
 	Size       int64
 	RetryCount int
 	CreatedAt  time.Time
+	Source     string // manual, playback, prediction, warmer
+	Quality    string // variant to cache; empty for the original
+	Container  string // container of the variant, when known
+	Bitrate    int    // bitrate of the variant in bits per second, when known
+	// BytesDownloaded is how far earlier attempts got, as recorded in the
+	// queue
+	BytesDownloaded int64
+	// Checksum is what the finished file is expected to hash to with
+	// ChecksumAlgorithm (empty means sha256). A download that does not
+	// match is discarded and retried. Empty when unknown; the checksum is
+	// still computed and stored for later integrity scans.
+	Checksum          string
+	ChecksumAlgorithm string
 }
 
+// Queue sources recorded on queue items. Reconciliation and cache warming
+// only ever touch items they queued themselves (SourcePrediction and
+// SourceWarmer) and leave the rest alone. SourceIntegrity marks lost items
+// an integrity scan queued to download again, SourceSubscription new
+// episodes of a subscribed series, SourceAdopted files that were
+// imported into the cache rather than downloaded, SourceSeed items a
+// one-off seeding run picked, SourceTrip items trip mode picked and
+// SourceCollection items of a collection or playlist queued for caching.
+const (
+	SourceManual       = "manual"
+	SourcePlayback     = "playback"
+	SourcePrediction   = "prediction"
+	SourceWarmer       = "warmer"
+	SourceIntegrity    = "integrity"
+	SourceSubscription = "subscription"
+	SourceAdopted      = "adopted"
+	SourceSeed         = "seed"
+	SourceTrip         = "trip"
+	SourceCollection   = "collection"
+)
+
 // DownloadResult contains the outcome of a download job.
 type DownloadResult struct {
 	Job         *DownloadJob
 	Success     bool
-	BytesRead   int64
+	BytesRead   int64 // Bytes fetched by this attempt
 	Duration    time.Duration
 	Error       error
 	HTTPStatus  int
 	CompletedAt time.Time
+	// HeadFetched marks a download stopped after the opening segment it
+	// was promoted for; the rest is requeued rather than failed
+	HeadFetched bool
+	// Stopped is why a download was stopped on purpose before it finished:
+	// preempted for playback, paused or cancelled. It is not a failure
+	Stopped error
+
+	// Size of the completed file, including any bytes resumed from earlier
+	// attempts
+	Size int64
+	// BytesDownloaded is how much of the file is on disk when the attempt
+	// ends, which the next attempt resumes from
+	BytesDownloaded int64
+
+	// Checksum of the whole file, computed while it streamed in. Empty when
+	// checksums are turned off
+	Checksum          string
+	ChecksumAlgorithm string
 }
 
 // ProgressCallback is called during download to report progress.
@@ -78,29 +210,77 @@ type ProgressCallback func(jobID string, downloaded, total int64)
 // ProgressReporter interface for sending progress updates (e.g., to WebSocket clients)
 type ProgressReporter interface {
 	BroadcastProgress(mediaID, status, message string, progress float64)
+	// BroadcastTransfer reports how an in-flight download is going: its
+	// progress, rolling speed in bytes per second and estimated time left,
+	// zero when not known.
+	BroadcastTransfer(mediaID string, progress float64, speed int64, eta time.Duration)
+}
+
+// Notifier delivers user-facing alerts (email, webhooks).
+type Notifier interface {
+	Notify(ctx context.Context, n *notify.Notification) error
 }
 
 // New creates a new download manager with the specified configuration.
 // It initializes the worker pool but doesn't start workers until Start() is called.
-func New(cfg *config.DownloadConfig, storage *storage.Manager, logger *slog.Logger) *Manager {
-	// Create rate limiter based on configuration
-	// Convert Mbps to bytes per second with burst allowance
-	bytesPerSecond := rate.Limit(cfg.RateLimitMbps * 1024 * 1024 / 8)
-	burstSize := int(bytesPerSecond * 5) // 5 second burst
-
+func New(cfg *config.DownloadConfig, storage Store, logger *slog.Logger) *Manager {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Manager{
-		workers: cfg.Workers,
-		jobs:    make(chan *DownloadJob, cfg.Workers*2), // Buffer for efficiency
-		results: make(chan *DownloadResult, cfg.Workers*2),
-		limiter: rate.NewLimiter(bytesPerSecond, burstSize),
-		storage: storage,
-		logger:  logger,
-		config:  cfg,
-		ctx:     ctx,
-		cancel:  cancel,
+	m := &Manager{
+		workers:    cfg.Workers,
+		jobs:       newJobQueue(cfg.Workers * 2), // Most urgent first; storage holds the rest
+		results:    make(chan *DownloadResult, cfg.Workers*2),
+		exemptNets: parseExemptNetworks(cfg.RateLimitExemption.CIDRs),
+		storage:    storage,
+		logger:     logger,
+		config:     cfg,
+		ctx:        ctx,
+		cancel:     cancel,
+		usage:      newUsageMeter(),
+		throughput: newThroughputRing(),
 	}
+	m.rateLimitMbps.Store(int64(cfg.RateLimitMbps))
+	// The rate limit is shared between in-flight downloads by priority
+	m.bandwidth = newBandwidthAllocator(m.currentRateLimit)
+
+	return m
+}
+
+// SetNotifier sets the notifier used for disk health alerts.
+func (m *Manager) SetNotifier(notifier Notifier) {
+	m.diskMu.Lock()
+	defer m.diskMu.Unlock()
+	m.notifier = notifier
+}
+
+// SetCipher makes completed downloads be stored encrypted with c. A nil
+// cipher stores them as downloaded.
+func (m *Manager) SetCipher(c *storage.Cipher) {
+	m.cipher = c
+}
+
+// SetArtworkCache makes completed downloads have their artwork cached by
+// a. A nil cache leaves artwork alone.
+func (m *Manager) SetArtworkCache(a *ArtworkCache) {
+	m.artwork = a
+}
+
+// SetTrickplayCache makes completed downloads have their seek previews
+// cached by t. A nil cache leaves them alone.
+func (m *Manager) SetTrickplayCache(t *TrickplayCache) {
+	m.trickplay = t
+}
+
+// SetNFOExporter makes completed downloads have their .nfo written by e.
+// A nil exporter writes none.
+func (m *Manager) SetNFOExporter(e *NFOExporter) {
+	m.nfo = e
+}
+
+// SetMediaInspector makes completed downloads have their codecs,
+// resolution and tracks recorded by i. A nil inspector records none.
+func (m *Manager) SetMediaInspector(i *MediaInspector) {
+	m.inspector = i
 }
 
 // SetProgressReporter sets the progress reporter for WebSocket updates
@@ -122,14 +302,20 @@ func (m *Manager) Start(ctx context.Context) error {
 
 	m.ctx, m.cancel = context.WithCancel(ctx)
 
+	// Pick up where the last run left off before any worker starts
+	m.restoreState()
+	if err := m.usage.restore(m.storage); err != nil {
+		m.logger.Warn("Failed to load today's bandwidth usage", "error", err)
+	}
+
 	m.logger.Info("Starting download manager",
 		"workers", m.workers,
-		"rate_limit_mbps", m.config.RateLimitMbps)
+		"rate_limit_mbps", m.rateLimitMbps.Load())
 
 	// Start worker goroutines
+	m.workerStops = nil
 	for i := 0; i < m.workers; i++ {
-		m.wg.Add(1)
-		go m.worker(i)
+		m.startWorker()
 	}
 
 	// Start result processor
@@ -140,6 +326,13 @@ func (m *Manager) Start(ctx context.Context) error {
 	m.wg.Add(1)
 	go m.queueProcessor()
 
+	// Report speed and ETA of active downloads
+	m.wg.Add(1)
+	go m.transferReporter()
+
+	// Downloads the last run was in the middle of continue right away
+	m.resumeInterrupted()
+
 	m.running = true
 	return nil
 }
@@ -156,11 +349,18 @@ func (m *Manager) Stop() error {
 
 	m.logger.Info("Stopping download manager")
 
-	// Signal shutdown
+	// Capture in-flight progress and bandwidth debt before cancelling
+	// unwinds them
+	progress := m.snapshotProgress()
+	debt := m.bandwidth.debt()
+
+	// Stop in-flight downloads so they flush their partial files, then
+	// signal shutdown
+	m.stopAll(errShutdown)
 	m.cancel()
 
-	// Close job channel to stop accepting new jobs
-	close(m.jobs)
+	// Stop accepting new jobs
+	m.jobs.close()
 
 	// Wait for all workers to complete
 	m.wg.Wait()
@@ -168,6 +368,10 @@ func (m *Manager) Stop() error {
 	// Close results channel
 	close(m.results)
 
+	m.saveShutdownState(progress, debt)
+	m.flushUsage()
+	m.closeIdleConnections()
+
 	m.running = false
 	m.logger.Info("Download manager stopped")
 
@@ -184,8 +388,16 @@ func (m *Manager) AddJob(job *DownloadJob) error {
 		Priority:  job.Priority,
 		URL:       job.URL,
 		LocalPath: job.LocalPath,
+		Size:      job.Size,
 		CreatedAt: job.CreatedAt,
 		Status:    "queued",
+		Source:    job.Source,
+		Quality:   job.Quality,
+		Container: job.Container,
+		Bitrate:   job.Bitrate,
+
+		Checksum:          job.Checksum,
+		ChecksumAlgorithm: job.ChecksumAlgorithm,
 	}
 
 	if err := m.storage.AddQueueItem(queueItem); err != nil {
@@ -198,20 +410,27 @@ func (m *Manager) AddJob(job *DownloadJob) error {
 		"priority", job.Priority,
 		"url", job.URL)
 
-	// Try to send to worker channel if there's space
-	select {
-	case m.jobs <- job:
-		// Job sent to worker immediately
-	default:
-		// Channel full, job will be picked up by queue processor
-		m.logger.Debug("Job channel full, job queued in storage",
+	// Speculative jobs stay in storage while the cache disk is unhealthy,
+	// and all but the most urgent once the daily cap is reached. Large
+	// speculative jobs wait for the current viewing window to end, and
+	// any job for its download window to open
+	if m.heldBack(job.Priority) || m.waitsForOffWindow(job.Priority, job.Size) || m.outsideWindow(job.Priority) {
+		m.logger.Debug("Job held in queue",
+			"job_id", job.ID, "priority", job.Priority)
+		return nil
+	}
+
+	// Hand to the workers if there's room; otherwise the queue processor
+	// loads it from storage later
+	if !m.enqueue(job) {
+		m.logger.Debug("Job queue full, job queued in storage",
 			"job_id", job.ID)
 	}
 
 	return nil
 }
 
-// queueProcessor continuously loads jobs from storage into the worker channel.
+// queueProcessor continuously loads jobs from storage into the worker queue.
 func (m *Manager) queueProcessor() {
 	defer m.wg.Done()
 
@@ -223,18 +442,49 @@ func (m *Manager) queueProcessor() {
 		case <-m.ctx.Done():
 			return
 		case <-ticker.C:
+			// Pick up peak hour transitions in the bandwidth shares
+			m.bandwidth.rebalance()
+			m.flushUsage()
 			m.loadJobsFromQueue()
 		}
 	}
 }
 
-// loadJobsFromQueue loads queued jobs from storage into the worker channel.
+// loadJobsFromQueue loads queued jobs from storage into the worker queue.
 func (m *Manager) loadJobsFromQueue() {
 	queueItem, err := m.storage.GetNextQueueItem()
 	if err != nil || queueItem == nil {
 		return // No queued items or error
 	}
 
+	// Download windows are per priority and retries wait out their
+	// backoff, so a head that has to wait must not hold back the items
+	// behind it
+	if now := time.Now(); m.outsideWindow(queueItem.Priority) || retryPending(queueItem, now) {
+		if queueItem = m.nextStartable(now); queueItem == nil {
+			return
+		}
+	}
+
+	// Only the head is tried otherwise: the queue is priority ordered, so
+	// if it is held back everything behind it is too
+	m.startQueueItem(queueItem)
+}
+
+// startQueueItem hands a queued item to the workers and marks it as
+// downloading. It reports false if the item is held back or the worker
+// queue has no room; the item then stays queued in storage.
+func (m *Manager) startQueueItem(queueItem *storage.QueueItem) bool {
+	if m.heldBack(queueItem.Priority) {
+		return false
+	}
+	if m.waitsForOffWindow(queueItem.Priority, queueItem.Size) || m.outsideWindow(queueItem.Priority) {
+		return false
+	}
+	if retryPending(queueItem, time.Now()) {
+		return false
+	}
+
 	job := &DownloadJob{
 		ID:        queueItem.ID,
 		MediaID:   queueItem.MediaID,
@@ -242,50 +492,66 @@ func (m *Manager) loadJobsFromQueue() {
 		URL:       queueItem.URL,
 		LocalPath: queueItem.LocalPath,
 		CreatedAt: queueItem.CreatedAt,
+		Source:    queueItem.Source,
+		Quality:   queueItem.Quality,
+		Container: queueItem.Container,
+		Bitrate:   queueItem.Bitrate,
+
+		RetryCount:        queueItem.RetryCount,
+		BytesDownloaded:   queueItem.BytesDownloaded,
+		Checksum:          queueItem.Checksum,
+		ChecksumAlgorithm: queueItem.ChecksumAlgorithm,
+	}
+
+	if !m.enqueue(job) {
+		return false // Already handed out, or full of more urgent jobs; try again later
 	}
 
-	select {
-	case m.jobs <- job:
-		// Update status to downloading
-		queueItem.Status = "downloading"
-		now := time.Now()
-		queueItem.StartedAt = now
+	// Update status to downloading
+	queueItem.Status = "downloading"
+	now := time.Now()
+	queueItem.StartedAt = now
 
-		if err := m.storage.UpdateQueueItem(queueItem); err != nil {
-			m.logger.Error("Failed to update queue item status",
-				"job_id", job.ID, "error", err)
-		}
-	case <-m.ctx.Done():
-		return
-	default:
-		// Channel full, try again later
+	if err := m.storage.UpdateQueueItem(queueItem); err != nil {
+		m.logger.Error("Failed to update queue item status",
+			"job_id", job.ID, "error", err)
 	}
+	return true
 }
 
-// worker processes download jobs from the jobs channel.
-func (m *Manager) worker(id int) {
+// startWorker starts another worker. Callers must hold m.mu.
+func (m *Manager) startWorker() {
+	ctx, stop := context.WithCancel(m.ctx)
+	id := len(m.workerStops)
+	m.workerStops = append(m.workerStops, stop)
+
+	m.wg.Add(1)
+	go m.worker(ctx, id)
+}
+
+// worker processes download jobs, most urgent first, until ctx is done.
+// A worker stopped on its own finishes the download it is on first.
+func (m *Manager) worker(ctx context.Context, id int) {
 	defer m.wg.Done()
 
 	m.logger.Debug("Starting download worker", "worker_id", id)
 
 	for {
-		select {
-		case job, ok := <-m.jobs:
-			if !ok {
-				m.logger.Debug("Jobs channel closed, worker exiting", "worker_id", id)
-				return
-			}
-
-			result := m.processJob(job)
+		if ctx.Err() != nil {
+			m.logger.Debug("Worker shutting down", "worker_id", id)
+			return
+		}
+		job, ok := m.jobs.pop(ctx)
+		if !ok {
+			m.logger.Debug("Worker shutting down", "worker_id", id)
+			return
+		}
 
-			select {
-			case m.results <- result:
-			case <-m.ctx.Done():
-				return
-			}
+		result := m.processJob(job)
 
+		select {
+		case m.results <- result:
 		case <-m.ctx.Done():
-			m.logger.Debug("Worker shutting down", "worker_id", id)
 			return
 		}
 	}
@@ -314,17 +580,35 @@ func (m *Manager) processJob(job *DownloadJob) *DownloadResult {
 		return result
 	}
 
+	// The server's own file beats downloading it when it is on this host
+	if m.takeLocal(m.ctx, job, result, start) {
+		return result
+	}
+
 	// Check for partial download to support resume
 	var startByte int64 = 0
-	if fileInfo, err := os.Stat(job.LocalPath + ".partial"); err == nil {
+	if fileInfo, err := os.Stat(storage.PartialPath(job.LocalPath)); err == nil {
 		startByte = fileInfo.Size()
 		m.logger.Info("Resuming partial download",
 			"job_id", job.ID,
 			"start_byte", startByte)
+	} else if job.BytesDownloaded > 0 {
+		m.logger.Warn("Partial download is gone, starting over",
+			"job_id", job.ID,
+			"lost_bytes", job.BytesDownloaded)
 	}
+	result.BytesDownloaded = startByte
+
+	// The watchdog cancels the request if the connection hangs or crawls,
+	// and pausing, cancelling or preempting the job cancels it too
+	ctx, abort := context.WithCancelCause(m.ctx)
+	defer abort(nil)
+	m.attachCancel(job.ID, abort)
+	defer m.detachCancel(job.ID)
+	watch := &downloadWatch{}
 
 	// Create HTTP request
-	req, err := http.NewRequestWithContext(m.ctx, "GET", job.URL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", job.URL, nil)
 	if err != nil {
 		result.Error = fmt.Errorf("failed to create request: %w", err)
 		return result
@@ -335,12 +619,44 @@ func (m *Manager) processJob(job *DownloadJob) *DownloadResult {
 		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", startByte))
 	}
 
-	client := &http.Client{
-		Timeout: 30 * time.Minute, // Long timeout for large files
+	client, err := m.httpClientFor(job.Priority)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to bind download interface: %w", err)
+		m.reportProgress(job.MediaID, 0, "failed", err.Error())
+		return result
+	}
+	if m.faults != nil {
+		client.Transport = m.faults.Transport(client.Transport)
 	}
 
+	// Wait for a connection slot to the server before the stall watchdog
+	// starts timing the request
+	release, err := m.acquireHost(ctx, req.URL.Host, job.Priority)
+	if err != nil {
+		if cause := stopCause(ctx); cause != nil {
+			result.Stopped = cause
+			return result
+		}
+		result.Error = fmt.Errorf("failed waiting for a connection slot: %w", err)
+		return result
+	}
+	defer release()
+
+	// Waiting for response headers counts towards the stall timeout
+	watch.waiting()
+	go m.stallWatchdog().run(ctx, watch, abort)
+
 	resp, err := client.Do(req)
+	watch.idle()
 	if err != nil {
+		if cause := stopCause(ctx); cause != nil {
+			result.Stopped = cause
+			return result
+		}
+		if cause := stallCause(ctx); cause != nil {
+			result.Error = m.stalled(job, cause, startByte)
+			return result
+		}
 		result.Error = fmt.Errorf("failed to make request: %w", err)
 		return result
 	}
@@ -349,6 +665,15 @@ func (m *Manager) processJob(job *DownloadJob) *DownloadResult {
 	// Capture HTTP status for retry logic
 	result.HTTPStatus = resp.StatusCode
 
+	// The partial file is complete or longer than the item now is; drop it
+	// and let the retry start over
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && startByte > 0 {
+		result.HTTPStatus = 0
+		result.BytesDownloaded = 0
+		result.Error = m.discardPartial(job, fmt.Errorf("server cannot resume at byte %d", startByte))
+		return result
+	}
+
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
 		result.Error = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 		m.reportProgress(job.MediaID, 0, "failed", fmt.Sprintf("HTTP error: %d", resp.StatusCode))
@@ -367,65 +692,151 @@ func (m *Manager) processJob(job *DownloadJob) *DownloadResult {
 		fmt.Sprintf("Downloading %s", filepath.Base(job.LocalPath)),
 	)
 
-	// Create rate-limited reader (bypass for Priority 0 - currently playing)
-	var dataReader io.Reader
-	if job.Priority == 0 {
-		// Priority 0 (currently playing) gets full bandwidth
-		m.logger.Debug("Using full bandwidth for Priority 0 download", "job_id", job.ID)
-		dataReader = resp.Body
+	// Create rate-limited reader. Priority 0 (currently playing) gets full
+	// bandwidth from its share; the rest split the limit by priority, and
+	// shares are rebalanced as jobs start, finish or change priority
+	dataReader := m.faults.Reader(watch.reader(resp.Body))
+	var limiter *rate.Limiter
+	if m.isRateLimitExempt(m.ctx, job.URL) {
+		// LAN-local servers are not worth throttling
+		m.logger.Debug("Using full bandwidth for rate limit exempt host", "job_id", job.ID)
 	} else {
-		// All other priorities use rate limiting
-		dataReader = m.createRateLimitedReader(resp.Body)
+		limiter = m.bandwidth.register(job.ID, job.Priority)
+		defer m.bandwidth.unregister(job.ID)
+		watch.limiter.Store(limiter)
+		dataReader = m.createRateLimitedReader(dataReader, limiter)
 	}
 
-	// Wrap with progress tracking
-	progressReader := io.TeeReader(dataReader, bar)
-
-	// Write file with resume support
-	if startByte > 0 && resp.StatusCode == http.StatusPartialContent {
-		// Append to existing partial file
-		partialPath := job.LocalPath + ".partial"
-		file, err := os.OpenFile(partialPath, os.O_APPEND|os.O_WRONLY, 0644)
-		if err != nil {
-			result.Error = fmt.Errorf("failed to open partial file: %w", err)
-			return result
+	// A 200 response restarts the file from scratch even if a partial existed
+	var offset, total int64
+	if resp.StatusCode == http.StatusPartialContent {
+		rangeStart, size, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err == nil && rangeStart != startByte {
+			err = fmt.Errorf("server resumed at byte %d instead of %d", rangeStart, startByte)
 		}
-		_, err = io.Copy(file, progressReader)
-		file.Close()
 		if err != nil {
-			result.Error = fmt.Errorf("failed to write to partial file: %w", err)
+			result.BytesDownloaded = 0
+			result.Error = m.discardPartial(job, err)
 			return result
 		}
-		// Move completed file to final location
-		if err := os.Rename(partialPath, job.LocalPath); err != nil {
-			result.Error = fmt.Errorf("failed to move completed file: %w", err)
-			return result
+		offset = startByte
+		if size > 0 {
+			total = size
+		}
+	}
+	m.resumes.recordStart(offset)
+	if total == 0 && contentLength > 0 {
+		total = offset + contentLength
+	}
+	if total > 0 {
+		// Stop early if read-ahead only wants the opening segment for now
+		dataReader = &headReader{r: dataReader, m: m, mediaID: job.MediaID, pos: offset, total: total}
+	}
+
+	// Stream straight into a temporary file beside the destination, so
+	// completion is a rename and the bytes so far can be streamed
+	partial, err := storage.CreatePartial(job.LocalPath, offset > 0)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	tracker := m.trackDownload(job, offset, total)
+	m.attachPartial(job.ID, partial)
+	defer m.untrackDownload(job.ID)
+
+	// Wrap with progress tracking
+	sink := io.MultiWriter(bar, tracker, m.usage, m.throughput)
+	progressReader := io.TeeReader(dataReader, sink)
+	segments := m.segmentCount(job, resp, offset, total)
+
+	// Checksum downloads as they stream in rather than rereading them. A
+	// resumed download first hashes the bytes kept from earlier attempts,
+	// and a segmented one is hashed once all its segments are in
+	var out io.Writer = partial
+	hasher, algorithm, err := m.downloadHasher(job)
+	if err == nil && hasher != nil && offset > 0 {
+		err = hashPrefix(hasher, partial.Path(), offset)
+	}
+	if err != nil {
+		partial.Close()
+		result.Error = err
+		return result
+	}
+	if hasher != nil && segments == 1 {
+		out = io.MultiWriter(partial, hasher)
+	}
+
+	if segments > 1 {
+		err = m.copySegments(ctx, abort, job, client, partial, progressReader, sink, limiter, watch, total, segments)
+		if err == nil && hasher != nil {
+			err = hashPrefix(hasher, partial.Path(), total)
 		}
 	} else {
-		// Write new file or restart download
-		partialPath := job.LocalPath + ".partial"
-		file, err := os.Create(partialPath)
-		if err != nil {
-			result.Error = fmt.Errorf("failed to create file: %w", err)
+		_, err = io.Copy(out, progressReader)
+	}
+	result.BytesDownloaded = partial.Written()
+	if err != nil {
+		// Keep what was written so the next attempt can resume. Downloads
+		// stopped on purpose are flushed to disk, as the next attempt may
+		// only come after a restart
+		cause := stopCause(ctx)
+		if cause == nil {
+			partial.Close()
+		} else if err := partial.Suspend(); err != nil {
+			m.logger.Warn("Failed to flush partial download",
+				"job_id", job.ID, "error", err)
+		}
+		if errors.Is(err, errHeadFetched) {
+			result.HeadFetched = true
 			return result
 		}
-		_, err = io.Copy(file, progressReader)
-		file.Close()
-		if err != nil {
-			result.Error = fmt.Errorf("failed to write file: %w", err)
+		if cause != nil {
+			result.Stopped = cause
 			return result
 		}
-		// Use atomic move for final file
-		if err := os.Rename(partialPath, job.LocalPath); err != nil {
-			result.Error = fmt.Errorf("failed to move completed file: %w", err)
+		if cause := stallCause(ctx); cause != nil {
+			result.Error = m.stalled(job, cause, tracker.downloaded.Load())
 			return result
 		}
+		result.Error = fmt.Errorf("failed to write file: %w", err)
+		return result
+	}
+	// A connection closed early can look like a clean end of the body
+	if total > 0 && partial.Written() != total {
+		partial.Close()
+		result.Error = fmt.Errorf("download ended at byte %d of %d", partial.Written(), total)
+		return result
+	}
+	var checksum string
+	if hasher != nil {
+		checksum = hex.EncodeToString(hasher.Sum(nil))
+	}
+	if err := m.verifyChecksum(job, checksum); err != nil {
+		partial.Close()
+		result.BytesDownloaded = 0
+		result.Error = err
+		m.reportProgress(job.MediaID, 0, "failed", err.Error())
+		return result
+	}
+	commit := partial.Commit
+	if m.cipher != nil {
+		commit = func() error { return partial.CommitEncrypted(m.cipher) }
+	}
+	if err := commit(); err != nil {
+		result.Error = err
+		return result
 	}
 
 	// Calculate final stats
 	result.Success = true
 	result.Duration = time.Since(start)
 	result.BytesRead = contentLength
+	result.Size = partial.Written()
+	if hasher != nil {
+		result.Checksum = checksum
+		result.ChecksumAlgorithm = algorithm
+	}
 
 	m.logger.Info("Download completed successfully",
 		"job_id", job.ID,
@@ -440,13 +851,10 @@ func (m *Manager) processJob(job *DownloadJob) *DownloadResult {
 }
 
 // createRateLimitedReader wraps an io.Reader with rate limiting.
-func (m *Manager) createRateLimitedReader(r io.Reader) io.Reader {
-	// Get current rate limit based on time and configuration
-	currentLimit := m.getCurrentRateLimit()
-
+func (m *Manager) createRateLimitedReader(r io.Reader, limiter *rate.Limiter) io.Reader {
 	return &rateLimitedReader{
 		reader:  r,
-		limiter: currentLimit,
+		limiter: limiter,
 		ctx:     m.ctx,
 	}
 }
@@ -459,6 +867,11 @@ type rateLimitedReader struct {
 }
 
 func (r *rateLimitedReader) Read(buf []byte) (int, error) {
+	// Shares can shrink mid-download, so never ask for more than one burst
+	if burst := r.limiter.Burst(); r.limiter.Limit() != rate.Inf && len(buf) > burst {
+		buf = buf[:burst]
+	}
+
 	// Wait for rate limiter permission
 	if err := r.limiter.WaitN(r.ctx, len(buf)); err != nil {
 		return 0, err
@@ -467,91 +880,28 @@ func (r *rateLimitedReader) Read(buf []byte) (int, error) {
 	return r.reader.Read(buf)
 }
 
-// getCurrentRateLimit returns the appropriate rate limiter based on current time and configuration
-func (m *Manager) getCurrentRateLimit() *rate.Limiter {
-	// Check if we're in peak hours
-	if m.isCurrentlyPeakHours() {
-		// During peak hours, use reduced bandwidth
-		peakBandwidth := float64(m.config.RateLimitMbps) * float64(m.config.RateLimitSchedule.PeakLimitPercent) / 100.0
-		bytesPerSecond := rate.Limit(peakBandwidth * 1024 * 1024 / 8)
-		burstSize := int(bytesPerSecond * 5) // 5 second burst
-
-		m.logger.Debug("Using peak hours rate limit",
-			"peak_bandwidth_mbps", peakBandwidth,
-			"peak_limit_percent", m.config.RateLimitSchedule.PeakLimitPercent)
+// currentRateLimit returns the total download rate in bytes per second
+// based on current time and configuration
+func (m *Manager) currentRateLimit() rate.Limit {
+	bandwidth := float64(m.rateLimitMbps.Load())
 
-		return rate.NewLimiter(bytesPerSecond, burstSize)
+	// During peak hours, use reduced bandwidth
+	if m.isCurrentlyPeakHours() {
+		bandwidth = bandwidth * float64(m.config.RateLimitSchedule.PeakLimitPercent) / 100.0
 	}
 
-	// Outside peak hours, use full bandwidth
-	return m.limiter
+	return rate.Limit(bandwidth * 1024 * 1024 / 8)
 }
 
-// isCurrentlyPeakHours checks if the current time falls within configured peak hours
+// isCurrentlyPeakHours checks if the current time falls within configured
+// peak hours, or within a user's viewing window when watch-time scheduling
+// has learned them
 func (m *Manager) isCurrentlyPeakHours() bool {
-	if m.config.RateLimitSchedule.PeakHours == "" {
-		return false // No peak hours configured
-	}
-
-	// Parse peak hours format "HH:MM-HH:MM"
-	peakStart, peakEnd, err := parsePeakHours(m.config.RateLimitSchedule.PeakHours)
-	if err != nil {
-		m.logger.Warn("Invalid peak hours format, ignoring peak hour limits",
-			"peak_hours", m.config.RateLimitSchedule.PeakHours,
-			"error", err)
-		return false
-	}
-
-	now := time.Now()
-	currentTime := now.Hour()*100 + now.Minute() // Convert to HHMM format
-
-	// Handle case where peak hours span midnight
-	if peakStart > peakEnd {
-		return currentTime >= peakStart || currentTime <= peakEnd
-	}
-
-	return currentTime >= peakStart && currentTime <= peakEnd
-}
-
-// parsePeakHours parses a time range string like "06:00-23:00" into start and end times in HHMM format
-func parsePeakHours(peakHours string) (int, int, error) {
-	// Expected format: "06:00-23:00"
-	parts := strings.Split(peakHours, "-")
-	if len(parts) != 2 {
-		return 0, 0, fmt.Errorf("invalid format, expected HH:MM-HH:MM")
-	}
-
-	startTime, err := parseTimeToHHMM(strings.TrimSpace(parts[0]))
-	if err != nil {
-		return 0, 0, fmt.Errorf("invalid start time: %w", err)
-	}
-
-	endTime, err := parseTimeToHHMM(strings.TrimSpace(parts[1]))
-	if err != nil {
-		return 0, 0, fmt.Errorf("invalid end time: %w", err)
+	if m.watchTimeScheduled() {
+		inside, _ := m.inViewingWindow(time.Now())
+		return inside
 	}
-
-	return startTime, endTime, nil
-}
-
-// parseTimeToHHMM converts a time string like "06:00" to HHMM integer format (600)
-func parseTimeToHHMM(timeStr string) (int, error) {
-	timeParts := strings.Split(timeStr, ":")
-	if len(timeParts) != 2 {
-		return 0, fmt.Errorf("invalid time format, expected HH:MM")
-	}
-
-	hour, err := strconv.Atoi(timeParts[0])
-	if err != nil || hour < 0 || hour > 23 {
-		return 0, fmt.Errorf("invalid hour: %s", timeParts[0])
-	}
-
-	minute, err := strconv.Atoi(timeParts[1])
-	if err != nil || minute < 0 || minute > 59 {
-		return 0, fmt.Errorf("invalid minute: %s", timeParts[1])
-	}
-
-	return hour*100 + minute, nil
+	return m.config.RateLimitSchedule.IsPeak(time.Now())
 }
 
 // reportProgress sends a progress update via the progress reporter (WebSocket)
@@ -586,17 +936,38 @@ func (m *Manager) resultProcessor() {
 func (m *Manager) handleResult(result *DownloadResult) {
 	job := result.Job
 
+	if result.HeadFetched {
+		m.finishHead(job)
+		return
+	}
+
+	if result.Stopped != nil {
+		m.handleStopped(job, result)
+		return
+	}
+
 	if result.Success {
+		// A read-ahead request may have arrived too late to matter
+		m.takeHeadRequest(job.MediaID)
+
 		// Add to downloads bucket
 		downloadRecord := &storage.DownloadRecord{
 			ID:           job.ID,
 			MediaType:    "unknown", // TODO: Extract from job metadata
 			JellyfinID:   job.MediaID,
 			LocalPath:    job.LocalPath,
-			Size:         result.BytesRead,
+			Size:         result.Size,
 			DownloadedAt: result.CompletedAt,
 			LastAccessed: result.CompletedAt,
 			Status:       "completed",
+			Priority:     job.Priority,
+			Source:       job.Source,
+			Quality:      job.Quality,
+			Container:    job.Container,
+			Bitrate:      job.Bitrate,
+
+			Checksum:          result.Checksum,
+			ChecksumAlgorithm: result.ChecksumAlgorithm,
 		}
 
 		if err := m.storage.AddDownloadRecord(downloadRecord); err != nil {
@@ -604,13 +975,35 @@ func (m *Manager) handleResult(result *DownloadResult) {
 				"job_id", job.ID, "error", err)
 		}
 
+		if err := m.storage.RecordDownloadCompleted(result.BytesRead); err != nil {
+			m.logger.Debug("Failed to record download stats", "job_id", job.ID, "error", err)
+		}
+		recordEvent(m.storage, m.logger, &storage.Event{
+			Kind:    storage.EventDownloadCompleted,
+			MediaID: job.MediaID,
+			Message: fmt.Sprintf("Downloaded at priority %d", job.Priority),
+			Details: map[string]interface{}{
+				"size_bytes": result.Size,
+				"source":     job.Source,
+				"priority":   job.Priority,
+				"quality":    job.Quality,
+				"seconds":    result.Duration.Seconds(),
+			},
+		})
+
 		// Remove from queue
 		if err := m.storage.RemoveQueueItem(job.ID); err != nil {
 			m.logger.Error("Failed to remove completed job from queue",
 				"job_id", job.ID, "error", err)
 		}
 
+		if m.artwork != nil || m.trickplay != nil || m.nfo != nil || m.inspector != nil {
+			m.cacheExtras(job.MediaID)
+		}
+
 	} else {
+		job.BytesDownloaded = result.BytesDownloaded
+
 		// Handle failed download
 		m.logger.Error("Download failed",
 			"job_id", job.ID,
@@ -618,6 +1011,11 @@ func (m *Manager) handleResult(result *DownloadResult) {
 			"error", result.Error,
 			"retry_count", job.RetryCount)
 
+		queueItem := m.failedQueueItem(job)
+		queueItem.ErrorMessage = result.Error.Error()
+		queueItem.ErrorHistory = attemptErrors(queueItem, result)
+		queueItem.BytesDownloaded = result.BytesDownloaded
+
 		// Check if error is retryable
 		if !m.isRetryableError(result.Error, result.HTTPStatus) {
 			m.logger.Info("Error is not retryable, marking as failed",
@@ -626,111 +1024,219 @@ func (m *Manager) handleResult(result *DownloadResult) {
 				"error", result.Error)
 
 			// Mark as permanently failed
-			queueItem := &storage.QueueItem{
-				ID:           job.ID,
-				MediaID:      job.MediaID,
-				Priority:     job.Priority,
-				URL:          job.URL,
-				LocalPath:    job.LocalPath,
-				CreatedAt:    job.CreatedAt,
-				Status:       "failed",
-				RetryCount:   job.RetryCount,
-				ErrorMessage: result.Error.Error(),
-			}
+			queueItem.Status = "failed"
+			queueItem.CompletedAt = time.Now()
+			queueItem.RetryCount = job.RetryCount
 			if err := m.storage.UpdateQueueItem(queueItem); err != nil {
 				m.logger.Error("Failed to update failed queue item",
 					"job_id", job.ID, "error", err)
 			}
+			m.recordFailure(queueItem, result.HTTPStatus)
 			return
 		}
 
 		// Update queue item with error and potentially retry
-		// Use < to allow exactly 6 retries (7 total attempts including initial): 1s, 2s, 4s, 8s, 16s, 30s
 		if job.RetryCount < m.config.RetryAttempts {
-			// Schedule retry with exponential backoff and jitter
+			// The queue processor leaves the item alone until the backoff
+			// is over
 			job.RetryCount++
-			// Exponential backoff base pattern: 1s, 2s, 4s, 8s, 16s, 30s (capped)
-			// Actual delays include ±25% jitter: 0.75s-1.25s, 1.5s-2.5s, 3s-5s, 6s-10s, 12s-20s, 22.5s-37.5s
-			retryDelay := m.config.RetryDelay * time.Duration(1<<uint(job.RetryCount-1))
-			if retryDelay > 30*time.Second {
-				retryDelay = 30 * time.Second
-			}
-
-			// Add jitter: ±25% randomization to prevent thundering herd
-			// Results in delay between 75% and 125% of base backoff value
-			jitter := time.Duration(float64(retryDelay) * (0.75 + 0.5*rand.Float64()))
-			retryDelay = jitter
+			retryDelay := retryBackoff(m.config.RetryDelay, job.RetryCount, rand.Float64())
 
 			m.logger.Info("Scheduling download retry with exponential backoff and jitter",
 				"job_id", job.ID,
 				"retry_count", job.RetryCount,
-				"delay", retryDelay,
-				"base_delay_pattern", "1s, 2s, 4s, 8s, 16s, 30s with ±25% jitter")
-
-			// Exponential backoff retry scheduling implemented
-			// For now, just update the queue item status
-			queueItem := &storage.QueueItem{
-				ID:           job.ID,
-				MediaID:      job.MediaID,
-				Priority:     job.Priority,
-				URL:          job.URL,
-				LocalPath:    job.LocalPath,
-				CreatedAt:    job.CreatedAt,
-				Status:       "queued", // Reset to queued for retry
-				RetryCount:   job.RetryCount,
-				ErrorMessage: result.Error.Error(),
-			}
+				"delay", retryDelay)
+
+			queueItem.Status = "queued" // Reset to queued for retry
+			queueItem.RetryCount = job.RetryCount
+			queueItem.NextAttemptAt = time.Now().Add(retryDelay)
 
 			if err := m.storage.UpdateQueueItem(queueItem); err != nil {
 				m.logger.Error("Failed to update queue item for retry",
 					"job_id", job.ID, "error", err)
 			}
 		} else {
-			// Max retries exceeded, mark as failed
-			queueItem := &storage.QueueItem{
-				ID:           job.ID,
-				MediaID:      job.MediaID,
-				Priority:     job.Priority,
-				URL:          job.URL,
-				LocalPath:    job.LocalPath,
-				CreatedAt:    job.CreatedAt,
-				Status:       "failed",
-				RetryCount:   job.RetryCount,
-				ErrorMessage: result.Error.Error(),
-			}
+			// Max retries exceeded: park the item in the dead letter status
+			// for RetryFailedJob to retry by hand
+			m.logger.Warn("Download retries exhausted, giving up",
+				"job_id", job.ID,
+				"media_id", job.MediaID,
+				"attempts", job.RetryCount+1)
+
+			queueItem.Status = "dead_letter"
+			queueItem.CompletedAt = time.Now()
+			queueItem.RetryCount = job.RetryCount
 
 			if err := m.storage.UpdateQueueItem(queueItem); err != nil {
 				m.logger.Error("Failed to update failed queue item",
 					"job_id", job.ID, "error", err)
 			}
+			m.reportProgress(job.MediaID, 0, "dead_letter",
+				fmt.Sprintf("Gave up after %d attempts: %v", job.RetryCount+1, result.Error))
+			m.recordFailure(queueItem, result.HTTPStatus)
 		}
 	}
 }
 
+// failedQueueItem returns the stored queue item of a failed job, so that
+// what the job does not carry, such as the users wanting it and a priority
+// changed during the attempt, survives the update. If the item is gone it
+// is rebuilt from the job.
+func (m *Manager) failedQueueItem(job *DownloadJob) *storage.QueueItem {
+	if item, err := m.findQueueItem(job.ID); err == nil {
+		return item
+	}
+	return &storage.QueueItem{
+		ID:        job.ID,
+		MediaID:   job.MediaID,
+		Priority:  job.Priority,
+		URL:       job.URL,
+		LocalPath: job.LocalPath,
+		Size:      job.Size,
+		CreatedAt: job.CreatedAt,
+		Source:    job.Source,
+		Quality:   job.Quality,
+		Container: job.Container,
+		Bitrate:   job.Bitrate,
+
+		Checksum:          job.Checksum,
+		ChecksumAlgorithm: job.ChecksumAlgorithm,
+	}
+}
+
+// cacheExtras inspects a completed download, writes its .nfo and fetches
+// its artwork and seek previews in the background, so slow image requests
+// do not hold up result processing.
+func (m *Manager) cacheExtras(mediaID string) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		// ffprobe cannot read an encrypted file
+		if m.inspector != nil && m.cipher == nil {
+			if _, err := m.inspector.Inspect(m.ctx, mediaID); err != nil && m.ctx.Err() == nil {
+				m.logger.Warn("Failed to inspect download", "media_id", mediaID, "error", err)
+			}
+		}
+		if m.nfo != nil {
+			if _, err := m.nfo.Export(mediaID); err != nil {
+				m.logger.Warn("Failed to write .nfo", "media_id", mediaID, "error", err)
+			}
+		}
+		if m.artwork != nil {
+			if _, err := m.artwork.CacheArtwork(m.ctx, mediaID, false); err != nil && m.ctx.Err() == nil {
+				m.logger.Warn("Failed to cache artwork", "media_id", mediaID, "error", err)
+			}
+		}
+		if m.trickplay != nil {
+			if _, err := m.trickplay.CacheTrickplay(m.ctx, mediaID); err != nil && m.ctx.Err() == nil {
+				m.logger.Warn("Failed to cache seek previews", "media_id", mediaID, "error", err)
+			}
+		}
+	}()
+}
+
 // QueueDownload adds a media item to the download queue with specified priority.
 // This is the primary interface for the prediction engine to queue downloads.
 func (m *Manager) QueueDownload(ctx context.Context, mediaID string, priority int) (string, error) {
+	return m.QueueDownloadWithSource(ctx, mediaID, priority, SourceManual)
+}
+
+// QueueDownloadWithSource queues a media item and records who requested it.
+// Queueing is idempotent per media ID: if the item is already queued or
+// downloading, the existing job ID is returned and its priority is raised
+// when the new request is more urgent.
+func (m *Manager) QueueDownloadWithSource(ctx context.Context, mediaID string, priority int, source string) (string, error) {
+	return m.QueueDownloadWithQuality(ctx, mediaID, priority, source, "")
+}
+
+// QueueDownloadWithQuality queues a media item to be cached in the given
+// quality variant (see config.PredictionConfig.DeviceQuality). An empty
+// quality caches the original, or the preferred quality when a variant
+// selector is set. Items already queued keep their variant.
+// When the item's priority class is at its queue limit, a *QueueFullError
+// wrapping ErrQueueFull is returned, and when it does not fit in the cache
+// an *InsufficientSpaceError wrapping ErrInsufficientSpace.
+func (m *Manager) QueueDownloadWithQuality(ctx context.Context, mediaID string, priority int, source, quality string) (string, error) {
 	m.mu.RLock()
 	running := m.running
+	libraries := m.libraries
 	m.mu.RUnlock()
 
 	if !running {
 		return "", fmt.Errorf("download manager is not running")
 	}
 
+	// Only Jellyfin items are cached; other providers serve media in place
+	if id := media.ParseID(mediaID); !id.IsJellyfin() {
+		return "", fmt.Errorf("media from provider %s cannot be downloaded", id.Provider)
+	}
+
+	if err := checkLibrary(m.storage, libraries, mediaID); err != nil {
+		return "", err
+	}
+
+	m.queueMu.Lock()
+	defer m.queueMu.Unlock()
+
+	existing, err := m.storage.FindActiveQueueItem(mediaID)
+	if err != nil {
+		return "", fmt.Errorf("failed to check existing queue entries: %w", err)
+	}
+	if existing != nil {
+		if priority < existing.Priority && existing.Status == "queued" {
+			if err := m.storage.UpdateQueueItemPriority(existing.ID, priority); err != nil {
+				return "", fmt.Errorf("failed to raise queue priority: %w", err)
+			}
+			m.logger.Debug("Raised priority of already queued download",
+				"media_id", mediaID,
+				"job_id", existing.ID,
+				"old_priority", existing.Priority,
+				"new_priority", priority)
+			existing.Priority = priority
+		}
+		// Asking for a predicted item makes it the asker's, so reconciling
+		// predictions no longer cancels it
+		if existing.Source == SourcePrediction && source != SourcePrediction && existing.Status == "queued" {
+			existing.Source = source
+			if err := m.storage.UpdateQueueItem(existing); err != nil {
+				return "", fmt.Errorf("failed to update queue source: %w", err)
+			}
+			m.logger.Debug("Took over predicted download",
+				"media_id", mediaID,
+				"job_id", existing.ID,
+				"source", source)
+		}
+		return existing.ID, nil
+	}
+
 	// Create download job for the media item
-	// Note: URL and other details would need to be fetched from Jellyfin API
+	// Note: LocalPath would be populated by Jellyfin API integration
 	job := &DownloadJob{
 		ID:        fmt.Sprintf("%s-%d", mediaID, time.Now().Unix()),
 		MediaID:   mediaID,
 		Priority:  priority,
+		Size:      m.mediaSize(mediaID),
 		CreatedAt: time.Now(),
-		// URL and LocalPath would be populated by Jellyfin API integration
+		Source:    source,
+		Quality:   quality,
+	}
+	if err := m.selectVariant(ctx, job); err != nil {
+		return "", err
+	}
+
+	if err := m.checkQueueLimit(priority, job.Size); err != nil {
+		return "", err
+	}
+	if err := m.checkCapacity(mediaID, priority, job.Size); err != nil {
+		return "", err
 	}
 
 	m.logger.Debug("Queuing download",
 		"media_id", mediaID,
 		"priority", priority,
+		"source", source,
+		"quality", job.Quality,
+		"size", job.Size,
 		"job_id", job.ID)
 
 	return job.ID, m.AddJob(job)
@@ -742,6 +1248,12 @@ type QueueStats struct {
 	ActiveDownloads int `json:"active_downloads"`
 	CompletedToday  int `json:"completed_today"`
 	FailedToday     int `json:"failed_today"`
+
+	// Download attempts since startup that resumed a partial file, how many
+	// bytes that saved, and attempts that had to start from byte 0
+	ResumedDownloads int   `json:"resumed_downloads"`
+	ResumedBytes     int64 `json:"resumed_bytes"`
+	ColdStarts       int   `json:"cold_starts"`
 }
 
 // GetQueueStats returns current download queue statistics for monitoring.
@@ -766,9 +1278,13 @@ func (m *Manager) GetQueueStats() QueueStats {
 	// In a full implementation, we'd track completed/failed counts
 	return QueueStats{
 		QueueSize:       totalQueue,
-		ActiveDownloads: len(m.jobs), // Approximate active downloads
-		CompletedToday:  0,           // Would track in storage
-		FailedToday:     0,           // Would track in storage
+		ActiveDownloads: m.jobs.len(), // Approximate active downloads
+		CompletedToday:  0,            // Would track in storage
+		FailedToday:     0,            // Would track in storage
+
+		ResumedDownloads: int(m.resumes.resumed.Load()),
+		ResumedBytes:     m.resumes.savedBytes.Load(),
+		ColdStarts:       int(m.resumes.coldStarts.Load()),
 	}
 }
 
@@ -792,29 +1308,10 @@ func (m *Manager) GetStatus() (map[string]interface{}, error) {
 		"running":     m.running,
 		"workers":     m.workers,
 		"queue_sizes": queueSizes,
-		"rate_limit":  m.config.RateLimitMbps,
+		"rate_limit":  int(m.rateLimitMbps.Load()),
 	}, nil
 }
 
-// RemoveFromQueue removes an item from the download queue.
-// If the item is currently downloading, it cancels the download.
-func (m *Manager) RemoveFromQueue(ctx context.Context, queueID string) error {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	if !m.running {
-		return fmt.Errorf("download manager not running")
-	}
-
-	// Remove from storage queue
-	if err := m.storage.RemoveQueueItem(queueID); err != nil {
-		return fmt.Errorf("failed to remove from storage queue: %w", err)
-	}
-
-	m.logger.Info("Removed item from download queue", "queue_id", queueID)
-	return nil
-}
-
 // isRetryableError determines if a download error should trigger a retry.
 // Returns false for permanent failures (404, 403, 410) and true for transient errors.
 func (m *Manager) isRetryableError(err error, httpStatus int) bool {
@@ -0,0 +1,60 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+)
+
+// tracksAhead is how many upcoming tracks or chapters are queued when
+// playback of a music track or audiobook chapter starts. Audio files are
+// small, so caching a little further ahead than for episodes is cheap.
+const tracksAhead = 2
+
+// predictNextTracks queues the tracks (or audiobook chapters) following
+// mediaID in album order: the next one at Priority 1 and the rest at
+// Priority 2. Callers must hold p.mu.
+func (p *Predictor) predictNextTracks(ctx context.Context, albumID, mediaID string) error {
+	tracks, err := p.storage.GetAlbumTracks(albumID)
+	if err != nil {
+		return fmt.Errorf("failed to get album tracks: %w", err)
+	}
+
+	current := -1
+	for i, track := range tracks {
+		if track.ID == mediaID {
+			current = i
+			break
+		}
+	}
+	if current < 0 {
+		return nil
+	}
+
+	for offset := 1; offset <= tracksAhead && current+offset < len(tracks); offset++ {
+		track := tracks[current+offset]
+
+		cached, err := p.storage.IsMediaCached(track.ID)
+		if err != nil || cached || p.downloadManager == nil {
+			continue
+		}
+
+		priority := 1
+		if offset > 1 {
+			priority = 2
+		}
+		if _, err := p.downloadManager.QueueDownloadWithSource(ctx, track.ID, priority, SourcePlayback); err != nil {
+			p.logger.Error("Failed to queue next track download",
+				"track_id", track.ID,
+				"error", err)
+			continue
+		}
+		p.logger.Info("Queued next track for download",
+			"album_id", albumID,
+			"track_id", track.ID,
+			"disc", track.Disc,
+			"track", track.Track,
+			"priority", priority)
+	}
+
+	return nil
+}
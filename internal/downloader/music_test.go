@@ -0,0 +1,60 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestPredictNextTracks(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	for _, metadata := range []*storage.MediaMetadata{
+		{ID: "t1", JellyfinID: "t1", Type: "audio", AlbumID: "album", DiscNumber: 1, TrackNumber: 1},
+		{ID: "t2", JellyfinID: "t2", Type: "audio", AlbumID: "album", DiscNumber: 1, TrackNumber: 2},
+		{ID: "t3", JellyfinID: "t3", Type: "audio", AlbumID: "album", DiscNumber: 2, TrackNumber: 1},
+		{ID: "t4", JellyfinID: "t4", Type: "audio", AlbumID: "album", DiscNumber: 2, TrackNumber: 2},
+		{ID: "c1", JellyfinID: "c1", Type: "audiobook", AlbumID: "book", TrackNumber: 1},
+		{ID: "c2", JellyfinID: "c2", Type: "audiobook", AlbumID: "book", TrackNumber: 2},
+	} {
+		require.NoError(t, store.AddMediaMetadata(metadata))
+	}
+
+	newPredictor := func() (*Predictor, *recordingQueuer) {
+		predictor := NewPredictor(store, &config.PredictionConfig{}, logger)
+		queuer := &recordingQueuer{}
+		predictor.SetDownloadManager(queuer)
+		return predictor, queuer
+	}
+
+	t.Run("queues the next tracks across discs", func(t *testing.T) {
+		predictor, queuer := newPredictor()
+		require.NoError(t, predictor.OnPlaybackStart(context.Background(), "t2"))
+		assert.Equal(t, []string{"t2", "t3", "t4"}, queuer.queued)
+	})
+
+	t.Run("skips cached tracks", func(t *testing.T) {
+		require.NoError(t, store.AddDownloadRecord(&storage.DownloadRecord{ID: "t2", JellyfinID: "t2", MediaType: "audio", Status: "completed"}))
+		predictor, queuer := newPredictor()
+		require.NoError(t, predictor.OnPlaybackStart(context.Background(), "t1"))
+		assert.Equal(t, []string{"t1", "t3"}, queuer.queued)
+	})
+
+	t.Run("next audiobook chapter", func(t *testing.T) {
+		predictor, queuer := newPredictor()
+		require.NoError(t, predictor.OnPlaybackStart(context.Background(), "c2"))
+		assert.Equal(t, []string{"c2"}, queuer.queued, "nothing follows the last chapter")
+
+		predictor, queuer = newPredictor()
+		require.NoError(t, predictor.OnPlaybackStart(context.Background(), "c1"))
+		assert.Equal(t, []string{"c1", "c2"}, queuer.queued)
+	})
+}
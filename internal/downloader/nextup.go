@@ -0,0 +1,126 @@
+package downloader
+
+import (
+	"context"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+)
+
+// Confidence of Priority 1 predictions Jellyfin reports: a partly watched
+// item is the surest thing to be played next.
+const (
+	serverResumeConfidence = 0.95
+	serverNextUpConfidence = 0.9
+)
+
+// serverNextUpLimit is how many items each server list is fetched with.
+const serverNextUpLimit = 20
+
+// ServerNextUpSource lists what Jellyfin itself expects a user to watch
+// next (implemented by jellyfin.Client). A metadata source that also
+// implements it lets prediction.server_next_up merge those lists into
+// continue watching predictions.
+type ServerNextUpSource interface {
+	GetResumeItems(ctx context.Context, userID string, limit int) ([]jellyfin.MediaItem, error)
+	GetUserNextUp(ctx context.Context, userID string, limit int) ([]jellyfin.MediaItem, error)
+}
+
+// refreshServerNextUp pulls userID's resume and Next Up lists along with
+// the viewing history. When a pull fails both lists are dropped, leaving
+// continue watching to local predictions. Callers must hold p.mu.
+func (p *Predictor) refreshServerNextUp(ctx context.Context, userID string) {
+	p.serverResume, p.serverNextUp = nil, nil
+
+	source, ok := p.metadata.(ServerNextUpSource)
+	if !ok || !p.config.ServerNextUp {
+		return
+	}
+
+	resume, err := source.GetResumeItems(ctx, userID, serverNextUpLimit)
+	if err != nil {
+		p.logger.Warn("Failed to pull resume items", "user_id", userID, "error", err)
+		return
+	}
+	nextUp, err := source.GetUserNextUp(ctx, userID, serverNextUpLimit)
+	if err != nil {
+		p.logger.Warn("Failed to pull Next Up", "user_id", userID, "error", err)
+		return
+	}
+	p.serverResume, p.serverNextUp = resume, nextUp
+	p.logger.Debug("Server Next Up refreshed", "resume", len(resume), "next_up", len(nextUp))
+}
+
+// mergeServerNextUp reconciles local continue watching predictions with
+// the lists Jellyfin reported. Every item on them becomes a Priority 1
+// prediction; where a local prediction is for the same item or the same
+// series, the server's item replaces it at the higher of the two
+// confidences. Server items already cached or in an excluded library are
+// left out and replace nothing; local predictions Jellyfin did not report
+// are kept.
+func (p *Predictor) mergeServerNextUp(local []PredictionResult) []PredictionResult {
+	if len(p.serverResume) == 0 && len(p.serverNextUp) == 0 {
+		return local
+	}
+
+	byMedia := make(map[string]int, len(local))
+	bySeries := make(map[string]int, len(local))
+	for i, pred := range local {
+		byMedia[pred.MediaID] = i
+		if pred.SeriesID != "" {
+			bySeries[pred.SeriesID] = i
+		}
+	}
+
+	var merged []PredictionResult
+	replaced := make(map[int]bool)
+	seen := make(map[string]bool)
+	add := func(items []jellyfin.MediaItem, confidence float64, reason string) {
+		for _, item := range items {
+			mediaType := jellyfin.CacheMediaType(item.Type)
+			if seen[item.ID] || mediaType == "" {
+				continue
+			}
+			seen[item.ID] = true
+
+			if checkLibrary(p.storage, p.libraries, item.ID) != nil {
+				continue
+			}
+			if cached, err := p.storage.IsMediaCached(item.ID); err != nil || cached {
+				continue
+			}
+
+			pred := PredictionResult{
+				MediaID:       item.ID,
+				Priority:      1,
+				Confidence:    confidence,
+				Reason:        reason,
+				SeriesID:      item.SeriesID,
+				Season:        item.SeasonNumber,
+				Episode:       item.EpisodeNumber,
+				MediaType:     mediaType,
+				EstimatedSize: item.Size,
+			}
+
+			i, ok := byMedia[item.ID]
+			if !ok && item.SeriesID != "" {
+				i, ok = bySeries[item.SeriesID]
+			}
+			if ok && !replaced[i] {
+				replaced[i] = true
+				if local[i].Confidence > pred.Confidence {
+					pred.Confidence = local[i].Confidence
+				}
+			}
+			merged = append(merged, pred)
+		}
+	}
+	add(p.serverResume, serverResumeConfidence, "Resume on Jellyfin")
+	add(p.serverNextUp, serverNextUpConfidence, "Next Up on Jellyfin")
+
+	for i, pred := range local {
+		if !replaced[i] {
+			merged = append(merged, pred)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,78 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+var _ ServerNextUpSource = (*jellyfin.Client)(nil)
+var _ ServerNextUpSource = (*jellyfintest.Mock)(nil)
+
+func TestMergeServerNextUp(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	predictor := NewPredictor(store, &config.PredictionConfig{HistoryDays: 30, MinConfidence: 0.7, ServerNextUp: true}, logger)
+
+	source := jellyfintest.New("http://jellyfin.local")
+	source.Resume = map[string][]jellyfin.MediaItem{
+		"user": {{ID: "film", Type: "Movie", Size: 4 << 30}},
+	}
+	source.NextUp = []jellyfin.MediaItem{
+		{ID: "got-s1e5", Type: "Episode", SeriesID: "got", SeasonNumber: 1, EpisodeNumber: 5},
+		{ID: "bb-s2e1", Type: "Episode", SeriesID: "bb", SeasonNumber: 2, EpisodeNumber: 1},
+		{ID: "cached", Type: "Episode", SeriesID: "lost", SeasonNumber: 1, EpisodeNumber: 2},
+	}
+	predictor.SetMetadataSource(source)
+	require.NoError(t, store.AddDownloadRecord(&storage.DownloadRecord{
+		ID: "cached", JellyfinID: "cached", MediaType: "episode", Status: "completed",
+	}))
+
+	local := []PredictionResult{
+		{MediaID: "got_S01E04", Priority: 1, Confidence: 0.98, SeriesID: "got", Season: 1, Episode: 4},
+		{MediaID: "bb_S01E09", Priority: 1, Confidence: 0.75, SeriesID: "bb", Season: 1, Episode: 9},
+		{MediaID: "lost_S01E02", Priority: 1, Confidence: 0.8, SeriesID: "lost", Season: 1, Episode: 2},
+		{MediaID: "office_S03E01", Priority: 1, Confidence: 0.8, SeriesID: "office", Season: 3, Episode: 1},
+	}
+
+	assert.Equal(t, local, predictor.mergeServerNextUp(local), "nothing to merge before the lists are pulled")
+
+	predictor.refreshServerNextUp(context.Background(), "user")
+	merged := predictor.mergeServerNextUp(local)
+
+	ids := make([]string, 0, len(merged))
+	for _, pred := range merged {
+		ids = append(ids, pred.MediaID)
+	}
+	assert.Equal(t, []string{"film", "got-s1e5", "bb-s2e1", "lost_S01E02", "office_S03E01"}, ids,
+		"server items replace local guesses for their series; cached ones are left out and replace nothing")
+	assert.Equal(t, serverResumeConfidence, merged[0].Confidence)
+	assert.Equal(t, "movie", merged[0].MediaType)
+	assert.Equal(t, 0.98, merged[1].Confidence, "the higher local confidence is kept")
+	assert.Equal(t, serverNextUpConfidence, merged[2].Confidence)
+	assert.Equal(t, 2, merged[2].Season)
+	for _, pred := range merged {
+		assert.Equal(t, 1, pred.Priority)
+	}
+
+	// When Jellyfin cannot be reached local predictions stand alone
+	source.Err = assert.AnError
+	predictor.refreshServerNextUp(context.Background(), "user")
+	assert.Equal(t, local, predictor.mergeServerNextUp(local))
+
+	// As they do with the option off
+	source.Err = nil
+	predictor.config.ServerNextUp = false
+	predictor.refreshServerNextUp(context.Background(), "user")
+	assert.Equal(t, local, predictor.mergeServerNextUp(local))
+}
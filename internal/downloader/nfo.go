@@ -0,0 +1,147 @@
+package downloader
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// NFOStore is the storage the .nfo exporter reads items from.
+type NFOStore interface {
+	GetDownload(mediaID string) (*storage.DownloadRecord, error)
+	FindMediaMetadata(mediaID string) (*storage.MediaMetadata, error)
+	ListDownloadRecords(mediaType string) ([]*storage.DownloadRecord, error)
+}
+
+// NFOExporter writes Kodi-compatible .nfo files next to cached movies and
+// episodes when cache.nfo_export is on, keeping them in step with the
+// stored metadata.
+type NFOExporter struct {
+	store  NFOStore
+	files  *storage.FileManager
+	config *config.CacheConfig
+	logger *slog.Logger
+}
+
+// NewNFOExporter creates an exporter that reads from store.
+func NewNFOExporter(store NFOStore, cfg *config.CacheConfig, logger *slog.Logger) *NFOExporter {
+	return &NFOExporter{
+		store:  store,
+		files:  storage.NewFileManager(cfg.TempDirectory, logger),
+		config: cfg,
+		logger: logger,
+	}
+}
+
+// Enabled reports whether .nfo export is turned on.
+func (e *NFOExporter) Enabled() bool {
+	return e.config.NFOExport
+}
+
+// Export writes the .nfo of a cached movie or episode and reports whether
+// it did. Other items, items that are not cached and items without stored
+// metadata are skipped.
+func (e *NFOExporter) Export(mediaID string) (bool, error) {
+	if !e.Enabled() {
+		return false, nil
+	}
+	record, err := e.store.GetDownload(mediaID)
+	if err != nil || record.LocalPath == "" || record.Status == "evicted" {
+		return false, nil
+	}
+	if _, err := os.Stat(record.LocalPath); err != nil {
+		return false, nil
+	}
+	metadata, err := e.store.FindMediaMetadata(mediaID)
+	if err != nil || metadata == nil || (metadata.Type != "movie" && metadata.Type != "episode") {
+		return false, nil
+	}
+
+	var series *storage.MediaMetadata
+	if metadata.Type == "episode" && metadata.SeriesID != "" {
+		if series, err = e.store.FindMediaMetadata(metadata.SeriesID); err != nil {
+			return false, err
+		}
+	}
+
+	if err := e.files.WriteNFO(record.LocalPath, metadata, series); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ExportAll writes the .nfo of every cached item and returns how many it
+// wrote.
+func (e *NFOExporter) ExportAll(ctx context.Context) (int, error) {
+	if !e.Enabled() {
+		return 0, nil
+	}
+	records, err := e.store.ListDownloadRecords("")
+	if err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		ok, err := e.Export(record.JellyfinID)
+		if err != nil {
+			e.logger.Warn("Failed to write .nfo", "media_id", record.JellyfinID, "error", err)
+			continue
+		}
+		if ok {
+			written++
+		}
+	}
+	return written, nil
+}
+
+// LibraryChanged rewrites the .nfo of cached items whose metadata library
+// sync found changed. A changed series rewrites the files of its cached
+// episodes, whose tvshow.nfo and show titles come from it. Register it
+// with jellyfin.LibrarySync.OnChange.
+func (e *NFOExporter) LibraryChanged(change jellyfin.LibraryChange) {
+	if !e.Enabled() {
+		return
+	}
+
+	var seriesIDs map[string]bool
+	for _, items := range [][]jellyfin.MediaItem{change.Added, change.Updated} {
+		for _, item := range items {
+			if item.Type == "Series" {
+				if seriesIDs == nil {
+					seriesIDs = make(map[string]bool)
+				}
+				seriesIDs[item.ID] = true
+				continue
+			}
+			if _, err := e.Export(item.ID); err != nil {
+				e.logger.Warn("Failed to write .nfo", "media_id", item.ID, "error", err)
+			}
+		}
+	}
+	if len(seriesIDs) == 0 {
+		return
+	}
+
+	records, err := e.store.ListDownloadRecords("")
+	if err != nil {
+		e.logger.Warn("Failed to list downloads for .nfo export", "error", err)
+		return
+	}
+	for _, record := range records {
+		metadata, err := e.store.FindMediaMetadata(record.JellyfinID)
+		if err != nil || metadata == nil || !seriesIDs[metadata.SeriesID] {
+			continue
+		}
+		if _, err := e.Export(record.JellyfinID); err != nil {
+			e.logger.Warn("Failed to write .nfo", "media_id", record.JellyfinID, "error", err)
+		}
+	}
+}
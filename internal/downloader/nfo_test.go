@@ -0,0 +1,85 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestNFOExporter(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	dir := t.TempDir()
+
+	moviePath := filepath.Join(dir, "movies", "m1", "Heat.mkv")
+	episodePath := filepath.Join(dir, "series", "s1", "S01E02", "episode.mkv")
+	for id, path := range map[string]string{"m1": moviePath, "e1": episodePath} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte("video"), 0644))
+		require.NoError(t, store.AddDownloadRecord(&storage.DownloadRecord{
+			ID: id, JellyfinID: id, MediaType: "unknown", LocalPath: path, Status: "completed",
+		}))
+	}
+	for _, metadata := range []*storage.MediaMetadata{
+		{ID: "m1", JellyfinID: "m1", Name: "Heat", Type: "movie", Overview: "Cops & robbers", Genres: []string{"Crime", "Drama"},
+			RunTimeTicks: int64(170 * time.Minute / 100), DateCreated: time.Date(2024, 3, 1, 20, 0, 0, 0, time.UTC)},
+		{ID: "s1", JellyfinID: "s1", Name: "Bluey", Type: "series", Genres: []string{"Kids"}},
+		{ID: "e1", JellyfinID: "e1", Name: "Camping", Type: "episode", SeriesID: "s1", SeasonNumber: 1, EpisodeNumber: 2},
+	} {
+		require.NoError(t, store.AddMediaMetadata(metadata))
+	}
+
+	cfg := &config.CacheConfig{TempDirectory: t.TempDir()}
+	exporter := NewNFOExporter(store, cfg, logger)
+
+	written, err := exporter.ExportAll(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, written, "nothing is written while export is off")
+
+	cfg.NFOExport = true
+	written, err = exporter.ExportAll(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, written)
+
+	movie, err := os.ReadFile(filepath.Join(dir, "movies", "m1", "Heat.nfo"))
+	require.NoError(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<movie>
+  <title>Heat</title>
+  <plot>Cops &amp; robbers</plot>
+  <genre>Crime</genre>
+  <genre>Drama</genre>
+  <runtime>170</runtime>
+  <dateadded>2024-03-01 20:00:00</dateadded>
+  <uniqueid type="jellyfin" default="true">m1</uniqueid>
+</movie>
+`, string(movie))
+
+	episode, err := os.ReadFile(storage.NFOPath(episodePath))
+	require.NoError(t, err)
+	assert.Contains(t, string(episode), "<showtitle>Bluey</showtitle>")
+	assert.Contains(t, string(episode), "<season>1</season>")
+	assert.Contains(t, string(episode), "<episode>2</episode>")
+
+	// A renamed series rewrites the files of its cached episodes
+	require.NoError(t, store.AddMediaMetadata(&storage.MediaMetadata{ID: "s1", JellyfinID: "s1", Name: "Bluey (2018)", Type: "series"}))
+	exporter.LibraryChanged(jellyfin.LibraryChange{Updated: []jellyfin.MediaItem{{ID: "s1", Type: "Series"}}})
+	show, err := os.ReadFile(storage.ShowNFOPath(episodePath))
+	require.NoError(t, err)
+	assert.Contains(t, string(show), "<title>Bluey (2018)</title>")
+	episode, err = os.ReadFile(storage.NFOPath(episodePath))
+	require.NoError(t, err)
+	assert.Contains(t, string(episode), "<showtitle>Bluey (2018)</showtitle>")
+}
@@ -0,0 +1,214 @@
+package downloader
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// playbackIdleTimeout is how long a player may stop fetching a stream
+// before its playback counts as ended.
+const playbackIdleTimeout = 2 * time.Minute
+
+// playbackWriteInterval limits how often the progress of a playback still
+// running is written to the viewing history.
+const playbackWriteInterval = 30 * time.Second
+
+// PlaybackTracker turns streams served from the cache into viewing
+// sessions of the local user. Players fetch a file as a series of range
+// requests, so a playback is an item streamed to one client, spanning
+// every request until the client stops fetching for a while. How much of
+// the file was sent stands in for how much was watched: seeking past a
+// part leaves it out, and the few bytes players read from the end of a
+// file to find its index barely count.
+type PlaybackTracker struct {
+	storage   storage.Store
+	predictor *Predictor
+	userID    string
+	logger    *slog.Logger
+
+	// now is stubbed by tests
+	now func() time.Time
+
+	mu        sync.Mutex
+	playbacks map[string]*streamPlayback // keyed by client and item
+}
+
+// streamPlayback is the progress of one item streamed to one client.
+type streamPlayback struct {
+	mediaID   string
+	device    string
+	size      int64
+	served    [][2]int64 // sorted, disjoint byte ranges sent
+	started   time.Time
+	lastSeen  time.Time
+	written   time.Time // last time progress was recorded
+	completed bool
+}
+
+// NewPlaybackTracker creates a tracker recording streams as viewing
+// sessions of userID. predictor may be nil.
+func NewPlaybackTracker(storage storage.Store, predictor *Predictor, userID string, logger *slog.Logger) *PlaybackTracker {
+	return &PlaybackTracker{
+		storage:   storage,
+		predictor: predictor,
+		userID:    userID,
+		logger:    logger,
+		now:       time.Now,
+		playbacks: make(map[string]*streamPlayback),
+	}
+}
+
+// StreamServed records that bytes [start, end) of the cached file of
+// mediaID, size bytes long, were sent to client. userAgent identifies the
+// device class. Progress is written at most every playbackWriteInterval,
+// and straight away once the playback becomes completed.
+func (t *PlaybackTracker) StreamServed(mediaID, client, userAgent string, start, end, size int64) {
+	if t.userID == "" || end <= start || size <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	key := client + "/" + mediaID
+	playback, ok := t.playbacks[key]
+	if !ok {
+		playback = &streamPlayback{
+			mediaID: mediaID,
+			device:  ClassifyUserAgent(userAgent),
+			size:    size,
+			started: now,
+		}
+		t.playbacks[key] = playback
+	}
+	playback.lastSeen = now
+	playback.served = addByteRange(playback.served, start, end)
+
+	completed := playback.watched() >= completionThreshold
+	if now.Sub(playback.written) < playbackWriteInterval && completed == playback.completed {
+		return
+	}
+	playback.completed = completed
+	t.record(playback)
+}
+
+// CloseIdle ends every playback whose client stopped fetching and records
+// its final progress. It returns how many it ended.
+func (t *PlaybackTracker) CloseIdle() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cutoff := t.now().Add(-playbackIdleTimeout)
+	closed := 0
+	for key, playback := range t.playbacks {
+		if playback.lastSeen.After(cutoff) {
+			continue
+		}
+		t.record(playback)
+		delete(t.playbacks, key)
+		closed++
+	}
+	return closed
+}
+
+// Run ends idle playbacks until ctx is cancelled, and records every
+// playback still open when it is.
+func (t *PlaybackTracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(playbackIdleTimeout / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.mu.Lock()
+			for _, playback := range t.playbacks {
+				t.record(playback)
+			}
+			t.mu.Unlock()
+			return
+		case <-ticker.C:
+			if n := t.CloseIdle(); n > 0 {
+				t.logger.Debug("Closed finished playbacks", "count", n)
+			}
+		}
+	}
+}
+
+// record writes the viewing session of playback. The caller holds t.mu.
+func (t *PlaybackTracker) record(playback *streamPlayback) {
+	if err := t.storage.UpsertViewingSession(t.userID, t.session(playback)); err != nil {
+		t.logger.Warn("Failed to record playback progress",
+			"user_id", t.userID, "media_id", playback.mediaID, "error", err)
+		return
+	}
+	playback.written = t.now()
+
+	if t.predictor != nil {
+		t.predictor.HistoryChanged(t.userID)
+	}
+}
+
+// session converts playback into a viewing session. The watched time is
+// the watched share of the item's runtime, or the time spent streaming
+// when the runtime is unknown.
+func (t *PlaybackTracker) session(playback *streamPlayback) storage.ViewingSession {
+	session := storage.ViewingSession{
+		MediaID:     playback.mediaID,
+		StartTime:   playback.started,
+		EndTime:     playback.lastSeen,
+		WatchedTime: int64(playback.lastSeen.Sub(playback.started).Seconds()),
+		Completed:   playback.watched() >= completionThreshold,
+		DeviceType:  playback.device,
+	}
+
+	if metadata, err := t.storage.GetMediaMetadata(playback.mediaID); err == nil {
+		session.MediaType = metadata.Type
+		session.SeriesID = metadata.SeriesID
+		session.Season = metadata.SeasonNumber
+		session.Episode = metadata.EpisodeNumber
+		if metadata.RunTimeTicks > 0 {
+			duration := ticksToDuration(metadata.RunTimeTicks)
+			session.Duration = int64(duration.Seconds())
+			session.WatchedTime = int64(duration.Seconds() * playback.watched())
+		}
+	}
+	return session
+}
+
+// watched returns the share of the file sent so far.
+func (p *streamPlayback) watched() float64 {
+	var sent int64
+	for _, r := range p.served {
+		sent += r[1] - r[0]
+	}
+	return float64(sent) / float64(p.size)
+}
+
+// addByteRange merges [start, end) into the sorted, disjoint ranges.
+func addByteRange(ranges [][2]int64, start, end int64) [][2]int64 {
+	// Players mostly read on from where they left off
+	if n := len(ranges); n > 0 && ranges[n-1][0] <= start && start <= ranges[n-1][1] {
+		ranges[n-1][1] = max(ranges[n-1][1], end)
+		return ranges
+	}
+
+	ranges = append(ranges, [2]int64{start, end})
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r[0] <= last[1] {
+			last[1] = max(last[1], r[1])
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
@@ -0,0 +1,76 @@
+package downloader
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+)
+
+func TestPlaybackTracker(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	require.NoError(t, store.AddMediaMetadata(&storage.MediaMetadata{
+		ID: "e1", JellyfinID: "e1", Type: "episode", SeriesID: "s1", SeasonNumber: 1, EpisodeNumber: 3,
+		RunTimeTicks: 40 * ticksPerMinute,
+	}))
+
+	tracker := NewPlaybackTracker(store, nil, "alice", logger)
+	now := time.Now().Truncate(time.Second)
+	tracker.now = func() time.Time { return now }
+
+	const size = 1000
+	const tv = "Mozilla/5.0 (SMART-TV; Linux; Tizen 6.0)"
+
+	// The player reads the index at the end, then plays from the start
+	tracker.StreamServed("e1", "10.0.0.5", tv, 990, 1000, size)
+	for offset := int64(0); offset < 800; offset += 100 {
+		now = now.Add(4 * time.Minute)
+		tracker.StreamServed("e1", "10.0.0.5", tv, offset, offset+100, size)
+	}
+
+	history, err := store.GetViewingHistory("alice", 30)
+	require.NoError(t, err)
+	require.Len(t, history, 1, "range requests of one playback share a session")
+	assert.Equal(t, "episode", history[0].MediaType)
+	assert.Equal(t, 3, history[0].Episode)
+	assert.Equal(t, int64(2400), history[0].Duration)
+	assert.False(t, history[0].Completed, "81% of the file was sent")
+
+	// Crossing the threshold is recorded straight away
+	now = now.Add(time.Second)
+	tracker.StreamServed("e1", "10.0.0.5", tv, 800, 900, size)
+	history, _ = store.GetViewingHistory("alice", 30)
+	require.Len(t, history, 1)
+	assert.True(t, history[0].Completed)
+	assert.Equal(t, int64(2400*0.91), history[0].WatchedTime)
+	assert.Equal(t, DeviceTV, history[0].DeviceType)
+
+	// Once the client stops fetching the playback is closed, and
+	// streaming the item again starts a new session
+	assert.Equal(t, 0, tracker.CloseIdle())
+	now = now.Add(playbackIdleTimeout + time.Second)
+	assert.Equal(t, 1, tracker.CloseIdle())
+
+	tracker.StreamServed("e1", "10.0.0.5", tv, 0, 100, size)
+	history, _ = store.GetViewingHistory("alice", 30)
+	assert.Len(t, history, 2)
+}
+
+func TestAddByteRange(t *testing.T) {
+	var ranges [][2]int64
+	ranges = addByteRange(ranges, 0, 100)
+	ranges = addByteRange(ranges, 100, 200)
+	ranges = addByteRange(ranges, 500, 600)
+	ranges = addByteRange(ranges, 300, 400)
+	assert.Equal(t, [][2]int64{{0, 200}, {300, 400}, {500, 600}}, ranges)
+
+	ranges = addByteRange(ranges, 150, 550)
+	assert.Equal(t, [][2]int64{{0, 600}}, ranges)
+}
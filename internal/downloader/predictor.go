@@ -4,8 +4,9 @@
 // The predictor analyzes Jellyfin viewing history to automatically queue
 // likely-next media for download, following the priority system:
 // - Priority 0: Currently playing (immediate download)
-// - Priority 1: Next unwatched episode in active series
+// - Priority 1: Next unwatched episode in active series, or Jellyfin's Next Up
 // - Priority 2: Following 2-3 episodes in sequence
+// - Watchlist: Unwatched favorites, at prediction.watchlist_priority
 // - Priority 3: New items matching viewing history
 // - Priority 4: Popular items in preferred genres
 package downloader
@@ -15,8 +16,11 @@ import (
 	"fmt"
 	"log/slog"
 	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
 	"github.com/opd-ai/go-jf-watch/internal/storage"
 	"github.com/opd-ai/go-jf-watch/pkg/config"
 )
@@ -25,27 +29,68 @@ import (
 // It maintains viewing history and user preferences to make intelligent
 // predictions about what content should be pre-cached.
 type Predictor struct {
-	storage         *storage.Manager
+	storage         storage.Store
 	logger          *slog.Logger
 	config          *config.PredictionConfig
 	downloadManager DownloadQueuer
+	libraries       *config.LibraryFilterConfig
+
+	// mu serializes prediction runs, playback triggers and queue
+	// reconciliation so they never race on history or the queue.
+	mu sync.Mutex
+
+	// paused turns predictions off while running; playback still queues
+	// what is playing
+	paused atomic.Bool
+
+	// tripEpisodes, during a trip, is how many episodes ahead of each
+	// active series are predicted whatever the binge rate; 0 otherwise
+	tripEpisodes int
 
 	// Cached analysis data
 	viewingHistory []ViewingSession
+	historyUser    string // user the cached history belongs to
 	preferences    UserPreferences
 	lastSync       time.Time
+
+	// New library items reported by library sync, keyed by media ID
+	recentlyAdded map[string]recentItem
+
+	// The user's favorites as of the last history refresh, when the
+	// metadata source is a WatchlistSource
+	watchlist []jellyfin.MediaItem
+
+	// The user's resume and Next Up lists as of the last history refresh,
+	// when prediction.server_next_up is set
+	serverResume []jellyfin.MediaItem
+	serverNextUp []jellyfin.MediaItem
+
+	// How earlier predicted downloads turned out, keyed by priority
+	accuracy map[int]PriorityAccuracy
+
+	// Where metadata missing from storage is looked up; nil leaves
+	// sessions without metadata out of genre analysis. Popular items for
+	// trending predictions come from it too when it is a TrendingSource
+	metadata MetadataSource
 }
 
 // DownloadQueuer interface for queueing downloads (implemented by Manager)
 type DownloadQueuer interface {
 	QueueDownload(ctx context.Context, mediaID string, priority int) (string, error)
+	QueueDownloadWithSource(ctx context.Context, mediaID string, priority int, source string) (string, error)
+}
+
+// QualityQueuer is implemented by download queuers that can cache a
+// transcoded variant instead of the original (implemented by Manager).
+type QualityQueuer interface {
+	QueueDownloadWithQuality(ctx context.Context, mediaID string, priority int, source, quality string) (string, error)
 }
 
 // ViewingSession represents a single media viewing session with metadata.
 // Used to track user behavior patterns for prediction analysis.
 type ViewingSession struct {
 	MediaID      string    `json:"media_id"`
-	MediaType    string    `json:"media_type"` // "movie", "episode"
+	MediaType    string    `json:"media_type"` // "movie", "episode", "audio", "audiobook"
 	SeriesID     string    `json:"series_id,omitempty"`
 	Season       int       `json:"season,omitempty"`
 	Episode      int       `json:"episode,omitempty"`
@@ -96,11 +141,14 @@ type PredictionResult struct {
 	Episode       int     `json:"episode,omitempty"`
 	MediaType     string  `json:"media_type"`
 	EstimatedSize int64   `json:"estimated_size,omitempty"`
+	// Watchlist marks an unwatched favorite, ranked ahead of other
+	// predictions at its priority
+	Watchlist bool `json:"watchlist,omitempty"`
 }
 
 // NewPredictor creates a new viewing pattern predictor instance.
 // Initializes with empty preferences that will be built from viewing history.
-func NewPredictor(storage *storage.Manager, config *config.PredictionConfig, logger *slog.Logger) *Predictor {
+func NewPredictor(storage storage.Store, config *config.PredictionConfig, logger *slog.Logger) *Predictor {
 	return &Predictor{
 		storage:        storage,
 		logger:         logger,
@@ -119,9 +167,26 @@ func (p *Predictor) SetDownloadManager(dm DownloadQueuer) {
 	p.downloadManager = dm
 }
 
+// SetEnabled turns predictions on or off while running. While off, no
+// predictions are made or reconciled, so what they already queued stays
+// queued; playback still downloads what is playing.
+func (p *Predictor) SetEnabled(enabled bool) {
+	if p.paused.Swap(!enabled) == enabled {
+		p.logger.Info("Predictions toggled", "enabled", enabled)
+	}
+}
+
+// Enabled reports whether predictions are made.
+func (p *Predictor) Enabled() bool {
+	return !p.paused.Load()
+}
+
 // OnPlaybackStart handles immediate prediction when user starts watching content.
 // This triggers Priority 0 (currently playing) download and queues next episode.
 func (p *Predictor) OnPlaybackStart(ctx context.Context, mediaID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	p.logger.Info("Playback started, triggering immediate predictions",
 		"media_id", mediaID)
 
@@ -139,6 +204,12 @@ func (p *Predictor) OnPlaybackStart(ctx context.Context, mediaID string) error {
 		return fmt.Errorf("failed to get metadata: %w", err)
 	}
 
+	if !p.libraries.Allows(metadata.Library) {
+		p.logger.Debug("Ignoring playback from excluded library",
+			"media_id", mediaID, "library", metadata.Library)
+		return nil
+	}
+
 	session.MediaType = metadata.Type
 	session.SeriesID = metadata.SeriesID
 	session.Season = metadata.SeasonNumber
@@ -156,7 +227,7 @@ func (p *Predictor) OnPlaybackStart(ctx context.Context, mediaID string) error {
 				"media_id", mediaID,
 				"priority", 0)
 
-			if _, err := p.downloadManager.QueueDownload(ctx, mediaID, 0); err != nil {
+			if _, err := p.downloadManager.QueueDownloadWithSource(ctx, mediaID, 0, SourcePlayback); err != nil {
 				p.logger.Error("Failed to queue current content download",
 					"media_id", mediaID,
 					"error", err)
@@ -167,50 +238,103 @@ func (p *Predictor) OnPlaybackStart(ctx context.Context, mediaID string) error {
 		}
 	}
 
+	if !p.Enabled() {
+		return nil
+	}
+
 	// If this is a TV series episode, predict next episode(s)
 	if session.MediaType == "episode" && session.SeriesID != "" {
 		return p.predictNextEpisodes(ctx, session.SeriesID, session.Season, session.Episode)
 	}
 
+	// For music and audiobooks, predict the next track(s) or chapter(s)
+	if (session.MediaType == "audio" || session.MediaType == "audiobook") && metadata.AlbumID != "" {
+		return p.predictNextTracks(ctx, metadata.AlbumID, mediaID)
+	}
+
 	return nil
 }
 
 // PredictNext analyzes viewing patterns and returns download recommendations.
 // This is the main prediction method called periodically for proactive caching.
 func (p *Predictor) PredictNext(ctx context.Context, userID string) ([]PredictionResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.Enabled() {
+		return nil, nil
+	}
+	return p.predictNext(ctx, userID)
+}
+
+// predictNext runs the prediction pipeline. Callers must hold p.mu.
+func (p *Predictor) predictNext(ctx context.Context, userID string) ([]PredictionResult, error) {
 	p.logger.Debug("Starting prediction analysis", "user_id", userID)
 
-	// Refresh viewing history if needed
-	if time.Since(p.lastSync) > p.config.SyncInterval {
+	// Refresh viewing history if needed, or if it belongs to another user
+	if time.Since(p.lastSync) > p.config.SyncInterval || userID != p.historyUser {
 		if err := p.refreshViewingHistory(ctx, userID); err != nil {
 			p.logger.Error("Failed to refresh viewing history", "error", err)
 			return nil, fmt.Errorf("failed to refresh history: %w", err)
 		}
+		p.refreshWatchlist(ctx)
+		p.refreshServerNextUp(ctx, userID)
 	}
 
+	// Fetch metadata of watched items the library sync has not stored, so
+	// their genres count
+	p.backfillGenreMetadata(ctx)
+
 	// Update user preferences from recent history
 	if err := p.updatePreferences(); err != nil {
 		p.logger.Warn("Failed to update preferences", "error", err)
 	}
 
+	// Let the download manager schedule large speculative downloads
+	// around when this user watches
+	if setter, ok := p.downloadManager.(ViewingWindowSetter); ok {
+		setter.SetViewingWindows(userID, p.preferences.PreferredViewTimes)
+	}
+
+	// Judge earlier predictions against what the household went on to watch
+	users := p.config.HouseholdUsers
+	if len(users) == 0 {
+		users = []string{userID}
+	}
+	if err := p.updateAccuracy(users, time.Now()); err != nil {
+		p.logger.Warn("Failed to update prediction accuracy", "error", err)
+	}
+
 	var predictions []PredictionResult
 
 	// Priority 1: Continue watching - next episodes in active series
 	continuePredictions := p.predictContinueWatching()
+	continuePredictions = p.mergeServerNextUp(continuePredictions)
 	predictions = append(predictions, continuePredictions...)
 
 	// Priority 2: Up next - following episodes in sequence
 	upNextPredictions := p.predictUpNext()
 	predictions = append(predictions, upNextPredictions...)
 
+	// Watchlist: unwatched favorites, ahead of recently added content
+	watchlistPredictions := p.predictWatchlist()
+	predictions = append(predictions, watchlistPredictions...)
+
 	// Priority 3: Recently added content matching preferences
 	recentPredictions := p.predictRecentlyAdded()
 	predictions = append(predictions, recentPredictions...)
 
 	// Priority 4: Trending content in preferred genres
-	trendingPredictions := p.predictTrending()
+	trendingPredictions := p.predictTrending(ctx)
+	trendingPredictions = p.applySeasonalBoosts(trendingPredictions, time.Now())
 	predictions = append(predictions, trendingPredictions...)
 
+	// Boost or drop predictions based on preferred audio languages
+	predictions = p.applyLanguagePreferences(predictions)
+
+	// Be more conservative at priorities whose predictions go unwatched
+	predictions = p.applyAccuracyWeights(predictions)
+
 	// Filter by confidence threshold and limit results
 	predictions = p.filterPredictions(predictions)
 
@@ -253,7 +377,7 @@ func (p *Predictor) predictNextEpisodes(ctx context.Context, seriesID string, cu
 
 				// Add to download queue with Priority 1
 				if p.downloadManager != nil {
-					if _, err := p.downloadManager.QueueDownload(ctx, episode.ID, 1); err != nil {
+					if _, err := p.downloadManager.QueueDownloadWithSource(ctx, episode.ID, 1, SourcePlayback); err != nil {
 						p.logger.Error("Failed to queue next episode download",
 							"episode_id", episode.ID,
 							"error", err)
@@ -282,7 +406,7 @@ func (p *Predictor) predictNextEpisodes(ctx context.Context, seriesID string, cu
 
 				// Add to download queue with Priority 2 (lower priority than next episode)
 				if p.downloadManager != nil {
-					if _, err := p.downloadManager.QueueDownload(ctx, firstEpisode.ID, 2); err != nil {
+					if _, err := p.downloadManager.QueueDownloadWithSource(ctx, firstEpisode.ID, 2, SourcePlayback); err != nil {
 						p.logger.Error("Failed to queue next season episode download",
 							"episode_id", firstEpisode.ID,
 							"error", err)
@@ -300,7 +424,31 @@ func (p *Predictor) predictNextEpisodes(ctx context.Context, seriesID string, cu
 func (p *Predictor) predictContinueWatching() []PredictionResult {
 	var predictions []PredictionResult
 
-	// Group viewing history by series
+	// Create predictions for active series (watched within last 30 days)
+	cutoff := time.Now().AddDate(0, 0, -30)
+	for _, progress := range p.seriesProgress() {
+		if progress.LastWatched.After(cutoff) && progress.CompletedEpisodes > 0 {
+			confidence := p.calculateContinueConfidence(progress)
+			if confidence >= p.config.MinConfidence {
+				predictions = append(predictions, PredictionResult{
+					MediaID:    fmt.Sprintf("%s_S%02dE%02d", progress.SeriesID, progress.LastSeason, progress.LastEpisode+1),
+					Priority:   1,
+					Confidence: confidence,
+					Reason:     "Next episode in partially watched series",
+					SeriesID:   progress.SeriesID,
+					Season:     progress.LastSeason,
+					Episode:    progress.LastEpisode + 1,
+					MediaType:  "episode",
+				})
+			}
+		}
+	}
+
+	return predictions
+}
+
+// seriesProgress groups the viewing history by series, keyed by series ID.
+func (p *Predictor) seriesProgress() map[string]ViewingProgress {
 	seriesProgress := make(map[string]ViewingProgress)
 
 	for _, session := range p.viewingHistory {
@@ -326,27 +474,7 @@ func (p *Predictor) predictContinueWatching() []PredictionResult {
 		}
 	}
 
-	// Create predictions for active series (watched within last 30 days)
-	cutoff := time.Now().AddDate(0, 0, -30)
-	for _, progress := range seriesProgress {
-		if progress.LastWatched.After(cutoff) && progress.CompletedEpisodes > 0 {
-			confidence := p.calculateContinueConfidence(progress)
-			if confidence >= p.config.MinConfidence {
-				predictions = append(predictions, PredictionResult{
-					MediaID:    fmt.Sprintf("%s_S%02dE%02d", progress.SeriesID, progress.LastSeason, progress.LastEpisode+1),
-					Priority:   1,
-					Confidence: confidence,
-					Reason:     "Next episode in partially watched series",
-					SeriesID:   progress.SeriesID,
-					Season:     progress.LastSeason,
-					Episode:    progress.LastEpisode + 1,
-					MediaType:  "episode",
-				})
-			}
-		}
-	}
-
-	return predictions
+	return seriesProgress
 }
 
 // ViewingProgress tracks user progress through a TV series.
@@ -360,65 +488,83 @@ type ViewingProgress struct {
 	CompletedEpisodes int
 }
 
-// predictUpNext predicts following episodes in sequence (Priority 2).
-// Based on binge-watching patterns and typical viewing behavior.
-func (p *Predictor) predictUpNext() []PredictionResult {
+// predictRecentlyAdded suggests new content matching user preferences (Priority 3).
+// New items come from library sync (see LibraryChanged) and the metadata
+// store; cached items are skipped.
+func (p *Predictor) predictRecentlyAdded() []PredictionResult {
 	var predictions []PredictionResult
 
-	// If user shows binge-watching behavior, predict multiple episodes ahead
-	if p.preferences.WatchingPatterns.PrefersBingeWatching {
-		episodeCount := 2
-		if p.preferences.SeriesBingeRate > 3.0 { // More than 3 episodes per day
-			episodeCount = 3
-		}
-
-		p.logger.Debug("User shows binge-watching pattern, predicting multiple episodes",
-			"episode_count", episodeCount,
-			"binge_rate", p.preferences.SeriesBingeRate)
-
-		// This would integrate with continue watching predictions
-		// to add additional episodes beyond the immediate next one
+	now := time.Now()
+	p.pruneRecentlyAdded(now)
+	candidates := p.recentCandidates(now)
+	if len(candidates) == 0 {
+		return predictions
 	}
 
-	return predictions
-}
+	watchedSeries := make(map[string]bool)
+	for _, session := range p.viewingHistory {
+		if session.SeriesID != "" {
+			watchedSeries[session.SeriesID] = true
+		}
+	}
 
-// predictRecentlyAdded suggests new content matching user preferences (Priority 3).
-func (p *Predictor) predictRecentlyAdded() []PredictionResult {
-	var predictions []PredictionResult
+	for _, item := range candidates {
+		if !p.libraries.Allows(item.Library) {
+			continue
+		}
+		if cached, err := p.storage.IsMediaCached(item.MediaID); err != nil || cached {
+			continue
+		}
 
-	// Get recently added content from storage
-	// Filter by preferred genres and languages
-	// Calculate confidence based on genre match and release recency
+		confidence, reason := p.recentConfidence(item, watchedSeries, now)
+		if confidence == 0 {
+			continue
+		}
+		predictions = append(predictions, PredictionResult{
+			MediaID:    item.MediaID,
+			Priority:   3,
+			Confidence: confidence,
+			Reason:     reason,
+			SeriesID:   item.SeriesID,
+			Season:     item.Season,
+			Episode:    item.Episode,
+			MediaType:  item.MediaType,
+		})
+	}
 
 	return predictions
 }
 
-// predictTrending suggests popular content in preferred genres (Priority 4).
-func (p *Predictor) predictTrending() []PredictionResult {
-	var predictions []PredictionResult
-
-	// This would integrate with Jellyfin popularity metrics
-	// Filter by genres user has shown interest in
-	// Lower priority as it's speculative
+// HistoryChanged tells the predictor that userID's stored viewing history
+// changed, so the next prediction for them reloads it.
+func (p *Predictor) HistoryChanged(userID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	return predictions
+	if userID == p.historyUser {
+		p.lastSync = time.Time{}
+	}
 }
 
 // refreshViewingHistory updates viewing history from Jellyfin API or storage.
 func (p *Predictor) refreshViewingHistory(ctx context.Context, userID string) error {
 	p.logger.Debug("Refreshing viewing history", "user_id", userID)
 
-	// Get viewing history from storage (this would be populated by Jellyfin sync)
+	// Get viewing history from storage, populated by local playback and the
+	// Jellyfin session syncer
 	history, err := p.storage.GetViewingHistory(userID, p.config.HistoryDays)
 	if err != nil {
 		return fmt.Errorf("failed to get viewing history: %w", err)
 	}
 
-	// Convert storage.ViewingSession to local ViewingSession
-	p.viewingHistory = make([]ViewingSession, len(history))
-	for i, h := range history {
-		p.viewingHistory[i] = ViewingSession{
+	// Convert storage.ViewingSession to local ViewingSession, leaving out
+	// anything watched in an excluded library
+	p.viewingHistory = make([]ViewingSession, 0, len(history))
+	for _, h := range history {
+		if !p.libraryAllowed(h.MediaID) {
+			continue
+		}
+		p.viewingHistory = append(p.viewingHistory, ViewingSession{
 			MediaID:      h.MediaID,
 			MediaType:    h.MediaType,
 			SeriesID:     h.SeriesID,
@@ -431,12 +577,13 @@ func (p *Predictor) refreshViewingHistory(ctx context.Context, userID string) er
 			Completed:    h.Completed,
 			DeviceType:   h.DeviceType,
 			QualityLevel: h.QualityLevel,
-		}
+		})
 	}
+	p.historyUser = userID
 	p.lastSync = time.Now()
 
 	p.logger.Info("Viewing history refreshed",
-		"sessions_loaded", len(history),
+		"sessions_loaded", len(p.viewingHistory),
 		"user_id", userID)
 
 	return nil
@@ -454,6 +601,7 @@ func (p *Predictor) updatePreferences() error {
 	// Analyze viewing patterns
 	p.analyzeWatchingPatterns()
 	p.analyzeGenrePreferences()
+	p.analyzeLanguagePreferences()
 	p.analyzeViewingTimes()
 	p.calculateMetrics()
 
@@ -508,30 +656,44 @@ func (p *Predictor) analyzeWatchingPatterns() {
 	p.preferences.WatchingPatterns.TypicalViewingDays = days
 }
 
-// analyzeGenrePreferences extracts preferred genres from viewing history.
+// analyzeGenrePreferences extracts preferred genres from viewing history,
+// joining each session with the genres in its stored metadata. Completed
+// viewings count fully and abandoned ones half. Sessions without metadata
+// are skipped; backfillGenreMetadata fetches what it can beforehand.
 func (p *Predictor) analyzeGenrePreferences() {
-	genreCount := make(map[string]int)
+	genreScores := make(map[string]float64)
 
 	for _, session := range p.viewingHistory {
+		metadata, err := p.storage.GetMediaMetadata(session.MediaID)
+		if err != nil {
+			continue
+		}
+
+		weight := 0.5
 		if session.Completed {
-			// This would get genre information from metadata
-			// For now, we'll simulate with placeholder logic
+			weight = 1.0
+		}
+		for _, genre := range metadata.Genres {
+			genreScores[genre] += weight
 		}
 	}
 
 	// Convert to sorted list of preferred genres
 	type genreScore struct {
 		genre string
-		count int
+		score float64
 	}
 
 	var scores []genreScore
-	for genre, count := range genreCount {
-		scores = append(scores, genreScore{genre, count})
+	for genre, score := range genreScores {
+		scores = append(scores, genreScore{genre, score})
 	}
 
 	sort.Slice(scores, func(i, j int) bool {
-		return scores[i].count > scores[j].count
+		if scores[i].score == scores[j].score {
+			return scores[i].genre < scores[j].genre
+		}
+		return scores[i].score > scores[j].score
 	})
 
 	// Take top genres (up to 5)
@@ -564,6 +726,7 @@ func (p *Predictor) analyzeViewingTimes() {
 	}
 
 	sort.Ints(p.preferences.WatchingPatterns.PreferredStartTimes)
+	p.preferences.PreferredViewTimes = viewingWindows(p.preferences.WatchingPatterns.PreferredStartTimes, hourCount)
 }
 
 // calculateMetrics computes completion rates and binge rates.
@@ -644,12 +807,15 @@ func (p *Predictor) filterPredictions(predictions []PredictionResult) []Predicti
 		}
 	}
 
-	// Sort by priority, then confidence
+	// Sort by priority, then the watchlist tier, then confidence
 	sort.Slice(filtered, func(i, j int) bool {
-		if filtered[i].Priority == filtered[j].Priority {
-			return filtered[i].Confidence > filtered[j].Confidence
+		if filtered[i].Priority != filtered[j].Priority {
+			return filtered[i].Priority < filtered[j].Priority
+		}
+		if filtered[i].Watchlist != filtered[j].Watchlist {
+			return filtered[i].Watchlist
 		}
-		return filtered[i].Priority < filtered[j].Priority
+		return filtered[i].Confidence > filtered[j].Confidence
 	})
 
 	// Limit results (don't overwhelm download queue)
@@ -0,0 +1,185 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// MediaProber reads the technical details of a media file (implemented by
+// FFprobe).
+type MediaProber interface {
+	Probe(ctx context.Context, path string) (*storage.MediaInfo, error)
+}
+
+// FFprobe is a MediaProber that runs the ffprobe command.
+type FFprobe struct {
+	path string
+}
+
+// NewFFprobe creates a MediaProber running the ffprobe binary at path.
+func NewFFprobe(path string) *FFprobe {
+	return &FFprobe{path: path}
+}
+
+// Probe implements MediaProber.
+func (f *FFprobe) Probe(ctx context.Context, path string) (*storage.MediaInfo, error) {
+	cmd := exec.CommandContext(ctx, f.path,
+		"-v", "error", "-print_format", "json", "-show_format", "-show_streams", path)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return parseFFprobe(output)
+}
+
+// ffprobeOutput is the part of ffprobe's JSON output that is recorded.
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType   string            `json:"codec_type"`
+		CodecName   string            `json:"codec_name"`
+		Width       int               `json:"width"`
+		Height      int               `json:"height"`
+		Channels    int               `json:"channels"`
+		Disposition map[string]int    `json:"disposition"`
+		Tags        map[string]string `json:"tags"`
+	} `json:"streams"`
+	Format struct {
+		FormatName string `json:"format_name"`
+		Duration   string `json:"duration"`
+		BitRate    string `json:"bit_rate"`
+	} `json:"format"`
+}
+
+// parseFFprobe converts ffprobe's JSON output into a MediaInfo.
+func parseFFprobe(data []byte) (*storage.MediaInfo, error) {
+	var out ffprobeOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+
+	info := &storage.MediaInfo{
+		// ffprobe lists the formats a demuxer handles, "matroska,webm"
+		Container: strings.Split(out.Format.FormatName, ",")[0],
+	}
+	info.DurationSeconds, _ = strconv.ParseFloat(out.Format.Duration, 64)
+	info.Bitrate, _ = strconv.Atoi(out.Format.BitRate)
+
+	for _, stream := range out.Streams {
+		track := storage.MediaTrack{
+			Codec:    stream.CodecName,
+			Language: stream.Tags["language"],
+			Title:    stream.Tags["title"],
+			Default:  stream.Disposition["default"] == 1,
+		}
+		switch stream.CodecType {
+		case "video":
+			// Cover art is stored as a one-frame video stream
+			if info.VideoCodec != "" || stream.Disposition["attached_pic"] == 1 {
+				continue
+			}
+			info.VideoCodec, info.Width, info.Height = stream.CodecName, stream.Width, stream.Height
+		case "audio":
+			track.Channels = stream.Channels
+			info.AudioTracks = append(info.AudioTracks, track)
+		case "subtitle":
+			info.SubtitleTracks = append(info.SubtitleTracks, track)
+		}
+	}
+	return info, nil
+}
+
+// InspectStore is the storage the media inspector reads cached items from
+// and records their details in.
+type InspectStore interface {
+	GetDownload(mediaID string) (*storage.DownloadRecord, error)
+	FindMediaMetadata(mediaID string) (*storage.MediaMetadata, error)
+	AddMediaMetadata(metadata *storage.MediaMetadata) error
+}
+
+// MediaInspector records what a completed download actually contains, as
+// reported by a MediaProber, in its metadata's ExtraData. Jellyfin's
+// description of an item is of the original, which a transcoded variant
+// differs from.
+type MediaInspector struct {
+	store  InspectStore
+	prober MediaProber
+	config *config.CacheProbeConfig
+	logger *slog.Logger
+
+	// now is stubbed by tests
+	now func() time.Time
+}
+
+// NewMediaInspector creates an inspector that runs prober on cached files.
+func NewMediaInspector(store InspectStore, prober MediaProber, cfg *config.CacheProbeConfig, logger *slog.Logger) *MediaInspector {
+	return &MediaInspector{
+		store:  store,
+		prober: prober,
+		config: cfg,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Enabled reports whether inspection is turned on.
+func (i *MediaInspector) Enabled() bool {
+	return i.config.Enabled
+}
+
+// Inspect probes a cached item and records the result with its metadata,
+// reporting whether it did. Items that are not cached or have no stored
+// metadata are skipped.
+func (i *MediaInspector) Inspect(ctx context.Context, mediaID string) (bool, error) {
+	if !i.Enabled() {
+		return false, nil
+	}
+	record, err := i.store.GetDownload(mediaID)
+	if err != nil || record.LocalPath == "" || record.Status == "evicted" {
+		return false, nil
+	}
+	if _, err := os.Stat(record.LocalPath); err != nil {
+		return false, nil
+	}
+	metadata, err := i.store.FindMediaMetadata(mediaID)
+	if err != nil || metadata == nil {
+		return false, err
+	}
+
+	if i.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, i.config.Timeout)
+		defer cancel()
+	}
+	info, err := i.prober.Probe(ctx, record.LocalPath)
+	if err != nil {
+		return false, err
+	}
+	info.InspectedAt = i.now()
+
+	if err := metadata.SetMediaInfo(info); err != nil {
+		return false, err
+	}
+	if err := i.store.AddMediaMetadata(metadata); err != nil {
+		return false, err
+	}
+
+	i.logger.Debug("Inspected cached file",
+		"media_id", mediaID,
+		"video_codec", info.VideoCodec,
+		"height", info.Height,
+		"audio_tracks", len(info.AudioTracks))
+	return true, nil
+}
@@ -0,0 +1,115 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+const ffprobeSample = `{
+  "streams": [
+    {"codec_type": "video", "codec_name": "hevc", "width": 3840, "height": 2160, "disposition": {"default": 1}},
+    {"codec_type": "audio", "codec_name": "eac3", "channels": 6, "disposition": {"default": 1}, "tags": {"language": "eng", "title": "Surround"}},
+    {"codec_type": "audio", "codec_name": "aac", "channels": 2, "disposition": {"default": 0}, "tags": {"language": "fre"}},
+    {"codec_type": "subtitle", "codec_name": "subrip", "tags": {"language": "eng"}},
+    {"codec_type": "video", "codec_name": "mjpeg", "width": 600, "height": 900, "disposition": {"attached_pic": 1}}
+  ],
+  "format": {"format_name": "matroska,webm", "duration": "5400.250000", "bit_rate": "24000000"}
+}`
+
+func TestParseFFprobe(t *testing.T) {
+	info, err := parseFFprobe([]byte(ffprobeSample))
+	require.NoError(t, err)
+
+	assert.Equal(t, "matroska", info.Container)
+	assert.Equal(t, 5400250*time.Millisecond, info.Duration())
+	assert.Equal(t, 24000000, info.Bitrate)
+	assert.Equal(t, "hevc", info.VideoCodec, "cover art is not the video")
+	assert.Equal(t, 3840, info.Width)
+	assert.Equal(t, 2160, info.Height)
+	assert.Equal(t, []storage.MediaTrack{
+		{Codec: "eac3", Language: "eng", Title: "Surround", Channels: 6, Default: true},
+		{Codec: "aac", Language: "fre", Channels: 2},
+	}, info.AudioTracks)
+	assert.Equal(t, []storage.MediaTrack{{Codec: "subrip", Language: "eng"}}, info.SubtitleTracks)
+
+	_, err = parseFFprobe([]byte("not json"))
+	assert.Error(t, err)
+}
+
+type proberFunc func(ctx context.Context, path string) (*storage.MediaInfo, error)
+
+func (f proberFunc) Probe(ctx context.Context, path string) (*storage.MediaInfo, error) {
+	return f(ctx, path)
+}
+
+func TestMediaInspector(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "Heat.mkv")
+	require.NoError(t, os.WriteFile(path, []byte("video"), 0644))
+	require.NoError(t, store.AddDownloadRecord(&storage.DownloadRecord{ID: "m1", JellyfinID: "m1", LocalPath: path, Status: "completed"}))
+	require.NoError(t, store.AddMediaMetadata(&storage.MediaMetadata{ID: "m1", JellyfinID: "m1", Name: "Heat", Type: "movie"}))
+	require.NoError(t, store.AddDownloadRecord(&storage.DownloadRecord{ID: "m2", JellyfinID: "m2", LocalPath: filepath.Join(dir, "gone.mkv"), Status: "completed"}))
+
+	var probed []string
+	prober := proberFunc(func(ctx context.Context, path string) (*storage.MediaInfo, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("Expected probing to be bounded by the timeout")
+		}
+		probed = append(probed, path)
+		return parseFFprobe([]byte(ffprobeSample))
+	})
+
+	cfg := &config.CacheProbeConfig{Timeout: time.Minute}
+	inspector := NewMediaInspector(store, prober, cfg, logger)
+	inspected := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	inspector.now = func() time.Time { return inspected }
+
+	done, err := inspector.Inspect(context.Background(), "m1")
+	require.NoError(t, err)
+	assert.False(t, done, "nothing is inspected while probing is off")
+
+	cfg.Enabled = true
+	done, err = inspector.Inspect(context.Background(), "m1")
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, []string{path}, probed)
+
+	metadata, err := store.GetMediaMetadata("m1")
+	require.NoError(t, err)
+	info := metadata.MediaInfo()
+	require.NotNil(t, info, "the result survives being stored")
+	assert.Equal(t, 2160, info.Height)
+	assert.Len(t, info.AudioTracks, 2)
+	assert.True(t, info.InspectedAt.Equal(inspected))
+	assert.Equal(t, "Heat", metadata.Name, "the rest of the metadata is kept")
+
+	// Missing files and items without a record are skipped
+	for _, id := range []string{"m2", "missing"} {
+		done, err = inspector.Inspect(context.Background(), id)
+		require.NoError(t, err)
+		assert.False(t, done, id)
+	}
+	assert.Len(t, probed, 1)
+
+	failing := NewMediaInspector(store, proberFunc(func(ctx context.Context, path string) (*storage.MediaInfo, error) {
+		return nil, errors.New("invalid data found when processing input")
+	}), cfg, logger)
+	_, err = failing.Inspect(context.Background(), "m1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,213 @@
+package downloader
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// ActiveDownload is a point-in-time snapshot of an in-flight download.
+type ActiveDownload struct {
+	JobID      string    `json:"job_id"`
+	MediaID    string    `json:"media_id"`
+	Priority   int       `json:"priority"`
+	Retries    int       `json:"retries"`          // Earlier failed attempts
+	Downloaded int64     `json:"downloaded_bytes"` // Includes bytes from a resumed partial file
+	Total      int64     `json:"total_bytes"`      // Zero when the server sent no Content-Length
+	Speed      int64     `json:"speed"`            // Bytes per second over the last speedWindow
+	StartedAt  time.Time `json:"started_at"`
+}
+
+// Download speed is measured over a rolling window, sampled once per
+// report, so bursts and rate-limiter pauses even out.
+const (
+	speedWindow            = 10 * time.Second
+	transferReportInterval = time.Second
+)
+
+// Percent returns download completion from 0 to 100, or zero if the total
+// size is unknown.
+func (a ActiveDownload) Percent() float64 {
+	if a.Total <= 0 {
+		return 0
+	}
+	percent := float64(a.Downloaded) / float64(a.Total) * 100
+	if percent > 100 {
+		return 100
+	}
+	return percent
+}
+
+// ETA returns how long the rest of the download will take at its current
+// speed, or zero if the speed or total size is unknown.
+func (a ActiveDownload) ETA() time.Duration {
+	remaining := a.Total - a.Downloaded
+	if a.Speed <= 0 || a.Total <= 0 || remaining <= 0 {
+		return 0
+	}
+	seconds := float64(remaining) / float64(a.Speed)
+	return (time.Duration(seconds * float64(time.Second))).Round(time.Second)
+}
+
+// downloadTracker counts bytes written for one active download.
+type downloadTracker struct {
+	info       ActiveDownload
+	downloaded atomic.Int64
+	partial    *storage.PartialFile // nil until the response body is being written
+	preempted  bool                 // set once it was stopped for a more urgent download
+
+	// samples of downloaded over the last speedWindow, oldest first, and
+	// the speed they give; guarded by the manager's activeMu
+	samples []throughputSample
+	speed   int64
+}
+
+// throughputSample is how much of a download was done at a point in time.
+type throughputSample struct {
+	at    time.Time
+	bytes int64
+}
+
+func (t *downloadTracker) Write(p []byte) (int, error) {
+	t.downloaded.Add(int64(len(p)))
+	return len(p), nil
+}
+
+// sample records how far the download got by now and updates its speed
+// over the samples still within speedWindow.
+func (t *downloadTracker) sample(now time.Time) {
+	t.samples = append(t.samples, throughputSample{at: now, bytes: t.downloaded.Load()})
+
+	// Keep the newest sample at or before the window start, so the window
+	// is covered in full
+	drop := 0
+	for drop+1 < len(t.samples) && !t.samples[drop+1].at.After(now.Add(-speedWindow)) {
+		drop++
+	}
+	t.samples = t.samples[drop:]
+
+	first, last := t.samples[0], t.samples[len(t.samples)-1]
+	if elapsed := last.at.Sub(first.at); elapsed > 0 {
+		t.speed = int64(float64(last.bytes-first.bytes) / elapsed.Seconds())
+	}
+}
+
+// trackDownload registers a download as active. offset is the number of
+// bytes already present from a resumed partial file.
+func (m *Manager) trackDownload(job *DownloadJob, offset, total int64) *downloadTracker {
+	tracker := &downloadTracker{info: ActiveDownload{
+		JobID:     job.ID,
+		MediaID:   job.MediaID,
+		Priority:  job.Priority,
+		Retries:   job.RetryCount,
+		Total:     total,
+		StartedAt: time.Now(),
+	}}
+	tracker.downloaded.Store(offset)
+	tracker.sample(tracker.info.StartedAt)
+
+	m.activeMu.Lock()
+	defer m.activeMu.Unlock()
+	if m.active == nil {
+		m.active = make(map[string]*downloadTracker)
+	}
+	m.active[job.ID] = tracker
+	return tracker
+}
+
+// attachPartial records the temporary file an active download is writing.
+func (m *Manager) attachPartial(jobID string, partial *storage.PartialFile) {
+	m.activeMu.Lock()
+	defer m.activeMu.Unlock()
+	if tracker, ok := m.active[jobID]; ok {
+		tracker.partial = partial
+	}
+}
+
+// untrackDownload removes a finished or failed download from the active set.
+func (m *Manager) untrackDownload(jobID string) {
+	m.activeMu.Lock()
+	defer m.activeMu.Unlock()
+	delete(m.active, jobID)
+}
+
+// ActiveDownloads returns the downloads currently transferring, most urgent
+// first (lowest priority number, then earliest started).
+func (m *Manager) ActiveDownloads() []ActiveDownload {
+	m.activeMu.Lock()
+	downloads := make([]ActiveDownload, 0, len(m.active))
+	for _, tracker := range m.active {
+		info := tracker.info
+		info.Downloaded = tracker.downloaded.Load()
+		info.Speed = tracker.speed
+		downloads = append(downloads, info)
+	}
+	m.activeMu.Unlock()
+
+	sort.Slice(downloads, func(i, j int) bool {
+		if downloads[i].Priority != downloads[j].Priority {
+			return downloads[i].Priority < downloads[j].Priority
+		}
+		return downloads[i].StartedAt.Before(downloads[j].StartedAt)
+	})
+	return downloads
+}
+
+// PartialDownload returns the temporary file an in-flight download of
+// mediaID is streaming into and how many bytes of it are on disk, so
+// playback can start before the download completes. ok is false when the
+// media is not downloading.
+func (m *Manager) PartialDownload(mediaID string) (path string, written, total int64, ok bool) {
+	m.activeMu.Lock()
+	defer m.activeMu.Unlock()
+
+	for _, tracker := range m.active {
+		if tracker.info.MediaID != mediaID || tracker.partial == nil {
+			continue
+		}
+		return tracker.partial.Path(), tracker.partial.Written(), tracker.info.Total, true
+	}
+
+	return "", 0, 0, false
+}
+
+// transferReporter samples the speed of active downloads and reports their
+// progress, speed and ETA every transferReportInterval until the manager
+// stops.
+func (m *Manager) transferReporter() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(transferReportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.reportTransfers(now)
+		}
+	}
+}
+
+// reportTransfers samples every active download at now and reports it.
+func (m *Manager) reportTransfers(now time.Time) {
+	m.activeMu.Lock()
+	downloads := make([]ActiveDownload, 0, len(m.active))
+	for _, tracker := range m.active {
+		tracker.sample(now)
+		info := tracker.info
+		info.Downloaded = tracker.downloaded.Load()
+		info.Speed = tracker.speed
+		downloads = append(downloads, info)
+	}
+	m.activeMu.Unlock()
+
+	if m.progressReporter == nil {
+		return
+	}
+	for _, download := range downloads {
+		m.progressReporter.BroadcastTransfer(download.MediaID, download.Percent(), download.Speed, download.ETA())
+	}
+}
@@ -0,0 +1,110 @@
+package downloader
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+func TestActiveDownloads(t *testing.T) {
+	manager := &Manager{}
+
+	speculative := manager.trackDownload(&DownloadJob{ID: "j1", MediaID: "m1", Priority: 4}, 0, 1000)
+	_, err := speculative.Write(make([]byte, 250))
+	require.NoError(t, err)
+
+	// Resumed download starts counting from the partial file size
+	playing := manager.trackDownload(&DownloadJob{ID: "j2", MediaID: "m2", Priority: 0}, 500, 1000)
+	playing.Write(make([]byte, 100))
+
+	active := manager.ActiveDownloads()
+	require.Len(t, active, 2)
+	assert.Equal(t, "m2", active[0].MediaID, "most urgent download first")
+	assert.Equal(t, int64(600), active[0].Downloaded)
+	assert.InDelta(t, 60.0, active[0].Percent(), 0.001)
+	assert.InDelta(t, 25.0, active[1].Percent(), 0.001)
+
+	manager.untrackDownload("j2")
+	active = manager.ActiveDownloads()
+	require.Len(t, active, 1)
+	assert.Equal(t, "m1", active[0].MediaID)
+
+	assert.Zero(t, ActiveDownload{Downloaded: 10}.Percent(), "unknown total size")
+}
+
+func TestPartialDownload(t *testing.T) {
+	manager := &Manager{}
+	dest := filepath.Join(t.TempDir(), "film.mkv")
+
+	_, _, _, ok := manager.PartialDownload("m1")
+	assert.False(t, ok)
+
+	manager.trackDownload(&DownloadJob{ID: "j1", MediaID: "m1", Priority: 0}, 0, 1000)
+	partial, err := storage.CreatePartial(dest, false)
+	require.NoError(t, err)
+	defer partial.Discard()
+	manager.attachPartial("j1", partial)
+
+	_, err = partial.Write(make([]byte, 300))
+	require.NoError(t, err)
+
+	path, written, total, ok := manager.PartialDownload("m1")
+	require.True(t, ok)
+	assert.Equal(t, storage.PartialPath(dest), path)
+	assert.Equal(t, int64(300), written)
+	assert.Equal(t, int64(1000), total)
+
+	manager.untrackDownload("j1")
+	_, _, _, ok = manager.PartialDownload("m1")
+	assert.False(t, ok)
+}
+
+type transferRecorder struct {
+	progress []float64
+	speeds   []int64
+	etas     []time.Duration
+}
+
+func (r *transferRecorder) BroadcastProgress(mediaID, status, message string, progress float64) {}
+
+func (r *transferRecorder) BroadcastTransfer(mediaID string, progress float64, speed int64, eta time.Duration) {
+	r.progress = append(r.progress, progress)
+	r.speeds = append(r.speeds, speed)
+	r.etas = append(r.etas, eta)
+}
+
+func TestTransferSpeedAndETA(t *testing.T) {
+	recorder := &transferRecorder{}
+	manager := &Manager{progressReporter: recorder}
+
+	// A resumed download; the bytes kept from before do not count as speed
+	tracker := manager.trackDownload(&DownloadJob{ID: "j1", MediaID: "m1"}, 1000, 11000)
+	start := tracker.info.StartedAt
+
+	tracker.Write(make([]byte, 1000))
+	manager.reportTransfers(start.Add(time.Second))
+	require.Len(t, recorder.speeds, 1)
+	assert.Equal(t, int64(1000), recorder.speeds[0])
+	assert.Equal(t, 9*time.Second, recorder.etas[0])
+	assert.InDelta(t, 2000.0/11000*100, recorder.progress[0], 0.001)
+
+	// Speed is averaged over the last speedWindow only: 500 B/s for the
+	// last ten seconds, after the fast first second
+	for i := 2; i <= 11; i++ {
+		tracker.Write(make([]byte, 500))
+		manager.reportTransfers(start.Add(time.Duration(i) * time.Second))
+	}
+	active := manager.ActiveDownloads()
+	require.Len(t, active, 1)
+	assert.Equal(t, int64(500), active[0].Speed)
+	assert.Equal(t, 8*time.Second, active[0].ETA())
+	assert.LessOrEqual(t, len(tracker.samples), 11, "old samples are dropped")
+
+	assert.Zero(t, ActiveDownload{Downloaded: 10, Speed: 100}.ETA(), "unknown total size")
+	assert.Zero(t, ActiveDownload{Downloaded: 10, Total: 100}.ETA(), "unknown speed")
+}
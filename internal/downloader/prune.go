@@ -0,0 +1,81 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PruneStaleSpeculative drops speculative (Priority 3-4) predicted downloads
+// that never started and are no longer relevant, either because they have
+// waited longer than the configured relevance window or because their
+// genres no longer match the user's preferences. Returns the number removed.
+func (p *Predictor) PruneStaleSpeculative(ctx context.Context) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.pruneStaleSpeculative(ctx)
+}
+
+// pruneStaleSpeculative implements PruneStaleSpeculative. Callers must hold p.mu.
+func (p *Predictor) pruneStaleSpeculative(ctx context.Context) (int, error) {
+	items, err := p.storage.GetQueueItems("queued")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list queued items: %w", err)
+	}
+
+	removed := 0
+	for _, item := range items {
+		if item.Priority < 3 || item.Source != SourcePrediction || !item.StartedAt.IsZero() {
+			continue
+		}
+
+		reason := p.staleReason(item.MediaID, item.CreatedAt)
+		if reason == "" {
+			continue
+		}
+
+		if err := p.storage.RemoveQueueItem(item.ID); err != nil {
+			p.logger.Warn("Failed to drop stale speculative download",
+				"job_id", item.ID, "media_id", item.MediaID, "error", err)
+			continue
+		}
+
+		p.logger.Info("Dropped stale speculative download",
+			"job_id", item.ID,
+			"media_id", item.MediaID,
+			"priority", item.Priority,
+			"queued_for", time.Since(item.CreatedAt).Round(time.Minute),
+			"reason", reason)
+		removed++
+	}
+
+	return removed, nil
+}
+
+// staleReason returns why a speculative item is no longer relevant,
+// or an empty string if it should stay queued.
+func (p *Predictor) staleReason(mediaID string, queuedAt time.Time) string {
+	if p.config.SpeculativeTTL > 0 && time.Since(queuedAt) > p.config.SpeculativeTTL {
+		return "relevance window expired"
+	}
+
+	if len(p.preferences.PreferredGenres) == 0 {
+		return ""
+	}
+
+	metadata, err := p.storage.GetMediaMetadata(mediaID)
+	if err != nil || len(metadata.Genres) == 0 {
+		return ""
+	}
+
+	for _, genre := range metadata.Genres {
+		for _, preferred := range p.preferences.PreferredGenres {
+			if genre == preferred {
+				return ""
+			}
+		}
+	}
+
+	return "no longer matches preferred genres"
+}
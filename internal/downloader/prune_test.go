@@ -0,0 +1,46 @@
+package downloader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+func TestPruneStaleSpeculative(t *testing.T) {
+	predictor, sm, _ := newReconcileTestPredictor(t)
+	predictor.config.SpeculativeTTL = 7 * 24 * time.Hour
+
+	old := time.Now().Add(-8 * 24 * time.Hour)
+	fresh := time.Now()
+
+	items := []*storage.QueueItem{
+		{ID: "expired", MediaID: "expired", Priority: 4, Status: "queued", CreatedAt: old, Source: SourcePrediction},
+		{ID: "recent", MediaID: "recent", Priority: 3, Status: "queued", CreatedAt: fresh, Source: SourcePrediction},
+		{ID: "next-ep", MediaID: "next-ep", Priority: 1, Status: "queued", CreatedAt: old, Source: SourcePrediction},
+		{ID: "manual", MediaID: "manual", Priority: 3, Status: "queued", CreatedAt: old, Source: SourceManual},
+		{ID: "off-genre", MediaID: "off-genre", Priority: 4, Status: "queued", CreatedAt: fresh, Source: SourcePrediction},
+	}
+	for _, item := range items {
+		require.NoError(t, sm.AddQueueItem(item))
+	}
+
+	require.NoError(t, sm.AddMediaMetadata(&storage.MediaMetadata{ID: "off-genre", JellyfinID: "off-genre", Genres: []string{"Horror"}}))
+	require.NoError(t, sm.AddMediaMetadata(&storage.MediaMetadata{ID: "recent", JellyfinID: "recent", Genres: []string{"Comedy"}}))
+	predictor.preferences.PreferredGenres = []string{"Comedy"}
+
+	removed, err := predictor.PruneStaleSpeculative(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+
+	remaining := queuedByMedia(t, sm)
+	assert.NotContains(t, remaining, "expired")
+	assert.NotContains(t, remaining, "off-genre")
+	assert.Contains(t, remaining, "recent")
+	assert.Contains(t, remaining, "next-ep")
+	assert.Contains(t, remaining, "manual")
+}
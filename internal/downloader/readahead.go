@@ -0,0 +1,277 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// headPriority is the priority an opening segment is fetched at. The next
+// episode's first minutes are needed as soon as the current one ends, so
+// they go ahead of everything, like the item being played.
+const headPriority = 0
+
+// errHeadFetched stops a download once the requested opening segment is on
+// disk; the rest is requeued at the job's usual priority.
+var errHeadFetched = errors.New("opening segment fetched")
+
+// headRequest asks for the first bytes of a media item ahead of the rest.
+type headRequest struct {
+	bytes        int64
+	tailPriority int // priority the remainder is requeued at
+}
+
+// headRequestFor returns the pending opening-segment request for mediaID.
+func (m *Manager) headRequestFor(mediaID string) (headRequest, bool) {
+	m.headMu.Lock()
+	defer m.headMu.Unlock()
+	req, ok := m.heads[mediaID]
+	return req, ok
+}
+
+// setHeadRequest records an opening-segment request for mediaID.
+func (m *Manager) setHeadRequest(mediaID string, req headRequest) {
+	m.headMu.Lock()
+	defer m.headMu.Unlock()
+	if m.heads == nil {
+		m.heads = make(map[string]headRequest)
+	}
+	m.heads[mediaID] = req
+}
+
+// takeHeadRequest removes and returns the request for mediaID.
+func (m *Manager) takeHeadRequest(mediaID string) (headRequest, bool) {
+	m.headMu.Lock()
+	defer m.headMu.Unlock()
+	req, ok := m.heads[mediaID]
+	delete(m.heads, mediaID)
+	return req, ok
+}
+
+// PrioritizeHead fetches the first bytes of mediaID ahead of all other
+// downloads and then lets the rest finish at its usual priority:
+//
+//   - not queued: queued with its opening segment first, then Priority 1
+//   - queued: promoted until the opening segment is down
+//   - downloading: promoted mid-flight, unless the segment is already down
+//
+// Downloads stream into their partial file in order, so the opening
+// segment is simply the prefix; stopping after it and requeueing resumes
+// from that offset.
+func (m *Manager) PrioritizeHead(ctx context.Context, mediaID string, bytes int64) error {
+	if bytes <= 0 {
+		return nil
+	}
+
+	item, err := m.storage.FindActiveQueueItem(mediaID)
+	if err != nil {
+		return fmt.Errorf("failed to check queue: %w", err)
+	}
+
+	if item == nil {
+		m.setHeadRequest(mediaID, headRequest{bytes: bytes, tailPriority: 1})
+		if _, err := m.QueueDownloadWithSource(ctx, mediaID, headPriority, SourcePlayback); err != nil {
+			m.takeHeadRequest(mediaID)
+			return err
+		}
+		m.logger.Info("Queued next item with opening segment first",
+			"media_id", mediaID, "head_bytes", bytes)
+		return nil
+	}
+
+	if item.Priority <= headPriority {
+		return nil // already fetched first in full
+	}
+	if _, pending := m.headRequestFor(mediaID); pending {
+		return nil
+	}
+
+	if item.Status == "downloading" {
+		m.activeMu.Lock()
+		tracker, ok := m.active[item.ID]
+		done := ok && tracker.downloaded.Load() >= bytes
+		m.activeMu.Unlock()
+		if done {
+			return nil
+		}
+
+		m.setHeadRequest(mediaID, headRequest{bytes: bytes, tailPriority: item.Priority})
+		if err := m.SetJobPriority(item.ID, headPriority); err != nil {
+			m.takeHeadRequest(mediaID)
+			return err
+		}
+	} else {
+		m.setHeadRequest(mediaID, headRequest{bytes: bytes, tailPriority: item.Priority})
+		if err := m.storage.UpdateQueueItemPriority(item.ID, headPriority); err != nil {
+			m.takeHeadRequest(mediaID)
+			return fmt.Errorf("failed to promote queued download: %w", err)
+		}
+	}
+
+	m.logger.Info("Fetching opening segment of next item first",
+		"media_id", mediaID,
+		"job_id", item.ID,
+		"status", item.Status,
+		"head_bytes", bytes,
+		"tail_priority", item.Priority)
+	return nil
+}
+
+// headReader ends a download with errHeadFetched once a pending
+// opening-segment request for it is satisfied. Requests are looked up on
+// every read, so one made mid-download takes effect immediately.
+type headReader struct {
+	r       io.Reader
+	m       *Manager
+	mediaID string
+	pos     int64 // absolute offset in the file
+	total   int64
+}
+
+func (h *headReader) Read(p []byte) (int, error) {
+	if req, ok := h.m.headRequestFor(h.mediaID); ok && req.bytes < h.total {
+		remaining := req.bytes - h.pos
+		if remaining <= 0 {
+			return 0, errHeadFetched
+		}
+		if int64(len(p)) > remaining {
+			p = p[:remaining]
+		}
+	}
+
+	n, err := h.r.Read(p)
+	h.pos += int64(n)
+	return n, err
+}
+
+// finishHead requeues a download stopped after its opening segment at the
+// priority it had before it was promoted. It resumes from the partial file.
+func (m *Manager) finishHead(job *DownloadJob) {
+	req, ok := m.takeHeadRequest(job.MediaID)
+	if !ok {
+		req.tailPriority = job.Priority
+	}
+
+	item, err := m.storage.FindActiveQueueItem(job.MediaID)
+	if err != nil || item == nil {
+		m.logger.Warn("Opening segment fetched but queue item is gone",
+			"job_id", job.ID, "media_id", job.MediaID, "error", err)
+		return
+	}
+
+	item.Status = "queued"
+	if err := m.storage.UpdateQueueItem(item); err != nil {
+		m.logger.Error("Failed to requeue download after opening segment",
+			"job_id", job.ID, "error", err)
+		return
+	}
+	if err := m.storage.UpdateQueueItemPriority(item.ID, req.tailPriority); err != nil {
+		m.logger.Error("Failed to restore download priority after opening segment",
+			"job_id", job.ID, "error", err)
+	}
+
+	m.logger.Info("Opening segment fetched, finishing at usual priority",
+		"job_id", job.ID,
+		"media_id", job.MediaID,
+		"head_bytes", req.bytes,
+		"priority", req.tailPriority)
+}
+
+// ReadAhead smooths binge transitions: while a cached episode or track is
+// streaming it makes sure the opening segment of the next one is fetched
+// before any other download, so the next item can start playing even if
+// it is still mid-download when the current one ends.
+type ReadAhead struct {
+	manager   *Manager
+	headBytes int64
+	logger    *slog.Logger
+}
+
+// NewReadAhead creates a read-ahead coordinator fetching the configured
+// read_ahead_mb of each next item through manager.
+func NewReadAhead(manager *Manager, logger *slog.Logger) *ReadAhead {
+	return &ReadAhead{
+		manager:   manager,
+		headBytes: int64(manager.config.ReadAheadMB) * 1024 * 1024,
+		logger:    logger,
+	}
+}
+
+// Enabled reports whether read-ahead is turned on.
+func (r *ReadAhead) Enabled() bool {
+	return r != nil && r.headBytes > 0
+}
+
+// OnCachedPlayback is called when a cached item starts streaming. It looks
+// up the next episode, track or chapter and, unless it is already cached,
+// has its opening segment fetched first.
+func (r *ReadAhead) OnCachedPlayback(ctx context.Context, mediaID string) error {
+	if !r.Enabled() {
+		return nil
+	}
+
+	nextID, err := r.nextInSequence(mediaID)
+	if err != nil || nextID == "" {
+		return err
+	}
+
+	if cached, err := r.manager.storage.IsMediaCached(nextID); err == nil && cached {
+		return nil
+	}
+
+	r.logger.Debug("Reading ahead into next item",
+		"media_id", mediaID, "next_id", nextID)
+	return r.manager.PrioritizeHead(ctx, nextID, r.headBytes)
+}
+
+// nextInSequence returns the episode, track or chapter that follows
+// mediaID, or "" when there is none or it is not a sequential type.
+func (r *ReadAhead) nextInSequence(mediaID string) (string, error) {
+	store := r.manager.storage
+
+	metadata, err := store.GetMediaMetadata(mediaID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get metadata: %w", err)
+	}
+
+	switch metadata.Type {
+	case "episode":
+		if metadata.SeriesID == "" {
+			return "", nil
+		}
+		episodes, err := store.GetSeriesEpisodes(metadata.SeriesID, metadata.SeasonNumber)
+		if err != nil {
+			return "", fmt.Errorf("failed to get series episodes: %w", err)
+		}
+		for _, episode := range episodes {
+			if episode.Season == metadata.SeasonNumber && episode.Episode == metadata.EpisodeNumber+1 {
+				return episode.ID, nil
+			}
+		}
+
+		// Last episode of the season: read ahead into the next season
+		next, err := store.GetSeriesEpisodes(metadata.SeriesID, metadata.SeasonNumber+1)
+		if err != nil || len(next) == 0 {
+			return "", nil
+		}
+		return next[0].ID, nil
+
+	case "audio", "audiobook":
+		if metadata.AlbumID == "" {
+			return "", nil
+		}
+		tracks, err := store.GetAlbumTracks(metadata.AlbumID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get album tracks: %w", err)
+		}
+		for i, track := range tracks {
+			if track.ID == mediaID && i+1 < len(tracks) {
+				return tracks[i+1].ID, nil
+			}
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,142 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func newReadAheadTestManager(t *testing.T) (*Manager, *storagetest.MemStore) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	for _, metadata := range []*storage.MediaMetadata{
+		{ID: "s1e1", JellyfinID: "s1e1", Type: "episode", SeriesID: "show", SeasonNumber: 1, EpisodeNumber: 1},
+		{ID: "s1e2", JellyfinID: "s1e2", Type: "episode", SeriesID: "show", SeasonNumber: 1, EpisodeNumber: 2},
+		{ID: "s2e1", JellyfinID: "s2e1", Type: "episode", SeriesID: "show", SeasonNumber: 2, EpisodeNumber: 1},
+		{ID: "film", JellyfinID: "film", Type: "movie"},
+	} {
+		require.NoError(t, store.AddMediaMetadata(metadata))
+	}
+
+	manager := New(&config.DownloadConfig{Workers: 1, ReadAheadMB: 1}, store, logger)
+	manager.running = true
+	return manager, store
+}
+
+func TestReadAheadQueuesNextEpisodeHeadFirst(t *testing.T) {
+	manager, store := newReadAheadTestManager(t)
+	readAhead := NewReadAhead(manager, manager.logger)
+	require.True(t, readAhead.Enabled())
+
+	require.NoError(t, readAhead.OnCachedPlayback(context.Background(), "s1e1"))
+
+	item, err := store.FindActiveQueueItem("s1e2")
+	require.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, headPriority, item.Priority)
+	req, ok := manager.headRequestFor("s1e2")
+	require.True(t, ok)
+	assert.Equal(t, int64(1024*1024), req.bytes)
+	assert.Equal(t, 1, req.tailPriority)
+
+	// The season finale reads ahead into the next season
+	require.NoError(t, readAhead.OnCachedPlayback(context.Background(), "s1e2"))
+	item, err = store.FindActiveQueueItem("s2e1")
+	require.NoError(t, err)
+	assert.NotNil(t, item)
+
+	// Movies have nothing to read ahead into
+	require.NoError(t, readAhead.OnCachedPlayback(context.Background(), "film"))
+}
+
+func TestReadAheadPromotesQueuedDownload(t *testing.T) {
+	manager, store := newReadAheadTestManager(t)
+	_, err := manager.QueueDownloadWithSource(context.Background(), "s1e2", 2, SourcePrediction)
+	require.NoError(t, err)
+
+	require.NoError(t, NewReadAhead(manager, manager.logger).OnCachedPlayback(context.Background(), "s1e1"))
+
+	item, err := store.FindActiveQueueItem("s1e2")
+	require.NoError(t, err)
+	assert.Equal(t, headPriority, item.Priority)
+	req, ok := manager.headRequestFor("s1e2")
+	require.True(t, ok)
+	assert.Equal(t, 2, req.tailPriority, "the rest finishes at the priority it had")
+}
+
+func TestReadAheadDisabled(t *testing.T) {
+	manager, store := newReadAheadTestManager(t)
+	manager.config.ReadAheadMB = 0
+	readAhead := NewReadAhead(manager, manager.logger)
+
+	assert.False(t, readAhead.Enabled())
+	require.NoError(t, readAhead.OnCachedPlayback(context.Background(), "s1e1"))
+	item, err := store.FindActiveQueueItem("s1e2")
+	require.NoError(t, err)
+	assert.Nil(t, item)
+}
+
+func TestHeadFirstDownloadResumesAtTailPriority(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 3*64*1024) // 3 MiB
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "episode.mkv", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	manager := New(&config.DownloadConfig{
+		Workers:            1,
+		RateLimitExemption: config.RateLimitExemptionConfig{AutoDetectLAN: true},
+	}, store, logger)
+
+	job := &DownloadJob{
+		ID:        "job-1",
+		MediaID:   "s1e2",
+		Priority:  headPriority,
+		URL:       server.URL,
+		LocalPath: filepath.Join(t.TempDir(), "episode.mkv"),
+		CreatedAt: time.Now(),
+	}
+	require.NoError(t, store.AddQueueItem(&storage.QueueItem{
+		ID: job.ID, MediaID: job.MediaID, Priority: job.Priority, Status: "downloading", CreatedAt: job.CreatedAt,
+	}))
+	manager.setHeadRequest("s1e2", headRequest{bytes: 1024 * 1024, tailPriority: 2})
+
+	// The first pass stops once the opening segment is on disk
+	result := manager.processJob(job)
+	require.True(t, result.HeadFetched, "error: %v", result.Error)
+	info, err := os.Stat(storage.PartialPath(job.LocalPath))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1024*1024), info.Size())
+
+	manager.handleResult(result)
+	item, err := store.FindActiveQueueItem("s1e2")
+	require.NoError(t, err)
+	assert.Equal(t, "queued", item.Status)
+	assert.Equal(t, 2, item.Priority)
+	_, pending := manager.headRequestFor("s1e2")
+	assert.False(t, pending)
+
+	// The second pass resumes from the segment and completes the file
+	job.Priority = item.Priority
+	result = manager.processJob(job)
+	require.True(t, result.Success, "error: %v", result.Error)
+	data, err := os.ReadFile(job.LocalPath)
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+}
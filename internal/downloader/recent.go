@@ -0,0 +1,180 @@
+package downloader
+
+import (
+	"sort"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+)
+
+// defaultRecentlyAddedDays is how long after being added to Jellyfin an
+// item still counts as new when prediction.recently_added_days is unset.
+const defaultRecentlyAddedDays = 14
+
+// recentlyAddedLimit caps how many new items the predictor remembers.
+const recentlyAddedLimit = 500
+
+// recentItem is a movie or episode added to the Jellyfin library.
+type recentItem struct {
+	MediaID   string    `json:"media_id"`
+	MediaType string    `json:"media_type"`
+	SeriesID  string    `json:"series_id,omitempty"`
+	Season    int       `json:"season,omitempty"`
+	Episode   int       `json:"episode,omitempty"`
+	Genres    []string  `json:"genres,omitempty"`
+	Library   string    `json:"library,omitempty"`
+	AddedAt   time.Time `json:"added_at"`
+}
+
+// LibraryChanged tells the predictor about items library sync found, so
+// predictRecentlyAdded can suggest the new ones. Items added to Jellyfin
+// longer ago than the recently-added window, such as everything found by
+// a first full sync, are ignored.
+func (p *Predictor) LibraryChanged(change jellyfin.LibraryChange) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cutoff := change.SyncedAt.Add(-p.recentlyAddedWindow())
+	for _, item := range change.Added {
+		mediaType := jellyfin.CacheMediaType(item.Type)
+		if (mediaType != "movie" && mediaType != "episode") || !item.DateCreated.After(cutoff) {
+			continue
+		}
+		if p.recentlyAdded == nil {
+			p.recentlyAdded = make(map[string]recentItem)
+		}
+		p.recentlyAdded[item.ID] = recentItem{
+			MediaID:   item.ID,
+			MediaType: mediaType,
+			SeriesID:  item.SeriesID,
+			Season:    item.SeasonNumber,
+			Episode:   item.EpisodeNumber,
+			Genres:    item.Genres,
+			Library:   item.LibraryName,
+			AddedAt:   item.DateCreated,
+		}
+	}
+	p.pruneRecentlyAdded(change.SyncedAt)
+}
+
+// recentlyAddedWindow returns how long after being added to Jellyfin an
+// item still counts as new.
+func (p *Predictor) recentlyAddedWindow() time.Duration {
+	days := p.config.RecentlyAddedDays
+	if days <= 0 {
+		days = defaultRecentlyAddedDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// pruneRecentlyAdded forgets items that are no longer new and, past the
+// limit, the oldest ones. Callers must hold p.mu.
+func (p *Predictor) pruneRecentlyAdded(now time.Time) {
+	cutoff := now.Add(-p.recentlyAddedWindow())
+	for id, item := range p.recentlyAdded {
+		if !item.AddedAt.After(cutoff) {
+			delete(p.recentlyAdded, id)
+		}
+	}
+	if len(p.recentlyAdded) <= recentlyAddedLimit {
+		return
+	}
+
+	items := p.recentItems()
+	for _, item := range items[recentlyAddedLimit:] {
+		delete(p.recentlyAdded, item.MediaID)
+	}
+}
+
+// recentCandidates returns the new items library sync reported together
+// with those the metadata store holds, newest first. The store also covers
+// items synced before this predictor started listening. Callers must hold
+// p.mu.
+func (p *Predictor) recentCandidates(now time.Time) []recentItem {
+	items := p.recentItems()
+
+	stored, err := p.storage.GetRecentMetadata(now.Add(-p.recentlyAddedWindow()), recentlyAddedLimit)
+	if err != nil {
+		p.logger.Warn("Failed to get recently added metadata", "error", err)
+		return items
+	}
+
+	for _, metadata := range stored {
+		if _, ok := p.recentlyAdded[metadata.JellyfinID]; ok {
+			continue
+		}
+		if metadata.Type != "movie" && metadata.Type != "episode" {
+			continue
+		}
+		items = append(items, recentItem{
+			MediaID:   metadata.JellyfinID,
+			MediaType: metadata.Type,
+			SeriesID:  metadata.SeriesID,
+			Season:    metadata.SeasonNumber,
+			Episode:   metadata.EpisodeNumber,
+			Genres:    metadata.Genres,
+			Library:   metadata.Library,
+			AddedAt:   metadata.AddedAt(),
+		})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].AddedAt.After(items[j].AddedAt)
+	})
+	return items
+}
+
+// recentItems returns the remembered new items, newest first. Callers must
+// hold p.mu.
+func (p *Predictor) recentItems() []recentItem {
+	items := make([]recentItem, 0, len(p.recentlyAdded))
+	for _, item := range p.recentlyAdded {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].AddedAt.Equal(items[j].AddedAt) {
+			return items[i].AddedAt.After(items[j].AddedAt)
+		}
+		return items[i].MediaID < items[j].MediaID
+	})
+	return items
+}
+
+// recentConfidence scores a new item against the viewing history: a new
+// episode of a series the user watches scores highest, then items sharing
+// genres the user prefers. Newer items score higher. It returns 0 for
+// items that match nothing.
+func (p *Predictor) recentConfidence(item recentItem, watchedSeries map[string]bool, now time.Time) (float64, string) {
+	var confidence float64
+	var reason string
+
+	switch {
+	case item.SeriesID != "" && watchedSeries[item.SeriesID]:
+		confidence = 0.9
+		reason = "New episode of a series you watch"
+	default:
+		matches := 0
+		for _, genre := range item.Genres {
+			for _, preferred := range p.preferences.PreferredGenres {
+				if genre == preferred {
+					matches++
+				}
+			}
+		}
+		if matches == 0 {
+			return 0, ""
+		}
+		confidence = 0.7 + 0.05*float64(matches)
+		if confidence > 0.85 {
+			confidence = 0.85
+		}
+		reason = "Recently added in a genre you watch"
+	}
+
+	// Lose up to a sixth of the confidence as the item ages out of the window
+	age := now.Sub(item.AddedAt)
+	if age > 0 {
+		confidence *= 1 - float64(age)/float64(p.recentlyAddedWindow())/6
+	}
+	return confidence, reason
+}
@@ -0,0 +1,98 @@
+package downloader
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestPredictRecentlyAdded(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	cfg := &config.PredictionConfig{HistoryDays: 30, SyncInterval: time.Hour, MinConfidence: 0.7}
+	predictor := NewPredictor(store, cfg, logger)
+	predictor.SetLibraryFilter(&config.LibraryFilterConfig{Exclude: []string{"Home Videos"}})
+	predictor.viewingHistory = []ViewingSession{{MediaID: "s1e1", MediaType: "episode", SeriesID: "s1", Season: 1, Episode: 1}}
+	predictor.preferences.PreferredGenres = []string{"Sci-Fi", "Drama"}
+
+	require.NoError(t, store.AddDownloadRecord(&storage.DownloadRecord{ID: "cached", JellyfinID: "cached", Status: "completed"}))
+
+	now := time.Now().UTC().Round(0)
+	day := 24 * time.Hour
+	predictor.LibraryChanged(jellyfin.LibraryChange{
+		SyncedAt: now,
+		Added: []jellyfin.MediaItem{
+			{ID: "s1e2", Type: "Episode", SeriesID: "s1", SeasonNumber: 1, EpisodeNumber: 2, DateCreated: now.Add(-day)},
+			{ID: "sci-fi", Type: "Movie", Genres: []string{"Sci-Fi", "Drama"}, DateCreated: now.Add(-2 * day)},
+			{ID: "romcom", Type: "Movie", Genres: []string{"Romance"}, DateCreated: now.Add(-day)},
+			{ID: "old", Type: "Movie", Genres: []string{"Sci-Fi"}, DateCreated: now.Add(-30 * day)},
+			{ID: "cached", Type: "Movie", Genres: []string{"Sci-Fi"}, DateCreated: now.Add(-day)},
+			{ID: "party", Type: "Movie", Genres: []string{"Drama"}, LibraryName: "Home Videos", DateCreated: now.Add(-day)},
+			{ID: "s2", Type: "Series", Genres: []string{"Sci-Fi"}, DateCreated: now.Add(-day)},
+		},
+	})
+	assert.Len(t, predictor.recentlyAdded, 5, "old items and series are not remembered")
+
+	predictions := predictor.predictRecentlyAdded()
+	require.Len(t, predictions, 2)
+	byID := make(map[string]PredictionResult)
+	for _, pred := range predictions {
+		byID[pred.MediaID] = pred
+		assert.Equal(t, 3, pred.Priority)
+	}
+
+	episode := byID["s1e2"]
+	assert.Equal(t, "episode", episode.MediaType)
+	assert.Equal(t, "s1", episode.SeriesID)
+	assert.Greater(t, episode.Confidence, byID["sci-fi"].Confidence, "a new episode of a watched series beats a genre match")
+	assert.Greater(t, byID["sci-fi"].Confidence, cfg.MinConfidence)
+
+	// New items survive a restart
+	require.NoError(t, predictor.SaveState())
+	restarted := NewPredictor(store, cfg, logger)
+	require.NoError(t, restarted.RestoreState())
+	assert.Equal(t, predictor.recentItems(), restarted.recentItems())
+}
+
+func TestPredictRecentlyAddedFromMetadataStore(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	cfg := &config.PredictionConfig{HistoryDays: 30, SyncInterval: time.Hour, MinConfidence: 0.7, RecentlyAddedDays: 7}
+	predictor := NewPredictor(store, cfg, logger)
+	predictor.preferences.PreferredGenres = []string{"Sci-Fi"}
+
+	now := time.Now().UTC().Round(0)
+	day := 24 * time.Hour
+	for _, metadata := range []*storage.MediaMetadata{
+		{ID: "new", JellyfinID: "new", Type: "movie", Genres: []string{"Sci-Fi"}, DateCreated: now.Add(-day), LastSynced: now},
+		{ID: "older", JellyfinID: "older", Type: "movie", Genres: []string{"Sci-Fi"}, DateCreated: now.Add(-5 * day), LastSynced: now},
+		// Refreshed recently, but added long ago
+		{ID: "refreshed", JellyfinID: "refreshed", Type: "movie", Genres: []string{"Sci-Fi"}, DateCreated: now.Add(-10 * day), LastSynced: now},
+		// No DateCreated from the server; the sync time stands in
+		{ID: "undated", JellyfinID: "undated", Type: "movie", Genres: []string{"Sci-Fi"}, LastSynced: now.Add(-2 * day)},
+		{ID: "cached", JellyfinID: "cached", Type: "movie", Genres: []string{"Sci-Fi"}, DateCreated: now.Add(-day)},
+		{ID: "series", JellyfinID: "series", Type: "series", Genres: []string{"Sci-Fi"}, DateCreated: now.Add(-day)},
+		{ID: "drama", JellyfinID: "drama", Type: "movie", Genres: []string{"Drama"}, DateCreated: now.Add(-day)},
+	} {
+		require.NoError(t, store.AddMediaMetadata(metadata))
+	}
+	require.NoError(t, store.AddDownloadRecord(&storage.DownloadRecord{ID: "cached", JellyfinID: "cached", Status: "completed"}))
+
+	predictions := predictor.predictRecentlyAdded()
+	ids := make([]string, len(predictions))
+	for i, pred := range predictions {
+		ids[i] = pred.MediaID
+		assert.Equal(t, 3, pred.Priority)
+	}
+	assert.Equal(t, []string{"new", "undated", "older"}, ids)
+	assert.Greater(t, predictions[0].Confidence, predictions[2].Confidence, "newer items score higher")
+}
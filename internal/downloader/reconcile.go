@@ -0,0 +1,348 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// ReconcileSummary reports what a queue reconciliation pass changed.
+type ReconcileSummary struct {
+	Added         int `json:"added"`
+	Reprioritized int `json:"reprioritized"`
+	Cancelled     int `json:"cancelled"`
+	Unchanged     int `json:"unchanged"`
+	// Shared counts predicted items already queued for another household
+	// user that this user's predictions now also hold on to
+	Shared int `json:"shared"`
+	// Trimmed counts predictions left out because their priority class
+	// was at its queue limit
+	Trimmed int `json:"trimmed"`
+	// Deferred counts predictions left out because the cache has no room
+	// for them; later cycles try again
+	Deferred int `json:"deferred"`
+	// OverBudget counts speculative predictions left out because they
+	// would exceed prediction.max_speculative_gb
+	OverBudget int `json:"over_budget"`
+}
+
+// RunPredictionCycle runs PredictNext, drops stale speculative downloads and
+// reconciles the download queue against the result while holding the
+// predictor lock, so a concurrent OnPlaybackStart cannot interleave and
+// queue overlapping items.
+func (p *Predictor) RunPredictionCycle(ctx context.Context, userID string) (*ReconcileSummary, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.Enabled() {
+		p.logger.Debug("Skipping prediction cycle, predictions are turned off")
+		return &ReconcileSummary{}, nil
+	}
+	predictions, err := p.predictNext(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.pruneStaleSpeculative(ctx); err != nil {
+		p.logger.Warn("Failed to prune stale speculative downloads", "error", err)
+	}
+
+	summary, err := p.reconcileQueue(ctx, userID, predictions)
+	if err != nil {
+		return nil, err
+	}
+	p.recordSync(userID, len(predictions), summary)
+	return summary, nil
+}
+
+// recordSync logs a prediction cycle in the event log.
+func (p *Predictor) recordSync(userID string, predicted int, summary *ReconcileSummary) {
+	details := map[string]interface{}{
+		"predicted":     predicted,
+		"added":         summary.Added,
+		"reprioritized": summary.Reprioritized,
+		"cancelled":     summary.Cancelled,
+		"deferred":      summary.Deferred,
+		"trimmed":       summary.Trimmed,
+		"over_budget":   summary.OverBudget,
+	}
+	if userID != "" {
+		details["user_id"] = userID
+	}
+	recordEvent(p.storage, p.logger, &storage.Event{
+		Kind: storage.EventSync,
+		Message: fmt.Sprintf("Prediction cycle: %d predicted, %d queued, %d cancelled",
+			predicted, summary.Added, summary.Cancelled),
+		Details: details,
+	})
+}
+
+// RunHouseholdCycle runs a prediction cycle for every configured household
+// user in turn and returns the combined summary. Items several users are
+// predicted to watch are cached once and kept until none of them wants it.
+func (p *Predictor) RunHouseholdCycle(ctx context.Context) (*ReconcileSummary, error) {
+	total := &ReconcileSummary{}
+
+	for _, userID := range p.config.HouseholdUsers {
+		summary, err := p.RunPredictionCycle(ctx, userID)
+		if err != nil {
+			return total, fmt.Errorf("prediction cycle for user %s: %w", userID, err)
+		}
+
+		total.Added += summary.Added
+		total.Reprioritized += summary.Reprioritized
+		total.Cancelled += summary.Cancelled
+		total.Unchanged += summary.Unchanged
+		total.Shared += summary.Shared
+		total.Trimmed += summary.Trimmed
+		total.Deferred += summary.Deferred
+		total.OverBudget += summary.OverBudget
+	}
+
+	return total, nil
+}
+
+// ReconcileQueue diffs a prediction set against the current download queue.
+// New predictions are queued, predicted items whose priority changed are
+// re-prioritized, and speculative items that are no longer predicted are
+// cancelled. Items queued manually or by playback are never cancelled.
+func (p *Predictor) ReconcileQueue(ctx context.Context, predictions []PredictionResult) (*ReconcileSummary, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.reconcileQueue(ctx, "", predictions)
+}
+
+// reconcileQueue implements ReconcileQueue on behalf of userID, which may be
+// empty when predictions are not tied to a household user. Callers must
+// hold p.mu.
+func (p *Predictor) reconcileQueue(ctx context.Context, userID string, predictions []PredictionResult) (*ReconcileSummary, error) {
+	summary := &ReconcileSummary{}
+
+	// Collapse duplicate predictions, keeping the most urgent priority
+	wanted := make(map[string]PredictionResult, len(predictions))
+	for _, pred := range predictions {
+		if existing, ok := wanted[pred.MediaID]; !ok || pred.Priority < existing.Priority {
+			wanted[pred.MediaID] = pred
+		}
+	}
+
+	queued, err := p.storage.GetQueueItems("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queue: %w", err)
+	}
+
+	for _, item := range queued {
+		if item.Status != "queued" && item.Status != "downloading" {
+			continue
+		}
+
+		pred, stillWanted := wanted[item.MediaID]
+		delete(wanted, item.MediaID)
+
+		// Only items the reconciler queued itself, and that have not started,
+		// are eligible for changes
+		if item.Source != SourcePrediction || item.Status != "queued" {
+			summary.Unchanged++
+			continue
+		}
+
+		// Household members other than this user who still want the item
+		others := otherUsers(item.Users, userID)
+
+		if !stillWanted {
+			if len(others) > 0 {
+				// Only drop this user's claim; the shared copy stays queued
+				if len(others) != len(item.Users) {
+					if err := p.storage.SetQueueItemUsers(item.ID, others); err != nil {
+						p.logger.Warn("Failed to release shared predicted download",
+							"job_id", item.ID, "media_id", item.MediaID, "error", err)
+					}
+				}
+				summary.Unchanged++
+				continue
+			}
+
+			if err := p.storage.RemoveQueueItem(item.ID); err != nil {
+				p.logger.Warn("Failed to cancel stale speculative download",
+					"job_id", item.ID, "media_id", item.MediaID, "error", err)
+				continue
+			}
+			p.logger.Info("Cancelled speculative download no longer predicted",
+				"job_id", item.ID, "media_id", item.MediaID, "priority", item.Priority)
+			summary.Cancelled++
+			continue
+		}
+
+		if userID != "" && !containsUser(item.Users, userID) {
+			users := append(append([]string(nil), item.Users...), userID)
+			if err := p.storage.SetQueueItemUsers(item.ID, users); err != nil {
+				p.logger.Warn("Failed to share predicted download",
+					"job_id", item.ID, "media_id", item.MediaID, "error", err)
+			} else if len(others) > 0 {
+				summary.Shared++
+			}
+		}
+
+		// A shared item is only ever made more urgent, never less
+		if pred.Priority != item.Priority && (len(others) == 0 || pred.Priority < item.Priority) {
+			if err := p.storage.UpdateQueueItemPriority(item.ID, pred.Priority); err != nil {
+				p.logger.Warn("Failed to re-prioritize predicted download",
+					"job_id", item.ID, "media_id", item.MediaID, "error", err)
+				continue
+			}
+			p.logger.Debug("Re-prioritized predicted download",
+				"job_id", item.ID,
+				"old_priority", item.Priority,
+				"new_priority", pred.Priority)
+			summary.Reprioritized++
+			continue
+		}
+
+		summary.Unchanged++
+	}
+
+	if p.downloadManager == nil {
+		return summary, nil
+	}
+
+	// Predicted items are cached for the device the user will likely watch
+	// on next, e.g. a 720p variant ahead of a morning commute on a phone
+	quality, device := p.PredictedQuality(time.Now())
+	qualityQueuer, canPickQuality := p.downloadManager.(QualityQueuer)
+	if quality != "" && canPickQuality {
+		p.logger.Debug("Caching predicted downloads for expected device",
+			"device", device, "quality", quality)
+	}
+
+	// Most urgent and most confident first, so a full queue trims the
+	// least likely predictions
+	pending := make([]PredictionResult, 0, len(wanted))
+	for _, pred := range wanted {
+		pending = append(pending, pred)
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].Priority != pending[j].Priority {
+			return pending[i].Priority < pending[j].Priority
+		}
+		if pending[i].Confidence != pending[j].Confidence {
+			return pending[i].Confidence > pending[j].Confidence
+		}
+		return pending[i].MediaID < pending[j].MediaID
+	})
+
+	// Counted after stale items were cancelled above, so they free space
+	budget, err := p.speculativeBudget()
+	if err != nil {
+		p.logger.Warn("Failed to measure speculative downloads, not capping them", "error", err)
+		budget = &speculativeBudget{}
+	}
+
+	for _, pred := range pending {
+		mediaID := pred.MediaID
+		if cached, err := p.storage.IsMediaCached(mediaID); err == nil && cached {
+			continue
+		}
+		size := p.predictedSize(pred)
+		if !budget.fits(pred.Priority, size) {
+			summary.OverBudget++
+			continue
+		}
+
+		var jobID string
+		var err error
+		if quality != "" && canPickQuality {
+			jobID, err = qualityQueuer.QueueDownloadWithQuality(ctx, mediaID, pred.Priority, SourcePrediction, quality)
+		} else {
+			jobID, err = p.downloadManager.QueueDownloadWithSource(ctx, mediaID, pred.Priority, SourcePrediction)
+		}
+		if errors.Is(err, ErrQueueFull) {
+			summary.Trimmed++
+			continue
+		}
+		if errors.Is(err, ErrInsufficientSpace) {
+			summary.Deferred++
+			continue
+		}
+		if err != nil {
+			p.logger.Warn("Failed to queue predicted download",
+				"media_id", mediaID, "error", err)
+			continue
+		}
+		if userID != "" {
+			if err := p.storage.SetQueueItemUsers(jobID, []string{userID}); err != nil {
+				p.logger.Warn("Failed to record predicted download user",
+					"job_id", jobID, "user_id", userID, "error", err)
+			}
+		}
+		budget.reserve(pred.Priority, size)
+		summary.Added++
+
+		details := map[string]interface{}{
+			"job_id":     jobID,
+			"priority":   pred.Priority,
+			"confidence": pred.Confidence,
+		}
+		if userID != "" {
+			details["user_id"] = userID
+		}
+		recordEvent(p.storage, p.logger, &storage.Event{
+			Kind:    storage.EventPredictionQueued,
+			MediaID: mediaID,
+			Message: pred.Reason,
+			Details: details,
+		})
+	}
+
+	if summary.Trimmed > 0 {
+		p.logger.Warn("Download queue full, trimmed predicted downloads",
+			"trimmed", summary.Trimmed, "user_id", userID)
+	}
+	if summary.Deferred > 0 {
+		p.logger.Info("Cache full, deferred predicted downloads",
+			"deferred", summary.Deferred, "user_id", userID)
+	}
+
+	if summary.OverBudget > 0 {
+		p.logger.Info("Speculative download budget reached, skipped predicted downloads",
+			"over_budget", summary.OverBudget, "user_id", userID)
+	}
+
+	p.logger.Info("Queue reconciliation complete",
+		"added", summary.Added,
+		"reprioritized", summary.Reprioritized,
+		"cancelled", summary.Cancelled,
+		"unchanged", summary.Unchanged,
+		"shared", summary.Shared,
+		"trimmed", summary.Trimmed,
+		"deferred", summary.Deferred,
+		"over_budget", summary.OverBudget,
+		"user_id", userID)
+
+	return summary, nil
+}
+
+// otherUsers returns users without userID.
+func otherUsers(users []string, userID string) []string {
+	var others []string
+	for _, user := range users {
+		if user != userID {
+			others = append(others, user)
+		}
+	}
+	return others
+}
+
+// containsUser reports whether userID is in users.
+func containsUser(users []string, userID string) bool {
+	for _, user := range users {
+		if user == userID {
+			return true
+		}
+	}
+	return false
+}
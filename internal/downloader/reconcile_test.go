@@ -0,0 +1,194 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// storageQueuer is a DownloadQueuer that writes straight to storage,
+// so reconciliation can be tested without running download workers.
+type storageQueuer struct {
+	storage *storage.Manager
+	calls   int
+}
+
+func (q *storageQueuer) QueueDownload(ctx context.Context, mediaID string, priority int) (string, error) {
+	return q.QueueDownloadWithSource(ctx, mediaID, priority, SourceManual)
+}
+
+func (q *storageQueuer) QueueDownloadWithSource(ctx context.Context, mediaID string, priority int, source string) (string, error) {
+	return q.QueueDownloadWithQuality(ctx, mediaID, priority, source, "")
+}
+
+func (q *storageQueuer) QueueDownloadWithQuality(ctx context.Context, mediaID string, priority int, source, quality string) (string, error) {
+	q.calls++
+	item := &storage.QueueItem{
+		ID:        fmt.Sprintf("%s-%d", mediaID, q.calls),
+		MediaID:   mediaID,
+		Priority:  priority,
+		Status:    "queued",
+		CreatedAt: time.Now(),
+		Source:    source,
+		Quality:   quality,
+	}
+	return item.ID, q.storage.AddQueueItem(item)
+}
+
+func newReconcileTestPredictor(t *testing.T) (*Predictor, *storage.Manager, *storageQueuer) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	storageManager, err := storage.NewManager(&config.CacheConfig{Directory: t.TempDir(), MaxSizeGB: 1, MetadataStore: "boltdb"}, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { storageManager.Close() })
+
+	predictor := NewPredictor(storageManager, &config.PredictionConfig{
+		SyncInterval:  time.Hour,
+		HistoryDays:   30,
+		MinConfidence: 0.5,
+	}, logger)
+	queuer := &storageQueuer{storage: storageManager}
+	predictor.SetDownloadManager(queuer)
+
+	return predictor, storageManager, queuer
+}
+
+func queuedByMedia(t *testing.T, sm *storage.Manager) map[string]*storage.QueueItem {
+	items, err := sm.GetQueueItems("")
+	require.NoError(t, err)
+
+	byMedia := make(map[string]*storage.QueueItem, len(items))
+	for _, item := range items {
+		byMedia[item.MediaID] = item
+	}
+	return byMedia
+}
+
+func TestReconcileQueue(t *testing.T) {
+	predictor, sm, _ := newReconcileTestPredictor(t)
+	ctx := context.Background()
+
+	// Pre-existing queue: one manual item, one stale prediction, one prediction to re-prioritize
+	require.NoError(t, sm.AddQueueItem(&storage.QueueItem{ID: "manual-1", MediaID: "manual", Priority: 3, Status: "queued", CreatedAt: time.Now(), Source: SourceManual}))
+	require.NoError(t, sm.AddQueueItem(&storage.QueueItem{ID: "stale-1", MediaID: "stale", Priority: 4, Status: "queued", CreatedAt: time.Now(), Source: SourcePrediction}))
+	require.NoError(t, sm.AddQueueItem(&storage.QueueItem{ID: "moved-1", MediaID: "moved", Priority: 4, Status: "queued", CreatedAt: time.Now(), Source: SourcePrediction}))
+
+	summary, err := predictor.ReconcileQueue(ctx, []PredictionResult{
+		{MediaID: "moved", Priority: 2, Confidence: 0.9},
+		{MediaID: "fresh", Priority: 3, Confidence: 0.8},
+		{MediaID: "fresh", Priority: 1, Confidence: 0.8}, // duplicate keeps most urgent priority
+		{MediaID: "manual", Priority: 1, Confidence: 0.8},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, summary.Added)
+	assert.Equal(t, 1, summary.Reprioritized)
+	assert.Equal(t, 1, summary.Cancelled)
+	assert.Equal(t, 1, summary.Unchanged)
+
+	byMedia := queuedByMedia(t, sm)
+	assert.NotContains(t, byMedia, "stale")
+	require.Contains(t, byMedia, "moved")
+	assert.Equal(t, 2, byMedia["moved"].Priority)
+	require.Contains(t, byMedia, "fresh")
+	assert.Equal(t, 1, byMedia["fresh"].Priority)
+	assert.Equal(t, SourcePrediction, byMedia["fresh"].Source)
+	require.Contains(t, byMedia, "manual")
+	assert.Equal(t, 3, byMedia["manual"].Priority, "manual items are never touched")
+
+	events, err := sm.GetEvents(storage.EventFilter{Kinds: []string{storage.EventPredictionQueued}})
+	require.NoError(t, err)
+	require.Len(t, events, 1, "queued predictions are logged")
+	assert.Equal(t, "fresh", events[0].MediaID)
+}
+
+func TestReconcileQueueIdempotent(t *testing.T) {
+	predictor, sm, queuer := newReconcileTestPredictor(t)
+	ctx := context.Background()
+
+	predictions := []PredictionResult{
+		{MediaID: "a", Priority: 3, Confidence: 0.9},
+		{MediaID: "b", Priority: 4, Confidence: 0.9},
+	}
+
+	_, err := predictor.ReconcileQueue(ctx, predictions)
+	require.NoError(t, err)
+
+	summary, err := predictor.ReconcileQueue(ctx, predictions)
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, summary.Added)
+	assert.Equal(t, 2, summary.Unchanged)
+	assert.Equal(t, 2, queuer.calls)
+	assert.Len(t, queuedByMedia(t, sm), 2)
+}
+
+func TestReconcileQueueKeepsStartedDownloads(t *testing.T) {
+	predictor, sm, _ := newReconcileTestPredictor(t)
+
+	require.NoError(t, sm.AddQueueItem(&storage.QueueItem{ID: "active-1", MediaID: "active", Priority: 4, Status: "downloading", CreatedAt: time.Now(), Source: SourcePrediction}))
+
+	summary, err := predictor.ReconcileQueue(context.Background(), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, summary.Cancelled)
+	assert.Contains(t, queuedByMedia(t, sm), "active")
+}
+
+func TestRunPredictionCycleDisabled(t *testing.T) {
+	predictor, sm, queuer := newReconcileTestPredictor(t)
+	ctx := context.Background()
+
+	require.NoError(t, sm.AddQueueItem(&storage.QueueItem{ID: "stale-1", MediaID: "stale", Priority: 4, Status: "queued", CreatedAt: time.Now(), Source: SourcePrediction}))
+
+	predictor.SetEnabled(false)
+	assert.False(t, predictor.Enabled())
+
+	summary, err := predictor.RunPredictionCycle(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, ReconcileSummary{}, *summary)
+	assert.Contains(t, queuedByMedia(t, sm), "stale", "earlier predictions stay queued while turned off")
+
+	predictions, err := predictor.PredictNext(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Empty(t, predictions)
+	assert.Zero(t, queuer.calls)
+
+	predictor.SetEnabled(true)
+	assert.True(t, predictor.Enabled())
+}
+
+func TestReconcileQueueKeepsRequestedPredictions(t *testing.T) {
+	predictor, sm, _ := newReconcileTestPredictor(t)
+	manager := New(&config.DownloadConfig{Workers: 1}, sm, predictor.logger)
+	manager.running = true
+	predictor.SetDownloadManager(manager)
+	ctx := context.Background()
+
+	_, err := predictor.ReconcileQueue(ctx, []PredictionResult{{MediaID: "wanted", Priority: 3, Confidence: 0.9}})
+	require.NoError(t, err)
+
+	// The user asks for the predicted item before it starts
+	_, err = manager.QueueDownloadWithSource(ctx, "wanted", 1, SourceManual)
+	require.NoError(t, err)
+	item := queuedByMedia(t, sm)["wanted"]
+	require.NotNil(t, item)
+	assert.Equal(t, SourceManual, item.Source)
+	assert.Equal(t, 1, item.Priority)
+
+	summary, err := predictor.ReconcileQueue(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, summary.Cancelled)
+	assert.Contains(t, queuedByMedia(t, sm), "wanted", "the request outlives the prediction")
+}
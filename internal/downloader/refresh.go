@@ -0,0 +1,302 @@
+package downloader
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// metadataRefreshBatch is how many items are re-fetched per Jellyfin request.
+const metadataRefreshBatch = 50
+
+// metadataRefreshInterval is how often Run looks for stale metadata. Items
+// only go stale after days, so a daily pass is plenty.
+const metadataRefreshInterval = 24 * time.Hour
+
+// metadataRefreshRequests bounds how many on-access refresh requests may
+// wait for Run; further requests are dropped and left to the daily pass.
+const metadataRefreshRequests = 16
+
+// MetadataSource looks up the current metadata of Jellyfin items
+// (implemented by jellyfin.Client).
+type MetadataSource interface {
+	GetItemsByID(ctx context.Context, ids []string) ([]jellyfin.MediaItem, error)
+}
+
+// MetadataStore is the storage the metadata refresher reads and updates.
+type MetadataStore interface {
+	GetStaleMetadata(before time.Time, limit int) ([]*storage.MediaMetadata, error)
+	GetMediaMetadata(mediaID string) (*storage.MediaMetadata, error)
+	AddMediaMetadata(metadata *storage.MediaMetadata) error
+	GetDownload(mediaID string) (*storage.DownloadRecord, error)
+}
+
+// MetadataRefresher re-fetches the metadata of cached items whose stored
+// copy is older than cache.metadata_max_age_days, so renames and corrected
+// descriptions on the server reach the cache database and the .meta.json
+// sidecars next to the cached files. Besides its daily pass, it refreshes
+// stale items as they are listed, see RequestRefresh.
+type MetadataRefresher struct {
+	source MetadataSource
+	store  MetadataStore
+	files  *storage.FileManager
+	nfo    *NFOExporter // nil leaves .nfo files alone
+	config *config.CacheConfig
+	logger *slog.Logger
+
+	// now is stubbed by tests
+	now func() time.Time
+
+	// mu serializes refresh passes
+	mu sync.Mutex
+
+	// requests carries IDs of stale items that were accessed to Run
+	requests chan []string
+}
+
+// NewMetadataRefresher creates a refresher that reads from source.
+func NewMetadataRefresher(source MetadataSource, store MetadataStore, cfg *config.CacheConfig, logger *slog.Logger) *MetadataRefresher {
+	return &MetadataRefresher{
+		source:   source,
+		store:    store,
+		files:    storage.NewFileManager(cfg.TempDirectory, logger),
+		config:   cfg,
+		logger:   logger,
+		now:      time.Now,
+		requests: make(chan []string, metadataRefreshRequests),
+	}
+}
+
+// SetNFOExporter makes refreshed items have their .nfo rewritten by e.
+func (r *MetadataRefresher) SetNFOExporter(e *NFOExporter) {
+	r.nfo = e
+}
+
+// Enabled reports whether metadata refreshing is turned on.
+func (r *MetadataRefresher) Enabled() bool {
+	return r.config.MetadataMaxAgeDays > 0
+}
+
+// Stale reports whether metadata last synced at lastSynced is older than
+// cache.metadata_max_age_days. Nothing is stale while refreshing is off.
+func (r *MetadataRefresher) Stale(lastSynced time.Time) bool {
+	return r.Enabled() && lastSynced.Before(r.now().AddDate(0, 0, -r.config.MetadataMaxAgeDays))
+}
+
+// RequestRefresh asks Run to re-fetch the metadata of the given items,
+// which were found stale on access. It never blocks; when too many
+// requests are waiting, the items are left to the next daily pass.
+func (r *MetadataRefresher) RequestRefresh(ids []string) {
+	if !r.Enabled() || len(ids) == 0 {
+		return
+	}
+	select {
+	case r.requests <- ids:
+	default:
+		r.logger.Debug("Metadata refresh requests backed up, deferring to daily pass", "items", len(ids))
+	}
+}
+
+// Refresh re-fetches stale metadata in batches until none is left and
+// returns how many items were updated. Items the server no longer has keep
+// their metadata but are marked checked, so they are not asked about again
+// until they go stale once more.
+func (r *MetadataRefresher) Refresh(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	cutoff := now.AddDate(0, 0, -r.config.MetadataMaxAgeDays)
+
+	// seen guards against looping on items whose update failed
+	seen := make(map[string]bool)
+	refreshed, missing := 0, 0
+	for ctx.Err() == nil {
+		stale, err := r.store.GetStaleMetadata(cutoff, metadataRefreshBatch)
+		if err != nil {
+			return refreshed, err
+		}
+
+		var batch []*storage.MediaMetadata
+		for _, metadata := range stale {
+			if !seen[metadata.JellyfinID] {
+				seen[metadata.JellyfinID] = true
+				batch = append(batch, metadata)
+			}
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		batchRefreshed, batchMissing, err := r.refreshBatch(ctx, batch, now)
+		refreshed += batchRefreshed
+		missing += batchMissing
+		if err != nil {
+			return refreshed, err
+		}
+	}
+
+	r.logger.Info("Metadata refresh complete",
+		"refreshed", refreshed,
+		"missing_on_server", missing)
+
+	return refreshed, ctx.Err()
+}
+
+// RefreshItems re-fetches the metadata of the given items that are still
+// stale and returns how many were updated.
+func (r *MetadataRefresher) RefreshItems(ctx context.Context, ids []string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var batch []*storage.MediaMetadata
+	for _, id := range ids {
+		metadata, err := r.store.GetMediaMetadata(id)
+		if err != nil || !r.Stale(metadata.LastSynced) {
+			continue // Refreshed since it was requested, or never stored
+		}
+		batch = append(batch, metadata)
+	}
+
+	refreshed := 0
+	for len(batch) > 0 {
+		n := min(metadataRefreshBatch, len(batch))
+		batchRefreshed, _, err := r.refreshBatch(ctx, batch[:n], r.now())
+		refreshed += batchRefreshed
+		if err != nil {
+			return refreshed, err
+		}
+		batch = batch[n:]
+	}
+	return refreshed, nil
+}
+
+// refreshBatch re-fetches the metadata of one batch of items and stores it
+// as synced at now. It returns how many items were updated and how many
+// the server no longer has.
+func (r *MetadataRefresher) refreshBatch(ctx context.Context, batch []*storage.MediaMetadata, now time.Time) (int, int, error) {
+	ids := make([]string, 0, len(batch))
+	for _, metadata := range batch {
+		ids = append(ids, metadata.JellyfinID)
+	}
+	items, err := r.source.GetItemsByID(ctx, ids)
+	if err != nil {
+		return 0, 0, err
+	}
+	current := make(map[string]jellyfin.MediaItem, len(items))
+	for _, item := range items {
+		current[item.ID] = item
+	}
+
+	refreshed, missing := 0, 0
+	for _, metadata := range batch {
+		item, ok := current[metadata.JellyfinID]
+		if ok {
+			jellyfin.ApplyMetadata(metadata, item)
+		} else {
+			missing++
+		}
+		metadata.LastSynced = now
+
+		if err := r.store.AddMediaMetadata(metadata); err != nil {
+			r.logger.Warn("Failed to store refreshed metadata",
+				"media_id", metadata.JellyfinID, "error", err)
+			continue
+		}
+		if ok {
+			refreshed++
+			r.updateSidecar(metadata)
+			if r.nfo != nil {
+				if _, err := r.nfo.Export(metadata.JellyfinID); err != nil {
+					r.logger.Warn("Failed to rewrite .nfo", "media_id", metadata.JellyfinID, "error", err)
+				}
+			}
+		}
+	}
+	return refreshed, missing, nil
+}
+
+// Run refreshes stale metadata immediately and then daily until ctx is
+// cancelled, and serves RequestRefresh in between. It is a no-op when
+// refreshing is disabled.
+func (r *MetadataRefresher) Run(ctx context.Context) {
+	if !r.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(metadataRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Refresh(ctx); err != nil && ctx.Err() == nil {
+			r.logger.Error("Metadata refresh failed", "error", err)
+		}
+
+		if !r.awaitTick(ctx, ticker.C) {
+			return
+		}
+	}
+}
+
+// awaitTick serves refresh requests until tick fires, returning true, or
+// ctx is cancelled, returning false.
+func (r *MetadataRefresher) awaitTick(ctx context.Context, tick <-chan time.Time) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-tick:
+			return true
+		case ids := <-r.requests:
+			if n, err := r.RefreshItems(ctx, ids); err != nil && ctx.Err() == nil {
+				r.logger.Warn("On-access metadata refresh failed", "error", err)
+			} else if n > 0 {
+				r.logger.Debug("Refreshed stale metadata on access", "items", n)
+			}
+		}
+	}
+}
+
+// updateSidecar rewrites the descriptive fields of the .meta.json file next
+// to the item's cached file. Sidecars describing another item in the same
+// directory are left alone.
+func (r *MetadataRefresher) updateSidecar(metadata *storage.MediaMetadata) {
+	record, err := r.store.GetDownload(metadata.JellyfinID)
+	if err != nil || record.LocalPath == "" {
+		return
+	}
+	if _, err := os.Stat(record.LocalPath); err != nil {
+		return
+	}
+
+	sidecar, err := r.files.ReadMetadata(record.LocalPath)
+	if err != nil {
+		sidecar = &storage.FileMetadata{
+			JellyfinID:   record.JellyfinID,
+			OriginalName: filepath.Base(record.LocalPath),
+			Size:         record.Size,
+			Checksum:     record.Checksum,
+			DownloadedAt: record.DownloadedAt,
+			ContentType:  record.ContentType,
+		}
+	} else if sidecar.JellyfinID != metadata.JellyfinID {
+		return
+	}
+
+	sidecar.Name = metadata.Name
+	sidecar.Overview = metadata.Overview
+	sidecar.Genres = metadata.Genres
+	sidecar.SyncedAt = metadata.LastSynced
+
+	if err := r.files.WriteMetadata(record.LocalPath, sidecar); err != nil {
+		r.logger.Warn("Failed to update metadata sidecar",
+			"media_id", metadata.JellyfinID, "path", record.LocalPath, "error", err)
+	}
+}
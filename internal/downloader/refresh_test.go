@@ -0,0 +1,173 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+var _ MetadataSource = (*jellyfin.Client)(nil)
+var _ MetadataSource = (*jellyfintest.Mock)(nil)
+
+func TestMetadataRefresher(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	old := now.AddDate(0, 0, -45)
+
+	dir := t.TempDir()
+	moviePath := filepath.Join(dir, "movies", "m1", "movie.mkv")
+	require.NoError(t, os.MkdirAll(filepath.Dir(moviePath), 0755))
+	require.NoError(t, os.WriteFile(moviePath, []byte("video"), 0644))
+
+	for _, metadata := range []*storage.MediaMetadata{
+		{ID: "m1", JellyfinID: "m1", Name: "Heat", Type: "movie", Size: 5, LastSynced: old},
+		{ID: "gone", JellyfinID: "gone", Name: "Deleted", Type: "movie", LastSynced: old},
+		{ID: "fresh", JellyfinID: "fresh", Name: "Fresh", Type: "movie", LastSynced: now.AddDate(0, 0, -1)},
+		{ID: "uncached", JellyfinID: "uncached", Name: "Uncached", Type: "movie", LastSynced: old},
+	} {
+		require.NoError(t, store.AddMediaMetadata(metadata))
+	}
+	for _, id := range []string{"m1", "gone", "fresh"} {
+		record := &storage.DownloadRecord{ID: id, JellyfinID: id, MediaType: "movie", Status: "completed"}
+		if id == "m1" {
+			record.LocalPath = moviePath
+			record.Size = 5
+		}
+		require.NoError(t, store.AddDownloadRecord(record))
+	}
+
+	source := jellyfintest.New("http://jellyfin.local")
+	source.Items = map[string]jellyfin.MediaItem{
+		"m1":    {ID: "m1", Name: "Heat (1995)", Type: "Movie", Overview: "Corrected", Genres: []string{"Crime"}},
+		"fresh": {ID: "fresh", Name: "Renamed"},
+	}
+
+	refresher := NewMetadataRefresher(source, store, &config.CacheConfig{MetadataMaxAgeDays: 30}, logger)
+	refresher.now = func() time.Time { return now }
+
+	refreshed, err := refresher.Refresh(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, refreshed)
+
+	m1, err := store.GetMediaMetadata("m1")
+	require.NoError(t, err)
+	assert.Equal(t, "Heat (1995)", m1.Name)
+	assert.Equal(t, "Corrected", m1.Overview)
+	assert.Equal(t, int64(5), m1.Size, "cache-only fields are kept")
+	assert.True(t, m1.LastSynced.Equal(now))
+
+	gone, err := store.GetMediaMetadata("gone")
+	require.NoError(t, err)
+	assert.Equal(t, "Deleted", gone.Name, "items missing on the server keep their metadata")
+	assert.True(t, gone.LastSynced.Equal(now), "and are not asked about again until stale")
+
+	fresh, _ := store.GetMediaMetadata("fresh")
+	assert.Equal(t, "Fresh", fresh.Name)
+	uncached, _ := store.GetMediaMetadata("uncached")
+	assert.True(t, uncached.LastSynced.Equal(old), "uncached items are not refreshed")
+
+	sidecar, err := storage.NewFileManager("", logger).ReadMetadata(moviePath)
+	require.NoError(t, err)
+	assert.Equal(t, "m1", sidecar.JellyfinID)
+	assert.Equal(t, "movie.mkv", sidecar.OriginalName)
+	assert.Equal(t, "Heat (1995)", sidecar.Name)
+	assert.Equal(t, []string{"Crime"}, sidecar.Genres)
+
+	refreshed, err = refresher.Refresh(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, refreshed, "nothing is stale after a pass")
+}
+
+func TestMetadataRefresherBatches(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	source := jellyfintest.New("http://jellyfin.local")
+	source.Items = make(map[string]jellyfin.MediaItem)
+
+	count := metadataRefreshBatch*2 + 5
+	for i := 0; i < count; i++ {
+		id := fmt.Sprintf("item%03d", i)
+		require.NoError(t, store.AddMediaMetadata(&storage.MediaMetadata{ID: id, JellyfinID: id, Type: "movie"}))
+		require.NoError(t, store.AddDownloadRecord(&storage.DownloadRecord{ID: id, JellyfinID: id, Status: "completed"}))
+		source.Items[id] = jellyfin.MediaItem{ID: id, Name: "new"}
+	}
+
+	refresher := NewMetadataRefresher(source, store, &config.CacheConfig{MetadataMaxAgeDays: 7}, logger)
+	refreshed, err := refresher.Refresh(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, count, refreshed)
+}
+
+func TestMetadataRefresherSourceError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	require.NoError(t, store.AddMediaMetadata(&storage.MediaMetadata{ID: "m1", JellyfinID: "m1", Type: "movie"}))
+	require.NoError(t, store.AddDownloadRecord(&storage.DownloadRecord{ID: "m1", JellyfinID: "m1", Status: "completed"}))
+
+	source := jellyfintest.New("http://jellyfin.local")
+	source.Err = errors.New("server unavailable")
+
+	refresher := NewMetadataRefresher(source, store, &config.CacheConfig{MetadataMaxAgeDays: 7}, logger)
+	_, err := refresher.Refresh(context.Background())
+	require.Error(t, err)
+
+	m1, _ := store.GetMediaMetadata("m1")
+	assert.True(t, m1.LastSynced.IsZero(), "a failed fetch leaves the item stale for the next pass")
+	assert.False(t, NewMetadataRefresher(source, store, &config.CacheConfig{}, logger).Enabled())
+}
+
+func TestMetadataRefresherOnAccess(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	now := time.Now()
+
+	require.NoError(t, store.AddMediaMetadata(&storage.MediaMetadata{ID: "old", JellyfinID: "old", Name: "Old", LastSynced: now.AddDate(0, 0, -10)}))
+	require.NoError(t, store.AddMediaMetadata(&storage.MediaMetadata{ID: "new", JellyfinID: "new", Name: "New", LastSynced: now}))
+
+	source := jellyfintest.New("http://jellyfin.local")
+	source.Items = map[string]jellyfin.MediaItem{
+		"old": {ID: "old", Name: "Old (Remastered)"},
+		"new": {ID: "new", Name: "New (Renamed)"},
+	}
+
+	refresher := NewMetadataRefresher(source, store, &config.CacheConfig{MetadataMaxAgeDays: 7}, logger)
+	assert.True(t, refresher.Stale(now.AddDate(0, 0, -10)))
+	assert.False(t, refresher.Stale(now))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		refresher.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Items that are fresh by the time the request is served are skipped
+	refresher.RequestRefresh([]string{"old", "new", "unknown"})
+	assert.Eventually(t, func() bool {
+		old, _ := store.GetMediaMetadata("old")
+		return old.Name == "Old (Remastered)"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	fresh, _ := store.GetMediaMetadata("new")
+	assert.Equal(t, "New", fresh.Name)
+}
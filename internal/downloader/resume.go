@@ -0,0 +1,88 @@
+package downloader
+
+import (
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// resumeStats counts how download attempts started since the manager was
+// created.
+type resumeStats struct {
+	resumed    atomic.Int64 // Attempts that continued a partial file
+	coldStarts atomic.Int64 // Attempts that started from byte 0
+	savedBytes atomic.Int64 // Bytes resumed attempts did not fetch again
+}
+
+// recordStart counts an attempt that continues from offset.
+func (s *resumeStats) recordStart(offset int64) {
+	if offset > 0 {
+		s.resumed.Add(1)
+		s.savedBytes.Add(offset)
+		return
+	}
+	s.coldStarts.Add(1)
+}
+
+// parseContentRange parses a Content-Range header such as
+// "bytes 100-999/1000" and returns the first byte and the complete size,
+// which is -1 when the server sends "*".
+func parseContentRange(header string) (start, size int64, err error) {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, 0, fmt.Errorf("unsupported content range %q", header)
+	}
+	span, complete, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("malformed content range %q", header)
+	}
+	first, _, ok := strings.Cut(span, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("malformed content range %q", header)
+	}
+	if start, err = strconv.ParseInt(first, 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("malformed content range %q", header)
+	}
+	size = -1
+	if complete != "*" {
+		if size, err = strconv.ParseInt(complete, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("malformed content range %q", header)
+		}
+	}
+	return start, size, nil
+}
+
+// hashPrefix feeds the first n bytes of the file at path to h, so the
+// checksum of a resumed download covers the bytes kept from earlier
+// attempts.
+func hashPrefix(h hash.Hash, path string, n int64) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open partial file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.CopyN(h, file, n); err != nil {
+		return fmt.Errorf("failed to checksum partial file: %w", err)
+	}
+	return nil
+}
+
+// discardPartial removes the partial file of job after the server refused to
+// resume it, and returns err so the retry starts from byte 0.
+func (m *Manager) discardPartial(job *DownloadJob, err error) error {
+	m.logger.Warn("Discarding partial download",
+		"job_id", job.ID,
+		"media_id", job.MediaID,
+		"reason", err)
+	if rmErr := os.Remove(storage.PartialPath(job.LocalPath)); rmErr != nil && !os.IsNotExist(rmErr) {
+		return fmt.Errorf("%w; failed to remove partial file: %v", err, rmErr)
+	}
+	return err
+}
@@ -0,0 +1,188 @@
+package downloader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func newResumeTestManager(t *testing.T, handler http.HandlerFunc) (*Manager, *storagetest.MemStore, *DownloadJob) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	store.Checksum = storage.ChecksumSHA256
+	manager := New(&config.DownloadConfig{
+		Workers:            1,
+		RetryAttempts:      3,
+		RetryDelay:         100 * time.Millisecond,
+		RateLimitExemption: config.RateLimitExemptionConfig{AutoDetectLAN: true},
+	}, store, logger)
+
+	job := &DownloadJob{
+		ID:        "job-1",
+		MediaID:   "m1",
+		Priority:  2,
+		URL:       server.URL,
+		LocalPath: filepath.Join(t.TempDir(), "movie.mkv"),
+		CreatedAt: time.Now(),
+	}
+	require.NoError(t, store.AddQueueItem(&storage.QueueItem{
+		ID: job.ID, MediaID: job.MediaID, Priority: job.Priority, Status: "downloading", CreatedAt: job.CreatedAt,
+	}))
+	return manager, store, job
+}
+
+func TestResumedDownloadChecksum(t *testing.T) {
+	content := bytes.Repeat([]byte("resumable "), 10000)
+	var ranges []string
+	manager, store, job := newResumeTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "movie.mkv", time.Time{}, bytes.NewReader(content))
+	})
+
+	// An earlier attempt left the first 40 KB behind
+	require.NoError(t, os.WriteFile(storage.PartialPath(job.LocalPath), content[:40000], 0644))
+	job.BytesDownloaded = 40000
+
+	result := manager.processJob(job)
+	require.True(t, result.Success, "error: %v", result.Error)
+	assert.Equal(t, []string{"bytes=40000-"}, ranges)
+	assert.Equal(t, int64(len(content)-40000), result.BytesRead)
+	assert.Equal(t, int64(len(content)), result.Size)
+
+	sum := sha256.Sum256(content)
+	assert.Equal(t, hex.EncodeToString(sum[:]), result.Checksum, "the checksum covers the resumed bytes")
+
+	manager.handleResult(result)
+	record, err := store.GetDownload("m1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), record.Size)
+
+	stats := manager.GetQueueStats()
+	assert.Equal(t, 1, stats.ResumedDownloads)
+	assert.Equal(t, int64(40000), stats.ResumedBytes)
+	assert.Zero(t, stats.ColdStarts)
+}
+
+func TestResumeRecordsBytesDownloaded(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 1000)
+	manager, store, job := newResumeTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		// The connection closes after 600 of the promised 1000 bytes
+		w.Header().Set("Content-Length", "1000")
+		w.Write(content[:600])
+	})
+
+	result := manager.processJob(job)
+	require.Error(t, result.Error)
+	assert.Equal(t, int64(600), result.BytesDownloaded)
+
+	manager.handleResult(result)
+	item, err := store.FindActiveQueueItem("m1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(600), item.BytesDownloaded, "the queue remembers how far the download got")
+	assert.Equal(t, 1, manager.GetQueueStats().ColdStarts)
+}
+
+func TestResumeRejectsMismatchedRange(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		header string
+	}{
+		{"wrong offset", http.StatusPartialContent, "bytes 0-999/1000"},
+		{"malformed", http.StatusPartialContent, "items 500-999"},
+		{"range not satisfiable", http.StatusRequestedRangeNotSatisfiable, "bytes */400"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, _, job := newResumeTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Range", tt.header)
+				w.WriteHeader(tt.status)
+			})
+			require.NoError(t, os.WriteFile(storage.PartialPath(job.LocalPath), make([]byte, 500), 0644))
+
+			result := manager.processJob(job)
+			require.Error(t, result.Error)
+			assert.True(t, manager.isRetryableError(result.Error, result.HTTPStatus), "the retry starts over")
+			assert.Zero(t, result.BytesDownloaded)
+			_, err := os.Stat(storage.PartialPath(job.LocalPath))
+			assert.True(t, os.IsNotExist(err), "the partial file is discarded")
+		})
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		header      string
+		start, size int64
+		wantErr     bool
+	}{
+		{"bytes 100-999/1000", 100, 1000, false},
+		{"bytes 0-0/*", 0, -1, false},
+		{"bytes 100-999", 0, 0, true},
+		{"bytes x-999/1000", 0, 0, true},
+		{"", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%q", tt.header), func(t *testing.T) {
+			start, size, err := parseContentRange(tt.header)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.start, start)
+			assert.Equal(t, tt.size, size)
+		})
+	}
+}
+
+func TestEncryptedDownload(t *testing.T) {
+	content := bytes.Repeat([]byte("encrypted "), 10000)
+	manager, _, job := newResumeTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "movie.mkv", time.Time{}, bytes.NewReader(content))
+	})
+	cipher, err := storage.NewCipher(&config.CacheEncryptionConfig{
+		Enabled: true,
+		Key:     "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+	}, t.TempDir())
+	require.NoError(t, err)
+	manager.SetCipher(cipher)
+
+	result := manager.processJob(job)
+	require.True(t, result.Success, "error: %v", result.Error)
+	assert.Equal(t, int64(len(content)), result.Size, "the size is of the content")
+
+	onDisk, err := os.ReadFile(job.LocalPath)
+	require.NoError(t, err)
+	assert.False(t, bytes.Contains(onDisk, content[:100]), "the file is stored encrypted")
+	_, err = os.Stat(storage.PartialPath(job.LocalPath))
+	assert.True(t, os.IsNotExist(err), "the unencrypted download is removed")
+
+	file, err := storage.OpenCached(job.LocalPath, cipher)
+	require.NoError(t, err)
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(content, data))
+}
@@ -0,0 +1,50 @@
+package downloader
+
+import (
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// maxRetryDelay caps the wait between attempts at a failing download.
+const maxRetryDelay = 30 * time.Second
+
+// maxErrorHistory is how many failed attempts a queue item keeps the
+// errors of.
+const maxErrorHistory = 10
+
+// retryBackoff returns how long retry number attempt (1 for the first)
+// waits: base, doubled for every earlier retry and capped at
+// maxRetryDelay, then spread by jitter, a number in [0, 1), to between 75%
+// and 125% of that so downloads that failed together do not retry
+// together. With a base of 1s that is about 1s, 2s, 4s, 8s, 16s, 30s.
+func retryBackoff(base time.Duration, attempt int, jitter float64) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return time.Duration(float64(delay) * (0.75 + 0.5*jitter))
+}
+
+// retryPending reports whether a queued item is still waiting out the
+// backoff after a failed attempt.
+func retryPending(item *storage.QueueItem, now time.Time) bool {
+	return item.NextAttemptAt.After(now)
+}
+
+// attemptErrors returns the error history of a queue item with the failed
+// attempt of result added, dropping the oldest beyond maxErrorHistory.
+func attemptErrors(item *storage.QueueItem, result *DownloadResult) []storage.AttemptError {
+	history := append(item.ErrorHistory, storage.AttemptError{
+		At:         time.Now(),
+		Message:    result.Error.Error(),
+		HTTPStatus: result.HTTPStatus,
+	})
+	if len(history) > maxErrorHistory {
+		history = history[len(history)-maxErrorHistory:]
+	}
+	return history
+}
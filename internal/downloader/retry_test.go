@@ -1,12 +1,21 @@
 package downloader
 
 import (
+	"context"
 	"errors"
+	"io"
+	"log/slog"
 	"math/rand"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
 )
 
 // TestIsRetryableError verifies error classification for retry logic
@@ -161,3 +170,114 @@ func TestRetryJitterRandomization(t *testing.T) {
 			value, maxExpected)
 	}
 }
+
+func TestRetryBackoff(t *testing.T) {
+	// Without jitter's spread: doubling from the base up to the cap
+	for attempt, want := range map[int]time.Duration{
+		1:  time.Second,
+		2:  2 * time.Second,
+		3:  4 * time.Second,
+		5:  16 * time.Second,
+		6:  30 * time.Second,
+		40: 30 * time.Second,
+	} {
+		assert.Equal(t, want, retryBackoff(time.Second, attempt, 0.5), "attempt %d", attempt)
+	}
+	assert.Equal(t, 750*time.Millisecond, retryBackoff(time.Second, 1, 0))
+	assert.Equal(t, 2500*time.Millisecond, retryBackoff(2*time.Second, 1, 0.999999).Round(time.Millisecond))
+}
+
+func TestRetryWaitsForBackoffThenDeadLetters(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	manager := New(&config.DownloadConfig{Workers: 1, RetryAttempts: 1, RetryDelay: 20 * time.Second}, store, logger)
+	manager.running = true
+
+	now := time.Now()
+	for _, item := range []*storage.QueueItem{
+		{ID: "a", MediaID: "a", Priority: 1, Status: "downloading", CreatedAt: now},
+		{ID: "b", MediaID: "b", Priority: 3, Status: "queued", CreatedAt: now},
+	} {
+		require.NoError(t, store.AddQueueItem(item))
+	}
+
+	fail := func(retries int) {
+		manager.handleResult(&DownloadResult{
+			Job:   &DownloadJob{ID: "a", MediaID: "a", Priority: 1, CreatedAt: now, RetryCount: retries},
+			Error: errors.New("connection reset by peer"),
+		})
+	}
+
+	fail(0)
+	item, err := store.FindActiveQueueItem("a")
+	require.NoError(t, err)
+	assert.Equal(t, "queued", item.Status)
+	assert.Equal(t, 1, item.RetryCount)
+	assert.True(t, item.NextAttemptAt.After(now.Add(14*time.Second)), "next attempt at %v", item.NextAttemptAt)
+
+	// The backing off head does not hold back the rest of the queue
+	manager.loadJobsFromQueue()
+	job, ok := manager.jobs.pop(context.Background())
+	require.True(t, ok)
+	assert.Equal(t, "b", job.ID)
+	manager.loadJobsFromQueue()
+	assert.Zero(t, manager.jobs.len(), "the retry waits for its backoff")
+
+	item.NextAttemptAt = time.Now().Add(-time.Second)
+	require.NoError(t, store.UpdateQueueItem(item))
+	manager.loadJobsFromQueue()
+	job, ok = manager.jobs.pop(context.Background())
+	require.True(t, ok)
+	assert.Equal(t, "a", job.ID)
+	assert.Equal(t, 1, job.RetryCount, "the attempt count carries over to the job")
+
+	// Out of retries
+	fail(job.RetryCount)
+	item, err = manager.findQueueItem("a")
+	require.NoError(t, err)
+	assert.Equal(t, "dead_letter", item.Status)
+	require.Len(t, item.ErrorHistory, 2, "every attempt's error is kept")
+	assert.Equal(t, "connection reset by peer", item.ErrorHistory[1].Message)
+	manager.loadJobsFromQueue()
+	assert.Zero(t, manager.jobs.len(), "dead letters are not retried")
+
+	require.NoError(t, manager.ResumeJob("a"))
+	item, err = manager.findQueueItem("a")
+	require.NoError(t, err)
+	assert.Equal(t, "queued", item.Status)
+	assert.Zero(t, item.RetryCount, "a resumed dead letter gets its retries back")
+}
+
+func TestRetryKeepsQueueItemUsers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	manager := New(&config.DownloadConfig{Workers: 1, RetryAttempts: 1, RetryDelay: time.Second}, store, logger)
+	manager.running = true
+
+	now := time.Now()
+	require.NoError(t, store.AddQueueItem(&storage.QueueItem{
+		ID: "a", MediaID: "a", Priority: 3, Size: 1 << 20, Status: "downloading", CreatedAt: now,
+		Source: SourcePrediction, Users: []string{"alice", "bob"},
+	}))
+
+	fail := func(retries int) *storage.QueueItem {
+		manager.handleResult(&DownloadResult{
+			Job:   &DownloadJob{ID: "a", MediaID: "a", Priority: 3, CreatedAt: now, RetryCount: retries, Source: SourcePrediction},
+			Error: errors.New("connection reset by peer"),
+		})
+		items, err := store.GetQueueItems("")
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		return items[0]
+	}
+
+	item := fail(0)
+	assert.Equal(t, "queued", item.Status)
+	assert.Equal(t, []string{"alice", "bob"}, item.Users)
+	assert.Equal(t, int64(1<<20), item.Size)
+
+	item = fail(1)
+	assert.Equal(t, "dead_letter", item.Status)
+	assert.Equal(t, []string{"alice", "bob"}, item.Users)
+	assert.Len(t, item.ErrorHistory, 2)
+}
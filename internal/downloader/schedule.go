@@ -0,0 +1,104 @@
+package downloader
+
+import (
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// ScheduleStatus reports the download schedule and, for every priority,
+// whether its downloads may start right now.
+type ScheduleStatus struct {
+	PeakHours        string `json:"peak_hours"`
+	PeakLimitPercent int    `json:"peak_limit_percent"`
+	// Peak is set while the peak hour bandwidth limit applies, which
+	// follows learned viewing windows under watch-time scheduling
+	Peak       bool               `json:"peak"`
+	Windows    []ScheduleWindow   `json:"windows"`
+	Priorities []PrioritySchedule `json:"priorities"`
+}
+
+// ScheduleWindow is a configured download window.
+type ScheduleWindow struct {
+	Priorities []int  `json:"priorities"`
+	Hours      string `json:"hours"`
+}
+
+// PrioritySchedule reports whether downloads of one priority may start.
+type PrioritySchedule struct {
+	Priority int    `json:"priority"`
+	Allowed  bool   `json:"allowed"`
+	Reason   string `json:"reason,omitempty"` // Why they may not start
+	// Windowed is set when download windows restrict the priority
+	Windowed bool `json:"windowed"`
+	// NextStart is when the next window for the priority opens, while it
+	// is outside all of them
+	NextStart *time.Time `json:"next_start,omitempty"`
+}
+
+// outsideWindow reports whether downloads of priority have to wait for
+// one of their download windows to open.
+func (m *Manager) outsideWindow(priority int) bool {
+	return !m.config.RateLimitSchedule.AllowsStart(priority, time.Now())
+}
+
+// nextStartable returns the most urgent queued item whose priority is in a
+// download window and that is not waiting to retry, or nil if there is
+// none.
+func (m *Manager) nextStartable(now time.Time) *storage.QueueItem {
+	items, err := m.storage.GetQueueItems("queued")
+	if err != nil {
+		return nil
+	}
+	for _, item := range items {
+		if !m.outsideWindow(item.Priority) && !retryPending(item, now) {
+			return item
+		}
+	}
+	return nil
+}
+
+// Schedule returns the download schedule and which priorities may start
+// downloads now.
+func (m *Manager) Schedule() ScheduleStatus {
+	schedule := &m.config.RateLimitSchedule
+	now := time.Now()
+
+	status := ScheduleStatus{
+		PeakHours:        schedule.PeakHours,
+		PeakLimitPercent: schedule.PeakLimitPercent,
+		Peak:             m.isCurrentlyPeakHours(),
+		Windows:          make([]ScheduleWindow, 0, len(schedule.Windows)),
+		Priorities:       make([]PrioritySchedule, 0, 5),
+	}
+	for _, window := range schedule.Windows {
+		status.Windows = append(status.Windows, ScheduleWindow{
+			Priorities: window.Priorities,
+			Hours:      window.Hours,
+		})
+	}
+
+	for priority := 0; priority <= 4; priority++ {
+		entry := PrioritySchedule{
+			Priority: priority,
+			Allowed:  true,
+			Windowed: schedule.Restricted(priority),
+		}
+		switch {
+		case !schedule.AllowsStart(priority, now):
+			entry.Allowed = false
+			entry.Reason = "Outside download window"
+			if next := schedule.NextStart(priority, now); !next.IsZero() {
+				entry.NextStart = &next
+			}
+		case priority > maxCriticalPriority && m.dailyCapReached():
+			entry.Allowed = false
+			entry.Reason = "Daily download cap reached"
+		case priority >= 3 && m.speculativePaused():
+			entry.Allowed = false
+			entry.Reason = "Cache disk unhealthy"
+		}
+		status.Priorities = append(status.Priorities, entry)
+	}
+	return status
+}
@@ -0,0 +1,49 @@
+package downloader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestDownloadWindowsHoldBackPriorities(t *testing.T) {
+	manager, store := newLimitedManager(t)
+
+	// A window for Priority 3 that opens in two hours
+	now := time.Now()
+	hours := now.Add(2*time.Hour).Format("15:04") + "-" + now.Add(3*time.Hour).Format("15:04")
+	manager.config.RateLimitSchedule.Windows = []config.DownloadWindowConfig{
+		{Priorities: []int{3}, Hours: hours},
+	}
+
+	require.NoError(t, manager.AddJob(&DownloadJob{ID: "night", MediaID: "night", Priority: 3, CreatedAt: now}))
+	assert.Equal(t, 0, manager.jobs.len(), "jobs outside their window wait in storage")
+
+	// The held back head does not block a less urgent priority
+	require.NoError(t, store.AddQueueItem(&storage.QueueItem{
+		ID: "any-time", MediaID: "any-time", Priority: 4, Status: "queued", CreatedAt: now,
+	}))
+	manager.loadJobsFromQueue()
+	require.Equal(t, 1, manager.jobs.len())
+	job, ok := manager.jobs.pop(context.Background())
+	require.True(t, ok)
+	assert.Equal(t, "any-time", job.ID)
+
+	status := manager.Schedule()
+	require.Len(t, status.Priorities, 5)
+	assert.True(t, status.Priorities[2].Allowed)
+	assert.False(t, status.Priorities[2].Windowed)
+
+	night := status.Priorities[3]
+	assert.False(t, night.Allowed)
+	assert.True(t, night.Windowed)
+	assert.Equal(t, "Outside download window", night.Reason)
+	require.NotNil(t, night.NextStart)
+	assert.WithinDuration(t, now.Add(2*time.Hour), *night.NextStart, time.Minute)
+}
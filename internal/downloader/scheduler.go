@@ -0,0 +1,292 @@
+package downloader
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+)
+
+// errPreempted aborts a download to free its worker for a more urgent one.
+// The download is requeued and later resumes from its partial file.
+var errPreempted = errors.New("download preempted by a more urgent one")
+
+// jobQueue hands jobs to workers most urgent first (lowest priority
+// number), first come first served within a priority. It holds at most
+// capacity jobs; storage remains the durable queue, so a job that does not
+// fit is simply loaded again later.
+type jobQueue struct {
+	mu       sync.Mutex
+	items    jobHeap
+	pending  map[string]*queuedJob // by job ID
+	capacity int
+	seq      uint64
+	closed   bool
+
+	// ready is signalled when a job is pushed, waking one idle worker
+	ready chan struct{}
+}
+
+type queuedJob struct {
+	job   *DownloadJob
+	seq   uint64
+	index int
+}
+
+func newJobQueue(capacity int) *jobQueue {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &jobQueue{
+		pending:  make(map[string]*queuedJob),
+		capacity: capacity,
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+// push adds job. It returns false when the job is already pending, the
+// queue is closed, or the queue is full of jobs at least as urgent. When a
+// full queue makes room by dropping its least urgent job, that job is
+// returned as evicted.
+func (q *jobQueue) push(job *DownloadJob) (ok bool, evicted *DownloadJob) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed || q.pending[job.ID] != nil {
+		return false, nil
+	}
+
+	if len(q.items) >= q.capacity {
+		last := q.leastUrgent()
+		if last.job.Priority <= job.Priority {
+			return false, nil
+		}
+		heap.Remove(&q.items, last.index)
+		delete(q.pending, last.job.ID)
+		evicted = last.job
+	}
+
+	q.seq++
+	entry := &queuedJob{job: job, seq: q.seq}
+	heap.Push(&q.items, entry)
+	q.pending[job.ID] = entry
+	q.signal()
+	return true, evicted
+}
+
+// pop blocks until a job is available and returns the most urgent one. It
+// returns false once ctx is done or the queue is closed and empty.
+func (q *jobQueue) pop(ctx context.Context) (*DownloadJob, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.items) > 0 {
+			entry := heap.Pop(&q.items).(*queuedJob)
+			delete(q.pending, entry.job.ID)
+			if len(q.items) > 0 {
+				q.signal() // Let another idle worker take the next one
+			}
+			q.mu.Unlock()
+			return entry.job, true
+		}
+		closed := q.closed
+		q.mu.Unlock()
+
+		if closed {
+			return nil, false
+		}
+
+		select {
+		case <-q.ready:
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+// setPriority moves a pending job to a new priority. It reports whether
+// the job was pending.
+func (q *jobQueue) setPriority(jobID string, priority int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entry := q.pending[jobID]
+	if entry == nil {
+		return false
+	}
+	entry.job.Priority = priority
+	heap.Fix(&q.items, entry.index)
+	return true
+}
+
+// remove drops a pending job. It reports whether the job was pending.
+func (q *jobQueue) remove(jobID string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entry := q.pending[jobID]
+	if entry == nil {
+		return false
+	}
+	heap.Remove(&q.items, entry.index)
+	delete(q.pending, jobID)
+	return true
+}
+
+// len returns the number of pending jobs.
+func (q *jobQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// close stops the queue accepting jobs and releases idle workers once it
+// is empty.
+func (q *jobQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	close(q.ready)
+}
+
+// signal wakes an idle worker without blocking. Callers must hold q.mu.
+func (q *jobQueue) signal() {
+	if q.closed {
+		return
+	}
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// leastUrgent returns the pending job a full queue gives up first: the
+// lowest priority, most recently pushed. Callers must hold q.mu.
+func (q *jobQueue) leastUrgent() *queuedJob {
+	last := q.items[0]
+	for _, entry := range q.items[1:] {
+		if entry.job.Priority > last.job.Priority ||
+			(entry.job.Priority == last.job.Priority && entry.seq > last.seq) {
+			last = entry
+		}
+	}
+	return last
+}
+
+// jobHeap implements heap.Interface ordered by priority, then arrival.
+type jobHeap []*queuedJob
+
+func (h jobHeap) Len() int { return len(h) }
+
+func (h jobHeap) Less(i, j int) bool {
+	if h[i].job.Priority != h[j].job.Priority {
+		return h[i].job.Priority < h[j].job.Priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h jobHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *jobHeap) Push(x any) {
+	entry := x.(*queuedJob)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *jobHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return entry
+}
+
+// enqueue hands job to the workers if the in-memory queue has room for it.
+// A job it displaces is marked queued again in storage so it is loaded
+// later. A playback job (Priority 0) arriving while every worker is busy
+// preempts the least urgent transfer.
+func (m *Manager) enqueue(job *DownloadJob) bool {
+	ok, evicted := m.jobs.push(job)
+	if evicted != nil {
+		if item, err := m.storage.FindActiveQueueItem(evicted.MediaID); err == nil && item != nil && item.Status != "queued" {
+			item.Status = "queued"
+			if err := m.storage.UpdateQueueItem(item); err != nil {
+				m.logger.Warn("Failed to return displaced job to queue",
+					"job_id", evicted.ID, "error", err)
+			}
+		}
+		m.logger.Debug("Displaced less urgent job for a more urgent one",
+			"job_id", evicted.ID, "priority", evicted.Priority, "by", job.ID)
+	}
+	if ok && job.Priority == 0 {
+		m.preemptFor(job.ID)
+	}
+	return ok
+}
+
+// preemptFor aborts the least urgent in-flight download when every worker
+// is busy and none of them is doing Priority 0 work, so the pending job
+// jobID can start right away. The aborted download keeps its partial file
+// and is requeued.
+func (m *Manager) preemptFor(jobID string) {
+	m.activeMu.Lock()
+	defer m.activeMu.Unlock()
+
+	// A worker is free, or one already preempted is about to be
+	busy := 0
+	for _, tracker := range m.active {
+		if !tracker.preempted {
+			busy++
+		}
+	}
+	if busy < m.workers {
+		return
+	}
+
+	var victim *downloadTracker
+	for id, tracker := range m.active {
+		if m.cancels[id] == nil || tracker.preempted {
+			continue
+		}
+		if tracker.info.Priority == 0 {
+			return // Playback already has a worker; wait for it
+		}
+		if victim == nil || tracker.info.Priority > victim.info.Priority ||
+			(tracker.info.Priority == victim.info.Priority && tracker.info.StartedAt.After(victim.info.StartedAt)) {
+			victim = tracker
+		}
+	}
+	if victim == nil {
+		return
+	}
+
+	victim.preempted = true
+	m.cancels[victim.info.JobID](errPreempted)
+	m.logger.Info("Pausing download for playback",
+		"job_id", victim.info.JobID,
+		"priority", victim.info.Priority,
+		"for_job", jobID)
+}
+
+// requeuePreempted returns a preempted download to the queue at its
+// priority. It resumes from its partial file.
+func (m *Manager) requeuePreempted(job *DownloadJob, downloaded int64) {
+	item, err := m.storage.FindActiveQueueItem(job.MediaID)
+	if err != nil || item == nil {
+		m.logger.Warn("Preempted download's queue item is gone",
+			"job_id", job.ID, "media_id", job.MediaID, "error", err)
+		return
+	}
+
+	item.Status = "queued"
+	item.BytesDownloaded = downloaded
+	if err := m.storage.UpdateQueueItem(item); err != nil {
+		m.logger.Error("Failed to requeue preempted download",
+			"job_id", job.ID, "error", err)
+		return
+	}
+	m.reportProgress(job.MediaID, 0, "queued", "Paused for a more urgent download")
+}
@@ -0,0 +1,155 @@
+package downloader
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func popIDs(t *testing.T, q *jobQueue) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var ids []string
+	for q.len() > 0 {
+		job, ok := q.pop(ctx)
+		require.True(t, ok)
+		ids = append(ids, job.ID)
+	}
+	return ids
+}
+
+func TestJobQueueOrder(t *testing.T) {
+	q := newJobQueue(10)
+	for _, job := range []*DownloadJob{
+		{ID: "background", Priority: 4},
+		{ID: "next-a", Priority: 1},
+		{ID: "playing", Priority: 0},
+		{ID: "next-b", Priority: 1},
+	} {
+		ok, _ := q.push(job)
+		require.True(t, ok)
+	}
+
+	ok, _ := q.push(&DownloadJob{ID: "next-a", Priority: 1})
+	assert.False(t, ok, "a pending job is not queued twice")
+
+	assert.Equal(t, []string{"playing", "next-a", "next-b", "background"}, popIDs(t, q))
+}
+
+func TestJobQueueEvictsLeastUrgent(t *testing.T) {
+	q := newJobQueue(2)
+	q.push(&DownloadJob{ID: "a", Priority: 3})
+	q.push(&DownloadJob{ID: "b", Priority: 4})
+
+	ok, evicted := q.push(&DownloadJob{ID: "c", Priority: 4})
+	assert.False(t, ok, "a full queue keeps jobs at least as urgent")
+	assert.Nil(t, evicted)
+
+	ok, evicted = q.push(&DownloadJob{ID: "d", Priority: 0})
+	require.True(t, ok)
+	require.NotNil(t, evicted)
+	assert.Equal(t, "b", evicted.ID)
+
+	assert.Equal(t, []string{"d", "a"}, popIDs(t, q))
+}
+
+func TestJobQueueSetPriorityAndRemove(t *testing.T) {
+	q := newJobQueue(10)
+	q.push(&DownloadJob{ID: "a", Priority: 2})
+	q.push(&DownloadJob{ID: "b", Priority: 3})
+	q.push(&DownloadJob{ID: "c", Priority: 4})
+
+	assert.True(t, q.setPriority("c", 0))
+	assert.True(t, q.remove("a"))
+	assert.False(t, q.setPriority("missing", 0))
+	assert.False(t, q.remove("missing"))
+
+	assert.Equal(t, []string{"c", "b"}, popIDs(t, q))
+}
+
+func TestJobQueuePopUnblocks(t *testing.T) {
+	q := newJobQueue(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	done := make(chan string)
+	go func() {
+		job, ok := q.pop(ctx)
+		if ok {
+			done <- job.ID
+		}
+		close(done)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	q.push(&DownloadJob{ID: "late", Priority: 2})
+	assert.Equal(t, "late", <-done)
+
+	q.close()
+	_, ok := q.pop(ctx)
+	assert.False(t, ok, "a closed, empty queue releases workers")
+}
+
+func TestPreemptForPlayback(t *testing.T) {
+	manager, _ := newLimitedManager(t)
+	manager.workers = 2
+
+	aborted := map[string]error{}
+	for _, job := range []*DownloadJob{
+		{ID: "next", MediaID: "m1", Priority: 1},
+		{ID: "background", MediaID: "m2", Priority: 4},
+	} {
+		manager.trackDownload(job, 0, 0)
+		id := job.ID
+		manager.attachCancel(id, func(cause error) { aborted[id] = cause })
+	}
+
+	manager.enqueue(&DownloadJob{ID: "playing", MediaID: "m3", Priority: 0})
+	assert.Equal(t, map[string]error{"background": errPreempted}, aborted)
+
+	// The aborted job is already on its way out; nothing else is paused
+	manager.enqueue(&DownloadJob{ID: "playing-2", MediaID: "m4", Priority: 0})
+	assert.Len(t, aborted, 1)
+}
+
+func TestPreemptedDownloadIsRequeued(t *testing.T) {
+	manager, store, job := newResumeTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "5000")
+		w.Write(make([]byte, 1000))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	job.Priority = 4
+
+	results := make(chan *DownloadResult, 1)
+	go func() { results <- manager.processJob(job) }()
+
+	require.Eventually(t, func() bool {
+		active := manager.ActiveDownloads()
+		return len(active) == 1 && active[0].Downloaded == 1000
+	}, 2*time.Second, 10*time.Millisecond)
+
+	manager.enqueue(&DownloadJob{ID: "playing", MediaID: "m2", Priority: 0})
+
+	var result *DownloadResult
+	select {
+	case result = <-results:
+	case <-time.After(2 * time.Second):
+		t.Fatal("download was not preempted")
+	}
+	assert.ErrorIs(t, result.Stopped, errPreempted)
+	assert.NoError(t, result.Error, "preemption is not a failure")
+	assert.Equal(t, int64(1000), result.BytesDownloaded)
+
+	manager.handleResult(result)
+	item, err := store.FindActiveQueueItem("m1")
+	require.NoError(t, err)
+	assert.Equal(t, "queued", item.Status)
+	assert.Equal(t, int64(1000), item.BytesDownloaded, "the download resumes where it was paused")
+	assert.Zero(t, item.RetryCount)
+}
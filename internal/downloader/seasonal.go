@@ -0,0 +1,61 @@
+package downloader
+
+import (
+	"strings"
+	"time"
+)
+
+// applySeasonalBoosts adjusts the confidence of Priority 4 trending
+// predictions whose genres match a seasonal rule active at the given time.
+// Multiple matching rules stack; the result is clamped to 0.0-1.0.
+func (p *Predictor) applySeasonalBoosts(predictions []PredictionResult, now time.Time) []PredictionResult {
+	if len(p.config.SeasonalRules) == 0 {
+		return predictions
+	}
+
+	for i := range predictions {
+		if predictions[i].Priority != 4 {
+			continue
+		}
+
+		metadata, err := p.storage.GetMediaMetadata(predictions[i].MediaID)
+		if err != nil || len(metadata.Genres) == 0 {
+			continue
+		}
+
+		for _, rule := range p.config.SeasonalRules {
+			if !rule.IsActive(now) || !genresOverlap(metadata.Genres, rule.Genres) {
+				continue
+			}
+
+			predictions[i].Confidence += rule.Boost
+			if predictions[i].Confidence > 1.0 {
+				predictions[i].Confidence = 1.0
+			}
+			if predictions[i].Confidence < 0 {
+				predictions[i].Confidence = 0
+			}
+
+			p.logger.Debug("Applied seasonal boost",
+				"media_id", predictions[i].MediaID,
+				"rule", rule.Name,
+				"boost", rule.Boost,
+				"confidence", predictions[i].Confidence)
+		}
+	}
+
+	return predictions
+}
+
+// genresOverlap reports whether any genre appears in both lists,
+// compared case-insensitively.
+func genresOverlap(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if strings.EqualFold(x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package downloader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestApplySeasonalBoosts(t *testing.T) {
+	predictor, sm, _ := newReconcileTestPredictor(t)
+	predictor.config.SeasonalRules = []config.SeasonalRule{
+		{Name: "holidays", Start: "12-01", End: "12-31", Genres: []string{"Holiday"}, Boost: 0.3},
+		{Name: "halloween", Start: "10-20", End: "10-31", Genres: []string{"horror"}, Boost: 0.4},
+	}
+
+	require.NoError(t, sm.AddMediaMetadata(&storage.MediaMetadata{ID: "xmas", JellyfinID: "xmas", Genres: []string{"Holiday", "Comedy"}}))
+	require.NoError(t, sm.AddMediaMetadata(&storage.MediaMetadata{ID: "scary", JellyfinID: "scary", Genres: []string{"Horror"}}))
+
+	december := time.Date(2026, time.December, 15, 20, 0, 0, 0, time.UTC)
+	result := predictor.applySeasonalBoosts([]PredictionResult{
+		{MediaID: "xmas", Priority: 4, Confidence: 0.5},
+		{MediaID: "scary", Priority: 4, Confidence: 0.5},
+		{MediaID: "xmas", Priority: 3, Confidence: 0.5}, // only trending predictions are boosted
+	}, december)
+
+	assert.InDelta(t, 0.8, result[0].Confidence, 0.001)
+	assert.InDelta(t, 0.5, result[1].Confidence, 0.001)
+	assert.InDelta(t, 0.5, result[2].Confidence, 0.001)
+
+	october := time.Date(2026, time.October, 28, 20, 0, 0, 0, time.UTC)
+	result = predictor.applySeasonalBoosts([]PredictionResult{
+		{MediaID: "scary", Priority: 4, Confidence: 0.8},
+	}, october)
+	assert.InDelta(t, 1.0, result[0].Confidence, 0.001, "confidence is clamped")
+}
@@ -0,0 +1,240 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// What seeded items were picked for, in the order the budget is spent.
+const (
+	SeedContinueWatching = "continue_watching" // Partly watched or listened to
+	SeedNextUp           = "next_up"           // Next episode of a series in progress
+	SeedTopPick          = "top_pick"          // Best rated unwatched movie
+)
+
+// Priorities seeded items are queued at: what is in progress matches the
+// warmers' Next Up class, top picks are speculative.
+const (
+	seedInProgressPriority = 2
+	seedTopPickPriority    = 3
+)
+
+// seedListLimit is how many items each list is fetched with.
+const seedListLimit = 50
+
+// SeedSource lists what seeding picks from and fills in the sizes the
+// lists leave out (implemented by jellyfin.Client).
+type SeedSource interface {
+	GetResumeItems(ctx context.Context, userID string, limit int) ([]jellyfin.MediaItem, error)
+	GetNextUp(ctx context.Context, limit int) ([]jellyfin.MediaItem, error)
+	GetPopular(ctx context.Context, limit int) ([]jellyfin.MediaItem, error)
+	GetItemsByID(ctx context.Context, ids []string) ([]jellyfin.MediaItem, error)
+}
+
+// SeedOptions controls a seeding run.
+type SeedOptions struct {
+	BudgetBytes int64 // Total size of what is queued
+	DryRun      bool  // Pick and report without queueing anything
+}
+
+// SeedReport describes what a seeding run queued, or would queue on a dry
+// run.
+type SeedReport struct {
+	DryRun      bool         `json:"dry_run"`
+	BudgetBytes int64        `json:"budget_bytes"`
+	TotalBytes  int64        `json:"total_bytes"`
+	Queued      []SeededItem `json:"queued"`
+	Skipped     []SeededItem `json:"skipped"`
+}
+
+// SeededItem is an item seeding picked. Reason says why a skipped item was
+// left out.
+type SeededItem struct {
+	MediaID  string `json:"media_id"`
+	Name     string `json:"name"`
+	Category string `json:"category"` // One of the Seed constants
+	Priority int    `json:"priority"`
+	Size     int64  `json:"size"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Seeder fills the cache in one go before a trip: what the user is in the
+// middle of, the next episodes of their series and the best rated movies
+// they have not watched, in that order, until a size budget is spent.
+type Seeder struct {
+	source    SeedSource
+	storage   storage.Store
+	queuer    DownloadQueuer
+	libraries *config.LibraryFilterConfig
+	userID    string
+	logger    *slog.Logger
+
+	// mu allows one seeding run at a time, so two cannot both spend the
+	// budget
+	mu sync.Mutex
+}
+
+// NewSeeder creates a seeder that picks for userID and queues through
+// queuer.
+func NewSeeder(source SeedSource, storage storage.Store, queuer DownloadQueuer, userID string, logger *slog.Logger) *Seeder {
+	return &Seeder{
+		source:  source,
+		storage: storage,
+		queuer:  queuer,
+		userID:  userID,
+		logger:  logger,
+	}
+}
+
+// SetLibraryFilter leaves items from libraries the filter excludes out of
+// seeding.
+func (s *Seeder) SetLibraryFilter(filter *config.LibraryFilterConfig) {
+	s.libraries = filter
+}
+
+// Seed picks items until opts.BudgetBytes is spent and, unless it is a dry
+// run, queues them. Items already cached or queued are not picked again,
+// and neither are items whose size is unknown, since they could overrun
+// the budget. An item too large for what is left of the budget is skipped
+// in favor of smaller ones after it.
+func (s *Seeder) Seed(ctx context.Context, opts SeedOptions) (*SeedReport, error) {
+	if opts.BudgetBytes <= 0 {
+		return nil, fmt.Errorf("seed budget must be positive")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	candidates, err := s.candidates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SeedReport{
+		DryRun:      opts.DryRun,
+		BudgetBytes: opts.BudgetBytes,
+		Queued:      []SeededItem{},
+		Skipped:     []SeededItem{},
+	}
+	for _, item := range candidates {
+		switch {
+		case item.Size <= 0:
+			item.Reason = "size unknown"
+		case report.TotalBytes+item.Size > opts.BudgetBytes:
+			item.Reason = "over budget"
+		}
+		if item.Reason == "" && !opts.DryRun {
+			if _, err := s.queuer.QueueDownloadWithSource(ctx, item.MediaID, item.Priority, SourceSeed); err != nil {
+				item.Reason = err.Error()
+			}
+		}
+
+		if item.Reason != "" {
+			report.Skipped = append(report.Skipped, item)
+			continue
+		}
+		report.Queued = append(report.Queued, item)
+		report.TotalBytes += item.Size
+	}
+
+	s.logger.Info("Cache seeding complete",
+		"dry_run", opts.DryRun,
+		"queued", len(report.Queued),
+		"skipped", len(report.Skipped),
+		"total_bytes", report.TotalBytes,
+		"budget_bytes", opts.BudgetBytes)
+
+	return report, nil
+}
+
+// candidates returns the items seeding may pick, in the order the budget
+// is spent on them, with their sizes filled in where they can be.
+func (s *Seeder) candidates(ctx context.Context) ([]SeededItem, error) {
+	resume, err := s.source.GetResumeItems(ctx, s.userID, seedListLimit)
+	if err != nil {
+		return nil, err
+	}
+	nextUp, err := s.source.GetNextUp(ctx, seedListLimit)
+	if err != nil {
+		return nil, err
+	}
+	popular, err := s.source.GetPopular(ctx, seedListLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []SeededItem
+	seen := make(map[string]bool)
+	add := func(items []jellyfin.MediaItem, category string, priority int) {
+		for _, item := range items {
+			if seen[item.ID] || jellyfin.CacheMediaType(item.Type) == "" {
+				continue
+			}
+			seen[item.ID] = true
+
+			if cached, err := s.storage.IsMediaCached(item.ID); err == nil && cached {
+				continue
+			}
+			if queued, err := s.storage.FindActiveQueueItem(item.ID); err == nil && queued != nil {
+				continue
+			}
+			if checkLibrary(s.storage, s.libraries, item.ID) != nil {
+				continue
+			}
+
+			candidates = append(candidates, SeededItem{
+				MediaID:  item.ID,
+				Name:     item.Name,
+				Category: category,
+				Priority: priority,
+				Size:     item.Size,
+			})
+		}
+	}
+	add(resume, SeedContinueWatching, seedInProgressPriority)
+	add(nextUp, SeedNextUp, seedInProgressPriority)
+	add(popular, SeedTopPick, seedTopPickPriority)
+
+	s.fillSizes(ctx, candidates)
+	return candidates, nil
+}
+
+// fillSizes looks up the sizes the lists left out, from the metadata
+// library sync stored and otherwise from the server.
+func (s *Seeder) fillSizes(ctx context.Context, candidates []SeededItem) {
+	var missing []string
+	for i := range candidates {
+		if candidates[i].Size > 0 {
+			continue
+		}
+		if metadata, err := s.storage.GetMediaMetadata(candidates[i].MediaID); err == nil && metadata.Size > 0 {
+			candidates[i].Size = metadata.Size
+			continue
+		}
+		missing = append(missing, candidates[i].MediaID)
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	items, err := s.source.GetItemsByID(ctx, missing)
+	if err != nil {
+		s.logger.Warn("Failed to look up sizes of seeded items", "error", err)
+		return
+	}
+	sizes := make(map[string]int64, len(items))
+	for _, item := range items {
+		sizes[item.ID] = item.Size
+	}
+	for i := range candidates {
+		if candidates[i].Size <= 0 {
+			candidates[i].Size = sizes[candidates[i].MediaID]
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+var _ SeedSource = (*jellyfin.Client)(nil)
+var _ SeedSource = (*jellyfintest.Mock)(nil)
+
+func seededIDs(items []SeededItem) []string {
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.MediaID)
+	}
+	return ids
+}
+
+func TestSeeder(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	manager := New(&config.DownloadConfig{Workers: 1}, store, logger)
+	manager.running = true
+
+	const gb = 1024 * 1024 * 1024
+	source := jellyfintest.New("http://jellyfin.local")
+	source.Resume = map[string][]jellyfin.MediaItem{
+		"user": {{ID: "m1", Name: "Half Watched", Type: "Movie", Size: 4 * gb}},
+	}
+	source.NextUp = []jellyfin.MediaItem{
+		{ID: "e1", Name: "Next Episode", Type: "Episode"}, // Size looked up by ID
+		{ID: "m1", Type: "Movie", Size: 4 * gb},           // Already picked
+		{ID: "cached", Type: "Episode", Size: gb},
+	}
+	source.Popular = []jellyfin.MediaItem{
+		{ID: "big", Name: "Epic", Type: "Movie", Size: 10 * gb},
+		{ID: "small", Name: "Short", Type: "Movie", Size: gb},
+		{ID: "unknown", Name: "Mystery", Type: "Movie"},
+	}
+	source.Items = map[string]jellyfin.MediaItem{
+		"e1": {ID: "e1", Type: "Episode", Size: 2 * gb},
+	}
+	require.NoError(t, store.AddDownloadRecord(&storage.DownloadRecord{ID: "cached", JellyfinID: "cached", MediaType: "episode", Status: "completed"}))
+
+	seeder := NewSeeder(source, store, manager, "user", logger)
+
+	// A dry run reports without queueing
+	report, err := seeder.Seed(context.Background(), SeedOptions{BudgetBytes: 8 * gb, DryRun: true})
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, []string{"m1", "e1", "small"}, seededIDs(report.Queued))
+	assert.Equal(t, []string{"big", "unknown"}, seededIDs(report.Skipped))
+	assert.Equal(t, int64(7*gb), report.TotalBytes)
+	assert.Equal(t, SeedContinueWatching, report.Queued[0].Category)
+	assert.Equal(t, "over budget", report.Skipped[0].Reason)
+	assert.Empty(t, queuedPriorities(t, store))
+
+	report, err = seeder.Seed(context.Background(), SeedOptions{BudgetBytes: 8 * gb})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"m1": 2, "e1": 2, "small": 3}, queuedPriorities(t, store))
+	items, err := store.GetQueueItems("queued")
+	require.NoError(t, err)
+	for _, item := range items {
+		assert.Equal(t, SourceSeed, item.Source)
+	}
+
+	// Seeding again picks nothing already queued
+	report, err = seeder.Seed(context.Background(), SeedOptions{BudgetBytes: 8 * gb, DryRun: true})
+	require.NoError(t, err)
+	assert.Empty(t, report.Queued)
+
+	_, err = seeder.Seed(context.Background(), SeedOptions{})
+	assert.Error(t, err, "a budget is required")
+}
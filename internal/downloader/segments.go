@@ -0,0 +1,163 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"golang.org/x/time/rate"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// defaultSegmentMinMB applies when download.segment_min_mb is unset.
+const defaultSegmentMinMB = 1024
+
+// segmentCount returns how many byte ranges a download is fetched in, 1
+// meaning a single stream. Only fresh downloads of at least
+// download.segment_min_mb from servers that accept range requests are
+// split, and not while read-ahead wants their opening segment first.
+func (m *Manager) segmentCount(job *DownloadJob, resp *http.Response, offset, total int64) int {
+	segments := m.config.Segments
+	if segments <= 1 || offset > 0 || resp.StatusCode != http.StatusOK ||
+		resp.Header.Get("Accept-Ranges") != "bytes" {
+		return 1
+	}
+
+	minMB := m.config.SegmentMinMB
+	if minMB <= 0 {
+		minMB = defaultSegmentMinMB
+	}
+	if total < int64(minMB)*1024*1024 || total < int64(segments) {
+		return 1
+	}
+
+	if _, ok := m.headRequestFor(job.MediaID); ok {
+		return 1
+	}
+	return segments
+}
+
+// copySegments downloads total bytes as segments byte ranges at once. The
+// first range is read from body, the response already open, and the rest
+// are requested in parallel. Every range waits for its own connection slot
+// and has its own stall watchdog, but they share the job's bandwidth
+// share, throughput check and progress. The partial file only counts as
+// written once every range is in; if any fails the others are aborted and
+// the file is cut back to the first range, so the retry resumes without a
+// hole.
+func (m *Manager) copySegments(ctx context.Context, abort context.CancelCauseFunc, job *DownloadJob,
+	client *http.Client, partial *storage.PartialFile, body io.Reader, sink io.Writer,
+	limiter *rate.Limiter, watch *downloadWatch, total int64, segments int) error {
+	size := total / int64(segments)
+
+	m.logger.Debug("Downloading in segments",
+		"job_id", job.ID,
+		"segments", segments,
+		"segment_bytes", size)
+
+	var wg sync.WaitGroup
+	errs := make([]error, segments)
+	for i := 1; i < segments; i++ {
+		start, end := int64(i)*size, int64(i+1)*size
+		if i == segments-1 {
+			end = total
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = m.fetchSegment(ctx, abort, job, client, partial, sink, limiter, watch, start, end)
+			if errs[i] != nil {
+				abort(errs[i])
+			}
+		}(i)
+	}
+
+	n, err := io.Copy(partial, io.LimitReader(body, size))
+	if err == nil && n != size {
+		err = fmt.Errorf("segment ended at byte %d of %d", n, size)
+	}
+	if err != nil {
+		abort(err)
+	}
+	errs[0] = err
+	wg.Wait()
+
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if trimErr := partial.Trim(); trimErr != nil {
+			m.logger.Warn("Failed to trim segmented download",
+				"job_id", job.ID, "error", trimErr)
+		}
+		// Report what stopped the download rather than the cancellation it
+		// caused in the other segments
+		if cause := context.Cause(ctx); cause != nil {
+			return cause
+		}
+		return err
+	}
+
+	partial.Filled(total)
+	return nil
+}
+
+// fetchSegment downloads bytes [start, end) of job into partial.
+func (m *Manager) fetchSegment(ctx context.Context, abort context.CancelCauseFunc, job *DownloadJob,
+	client *http.Client, partial *storage.PartialFile, sink io.Writer,
+	limiter *rate.Limiter, parent *downloadWatch, start, end int64) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", job.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end-1))
+
+	release, err := m.acquireHost(ctx, req.URL.Host, job.Priority)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Slow segments are left to the job's throughput check, which sees
+	// the bytes of all of them
+	watch := &downloadWatch{parent: parent}
+	watchdog := m.stallWatchdog()
+	watchdog.minRate = 0
+	watch.waiting()
+	go watchdog.run(ctx, watch, abort)
+
+	resp, err := client.Do(req)
+	watch.idle()
+	if err != nil {
+		return fmt.Errorf("segment at byte %d: %w", start, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("segment at byte %d: unexpected status code: %d", start, resp.StatusCode)
+	}
+	if rangeStart, _, err := parseContentRange(resp.Header.Get("Content-Range")); err != nil || rangeStart != start {
+		return fmt.Errorf("segment at byte %d: server sent another range", start)
+	}
+
+	var r io.Reader = m.faults.Reader(watch.reader(resp.Body))
+	if limiter != nil {
+		r = m.createRateLimitedReader(r, limiter)
+	}
+
+	n, err := io.Copy(io.NewOffsetWriter(partial, start), io.TeeReader(io.LimitReader(r, end-start), sink))
+	if err != nil {
+		return fmt.Errorf("segment at byte %d: %w", start, err)
+	}
+	if n != end-start {
+		return fmt.Errorf("segment at byte %d ended at byte %d of %d", start, start+n, end)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package downloader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+func TestSegmentedDownload(t *testing.T) {
+	content := bytes.Repeat([]byte("segmented "), 300000)
+	var mu sync.Mutex
+	var ranges []string
+	manager, _, job := newResumeTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		http.ServeContent(w, r, "movie.mkv", time.Time{}, bytes.NewReader(content))
+	})
+	manager.config.Segments = 3
+	manager.config.SegmentMinMB = 1
+
+	result := manager.processJob(job)
+	require.True(t, result.Success, "error: %v", result.Error)
+	assert.ElementsMatch(t, []string{"", "bytes=1000000-1999999", "bytes=2000000-2999999"}, ranges)
+
+	data, err := os.ReadFile(job.LocalPath)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(content, data), "segments are reassembled in order")
+
+	sum := sha256.Sum256(content)
+	assert.Equal(t, hex.EncodeToString(sum[:]), result.Checksum)
+	assert.Equal(t, int64(len(content)), result.Size)
+
+	// Items below segment_min_mb are fetched in one stream
+	ranges = nil
+	manager.config.SegmentMinMB = 4
+	job.LocalPath += ".small"
+	result = manager.processJob(job)
+	require.True(t, result.Success, "error: %v", result.Error)
+	assert.Equal(t, []string{""}, ranges)
+}
+
+func TestSegmentedDownloadFailureResumesWithoutHoles(t *testing.T) {
+	content := bytes.Repeat([]byte("segmented "), 300000)
+	var mu sync.Mutex
+	failed := false
+	manager, _, job := newResumeTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fail := !failed && strings.HasPrefix(r.Header.Get("Range"), "bytes=2000000-")
+		failed = failed || fail
+		mu.Unlock()
+		if fail {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeContent(w, r, "movie.mkv", time.Time{}, bytes.NewReader(content))
+	})
+	manager.config.Segments = 3
+	manager.config.SegmentMinMB = 1
+
+	result := manager.processJob(job)
+	require.Error(t, result.Error)
+
+	info, err := os.Stat(storage.PartialPath(job.LocalPath))
+	require.NoError(t, err)
+	assert.LessOrEqual(t, info.Size(), int64(1000000), "only the unbroken first segment is kept")
+	assert.Equal(t, info.Size(), result.BytesDownloaded)
+
+	result = manager.processJob(job)
+	require.True(t, result.Success, "error: %v", result.Error)
+	data, err := os.ReadFile(job.LocalPath)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(content, data))
+}
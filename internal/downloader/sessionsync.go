@@ -0,0 +1,354 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// resumeLimit caps how many resume points are read per user and sync.
+const resumeLimit = 50
+
+// resumeSlack is how close a resume point must be to the end of a recorded
+// viewing session to be taken for that session rather than a new one.
+const resumeSlack = 5 * time.Minute
+
+// SessionSource lists playback on the Jellyfin server (implemented by
+// jellyfin.Client).
+type SessionSource interface {
+	GetSessions(ctx context.Context) ([]jellyfin.Session, error)
+	GetResumeItems(ctx context.Context, userID string, limit int) ([]jellyfin.MediaItem, error)
+}
+
+// SessionSyncer records playback on the Jellyfin server as viewing
+// sessions, so the predictor learns from everything a user watches on any
+// client rather than only streams served from the cache. Each sync reads
+// the server's active sessions and every user's resume points, which catch
+// playback that happened between syncs or while go-jf-watch was down.
+type SessionSyncer struct {
+	source    SessionSource
+	storage   storage.Store
+	predictor *Predictor
+	users     map[string]bool
+	config    *config.PredictionConfig
+	logger    *slog.Logger
+
+	// connectivity queues syncs that fail while Jellyfin is unreachable
+	connectivity *jellyfin.Connectivity
+
+	// now is stubbed by tests
+	now func() time.Time
+
+	mu sync.Mutex
+	// active maps each playback in progress (user, session and item) to
+	// when it was first seen, which keys its viewing session
+	active map[string]time.Time
+	// pushed does the same for playback reported by webhook, which names
+	// no session, keyed by user and item
+	pushed map[string]time.Time
+}
+
+// NewSessionSyncer creates a syncer that records playback of users. Other
+// users' sessions are ignored.
+func NewSessionSyncer(source SessionSource, storage storage.Store, predictor *Predictor, users []string, cfg *config.PredictionConfig, logger *slog.Logger) *SessionSyncer {
+	tracked := make(map[string]bool, len(users))
+	for _, userID := range users {
+		if userID != "" {
+			tracked[userID] = true
+		}
+	}
+	return &SessionSyncer{
+		source:    source,
+		storage:   storage,
+		predictor: predictor,
+		users:     tracked,
+		config:    cfg,
+		logger:    logger,
+		now:       time.Now,
+		active:    make(map[string]time.Time),
+		pushed:    make(map[string]time.Time),
+	}
+}
+
+// SetConnectivity makes Run defer syncs that fail because Jellyfin is
+// unreachable until it returns, rather than waiting for the next
+// interval.
+func (s *SessionSyncer) SetConnectivity(connectivity *jellyfin.Connectivity) {
+	s.connectivity = connectivity
+}
+
+// Enabled reports whether session syncing is turned on.
+func (s *SessionSyncer) Enabled() bool {
+	return s.config.SessionSyncInterval > 0 && len(s.users) > 0
+}
+
+// Sync records current playback and new resume points of the tracked users
+// and returns how many viewing sessions it wrote. Users whose history
+// changed are reloaded by the predictor on its next run.
+func (s *SessionSyncer) Sync(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.source.GetSessions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := s.now()
+	changed := make(map[string]bool)
+	playing := make(map[string]bool) // user/item pairs in active sessions
+	active := make(map[string]time.Time)
+	recorded := 0
+
+	for _, session := range sessions {
+		item := session.NowPlaying
+		if item == nil || !s.users[session.UserID] || jellyfin.CacheMediaType(item.Type) == "" {
+			continue
+		}
+
+		device := ClassifyUserAgent(session.Client + " " + session.DeviceName)
+		key := session.UserID + "/" + session.ID + "/" + item.ID
+		started, seen := s.active[key]
+		if !seen {
+			started, seen = s.pushed[session.UserID+"/"+item.ID]
+		}
+		if !seen {
+			started = now
+			if device != "" {
+				if err := s.storage.RecordDevicePlayback(device, now); err != nil {
+					s.logger.Debug("Failed to record device playback", "device", device, "error", err)
+				}
+			}
+		}
+		active[key] = started
+		playing[session.UserID+"/"+item.ID] = true
+
+		viewing := sessionFromItem(*item, started, now, ticksToDuration(session.PositionTicks))
+		viewing.DeviceType = device
+		if err := s.storage.UpsertViewingSession(session.UserID, viewing); err != nil {
+			return recorded, fmt.Errorf("failed to record session for user %s: %w", session.UserID, err)
+		}
+		changed[session.UserID] = true
+		recorded++
+	}
+	s.active = active
+
+	// Playback whose stop webhook never arrived has ended by now
+	for key := range s.pushed {
+		if !playing[key] {
+			delete(s.pushed, key)
+		}
+	}
+
+	for userID := range s.users {
+		n, err := s.syncResumePoints(ctx, userID, playing)
+		if err != nil {
+			s.logger.Warn("Failed to sync resume points", "user_id", userID, "error", err)
+		}
+		if n > 0 {
+			changed[userID] = true
+			recorded += n
+		}
+	}
+
+	if s.predictor != nil {
+		for userID := range changed {
+			s.predictor.HistoryChanged(userID)
+		}
+	}
+
+	s.logger.Debug("Synced Jellyfin playback", "sessions", recorded, "users", len(changed))
+	return recorded, nil
+}
+
+// HandleWebhook records a playback event pushed by the Jellyfin webhook
+// plugin, so viewing history is current without waiting for the next
+// sync. A start also runs playback prediction for the item, and a stop
+// closes its viewing session, so the next start of the item begins a new
+// one. It reports whether the event was recorded; events of other users,
+// other notification types and items that are not cached are ignored.
+func (s *SessionSyncer) HandleWebhook(ctx context.Context, event jellyfin.WebhookEvent) (bool, error) {
+	switch event.NotificationType {
+	case jellyfin.WebhookPlaybackStart, jellyfin.WebhookPlaybackProgress, jellyfin.WebhookPlaybackStop:
+	default:
+		return false, nil
+	}
+	if !s.users[event.UserID] || event.Item.ID == "" || jellyfin.CacheMediaType(event.Item.Type) == "" {
+		return false, nil
+	}
+
+	if err := s.recordWebhook(event); err != nil {
+		return false, err
+	}
+
+	if s.predictor == nil {
+		return true, nil
+	}
+	s.predictor.HistoryChanged(event.UserID)
+	if event.NotificationType == jellyfin.WebhookPlaybackStart {
+		// The playback is recorded either way, e.g. for items not synced
+		// into the metadata store yet
+		if err := s.predictor.OnPlaybackStart(ctx, event.Item.ID); err != nil {
+			s.logger.Warn("Failed to trigger playback prediction",
+				"media_id", event.Item.ID, "error", err)
+		}
+	}
+	return true, nil
+}
+
+// recordWebhook writes the viewing session a webhook event reports.
+func (s *SessionSyncer) recordWebhook(event jellyfin.WebhookEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	key := event.UserID + "/" + event.Item.ID
+	device := ClassifyUserAgent(event.Client + " " + event.DeviceName)
+	started, seen := s.startedAt(event.UserID, event.Item.ID)
+	if !seen {
+		started = now
+		if device != "" {
+			if err := s.storage.RecordDevicePlayback(device, now); err != nil {
+				s.logger.Debug("Failed to record device playback", "device", device, "error", err)
+			}
+		}
+	}
+
+	if event.NotificationType == jellyfin.WebhookPlaybackStop {
+		delete(s.pushed, key)
+		for activeKey := range s.active {
+			if strings.HasPrefix(activeKey, event.UserID+"/") && strings.HasSuffix(activeKey, "/"+event.Item.ID) {
+				delete(s.active, activeKey)
+			}
+		}
+	} else {
+		s.pushed[key] = started
+	}
+
+	viewing := sessionFromItem(event.Item, started, now, ticksToDuration(event.PositionTicks))
+	viewing.DeviceType = device
+	viewing.Completed = viewing.Completed || event.PlayedToCompletion
+	if err := s.storage.UpsertViewingSession(event.UserID, viewing); err != nil {
+		return fmt.Errorf("failed to record session for user %s: %w", event.UserID, err)
+	}
+
+	s.logger.Debug("Recorded Jellyfin webhook",
+		"type", event.NotificationType,
+		"user_id", event.UserID,
+		"media_id", event.Item.ID)
+	return nil
+}
+
+// startedAt returns when the playback of itemID by userID in progress was
+// first seen, by webhook or by a sync. The caller holds s.mu.
+func (s *SessionSyncer) startedAt(userID, itemID string) (time.Time, bool) {
+	if started, ok := s.pushed[userID+"/"+itemID]; ok {
+		return started, true
+	}
+	for key, started := range s.active {
+		if strings.HasPrefix(key, userID+"/") && strings.HasSuffix(key, "/"+itemID) {
+			return started, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// syncResumePoints records the resume points of userID that no stored
+// viewing session accounts for.
+func (s *SessionSyncer) syncResumePoints(ctx context.Context, userID string, playing map[string]bool) (int, error) {
+	items, err := s.source.GetResumeItems(ctx, userID, resumeLimit)
+	if err != nil {
+		return 0, err
+	}
+
+	history, err := s.storage.GetViewingHistory(userID, s.config.HistoryDays)
+	if err != nil {
+		return 0, err
+	}
+	lastEnd := make(map[string]time.Time, len(history))
+	for _, session := range history {
+		if session.EndTime.After(lastEnd[session.MediaID]) {
+			lastEnd[session.MediaID] = session.EndTime
+		}
+	}
+
+	recorded := 0
+	for _, item := range items {
+		if item.UserData == nil || item.UserData.LastPlayedDate.IsZero() ||
+			playing[userID+"/"+item.ID] || jellyfin.CacheMediaType(item.Type) == "" {
+			continue
+		}
+		lastPlayed := item.UserData.LastPlayedDate
+		if !lastEnd[item.ID].Before(lastPlayed.Add(-resumeSlack)) {
+			continue // Already recorded, by a session sync or an earlier resume point
+		}
+
+		viewing := sessionFromItem(item, lastPlayed, lastPlayed, ticksToDuration(item.UserData.PlaybackPositionTicks))
+		if err := s.storage.UpsertViewingSession(userID, viewing); err != nil {
+			return recorded, err
+		}
+		recorded++
+	}
+	return recorded, nil
+}
+
+// Run syncs immediately and then every session sync interval until ctx is
+// cancelled. It is a no-op when session syncing is disabled.
+func (s *SessionSyncer) Run(ctx context.Context) {
+	if !s.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(s.config.SessionSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		_, err := s.Sync(ctx)
+		switch {
+		case errors.Is(err, jellyfin.ErrOffline) && s.connectivity != nil:
+			s.connectivity.Defer("sessions", func(ctx context.Context) error {
+				_, err := s.Sync(ctx)
+				return err
+			})
+		case err != nil && ctx.Err() == nil:
+			s.logger.Warn("Jellyfin session sync failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sessionFromItem converts playback of item up to position into a viewing
+// session.
+func sessionFromItem(item jellyfin.MediaItem, start, end time.Time, position time.Duration) storage.ViewingSession {
+	duration := ticksToDuration(item.RunTimeTicks)
+	return storage.ViewingSession{
+		MediaID:     item.ID,
+		MediaType:   jellyfin.CacheMediaType(item.Type),
+		SeriesID:    item.SeriesID,
+		Season:      item.SeasonNumber,
+		Episode:     item.EpisodeNumber,
+		StartTime:   start,
+		EndTime:     end,
+		Duration:    int64(duration.Seconds()),
+		WatchedTime: int64(position.Seconds()),
+		Completed:   duration > 0 && float64(position) >= completionThreshold*float64(duration),
+	}
+}
+
+// ticksToDuration converts Jellyfin's 100ns ticks.
+func ticksToDuration(ticks int64) time.Duration {
+	return time.Duration(ticks) * 100
+}
@@ -0,0 +1,185 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+var _ SessionSource = (*jellyfin.Client)(nil)
+var _ SessionSource = (*jellyfintest.Mock)(nil)
+
+const ticksPerMinute = int64(time.Minute / 100)
+
+func TestSessionSyncer(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	cfg := &config.PredictionConfig{HistoryDays: 30, SessionSyncInterval: time.Minute}
+	predictor := NewPredictor(store, cfg, logger)
+
+	episode := jellyfin.MediaItem{ID: "e1", Type: "Episode", SeriesID: "s1", SeasonNumber: 1, EpisodeNumber: 4, RunTimeTicks: 40 * ticksPerMinute}
+	lastPlayed := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+
+	source := jellyfintest.New("http://jellyfin.local")
+	source.Sessions = []jellyfin.Session{
+		{ID: "tv", UserID: "alice", Client: "Jellyfin Android TV", NowPlaying: &episode, PositionTicks: 10 * ticksPerMinute},
+		{ID: "web", UserID: "stranger", Client: "Jellyfin Web", NowPlaying: &jellyfin.MediaItem{ID: "m9", Type: "Movie"}},
+		{ID: "idle", UserID: "alice", Client: "Jellyfin Web"},
+	}
+	source.Resume = map[string][]jellyfin.MediaItem{
+		"alice": {
+			{ID: "m1", Type: "Movie", RunTimeTicks: 100 * ticksPerMinute,
+				UserData: &jellyfin.UserData{PlaybackPositionTicks: 50 * ticksPerMinute, LastPlayedDate: lastPlayed}},
+			{ID: "e1", Type: "Episode", RunTimeTicks: 40 * ticksPerMinute,
+				UserData: &jellyfin.UserData{PlaybackPositionTicks: 5 * ticksPerMinute, LastPlayedDate: time.Now()}},
+		},
+	}
+
+	syncer := NewSessionSyncer(source, store, predictor, []string{"alice"}, cfg, logger)
+	require.True(t, syncer.Enabled())
+
+	// Alice's cached history is stale once the syncer records her playback
+	predictor.historyUser = "alice"
+	predictor.lastSync = time.Now()
+
+	recorded, err := syncer.Sync(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, recorded, "the episode in progress and the movie resume point")
+	assert.True(t, predictor.lastSync.IsZero())
+
+	history, err := store.GetViewingHistory("alice", 30)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	byMedia := make(map[string]int)
+	for i, session := range history {
+		byMedia[session.MediaID] = i
+	}
+	playing := history[byMedia["e1"]]
+	assert.Equal(t, "episode", playing.MediaType)
+	assert.Equal(t, "s1", playing.SeriesID)
+	assert.Equal(t, 4, playing.Episode)
+	assert.Equal(t, int64(600), playing.WatchedTime)
+	assert.Equal(t, DeviceTV, playing.DeviceType)
+	assert.False(t, playing.Completed)
+	resumed := history[byMedia["m1"]]
+	assert.True(t, resumed.StartTime.Equal(lastPlayed))
+	assert.Equal(t, int64(3000), resumed.WatchedTime)
+
+	stranger, _ := store.GetViewingHistory("stranger", 30)
+	assert.Empty(t, stranger, "untracked users are ignored")
+
+	// Later polls update the same viewing session rather than adding one
+	source.Sessions[0].PositionTicks = 38 * ticksPerMinute
+	_, err = syncer.Sync(context.Background())
+	require.NoError(t, err)
+	history, _ = store.GetViewingHistory("alice", 30)
+	require.Len(t, history, 2)
+	for _, session := range history {
+		if session.MediaID == "e1" {
+			assert.True(t, session.Completed, "watched past the completion threshold")
+		}
+	}
+
+	usage, err := store.GetDeviceUsage()
+	require.NoError(t, err)
+	require.Len(t, usage, 1)
+	assert.Equal(t, DeviceTV, usage[0].Class)
+	assert.Equal(t, 1, usage[0].Plays, "a playback start is recorded once")
+}
+
+func TestSessionSyncerSourceError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	source := jellyfintest.New("http://jellyfin.local")
+	source.Err = errors.New("forbidden")
+
+	syncer := NewSessionSyncer(source, store, nil, []string{"alice"}, &config.PredictionConfig{HistoryDays: 30}, logger)
+	assert.False(t, syncer.Enabled(), "no interval configured")
+
+	_, err := syncer.Sync(context.Background())
+	require.Error(t, err)
+}
+
+func TestSessionSyncerWebhook(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	cfg := &config.PredictionConfig{HistoryDays: 30, SessionSyncInterval: time.Minute}
+	predictor := NewPredictor(store, cfg, logger)
+	source := jellyfintest.New("http://jellyfin.local")
+	syncer := NewSessionSyncer(source, store, predictor, []string{"alice"}, cfg, logger)
+
+	now := time.Now().Truncate(time.Second)
+	syncer.now = func() time.Time { return now }
+
+	episode := jellyfin.MediaItem{ID: "e1", Type: "Episode", SeriesID: "s1", SeasonNumber: 1, EpisodeNumber: 4, RunTimeTicks: 40 * ticksPerMinute}
+	event := jellyfin.WebhookEvent{
+		NotificationType: jellyfin.WebhookPlaybackStart,
+		UserID:           "alice",
+		Item:             episode,
+		Client:           "Jellyfin Android TV",
+	}
+
+	recorded, err := syncer.HandleWebhook(context.Background(), event)
+	require.NoError(t, err, "prediction failures are logged, not returned")
+	assert.True(t, recorded)
+
+	// A sync polling the same playback continues its viewing session
+	now = now.Add(5 * time.Minute)
+	source.Sessions = []jellyfin.Session{
+		{ID: "tv", UserID: "alice", Client: "Jellyfin Android TV", NowPlaying: &episode, PositionTicks: 5 * ticksPerMinute},
+	}
+	_, err = syncer.Sync(context.Background())
+	require.NoError(t, err)
+
+	now = now.Add(30 * time.Minute)
+	event.NotificationType = jellyfin.WebhookPlaybackStop
+	event.PositionTicks = 20 * ticksPerMinute
+	event.PlayedToCompletion = true
+	recorded, err = syncer.HandleWebhook(context.Background(), event)
+	require.NoError(t, err)
+	assert.True(t, recorded)
+
+	history, err := store.GetViewingHistory("alice", 30)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, int64(1200), history[0].WatchedTime)
+	assert.True(t, history[0].Completed, "the plugin's completion flag is trusted")
+	assert.Equal(t, DeviceTV, history[0].DeviceType)
+	assert.Equal(t, 35*time.Minute, history[0].EndTime.Sub(history[0].StartTime))
+
+	usage, err := store.GetDeviceUsage()
+	require.NoError(t, err)
+	require.Len(t, usage, 1)
+	assert.Equal(t, 1, usage[0].Plays)
+
+	// The stop closed the session, so watching again starts a new one
+	now = now.Add(time.Hour)
+	event.NotificationType = jellyfin.WebhookPlaybackProgress
+	event.PlayedToCompletion = false
+	_, err = syncer.HandleWebhook(context.Background(), event)
+	require.NoError(t, err)
+	history, _ = store.GetViewingHistory("alice", 30)
+	assert.Len(t, history, 2)
+
+	// Other users, notification types and uncacheable items are ignored
+	for _, ignored := range []jellyfin.WebhookEvent{
+		{NotificationType: jellyfin.WebhookPlaybackStart, UserID: "stranger", Item: episode},
+		{NotificationType: "ItemAdded", UserID: "alice", Item: episode},
+		{NotificationType: jellyfin.WebhookPlaybackStart, UserID: "alice", Item: jellyfin.MediaItem{ID: "tv1", Type: "TvChannel"}},
+	} {
+		recorded, err := syncer.HandleWebhook(context.Background(), ignored)
+		require.NoError(t, err)
+		assert.False(t, recorded, ignored.NotificationType)
+	}
+}
@@ -0,0 +1,148 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// defaultStallTimeout applies when download.stall_timeout is unset.
+const defaultStallTimeout = 60 * time.Second
+
+// throughputWindow is the span download.min_throughput_kbps is averaged
+// over.
+const throughputWindow = time.Minute
+
+// Causes a download is aborted with. Both are retried, resuming from the
+// partial file.
+var (
+	errDownloadStalled = errors.New("download stalled")
+	errDownloadTooSlow = errors.New("download below minimum throughput")
+)
+
+// downloadWatch observes one download's network reads for the stall
+// watchdog. Reads are timed from the moment they start waiting on the
+// network, so time spent waiting on the rate limiter never counts as a
+// stall.
+type downloadWatch struct {
+	bytes        atomic.Int64
+	waitingSince atomic.Int64 // UnixNano when the pending read began; 0 when idle
+	limiter      atomic.Pointer[rate.Limiter]
+	parent       *downloadWatch // also counts the bytes read, for segments
+}
+
+// waiting marks the start of a network wait, such as for response headers.
+func (w *downloadWatch) waiting() {
+	w.waitingSince.Store(time.Now().UnixNano())
+}
+
+// idle marks the end of a network wait.
+func (w *downloadWatch) idle() {
+	w.waitingSince.Store(0)
+}
+
+// reader wraps a response body so its reads are observed.
+func (w *downloadWatch) reader(r io.Reader) io.Reader {
+	return &watchedReader{r: r, watch: w}
+}
+
+type watchedReader struct {
+	r     io.Reader
+	watch *downloadWatch
+}
+
+func (r *watchedReader) Read(p []byte) (int, error) {
+	r.watch.waiting()
+	n, err := r.r.Read(p)
+	r.watch.idle()
+	r.watch.bytes.Add(int64(n))
+	if r.watch.parent != nil {
+		r.watch.parent.bytes.Add(int64(n))
+	}
+	return n, err
+}
+
+// stallWatchdog aborts downloads that hang or crawl.
+type stallWatchdog struct {
+	timeout time.Duration // longest a network wait may last
+	minRate float64       // bytes per second; 0 disables the throughput check
+	window  time.Duration // span the throughput is averaged over
+}
+
+// stallWatchdog returns the watchdog configured for this manager.
+func (m *Manager) stallWatchdog() stallWatchdog {
+	timeout := m.config.StallTimeout
+	if timeout <= 0 {
+		timeout = defaultStallTimeout
+	}
+	return stallWatchdog{
+		timeout: timeout,
+		minRate: float64(m.config.MinThroughputKBps) * 1024,
+		window:  throughputWindow,
+	}
+}
+
+// run checks watch until ctx is done and calls abort with
+// errDownloadStalled when a network wait outlasts the timeout, or with
+// errDownloadTooSlow when the download averages below the minimum rate over
+// a window while its bandwidth share would allow more.
+func (d stallWatchdog) run(ctx context.Context, watch *downloadWatch, abort context.CancelCauseFunc) {
+	interval := d.timeout / 4
+	if interval > time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	windowStart, windowBytes := time.Now(), watch.bytes.Load()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if since := watch.waitingSince.Load(); since != 0 && now.Sub(time.Unix(0, since)) >= d.timeout {
+				abort(errDownloadStalled)
+				return
+			}
+
+			if d.minRate <= 0 || now.Sub(windowStart) < d.window {
+				continue
+			}
+			received := watch.bytes.Load()
+			throughput := float64(received-windowBytes) / now.Sub(windowStart).Seconds()
+			limiter := watch.limiter.Load()
+			if throughput < d.minRate && (limiter == nil || float64(limiter.Limit()) >= d.minRate) {
+				abort(errDownloadTooSlow)
+				return
+			}
+			windowStart, windowBytes = now, received
+		}
+	}
+}
+
+// stalled logs and reports a download the watchdog aborted after at bytes
+// and returns its error. The retry resumes from the partial file.
+func (m *Manager) stalled(job *DownloadJob, cause error, at int64) error {
+	m.logger.Warn("Aborting download",
+		"job_id", job.ID,
+		"media_id", job.MediaID,
+		"reason", cause,
+		"resume_from", at)
+	m.reportProgress(job.MediaID, 0, "stalled", fmt.Sprintf("%v; will resume from byte %d", cause, at))
+	return fmt.Errorf("%w at byte %d", cause, at)
+}
+
+// stallCause returns the watchdog's reason for aborting ctx, or nil if it
+// did not.
+func stallCause(ctx context.Context) error {
+	cause := context.Cause(ctx)
+	if errors.Is(cause, errDownloadStalled) || errors.Is(cause, errDownloadTooSlow) {
+		return cause
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestStalledDownloadResumes(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 64*1024) // 1 MiB
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) > 1 {
+			http.ServeContent(w, r, "episode.mkv", time.Time{}, bytes.NewReader(content))
+			return
+		}
+		// The first connection sends half the file and then hangs
+		w.Header().Set("Content-Length", "1048576")
+		w.Write(content[:len(content)/2])
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	manager := New(&config.DownloadConfig{
+		Workers:            1,
+		RetryAttempts:      3,
+		RetryDelay:         100 * time.Millisecond,
+		StallTimeout:       200 * time.Millisecond,
+		RateLimitExemption: config.RateLimitExemptionConfig{AutoDetectLAN: true},
+	}, store, logger)
+
+	job := &DownloadJob{
+		ID:        "job-1",
+		MediaID:   "m1",
+		Priority:  2,
+		URL:       server.URL,
+		LocalPath: filepath.Join(t.TempDir(), "episode.mkv"),
+		CreatedAt: time.Now(),
+	}
+	require.NoError(t, store.AddQueueItem(&storage.QueueItem{
+		ID: job.ID, MediaID: job.MediaID, Priority: job.Priority, Status: "downloading", CreatedAt: job.CreatedAt,
+	}))
+
+	started := time.Now()
+	result := manager.processJob(job)
+	require.ErrorIs(t, result.Error, errDownloadStalled)
+	assert.Less(t, time.Since(started), 5*time.Second, "the stall is caught long before the request timeout")
+	info, err := os.Stat(storage.PartialPath(job.LocalPath))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)/2), info.Size(), "the bytes received so far are kept")
+
+	// The stall is retried like any transient failure
+	manager.handleResult(result)
+	item, err := store.FindActiveQueueItem("m1")
+	require.NoError(t, err)
+	assert.Equal(t, "queued", item.Status)
+	assert.Equal(t, 1, item.RetryCount)
+
+	result = manager.processJob(job)
+	require.True(t, result.Success, "error: %v", result.Error)
+	data, err := os.ReadFile(job.LocalPath)
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+}
+
+func TestStallWatchdogHeaders(t *testing.T) {
+	watch := &downloadWatch{}
+	watch.waiting()
+
+	ctx, abort := context.WithCancelCause(context.Background())
+	go stallWatchdog{timeout: 50 * time.Millisecond}.run(ctx, watch, abort)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("watchdog did not abort a request stuck waiting for headers")
+	}
+	assert.ErrorIs(t, context.Cause(ctx), errDownloadStalled)
+}
+
+func TestStallWatchdogThroughput(t *testing.T) {
+	slow := stallWatchdog{timeout: time.Minute, minRate: 64 * 1024, window: 50 * time.Millisecond}
+
+	ctx, abort := context.WithCancelCause(context.Background())
+	go slow.run(ctx, &downloadWatch{}, abort)
+	select {
+	case <-ctx.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("watchdog did not abort a download below the minimum throughput")
+	}
+	assert.ErrorIs(t, context.Cause(ctx), errDownloadTooSlow)
+
+	// A download held back by its bandwidth share is not the connection's fault
+	throttled := &downloadWatch{}
+	throttled.limiter.Store(rate.NewLimiter(rate.Limit(1024), minBurst))
+	ctx, abort = context.WithCancelCause(context.Background())
+	defer abort(nil)
+	go slow.run(ctx, throttled, abort)
+	time.Sleep(300 * time.Millisecond)
+	assert.NoError(t, ctx.Err())
+}
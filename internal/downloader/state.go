@@ -0,0 +1,237 @@
+package downloader
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// Keys the downloader's state is saved under.
+const (
+	limiterStateKey   = "downloader.limiter"
+	predictorStateKey = "predictor"
+)
+
+// limiterState is the bandwidth debt outstanding at shutdown.
+type limiterState struct {
+	DebtBytes float64   `json:"debt_bytes"`
+	SavedAt   time.Time `json:"saved_at"`
+}
+
+// predictorState is the predictor's cached history and analysis.
+type predictorState struct {
+	HistoryUser    string           `json:"history_user"`
+	ViewingHistory []ViewingSession `json:"viewing_history"`
+	Preferences    UserPreferences  `json:"preferences"`
+	LastSync       time.Time        `json:"last_sync"`
+	RecentlyAdded  []recentItem     `json:"recently_added,omitempty"`
+}
+
+// snapshotProgress returns the completed fraction of each in-flight
+// download, 0 for those whose size is not known.
+func (m *Manager) snapshotProgress() map[string]float64 {
+	m.activeMu.Lock()
+	defer m.activeMu.Unlock()
+
+	progress := make(map[string]float64, len(m.active))
+	for jobID, tracker := range m.active {
+		progress[jobID] = 0
+		if tracker.info.Total > 0 {
+			progress[jobID] = float64(tracker.downloaded.Load()) / float64(tracker.info.Total)
+		}
+	}
+	return progress
+}
+
+// saveShutdownState puts downloads interrupted by shutdown back in the
+// queue with their progress and partial files, so the next start resumes
+// them, and saves the bandwidth debt so the restart does not begin with a
+// fresh burst. Called by Stop once the workers have flushed their partial
+// files and exited.
+func (m *Manager) saveShutdownState(progress map[string]float64, debt float64) {
+	requeued, kept := m.requeueInterrupted(progress)
+
+	state := limiterState{DebtBytes: debt, SavedAt: time.Now()}
+	if err := m.storage.SaveState(limiterStateKey, state); err != nil {
+		m.logger.Warn("Failed to save rate limiter state", "error", err)
+	}
+
+	m.logger.Info("Saved download state",
+		"requeued", requeued,
+		"partial_bytes", kept,
+		"bandwidth_debt_bytes", int64(debt))
+}
+
+// restoreState requeues downloads a crash left marked as in flight and
+// carries over whatever bandwidth debt the last shutdown saved, less what
+// the downtime has paid off. Called by Start before the workers run.
+func (m *Manager) restoreState() {
+	if requeued, kept := m.requeueInterrupted(nil); requeued > 0 {
+		m.logger.Info("Requeued downloads interrupted by an unclean shutdown",
+			"count", requeued, "partial_bytes", kept)
+	}
+
+	var state limiterState
+	found, err := m.storage.LoadState(limiterStateKey, &state)
+	if err != nil {
+		m.logger.Warn("Failed to load rate limiter state", "error", err)
+		return
+	}
+	if !found || state.DebtBytes <= 0 {
+		return
+	}
+
+	limit := m.currentRateLimit()
+	if limit == rate.Inf || limit <= 0 {
+		return
+	}
+	owed := state.DebtBytes - time.Since(state.SavedAt).Seconds()*float64(limit)
+	if owed > 0 {
+		m.bandwidth.carryDebt(owed)
+		m.logger.Debug("Carried over bandwidth debt", "bytes", int64(owed))
+	}
+}
+
+// requeueInterrupted resets queue items left in the downloading state, and
+// those progress lists as in flight, to queued, recording how far they got
+// and the partial file holding it. It returns how many were reset and how
+// many bytes their partial files keep.
+func (m *Manager) requeueInterrupted(progress map[string]float64) (int, int64) {
+	items, err := m.storage.GetQueueItems("")
+	if err != nil {
+		m.logger.Warn("Failed to list interrupted downloads", "error", err)
+		return 0, 0
+	}
+
+	requeued := 0
+	var kept int64
+	for _, item := range items {
+		_, inFlight := progress[item.ID]
+		if item.Status != "downloading" && !(inFlight && item.Status == "queued") {
+			continue
+		}
+
+		item.Status = "queued"
+		item.StartedAt = time.Time{}
+		if done, ok := progress[item.ID]; ok {
+			item.Progress = done
+		}
+
+		// The partial file on disk is the truth of how far it got
+		if item.LocalPath != "" {
+			item.BytesDownloaded = 0
+			item.PartialPath = ""
+			path := storage.PartialPath(item.LocalPath)
+			if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+				item.BytesDownloaded = info.Size()
+				item.PartialPath = path
+				kept += info.Size()
+				if item.Size > 0 {
+					item.Progress = float64(info.Size()) / float64(item.Size)
+				}
+			}
+		}
+
+		if err := m.storage.UpdateQueueItem(item); err != nil {
+			m.logger.Warn("Failed to requeue interrupted download",
+				"job_id", item.ID, "error", err)
+			continue
+		}
+		requeued++
+	}
+	return requeued, kept
+}
+
+// resumeInterrupted hands queued downloads with a partial file straight to
+// the workers, most urgent first, rather than waiting for the queue
+// processor to get to them one at a time. Those that do not fit in the
+// worker queue or are held back stay queued. Called by Start once the
+// workers run.
+func (m *Manager) resumeInterrupted() {
+	items, err := m.storage.GetQueueItems("queued")
+	if err != nil {
+		m.logger.Warn("Failed to list interrupted downloads", "error", err)
+		return
+	}
+
+	var partial []*storage.QueueItem
+	for _, item := range items {
+		if item.BytesDownloaded > 0 {
+			partial = append(partial, item)
+		}
+	}
+	sort.SliceStable(partial, func(i, j int) bool {
+		return partial[i].Priority < partial[j].Priority
+	})
+
+	resumed := 0
+	for _, item := range partial {
+		if m.startQueueItem(item) {
+			resumed++
+		}
+	}
+	if resumed > 0 {
+		m.logger.Info("Resuming interrupted downloads", "count", resumed)
+	}
+}
+
+// SaveState persists the predictor's cached viewing history and analysis,
+// so the first prediction cycle after a restart can reuse them instead of
+// starting cold and reshuffling the queue.
+func (p *Predictor) SaveState() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state := predictorState{
+		HistoryUser:    p.historyUser,
+		ViewingHistory: p.viewingHistory,
+		Preferences:    p.preferences,
+		LastSync:       p.lastSync,
+		RecentlyAdded:  p.recentItems(),
+	}
+	if err := p.storage.SaveState(predictorStateKey, state); err != nil {
+		return fmt.Errorf("failed to save predictor state: %w", err)
+	}
+	return nil
+}
+
+// RestoreState loads the state saved by SaveState. A stale snapshot is
+// still loaded; the next cycle refreshes it once the sync interval has
+// passed, as it would have without the restart.
+func (p *Predictor) RestoreState() error {
+	var state predictorState
+	found, err := p.storage.LoadState(predictorStateKey, &state)
+	if err != nil {
+		return fmt.Errorf("failed to restore predictor state: %w", err)
+	}
+	if !found {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.historyUser = state.HistoryUser
+	if state.ViewingHistory != nil {
+		p.viewingHistory = state.ViewingHistory
+	}
+	p.preferences = state.Preferences
+	p.lastSync = state.LastSync
+	for _, item := range state.RecentlyAdded {
+		if p.recentlyAdded == nil {
+			p.recentlyAdded = make(map[string]recentItem)
+		}
+		p.recentlyAdded[item.MediaID] = item
+	}
+
+	p.logger.Info("Restored predictor state",
+		"user_id", state.HistoryUser,
+		"sessions", len(state.ViewingHistory),
+		"last_sync", state.LastSync)
+	return nil
+}
@@ -0,0 +1,202 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestBandwidthDebtCarriesOver(t *testing.T) {
+	const total = rate.Limit(100 * 1024)
+	a := newBandwidthAllocator(func() rate.Limit { return total })
+
+	limiter := a.register("a", 3)
+	limiter.ReserveN(time.Now(), limiter.Burst())
+	limiter.ReserveN(time.Now(), limiter.Burst())
+	debt := a.debt()
+	assert.Greater(t, debt, float64(limiter.Burst())/2, "reading past the allowance leaves debt")
+
+	restarted := newBandwidthAllocator(func() rate.Limit { return total })
+	restarted.carryDebt(debt)
+
+	// Playback is never throttled, so the debt waits for a limited job
+	restarted.register("playing", 0)
+	assert.InDelta(t, debt, restarted.debt(), 1)
+
+	next := restarted.register("next", 3)
+	assert.Less(t, next.Tokens(), 0.0, "the next limited job pays the carried debt")
+	assert.InDelta(t, debt, restarted.debt(), float64(total)/10)
+}
+
+func TestStopRequeuesInterruptedDownloads(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	require.NoError(t, store.AddQueueItem(&storage.QueueItem{
+		ID: "job-1", MediaID: "media-1", Status: "downloading",
+		CreatedAt: time.Now(), StartedAt: time.Now(),
+	}))
+	require.NoError(t, store.AddQueueItem(&storage.QueueItem{
+		ID: "job-2", MediaID: "media-2", Status: "failed", CreatedAt: time.Now(),
+	}))
+
+	manager := New(&config.DownloadConfig{Workers: 1, RateLimitMbps: 10}, store, logger)
+	manager.saveShutdownState(map[string]float64{"job-1": 0.4}, 5e6)
+
+	items, err := store.GetQueueItems("queued")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "job-1", items[0].ID)
+	assert.InDelta(t, 0.4, items[0].Progress, 0.001)
+	assert.True(t, items[0].StartedAt.IsZero())
+
+	failed, err := store.GetQueueItems("failed")
+	require.NoError(t, err)
+	assert.Len(t, failed, 1, "finished items are left alone")
+
+	// A restart picks the debt up, less what the downtime paid off; at
+	// 10Mbps the delta allows for 100ms between saving and restoring
+	restarted := New(&config.DownloadConfig{Workers: 1, RateLimitMbps: 10}, store, logger)
+	restarted.restoreState()
+	assert.InDelta(t, 5e6, restarted.bandwidth.debt(), 10*1024*1024/8/10)
+}
+
+func TestStartRequeuesDownloadsAfterCrash(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	require.NoError(t, store.AddQueueItem(&storage.QueueItem{
+		ID: "job-1", MediaID: "media-1", Status: "downloading", CreatedAt: time.Now(),
+	}))
+
+	manager := New(&config.DownloadConfig{Workers: 1}, store, logger)
+	manager.restoreState()
+
+	items, err := store.GetQueueItems("downloading")
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	next, err := store.GetNextQueueItem()
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	assert.Equal(t, "job-1", next.ID)
+}
+
+func TestShutdownKeepsPartialDownloadForRestart(t *testing.T) {
+	content := bytes.Repeat([]byte("partial "), 1000)
+	var ranges []string
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		first := len(ranges) == 1
+		mu.Unlock()
+
+		if !first {
+			http.ServeContent(w, r, "movie.mkv", time.Time{}, bytes.NewReader(content))
+			return
+		}
+		// The first attempt stalls after 3000 bytes until shutdown
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Write(content[:3000])
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	cfg := &config.DownloadConfig{
+		Workers:            1,
+		StallTimeout:       time.Minute,
+		RateLimitExemption: config.RateLimitExemptionConfig{AutoDetectLAN: true},
+	}
+	localPath := filepath.Join(t.TempDir(), "movie.mkv")
+
+	manager := New(cfg, store, logger)
+	require.NoError(t, manager.Start(context.Background()))
+	require.NoError(t, manager.AddJob(&DownloadJob{
+		ID: "job-1", MediaID: "m1", Priority: 2, URL: server.URL,
+		LocalPath: localPath, CreatedAt: time.Now(),
+	}))
+	require.Eventually(t, func() bool {
+		info, err := os.Stat(storage.PartialPath(localPath))
+		return err == nil && info.Size() == 3000
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, manager.Stop())
+
+	item, err := store.FindActiveQueueItem("m1")
+	require.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "queued", item.Status)
+	assert.Equal(t, int64(3000), item.BytesDownloaded)
+	assert.Equal(t, storage.PartialPath(localPath), item.PartialPath)
+
+	// The next start resumes the download without waiting for the queue
+	// processor
+	restarted := New(cfg, store, logger)
+	require.NoError(t, restarted.Start(context.Background()))
+	defer restarted.Stop()
+
+	require.Eventually(t, func() bool {
+		record, err := store.GetDownload("m1")
+		return err == nil && record.Status == "completed"
+	}, 4*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	assert.Equal(t, []string{"", "bytes=3000-"}, ranges)
+	mu.Unlock()
+	data, err := os.ReadFile(localPath)
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+}
+
+func TestPredictorStateRoundTrip(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	cfg := &config.PredictionConfig{HistoryDays: 30, SyncInterval: time.Hour}
+	lastSync := time.Now().Add(-10 * time.Minute).Round(time.Second)
+
+	predictor := NewPredictor(store, cfg, logger)
+	predictor.historyUser = "alice"
+	predictor.viewingHistory = []ViewingSession{{MediaID: "ep1", SeriesID: "show", Season: 1, Episode: 1}}
+	predictor.preferences.SeriesBingeRate = 2.5
+	predictor.lastSync = lastSync
+	require.NoError(t, predictor.SaveState())
+
+	restarted := NewPredictor(store, cfg, logger)
+	require.NoError(t, restarted.RestoreState())
+	assert.Equal(t, "alice", restarted.historyUser)
+	assert.Equal(t, predictor.viewingHistory, restarted.viewingHistory)
+	assert.Equal(t, 2.5, restarted.preferences.SeriesBingeRate)
+	assert.True(t, lastSync.Equal(restarted.lastSync))
+
+	// The restored history is fresh, so the first cycle does not reload it
+	require.NoError(t, store.StoreViewingSession("alice", storage.ViewingSession{MediaID: "other", StartTime: time.Now()}))
+	_, err := restarted.PredictNext(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Len(t, restarted.viewingHistory, 1)
+}
+
+func TestPredictorRestoreWithoutSavedState(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	predictor := NewPredictor(storagetest.New(), &config.PredictionConfig{SyncInterval: time.Hour}, logger)
+
+	require.NoError(t, predictor.RestoreState())
+	assert.Empty(t, predictor.historyUser)
+	assert.NotNil(t, predictor.viewingHistory)
+}
@@ -0,0 +1,135 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// SubscriptionStore holds series subscriptions (implemented by
+// storage.Manager).
+type SubscriptionStore interface {
+	AddSubscription(sub *storage.Subscription) error
+	GetSubscription(seriesID string) (*storage.Subscription, error)
+	ListSubscriptions() ([]*storage.Subscription, error)
+	RemoveSubscription(seriesID string) error
+}
+
+// Subscriptions caches series the user always wants, independently of the
+// predictor: every episode library sync finds for a subscribed series
+// after the subscription was made is queued at the subscription's
+// priority. Episodes that were already in the library are left to the
+// predictor, or to the series cache endpoint.
+type Subscriptions struct {
+	store    SubscriptionStore
+	queuer   DownloadQueuer
+	priority int // Default priority of new subscriptions
+	logger   *slog.Logger
+
+	// now is stubbed by tests
+	now func() time.Time
+}
+
+// NewSubscriptions creates subscriptions that queue new episodes through
+// queuer, at priority unless a subscription sets its own.
+func NewSubscriptions(store SubscriptionStore, queuer DownloadQueuer, priority int, logger *slog.Logger) *Subscriptions {
+	return &Subscriptions{
+		store:    store,
+		queuer:   queuer,
+		priority: priority,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Subscribe subscribes to seriesID, or changes the priority of an existing
+// subscription. A priority of 0 uses the configured default.
+func (s *Subscriptions) Subscribe(seriesID, seriesName string, priority int) (*storage.Subscription, error) {
+	if priority < 0 || priority > 4 {
+		return nil, fmt.Errorf("priority must be between 1 and 4")
+	}
+	if priority == 0 {
+		priority = s.priority
+	}
+
+	sub, err := s.store.GetSubscription(seriesID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription: %w", err)
+	}
+	if sub == nil {
+		sub = &storage.Subscription{SeriesID: seriesID, CreatedAt: s.now()}
+	}
+	if seriesName != "" {
+		sub.SeriesName = seriesName
+	}
+	sub.Priority = priority
+
+	if err := s.store.AddSubscription(sub); err != nil {
+		return nil, fmt.Errorf("failed to store subscription: %w", err)
+	}
+	return sub, nil
+}
+
+// Unsubscribe stops caching new episodes of seriesID. Episodes already
+// cached or queued stay.
+func (s *Subscriptions) Unsubscribe(seriesID string) error {
+	return s.store.RemoveSubscription(seriesID)
+}
+
+// Get returns the subscription to seriesID, or nil if there is none.
+func (s *Subscriptions) Get(seriesID string) (*storage.Subscription, error) {
+	return s.store.GetSubscription(seriesID)
+}
+
+// List returns every subscription.
+func (s *Subscriptions) List() ([]*storage.Subscription, error) {
+	return s.store.ListSubscriptions()
+}
+
+// LibraryChanged queues the episodes library sync added to subscribed
+// series. Register it with jellyfin.LibrarySync.OnChange.
+func (s *Subscriptions) LibraryChanged(change jellyfin.LibraryChange) {
+	subs := make(map[string]*storage.Subscription)
+
+	for _, item := range change.Added {
+		if item.SeriesID == "" || jellyfin.CacheMediaType(item.Type) != "episode" {
+			continue
+		}
+
+		sub, ok := subs[item.SeriesID]
+		if !ok {
+			var err error
+			sub, err = s.store.GetSubscription(item.SeriesID)
+			if err != nil {
+				s.logger.Warn("Failed to get subscription", "series_id", item.SeriesID, "error", err)
+			}
+			subs[item.SeriesID] = sub
+		}
+		// A first sync reports the whole back catalogue as added
+		if sub == nil || (!item.DateCreated.IsZero() && item.DateCreated.Before(sub.CreatedAt)) {
+			continue
+		}
+
+		// Listeners run on the syncing goroutine without its context
+		_, err := s.queuer.QueueDownloadWithSource(context.Background(), item.ID, sub.Priority, SourceSubscription)
+		switch {
+		case errors.Is(err, ErrLibraryExcluded):
+			s.logger.Debug("Skipping subscribed episode in excluded library", "media_id", item.ID)
+		case err != nil:
+			s.logger.Warn("Failed to queue subscribed episode",
+				"series_id", item.SeriesID,
+				"media_id", item.ID,
+				"error", err)
+		default:
+			s.logger.Info("Queued new episode of subscribed series",
+				"series_id", item.SeriesID,
+				"media_id", item.ID,
+				"priority", sub.Priority)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// priorityQueuer records the priority and source of every queued item.
+type priorityQueuer struct {
+	priorities map[string]int
+	sources    map[string]string
+}
+
+func (q *priorityQueuer) QueueDownload(ctx context.Context, mediaID string, priority int) (string, error) {
+	return q.QueueDownloadWithSource(ctx, mediaID, priority, SourceManual)
+}
+
+func (q *priorityQueuer) QueueDownloadWithSource(ctx context.Context, mediaID string, priority int, source string) (string, error) {
+	q.priorities[mediaID] = priority
+	q.sources[mediaID] = source
+	return mediaID, nil
+}
+
+func TestSubscriptionsQueueNewEpisodes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := storage.NewManager(&config.CacheConfig{Directory: t.TempDir(), MaxSizeGB: 1, MetadataStore: "boltdb"}, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	queuer := &priorityQueuer{priorities: make(map[string]int), sources: make(map[string]string)}
+	subs := NewSubscriptions(store, queuer, 2, logger)
+
+	subscribed := time.Now().Add(-time.Hour)
+	subs.now = func() time.Time { return subscribed }
+
+	sub, err := subs.Subscribe("kids-show", "Bluey", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, sub.Priority, "unset priority uses the default")
+
+	_, err = subs.Subscribe("drama", "", 4)
+	require.NoError(t, err)
+	_, err = subs.Subscribe("drama", "", 9)
+	assert.Error(t, err)
+
+	subs.LibraryChanged(jellyfin.LibraryChange{
+		SyncedAt: time.Now(),
+		Added: []jellyfin.MediaItem{
+			{ID: "new", Type: "Episode", SeriesID: "kids-show", DateCreated: time.Now()},
+			{ID: "back-catalogue", Type: "Episode", SeriesID: "kids-show", DateCreated: subscribed.Add(-24 * time.Hour)},
+			{ID: "drama-new", Type: "Episode", SeriesID: "drama", DateCreated: time.Now()},
+			{ID: "other", Type: "Episode", SeriesID: "unsubscribed", DateCreated: time.Now()},
+			{ID: "movie", Type: "Movie", DateCreated: time.Now()},
+		},
+	})
+
+	assert.Equal(t, map[string]int{"new": 2, "drama-new": 4}, queuer.priorities)
+	assert.Equal(t, SourceSubscription, queuer.sources["new"])
+
+	// Changing the priority keeps the subscription date
+	sub, err = subs.Subscribe("kids-show", "", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Bluey", sub.SeriesName)
+	assert.Equal(t, 1, sub.Priority)
+	assert.WithinDuration(t, subscribed, sub.CreatedAt, time.Second)
+
+	require.NoError(t, subs.Unsubscribe("drama"))
+	list, err := subs.List()
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "kids-show", list[0].SeriesID)
+}
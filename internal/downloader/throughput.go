@@ -0,0 +1,89 @@
+package downloader
+
+import (
+	"sync"
+	"time"
+)
+
+// ThroughputBucketSize is the length of the intervals ThroughputHistory
+// reports. History is kept in memory at a resolution fine enough to chart,
+// for the last day only; usageMeter keeps the long-term hourly totals in
+// storage.
+const ThroughputBucketSize = 5 * time.Minute
+
+const (
+	throughputHistory = 24 * time.Hour
+	throughputBuckets = int(throughputHistory / ThroughputBucketSize)
+)
+
+// ThroughputBucket is how much was downloaded in one interval of
+// ThroughputBucketSize starting at Start.
+type ThroughputBucket struct {
+	Start time.Time `json:"start"`
+	Bytes int64     `json:"bytes"`
+}
+
+// throughputRing counts downloaded bytes in fixed intervals over the last
+// day, overwriting the oldest interval as time moves on. It is an
+// io.Writer so it can sit in a download's tee.
+type throughputRing struct {
+	// now is stubbed by tests
+	now func() time.Time
+
+	mu      sync.Mutex
+	buckets [throughputBuckets]ThroughputBucket
+}
+
+func newThroughputRing() *throughputRing {
+	return &throughputRing{now: time.Now}
+}
+
+func (r *throughputRing) Write(p []byte) (int, error) {
+	r.add(r.now(), int64(len(p)))
+	return len(p), nil
+}
+
+// add counts n bytes downloaded at t.
+func (r *throughputRing) add(t time.Time, n int64) {
+	start := t.Truncate(ThroughputBucketSize)
+	bucket := &r.buckets[r.index(start)]
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !bucket.Start.Equal(start) {
+		// Left over from a day ago
+		*bucket = ThroughputBucket{Start: start}
+	}
+	bucket.Bytes += n
+}
+
+// index returns the slot of the interval starting at start.
+func (r *throughputRing) index(start time.Time) int {
+	return int(start.Unix()/int64(ThroughputBucketSize/time.Second)) % throughputBuckets
+}
+
+// history returns every interval of the last day up to and including the
+// current one, oldest first. Intervals nothing was downloaded in are
+// included with zero bytes.
+func (r *throughputRing) history() []ThroughputBucket {
+	current := r.now().Truncate(ThroughputBucketSize)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	history := make([]ThroughputBucket, throughputBuckets)
+	for i := range history {
+		start := current.Add(-time.Duration(throughputBuckets-1-i) * ThroughputBucketSize)
+		history[i] = ThroughputBucket{Start: start}
+		if bucket := r.buckets[r.index(start)]; bucket.Start.Equal(start) {
+			history[i].Bytes = bucket.Bytes
+		}
+	}
+	return history
+}
+
+// ThroughputHistory returns how much was downloaded in each
+// ThroughputBucketSize interval of the last 24 hours, oldest first.
+func (m *Manager) ThroughputHistory() []ThroughputBucket {
+	return m.throughput.history()
+}
@@ -0,0 +1,37 @@
+package downloader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThroughputRing(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 7, 0, 0, time.UTC)
+	ring := newThroughputRing()
+	ring.now = func() time.Time { return now }
+
+	ring.Write(make([]byte, 100))
+	ring.add(now.Add(-2*time.Minute), 50) // same interval, 12:05
+	ring.add(now.Add(-10*time.Minute), 70)
+	ring.add(now.Add(-25*time.Hour), 999) // a day ago, in the slot 11:05 uses
+
+	history := ring.history()
+	require.Len(t, history, 288)
+	last := history[len(history)-1]
+	assert.Equal(t, time.Date(2026, 10, 15, 12, 5, 0, 0, time.UTC), last.Start)
+	assert.Equal(t, int64(150), last.Bytes)
+	assert.Equal(t, int64(70), history[len(history)-3].Bytes)
+	assert.Equal(t, last.Start.Add(-24*time.Hour+ThroughputBucketSize), history[0].Start, "the last 24 hours")
+
+	// Intervals are overwritten once a day has passed
+	now = now.Add(23*time.Hour + 58*time.Minute) // 12:05 the next day
+	ring.Write(make([]byte, 10))
+	history = ring.history()
+	assert.Equal(t, int64(10), history[len(history)-1].Bytes)
+	for _, bucket := range history[:len(history)-1] {
+		assert.Zero(t, bucket.Bytes, "nothing else was downloaded within a day of %v", bucket.Start)
+	}
+}
@@ -0,0 +1,148 @@
+package downloader
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// transportFor returns the shared transport that dials from localIP, or
+// from the default route when localIP is nil, creating it on first use.
+// Sharing transports lets downloads reuse warm, pooled connections (and
+// multiplex over HTTP/2 where the server offers it) instead of opening a
+// cold connection for every file.
+func (m *Manager) transportFor(localIP net.IP) (*http.Transport, error) {
+	key := ""
+	if localIP != nil {
+		key = localIP.String()
+	}
+
+	m.transportsMu.Lock()
+	defer m.transportsMu.Unlock()
+
+	if transport, ok := m.transports[key]; ok {
+		return transport, nil
+	}
+
+	transport, err := m.newTransport(localIP)
+	if err != nil {
+		return nil, err
+	}
+	if m.transports == nil {
+		m.transports = make(map[string]*http.Transport)
+	}
+	m.transports[key] = transport
+	return transport, nil
+}
+
+// newTransport builds a transport tuned for long downloads from a few
+// hosts, per download.http.
+func (m *Manager) newTransport(localIP net.IP) (*http.Transport, error) {
+	cfg := m.config.HTTP
+
+	tlsConfig, err := downloadTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if localIP != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: localIP}
+	}
+
+	idleTimeout := cfg.IdleConnTimeout
+	if idleTimeout == 0 {
+		idleTimeout = 90 * time.Second
+	}
+
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		ForceAttemptHTTP2:     !cfg.DisableHTTP2,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   max(m.workerCount(), 2),
+		IdleConnTimeout:       idleTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}, nil
+}
+
+// downloadTLSConfig returns the TLS settings for download connections:
+// the minimum version, extra trusted CAs for self-signed Jellyfin
+// servers, and optionally no verification at all.
+func downloadTLSConfig(cfg config.DownloadHTTPConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.MinTLSVersion == "1.3" {
+		tlsConfig.MinVersion = tls.VersionTLS13
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// closeIdleConnections drops pooled connections, e.g. at shutdown.
+func (m *Manager) closeIdleConnections() {
+	m.transportsMu.Lock()
+	defer m.transportsMu.Unlock()
+
+	for _, transport := range m.transports {
+		transport.CloseIdleConnections()
+	}
+}
+
+// acquireHost waits for one of the download.http.max_conns_per_host slots
+// of host and returns the function that frees it, so a burst of queued
+// downloads does not open many parallel cold connections to the Jellyfin
+// server. Playback (Priority 0) never waits. It fails once ctx is done.
+func (m *Manager) acquireHost(ctx context.Context, host string, priority int) (func(), error) {
+	limit := m.config.HTTP.MaxConnsPerHost
+	if limit <= 0 || priority == 0 {
+		return func() {}, nil
+	}
+
+	m.transportsMu.Lock()
+	if m.hostSlots == nil {
+		m.hostSlots = make(map[string]chan struct{})
+	}
+	slots, ok := m.hostSlots[host]
+	if !ok {
+		slots = make(chan struct{}, limit)
+		m.hostSlots[host] = slots
+	}
+	m.transportsMu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	}
+}
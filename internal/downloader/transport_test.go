@@ -0,0 +1,97 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestSharedTransportReusesConnections(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var opened atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			opened.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	manager := New(&config.DownloadConfig{Workers: 2, RateLimitMbps: 10}, nil, logger)
+
+	for i := 0; i < 3; i++ {
+		client, err := manager.httpClientFor(3)
+		require.NoError(t, err)
+
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, int32(1), opened.Load(), "downloads share one pooled connection")
+}
+
+func TestAcquireHostLimitsConnections(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.DownloadConfig{
+		Workers: 4, RateLimitMbps: 10,
+		HTTP: config.DownloadHTTPConfig{MaxConnsPerHost: 2},
+	}
+	manager := New(cfg, nil, logger)
+
+	ctx := context.Background()
+	first, err := manager.acquireHost(ctx, "jellyfin:8096", 3)
+	require.NoError(t, err)
+	_, err = manager.acquireHost(ctx, "jellyfin:8096", 4)
+	require.NoError(t, err)
+
+	// A third speculative download waits for a slot
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = manager.acquireHost(waitCtx, "jellyfin:8096", 3)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Playback and other hosts are not held up
+	release, err := manager.acquireHost(ctx, "jellyfin:8096", 0)
+	require.NoError(t, err)
+	release()
+	release, err = manager.acquireHost(ctx, "other:8096", 3)
+	require.NoError(t, err)
+	release()
+
+	// Freeing a slot lets the next download in
+	first()
+	release, err = manager.acquireHost(ctx, "jellyfin:8096", 3)
+	require.NoError(t, err)
+	release()
+}
+
+func TestDownloadTLSConfig(t *testing.T) {
+	tlsConfig, err := downloadTLSConfig(config.DownloadHTTPConfig{MinTLSVersion: "1.3"})
+	require.NoError(t, err)
+	assert.Equal(t, uint16(0x0304), tlsConfig.MinVersion)
+	assert.Nil(t, tlsConfig.RootCAs, "the system pool is used by default")
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0o600))
+	_, err = downloadTLSConfig(config.DownloadHTTPConfig{CAFile: caFile})
+	assert.Error(t, err)
+}
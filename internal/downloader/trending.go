@@ -0,0 +1,102 @@
+package downloader
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+)
+
+// trendingLimit is how many popular items are fetched from Jellyfin each
+// prediction cycle before they are filtered by genre.
+const trendingLimit = 50
+
+// TrendingSource lists the library's popular items the user has not
+// watched, most popular first (implemented by jellyfin.Client). A metadata
+// source that also implements it enables trending predictions.
+type TrendingSource interface {
+	GetPopular(ctx context.Context, limit int) ([]jellyfin.MediaItem, error)
+}
+
+// predictTrending suggests popular content in preferred genres (Priority 4).
+// Popular items come from the metadata source when it is a TrendingSource;
+// only those sharing a genre with the viewing history are kept, since
+// popularity alone says little about this household.
+func (p *Predictor) predictTrending(ctx context.Context) []PredictionResult {
+	var predictions []PredictionResult
+
+	source, ok := p.metadata.(TrendingSource)
+	if !ok || len(p.preferences.PreferredGenres) == 0 {
+		return predictions
+	}
+
+	items, err := source.GetPopular(ctx, trendingLimit)
+	if err != nil {
+		p.logger.Warn("Failed to fetch popular items", "error", err)
+		return predictions
+	}
+
+	watched := make(map[string]bool, len(p.viewingHistory))
+	for _, session := range p.viewingHistory {
+		watched[session.MediaID] = true
+	}
+
+	now := time.Now()
+	for _, item := range items {
+		matches := genreMatches(item.Genres, p.preferences.PreferredGenres)
+		if matches == 0 || watched[item.ID] {
+			continue
+		}
+		if cached, err := p.storage.IsMediaCached(item.ID); err != nil || cached {
+			continue
+		}
+
+		// Seasonal boosts and language preferences read genres and audio
+		// languages from storage; keep what library sync stored if any
+		size := item.Size
+		if metadata, err := p.storage.GetMediaMetadata(item.ID); err == nil {
+			if !p.libraries.Allows(metadata.Library) {
+				continue
+			}
+			if size == 0 {
+				size = metadata.Size
+			}
+		} else if err := p.storeItemMetadata(item, now); err != nil {
+			p.logger.Warn("Failed to store metadata of popular item",
+				"media_id", item.ID, "error", err)
+		}
+
+		predictions = append(predictions, PredictionResult{
+			MediaID:       item.ID,
+			Priority:      4,
+			Confidence:    trendingConfidence(item.CommunityRating, matches),
+			Reason:        "Popular in a genre you watch",
+			MediaType:     jellyfin.CacheMediaType(item.Type),
+			EstimatedSize: size,
+		})
+	}
+
+	return predictions
+}
+
+// trendingConfidence scores a popular item from its community rating (0-10)
+// and how many preferred genres it shares, between 0.5 and 0.9.
+func trendingConfidence(rating float64, matches int) float64 {
+	rating = max(0, min(rating, 10))
+	return 0.5 + 0.25*rating/10 + 0.05*float64(min(matches, 3))
+}
+
+// genreMatches counts the genres that appear in both lists, ignoring case.
+func genreMatches(genres, preferred []string) int {
+	matches := 0
+	for _, genre := range genres {
+		for _, want := range preferred {
+			if strings.EqualFold(genre, want) {
+				matches++
+				break
+			}
+		}
+	}
+	return matches
+}
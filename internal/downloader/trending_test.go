@@ -0,0 +1,94 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestPredictTrending(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	predictor := NewPredictor(store, &config.PredictionConfig{HistoryDays: 30, MinConfidence: 0.7}, logger)
+
+	source := jellyfintest.New("http://jellyfin.local")
+	source.Popular = []jellyfin.MediaItem{
+		{ID: "dune", Type: "Movie", Genres: []string{"Sci-Fi", "Drama"}, CommunityRating: 8, Size: 4 << 30},
+		{ID: "notebook", Type: "Movie", Genres: []string{"Romance"}, CommunityRating: 9},
+		{ID: "seen", Type: "Movie", Genres: []string{"Sci-Fi"}, CommunityRating: 9},
+		{ID: "cached", Type: "Movie", Genres: []string{"Sci-Fi"}, CommunityRating: 9},
+	}
+	predictor.SetMetadataSource(source)
+	predictor.preferences.PreferredGenres = []string{"sci-fi", "Drama"}
+	predictor.viewingHistory = []ViewingSession{{MediaID: "seen", Completed: true}}
+	require.NoError(t, store.AddDownloadRecord(&storage.DownloadRecord{
+		ID: "cached", JellyfinID: "cached", MediaType: "movie", Status: "completed",
+	}))
+
+	predictions := predictor.predictTrending(context.Background())
+	require.Len(t, predictions, 1)
+	assert.Equal(t, "dune", predictions[0].MediaID)
+	assert.Equal(t, 4, predictions[0].Priority)
+	assert.Equal(t, "movie", predictions[0].MediaType)
+	assert.Equal(t, int64(4<<30), predictions[0].EstimatedSize)
+	assert.InDelta(t, 0.5+0.2+0.1, predictions[0].Confidence, 1e-9)
+
+	// Genres are stored so seasonal rules can boost the prediction
+	metadata, err := store.GetMediaMetadata("dune")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Sci-Fi", "Drama"}, metadata.Genres)
+
+	// Without genre preferences popularity alone predicts nothing
+	predictor.preferences.PreferredGenres = nil
+	assert.Empty(t, predictor.predictTrending(context.Background()))
+}
+
+func TestReconcileQueueSpeculativeBudget(t *testing.T) {
+	predictor, sm, _ := newReconcileTestPredictor(t)
+	predictor.config.MaxSpeculativeGB = 10
+	ctx := context.Background()
+
+	// 4 GB of earlier speculative downloads are already cached
+	require.NoError(t, sm.AddDownloadRecord(&storage.DownloadRecord{
+		ID: "old", JellyfinID: "old", MediaType: "movie", Status: "completed",
+		Size: 4 << 30, Priority: 4, Source: SourcePrediction,
+	}))
+	// Sizes come from the prediction or from stored metadata
+	require.NoError(t, sm.AddMediaMetadata(&storage.MediaMetadata{ID: "b", JellyfinID: "b", Type: "movie", Size: 3 << 30}))
+
+	summary, err := predictor.ReconcileQueue(ctx, []PredictionResult{
+		{MediaID: "urgent", Priority: 1, Confidence: 0.9, EstimatedSize: 20 << 30},
+		{MediaID: "a", Priority: 3, Confidence: 0.9, EstimatedSize: 2 << 30},
+		{MediaID: "b", Priority: 4, Confidence: 0.9},
+		{MediaID: "c", Priority: 4, Confidence: 0.8, EstimatedSize: 2 << 30},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, summary.Added)
+	assert.Equal(t, 1, summary.OverBudget)
+	byMedia := queuedByMedia(t, sm)
+	assert.Contains(t, byMedia, "urgent", "urgent priorities are not capped")
+	assert.Contains(t, byMedia, "a")
+	assert.Contains(t, byMedia, "b")
+	assert.NotContains(t, byMedia, "c")
+
+	// Queued speculative items keep counting on the next cycle
+	summary, err = predictor.ReconcileQueue(ctx, []PredictionResult{
+		{MediaID: "a", Priority: 3, Confidence: 0.9, EstimatedSize: 2 << 30},
+		{MediaID: "b", Priority: 4, Confidence: 0.9},
+		{MediaID: "d", Priority: 4, Confidence: 0.8, EstimatedSize: 1 << 30},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Added)
+	assert.Equal(t, 0, summary.OverBudget)
+}
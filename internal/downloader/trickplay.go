@@ -0,0 +1,180 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// chapterImageWidth is the widest chapter images are cached at; they are
+// only shown as seek previews.
+const chapterImageWidth = 320
+
+// TrickplaySource downloads seek preview images (implemented by
+// jellyfin.Client).
+type TrickplaySource interface {
+	GetTrickplayInfo(ctx context.Context, itemID string) (*jellyfin.TrickplayInfo, []jellyfin.Chapter, error)
+	GetTrickplayTile(ctx context.Context, itemID string, width, index int) ([]byte, error)
+	GetChapterImage(ctx context.Context, itemID string, index, maxWidth int) ([]byte, error)
+}
+
+// TrickplayStore is the storage the trickplay cache finds cached items in.
+type TrickplayStore interface {
+	GetDownload(mediaID string) (*storage.DownloadRecord, error)
+	ListDownloadRecords(mediaType string) ([]*storage.DownloadRecord, error)
+}
+
+// TrickplayCache keeps seek previews of cached videos next to their media
+// files, so the player can show them without asking Jellyfin. It caches
+// the trickplay tiles Jellyfin generated, or the chapter images of items
+// without them. Jellyfin generates both in a scheduled task that may run
+// after an item was downloaded, so CacheMissing, run as maintenance, picks
+// up previews that appeared later.
+type TrickplayCache struct {
+	source TrickplaySource
+	store  TrickplayStore
+	files  *storage.FileManager
+	logger *slog.Logger
+
+	// now is stubbed by tests
+	now func() time.Time
+}
+
+// NewTrickplayCache creates a trickplay cache that downloads from source.
+func NewTrickplayCache(source TrickplaySource, store TrickplayStore, cfg *config.CacheConfig, logger *slog.Logger) *TrickplayCache {
+	return &TrickplayCache{
+		source: source,
+		store:  store,
+		files:  storage.NewFileManager(cfg.TempDirectory, logger),
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// CacheTrickplay stores the seek previews of a cached video and reports
+// whether it did. Items that are not cached, are not videos, already have
+// previews or have none on the server are skipped.
+func (t *TrickplayCache) CacheTrickplay(ctx context.Context, mediaID string) (bool, error) {
+	record, err := t.store.GetDownload(mediaID)
+	if err != nil || !t.wantsPreviews(record) {
+		return false, nil
+	}
+	if manifest, err := storage.ReadTrickplayManifest(record.LocalPath); err == nil && manifest != nil {
+		return false, nil
+	}
+
+	info, chapters, err := t.source.GetTrickplayInfo(ctx, mediaID)
+	if err != nil {
+		return false, err
+	}
+
+	manifest := &storage.TrickplayManifest{CachedAt: t.now()}
+	dir := storage.TrickplayDir(record.LocalPath)
+	if info != nil {
+		sheets := info.Tiles()
+		for i := 0; i < sheets; i++ {
+			data, err := t.source.GetTrickplayTile(ctx, mediaID, info.Width, i)
+			if err != nil {
+				return false, err
+			}
+			if err := t.files.WriteFileAtomic(filepath.Join(dir, storage.TrickplayTileName(i)), data); err != nil {
+				return false, err
+			}
+		}
+		manifest.Tiles = &storage.TrickplayTiles{
+			Width:          info.Width,
+			Height:         info.Height,
+			Columns:        info.TileWidth,
+			Rows:           info.TileHeight,
+			ThumbnailCount: info.ThumbnailCount,
+			Interval:       time.Duration(info.Interval) * time.Millisecond,
+			Sheets:         sheets,
+		}
+	} else {
+		for i, chapter := range chapters {
+			if chapter.ImageTag == "" {
+				continue
+			}
+			data, err := t.source.GetChapterImage(ctx, mediaID, i, chapterImageWidth)
+			if errors.Is(err, jellyfin.ErrNoImage) {
+				continue
+			}
+			if err != nil {
+				return false, err
+			}
+			name := storage.TrickplayChapterName(i)
+			if err := t.files.WriteFileAtomic(filepath.Join(dir, name), data); err != nil {
+				return false, err
+			}
+			manifest.Chapters = append(manifest.Chapters, storage.TrickplayChapter{
+				Name:  chapter.Name,
+				Start: time.Duration(chapter.StartPositionTicks) * 100,
+				Image: name,
+			})
+		}
+	}
+	if manifest.Tiles == nil && len(manifest.Chapters) == 0 {
+		return false, nil
+	}
+
+	if err := t.files.WriteTrickplayManifest(record.LocalPath, manifest); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CacheMissing caches seek previews for every cached video that has none
+// yet and returns for how many it did.
+func (t *TrickplayCache) CacheMissing(ctx context.Context) (int, error) {
+	records, err := t.store.ListDownloadRecords("")
+	if err != nil {
+		return 0, err
+	}
+
+	cached := 0
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return cached, err
+		}
+		if !t.wantsPreviews(record) {
+			continue
+		}
+		ok, err := t.CacheTrickplay(ctx, record.JellyfinID)
+		if err != nil {
+			if errors.Is(err, jellyfin.ErrOffline) {
+				return cached, err
+			}
+			t.logger.Warn("Failed to cache seek previews", "media_id", record.JellyfinID, "error", err)
+			continue
+		}
+		if ok {
+			cached++
+		}
+	}
+
+	if cached > 0 {
+		t.logger.Info("Cached seek previews", "items", cached)
+	}
+	return cached, nil
+}
+
+// wantsPreviews reports whether record is a cached video, which seek
+// previews are kept for.
+func (t *TrickplayCache) wantsPreviews(record *storage.DownloadRecord) bool {
+	if record == nil || record.LocalPath == "" || record.Status == "evicted" {
+		return false
+	}
+	switch record.MediaType {
+	case "audio", "audiobook":
+		return false
+	}
+	_, err := os.Stat(record.LocalPath)
+	return err == nil
+}
@@ -0,0 +1,85 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+var _ TrickplaySource = (*jellyfin.Client)(nil)
+var _ TrickplaySource = (*jellyfintest.Mock)(nil)
+
+func TestTrickplayCache(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	dir := t.TempDir()
+
+	paths := make(map[string]string)
+	for _, id := range []string{"tiled", "chaptered", "plain"} {
+		paths[id] = filepath.Join(dir, "movies", id, "movie.mkv")
+		require.NoError(t, os.MkdirAll(filepath.Dir(paths[id]), 0755))
+		require.NoError(t, os.WriteFile(paths[id], []byte("video"), 0644))
+		require.NoError(t, store.AddDownloadRecord(&storage.DownloadRecord{
+			ID: id, JellyfinID: id, MediaType: "movie", LocalPath: paths[id], Status: "completed",
+		}))
+	}
+
+	source := jellyfintest.New("http://jellyfin.local")
+	source.Trickplay = map[string]*jellyfin.TrickplayInfo{
+		"tiled": {Width: 320, Height: 180, TileWidth: 10, TileHeight: 10, ThumbnailCount: 150, Interval: 10000},
+	}
+	source.Chapters = map[string][]jellyfin.Chapter{
+		"chaptered": {
+			{Name: "Opening", StartPositionTicks: 0, ImageTag: "a"},
+			{Name: "No image", StartPositionTicks: 600_000_000},
+			{Name: "Heist", StartPositionTicks: 12_000_000_000, ImageTag: "b"},
+		},
+	}
+	source.Images = map[string][]byte{
+		"tiled/Trickplay/0":   []byte("sheet 0"),
+		"tiled/Trickplay/1":   []byte("sheet 1"),
+		"chaptered/Chapter/0": []byte("opening"),
+		"chaptered/Chapter/2": []byte("heist"),
+	}
+	cache := NewTrickplayCache(source, store, &config.CacheConfig{TempDirectory: t.TempDir()}, logger)
+
+	cached, err := cache.CacheMissing(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, cached, "the item without previews is skipped")
+
+	manifest, err := storage.ReadTrickplayManifest(paths["tiled"])
+	require.NoError(t, err)
+	require.NotNil(t, manifest.Tiles)
+	assert.Equal(t, 2, manifest.Tiles.Sheets)
+	assert.Equal(t, 10*time.Second, manifest.Tiles.Interval)
+	sheet, err := os.ReadFile(filepath.Join(storage.TrickplayDir(paths["tiled"]), storage.TrickplayTileName(1)))
+	require.NoError(t, err)
+	assert.Equal(t, "sheet 1", string(sheet))
+
+	manifest, err = storage.ReadTrickplayManifest(paths["chaptered"])
+	require.NoError(t, err)
+	require.Len(t, manifest.Chapters, 2)
+	assert.Equal(t, storage.TrickplayChapter{Name: "Heist", Start: 20 * time.Minute, Image: "chapter-2.jpg"}, manifest.Chapters[1])
+
+	manifest, err = storage.ReadTrickplayManifest(paths["plain"])
+	require.NoError(t, err)
+	assert.Nil(t, manifest)
+
+	// Cached previews are not fetched again
+	cached, err = cache.CacheMissing(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, cached)
+}
@@ -0,0 +1,323 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// defaultTripEpisodes is how many episodes ahead of each active series a
+// trip caches when it does not say.
+const defaultTripEpisodes = 10
+
+// tripActiveDays is how recently a series must have been watched for a
+// trip to cache its upcoming episodes; wider than a binge, since a trip is
+// planned ahead.
+const tripActiveDays = 30
+
+// Priorities trip items are queued at: the next episodes match binge
+// prefetching, favorite movies the warmers' speculative class.
+const (
+	tripEpisodePriority  = 2
+	tripFavoritePriority = 3
+)
+
+// tripCheckInterval is how often Run checks whether the trip has ended.
+const tripCheckInterval = time.Minute
+
+// TripSource lists the favorite movies a trip caches (implemented by
+// jellyfin.Client).
+type TripSource interface {
+	GetFavorites(ctx context.Context) ([]jellyfin.MediaItem, error)
+}
+
+// TripStore persists the trip and the queue it fills (implemented by
+// storage.Manager).
+type TripStore interface {
+	storage.QueueStore
+	storage.MediaStore
+	SaveTrip(trip *storage.Trip) error
+	GetTrip() (*storage.Trip, error)
+	ClearTrip() error
+}
+
+// TripLimits are the cache limits a trip raises (implemented by
+// storage.CacheManager).
+type TripLimits interface {
+	SetLimits(maxSizeGB int, evictionThreshold float64)
+	MaxSize() int64
+	EvictionThreshold() float64
+}
+
+// TripOptions describes a trip to start.
+type TripOptions struct {
+	Until     time.Time // When the trip ends
+	Episodes  int       // Episodes ahead of each active series, default 10
+	Favorites bool      // Cache every favorite movie too
+	MaxSizeGB int       // Cache size limit during the trip, 0 keeps the current one
+}
+
+// TripMode caches for time away from the server: until the trip ends it
+// downloads the next episodes of every series watched lately and the
+// user's favorite movies, keeps predicting that many episodes ahead, may
+// let the cache grow and protects what it picked from eviction. When the
+// trip ends, the limits are restored and what it picked is evicted as
+// usual.
+type TripMode struct {
+	predictor *Predictor
+	source    TripSource
+	storage   TripStore
+	queuer    DownloadQueuer
+	cache     TripLimits // nil leaves the cache limits alone
+	userID    string
+	logger    *slog.Logger
+
+	// mu serializes starting and ending trips
+	mu sync.Mutex
+}
+
+// NewTripMode creates trip mode for userID, queueing through queuer. cache
+// may be nil.
+func NewTripMode(predictor *Predictor, source TripSource, storage TripStore, queuer DownloadQueuer, cache TripLimits, userID string, logger *slog.Logger) *TripMode {
+	return &TripMode{
+		predictor: predictor,
+		source:    source,
+		storage:   storage,
+		queuer:    queuer,
+		cache:     cache,
+		userID:    userID,
+		logger:    logger,
+	}
+}
+
+// Current returns the trip, or nil if none is under way.
+func (t *TripMode) Current() (*storage.Trip, error) {
+	trip, err := t.storage.GetTrip()
+	if err != nil || !trip.Active(time.Now()) {
+		return nil, err
+	}
+	return trip, nil
+}
+
+// Start starts a trip, replacing any under way, and queues what it caches.
+// Favorite movies already cached are not downloaded again but are
+// protected from eviction like the rest.
+func (t *TripMode) Start(ctx context.Context, opts TripOptions) (*storage.Trip, error) {
+	now := time.Now()
+	if !opts.Until.After(now) {
+		return nil, fmt.Errorf("trip must end in the future")
+	}
+	if opts.Episodes <= 0 {
+		opts.Episodes = defaultTripEpisodes
+	}
+	if opts.MaxSizeGB < 0 {
+		return nil, fmt.Errorf("max_size_gb must not be negative")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previous, err := t.storage.GetTrip()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trip: %w", err)
+	}
+
+	trip := &storage.Trip{
+		StartedAt: now,
+		Until:     opts.Until,
+		Episodes:  opts.Episodes,
+		Favorites: opts.Favorites,
+		MaxSizeGB: opts.MaxSizeGB,
+	}
+	if t.cache != nil {
+		trip.NormalMaxSizeGB = int(t.cache.MaxSize() / (1024 * 1024 * 1024))
+	}
+	// A trip replacing another restores the limit from before either
+	if previous != nil && previous.MaxSizeGB > 0 {
+		trip.NormalMaxSizeGB = previous.NormalMaxSizeGB
+	}
+
+	episodes, err := t.predictor.planTrip(ctx, t.userID, opts.Episodes)
+	if err != nil {
+		return nil, err
+	}
+	var favorites []jellyfin.MediaItem
+	if opts.Favorites {
+		if favorites, err = t.source.GetFavorites(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	// Raise the limits before queueing, so the downloads fit
+	t.apply(trip)
+
+	seen := make(map[string]bool)
+	pick := func(mediaID string, priority int) {
+		if seen[mediaID] {
+			return
+		}
+		seen[mediaID] = true
+		trip.MediaIDs = append(trip.MediaIDs, mediaID)
+
+		if cached, err := t.storage.IsMediaCached(mediaID); err == nil && cached {
+			return
+		}
+		if _, err := t.queuer.QueueDownloadWithSource(ctx, mediaID, priority, SourceTrip); err != nil {
+			t.logger.Warn("Failed to queue trip download", "media_id", mediaID, "error", err)
+		}
+	}
+	for _, episode := range episodes {
+		pick(episode.MediaID, tripEpisodePriority)
+	}
+	for _, item := range favorites {
+		if jellyfin.CacheMediaType(item.Type) == "movie" {
+			pick(item.ID, tripFavoritePriority)
+		}
+	}
+
+	if err := t.storage.SaveTrip(trip); err != nil {
+		t.revert(trip)
+		return nil, fmt.Errorf("failed to save trip: %w", err)
+	}
+
+	t.logger.Info("Trip started",
+		"until", trip.Until,
+		"episodes", trip.Episodes,
+		"favorites", trip.Favorites,
+		"max_size_gb", trip.MaxSizeGB,
+		"items", len(trip.MediaIDs))
+
+	return trip, nil
+}
+
+// End ends the trip, if one was started: trip downloads still queued are
+// cancelled, the cache limits and predictions go back to normal and what
+// the trip cached may be evicted again.
+func (t *TripMode) End(reason string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	trip, err := t.storage.GetTrip()
+	if err != nil {
+		return fmt.Errorf("failed to get trip: %w", err)
+	}
+	if trip == nil {
+		return nil
+	}
+
+	t.revert(trip)
+
+	items, err := t.storage.GetQueueItems("queued")
+	if err != nil {
+		return fmt.Errorf("failed to get queued items: %w", err)
+	}
+	cancelled := 0
+	for _, item := range items {
+		if item.Source != SourceTrip {
+			continue
+		}
+		if err := t.storage.RemoveQueueItem(item.ID); err != nil {
+			t.logger.Warn("Failed to cancel trip download",
+				"job_id", item.ID, "media_id", item.MediaID, "error", err)
+			continue
+		}
+		cancelled++
+	}
+
+	if err := t.storage.ClearTrip(); err != nil {
+		return fmt.Errorf("failed to clear trip: %w", err)
+	}
+
+	t.logger.Info("Trip ended", "reason", reason, "cancelled", cancelled)
+	return nil
+}
+
+// Restore applies a trip under way when the daemon starts, and ends one
+// that ran out while it was stopped.
+func (t *TripMode) Restore() error {
+	trip, err := t.storage.GetTrip()
+	if err != nil || trip == nil {
+		return err
+	}
+	if !trip.Active(time.Now()) {
+		return t.End("expired")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.apply(trip)
+	return nil
+}
+
+// Run ends the trip once its end date passes, until ctx is cancelled.
+func (t *TripMode) Run(ctx context.Context) {
+	ticker := time.NewTicker(tripCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		trip, err := t.storage.GetTrip()
+		if err != nil {
+			t.logger.Warn("Failed to check trip", "error", err)
+			continue
+		}
+		if trip != nil && !trip.Active(time.Now()) {
+			if err := t.End("expired"); err != nil {
+				t.logger.Error("Failed to end trip", "error", err)
+			}
+		}
+	}
+}
+
+// apply raises the limits and predictions for trip. Callers must hold
+// t.mu.
+func (t *TripMode) apply(trip *storage.Trip) {
+	if t.cache != nil && trip.MaxSizeGB > 0 {
+		t.cache.SetLimits(trip.MaxSizeGB, t.cache.EvictionThreshold())
+	}
+	t.predictor.SetTripEpisodes(trip.Episodes)
+}
+
+// revert restores the limits and predictions from before trip. Callers
+// must hold t.mu.
+func (t *TripMode) revert(trip *storage.Trip) {
+	if t.cache != nil && trip.MaxSizeGB > 0 {
+		t.cache.SetLimits(trip.NormalMaxSizeGB, t.cache.EvictionThreshold())
+	}
+	t.predictor.SetTripEpisodes(0)
+}
+
+// SetTripEpisodes makes predictions cover n episodes ahead of each
+// recently watched series whatever the binge rate, or stops that with 0.
+func (p *Predictor) SetTripEpisodes(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tripEpisodes = n
+}
+
+// planTrip returns the count episodes following the last one watched of
+// every series userID watched in the last tripActiveDays, leaving out
+// those already cached.
+func (p *Predictor) planTrip(ctx context.Context, userID string, count int) ([]PredictionResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if time.Since(p.lastSync) > p.config.SyncInterval || userID != p.historyUser {
+		if err := p.refreshViewingHistory(ctx, userID); err != nil {
+			return nil, fmt.Errorf("failed to refresh history: %w", err)
+		}
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -tripActiveDays)
+	return p.upcomingEpisodes(cutoff, count, "Trip"), nil
+}
@@ -0,0 +1,115 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+var _ TripSource = (*jellyfin.Client)(nil)
+var _ TripStore = (*storage.Manager)(nil)
+var _ TripLimits = (*storage.CacheManager)(nil)
+
+func TestTripMode(t *testing.T) {
+	predictor, store, queuer := newReconcileTestPredictor(t)
+	cache := storage.NewCacheManager(&config.CacheConfig{MaxSizeGB: 1, EvictionThreshold: 0.9}, store, predictor.logger)
+
+	for episode := 1; episode <= 14; episode++ {
+		id := fmt.Sprintf("e%d", episode)
+		require.NoError(t, store.AddMediaMetadata(&storage.MediaMetadata{
+			ID: id, JellyfinID: id, Type: "episode", SeriesID: "show",
+			SeasonNumber: 1, EpisodeNumber: episode, Size: 1000,
+		}))
+	}
+	require.NoError(t, store.UpsertViewingSession("user", storage.ViewingSession{
+		MediaID: "e1", MediaType: "episode", SeriesID: "show", Season: 1, Episode: 1,
+		StartTime: time.Now().AddDate(0, 0, -10), Completed: true,
+	}))
+	// A favorite movie already cached is protected but not downloaded
+	require.NoError(t, store.AddDownloadRecord(&storage.DownloadRecord{ID: "cached", JellyfinID: "cached", MediaType: "movie", Status: "completed"}))
+
+	source := jellyfintest.New("http://jellyfin.local")
+	source.Favorites = []jellyfin.MediaItem{
+		{ID: "m1", Type: "Movie"},
+		{ID: "cached", Type: "Movie"},
+		{ID: "ep", Type: "Episode"}, // Only favorite movies are picked
+	}
+	trip := NewTripMode(predictor, source, store, queuer, cache, "user", predictor.logger)
+
+	_, err := trip.Start(context.Background(), TripOptions{Until: time.Now().Add(-time.Hour)})
+	assert.Error(t, err, "a trip must end in the future")
+
+	started, err := trip.Start(context.Background(), TripOptions{Until: time.Now().Add(72 * time.Hour), Favorites: true, MaxSizeGB: 50})
+	require.NoError(t, err)
+	assert.Equal(t, defaultTripEpisodes, started.Episodes)
+	assert.Equal(t, 1, started.NormalMaxSizeGB)
+	assert.Len(t, started.MediaIDs, 12, "ten episodes ahead and two favorite movies")
+	assert.Contains(t, started.MediaIDs, "cached")
+	assert.Equal(t, int64(50)*1024*1024*1024, cache.MaxSize())
+	assert.Equal(t, 0.9, cache.EvictionThreshold())
+
+	queued := queuedByMedia(t, store)
+	assert.Len(t, queued, 11)
+	assert.NotContains(t, queued, "cached")
+	assert.Equal(t, tripEpisodePriority, queued["e2"].Priority)
+	assert.Equal(t, tripFavoritePriority, queued["m1"].Priority)
+	for _, item := range queued {
+		assert.Equal(t, SourceTrip, item.Source)
+	}
+
+	// Predictions keep up with the trip whatever the binge rate
+	predictor.mu.Lock()
+	assert.Len(t, predictor.predictUpNext(), 0, "the series was last watched longer ago than a binge")
+	assert.Equal(t, defaultTripEpisodes, predictor.tripEpisodes)
+	predictor.mu.Unlock()
+
+	current, err := trip.Current()
+	require.NoError(t, err)
+	require.NotNil(t, current)
+	assert.Equal(t, started.MediaIDs, current.MediaIDs)
+
+	// A manually queued item stays when the trip ends
+	_, err = queuer.QueueDownloadWithSource(context.Background(), "manual", 1, SourceManual)
+	require.NoError(t, err)
+
+	require.NoError(t, trip.End("cancelled"))
+	assert.Equal(t, int64(1024*1024*1024), cache.MaxSize())
+	assert.Equal(t, 0, predictor.tripEpisodes)
+	queued = queuedByMedia(t, store)
+	assert.Len(t, queued, 1)
+	assert.Contains(t, queued, "manual")
+
+	current, err = trip.Current()
+	require.NoError(t, err)
+	assert.Nil(t, current)
+	require.NoError(t, trip.End("cancelled"), "ending no trip does nothing")
+}
+
+func TestTripModeRestore(t *testing.T) {
+	predictor, store, queuer := newReconcileTestPredictor(t)
+	cache := storage.NewCacheManager(&config.CacheConfig{MaxSizeGB: 1, EvictionThreshold: 0.9}, store, predictor.logger)
+	trip := NewTripMode(predictor, jellyfintest.New("http://jellyfin.local"), store, queuer, cache, "user", predictor.logger)
+
+	require.NoError(t, store.SaveTrip(&storage.Trip{Until: time.Now().Add(time.Hour), Episodes: 8, MaxSizeGB: 20, NormalMaxSizeGB: 1}))
+	require.NoError(t, trip.Restore())
+	assert.Equal(t, int64(20)*1024*1024*1024, cache.MaxSize())
+	assert.Equal(t, 8, predictor.tripEpisodes)
+
+	// A trip that ran out while stopped is ended
+	require.NoError(t, store.SaveTrip(&storage.Trip{Until: time.Now().Add(-time.Hour), Episodes: 8, MaxSizeGB: 20, NormalMaxSizeGB: 1}))
+	require.NoError(t, trip.Restore())
+	assert.Equal(t, int64(1024*1024*1024), cache.MaxSize())
+	assert.Equal(t, 0, predictor.tripEpisodes)
+	saved, err := store.GetTrip()
+	require.NoError(t, err)
+	assert.Nil(t, saved)
+}
@@ -0,0 +1,59 @@
+package downloader
+
+import (
+	"fmt"
+)
+
+// SetWorkers changes how many downloads run at once. Added workers start
+// right away if the manager is running; workers that are no longer needed
+// stop once their current download finishes.
+func (m *Manager) SetWorkers(workers int) error {
+	if workers < 1 {
+		return fmt.Errorf("workers must be at least 1")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.activeMu.Lock()
+	previous := m.workers
+	m.workers = workers
+	m.activeMu.Unlock()
+
+	if m.running {
+		for len(m.workerStops) < workers {
+			m.startWorker()
+		}
+		for len(m.workerStops) > workers {
+			last := len(m.workerStops) - 1
+			m.workerStops[last]()
+			m.workerStops = m.workerStops[:last]
+		}
+	}
+
+	if previous != workers {
+		m.logger.Info("Changed download workers", "from", previous, "to", workers)
+	}
+	return nil
+}
+
+// workerCount returns how many downloads run at once.
+func (m *Manager) workerCount() int {
+	m.activeMu.Lock()
+	defer m.activeMu.Unlock()
+	return m.workers
+}
+
+// SetRateLimit changes the total download rate in megabits per second.
+// In-flight downloads get their new share of it at once.
+func (m *Manager) SetRateLimit(mbps int) error {
+	if mbps < 1 {
+		return fmt.Errorf("rate limit must be at least 1 Mbps")
+	}
+
+	if previous := m.rateLimitMbps.Swap(int64(mbps)); previous != int64(mbps) {
+		m.logger.Info("Changed download rate limit", "from_mbps", previous, "to_mbps", mbps)
+	}
+	m.bandwidth.rebalance()
+	return nil
+}
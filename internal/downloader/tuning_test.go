@@ -0,0 +1,58 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestSetWorkers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	manager := New(&config.DownloadConfig{Workers: 2, RateLimitMbps: 10}, storagetest.New(), logger)
+
+	// Before starting only the count changes
+	require.NoError(t, manager.SetWorkers(3))
+	assert.Empty(t, manager.workerStops)
+
+	require.NoError(t, manager.Start(context.Background()))
+	assert.Len(t, manager.workerStops, 3)
+
+	require.NoError(t, manager.SetWorkers(5))
+	assert.Len(t, manager.workerStops, 5)
+	assert.Equal(t, 5, manager.workerCount())
+
+	require.NoError(t, manager.SetWorkers(1))
+	assert.Len(t, manager.workerStops, 1)
+	status, err := manager.GetStatus()
+	require.NoError(t, err)
+	assert.Equal(t, 1, status["workers"])
+
+	assert.Error(t, manager.SetWorkers(0))
+
+	// Stop waits for every worker, so it only returns once the ones
+	// stopped on their own have exited too
+	require.NoError(t, manager.Stop())
+}
+
+func TestSetRateLimit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	manager := New(&config.DownloadConfig{Workers: 1, RateLimitMbps: 10}, storagetest.New(), logger)
+
+	limiter := manager.bandwidth.register("job", 3)
+	assert.Equal(t, rate.Limit(10*1024*1024/8), limiter.Limit())
+
+	require.NoError(t, manager.SetRateLimit(20))
+	assert.Equal(t, rate.Limit(20*1024*1024/8), manager.currentRateLimit())
+	assert.Equal(t, rate.Limit(20*1024*1024/8), limiter.Limit(), "in-flight downloads get the new rate at once")
+
+	assert.Error(t, manager.SetRateLimit(0))
+	assert.Equal(t, int64(20), manager.rateLimitMbps.Load())
+}
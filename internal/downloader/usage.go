@@ -0,0 +1,207 @@
+package downloader
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxCriticalPriority is the least urgent priority still started once the
+// daily download cap is reached: what is playing and what comes next.
+const maxCriticalPriority = 1
+
+// BandwidthStatus reports how much has been downloaded against the daily
+// cap.
+type BandwidthStatus struct {
+	TodayBytes    int64 `json:"today_bytes"`
+	HourBytes     int64 `json:"hour_bytes"`
+	DailyCapBytes int64 `json:"daily_cap_bytes,omitempty"` // Zero when there is no cap
+	CapReached    bool  `json:"cap_reached"`
+}
+
+// usageMeter counts downloaded bytes per clock hour. Counts are kept in
+// memory as downloads stream in and written to storage by flush, and the
+// running totals for today and this hour are kept alongside for the cap
+// check. It is an io.Writer so it can sit in a download's tee.
+type usageMeter struct {
+	// now is stubbed by tests
+	now func() time.Time
+
+	mu        sync.Mutex
+	pending   map[time.Time]int64 // bytes per hour not yet stored
+	day       time.Time           // local midnight starting the day today counts
+	today     int64
+	hour      time.Time
+	hourBytes int64
+	total     int64 // since the meter was created
+}
+
+func newUsageMeter() *usageMeter {
+	return &usageMeter{
+		now:     time.Now,
+		pending: make(map[time.Time]int64),
+	}
+}
+
+// startOfDay and startOfHour truncate t in its own location, so days and
+// hours follow local time across DST changes.
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func startOfHour(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+}
+
+func (u *usageMeter) Write(p []byte) (int, error) {
+	u.add(int64(len(p)))
+	return len(p), nil
+}
+
+// add counts n bytes downloaded now and returns today's total.
+func (u *usageMeter) add(n int64) int64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.rollLocked(u.now())
+	u.pending[u.hour] += n
+	u.today += n
+	u.hourBytes += n
+	u.total += n
+	return u.today
+}
+
+// rollLocked starts a new day or hour once now has moved past the current
+// one. Callers must hold u.mu.
+func (u *usageMeter) rollLocked(now time.Time) {
+	if day := startOfDay(now); !day.Equal(u.day) {
+		u.day = day
+		u.today = 0
+	}
+	if hour := startOfHour(now); !hour.Equal(u.hour) {
+		u.hour = hour
+		u.hourBytes = 0
+	}
+}
+
+// totals returns the bytes downloaded today and this hour.
+func (u *usageMeter) totals() (today, hour int64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.rollLocked(u.now())
+	return u.today, u.hourBytes
+}
+
+// sinceStart returns the bytes downloaded since the meter was created.
+func (u *usageMeter) sinceStart() int64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.total
+}
+
+// restore seeds today's and this hour's totals from what storage recorded
+// before a restart.
+func (u *usageMeter) restore(store Store) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	now := u.now()
+	u.rollLocked(now)
+
+	usage, err := store.GetBandwidthUsage(u.day, now)
+	if err != nil {
+		return err
+	}
+	for _, hour := range usage {
+		u.today += hour.Bytes
+		if hour.Hour.Equal(u.hour) {
+			u.hourBytes += hour.Bytes
+		}
+	}
+	return nil
+}
+
+// flush writes the counted bytes to storage. Hours that fail to write are
+// kept for the next flush.
+func (u *usageMeter) flush(store Store) error {
+	u.mu.Lock()
+	pending := u.pending
+	u.pending = make(map[time.Time]int64)
+	u.mu.Unlock()
+
+	var firstErr error
+	for hour, bytes := range pending {
+		if err := store.RecordBandwidth(hour, bytes); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to record bandwidth usage: %w", err)
+			}
+			u.mu.Lock()
+			u.pending[hour] += bytes
+			u.mu.Unlock()
+		}
+	}
+	return firstErr
+}
+
+// dailyCapBytes returns download.max_daily_gb in bytes, or 0 for no cap.
+func (m *Manager) dailyCapBytes() int64 {
+	return int64(m.config.MaxDailyGB) * 1024 * 1024 * 1024
+}
+
+// BandwidthStatus returns today's download usage against the daily cap.
+func (m *Manager) BandwidthStatus() BandwidthStatus {
+	today, hour := m.usage.totals()
+	status := BandwidthStatus{
+		TodayBytes:    today,
+		HourBytes:     hour,
+		DailyCapBytes: m.dailyCapBytes(),
+	}
+	status.CapReached = status.DailyCapBytes > 0 && today >= status.DailyCapBytes
+	return status
+}
+
+// DownloadedBytes returns the bytes downloaded since the manager was
+// created, counting every attempt including failed and resumed ones.
+func (m *Manager) DownloadedBytes() int64 {
+	return m.usage.sinceStart()
+}
+
+// dailyCapReached reports whether downloads less urgent than
+// maxCriticalPriority are held back for the rest of the day. The first
+// check to find the cap newly reached, or lifted at midnight, logs it.
+func (m *Manager) dailyCapReached() bool {
+	status := m.BandwidthStatus()
+
+	if status.CapReached != m.capReached.Swap(status.CapReached) {
+		if status.CapReached {
+			m.logger.Warn("Daily download cap reached, holding back downloads past Priority 1",
+				"downloaded_bytes", status.TodayBytes,
+				"cap_bytes", status.DailyCapBytes)
+			m.reportProgress("", 0, "bandwidth_cap", "Daily download cap reached; only current and next items download until midnight")
+		} else {
+			m.logger.Info("Daily download cap reset, resuming downloads")
+		}
+	}
+
+	return status.CapReached
+}
+
+// heldBack reports whether a download at priority waits in storage instead
+// of starting: speculative downloads while the cache disk is unhealthy, and
+// anything past Priority 1 once the daily download cap is reached. Both
+// only hold back less urgent priorities than they let through, so a queue
+// whose head is held back is held back entirely.
+func (m *Manager) heldBack(priority int) bool {
+	if priority > maxCriticalPriority && m.dailyCapReached() {
+		return true
+	}
+	return priority >= 3 && m.speculativePaused()
+}
+
+// flushUsage writes counted download bytes to storage.
+func (m *Manager) flushUsage() {
+	if err := m.usage.flush(m.storage); err != nil {
+		m.logger.Debug("Failed to flush bandwidth usage", "error", err)
+	}
+}
@@ -0,0 +1,73 @@
+package downloader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+)
+
+func TestUsageMeter(t *testing.T) {
+	store := storagetest.New()
+	meter := newUsageMeter()
+	now := time.Date(2024, 3, 9, 23, 30, 0, 0, time.Local)
+	meter.now = func() time.Time { return now }
+
+	meter.Write(make([]byte, 100))
+	require.NoError(t, meter.flush(store))
+	meter.Write(make([]byte, 50))
+
+	// Midnight starts a new day and hour
+	now = now.Add(40 * time.Minute)
+	meter.Write(make([]byte, 30))
+	today, hour := meter.totals()
+	assert.Equal(t, int64(30), today)
+	assert.Equal(t, int64(30), hour)
+
+	require.NoError(t, meter.flush(store))
+	usage, err := store.GetBandwidthUsage(now.Add(-24*time.Hour), now)
+	require.NoError(t, err)
+	require.Len(t, usage, 2)
+	assert.Equal(t, int64(150), usage[0].Bytes)
+	assert.Equal(t, int64(30), usage[1].Bytes)
+
+	// The running total does not reset at midnight
+	assert.Equal(t, int64(180), meter.sinceStart())
+
+	// A restarted meter picks up today's usage from storage
+	restarted := newUsageMeter()
+	restarted.now = meter.now
+	require.NoError(t, restarted.restore(store))
+	today, hour = restarted.totals()
+	assert.Equal(t, int64(30), today)
+	assert.Equal(t, int64(30), hour)
+}
+
+func TestDailyCapHoldsBackDownloads(t *testing.T) {
+	manager, store := newLimitedManager(t)
+	manager.config.MaxDailyGB = 1
+
+	manager.usage.add(1<<30 - 1)
+	assert.False(t, manager.BandwidthStatus().CapReached)
+	assert.False(t, manager.heldBack(4))
+
+	manager.usage.add(1)
+	status := manager.BandwidthStatus()
+	assert.True(t, status.CapReached)
+	assert.Equal(t, int64(1<<30), status.DailyCapBytes)
+	assert.True(t, manager.heldBack(2))
+	assert.False(t, manager.heldBack(1))
+
+	// Held back jobs wait in storage; urgent ones still go to the workers
+	require.NoError(t, manager.AddJob(&DownloadJob{ID: "later", MediaID: "later", Priority: 2, CreatedAt: time.Now()}))
+	assert.Equal(t, 0, manager.jobs.len())
+	require.NoError(t, manager.AddJob(&DownloadJob{ID: "next", MediaID: "next", Priority: 1, CreatedAt: time.Now()}))
+	assert.Equal(t, 1, manager.jobs.len())
+
+	items, err := store.GetQueueItems("queued")
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+}
@@ -0,0 +1,61 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+)
+
+// VariantSelector chooses between downloading an item's original file and
+// a server-side transcode (implemented by jellyfin.Client).
+type VariantSelector interface {
+	SelectVariant(ctx context.Context, mediaID, preference string) (*jellyfin.Variant, error)
+}
+
+// SetVariantSelector makes queued downloads fetch what selector picks for
+// them: the original, or a transcode at preference (ui.video_quality_preference)
+// when that is smaller. Callers asking for a specific quality override
+// preference. Without a selector jobs are queued without a URL, for the
+// caller to fill in.
+func (m *Manager) SetVariantSelector(selector VariantSelector, preference string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.variants = selector
+	m.qualityPreference = preference
+}
+
+// selectVariant picks what to download for mediaID and fills in the job's
+// URL, quality, container and bitrate. The job's size is replaced by the
+// variant's when that is known, so capacity checks see what will actually
+// be stored. It leaves the job untouched without a selector.
+func (m *Manager) selectVariant(ctx context.Context, job *DownloadJob) error {
+	m.mu.RLock()
+	selector := m.variants
+	preference := m.qualityPreference
+	m.mu.RUnlock()
+
+	if selector == nil {
+		return nil
+	}
+	if job.Quality != "" {
+		preference = job.Quality
+	}
+
+	variant, err := selector.SelectVariant(ctx, job.MediaID, preference)
+	if err != nil {
+		return fmt.Errorf("failed to select download variant: %w", err)
+	}
+
+	job.URL = variant.URL
+	job.Quality = ""
+	if !variant.IsOriginal() {
+		job.Quality = variant.Quality
+	}
+	job.Container = variant.Container
+	job.Bitrate = variant.Bitrate
+	if variant.Size > 0 {
+		job.Size = variant.Size
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+)
+
+// fakeSelector transcodes everything except preference "original", and
+// records the preference it was asked for.
+type fakeSelector struct {
+	preferences []string
+	err         error
+}
+
+func (f *fakeSelector) SelectVariant(ctx context.Context, mediaID, preference string) (*jellyfin.Variant, error) {
+	f.preferences = append(f.preferences, preference)
+	if f.err != nil {
+		return nil, f.err
+	}
+	if preference == "original" {
+		return &jellyfin.Variant{Quality: "original", URL: "http://jf/" + mediaID, Container: "mkv", Bitrate: 20_000_000, Size: 4000}, nil
+	}
+	return &jellyfin.Variant{Quality: preference, URL: "http://jf/" + mediaID + "/" + preference, Container: "mp4", Bitrate: 4_000_000, Size: 800}, nil
+}
+
+func TestQueueDownloadSelectsVariant(t *testing.T) {
+	manager, store := newLimitedManager(t)
+	selector := &fakeSelector{}
+	manager.SetVariantSelector(selector, "720p")
+	ctx := context.Background()
+
+	_, err := manager.QueueDownload(ctx, "a", 3)
+	require.NoError(t, err)
+	item, err := store.FindActiveQueueItem("a")
+	require.NoError(t, err)
+	assert.Equal(t, "http://jf/a/720p", item.URL)
+	assert.Equal(t, "720p", item.Quality)
+	assert.Equal(t, "mp4", item.Container)
+	assert.Equal(t, 4_000_000, item.Bitrate)
+	assert.Equal(t, int64(800), item.Size, "the transcode's size is what gets cached")
+
+	// A requested quality overrides the preference, and the original is
+	// recorded with an empty quality
+	_, err = manager.QueueDownloadWithQuality(ctx, "b", 3, SourcePrediction, "original")
+	require.NoError(t, err)
+	item, err = store.FindActiveQueueItem("b")
+	require.NoError(t, err)
+	assert.Equal(t, "http://jf/b", item.URL)
+	assert.Empty(t, item.Quality)
+	assert.Equal(t, "mkv", item.Container)
+	assert.Equal(t, []string{"720p", "original"}, selector.preferences)
+
+	selector.err = errors.New("jellyfin unreachable")
+	_, err = manager.QueueDownload(ctx, "c", 3)
+	assert.ErrorContains(t, err, "failed to select download variant")
+}
@@ -0,0 +1,49 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// ErrChecksumMismatch is returned when a finished download does not match
+// the checksum expected for it. The file is discarded and the download
+// retried from the start.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// downloadHasher returns the hash a download is checksummed with while it
+// streams in, and its algorithm. Jobs with an expected checksum use its
+// algorithm even when checksums are turned off, so they can be verified;
+// the rest use the configured one. The hash is nil when there is nothing
+// to compute.
+func (m *Manager) downloadHasher(job *DownloadJob) (hash.Hash, string, error) {
+	algorithm := m.storage.ChecksumAlgorithm()
+	if job.Checksum != "" {
+		algorithm = job.ChecksumAlgorithm
+		if algorithm == "" {
+			algorithm = storage.ChecksumSHA256
+		}
+	}
+	if algorithm == storage.ChecksumOff {
+		return nil, algorithm, nil
+	}
+
+	hasher, err := storage.NewChecksumHash(algorithm)
+	if err != nil {
+		return nil, "", err
+	}
+	return hasher, algorithm, nil
+}
+
+// verifyChecksum compares a finished download's checksum with the one
+// expected for the job, if any. On a mismatch the partial file is removed
+// so the retry starts over instead of resuming corrupt bytes.
+func (m *Manager) verifyChecksum(job *DownloadJob, checksum string) error {
+	if job.Checksum == "" || checksum == job.Checksum {
+		return nil
+	}
+	err := fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, job.Checksum, checksum)
+	return m.discardPartial(job, err)
+}
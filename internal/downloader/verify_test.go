@@ -0,0 +1,67 @@
+package downloader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+func TestDownloadChecksumVerification(t *testing.T) {
+	content := bytes.Repeat([]byte("verified "), 10000)
+	sum := sha256.Sum256(content)
+	expected := hex.EncodeToString(sum[:])
+
+	corrupt := true
+	manager, store, job := newResumeTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		body := content
+		if corrupt {
+			body = bytes.Replace(content, []byte("verified"), []byte("vErified"), 1)
+		}
+		http.ServeContent(w, r, "movie.mkv", time.Time{}, bytes.NewReader(body))
+	})
+	// Checksums are off, but a job with an expected checksum is still verified
+	store.Checksum = storage.ChecksumOff
+	job.Checksum = expected
+	item, err := store.FindActiveQueueItem("m1")
+	require.NoError(t, err)
+	item.Checksum = expected
+	require.NoError(t, store.UpdateQueueItem(item))
+
+	result := manager.processJob(job)
+	require.False(t, result.Success)
+	require.ErrorIs(t, result.Error, ErrChecksumMismatch)
+	assert.Zero(t, result.BytesDownloaded)
+	_, err = os.Stat(storage.PartialPath(job.LocalPath))
+	assert.True(t, os.IsNotExist(err), "a corrupt download is not kept to resume from")
+	_, err = os.Stat(job.LocalPath)
+	assert.True(t, os.IsNotExist(err), "a corrupt download is never committed")
+
+	// The mismatch is retried with the expected checksum kept
+	manager.handleResult(result)
+	item, err = store.FindActiveQueueItem("m1")
+	require.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "queued", item.Status)
+	assert.Equal(t, expected, item.Checksum)
+
+	corrupt = false
+	job.RetryCount = item.RetryCount
+	result = manager.processJob(job)
+	require.True(t, result.Success, "error: %v", result.Error)
+	assert.Equal(t, expected, result.Checksum)
+	assert.Equal(t, storage.ChecksumSHA256, result.ChecksumAlgorithm)
+
+	manager.handleResult(result)
+	record, err := store.GetDownload("m1")
+	require.NoError(t, err)
+	assert.Equal(t, expected, record.Checksum, "the verified checksum is stored for integrity scans")
+}
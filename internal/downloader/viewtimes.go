@@ -0,0 +1,115 @@
+package downloader
+
+import (
+	"sort"
+	"time"
+)
+
+// ViewingWindowSetter is implemented by download queuers that schedule
+// speculative downloads around the times users usually watch (implemented
+// by Manager).
+type ViewingWindowSetter interface {
+	SetViewingWindows(userID string, windows []TimeWindow)
+}
+
+// SetViewingWindows records when userID usually starts watching. With
+// download.watch_time_scheduling enabled, large Priority 3-4 downloads wait
+// outside the windows of every user, which get the full bandwidth, and
+// the peak hour limit applies during the windows instead of the
+// configured peak hours.
+func (m *Manager) SetViewingWindows(userID string, windows []TimeWindow) {
+	m.windowsMu.Lock()
+	defer m.windowsMu.Unlock()
+
+	if m.windows == nil {
+		m.windows = make(map[string][]TimeWindow)
+	}
+	if len(windows) == 0 {
+		delete(m.windows, userID)
+		return
+	}
+	m.windows[userID] = append([]TimeWindow(nil), windows...)
+}
+
+// inViewingWindow reports whether t falls in any user's viewing window,
+// and whether any windows are known at all.
+func (m *Manager) inViewingWindow(t time.Time) (inside, known bool) {
+	m.windowsMu.Lock()
+	defer m.windowsMu.Unlock()
+
+	hour := t.Hour()
+	for _, windows := range m.windows {
+		for _, window := range windows {
+			known = true
+			if window.contains(hour) {
+				return true, true
+			}
+		}
+	}
+	return false, known
+}
+
+// watchTimeScheduled reports whether learned viewing windows replace the
+// configured peak hours.
+func (m *Manager) watchTimeScheduled() bool {
+	if !m.config.WatchTimeScheduling {
+		return false
+	}
+	_, known := m.inViewingWindow(time.Now())
+	return known
+}
+
+// waitsForOffWindow reports whether a speculative download is large
+// enough to wait until the current viewing window ends, so it runs at full
+// bandwidth and is done before the next one rather than competing with
+// playback. Downloads of unknown size count as large.
+func (m *Manager) waitsForOffWindow(priority int, size int64) bool {
+	if !m.config.WatchTimeScheduling || priority < 3 {
+		return false
+	}
+	if size > 0 && size < int64(m.config.LargeDownloadMB)*1024*1024 {
+		return false
+	}
+	inside, _ := m.inViewingWindow(time.Now())
+	return inside
+}
+
+// contains reports whether hour lies in the window. Windows may wrap past
+// midnight (e.g. 22 to 1).
+func (w TimeWindow) contains(hour int) bool {
+	if w.StartHour <= w.EndHour {
+		return hour >= w.StartHour && hour <= w.EndHour
+	}
+	return hour >= w.StartHour || hour <= w.EndHour
+}
+
+// viewingWindows merges consecutive preferred start hours into windows,
+// joining 23 and 0 across midnight. counts gives how many sessions started
+// in each hour.
+func viewingWindows(hours []int, counts map[int]int) []TimeWindow {
+	if len(hours) == 0 {
+		return []TimeWindow{}
+	}
+
+	sorted := append([]int(nil), hours...)
+	sort.Ints(sorted)
+
+	var windows []TimeWindow
+	for _, hour := range sorted {
+		if n := len(windows); n > 0 && windows[n-1].EndHour == hour-1 {
+			windows[n-1].EndHour = hour
+			windows[n-1].Frequency += counts[hour]
+			continue
+		}
+		windows = append(windows, TimeWindow{StartHour: hour, EndHour: hour, Frequency: counts[hour]})
+	}
+
+	// A window ending at 23 continues into one starting at 0
+	if n := len(windows); n > 1 && windows[0].StartHour == 0 && windows[n-1].EndHour == 23 {
+		windows[n-1].EndHour = windows[0].EndHour
+		windows[n-1].Frequency += windows[0].Frequency
+		windows = windows[1:]
+	}
+
+	return windows
+}
@@ -0,0 +1,74 @@
+package downloader
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestViewingWindows(t *testing.T) {
+	counts := map[int]int{0: 2, 19: 3, 20: 5, 21: 4, 23: 1}
+	windows := viewingWindows([]int{21, 19, 20, 23, 0}, counts)
+
+	assert.Equal(t, []TimeWindow{
+		{StartHour: 19, EndHour: 21, Frequency: 12},
+		{StartHour: 23, EndHour: 0, Frequency: 3},
+	}, windows)
+
+	assert.True(t, windows[1].contains(23))
+	assert.True(t, windows[1].contains(0))
+	assert.False(t, windows[1].contains(1))
+	assert.Empty(t, viewingWindows(nil, nil))
+}
+
+func TestWatchTimeScheduling(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.DownloadConfig{
+		Workers: 1, RateLimitMbps: 80, LargeDownloadMB: 1024,
+		RateLimitSchedule: config.RateLimitScheduleConfig{PeakHours: "00:00-23:59", PeakLimitPercent: 25},
+	}
+	m := New(cfg, storagetest.New(), logger)
+
+	hour := time.Now().Hour()
+	now := TimeWindow{StartHour: hour, EndHour: hour}
+	later := TimeWindow{StartHour: (hour + 2) % 24, EndHour: (hour + 3) % 24}
+	large := int64(2 << 30)
+
+	// Disabled: windows change nothing
+	m.SetViewingWindows("alice", []TimeWindow{now})
+	assert.False(t, m.waitsForOffWindow(4, large))
+	assert.Equal(t, rate.Limit(80*1024*1024/8/4), m.currentRateLimit())
+
+	cfg.WatchTimeScheduling = true
+
+	// Inside a window: large speculative downloads wait, the rest start,
+	// and the peak limit applies
+	assert.True(t, m.waitsForOffWindow(3, large))
+	assert.True(t, m.waitsForOffWindow(4, 0), "unknown sizes count as large")
+	assert.False(t, m.waitsForOffWindow(4, 100<<20))
+	assert.False(t, m.waitsForOffWindow(2, large))
+	assert.Equal(t, rate.Limit(80*1024*1024/8/4), m.currentRateLimit())
+
+	// Outside every user's window: full bandwidth despite the peak hours
+	m.SetViewingWindows("alice", []TimeWindow{later})
+	m.SetViewingWindows("bob", []TimeWindow{later})
+	assert.False(t, m.waitsForOffWindow(4, large))
+	assert.Equal(t, rate.Limit(80*1024*1024/8), m.currentRateLimit())
+
+	// Any household user's window counts
+	m.SetViewingWindows("bob", []TimeWindow{now})
+	assert.True(t, m.waitsForOffWindow(4, large))
+
+	// Without learned windows the configured peak hours apply again
+	m.SetViewingWindows("alice", nil)
+	m.SetViewingWindows("bob", nil)
+	assert.False(t, m.waitsForOffWindow(4, large))
+	assert.Equal(t, rate.Limit(80*1024*1024/8/4), m.currentRateLimit())
+}
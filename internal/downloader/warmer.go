@@ -0,0 +1,184 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// Priorities warmed items are queued at: Next Up matches the predictor's
+// "up next" class, favorites are speculative.
+const (
+	warmNextUpPriority   = 2
+	warmFavoritePriority = 3
+)
+
+// WarmSource lists the Jellyfin items the cache warmers keep cached
+// (implemented by jellyfin.Client).
+type WarmSource interface {
+	GetNextUp(ctx context.Context, limit int) ([]jellyfin.MediaItem, error)
+	GetFavorites(ctx context.Context) ([]jellyfin.MediaItem, error)
+}
+
+// Warmer deterministically caches the user's Jellyfin Next Up list and,
+// optionally, their favorites. Unlike the predictor it needs no viewing
+// history, so it is useful while the predictor is still learning.
+type Warmer struct {
+	source  WarmSource
+	storage storage.Store
+	queuer  DownloadQueuer
+	config  *config.PredictionConfig
+	logger  *slog.Logger
+
+	// mu serializes warm passes so two cannot queue the same items
+	mu sync.Mutex
+}
+
+// NewWarmer creates a cache warmer that queues through queuer.
+func NewWarmer(source WarmSource, storage storage.Store, queuer DownloadQueuer, cfg *config.PredictionConfig, logger *slog.Logger) *Warmer {
+	return &Warmer{
+		source:  source,
+		storage: storage,
+		queuer:  queuer,
+		config:  cfg,
+		logger:  logger,
+	}
+}
+
+// Enabled reports whether any warmer is turned on.
+func (w *Warmer) Enabled() bool {
+	return w.config.WarmNextUp || w.config.WarmFavorites
+}
+
+// Warm fetches the enabled lists and reconciles the queue against them:
+// new items are queued, warmed items whose priority changed are
+// re-prioritized, and queued warmed items that dropped off every list are
+// cancelled. Items queued by anything else are left alone.
+func (w *Warmer) Warm(ctx context.Context) (*ReconcileSummary, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	wanted := make(map[string]int)
+	if w.config.WarmNextUp {
+		items, err := w.source.GetNextUp(ctx, w.config.NextUpLimit)
+		if err != nil {
+			return nil, err
+		}
+		addWanted(wanted, items, warmNextUpPriority)
+	}
+	if w.config.WarmFavorites {
+		items, err := w.source.GetFavorites(ctx)
+		if err != nil {
+			return nil, err
+		}
+		addWanted(wanted, items, warmFavoritePriority)
+	}
+
+	queued, err := w.storage.GetQueueItems("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queue: %w", err)
+	}
+
+	summary := &ReconcileSummary{}
+	for _, item := range queued {
+		if item.Status != "queued" && item.Status != "downloading" {
+			continue
+		}
+
+		priority, stillWanted := wanted[item.MediaID]
+		delete(wanted, item.MediaID)
+
+		if item.Source != SourceWarmer || item.Status != "queued" {
+			summary.Unchanged++
+			continue
+		}
+
+		if !stillWanted {
+			if err := w.storage.RemoveQueueItem(item.ID); err != nil {
+				w.logger.Warn("Failed to cancel warmed download",
+					"job_id", item.ID, "media_id", item.MediaID, "error", err)
+				continue
+			}
+			summary.Cancelled++
+			continue
+		}
+
+		if priority != item.Priority {
+			if err := w.storage.UpdateQueueItemPriority(item.ID, priority); err != nil {
+				w.logger.Warn("Failed to re-prioritize warmed download",
+					"job_id", item.ID, "media_id", item.MediaID, "error", err)
+				continue
+			}
+			summary.Reprioritized++
+			continue
+		}
+
+		summary.Unchanged++
+	}
+
+	for mediaID, priority := range wanted {
+		if cached, err := w.storage.IsMediaCached(mediaID); err == nil && cached {
+			continue
+		}
+		if _, err := w.queuer.QueueDownloadWithSource(ctx, mediaID, priority, SourceWarmer); err != nil {
+			w.logger.Warn("Failed to queue warmed download",
+				"media_id", mediaID, "error", err)
+			continue
+		}
+		summary.Added++
+	}
+
+	w.logger.Info("Cache warming complete",
+		"added", summary.Added,
+		"reprioritized", summary.Reprioritized,
+		"cancelled", summary.Cancelled,
+		"unchanged", summary.Unchanged)
+
+	return summary, nil
+}
+
+// Run warms the cache immediately and then every sync interval until ctx
+// is cancelled. It is a no-op when no warmer is enabled.
+func (w *Warmer) Run(ctx context.Context) {
+	if !w.Enabled() {
+		return
+	}
+
+	interval := w.config.SyncInterval
+	if interval <= 0 {
+		interval = 4 * time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.Warm(ctx); err != nil {
+			w.logger.Error("Cache warming failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// addWanted records the cacheable items at priority, keeping the more
+// urgent priority for items on several lists.
+func addWanted(wanted map[string]int, items []jellyfin.MediaItem, priority int) {
+	for _, item := range items {
+		if jellyfin.CacheMediaType(item.Type) == "" {
+			continue
+		}
+		if existing, ok := wanted[item.ID]; !ok || priority < existing {
+			wanted[item.ID] = priority
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+var _ WarmSource = (*jellyfin.Client)(nil)
+var _ WarmSource = (*jellyfintest.Mock)(nil)
+
+func queuedPriorities(t *testing.T, store storage.QueueStore) map[string]int {
+	items, err := store.GetQueueItems("queued")
+	require.NoError(t, err)
+	priorities := make(map[string]int, len(items))
+	for _, item := range items {
+		priorities[item.MediaID] = item.Priority
+	}
+	return priorities
+}
+
+func TestWarmer(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	manager := New(&config.DownloadConfig{Workers: 1}, store, logger)
+	manager.running = true
+
+	source := jellyfintest.New("http://jellyfin.local")
+	source.NextUp = []jellyfin.MediaItem{
+		{ID: "e1", Type: "Episode"},
+		{ID: "e2", Type: "Episode"},
+	}
+	source.Favorites = []jellyfin.MediaItem{
+		{ID: "m1", Type: "Movie"},
+		{ID: "e2", Type: "Episode"},
+		{ID: "s1", Type: "Series"},
+	}
+	cfg := &config.PredictionConfig{WarmNextUp: true, NextUpLimit: 10}
+	warmer := NewWarmer(source, store, manager, cfg, logger)
+
+	// A manually queued item is never touched by the warmer
+	_, err := manager.QueueDownload(context.Background(), "manual", 3)
+	require.NoError(t, err)
+
+	summary, err := warmer.Warm(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.Added)
+	assert.Equal(t, map[string]int{"e1": 2, "e2": 2, "manual": 3}, queuedPriorities(t, store))
+
+	// Favorites add speculative items; Next Up keeps precedence
+	cfg.WarmFavorites = true
+	summary, err = warmer.Warm(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Added)
+	assert.Equal(t, map[string]int{"e1": 2, "e2": 2, "m1": 3, "manual": 3}, queuedPriorities(t, store))
+
+	// Watching e1 drops it from Next Up; e2 now only remains a favorite
+	source.NextUp = nil
+	summary, err = warmer.Warm(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Cancelled)
+	assert.Equal(t, 1, summary.Reprioritized)
+	assert.Equal(t, map[string]int{"e2": 3, "m1": 3, "manual": 3}, queuedPriorities(t, store))
+
+	// Jellyfin errors leave the queue alone
+	source.Err = errors.New("jellyfin unavailable")
+	_, err = warmer.Warm(context.Background())
+	assert.Error(t, err)
+	assert.Len(t, queuedPriorities(t, store), 3)
+}
+
+func TestWarmerDisabled(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	warmer := NewWarmer(jellyfintest.New(""), storagetest.New(), &recordingQueuer{}, &config.PredictionConfig{}, logger)
+	assert.False(t, warmer.Enabled())
+
+	// Run returns immediately rather than blocking on the sync interval
+	warmer.Run(context.Background())
+}
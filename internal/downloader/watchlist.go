@@ -0,0 +1,90 @@
+package downloader
+
+import (
+	"context"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+)
+
+// watchlistConfidence is the confidence of watchlist predictions: the user
+// asked for these, but not to watch them next.
+const watchlistConfidence = 0.9
+
+// defaultWatchlistPriority is the priority of watchlist predictions when
+// prediction.watchlist_priority is not set.
+const defaultWatchlistPriority = 3
+
+// WatchlistSource lists the favorites of the configured Jellyfin user, who
+// keeps them as a watchlist (implemented by jellyfin.Client). A metadata
+// source that also implements it enables watchlist predictions.
+type WatchlistSource interface {
+	GetFavorites(ctx context.Context) ([]jellyfin.MediaItem, error)
+}
+
+// refreshWatchlist pulls the favorites along with the viewing history.
+// When the pull fails the previous watchlist is kept. Callers must hold
+// p.mu.
+func (p *Predictor) refreshWatchlist(ctx context.Context) {
+	source, ok := p.metadata.(WatchlistSource)
+	if !ok {
+		return
+	}
+
+	items, err := source.GetFavorites(ctx)
+	if err != nil {
+		p.logger.Warn("Failed to pull watchlist", "error", err)
+		return
+	}
+	p.watchlist = items
+	p.logger.Debug("Watchlist refreshed", "items", len(items))
+}
+
+// predictWatchlist predicts the unwatched items on the watchlist at
+// prediction.watchlist_priority. They form their own tier, ranked ahead of
+// recently added content at the same priority (see filterPredictions).
+func (p *Predictor) predictWatchlist() []PredictionResult {
+	var predictions []PredictionResult
+
+	if len(p.watchlist) == 0 {
+		return predictions
+	}
+	priority := p.config.WatchlistPriority
+	if priority <= 0 {
+		priority = defaultWatchlistPriority
+	}
+
+	watched := make(map[string]bool, len(p.viewingHistory))
+	for _, session := range p.viewingHistory {
+		if session.Completed {
+			watched[session.MediaID] = true
+		}
+	}
+
+	for _, item := range p.watchlist {
+		mediaType := jellyfin.CacheMediaType(item.Type)
+		if mediaType == "" || watched[item.ID] || (item.UserData != nil && item.UserData.Played) {
+			continue
+		}
+		if checkLibrary(p.storage, p.libraries, item.ID) != nil {
+			continue
+		}
+		if cached, err := p.storage.IsMediaCached(item.ID); err != nil || cached {
+			continue
+		}
+
+		predictions = append(predictions, PredictionResult{
+			MediaID:       item.ID,
+			Priority:      priority,
+			Confidence:    watchlistConfidence,
+			Reason:        "On your watchlist",
+			SeriesID:      item.SeriesID,
+			Season:        item.SeasonNumber,
+			Episode:       item.EpisodeNumber,
+			MediaType:     mediaType,
+			EstimatedSize: item.Size,
+			Watchlist:     true,
+		})
+	}
+
+	return predictions
+}
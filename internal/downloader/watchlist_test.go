@@ -0,0 +1,65 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+var _ WatchlistSource = (*jellyfin.Client)(nil)
+var _ WatchlistSource = (*jellyfintest.Mock)(nil)
+
+func TestPredictWatchlist(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	predictor := NewPredictor(store, &config.PredictionConfig{HistoryDays: 30, MinConfidence: 0.7, WatchlistPriority: 2}, logger)
+
+	source := jellyfintest.New("http://jellyfin.local")
+	source.Favorites = []jellyfin.MediaItem{
+		{ID: "dune", Type: "Movie", Size: 4 << 30},
+		{ID: "played", Type: "Movie", UserData: &jellyfin.UserData{Played: true}},
+		{ID: "seen", Type: "Movie"},
+		{ID: "cached", Type: "Movie"},
+		{ID: "show", Type: "Series"}, // Not cacheable itself
+	}
+	predictor.SetMetadataSource(source)
+	predictor.viewingHistory = []ViewingSession{{MediaID: "seen", Completed: true}}
+	require.NoError(t, store.AddDownloadRecord(&storage.DownloadRecord{
+		ID: "cached", JellyfinID: "cached", MediaType: "movie", Status: "completed",
+	}))
+
+	assert.Empty(t, predictor.predictWatchlist(), "nothing before the watchlist is pulled")
+
+	predictor.refreshWatchlist(context.Background())
+	predictions := predictor.predictWatchlist()
+	require.Len(t, predictions, 1)
+	assert.Equal(t, "dune", predictions[0].MediaID)
+	assert.Equal(t, 2, predictions[0].Priority)
+	assert.Equal(t, "movie", predictions[0].MediaType)
+	assert.Equal(t, int64(4<<30), predictions[0].EstimatedSize)
+	assert.True(t, predictions[0].Watchlist)
+
+	// Watchlist items rank ahead of more confident ones at their priority
+	filtered := predictor.filterPredictions([]PredictionResult{
+		{MediaID: "new", Priority: 2, Confidence: 0.95},
+		predictions[0],
+		{MediaID: "next", Priority: 1, Confidence: 0.8},
+	})
+	require.Len(t, filtered, 3)
+	assert.Equal(t, []string{"next", "dune", "new"}, []string{filtered[0].MediaID, filtered[1].MediaID, filtered[2].MediaID})
+
+	// A failed pull keeps the previous watchlist
+	source.Err = assert.AnError
+	predictor.refreshWatchlist(context.Background())
+	assert.Len(t, predictor.predictWatchlist(), 1)
+}
@@ -0,0 +1,211 @@
+// Package hls repackages cached media files as HTTP Live Streaming, for
+// clients such as iOS Safari that cannot play Matroska and other
+// containers progressively.
+//
+// Design Philosophy:
+//   - Repackaging is on demand: nothing is done until a client asks for HLS
+//   - Video is remuxed, never re-encoded, into fMP4 segments; only audio is
+//     converted, to AAC, when the source codec is not playable
+//   - Segments are cached next to the media file and evicted with it
+//   - Playback starts as soon as the first segment exists, while the rest
+//     are still being written
+package hls
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// PlaylistName is the media playlist a Packager writes into its output
+// directory.
+const PlaylistName = "index.m3u8"
+
+// pollInterval is how often Prepare checks whether the first segment of a
+// running repackaging is ready.
+const pollInterval = 100 * time.Millisecond
+
+// Packager remuxes a media file into HLS.
+type Packager interface {
+	// Package writes the HLS repackaging of input into outDir: PlaylistName,
+	// an init segment and media segments. The playlist is written as
+	// segments are finished, so it can be played before Package returns.
+	Package(ctx context.Context, input, outDir string) error
+}
+
+// FFmpeg is a Packager that runs the ffmpeg command.
+type FFmpeg struct {
+	path            string
+	segmentDuration time.Duration
+}
+
+// NewFFmpeg creates a Packager running the ffmpeg binary at path, cutting
+// segments of about segmentDuration.
+func NewFFmpeg(path string, segmentDuration time.Duration) *FFmpeg {
+	return &FFmpeg{path: path, segmentDuration: segmentDuration}
+}
+
+// Package implements Packager.
+func (f *FFmpeg) Package(ctx context.Context, input, outDir string) error {
+	output, err := exec.CommandContext(ctx, f.path, f.args(input, outDir)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg failed: %w: %s", err, bytes.TrimSpace(output))
+	}
+	return nil
+}
+
+// args returns the ffmpeg arguments repackaging input into outDir.
+func (f *FFmpeg) args(input, outDir string) []string {
+	return []string{
+		"-nostdin", "-hide_banner", "-loglevel", "error", "-y",
+		"-i", input,
+		// The first video and audio track; subtitles are served separately
+		"-map", "0:v:0", "-map", "0:a:0?",
+		"-c:v", "copy", "-c:a", "aac",
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(max(int(f.segmentDuration.Seconds()), 1)),
+		"-hls_playlist_type", "event",
+		"-hls_segment_type", "fmp4",
+		"-hls_fmp4_init_filename", "init.mp4",
+		"-hls_segment_filename", filepath.Join(outDir, "seg-%05d.m4s"),
+		filepath.Join(outDir, PlaylistName),
+	}
+}
+
+// Repackager runs a Packager on cached files when they are first asked for
+// as HLS, once per file however many clients ask at the same time.
+type Repackager struct {
+	packager Packager
+	logger   *slog.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+// job is a running repackaging.
+type job struct {
+	dir  string
+	done chan struct{}
+	err  error
+}
+
+// New creates a Repackager using packager.
+func New(packager Packager, logger *slog.Logger) *Repackager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Repackager{
+		packager: packager,
+		logger:   logger,
+		ctx:      ctx,
+		cancel:   cancel,
+		jobs:     make(map[string]*job),
+	}
+}
+
+// Prepare makes the HLS repackaging of the cached file at mediaPath ready
+// to play from storage.HLSDir(mediaPath). It returns once the repackaging
+// is complete or, if it has to be done now, once its first segment is
+// written; the rest is written in the background.
+func (r *Repackager) Prepare(ctx context.Context, mediaPath string) error {
+	if storage.HLSComplete(mediaPath) {
+		return nil
+	}
+
+	j, err := r.start(mediaPath)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		if playable(j.dir) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-j.done:
+			return j.err
+		case <-ticker.C:
+		}
+	}
+}
+
+// start returns the running repackaging of mediaPath, starting it if there
+// is none.
+func (r *Repackager) start(mediaPath string) (*job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if j, ok := r.jobs[mediaPath]; ok {
+		return j, nil
+	}
+	if r.ctx.Err() != nil {
+		return nil, errors.New("repackager stopped")
+	}
+
+	// Whatever is there was left by a repackaging that never finished
+	dir := storage.HLSDir(mediaPath)
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to clear HLS directory: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create HLS directory: %w", err)
+	}
+
+	j := &job{dir: dir, done: make(chan struct{})}
+	r.jobs[mediaPath] = j
+	r.wg.Add(1)
+	go r.run(mediaPath, j)
+	return j, nil
+}
+
+// run repackages mediaPath and marks the result complete, or removes it if
+// the packager fails.
+func (r *Repackager) run(mediaPath string, j *job) {
+	defer r.wg.Done()
+
+	started := time.Now()
+	err := r.packager.Package(r.ctx, mediaPath, j.dir)
+	if err == nil {
+		err = storage.MarkHLSComplete(mediaPath)
+	}
+	if err != nil {
+		r.logger.Warn("Failed to repackage as HLS", "path", mediaPath, "error", err)
+		os.RemoveAll(j.dir)
+	} else {
+		r.logger.Info("Repackaged as HLS", "path", mediaPath, "duration", time.Since(started))
+	}
+
+	r.mu.Lock()
+	delete(r.jobs, mediaPath)
+	j.err = err
+	close(j.done)
+	r.mu.Unlock()
+}
+
+// Stop cancels running repackagings and waits for them to clean up.
+func (r *Repackager) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// playable reports whether the playlist in dir lists at least one segment.
+func playable(dir string) bool {
+	data, err := os.ReadFile(filepath.Join(dir, PlaylistName))
+	return err == nil && bytes.Contains(data, []byte("#EXTINF"))
+}
@@ -0,0 +1,189 @@
+package hls
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// fakePackager writes a playlist with one segment, then waits to be
+// released before finishing it.
+type fakePackager struct {
+	calls   atomic.Int32
+	release chan struct{}
+	err     error
+}
+
+func (f *fakePackager) Package(ctx context.Context, input, outDir string) error {
+	f.calls.Add(1)
+	playlist := "#EXTM3U\n#EXT-X-MAP:URI=\"init.mp4\"\n#EXTINF:6.0,\nseg-00000.m4s\n"
+	if err := os.WriteFile(filepath.Join(outDir, PlaylistName), []byte(playlist), 0644); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-f.release:
+	}
+	if f.err != nil {
+		return f.err
+	}
+	return os.WriteFile(filepath.Join(outDir, PlaylistName), []byte(playlist+"#EXT-X-ENDLIST\n"), 0644)
+}
+
+func newTestRepackager(t *testing.T, packager Packager) *Repackager {
+	t.Helper()
+	r := New(packager, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.Cleanup(r.Stop)
+	return r
+}
+
+func writeMedia(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "movie.mkv")
+	if err := os.WriteFile(path, []byte("video"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	return path
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("Timed out waiting for %s", what)
+}
+
+func TestPrepare(t *testing.T) {
+	packager := &fakePackager{release: make(chan struct{})}
+	r := newTestRepackager(t, packager)
+	path := writeMedia(t)
+
+	// A stale directory from an interrupted run is replaced
+	if err := os.MkdirAll(storage.HLSDir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(storage.HLSDir(path), "seg-00099.m4s"), nil, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	// Clients asking at the same time share one repackaging, and return as
+	// soon as the first segment is listed
+	errs := make(chan error, 3)
+	for range 3 {
+		go func() { errs <- r.Prepare(context.Background(), path) }()
+	}
+	for range 3 {
+		if err := <-errs; err != nil {
+			t.Fatalf("Failed to prepare: %v", err)
+		}
+	}
+	if calls := packager.calls.Load(); calls != 1 {
+		t.Errorf("Expected one repackaging, got %d", calls)
+	}
+	if _, err := os.Stat(filepath.Join(storage.HLSDir(path), "seg-00099.m4s")); !os.IsNotExist(err) {
+		t.Error("Expected the stale segment to be removed")
+	}
+	if storage.HLSComplete(path) {
+		t.Error("Expected the repackaging to be incomplete while running")
+	}
+
+	close(packager.release)
+	waitFor(t, "the repackaging to complete", func() bool { return storage.HLSComplete(path) })
+
+	// Once complete, the cached result is used
+	if err := r.Prepare(context.Background(), path); err != nil {
+		t.Fatalf("Failed to prepare: %v", err)
+	}
+	if calls := packager.calls.Load(); calls != 1 {
+		t.Errorf("Expected the completed repackaging to be reused, got %d runs", calls)
+	}
+}
+
+func TestPrepareFailure(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	packager := &fakePackager{release: release, err: errors.New("unsupported codec")}
+	r := newTestRepackager(t, packager)
+	path := writeMedia(t)
+
+	// The playlist may be seen before the failure, so wait for the cleanup
+	r.Prepare(context.Background(), path)
+	waitFor(t, "the failed repackaging to be removed", func() bool {
+		_, err := os.Stat(storage.HLSDir(path))
+		return os.IsNotExist(err)
+	})
+	if storage.HLSComplete(path) {
+		t.Error("Expected a failed repackaging not to be marked complete")
+	}
+
+	// A later request tries again
+	waitFor(t, "the job to end", func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return len(r.jobs) == 0
+	})
+	r.Prepare(context.Background(), path)
+	if calls := packager.calls.Load(); calls != 2 {
+		t.Errorf("Expected a retry, got %d runs", calls)
+	}
+}
+
+func TestPrepareCancelled(t *testing.T) {
+	r := newTestRepackager(t, packagerFunc(func(ctx context.Context, input, outDir string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+	path := writeMedia(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := r.Prepare(ctx, path); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the request's deadline, got %v", err)
+	}
+
+	// Stopping cancels the repackaging and removes what it wrote
+	r.Stop()
+	if _, err := os.Stat(storage.HLSDir(path)); !os.IsNotExist(err) {
+		t.Error("Expected the cancelled repackaging to be removed")
+	}
+	if err := r.Prepare(context.Background(), path); err == nil {
+		t.Error("Expected a stopped repackager to refuse new work")
+	}
+}
+
+type packagerFunc func(ctx context.Context, input, outDir string) error
+
+func (f packagerFunc) Package(ctx context.Context, input, outDir string) error {
+	return f(ctx, input, outDir)
+}
+
+func TestFFmpegArgs(t *testing.T) {
+	args := NewFFmpeg("ffmpeg", 4*time.Second).args("/cache/movie.mkv", "/cache/.hls")
+	joined := strings.Join(args, " ")
+
+	for _, want := range []string{"-i /cache/movie.mkv", "-c:v copy", "-hls_time 4", "-hls_segment_type fmp4", "-hls_segment_filename /cache/.hls/seg-%05d.m4s"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected %q in %s", want, joined)
+		}
+	}
+	if args[len(args)-1] != filepath.Join("/cache/.hls", PlaylistName) {
+		t.Errorf("Expected the playlist as output, got %s", args[len(args)-1])
+	}
+	if !slices.Contains(args, "-nostdin") {
+		t.Error("Expected ffmpeg not to read stdin")
+	}
+}
@@ -1,134 +1,362 @@
 package jellyfin
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
 	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// clientName and clientVersion identify go-jf-watch in the authorization
+// header of login requests; Jellyfin lists its sessions and devices by them.
+const (
+	clientName    = "go-jf-watch"
+	clientVersion = "1.0.0"
+)
+
+// sessionStateKey is the state key the login session is saved under.
+const sessionStateKey = "jellyfin.session"
+
+// quickConnectPollInterval is how often a pending Quick Connect request is
+// checked for approval, and quickConnectTimeout how long approval is waited
+// for; Jellyfin forgets unapproved requests after ten minutes.
+const (
+	quickConnectPollInterval = 5 * time.Second
+	quickConnectTimeout      = 10 * time.Minute
 )
 
-// AuthManager handles authentication and session management for Jellyfin.
-// It provides automatic token refresh and fallback to API key authentication.
-type AuthManager struct {
-	client       *Client
-	logger       *slog.Logger
-	sessionToken string
-	tokenExpiry  time.Time
+// ErrInvalidCredentials is returned when Jellyfin rejects a username and
+// password.
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// savedSession is a login session kept across restarts. It is only reused
+// against the server and user it was issued for.
+type savedSession struct {
+	ServerURL   string `json:"server_url"`
+	Username    string `json:"username,omitempty"`
+	UserID      string `json:"user_id"`
+	AccessToken string `json:"access_token"`
+	DeviceID    string `json:"device_id"`
+}
+
+// authResult is the response of the AuthenticateByName and
+// AuthenticateWithQuickConnect endpoints.
+type authResult struct {
+	AccessToken string `json:"AccessToken"`
+	User        struct {
+		ID string `json:"Id"`
+	} `json:"User"`
+}
+
+// quickConnectState is the response of the Quick Connect endpoints.
+type quickConnectState struct {
+	Secret        string `json:"Secret"`
+	Code          string `json:"Code"`
+	Authenticated bool   `json:"Authenticated"`
+}
+
+// SetTokenStore keeps login sessions in store, so a restart reuses the
+// session instead of logging in again. Without a store every start logs in.
+func (c *Client) SetTokenStore(store storage.StateStore) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	c.tokens = store
+}
+
+// usesLogin reports whether the client logs in as a user rather than using
+// an API key. An API key wins when both are configured.
+func (c *Client) usesLogin() bool {
+	return c.config.APIKey == "" && (c.config.Username != "" || c.config.QuickConnect)
 }
 
-// NewAuthManager creates a new authentication manager for the given client.
-func NewAuthManager(client *Client, logger *slog.Logger) *AuthManager {
-	return &AuthManager{
-		client: client,
-		logger: logger,
+// token returns the token requests are authorized with: the login
+// session's access token, or the API key.
+func (c *Client) token() string {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	if c.sessionToken != "" {
+		return c.sessionToken
 	}
+	return c.config.APIKey
 }
 
-// Authenticate performs initial authentication with the Jellyfin server.
-// It tries to authenticate using the API key and establishes a session.
-func (a *AuthManager) Authenticate(ctx context.Context) error {
-	a.logger.Info("Authenticating with Jellyfin server")
+// authenticate establishes a login session, reusing the saved one when
+// there is one for this server and user.
+func (c *Client) authenticate(ctx context.Context) error {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
 
-	// Validate that we have the necessary credentials
-	if a.client.config.APIKey == "" {
-		return fmt.Errorf("API key is required for authentication")
+	if c.sessionToken != "" {
+		return nil
 	}
+	if c.restoreSessionLocked() {
+		c.logger.Debug("Reusing saved Jellyfin session", "user_id", c.config.UserID)
+		return nil
+	}
+	return c.loginLocked(ctx)
+}
 
-	if a.client.config.UserID == "" {
-		return fmt.Errorf("user ID is required for authentication")
+// reauthenticate replaces a login session the server rejected. rejected is
+// the token the failed request was sent with; when another request has
+// already replaced it, that session is used instead of logging in again.
+func (c *Client) reauthenticate(ctx context.Context, rejected string) error {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	if c.sessionToken != rejected {
+		return nil
 	}
+	c.logger.Info("Jellyfin rejected the session token, logging in again")
+	c.sessionToken = ""
+	return c.loginLocked(ctx)
+}
 
-	if a.client.httpClient == nil {
-		return fmt.Errorf("jellyfin client not initialized")
+// restoreSessionLocked loads the saved session if it belongs to the
+// configured server and user. Callers must hold c.authMu.
+func (c *Client) restoreSessionLocked() bool {
+	if c.tokens == nil {
+		return false
 	}
 
-	// Set API key for authentication
-	a.sessionToken = a.client.config.APIKey
+	var saved savedSession
+	found, err := c.tokens.LoadState(sessionStateKey, &saved)
+	if err != nil {
+		c.logger.Warn("Failed to load saved Jellyfin session", "error", err)
+		return false
+	}
+	if !found {
+		return false
+	}
 
-	// Verify authentication by testing connection
-	if err := a.client.TestConnection(ctx); err != nil {
-		return fmt.Errorf("authentication failed: %w", err)
+	// The device ID survives a mismatch so Jellyfin sees the same device
+	c.deviceID = saved.DeviceID
+	if saved.ServerURL != c.config.ServerURL || saved.Username != c.config.Username || saved.AccessToken == "" {
+		return false
 	}
 
-	// For API key auth, we don't get a session token, but we mark as authenticated
-	a.tokenExpiry = time.Now().Add(24 * time.Hour) // Assume API keys don't expire quickly
-	a.client.sessionToken = a.sessionToken
-	a.client.tokenExpiry = a.tokenExpiry
+	c.sessionToken = saved.AccessToken
+	if c.config.UserID == "" {
+		c.config.UserID = saved.UserID
+	}
+	return true
+}
 
-	a.logger.Info("Authentication successful")
+// loginLocked logs in with the configured username and password, falling
+// back to Quick Connect when there is no username or the login is
+// rejected, and saves the new session. Callers must hold c.authMu.
+func (c *Client) loginLocked(ctx context.Context) error {
+	if c.deviceID == "" {
+		c.deviceID = newDeviceID()
+	}
+
+	var result *authResult
+	var err error
+	if c.config.Username != "" {
+		result, err = c.authenticateByName(ctx)
+		if err != nil && c.config.QuickConnect {
+			c.logger.Warn("Jellyfin password login failed, falling back to Quick Connect", "error", err)
+		}
+	}
+	if result == nil && c.config.QuickConnect {
+		result, err = c.authenticateWithQuickConnect(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.sessionToken = result.AccessToken
+	if c.config.UserID == "" {
+		c.config.UserID = result.User.ID
+	} else if c.config.UserID != result.User.ID {
+		c.logger.Warn("Jellyfin login returned a different user than user_id",
+			"user_id", c.config.UserID,
+			"login_user_id", result.User.ID)
+	}
+	c.logger.Info("Logged in to Jellyfin", "user_id", result.User.ID)
 
+	if c.tokens != nil {
+		err := c.tokens.SaveState(sessionStateKey, savedSession{
+			ServerURL:   c.config.ServerURL,
+			Username:    c.config.Username,
+			UserID:      result.User.ID,
+			AccessToken: result.AccessToken,
+			DeviceID:    c.deviceID,
+		})
+		if err != nil {
+			c.logger.Warn("Failed to save Jellyfin session", "error", err)
+		}
+	}
 	return nil
 }
 
-// RefreshToken refreshes the authentication token if it's close to expiry.
-// Returns true if the token was refreshed, false if refresh wasn't needed.
-func (a *AuthManager) RefreshToken(ctx context.Context) (bool, error) {
-	// Check if token needs refresh (within 1 hour of expiry)
-	if time.Until(a.tokenExpiry) > time.Hour {
-		return false, nil
+// authenticateByName logs in with the configured username and password.
+func (c *Client) authenticateByName(ctx context.Context) (*authResult, error) {
+	body := map[string]string{
+		"Username": c.config.Username,
+		"Pw":       c.config.Password,
 	}
 
-	a.logger.Debug("Refreshing authentication token")
+	var result authResult
+	if err := c.postAuth(ctx, "/Users/AuthenticateByName", body, &result); err != nil {
+		return nil, fmt.Errorf("password login failed: %w", err)
+	}
+	if result.AccessToken == "" {
+		return nil, fmt.Errorf("password login failed: no access token in response")
+	}
+	return &result, nil
+}
 
-	// For API key authentication, we just need to verify the key is still valid
-	if a.client.httpClient == nil {
-		return false, fmt.Errorf("jellyfin client not initialized")
+// VerifyCredentials checks a username and password against the Jellyfin
+// server and returns the user's ID, so people can sign in to go-jf-watch
+// with their Jellyfin account. The session the check opens is closed
+// again. A rejected login returns ErrInvalidCredentials.
+func (c *Client) VerifyCredentials(ctx context.Context, username, password string) (string, error) {
+	body := map[string]string{
+		"Username": username,
+		"Pw":       password,
 	}
 
-	// Test if API key is still valid
-	if err := a.client.TestConnection(ctx); err != nil {
-		return false, fmt.Errorf("API key validation failed: %w", err)
+	var result authResult
+	if err := c.postAuth(ctx, "/Users/AuthenticateByName", body, &result); err != nil {
+		return "", err
+	}
+	if result.AccessToken == "" || result.User.ID == "" {
+		return "", fmt.Errorf("no access token in response")
 	}
 
-	// Extend token expiry
-	oldExpiry := a.tokenExpiry
-	a.tokenExpiry = time.Now().Add(24 * time.Hour)
-	a.client.tokenExpiry = a.tokenExpiry
+	req, err := http.NewRequestWithContext(ctx, "POST", c.config.ServerURL+"/Sessions/Logout", nil)
+	if err == nil {
+		req.Header.Set("X-Emby-Token", result.AccessToken)
+		var resp *http.Response
+		if resp, err = c.do(req); err == nil {
+			resp.Body.Close()
+		}
+	}
+	if err != nil {
+		c.logger.Debug("Failed to close Jellyfin session after verifying credentials", "error", err)
+	}
+	return result.User.ID, nil
+}
 
-	a.logger.Info("Token refreshed successfully",
-		"old_expiry", oldExpiry.Format(time.RFC3339),
-		"new_expiry", a.tokenExpiry.Format(time.RFC3339))
+// authenticateWithQuickConnect starts a Quick Connect request, logs the
+// code a signed-in user approves it with and waits for approval.
+func (c *Client) authenticateWithQuickConnect(ctx context.Context) (*authResult, error) {
+	var state quickConnectState
+	if err := c.postAuth(ctx, "/QuickConnect/Initiate", nil, &state); err != nil {
+		return nil, fmt.Errorf("quick connect failed: %w", err)
+	}
 
-	return true, nil
-}
+	c.logger.Warn("Approve this Quick Connect code in a signed-in Jellyfin app to log in",
+		"code", state.Code,
+		"timeout", quickConnectTimeout)
+
+	ctx, cancel := context.WithTimeout(ctx, quickConnectTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(quickConnectPollInterval)
+	defer ticker.Stop()
+
+	for !state.Authenticated {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("quick connect code %s was not approved: %w", state.Code, ctx.Err())
+		case <-ticker.C:
+		}
+
+		query := url.Values{}
+		query.Set("secret", state.Secret)
+		if err := c.getAuth(ctx, "/QuickConnect/Connect?"+query.Encode(), &state); err != nil {
+			return nil, fmt.Errorf("quick connect failed: %w", err)
+		}
+	}
 
-// IsTokenValid returns true if the current token is valid and not expired.
-func (a *AuthManager) IsTokenValid() bool {
-	return a.sessionToken != "" && time.Now().Before(a.tokenExpiry)
+	var result authResult
+	if err := c.postAuth(ctx, "/Users/AuthenticateWithQuickConnect", map[string]string{"Secret": state.Secret}, &result); err != nil {
+		return nil, fmt.Errorf("quick connect failed: %w", err)
+	}
+	if result.AccessToken == "" {
+		return nil, fmt.Errorf("quick connect failed: no access token in response")
+	}
+	return &result, nil
 }
 
-// GetAuthHeaders returns the authentication headers for API requests.
-// It automatically refreshes the token if necessary.
-func (a *AuthManager) GetAuthHeaders(ctx context.Context) (map[string]string, error) {
-	// Try to refresh token if needed
-	if _, err := a.RefreshToken(ctx); err != nil {
-		return nil, fmt.Errorf("failed to refresh token: %w", err)
+// postAuth sends an unauthenticated login request with a JSON body and
+// decodes the response into v.
+func (c *Client) postAuth(ctx context.Context, path string, body, v interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
 	}
 
-	if !a.IsTokenValid() {
-		return nil, fmt.Errorf("no valid authentication token available")
+	req, err := http.NewRequestWithContext(ctx, "POST", c.config.ServerURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.doAuth(req, v)
+}
 
-	// TODO: Return actual headers when jellyfin-go is available
-	// For now, return mock headers
-	return map[string]string{
-		"Authorization": fmt.Sprintf("Bearer %s", a.sessionToken),
-		"X-Emby-Token":  a.client.config.APIKey,
-	}, nil
+// getAuth sends an unauthenticated login request and decodes the response
+// into v.
+func (c *Client) getAuth(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.config.ServerURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	return c.doAuth(req, v)
 }
 
-// Logout invalidates the current session and clears stored credentials.
-func (a *AuthManager) Logout(ctx context.Context) error {
-	a.logger.Info("Logging out from Jellyfin server")
+func (c *Client) doAuth(req *http.Request, v interface{}) error {
+	req.Header.Set("Authorization", c.authorizationHeader())
 
-	// TODO: Implement actual logout when jellyfin-go is available
-	// For now, just clear local session data
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
 
-	a.sessionToken = ""
-	a.tokenExpiry = time.Time{}
-	a.client.sessionToken = ""
-	a.client.tokenExpiry = time.Time{}
+	if resp.StatusCode == http.StatusUnauthorized && strings.HasSuffix(req.URL.Path, "/Users/AuthenticateByName") {
+		return ErrInvalidCredentials
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
 
-	a.logger.Info("Logout successful")
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
 	return nil
 }
+
+// authorizationHeader identifies this client and device to Jellyfin, which
+// ties the issued token to the device.
+func (c *Client) authorizationHeader() string {
+	device, err := os.Hostname()
+	if err != nil || device == "" {
+		device = clientName
+	}
+	return fmt.Sprintf("MediaBrowser Client=%q, Device=%q, DeviceId=%q, Version=%q",
+		clientName, device, c.deviceID, clientVersion)
+}
+
+// newDeviceID returns a random device ID for a client that has none saved.
+func newDeviceID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%s-%d", clientName, time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
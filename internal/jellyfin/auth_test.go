@@ -0,0 +1,214 @@
+package jellyfin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// loginServer is a Jellyfin stand-in that issues a new token per login and
+// accepts only the latest one.
+type loginServer struct {
+	mu       sync.Mutex
+	logins   int
+	logouts  int
+	token    string
+	password string
+	quick    bool // Quick Connect enabled
+}
+
+func (s *loginServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if strings.HasPrefix(r.URL.Path, "/Users/Authenticate") || strings.HasPrefix(r.URL.Path, "/QuickConnect/") {
+		if !strings.Contains(r.Header.Get("Authorization"), `Client="go-jf-watch"`) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch r.URL.Path {
+	case "/Users/AuthenticateByName":
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["Username"] != "alice" || body["Pw"] != s.password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		s.issue(w)
+	case "/QuickConnect/Initiate":
+		if !s.quick {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// Approved straight away, as if the user was waiting for the code
+		fmt.Fprint(w, `{"Secret":"qc-secret","Code":"123456","Authenticated":true}`)
+	case "/Users/AuthenticateWithQuickConnect":
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["Secret"] != "qc-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		s.issue(w)
+	case "/Sessions/Logout":
+		if r.Header.Get("X-Emby-Token") == s.token {
+			s.logouts++
+			s.token = ""
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case "/System/Info":
+		if s.token == "" || r.Header.Get("X-Emby-Token") != s.token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"ServerName":"test","Version":"10.9.0"}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *loginServer) issue(w http.ResponseWriter) {
+	s.logins++
+	s.token = fmt.Sprintf("token-%d", s.logins)
+	fmt.Fprintf(w, `{"AccessToken":%q,"User":{"Id":"user-alice"}}`, s.token)
+}
+
+// revoke invalidates the current token, as signing the device out does.
+func (s *loginServer) revoke() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = "revoked"
+}
+
+func newLoginTestClient(serverURL string, cfg config.JellyfinConfig) *Client {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	cfg.ServerURL = serverURL
+	return New(&cfg, logger)
+}
+
+func TestClientLoginByName(t *testing.T) {
+	jf := &loginServer{password: "secret"}
+	server := httptest.NewServer(jf)
+	defer server.Close()
+
+	store := storagetest.New()
+	client := newLoginTestClient(server.URL, config.JellyfinConfig{Username: "alice", Password: "secret"})
+	client.SetTokenStore(store)
+
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	if client.config.UserID != "user-alice" {
+		t.Errorf("Expected user ID from login, got %q", client.config.UserID)
+	}
+
+	streamURL, err := client.GetStreamURL("m1")
+	if err != nil || !strings.HasSuffix(streamURL, "api_key=token-1") {
+		t.Errorf("Expected stream URL with session token, got %q (%v)", streamURL, err)
+	}
+
+	// A revoked token is replaced by logging in again
+	jf.revoke()
+	if _, err := client.GetServerInfo(context.Background()); err != nil {
+		t.Fatalf("Expected request to succeed after logging in again: %v", err)
+	}
+	if jf.logins != 2 || client.token() != "token-2" {
+		t.Errorf("Expected a second login, got %d logins with token %q", jf.logins, client.token())
+	}
+
+	// The saved session is reused by the next client
+	restarted := newLoginTestClient(server.URL, config.JellyfinConfig{Username: "alice", Password: "secret"})
+	restarted.SetTokenStore(store)
+	if err := restarted.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect after restart failed: %v", err)
+	}
+	if jf.logins != 2 {
+		t.Errorf("Expected saved session to be reused, got %d logins", jf.logins)
+	}
+	if restarted.config.UserID != "user-alice" || restarted.deviceID != client.deviceID {
+		t.Errorf("Expected saved user and device, got %q and %q", restarted.config.UserID, restarted.deviceID)
+	}
+
+	// A session saved for another user is not
+	other := newLoginTestClient(server.URL, config.JellyfinConfig{Username: "bob", Password: "secret"})
+	other.SetTokenStore(store)
+	if err := other.Connect(context.Background()); err == nil {
+		t.Error("Expected login as an unknown user to fail")
+	}
+}
+
+func TestClientLoginQuickConnectFallback(t *testing.T) {
+	jf := &loginServer{password: "secret", quick: true}
+	server := httptest.NewServer(jf)
+	defer server.Close()
+
+	client := newLoginTestClient(server.URL, config.JellyfinConfig{Username: "alice", Password: "wrong", QuickConnect: true})
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Expected Quick Connect fallback to log in: %v", err)
+	}
+	if client.config.UserID != "user-alice" || client.token() != "token-1" {
+		t.Errorf("Unexpected session: user %q token %q", client.config.UserID, client.token())
+	}
+
+	jf.quick = false
+	client = newLoginTestClient(server.URL, config.JellyfinConfig{Username: "alice", Password: "wrong", QuickConnect: true})
+	if err := client.Connect(context.Background()); err == nil {
+		t.Error("Expected error when both login methods fail")
+	}
+}
+
+func TestClientAPIKeyNotRefreshed(t *testing.T) {
+	jf := &loginServer{password: "secret"}
+	server := httptest.NewServer(jf)
+	defer server.Close()
+
+	// An API key is never swapped for a login, even with a username set
+	client := newLoginTestClient(server.URL, config.JellyfinConfig{APIKey: "rejected-key", UserID: "u1", Username: "alice", Password: "secret"})
+	if err := client.Connect(context.Background()); err == nil {
+		t.Error("Expected rejected API key to fail")
+	}
+	if jf.logins != 0 {
+		t.Errorf("Expected no login with an API key, got %d", jf.logins)
+	}
+}
+
+func TestVerifyCredentials(t *testing.T) {
+	jf := &loginServer{password: "secret"}
+	server := httptest.NewServer(jf)
+	defer server.Close()
+
+	// Works with any client configuration, an API key included
+	client := newLoginTestClient(server.URL, config.JellyfinConfig{APIKey: "key", UserID: "u1"})
+
+	userID, err := client.VerifyCredentials(context.Background(), "alice", "secret")
+	if err != nil || userID != "user-alice" {
+		t.Fatalf("Expected alice to be verified, got %q (%v)", userID, err)
+	}
+	if jf.logouts != 1 {
+		t.Errorf("Expected the verifying session to be closed, got %d logouts", jf.logouts)
+	}
+
+	if _, err := client.VerifyCredentials(context.Background(), "alice", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+
+	server.Close()
+	if _, err := client.VerifyCredentials(context.Background(), "alice", "secret"); !errors.Is(err, ErrOffline) {
+		t.Errorf("Expected ErrOffline from an unreachable server, got %v", err)
+	}
+}
@@ -8,8 +8,11 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"sync"
 	"time"
 
+	"github.com/opd-ai/go-jf-watch/internal/chaos"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
 	"github.com/opd-ai/go-jf-watch/pkg/config"
 )
 
@@ -22,10 +25,16 @@ type Client struct {
 	// HTTP client for API calls
 	httpClient *http.Client
 
-	// Session management
+	// Session management. sessionToken is the access token of a login
+	// session, guarded by authMu along with tokens and deviceID.
+	authMu       sync.Mutex
+	tokens       storage.StateStore
+	deviceID     string
 	sessionToken string
 	tokenExpiry  time.Time
 	connected    bool
+
+	connectivity *Connectivity
 }
 
 // SystemInfo represents Jellyfin system information response
@@ -45,11 +54,13 @@ func New(cfg *config.JellyfinConfig, logger *slog.Logger) *Client {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		connectivity: newConnectivity(logger),
 	}
 }
 
 // Connect establishes a connection to the Jellyfin server and authenticates.
-// It attempts authentication with the API key and validates the connection.
+// It uses the API key when one is configured and logs in as the configured
+// user otherwise, then validates the connection.
 func (c *Client) Connect(ctx context.Context) error {
 	c.logger.Info("Connecting to Jellyfin server",
 		"server_url", c.config.ServerURL,
@@ -60,18 +71,24 @@ func (c *Client) Connect(ctx context.Context) error {
 		return fmt.Errorf("server URL is empty")
 	}
 
-	if c.config.APIKey == "" {
-		return fmt.Errorf("API key is empty")
-	}
-
-	if c.config.UserID == "" {
-		return fmt.Errorf("user ID is empty")
-	}
-
 	if c.httpClient == nil {
 		return fmt.Errorf("HTTP client not initialized")
 	}
 
+	if c.usesLogin() {
+		if err := c.authenticate(ctx); err != nil {
+			return fmt.Errorf("failed to log in to jellyfin server: %w", err)
+		}
+	} else {
+		if c.config.APIKey == "" {
+			return fmt.Errorf("API key is empty")
+		}
+
+		if c.config.UserID == "" {
+			return fmt.Errorf("user ID is empty")
+		}
+	}
+
 	// Test connection by getting server info
 	_, err := c.getSystemInfo(ctx)
 	if err != nil {
@@ -97,7 +114,11 @@ func (c *Client) TestConnection(ctx context.Context) error {
 		return fmt.Errorf("server URL not configured")
 	}
 
-	if c.config.APIKey == "" {
+	if c.usesLogin() {
+		if err := c.authenticate(ctx); err != nil {
+			return fmt.Errorf("jellyfin login failed: %w", err)
+		}
+	} else if c.config.APIKey == "" {
 		return fmt.Errorf("API key not configured")
 	}
 
@@ -113,17 +134,9 @@ func (c *Client) TestConnection(ctx context.Context) error {
 
 // getSystemInfo makes an HTTP request to get system information
 func (c *Client) getSystemInfo(ctx context.Context) (*SystemInfo, error) {
-	url := fmt.Sprintf("%s/System/Info", c.config.ServerURL)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	resp, err := c.get(ctx, c.config.ServerURL+"/System/Info")
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("X-Emby-Token", c.config.APIKey)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -139,6 +152,69 @@ func (c *Client) getSystemInfo(ctx context.Context) (*SystemInfo, error) {
 	return &sysInfo, nil
 }
 
+// get sends an authorized GET request. When the server rejects a login
+// session's token, which happens when the device is signed out in
+// Jellyfin, it logs in again and retries once.
+func (c *Client) get(ctx context.Context, url string) (*http.Response, error) {
+	token := c.token()
+	resp, err := c.sendGet(ctx, url, token)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !c.usesLogin() {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	if err := c.reauthenticate(ctx, token); err != nil {
+		return nil, fmt.Errorf("failed to log in again: %w", err)
+	}
+	return c.sendGet(ctx, url, c.token())
+}
+
+func (c *Client) sendGet(ctx context.Context, url, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("X-Emby-Token", token)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	return resp, nil
+}
+
+// do sends req and reports whether it reached the server. Errors from a
+// server that cannot be reached wrap ErrOffline; a cancelled request says
+// nothing about the server.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if req.Context().Err() != nil {
+		return resp, err
+	}
+	if err != nil {
+		c.connectivity.report(err, 0)
+		return nil, fmt.Errorf("%w: %w", ErrOffline, err)
+	}
+	c.connectivity.report(nil, resp.StatusCode)
+	return resp, nil
+}
+
+// Connectivity returns the tracker of whether the server is reachable.
+func (c *Client) Connectivity() *Connectivity {
+	return c.connectivity
+}
+
+// MonitorConnectivity probes the server every
+// jellyfin.connectivity_check_interval while it is unreachable and
+// replays deferred syncs when it returns, until ctx is cancelled.
+func (c *Client) MonitorConnectivity(ctx context.Context) {
+	c.connectivity.Run(ctx, c.config.ConnectivityCheckInterval, func(ctx context.Context) error {
+		_, err := c.getSystemInfo(ctx)
+		return err
+	})
+}
+
 // IsConnected returns true if the client has an active session.
 func (c *Client) IsConnected() bool {
 	return c.connected && c.httpClient != nil
@@ -147,6 +223,8 @@ func (c *Client) IsConnected() bool {
 // Disconnect closes the connection and clears the session.
 func (c *Client) Disconnect() {
 	c.logger.Info("Disconnecting from Jellyfin server")
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
 	c.sessionToken = ""
 	c.tokenExpiry = time.Time{}
 	c.connected = false
@@ -190,14 +268,15 @@ func (c *Client) GetStreamURL(mediaID string) (string, error) {
 		return "", fmt.Errorf("server URL not configured")
 	}
 
-	if c.config.APIKey == "" {
+	token := c.token()
+	if token == "" {
 		return "", fmt.Errorf("API key not configured")
 	}
 
 	// Construct direct stream URL for Jellyfin
-	// Format: {server}/Videos/{id}/stream?Static=true&api_key={key}
+	// Format: {server}/Videos/{id}/stream?Static=true&api_key={token}
 	streamURL := fmt.Sprintf("%s/Videos/%s/stream?Static=true&api_key=%s",
-		c.config.ServerURL, mediaID, c.config.APIKey)
+		c.config.ServerURL, mediaID, token)
 
 	c.logger.Debug("Generated stream URL for media",
 		"media_id", mediaID,
@@ -205,3 +284,112 @@ func (c *Client) GetStreamURL(mediaID string) (string, error) {
 
 	return streamURL, nil
 }
+
+// variantLimits maps a quality level to the maximum height and video bitrate
+// Jellyfin transcodes to for it.
+var variantLimits = map[string]struct {
+	height  int
+	bitrate int
+}{
+	"1080p": {1080, 8_000_000},
+	"720p":  {720, 4_000_000},
+	"480p":  {480, 1_500_000},
+}
+
+// GetVariantURL constructs the stream URL for a media item transcoded by
+// Jellyfin to the given quality (1080p, 720p or 480p). "original" or an
+// empty quality returns the direct stream URL.
+func (c *Client) GetVariantURL(mediaID, quality string) (string, error) {
+	return c.GetTranscodeURL(mediaID, quality, 0)
+}
+
+// GetTranscodeURL is GetVariantURL with the video bitrate further capped
+// at maxBitrate bits per second; 0 leaves the quality's own limit. With a
+// cap, "original" is transcoded at its own resolution instead of streamed
+// directly.
+func (c *Client) GetTranscodeURL(mediaID, quality string, maxBitrate int) (string, error) {
+	if quality == "" {
+		quality = "original"
+	}
+	if quality == "original" && maxBitrate <= 0 {
+		return c.GetStreamURL(mediaID)
+	}
+
+	limits, ok := variantLimits[quality]
+	if !ok && quality != "original" {
+		return "", fmt.Errorf("unsupported quality: %s", quality)
+	}
+	bitrate := limits.bitrate
+	if maxBitrate > 0 && (bitrate == 0 || maxBitrate < bitrate) {
+		bitrate = maxBitrate
+	}
+
+	if c.config.ServerURL == "" {
+		return "", fmt.Errorf("server URL not configured")
+	}
+
+	token := c.token()
+	if token == "" {
+		return "", fmt.Errorf("API key not configured")
+	}
+
+	// Progressive MP4 so the transcode can be downloaded as a single file
+	var streamURL string
+	if limits.height > 0 {
+		streamURL = fmt.Sprintf("%s/Videos/%s/stream.mp4?VideoCodec=h264&AudioCodec=aac&MaxHeight=%d&VideoBitrate=%d&api_key=%s",
+			c.config.ServerURL, mediaID, limits.height, bitrate, token)
+	} else {
+		streamURL = fmt.Sprintf("%s/Videos/%s/stream.mp4?VideoCodec=h264&AudioCodec=aac&VideoBitrate=%d&api_key=%s",
+			c.config.ServerURL, mediaID, bitrate, token)
+	}
+
+	c.logger.Debug("Generated variant stream URL for media",
+		"media_id", mediaID,
+		"quality", quality,
+		"bitrate", bitrate)
+
+	return streamURL, nil
+}
+
+// FilterLibraries drops items from libraries excluded by the configured
+// library filter, so library sync never stores or caches them.
+func (c *Client) FilterLibraries(items []MediaItem) []MediaItem {
+	kept := items[:0:0]
+	for _, item := range items {
+		if c.config.Libraries.Allows(item.LibraryName) {
+			kept = append(kept, item)
+			continue
+		}
+		c.logger.Debug("Skipping item from excluded library",
+			"media_id", item.ID,
+			"library", item.LibraryName)
+	}
+	return kept
+}
+
+// CacheMediaType maps a Jellyfin item type to the media type used in the
+// cache, or "" for container types (series, albums, folders) that are not
+// cached themselves. Library sync uses it to decide which items to store.
+func CacheMediaType(itemType string) string {
+	switch itemType {
+	case "Movie":
+		return "movie"
+	case "Episode":
+		return "episode"
+	case "Audio":
+		return "audio"
+	case "AudioBook":
+		return "audiobook"
+	default:
+		return ""
+	}
+}
+
+// SetFaultInjector makes Jellyfin API requests fail at the injector's
+// Jellyfin error rate. A nil injector leaves the client untouched.
+func (c *Client) SetFaultInjector(inj *chaos.Injector) {
+	if inj == nil || c.httpClient == nil {
+		return
+	}
+	c.httpClient.Transport = inj.Transport(c.httpClient.Transport)
+}
@@ -221,4 +221,98 @@ func TestClientDisconnect(t *testing.T) {
 	if client.sessionToken != "" {
 		t.Error("Expected sessionToken to be cleared after disconnect")
 	}
-}
\ No newline at end of file
+}
+func TestClientGetVariantURL(t *testing.T) {
+	cfg := &config.JellyfinConfig{
+		ServerURL: "https://jellyfin.example.com",
+		APIKey:    "test-api-key",
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	client := New(cfg, logger)
+
+	original, err := client.GetVariantURL("abc", "original")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	direct, _ := client.GetStreamURL("abc")
+	if original != direct {
+		t.Errorf("Expected original quality to use the direct stream URL, got %s", original)
+	}
+
+	variant, err := client.GetVariantURL("abc", "720p")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := "https://jellyfin.example.com/Videos/abc/stream.mp4?VideoCodec=h264&AudioCodec=aac&MaxHeight=720&VideoBitrate=4000000&api_key=test-api-key"
+	if variant != want {
+		t.Errorf("Expected %s, got %s", want, variant)
+	}
+
+	if _, err := client.GetVariantURL("abc", "4k"); err == nil {
+		t.Error("Expected error for unsupported quality")
+	}
+
+	capped, err := client.GetTranscodeURL("abc", "720p", 2_000_000)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want = "https://jellyfin.example.com/Videos/abc/stream.mp4?VideoCodec=h264&AudioCodec=aac&MaxHeight=720&VideoBitrate=2000000&api_key=test-api-key"
+	if capped != want {
+		t.Errorf("Expected %s, got %s", want, capped)
+	}
+
+	capped, err = client.GetTranscodeURL("abc", "original", 10_000_000)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want = "https://jellyfin.example.com/Videos/abc/stream.mp4?VideoCodec=h264&AudioCodec=aac&VideoBitrate=10000000&api_key=test-api-key"
+	if capped != want {
+		t.Errorf("Expected %s, got %s", want, capped)
+	}
+}
+
+func TestClientFilterLibraries(t *testing.T) {
+	cfg := &config.JellyfinConfig{
+		ServerURL: "https://jellyfin.example.com",
+		APIKey:    "test-api-key",
+		Libraries: config.LibraryFilterConfig{Exclude: []string{"Home Videos", "Music"}},
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	client := New(cfg, logger)
+
+	items := []MediaItem{
+		{ID: "1", LibraryName: "Movies"},
+		{ID: "2", LibraryName: "Home Videos"},
+		{ID: "3", LibraryName: "music"},
+		{ID: "4"},
+	}
+	kept := client.FilterLibraries(items)
+
+	if len(kept) != 2 || kept[0].ID != "1" || kept[1].ID != "4" {
+		t.Errorf("Expected items 1 and 4 to be kept, got %+v", kept)
+	}
+	if items[1].ID != "2" {
+		t.Error("Expected the input slice to be left untouched")
+	}
+}
+
+func TestCacheMediaType(t *testing.T) {
+	tests := map[string]string{
+		"Movie":      "movie",
+		"Episode":    "episode",
+		"Audio":      "audio",
+		"AudioBook":  "audiobook",
+		"MusicAlbum": "",
+		"Series":     "",
+	}
+
+	for itemType, want := range tests {
+		if got := CacheMediaType(itemType); got != want {
+			t.Errorf("CacheMediaType(%q) = %q, want %q", itemType, got, want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package jellyfin
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// collectionItemTypes are the container types GetCollections lists.
+const collectionItemTypes = "BoxSet,Playlist"
+
+// GetCollections returns the collections (Type "BoxSet") and playlists
+// (Type "Playlist") visible to the configured user.
+func (c *Client) GetCollections(ctx context.Context) ([]MediaItem, error) {
+	query := url.Values{}
+	query.Set("Recursive", "true")
+	query.Set("IncludeItemTypes", collectionItemTypes)
+	query.Set("SortBy", "SortName")
+
+	items, err := c.getItems(ctx, fmt.Sprintf("/Users/%s/Items", url.PathEscape(c.config.UserID)), query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collections: %w", err)
+	}
+	return items, nil
+}
+
+// GetCollectionItems returns the movies, episodes, tracks and audiobooks in
+// a collection, in release order, along with the size of their files.
+// Series in the collection are expanded into their episodes.
+func (c *Client) GetCollectionItems(ctx context.Context, collectionID string) ([]MediaItem, error) {
+	query := url.Values{}
+	query.Set("ParentId", collectionID)
+	query.Set("Recursive", "true")
+	query.Set("IncludeItemTypes", cacheableItemTypes)
+	query.Set("SortBy", "PremiereDate,ParentIndexNumber,IndexNumber,SortName")
+	query.Set("Fields", "MediaSources")
+
+	items, err := c.getItems(ctx, fmt.Sprintf("/Users/%s/Items", url.PathEscape(c.config.UserID)), query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection items: %w", err)
+	}
+	return items, nil
+}
+
+// GetPlaylistItems returns the items of a playlist in playlist order,
+// along with the size of their files.
+func (c *Client) GetPlaylistItems(ctx context.Context, playlistID string) ([]MediaItem, error) {
+	query := url.Values{}
+	query.Set("UserId", c.config.UserID)
+	query.Set("Fields", "MediaSources")
+
+	items, err := c.getItems(ctx, fmt.Sprintf("/Playlists/%s/Items", url.PathEscape(playlistID)), query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get playlist items: %w", err)
+	}
+	return items, nil
+}
@@ -0,0 +1,69 @@
+package jellyfin
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestClientCollections(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.URL.Path == "/Users/user1/Items" && query.Get("IncludeItemTypes") == collectionItemTypes:
+			fmt.Fprint(w, `{"Items":[{"Id":"c1","Name":"Alien Collection","Type":"BoxSet"},{"Id":"p1","Name":"Road Trip","Type":"Playlist"}]}`)
+		case r.URL.Path == "/Users/user1/Items" && query.Get("ParentId") == "c1":
+			if query.Get("Recursive") != "true" || query.Get("Fields") != "MediaSources" {
+				t.Errorf("Unexpected collection items query %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"Items":[{"Id":"m1","Type":"Movie","MediaSources":[{"Size":4096}]},{"Id":"m2","Type":"Movie"}]}`)
+		case r.URL.Path == "/Playlists/p1/Items":
+			if query.Get("UserId") != "user1" {
+				t.Errorf("Unexpected playlist items query %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"Items":[{"Id":"t2","Type":"Audio"},{"Id":"t1","Type":"Audio"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	client := New(&config.JellyfinConfig{ServerURL: server.URL, APIKey: "test-api-key", UserID: "user1"}, logger)
+
+	collections, err := client.GetCollections(context.Background())
+	if err != nil {
+		t.Fatalf("GetCollections failed: %v", err)
+	}
+	if len(collections) != 2 || collections[0].Type != "BoxSet" || collections[1].Type != "Playlist" {
+		t.Errorf("Unexpected collections %+v", collections)
+	}
+
+	items, err := client.GetCollectionItems(context.Background(), "c1")
+	if err != nil {
+		t.Fatalf("GetCollectionItems failed: %v", err)
+	}
+	if len(items) != 2 || items[0].ID != "m1" || items[0].Size != 4096 {
+		t.Errorf("Unexpected collection items %+v", items)
+	}
+
+	items, err = client.GetPlaylistItems(context.Background(), "p1")
+	if err != nil {
+		t.Fatalf("GetPlaylistItems failed: %v", err)
+	}
+	if len(items) != 2 || items[0].ID != "t2" || items[1].ID != "t1" {
+		t.Errorf("Expected playlist order, got %+v", items)
+	}
+
+	if _, err := client.GetPlaylistItems(context.Background(), "gone"); err == nil {
+		t.Error("Expected error for a missing playlist")
+	}
+}
@@ -0,0 +1,189 @@
+package jellyfin
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrOffline is wrapped by errors from requests that could not reach the
+// Jellyfin server.
+var ErrOffline = errors.New("jellyfin server is unreachable")
+
+// defaultConnectivityCheckInterval applies when
+// jellyfin.connectivity_check_interval is unset.
+const defaultConnectivityCheckInterval = 30 * time.Second
+
+// Connectivity tracks whether the Jellyfin server is reachable. Every API
+// request reports its outcome, so an outage is noticed by the first request
+// that cannot reach the server and the end of one by the first that gets
+// through. While the server is offline, sync operations that needed it are
+// queued with Defer and replayed, once each, when it comes back.
+type Connectivity struct {
+	logger *slog.Logger
+
+	// now is stubbed by tests
+	now func() time.Time
+
+	mu       sync.Mutex
+	online   bool
+	since    time.Time // when online last changed
+	lastErr  error
+	pending  []deferredSync
+	returned chan struct{} // signalled when the server comes back
+}
+
+// deferredSync is a sync operation waiting for the server to return.
+type deferredSync struct {
+	name string
+	run  func(context.Context) error
+}
+
+// ConnectivityStatus reports whether the Jellyfin server is reachable.
+type ConnectivityStatus struct {
+	Online bool      `json:"online"`
+	Since  time.Time `json:"since"`
+	// LastError is why the server was last found unreachable
+	LastError string `json:"last_error,omitempty"`
+	// PendingSyncs are the sync operations replayed when the server returns
+	PendingSyncs []string `json:"pending_syncs,omitempty"`
+}
+
+func newConnectivity(logger *slog.Logger) *Connectivity {
+	return &Connectivity{
+		logger:   logger,
+		now:      time.Now,
+		online:   true,
+		since:    time.Now(),
+		returned: make(chan struct{}, 1),
+	}
+}
+
+// Online reports whether the last request reached the server.
+func (c *Connectivity) Online() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.online
+}
+
+// Status returns the current connectivity state.
+func (c *Connectivity) Status() ConnectivityStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := ConnectivityStatus{
+		Online: c.online,
+		Since:  c.since,
+	}
+	if c.lastErr != nil {
+		status.LastError = c.lastErr.Error()
+	}
+	for _, op := range c.pending {
+		status.PendingSyncs = append(status.PendingSyncs, op.name)
+	}
+	return status
+}
+
+// Defer queues run to be called when the server is reachable again. An
+// operation already queued under name is replaced, so a sync that keeps
+// failing during an outage is replayed once.
+func (c *Connectivity) Defer(name string, run func(context.Context) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, op := range c.pending {
+		if op.name == name {
+			c.pending[i].run = run
+			return
+		}
+	}
+	c.pending = append(c.pending, deferredSync{name: name, run: run})
+	c.logger.Info("Deferring sync until Jellyfin is reachable", "sync", name)
+}
+
+// report records the outcome of a request. err is the transport error,
+// or nil when the server answered with status.
+func (c *Connectivity) report(err error, status int) {
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		// A reverse proxy answering for a server that is down
+		if err == nil {
+			err = errors.New(http.StatusText(status))
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err != nil {
+		c.lastErr = err
+		if c.online {
+			c.online = false
+			c.since = c.now()
+			c.logger.Warn("Jellyfin server is unreachable, serving from the cache", "error", err)
+		}
+		return
+	}
+
+	if !c.online {
+		c.online = true
+		c.since = c.now()
+		c.logger.Info("Jellyfin server is reachable again", "pending_syncs", len(c.pending))
+		select {
+		case c.returned <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run probes the server with probe every interval while it is offline,
+// and replays deferred syncs whenever it comes back, until ctx is
+// cancelled.
+func (c *Connectivity) Run(ctx context.Context, interval time.Duration, probe func(context.Context) error) {
+	if interval <= 0 {
+		interval = defaultConnectivityCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !c.Online() {
+				// The outcome is reported by the request itself
+				_ = probe(ctx)
+			}
+		case <-c.returned:
+			c.replay(ctx)
+		}
+	}
+}
+
+// replay runs the deferred syncs in the order they were queued. One that
+// fails because the server went away again is queued once more.
+func (c *Connectivity) replay(ctx context.Context) {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = nil
+	c.mu.Unlock()
+
+	for _, op := range pending {
+		if ctx.Err() != nil {
+			return
+		}
+		err := op.run(ctx)
+		switch {
+		case errors.Is(err, ErrOffline):
+			c.Defer(op.name, op.run)
+		case err != nil:
+			c.logger.Warn("Replayed sync failed", "sync", op.name, "error", err)
+		default:
+			c.logger.Info("Replayed deferred sync", "sync", op.name)
+		}
+	}
+}
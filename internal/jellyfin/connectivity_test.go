@@ -0,0 +1,143 @@
+package jellyfin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestConnectivityTracksRequests(t *testing.T) {
+	var down atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, `{"ServerName":"test","Version":"10.9.0"}`)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	client := New(&config.JellyfinConfig{ServerURL: server.URL, APIKey: "key", UserID: "u1"}, logger)
+	connectivity := client.Connectivity()
+
+	if _, err := client.getSystemInfo(context.Background()); err != nil {
+		t.Fatalf("getSystemInfo failed: %v", err)
+	}
+	if !connectivity.Online() {
+		t.Fatal("Expected the server to be online")
+	}
+
+	// A proxy answering for a server that is down counts as unreachable
+	down.Store(true)
+	if _, err := client.getSystemInfo(context.Background()); err == nil {
+		t.Fatal("Expected an error from a 502 response")
+	}
+	status := connectivity.Status()
+	if status.Online || status.LastError == "" {
+		t.Errorf("Expected offline with a reason, got %+v", status)
+	}
+
+	// A cancelled request says nothing about the server
+	down.Store(false)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client.getSystemInfo(ctx)
+	if connectivity.Online() {
+		t.Error("Expected a cancelled request to leave the server offline")
+	}
+
+	if _, err := client.getSystemInfo(context.Background()); err != nil {
+		t.Fatalf("getSystemInfo failed: %v", err)
+	}
+	if !connectivity.Online() {
+		t.Error("Expected a successful request to bring the server back online")
+	}
+}
+
+func TestConnectivityUnreachableServer(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	client := New(&config.JellyfinConfig{ServerURL: url, APIKey: "key", UserID: "u1"}, logger)
+
+	_, err := client.GetLibraries(context.Background())
+	if !errors.Is(err, ErrOffline) {
+		t.Fatalf("Expected ErrOffline, got %v", err)
+	}
+	if client.Connectivity().Online() {
+		t.Error("Expected the server to be offline")
+	}
+}
+
+func TestConnectivityReplaysDeferredSyncs(t *testing.T) {
+	var down atomic.Bool
+	down.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"ServerName":"test","Version":"10.9.0"}`)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	client := New(&config.JellyfinConfig{
+		ServerURL:                 server.URL,
+		APIKey:                    "key",
+		UserID:                    "u1",
+		ConnectivityCheckInterval: 10 * time.Millisecond,
+	}, logger)
+	connectivity := client.Connectivity()
+
+	client.getSystemInfo(context.Background())
+	if connectivity.Online() {
+		t.Fatal("Expected the server to be offline")
+	}
+
+	var runs atomic.Int32
+	replayed := make(chan struct{}, 2)
+	librarySync := func(ctx context.Context) error {
+		runs.Add(1)
+		replayed <- struct{}{}
+		return nil
+	}
+	// Deferring the same sync twice replays it once
+	connectivity.Defer("library", librarySync)
+	connectivity.Defer("library", librarySync)
+	if got := connectivity.Status().PendingSyncs; len(got) != 1 || got[0] != "library" {
+		t.Fatalf("Expected one pending library sync, got %v", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.MonitorConnectivity(ctx)
+
+	// The probe notices the server is back and the sync is replayed
+	down.Store(false)
+	select {
+	case <-replayed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Deferred sync was not replayed")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if runs.Load() != 1 {
+		t.Errorf("Expected one replay, got %d", runs.Load())
+	}
+	if status := connectivity.Status(); !status.Online || len(status.PendingSyncs) != 0 {
+		t.Errorf("Expected online with nothing pending, got %+v", status)
+	}
+}
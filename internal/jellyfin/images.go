@@ -0,0 +1,66 @@
+package jellyfin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// maxImageBytes bounds the size of a downloaded image.
+const maxImageBytes = 20 << 20
+
+// ErrNoImage is returned by GetItemImage when the item has no image of the
+// requested type.
+var ErrNoImage = errors.New("item has no such image")
+
+// GetItemImage downloads an item's image of imageType, such as "Primary"
+// or "Backdrop", scaled down to at most maxWidth pixels wide when maxWidth
+// is positive. It returns ErrNoImage if the item has none.
+func (c *Client) GetItemImage(ctx context.Context, itemID, imageType string, maxWidth int) ([]byte, error) {
+	path := fmt.Sprintf("/Items/%s/Images/%s", url.PathEscape(itemID), url.PathEscape(imageType))
+	data, err := c.getImage(ctx, path, maxWidth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s image of %s: %w", imageType, itemID, err)
+	}
+	return data, nil
+}
+
+// getImage downloads the image at path, returning ErrNoImage if the server
+// has none there.
+func (c *Client) getImage(ctx context.Context, path string, maxWidth int) ([]byte, error) {
+	if c.httpClient == nil {
+		return nil, fmt.Errorf("HTTP client not initialized")
+	}
+
+	query := url.Values{}
+	if maxWidth > 0 {
+		query.Set("maxWidth", strconv.Itoa(maxWidth))
+	}
+
+	resp, err := c.get(ctx, c.config.ServerURL+path+"?"+query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrNoImage
+	default:
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if len(data) > maxImageBytes {
+		return nil, fmt.Errorf("image is larger than %d bytes", maxImageBytes)
+	}
+	return data, nil
+}
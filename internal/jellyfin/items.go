@@ -0,0 +1,247 @@
+package jellyfin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cacheableItemTypes are the Jellyfin item types requested from list
+// endpoints; container types such as Series and MusicAlbum are left out.
+const cacheableItemTypes = "Movie,Episode,Audio,AudioBook"
+
+// apiItem is an item as returned by the Jellyfin API.
+type apiItem struct {
+	ID                string    `json:"Id"`
+	Name              string    `json:"Name"`
+	Type              string    `json:"Type"`
+	Path              string    `json:"Path"`
+	Container         string    `json:"Container"`
+	SeriesID          string    `json:"SeriesId"`
+	SeriesName        string    `json:"SeriesName"`
+	AlbumID           string    `json:"AlbumId"`
+	Album             string    `json:"Album"`
+	ParentIndexNumber int       `json:"ParentIndexNumber"`
+	IndexNumber       int       `json:"IndexNumber"`
+	Overview          string    `json:"Overview"`
+	Genres            []string  `json:"Genres"`
+	RunTimeTicks      int64     `json:"RunTimeTicks"`
+	DateCreated       time.Time `json:"DateCreated"`
+	CommunityRating   float64   `json:"CommunityRating"`
+
+	// MediaSources and MediaStreams are only filled in when requested
+	// through Fields
+	MediaSources []struct {
+		Size int64 `json:"Size"`
+	} `json:"MediaSources"`
+	MediaStreams []apiMediaStream `json:"MediaStreams"`
+
+	UserData *apiUserData `json:"UserData"`
+}
+
+// apiUserData is the requesting user's playback state of an API item.
+type apiUserData struct {
+	PlaybackPositionTicks int64     `json:"PlaybackPositionTicks"`
+	PlayCount             int       `json:"PlayCount"`
+	IsFavorite            bool      `json:"IsFavorite"`
+	Played                bool      `json:"Played"`
+	LastPlayedDate        time.Time `json:"LastPlayedDate"`
+}
+
+// apiMediaStream is a video, audio or subtitle stream of an API item.
+type apiMediaStream struct {
+	Type     string `json:"Type"`
+	Language string `json:"Language"`
+}
+
+// streamLanguages returns the languages of the streams of type kind, each
+// once, in stream order. Streams without a language, or tagged "und",
+// are left out.
+func streamLanguages(streams []apiMediaStream, kind string) []string {
+	var languages []string
+	for _, stream := range streams {
+		lang := strings.ToLower(strings.TrimSpace(stream.Language))
+		if stream.Type != kind || lang == "" || lang == "und" || slices.Contains(languages, lang) {
+			continue
+		}
+		languages = append(languages, lang)
+	}
+	return languages
+}
+
+// itemsResponse is the envelope Jellyfin wraps item lists in.
+type itemsResponse struct {
+	Items            []apiItem `json:"Items"`
+	TotalRecordCount int       `json:"TotalRecordCount"`
+}
+
+// toMediaItem converts an API item, mapping Jellyfin's parent index and
+// index numbers to season/episode or disc/track depending on the type.
+func (i apiItem) toMediaItem() MediaItem {
+	item := MediaItem{
+		ID:         i.ID,
+		Name:       i.Name,
+		Type:       i.Type,
+		Path:       i.Path,
+		Container:  i.Container,
+		SeriesID:   i.SeriesID,
+		SeriesName: i.SeriesName,
+		AlbumID:    i.AlbumID,
+		AlbumName:  i.Album,
+		Overview:   i.Overview,
+		Genres:     i.Genres,
+
+		RunTimeTicks:    i.RunTimeTicks,
+		DateCreated:     i.DateCreated,
+		CommunityRating: i.CommunityRating,
+
+		AudioLanguages:    streamLanguages(i.MediaStreams, "Audio"),
+		SubtitleLanguages: streamLanguages(i.MediaStreams, "Subtitle"),
+	}
+	if len(i.MediaSources) > 0 {
+		item.Size = i.MediaSources[0].Size
+	}
+	if i.UserData != nil {
+		item.UserData = &UserData{
+			PlaybackPositionTicks: i.UserData.PlaybackPositionTicks,
+			PlayCount:             i.UserData.PlayCount,
+			IsFavorite:            i.UserData.IsFavorite,
+			Played:                i.UserData.Played,
+			LastPlayedDate:        i.UserData.LastPlayedDate,
+		}
+	}
+
+	switch i.Type {
+	case "Episode":
+		item.SeasonNumber = i.ParentIndexNumber
+		item.EpisodeNumber = i.IndexNumber
+	case "Audio", "AudioBook":
+		item.DiscNumber = i.ParentIndexNumber
+		item.TrackNumber = i.IndexNumber
+	}
+
+	return item
+}
+
+// GetNextUp returns the configured user's Next Up episodes, the next unwatched
+// episode of each series in progress, most relevant first.
+func (c *Client) GetNextUp(ctx context.Context, limit int) ([]MediaItem, error) {
+	return c.GetUserNextUp(ctx, c.config.UserID, limit)
+}
+
+// GetUserNextUp returns userID's Next Up episodes, as GetNextUp does for the
+// configured user.
+func (c *Client) GetUserNextUp(ctx context.Context, userID string, limit int) ([]MediaItem, error) {
+	query := url.Values{}
+	query.Set("UserId", userID)
+	if limit > 0 {
+		query.Set("Limit", strconv.Itoa(limit))
+	}
+
+	items, err := c.getItems(ctx, "/Shows/NextUp", query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get next up: %w", err)
+	}
+	return items, nil
+}
+
+// GetFavorites returns every movie, episode, track and audiobook the
+// configured user has marked as a favorite.
+func (c *Client) GetFavorites(ctx context.Context) ([]MediaItem, error) {
+	query := url.Values{}
+	query.Set("Filters", "IsFavorite")
+	query.Set("Recursive", "true")
+	query.Set("IncludeItemTypes", cacheableItemTypes)
+
+	items, err := c.getItems(ctx, fmt.Sprintf("/Users/%s/Items", url.PathEscape(c.config.UserID)), query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get favorites: %w", err)
+	}
+	return items, nil
+}
+
+// GetPopular returns up to limit movies the configured user has not watched
+// yet, best rated and most played first.
+func (c *Client) GetPopular(ctx context.Context, limit int) ([]MediaItem, error) {
+	query := url.Values{}
+	query.Set("Recursive", "true")
+	query.Set("IncludeItemTypes", "Movie")
+	query.Set("Filters", "IsUnplayed")
+	query.Set("SortBy", "CommunityRating,PlayCount")
+	query.Set("SortOrder", "Descending")
+	query.Set("Fields", "Genres,DateCreated,MediaSources")
+	if limit > 0 {
+		query.Set("Limit", strconv.Itoa(limit))
+	}
+
+	items, err := c.getItems(ctx, fmt.Sprintf("/Users/%s/Items", url.PathEscape(c.config.UserID)), query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get popular items: %w", err)
+	}
+	return items, nil
+}
+
+// GetItemsByID returns the current metadata of the given items, including
+// the path and size of their files. Items that no longer exist on the
+// server are missing from the result.
+func (c *Client) GetItemsByID(ctx context.Context, ids []string) ([]MediaItem, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query := url.Values{}
+	query.Set("Ids", strings.Join(ids, ","))
+	query.Set("Fields", "Path,Overview,Genres,MediaSources,MediaStreams")
+
+	items, err := c.getItems(ctx, fmt.Sprintf("/Users/%s/Items", url.PathEscape(c.config.UserID)), query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get items by ID: %w", err)
+	}
+	return items, nil
+}
+
+// getItems fetches an item list from the Jellyfin API.
+func (c *Client) getItems(ctx context.Context, path string, query url.Values) ([]MediaItem, error) {
+	if c.config.UserID == "" {
+		return nil, fmt.Errorf("user ID not configured")
+	}
+
+	var body itemsResponse
+	if err := c.getJSON(ctx, path, query, &body); err != nil {
+		return nil, err
+	}
+
+	items := make([]MediaItem, 0, len(body.Items))
+	for _, item := range body.Items {
+		items = append(items, item.toMediaItem())
+	}
+	return items, nil
+}
+
+// getJSON fetches path from the Jellyfin API and decodes the response into v.
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, v interface{}) error {
+	if c.httpClient == nil {
+		return fmt.Errorf("HTTP client not initialized")
+	}
+
+	resp, err := c.get(ctx, c.config.ServerURL+path+"?"+query.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
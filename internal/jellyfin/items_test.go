@@ -0,0 +1,167 @@
+package jellyfin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestClientGetNextUpAndFavorites(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Emby-Token") != "test-api-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/Shows/NextUp":
+			if r.URL.Query().Get("UserId") != "user1" || r.URL.Query().Get("Limit") != "5" {
+				t.Errorf("Unexpected next up query %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"Items":[{"Id":"e1","Name":"Pilot","Type":"Episode","SeriesId":"s1","ParentIndexNumber":1,"IndexNumber":2}],"TotalRecordCount":1}`)
+		case "/Users/user1/Items":
+			if r.URL.Query().Get("Filters") != "IsFavorite" {
+				t.Errorf("Unexpected favorites query %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"Items":[{"Id":"m1","Type":"Movie"},{"Id":"t1","Type":"Audio","AlbumId":"a1","ParentIndexNumber":1,"IndexNumber":3}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	client := New(&config.JellyfinConfig{ServerURL: server.URL, APIKey: "test-api-key", UserID: "user1"}, logger)
+
+	nextUp, err := client.GetNextUp(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("GetNextUp failed: %v", err)
+	}
+	if len(nextUp) != 1 || nextUp[0].ID != "e1" || nextUp[0].SeasonNumber != 1 || nextUp[0].EpisodeNumber != 2 {
+		t.Errorf("Unexpected next up items %+v", nextUp)
+	}
+
+	favorites, err := client.GetFavorites(context.Background())
+	if err != nil {
+		t.Fatalf("GetFavorites failed: %v", err)
+	}
+	if len(favorites) != 2 || favorites[1].AlbumID != "a1" || favorites[1].DiscNumber != 1 || favorites[1].TrackNumber != 3 {
+		t.Errorf("Unexpected favorites %+v", favorites)
+	}
+
+	client.config.APIKey = "wrong"
+	if _, err := client.GetNextUp(context.Background(), 5); err == nil {
+		t.Error("Expected error for rejected API key")
+	}
+}
+
+func TestClientGetItemsByID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/Users/user1/Items" || r.URL.Query().Get("Ids") != "e1,gone" {
+			t.Errorf("Unexpected request %s?%s", r.URL.Path, r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `{"Items":[{"Id":"e1","Name":"Pilot (Director's Cut)","Type":"Episode","Overview":"Fixed","ParentIndexNumber":1,"IndexNumber":1,
+			"MediaStreams":[{"Type":"Audio","Language":"JPN"},{"Type":"Subtitle","Language":"eng"}]}]}`)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	client := New(&config.JellyfinConfig{ServerURL: server.URL, APIKey: "test-api-key", UserID: "user1"}, logger)
+
+	items, err := client.GetItemsByID(context.Background(), []string{"e1", "gone"})
+	if err != nil {
+		t.Fatalf("GetItemsByID failed: %v", err)
+	}
+	if len(items) != 1 || items[0].Name != "Pilot (Director's Cut)" || items[0].Overview != "Fixed" ||
+		len(items[0].AudioLanguages) != 1 || items[0].AudioLanguages[0] != "jpn" ||
+		len(items[0].SubtitleLanguages) != 1 || items[0].SubtitleLanguages[0] != "eng" {
+		t.Errorf("Unexpected items %+v", items)
+	}
+
+	if items, err := client.GetItemsByID(context.Background(), nil); err != nil || items != nil {
+		t.Errorf("Expected no request for no IDs, got %v, %v", items, err)
+	}
+}
+
+func TestClientGetItemImage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/Items/m1/Images/Primary":
+			if r.URL.Query().Get("maxWidth") != "600" {
+				t.Errorf("Unexpected image query %s", r.URL.RawQuery)
+			}
+			w.Header().Set("Content-Type", "image/jpeg")
+			fmt.Fprint(w, "poster")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	client := New(&config.JellyfinConfig{ServerURL: server.URL, APIKey: "test-api-key", UserID: "user1"}, logger)
+
+	data, err := client.GetItemImage(context.Background(), "m1", "Primary", 600)
+	if err != nil || string(data) != "poster" {
+		t.Fatalf("Expected the poster, got %q, %v", data, err)
+	}
+	if _, err := client.GetItemImage(context.Background(), "m1", "Backdrop", 0); !errors.Is(err, ErrNoImage) {
+		t.Errorf("Expected ErrNoImage for a missing backdrop, got %v", err)
+	}
+}
+
+func TestClientGetTrickplayInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/Users/user1/Items/m1":
+			if r.URL.Query().Get("Fields") != "Trickplay,Chapters" {
+				t.Errorf("Unexpected item query %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"Id":"m1","Trickplay":{"src1":{
+				"640":{"Width":640,"Height":360,"TileWidth":10,"TileHeight":10,"ThumbnailCount":120,"Interval":10000},
+				"320":{"Width":320,"Height":180,"TileWidth":10,"TileHeight":10,"ThumbnailCount":120,"Interval":10000}}},
+				"Chapters":[{"Name":"Opening","StartPositionTicks":0,"ImageTag":"t1"}]}`)
+		case "/Videos/m1/Trickplay/320/1.jpg":
+			fmt.Fprint(w, "sheet")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	client := New(&config.JellyfinConfig{ServerURL: server.URL, APIKey: "test-api-key", UserID: "user1"}, logger)
+
+	info, chapters, err := client.GetTrickplayInfo(context.Background(), "m1")
+	if err != nil {
+		t.Fatalf("GetTrickplayInfo failed: %v", err)
+	}
+	if info == nil || info.Width != 320 || info.Tiles() != 2 {
+		t.Errorf("Expected the narrowest resolution in 2 sheets, got %+v", info)
+	}
+	if len(chapters) != 1 || chapters[0].Name != "Opening" {
+		t.Errorf("Unexpected chapters %+v", chapters)
+	}
+
+	sheet, err := client.GetTrickplayTile(context.Background(), "m1", 320, 1)
+	if err != nil || string(sheet) != "sheet" {
+		t.Errorf("Expected the sprite sheet, got %q, %v", sheet, err)
+	}
+	if _, err := client.GetChapterImage(context.Background(), "m1", 0, 320); !errors.Is(err, ErrNoImage) {
+		t.Errorf("Expected ErrNoImage for a missing chapter image, got %v", err)
+	}
+}
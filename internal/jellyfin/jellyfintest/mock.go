@@ -0,0 +1,251 @@
+// Package jellyfintest provides a mock Jellyfin client for unit tests that
+// need stream URL resolution or item lists without a running Jellyfin
+// server.
+//
+// Point ServerURL at an httptest.Server to exercise code that proxies or
+// downloads from the returned URLs.
+package jellyfintest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+)
+
+// Mock resolves stream URLs against ServerURL the same way jellyfin.Client
+// does, and records every media ID it was asked about.
+type Mock struct {
+	// ServerURL is the base of every returned URL.
+	ServerURL string
+	// Err, when set, is returned by every call instead of a result.
+	Err error
+	// NextUp and Favorites are returned by GetNextUp and GetFavorites;
+	// GetUserNextUp returns NextUp whatever the user.
+	NextUp    []jellyfin.MediaItem
+	Favorites []jellyfin.MediaItem
+	// Popular is returned by GetPopular.
+	Popular []jellyfin.MediaItem
+	// Collections are returned by GetCollections, and CollectionItems by
+	// GetCollectionItems and GetPlaylistItems, keyed by collection ID.
+	Collections     []jellyfin.MediaItem
+	CollectionItems map[string][]jellyfin.MediaItem
+	// Items are looked up by GetItemsByID, keyed by item ID.
+	Items map[string]jellyfin.MediaItem
+	// Sessions are returned by GetSessions, and Resume by GetResumeItems,
+	// keyed by user ID.
+	Sessions []jellyfin.Session
+	Resume   map[string][]jellyfin.MediaItem
+	// Images are returned by GetItemImage, keyed by "{id}/{type}", and by
+	// GetTrickplayTile and GetChapterImage, keyed by "{id}/Trickplay/{n}"
+	// and "{id}/Chapter/{n}"; other images do not exist.
+	Images map[string][]byte
+	// Trickplay and Chapters are returned by GetTrickplayInfo, keyed by
+	// item ID.
+	Trickplay map[string]*jellyfin.TrickplayInfo
+	Chapters  map[string][]jellyfin.Chapter
+
+	mu       sync.Mutex
+	requests []string
+}
+
+// New returns a mock that resolves URLs against serverURL.
+func New(serverURL string) *Mock {
+	return &Mock{ServerURL: serverURL}
+}
+
+// GetStreamURL returns {ServerURL}/Videos/{id}/stream?Static=true.
+func (m *Mock) GetStreamURL(mediaID string) (string, error) {
+	m.record(mediaID)
+	if m.Err != nil {
+		return "", m.Err
+	}
+	return fmt.Sprintf("%s/Videos/%s/stream?Static=true", m.ServerURL, mediaID), nil
+}
+
+// GetVariantURL returns the direct stream URL for "original" or an empty
+// quality, and {ServerURL}/Videos/{id}/stream.mp4?Quality={quality}
+// otherwise.
+func (m *Mock) GetVariantURL(mediaID, quality string) (string, error) {
+	if quality == "" || quality == "original" {
+		return m.GetStreamURL(mediaID)
+	}
+	m.record(mediaID)
+	if m.Err != nil {
+		return "", m.Err
+	}
+	return fmt.Sprintf("%s/Videos/%s/stream.mp4?Quality=%s", m.ServerURL, mediaID, quality), nil
+}
+
+// GetTranscodeURL returns GetVariantURL with &MaxBitrate={maxBitrate}
+// appended when maxBitrate is set.
+func (m *Mock) GetTranscodeURL(mediaID, quality string, maxBitrate int) (string, error) {
+	if maxBitrate <= 0 {
+		return m.GetVariantURL(mediaID, quality)
+	}
+	if quality == "" {
+		quality = "original"
+	}
+	m.record(mediaID)
+	if m.Err != nil {
+		return "", m.Err
+	}
+	return fmt.Sprintf("%s/Videos/%s/stream.mp4?Quality=%s&MaxBitrate=%d", m.ServerURL, mediaID, quality, maxBitrate), nil
+}
+
+// GetNextUp returns up to limit items of NextUp.
+func (m *Mock) GetNextUp(ctx context.Context, limit int) ([]jellyfin.MediaItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	items := m.NextUp
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return append([]jellyfin.MediaItem(nil), items...), nil
+}
+
+// GetUserNextUp returns up to limit items of NextUp.
+func (m *Mock) GetUserNextUp(ctx context.Context, userID string, limit int) ([]jellyfin.MediaItem, error) {
+	return m.GetNextUp(ctx, limit)
+}
+
+// GetFavorites returns Favorites.
+func (m *Mock) GetFavorites(ctx context.Context) ([]jellyfin.MediaItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return append([]jellyfin.MediaItem(nil), m.Favorites...), nil
+}
+
+// GetPopular returns up to limit items of Popular.
+func (m *Mock) GetPopular(ctx context.Context, limit int) ([]jellyfin.MediaItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	items := m.Popular
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return append([]jellyfin.MediaItem(nil), items...), nil
+}
+
+// GetCollections returns Collections.
+func (m *Mock) GetCollections(ctx context.Context) ([]jellyfin.MediaItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return append([]jellyfin.MediaItem(nil), m.Collections...), nil
+}
+
+// GetCollectionItems returns CollectionItems[collectionID].
+func (m *Mock) GetCollectionItems(ctx context.Context, collectionID string) ([]jellyfin.MediaItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return append([]jellyfin.MediaItem(nil), m.CollectionItems[collectionID]...), nil
+}
+
+// GetPlaylistItems returns CollectionItems[playlistID].
+func (m *Mock) GetPlaylistItems(ctx context.Context, playlistID string) ([]jellyfin.MediaItem, error) {
+	return m.GetCollectionItems(ctx, playlistID)
+}
+
+// GetItemsByID returns the entries of Items for ids, skipping unknown IDs.
+func (m *Mock) GetItemsByID(ctx context.Context, ids []string) ([]jellyfin.MediaItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var items []jellyfin.MediaItem
+	for _, id := range ids {
+		if item, ok := m.Items[id]; ok {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// GetItemImage returns Images["{itemID}/{imageType}"], or
+// jellyfin.ErrNoImage if there is no such entry.
+func (m *Mock) GetItemImage(ctx context.Context, itemID, imageType string, maxWidth int) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	data, ok := m.Images[itemID+"/"+imageType]
+	if !ok {
+		return nil, jellyfin.ErrNoImage
+	}
+	return data, nil
+}
+
+// GetTrickplayInfo returns Trickplay[itemID] and Chapters[itemID].
+func (m *Mock) GetTrickplayInfo(ctx context.Context, itemID string) (*jellyfin.TrickplayInfo, []jellyfin.Chapter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, nil, m.Err
+	}
+	return m.Trickplay[itemID], m.Chapters[itemID], nil
+}
+
+// GetTrickplayTile returns Images["{itemID}/Trickplay/{index}"].
+func (m *Mock) GetTrickplayTile(ctx context.Context, itemID string, width, index int) ([]byte, error) {
+	return m.GetItemImage(ctx, itemID, fmt.Sprintf("Trickplay/%d", index), 0)
+}
+
+// GetChapterImage returns Images["{itemID}/Chapter/{index}"].
+func (m *Mock) GetChapterImage(ctx context.Context, itemID string, index, maxWidth int) ([]byte, error) {
+	return m.GetItemImage(ctx, itemID, fmt.Sprintf("Chapter/%d", index), maxWidth)
+}
+
+// GetSessions returns Sessions.
+func (m *Mock) GetSessions(ctx context.Context) ([]jellyfin.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return append([]jellyfin.Session(nil), m.Sessions...), nil
+}
+
+// GetResumeItems returns up to limit items of Resume[userID].
+func (m *Mock) GetResumeItems(ctx context.Context, userID string, limit int) ([]jellyfin.MediaItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	items := m.Resume[userID]
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return append([]jellyfin.MediaItem(nil), items...), nil
+}
+
+// Requests returns the media IDs resolved so far, in call order.
+func (m *Mock) Requests() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.requests...)
+}
+
+func (m *Mock) record(mediaID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, mediaID)
+}
@@ -0,0 +1,330 @@
+package jellyfin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// libraryItemTypes are the item types library sync stores. Series are kept
+// for their names and genres; their episodes are what gets cached.
+const libraryItemTypes = "Movie,Series,Episode"
+
+// libraryPageSize is how many items each library request returns.
+const libraryPageSize = 200
+
+// librarySyncOverlap widens incremental syncs so items saved on the server
+// while the previous sync was running are not missed.
+const librarySyncOverlap = 5 * time.Minute
+
+// Library is a top-level Jellyfin library (collection folder).
+type Library struct {
+	ID             string `json:"Id"`
+	Name           string `json:"Name"`
+	CollectionType string `json:"CollectionType"` // e.g. "movies", "tvshows"
+}
+
+// GetLibraries returns the libraries visible to the configured user.
+func (c *Client) GetLibraries(ctx context.Context) ([]Library, error) {
+	var body struct {
+		Items []Library `json:"Items"`
+	}
+	if err := c.getJSON(ctx, fmt.Sprintf("/Users/%s/Views", url.PathEscape(c.config.UserID)), url.Values{}, &body); err != nil {
+		return nil, fmt.Errorf("failed to get libraries: %w", err)
+	}
+	return body.Items, nil
+}
+
+// GetLibraryItems returns one page of the movies, series and episodes in
+// a library, oldest first, along with the library's total item count. A
+// non-zero since only returns items saved on the server after it.
+func (c *Client) GetLibraryItems(ctx context.Context, libraryID string, since time.Time, start, limit int) ([]MediaItem, int, error) {
+	query := url.Values{}
+	query.Set("ParentId", libraryID)
+	query.Set("Recursive", "true")
+	query.Set("IncludeItemTypes", libraryItemTypes)
+	query.Set("Fields", "Path,Overview,Genres,DateCreated,MediaStreams")
+	query.Set("SortBy", "DateCreated,SortName")
+	query.Set("SortOrder", "Ascending")
+	query.Set("StartIndex", strconv.Itoa(start))
+	query.Set("Limit", strconv.Itoa(limit))
+	if !since.IsZero() {
+		query.Set("MinDateLastSaved", since.UTC().Format(time.RFC3339))
+	}
+
+	var body itemsResponse
+	if err := c.getJSON(ctx, fmt.Sprintf("/Users/%s/Items", url.PathEscape(c.config.UserID)), query, &body); err != nil {
+		return nil, 0, fmt.Errorf("failed to get library items: %w", err)
+	}
+
+	items := make([]MediaItem, 0, len(body.Items))
+	for _, item := range body.Items {
+		items = append(items, item.toMediaItem())
+	}
+	return items, body.TotalRecordCount, nil
+}
+
+// LibraryStore is the metadata storage library sync reads and writes
+// (implemented by storage.Manager).
+type LibraryStore interface {
+	FindMediaMetadata(mediaID string) (*storage.MediaMetadata, error)
+	AddMediaMetadata(metadata *storage.MediaMetadata) error
+}
+
+// LibraryChange lists the items one library sync stored for the first time
+// or whose metadata changed on the server.
+type LibraryChange struct {
+	Added    []MediaItem
+	Updated  []MediaItem
+	SyncedAt time.Time
+}
+
+// LibrarySync mirrors the movies, series and episodes of the Jellyfin
+// libraries into the metadata store, so prediction, series caching and
+// the cache browser see the whole library rather than only items that
+// were played or cached. The first sync reads every item; later ones only
+// ask for items saved on the server since the previous sync. Excluded
+// libraries are skipped.
+type LibrarySync struct {
+	client   *Client
+	store    LibraryStore
+	interval time.Duration
+	logger   *slog.Logger
+
+	// now is stubbed by tests
+	now func() time.Time
+
+	mu        sync.Mutex
+	lastSync  time.Time // start of the last complete sync; zero forces a full one
+	listeners []func(LibraryChange)
+}
+
+// NewLibrarySync creates a library sync that runs every interval. An
+// interval of 0 disables Run.
+func NewLibrarySync(client *Client, store LibraryStore, interval time.Duration, logger *slog.Logger) *LibrarySync {
+	return &LibrarySync{
+		client:   client,
+		store:    store,
+		interval: interval,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// OnChange registers fn to be called after every sync that added or
+// updated items. fn runs on the syncing goroutine.
+func (s *LibrarySync) OnChange(fn func(LibraryChange)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// Enabled reports whether library syncing is turned on.
+func (s *LibrarySync) Enabled() bool {
+	return s.interval > 0
+}
+
+// Sync stores new and changed library items and returns what changed.
+// Items stored before an error are kept and reported, and the next sync
+// asks for everything the failed one was meant to cover.
+func (s *LibrarySync) Sync(ctx context.Context) (LibraryChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	started := s.now()
+	change := LibraryChange{SyncedAt: started}
+	err := s.sync(ctx, &change)
+	if err == nil {
+		s.lastSync = started
+	}
+
+	if len(change.Added) > 0 || len(change.Updated) > 0 {
+		for _, fn := range s.listeners {
+			fn(change)
+		}
+	}
+	return change, err
+}
+
+func (s *LibrarySync) sync(ctx context.Context, change *LibraryChange) error {
+	var since time.Time
+	if !s.lastSync.IsZero() {
+		since = s.lastSync.Add(-librarySyncOverlap)
+	}
+
+	libraries, err := s.client.GetLibraries(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, library := range libraries {
+		if !s.client.config.Libraries.Allows(library.Name) {
+			continue
+		}
+
+		// Servers may return fewer items than asked for, so page by count
+		for start := 0; ; {
+			items, total, err := s.client.GetLibraryItems(ctx, library.ID, since, start, libraryPageSize)
+			if err != nil {
+				return fmt.Errorf("library %s: %w", library.Name, err)
+			}
+
+			for _, item := range items {
+				item.LibraryName = library.Name
+				if err := s.storeItem(item, change); err != nil {
+					return err
+				}
+			}
+
+			start += len(items)
+			if len(items) == 0 || start >= total {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// storeItem writes item to the metadata store if it is new or changed.
+func (s *LibrarySync) storeItem(item MediaItem, change *LibraryChange) error {
+	metadata, err := s.store.FindMediaMetadata(item.ID)
+	if err != nil {
+		return err
+	}
+
+	added := metadata == nil
+	if added {
+		metadata = &storage.MediaMetadata{
+			ID:         item.ID,
+			JellyfinID: item.ID,
+			Type:       libraryMediaType(item.Type),
+			Container:  item.Container,
+		}
+	}
+	if !ApplyMetadata(metadata, item) && !added {
+		return nil
+	}
+
+	metadata.LastSynced = change.SyncedAt
+	if err := s.store.AddMediaMetadata(metadata); err != nil {
+		return fmt.Errorf("failed to store metadata for %s: %w", item.ID, err)
+	}
+
+	if added {
+		change.Added = append(change.Added, item)
+	} else {
+		change.Updated = append(change.Updated, item)
+	}
+	return nil
+}
+
+// Run syncs immediately and then every interval until ctx is cancelled.
+// It is a no-op when library syncing is disabled.
+func (s *LibrarySync) Run(ctx context.Context) {
+	if !s.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		change, err := s.Sync(ctx)
+		switch {
+		case errors.Is(err, ErrOffline):
+			s.client.connectivity.Defer("library", s.replay)
+		case err != nil && ctx.Err() == nil:
+			s.logger.Error("Library sync failed",
+				"added", len(change.Added),
+				"updated", len(change.Updated),
+				"error", err)
+		case err == nil:
+			s.logger.Info("Library sync complete",
+				"added", len(change.Added),
+				"updated", len(change.Updated))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// replay is the library sync deferred while the server was unreachable.
+func (s *LibrarySync) replay(ctx context.Context) error {
+	change, err := s.Sync(ctx)
+	if err == nil {
+		s.logger.Info("Library sync complete",
+			"added", len(change.Added),
+			"updated", len(change.Updated))
+	}
+	return err
+}
+
+// ApplyMetadata copies the fields Jellyfin owns onto stored metadata,
+// keeping anything only the cache knows, such as the file size. It
+// reports whether any of them changed.
+func ApplyMetadata(metadata *storage.MediaMetadata, item MediaItem) bool {
+	before := *metadata
+
+	metadata.Name = item.Name
+	metadata.Overview = item.Overview
+	metadata.Genres = item.Genres
+	if item.LibraryName != "" {
+		metadata.Library = item.LibraryName
+	}
+	if item.SeriesID != "" {
+		metadata.SeriesID = item.SeriesID
+		metadata.SeasonNumber = item.SeasonNumber
+		metadata.EpisodeNumber = item.EpisodeNumber
+	}
+	if item.AlbumID != "" {
+		metadata.AlbumID = item.AlbumID
+		metadata.DiscNumber = item.DiscNumber
+		metadata.TrackNumber = item.TrackNumber
+	}
+	if !item.DateCreated.IsZero() {
+		metadata.DateCreated = item.DateCreated
+	}
+	if item.RunTimeTicks > 0 {
+		metadata.RunTimeTicks = item.RunTimeTicks
+	}
+	// Only fetches that asked for the streams know the languages
+	if len(item.AudioLanguages) > 0 || len(item.SubtitleLanguages) > 0 {
+		metadata.AudioLanguages = item.AudioLanguages
+		metadata.SubtitleLanguages = item.SubtitleLanguages
+	}
+
+	return metadata.Name != before.Name ||
+		metadata.Overview != before.Overview ||
+		!slices.Equal(metadata.Genres, before.Genres) ||
+		metadata.Library != before.Library ||
+		metadata.SeriesID != before.SeriesID ||
+		metadata.SeasonNumber != before.SeasonNumber ||
+		metadata.EpisodeNumber != before.EpisodeNumber ||
+		metadata.AlbumID != before.AlbumID ||
+		metadata.DiscNumber != before.DiscNumber ||
+		metadata.TrackNumber != before.TrackNumber ||
+		metadata.RunTimeTicks != before.RunTimeTicks ||
+		!slices.Equal(metadata.AudioLanguages, before.AudioLanguages) ||
+		!slices.Equal(metadata.SubtitleLanguages, before.SubtitleLanguages) ||
+		!metadata.DateCreated.Equal(before.DateCreated)
+}
+
+// libraryMediaType is the stored media type of a synced item.
+func libraryMediaType(itemType string) string {
+	if itemType == "Series" {
+		return "series"
+	}
+	return CacheMediaType(itemType)
+}
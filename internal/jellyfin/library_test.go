@@ -0,0 +1,170 @@
+package jellyfin
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestLibrarySync(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		movieName = "Arrival"
+		requests  []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/Users/u1/Views":
+			fmt.Fprint(w, `{"Items":[{"Id":"lib-movies","Name":"Movies","CollectionType":"movies"},
+				{"Id":"lib-home","Name":"Home Videos","CollectionType":"homevideos"}]}`)
+		case "/Users/u1/Items":
+			query := r.URL.Query()
+			if !strings.Contains(query.Get("Fields"), "MediaStreams") {
+				t.Errorf("Expected media streams to be requested, got fields %q", query.Get("Fields"))
+			}
+			requests = append(requests, query.Get("ParentId")+"@"+query.Get("StartIndex")+"/"+query.Get("MinDateLastSaved"))
+
+			if query.Get("MinDateLastSaved") != "" {
+				fmt.Fprintf(w, `{"Items":[{"Id":"m1","Name":%q,"Type":"Movie","Genres":["Sci-Fi"],"DateCreated":"2024-05-01T00:00:00.0000000Z"}],"TotalRecordCount":1}`, movieName)
+				return
+			}
+			// The server returns two items per page however many are asked for
+			switch query.Get("StartIndex") {
+			case "0":
+				fmt.Fprintf(w, `{"Items":[
+					{"Id":"m1","Name":%q,"Type":"Movie","Genres":["Sci-Fi"],"DateCreated":"2024-05-01T00:00:00.0000000Z",
+						"MediaStreams":[{"Type":"Video"},{"Type":"Audio","Language":"eng"},{"Type":"Audio","Language":"fre"},
+							{"Type":"Audio","Language":"eng"},{"Type":"Subtitle","Language":"spa"},{"Type":"Subtitle","Language":"und"}]},
+					{"Id":"s1","Name":"Severance","Type":"Series"}],"TotalRecordCount":3}`, movieName)
+			case "2":
+				fmt.Fprint(w, `{"Items":[{"Id":"e1","Name":"Good News About Hell","Type":"Episode","SeriesId":"s1",
+					"ParentIndexNumber":1,"IndexNumber":1,"DateCreated":"2024-05-02T00:00:00Z"}],"TotalRecordCount":3}`)
+			default:
+				fmt.Fprint(w, `{"Items":[],"TotalRecordCount":3}`)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	client := New(&config.JellyfinConfig{
+		ServerURL: server.URL,
+		APIKey:    "test-api-key",
+		UserID:    "u1",
+		Libraries: config.LibraryFilterConfig{Exclude: []string{"Home Videos"}},
+	}, logger)
+
+	store := storagetest.New()
+	syncer := NewLibrarySync(client, store, time.Hour, logger)
+	var events []LibraryChange
+	syncer.OnChange(func(change LibraryChange) { events = append(events, change) })
+
+	change, err := syncer.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if len(change.Added) != 3 || len(change.Updated) != 0 {
+		t.Errorf("Expected 3 added items, got %d added and %d updated", len(change.Added), len(change.Updated))
+	}
+
+	episode, err := store.GetMediaMetadata("e1")
+	if err != nil {
+		t.Fatalf("Episode metadata not stored: %v", err)
+	}
+	if episode.Type != "episode" || episode.SeriesID != "s1" || episode.EpisodeNumber != 1 || episode.Library != "Movies" {
+		t.Errorf("Unexpected episode metadata: %+v", episode)
+	}
+	if !episode.DateCreated.Equal(time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected date created to be stored, got %v", episode.DateCreated)
+	}
+	movie, err := store.GetMediaMetadata("m1")
+	if err != nil {
+		t.Fatalf("Movie metadata not stored: %v", err)
+	}
+	if !slices.Equal(movie.AudioLanguages, []string{"eng", "fre"}) || !slices.Equal(movie.SubtitleLanguages, []string{"spa"}) {
+		t.Errorf("Expected stream languages to be stored, got audio %v and subtitles %v", movie.AudioLanguages, movie.SubtitleLanguages)
+	}
+	if series, err := store.GetMediaMetadata("s1"); err != nil || series.Type != "series" {
+		t.Errorf("Expected series metadata, got %+v (%v)", series, err)
+	}
+
+	// Nothing changed: no event. Then the movie is renamed on the server
+	if _, err := syncer.Sync(context.Background()); err != nil {
+		t.Fatalf("Second sync failed: %v", err)
+	}
+	mu.Lock()
+	movieName = "Arrival (2016)"
+	mu.Unlock()
+	change, err = syncer.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Third sync failed: %v", err)
+	}
+	if len(change.Added) != 0 || len(change.Updated) != 1 || change.Updated[0].ID != "m1" {
+		t.Errorf("Expected m1 to be updated, got %+v", change)
+	}
+	if movie, _ := store.GetMediaMetadata("m1"); movie == nil || movie.Name != "Arrival (2016)" || len(movie.AudioLanguages) != 2 {
+		t.Errorf("Expected renamed movie keeping its languages, got %+v", movie)
+	}
+
+	if len(events) != 2 {
+		t.Errorf("Expected change events for the first and third sync, got %d", len(events))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, req := range requests {
+		if strings.HasPrefix(req, "lib-home") {
+			t.Errorf("Excluded library was requested: %s", req)
+		}
+	}
+	if len(requests) != 4 || requests[0] != "lib-movies@0/" || requests[1] != "lib-movies@2/" || !strings.HasSuffix(requests[2], "Z") {
+		t.Errorf("Expected a paged full sync and then incremental syncs, got %v", requests)
+	}
+}
+
+func TestLibrarySyncDefersWhileOffline(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	client := New(&config.JellyfinConfig{ServerURL: server.URL, APIKey: "test-api-key", UserID: "u1"}, logger)
+	syncer := NewLibrarySync(client, storagetest.New(), time.Hour, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		syncer.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(client.Connectivity().Status().PendingSyncs) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if got := client.Connectivity().Status().PendingSyncs; len(got) != 1 || got[0] != "library" {
+		t.Errorf("Expected the library sync to be deferred, got %v", got)
+	}
+}
@@ -0,0 +1,116 @@
+package jellyfin
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// ticksPerSecond converts Jellyfin's 100ns ticks to seconds.
+const ticksPerSecond = 10_000_000
+
+// Variant is what to download for an item: the original file or a
+// server-side transcode.
+type Variant struct {
+	Quality   string // "original", or the transcode quality (1080p, 720p, 480p)
+	URL       string
+	Container string
+	Bitrate   int   // bits per second; 0 when unknown
+	Size      int64 // bytes; estimated from the bitrate for transcodes, 0 when unknown
+}
+
+// IsOriginal reports whether the variant is the original file.
+func (v *Variant) IsOriginal() bool {
+	return v.Quality == "original"
+}
+
+// playbackInfo is the part of the /Items/{id}/PlaybackInfo response used to
+// choose a variant.
+type playbackInfo struct {
+	MediaSources []struct {
+		Container           string `json:"Container"`
+		Size                int64  `json:"Size"`
+		Bitrate             int    `json:"Bitrate"`
+		RunTimeTicks        int64  `json:"RunTimeTicks"`
+		SupportsTranscoding bool   `json:"SupportsTranscoding"`
+		MediaStreams        []struct {
+			Type   string `json:"Type"`
+			Height int    `json:"Height"`
+		} `json:"MediaStreams"`
+	} `json:"MediaSources"`
+}
+
+// SelectVariant chooses between downloading mediaID's original file and a
+// transcode at preference (original, 1080p, 720p or 480p), using the
+// item's playback info. The original is kept when it is already within the
+// preferred height and bitrate, since transcoding would only cost quality,
+// and when the server cannot transcode the item.
+func (c *Client) SelectVariant(ctx context.Context, mediaID, preference string) (*Variant, error) {
+	if c.config.UserID == "" {
+		return nil, fmt.Errorf("user ID not configured")
+	}
+
+	limits, transcodable := variantLimits[preference]
+	if !transcodable && preference != "" && preference != "original" {
+		return nil, fmt.Errorf("unsupported quality: %s", preference)
+	}
+
+	query := url.Values{}
+	query.Set("UserId", c.config.UserID)
+
+	var info playbackInfo
+	if err := c.getJSON(ctx, "/Items/"+url.PathEscape(mediaID)+"/PlaybackInfo", query, &info); err != nil {
+		return nil, fmt.Errorf("failed to get playback info: %w", err)
+	}
+	if len(info.MediaSources) == 0 {
+		return nil, fmt.Errorf("item %s has no media sources", mediaID)
+	}
+	// The first source is the one Jellyfin plays by default
+	source := info.MediaSources[0]
+
+	height := 0
+	for _, stream := range source.MediaStreams {
+		if stream.Type == "Video" && stream.Height > height {
+			height = stream.Height
+		}
+	}
+
+	withinLimits := height <= limits.height && source.Bitrate > 0 && source.Bitrate <= limits.bitrate
+	if !transcodable || !source.SupportsTranscoding || withinLimits {
+		streamURL, err := c.GetStreamURL(mediaID)
+		if err != nil {
+			return nil, err
+		}
+		return &Variant{
+			Quality:   "original",
+			URL:       streamURL,
+			Container: source.Container,
+			Bitrate:   source.Bitrate,
+			Size:      source.Size,
+		}, nil
+	}
+
+	streamURL, err := c.GetVariantURL(mediaID, preference)
+	if err != nil {
+		return nil, err
+	}
+	variant := &Variant{
+		Quality:   preference,
+		URL:       streamURL,
+		Container: "mp4",
+		Bitrate:   limits.bitrate,
+	}
+	if source.Bitrate > 0 && source.Bitrate < variant.Bitrate {
+		variant.Bitrate = source.Bitrate
+	}
+	variant.Size = source.RunTimeTicks / ticksPerSecond * int64(variant.Bitrate) / 8
+
+	c.logger.Debug("Selected transcode for download",
+		"media_id", mediaID,
+		"quality", preference,
+		"source_height", height,
+		"source_bitrate", source.Bitrate,
+		"estimated_size", variant.Size)
+
+	return variant, nil
+}
@@ -0,0 +1,79 @@
+package jellyfin
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestClientSelectVariant(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("UserId") != "user1" {
+			t.Errorf("Unexpected playback info query %s", r.URL.RawQuery)
+		}
+		switch r.URL.Path {
+		case "/Items/big/PlaybackInfo":
+			// Two hours of 4K at 40 Mbit/s
+			fmt.Fprint(w, `{"MediaSources":[{"Container":"mkv","Size":36000000000,"Bitrate":40000000,"RunTimeTicks":72000000000,
+				"SupportsTranscoding":true,"MediaStreams":[{"Type":"Audio"},{"Type":"Video","Height":2160}]}]}`)
+		case "/Items/small/PlaybackInfo":
+			fmt.Fprint(w, `{"MediaSources":[{"Container":"mp4","Size":900000000,"Bitrate":3000000,"RunTimeTicks":24000000000,
+				"SupportsTranscoding":true,"MediaStreams":[{"Type":"Video","Height":576}]}]}`)
+		case "/Items/locked/PlaybackInfo":
+			fmt.Fprint(w, `{"MediaSources":[{"Container":"mkv","Size":36000000000,"Bitrate":40000000,
+				"SupportsTranscoding":false,"MediaStreams":[{"Type":"Video","Height":2160}]}]}`)
+		case "/Items/empty/PlaybackInfo":
+			fmt.Fprint(w, `{"MediaSources":[]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	client := New(&config.JellyfinConfig{ServerURL: server.URL, APIKey: "test-api-key", UserID: "user1"}, logger)
+	ctx := context.Background()
+
+	variant, err := client.SelectVariant(ctx, "big", "720p")
+	if err != nil {
+		t.Fatalf("SelectVariant failed: %v", err)
+	}
+	if variant.IsOriginal() || variant.Container != "mp4" || variant.Bitrate != 4_000_000 || !strings.Contains(variant.URL, "MaxHeight=720") {
+		t.Errorf("Expected 720p transcode, got %+v", variant)
+	}
+	if variant.Size != 7200*4_000_000/8 {
+		t.Errorf("Expected size estimated from runtime and bitrate, got %d", variant.Size)
+	}
+
+	variant, err = client.SelectVariant(ctx, "big", "original")
+	if err != nil || !variant.IsOriginal() || variant.Size != 36000000000 || variant.Container != "mkv" {
+		t.Errorf("Expected original, got %+v (%v)", variant, err)
+	}
+
+	// Already within 720p: transcoding would only cost quality
+	variant, err = client.SelectVariant(ctx, "small", "720p")
+	if err != nil || !variant.IsOriginal() || !strings.Contains(variant.URL, "Static=true") {
+		t.Errorf("Expected original for a small source, got %+v (%v)", variant, err)
+	}
+
+	variant, err = client.SelectVariant(ctx, "locked", "480p")
+	if err != nil || !variant.IsOriginal() {
+		t.Errorf("Expected original when the server cannot transcode, got %+v (%v)", variant, err)
+	}
+
+	if _, err := client.SelectVariant(ctx, "empty", "720p"); err == nil {
+		t.Error("Expected error for an item without media sources")
+	}
+	if _, err := client.SelectVariant(ctx, "big", "4k"); err == nil {
+		t.Error("Expected error for an unsupported quality")
+	}
+}
@@ -0,0 +1,98 @@
+package jellyfin
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// sessionActiveWithin limits GetSessions to clients seen this recently.
+const sessionActiveWithin = 15 * time.Minute
+
+// Session is a client connected to the Jellyfin server and what it is
+// playing.
+type Session struct {
+	ID            string     `json:"id"`
+	UserID        string     `json:"user_id"`
+	UserName      string     `json:"user_name"`
+	Client        string     `json:"client"`      // App name, e.g. "Jellyfin Android TV"
+	DeviceName    string     `json:"device_name"` // e.g. "Living Room"
+	LastActivity  time.Time  `json:"last_activity"`
+	NowPlaying    *MediaItem `json:"now_playing,omitempty"` // nil when idle
+	PositionTicks int64      `json:"position_ticks"`
+	IsPaused      bool       `json:"is_paused"`
+}
+
+// apiSession is a session as returned by the Jellyfin API.
+type apiSession struct {
+	ID               string    `json:"Id"`
+	UserID           string    `json:"UserId"`
+	UserName         string    `json:"UserName"`
+	Client           string    `json:"Client"`
+	DeviceName       string    `json:"DeviceName"`
+	LastActivityDate time.Time `json:"LastActivityDate"`
+	NowPlayingItem   *apiItem  `json:"NowPlayingItem"`
+	PlayState        struct {
+		PositionTicks int64 `json:"PositionTicks"`
+		IsPaused      bool  `json:"IsPaused"`
+	} `json:"PlayState"`
+}
+
+// GetSessions returns the sessions of every client active on the server in
+// the last few minutes, playing or not. The API key must belong to an
+// administrator to see other users' sessions.
+func (c *Client) GetSessions(ctx context.Context) ([]Session, error) {
+	query := url.Values{}
+	query.Set("ActiveWithinSeconds", strconv.Itoa(int(sessionActiveWithin.Seconds())))
+
+	var body []apiSession
+	if err := c.getJSON(ctx, "/Sessions", query, &body); err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	sessions := make([]Session, 0, len(body))
+	for _, s := range body {
+		session := Session{
+			ID:            s.ID,
+			UserID:        s.UserID,
+			UserName:      s.UserName,
+			Client:        s.Client,
+			DeviceName:    s.DeviceName,
+			LastActivity:  s.LastActivityDate,
+			PositionTicks: s.PlayState.PositionTicks,
+			IsPaused:      s.PlayState.IsPaused,
+		}
+		if s.NowPlayingItem != nil {
+			item := s.NowPlayingItem.toMediaItem()
+			session.NowPlaying = &item
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// GetResumeItems returns the items userID has partly watched or listened
+// to, most recently played first, with their playback position in
+// UserData.
+func (c *Client) GetResumeItems(ctx context.Context, userID string, limit int) ([]MediaItem, error) {
+	query := url.Values{}
+	query.Set("Recursive", "true")
+	query.Set("IncludeItemTypes", cacheableItemTypes)
+	query.Set("EnableUserData", "true")
+	if limit > 0 {
+		query.Set("Limit", strconv.Itoa(limit))
+	}
+
+	var body itemsResponse
+	if err := c.getJSON(ctx, fmt.Sprintf("/Users/%s/Items/Resume", url.PathEscape(userID)), query, &body); err != nil {
+		return nil, fmt.Errorf("failed to get resume items: %w", err)
+	}
+
+	items := make([]MediaItem, 0, len(body.Items))
+	for _, item := range body.Items {
+		items = append(items, item.toMediaItem())
+	}
+	return items, nil
+}
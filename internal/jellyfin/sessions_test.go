@@ -0,0 +1,67 @@
+package jellyfin
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestClientGetSessionsAndResumeItems(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/Sessions":
+			fmt.Fprint(w, `[
+				{"Id":"sess1","UserId":"u1","Client":"Jellyfin Android TV","DeviceName":"Living Room",
+				 "NowPlayingItem":{"Id":"e1","Type":"Episode","SeriesId":"s1","ParentIndexNumber":2,"IndexNumber":3,"RunTimeTicks":27000000000},
+				 "PlayState":{"PositionTicks":9000000000,"IsPaused":true}},
+				{"Id":"sess2","UserId":"u2","Client":"Jellyfin Web"}
+			]`)
+		case "/Users/u1/Items/Resume":
+			fmt.Fprint(w, `{"Items":[{"Id":"m1","Type":"Movie","RunTimeTicks":72000000000,
+				"UserData":{"PlaybackPositionTicks":36000000000,"LastPlayedDate":"2024-05-01T20:00:00Z"}}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	client := New(&config.JellyfinConfig{ServerURL: server.URL, APIKey: "test-api-key", UserID: "u1"}, logger)
+
+	sessions, err := client.GetSessions(context.Background())
+	if err != nil {
+		t.Fatalf("GetSessions failed: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got %d", len(sessions))
+	}
+	playing := sessions[0]
+	if playing.NowPlaying == nil || playing.NowPlaying.SeasonNumber != 2 || playing.NowPlaying.RunTimeTicks != 27000000000 {
+		t.Errorf("Unexpected now playing item %+v", playing.NowPlaying)
+	}
+	if playing.PositionTicks != 9000000000 || !playing.IsPaused || playing.Client != "Jellyfin Android TV" {
+		t.Errorf("Unexpected play state %+v", playing)
+	}
+	if sessions[1].NowPlaying != nil {
+		t.Errorf("Expected idle session, got %+v", sessions[1].NowPlaying)
+	}
+
+	resume, err := client.GetResumeItems(context.Background(), "u1", 20)
+	if err != nil {
+		t.Fatalf("GetResumeItems failed: %v", err)
+	}
+	if len(resume) != 1 || resume[0].UserData == nil || resume[0].UserData.PlaybackPositionTicks != 36000000000 {
+		t.Errorf("Unexpected resume items %+v", resume)
+	}
+	if resume[0].UserData.LastPlayedDate.IsZero() {
+		t.Error("Expected last played date to be parsed")
+	}
+}
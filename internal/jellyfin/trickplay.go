@@ -0,0 +1,91 @@
+package jellyfin
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// TrickplayInfo describes the trickplay tiles Jellyfin generated for an
+// item: sprite sheets of TileWidth x TileHeight thumbnails, one thumbnail
+// every Interval milliseconds.
+type TrickplayInfo struct {
+	Width          int `json:"Width"`
+	Height         int `json:"Height"`
+	TileWidth      int `json:"TileWidth"`
+	TileHeight     int `json:"TileHeight"`
+	ThumbnailCount int `json:"ThumbnailCount"`
+	Interval       int `json:"Interval"`
+}
+
+// Tiles returns how many sprite sheets hold the thumbnails.
+func (t *TrickplayInfo) Tiles() int {
+	perTile := t.TileWidth * t.TileHeight
+	if perTile <= 0 {
+		return 0
+	}
+	return (t.ThumbnailCount + perTile - 1) / perTile
+}
+
+// Chapter is a chapter marker of an item.
+type Chapter struct {
+	Name               string `json:"Name"`
+	StartPositionTicks int64  `json:"StartPositionTicks"`
+	// ImageTag is set when Jellyfin extracted an image for the chapter
+	ImageTag string `json:"ImageTag"`
+}
+
+// GetTrickplayInfo returns the narrowest trickplay resolution generated for
+// an item, or nil if there is none, along with the item's chapters.
+func (c *Client) GetTrickplayInfo(ctx context.Context, itemID string) (*TrickplayInfo, []Chapter, error) {
+	query := url.Values{}
+	query.Set("Fields", "Trickplay,Chapters")
+
+	var body struct {
+		// Trickplay is keyed by media source ID, then by width
+		Trickplay map[string]map[string]TrickplayInfo `json:"Trickplay"`
+		Chapters  []Chapter                           `json:"Chapters"`
+	}
+	path := fmt.Sprintf("/Users/%s/Items/%s", url.PathEscape(c.config.UserID), url.PathEscape(itemID))
+	if err := c.getJSON(ctx, path, query, &body); err != nil {
+		return nil, nil, fmt.Errorf("failed to get trickplay info of %s: %w", itemID, err)
+	}
+
+	var resolutions []TrickplayInfo
+	for _, byWidth := range body.Trickplay {
+		for _, info := range byWidth {
+			if info.Tiles() > 0 && info.Interval > 0 {
+				resolutions = append(resolutions, info)
+			}
+		}
+	}
+	if len(resolutions) == 0 {
+		return nil, body.Chapters, nil
+	}
+	sort.Slice(resolutions, func(i, j int) bool { return resolutions[i].Width < resolutions[j].Width })
+	return &resolutions[0], body.Chapters, nil
+}
+
+// GetTrickplayTile downloads sprite sheet index of an item's trickplay
+// tiles at width.
+func (c *Client) GetTrickplayTile(ctx context.Context, itemID string, width, index int) ([]byte, error) {
+	path := fmt.Sprintf("/Videos/%s/Trickplay/%d/%d.jpg", url.PathEscape(itemID), width, index)
+	data, err := c.getImage(ctx, path, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trickplay tile %d of %s: %w", index, itemID, err)
+	}
+	return data, nil
+}
+
+// GetChapterImage downloads the image of chapter index of an item, scaled
+// down to at most maxWidth pixels wide when maxWidth is positive.
+func (c *Client) GetChapterImage(ctx context.Context, itemID string, index, maxWidth int) ([]byte, error) {
+	path := fmt.Sprintf("/Items/%s/Images/Chapter/%s", url.PathEscape(itemID), strconv.Itoa(index))
+	data, err := c.getImage(ctx, path, maxWidth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chapter %d image of %s: %w", index, itemID, err)
+	}
+	return data, nil
+}
@@ -7,11 +7,13 @@ import "time"
 type MediaItem struct {
 	ID                string    `json:"id"`
 	Name              string    `json:"name"`
-	Type              string    `json:"type"` // Movie, Episode, Series, etc.
+	Type              string    `json:"type"` // Movie, Episode, Series, Audio, AudioBook, etc.
 	Path              string    `json:"path"`
 	Container         string    `json:"container"`
 	Size              int64     `json:"size"`
 	Bitrate           int       `json:"bitrate"`
+	RunTimeTicks      int64     `json:"run_time_ticks,omitempty"` // Duration in 100ns ticks
+	LibraryName       string    `json:"library_name,omitempty"` // Library (collection folder) the item belongs to
 	
 	// Series information (for episodes)
 	SeriesID          string    `json:"series_id,omitempty"`
@@ -19,12 +21,22 @@ type MediaItem struct {
 	SeasonNumber      int       `json:"season_number,omitempty"`
 	EpisodeNumber     int       `json:"episode_number,omitempty"`
 	
+	// Album information (for music tracks and audiobook chapters)
+	AlbumID           string    `json:"album_id,omitempty"`
+	AlbumName         string    `json:"album_name,omitempty"`
+	DiscNumber        int       `json:"disc_number,omitempty"`  // ParentIndexNumber in Jellyfin
+	TrackNumber       int       `json:"track_number,omitempty"` // IndexNumber in Jellyfin
+	
 	// Metadata
 	Overview          string    `json:"overview,omitempty"`
 	Genres            []string  `json:"genres,omitempty"`
 	Studios           []string  `json:"studios,omitempty"`
+	CommunityRating   float64   `json:"community_rating,omitempty"` // 0-10
 	DateCreated       time.Time `json:"date_created"`
 	DateAdded         time.Time `json:"date_added"`
+	// Languages of the audio and subtitle streams, when requested
+	AudioLanguages    []string  `json:"audio_languages,omitempty"`
+	SubtitleLanguages []string  `json:"subtitle_languages,omitempty"`
 	
 	// Playback information
 	PlaybackInfo      *PlaybackInfo `json:"playback_info,omitempty"`
@@ -87,7 +99,10 @@ type UserData struct {
 // LibraryItem represents an item in a Jellyfin library.
 type LibraryItem struct {
 	MediaItem
-	Children []LibraryItem `json:"children,omitempty"` // For series with episodes
+	Children  []LibraryItem `json:"children,omitempty"`  // For series with episodes
+	Pinned    bool          `json:"pinned,omitempty"`    // Protected from cache eviction
+	Stale     bool          `json:"stale,omitempty"`     // Metadata is older than cache.metadata_max_age_days and being refreshed
+	Thumbnail string        `json:"thumbnail,omitempty"` // URL of the cached poster, when there is one
 }
 
 // ViewingSession represents a user's viewing session for analytics.
@@ -0,0 +1,109 @@
+package jellyfin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Notification types of the Jellyfin webhook plugin that describe
+// playback.
+const (
+	WebhookPlaybackStart    = "PlaybackStart"
+	WebhookPlaybackProgress = "PlaybackProgress"
+	WebhookPlaybackStop     = "PlaybackStop"
+)
+
+// WebhookEvent is a notification from the Jellyfin webhook plugin's
+// generic destination.
+type WebhookEvent struct {
+	NotificationType   string
+	UserID             string
+	Item               MediaItem
+	PositionTicks      int64
+	PlayedToCompletion bool
+	Client             string // App name, e.g. "Jellyfin Android TV"
+	DeviceName         string
+}
+
+// ParseWebhookEvent decodes a webhook plugin notification. The plugin
+// posts whatever its template renders, and fields it has no value for
+// render empty, so numbers and booleans are accepted quoted or bare and
+// missing ones are zero. IDs are normalized to the form the Jellyfin API
+// uses.
+func ParseWebhookEvent(r io.Reader) (*WebhookEvent, error) {
+	var fields map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&fields); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook: %w", err)
+	}
+
+	event := &WebhookEvent{
+		NotificationType: webhookString(fields["NotificationType"]),
+		UserID:           webhookID(fields["UserId"]),
+		Client:           webhookString(fields["ClientName"]),
+		DeviceName:       webhookString(fields["DeviceName"]),
+		Item: MediaItem{
+			ID:       webhookID(fields["ItemId"]),
+			Name:     webhookString(fields["Name"]),
+			Type:     webhookString(fields["ItemType"]),
+			SeriesID: webhookID(fields["SeriesId"]),
+		},
+	}
+	if event.NotificationType == "" {
+		return nil, fmt.Errorf("webhook has no NotificationType")
+	}
+
+	var err error
+	if event.Item.SeasonNumber, err = webhookInt[int](fields, "SeasonNumber"); err != nil {
+		return nil, err
+	}
+	if event.Item.EpisodeNumber, err = webhookInt[int](fields, "EpisodeNumber"); err != nil {
+		return nil, err
+	}
+	if event.Item.RunTimeTicks, err = webhookInt[int64](fields, "RunTimeTicks"); err != nil {
+		return nil, err
+	}
+	if event.PositionTicks, err = webhookInt[int64](fields, "PlaybackPositionTicks"); err != nil {
+		return nil, err
+	}
+	if s := webhookString(fields["PlayedToCompletion"]); s != "" {
+		if event.PlayedToCompletion, err = strconv.ParseBool(strings.ToLower(s)); err != nil {
+			return nil, fmt.Errorf("invalid PlayedToCompletion %q", s)
+		}
+	}
+
+	return event, nil
+}
+
+// webhookString returns a field as text, unquoting strings.
+func webhookString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return strings.TrimSpace(s)
+	}
+	if string(raw) == "null" {
+		return ""
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// webhookID returns an ID field without the dashes the plugin may format
+// GUIDs with.
+func webhookID(raw json.RawMessage) string {
+	return strings.ToLower(strings.ReplaceAll(webhookString(raw), "-", ""))
+}
+
+// webhookInt parses the named numeric field; empty is 0.
+func webhookInt[T int | int64](fields map[string]json.RawMessage, name string) (T, error) {
+	s := webhookString(fields[name])
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", name, s)
+	}
+	return T(n), nil
+}
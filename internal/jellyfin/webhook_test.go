@@ -0,0 +1,51 @@
+package jellyfin
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseWebhookEvent(t *testing.T) {
+	event, err := ParseWebhookEvent(strings.NewReader(`{
+		"NotificationType": "PlaybackStop",
+		"UserId": "5D1C6A3E-0B2F-4C8A-9E1D-7F2A3B4C5D6E",
+		"ItemId": "a1b2c3d4e5f60718293a4b5c6d7e8f90",
+		"ItemType": "Episode",
+		"Name": "Pilot",
+		"SeriesId": "",
+		"SeasonNumber": "1",
+		"EpisodeNumber": 2,
+		"RunTimeTicks": 27000000000,
+		"PlaybackPositionTicks": "",
+		"PlayedToCompletion": "True",
+		"ClientName": "Jellyfin Android TV",
+		"DeviceName": "Living Room"
+	}`))
+	if err != nil {
+		t.Fatalf("ParseWebhookEvent failed: %v", err)
+	}
+
+	if event.UserID != "5d1c6a3e0b2f4c8a9e1d7f2a3b4c5d6e" {
+		t.Errorf("Expected the user ID in API form, got %q", event.UserID)
+	}
+	if event.Item.ID != "a1b2c3d4e5f60718293a4b5c6d7e8f90" || event.Item.Type != "Episode" {
+		t.Errorf("Unexpected item %+v", event.Item)
+	}
+	if event.Item.SeasonNumber != 1 || event.Item.EpisodeNumber != 2 || event.Item.RunTimeTicks != 27000000000 {
+		t.Errorf("Quoted and bare numbers should both parse, got %+v", event.Item)
+	}
+	if event.PositionTicks != 0 || !event.PlayedToCompletion {
+		t.Errorf("Unexpected play state %+v", event)
+	}
+
+	for name, body := range map[string]string{
+		"invalid json":    `{`,
+		"no type":         `{"ItemId": "a1"}`,
+		"invalid number":  `{"NotificationType": "PlaybackStart", "RunTimeTicks": "soon"}`,
+		"invalid boolean": `{"NotificationType": "PlaybackStop", "PlayedToCompletion": "maybe"}`,
+	} {
+		if _, err := ParseWebhookEvent(strings.NewReader(body)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+// Package maintenance runs expensive background work for go-jf-watch inside
+// a configured daily maintenance window, keeping the rest of the day
+// responsive.
+//
+// Design Philosophy:
+// - Tasks run once per window occurrence, in registration order
+// - A task's context is cancelled when the window closes
+// - Tasks that could not start before the window closed wait for the next one
+// - Without a configured window, tasks run once a day at any time
+// - DST changes neither skip nor repeat a window; nor does setting the clock back
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// checkInterval is how often Run checks whether tasks are due.
+const checkInterval = time.Minute
+
+// unrestrictedInterval is how often tasks run when no window is configured.
+const unrestrictedInterval = 24 * time.Hour
+
+// TaskFunc performs a unit of maintenance work. It should return promptly
+// once ctx is cancelled.
+type TaskFunc func(ctx context.Context) error
+
+// TaskStatus reports the outcome of a task's most recent run.
+type TaskStatus struct {
+	Name      string        `json:"name"`
+	LastRun   time.Time     `json:"last_run,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	LastError string        `json:"last_error,omitempty"`
+}
+
+// Status describes the maintenance window and its tasks.
+type Status struct {
+	Enabled bool          `json:"enabled"`
+	Start   string        `json:"start,omitempty"`
+	End     string        `json:"end,omitempty"`
+	Active  bool          `json:"active"`
+	Tasks   []*TaskStatus `json:"tasks"`
+}
+
+type task struct {
+	name   string
+	fn     TaskFunc
+	status TaskStatus
+	done   time.Time // window (or day) the task last completed in
+}
+
+// Scheduler runs registered tasks inside the maintenance window.
+type Scheduler struct {
+	config *config.MaintenanceConfig
+	logger *slog.Logger
+
+	mu    sync.Mutex
+	tasks []*task
+
+	// runMu keeps RunDue passes from overlapping
+	runMu sync.Mutex
+
+	now func() time.Time
+}
+
+// New creates a scheduler for the given maintenance window.
+func New(cfg *config.MaintenanceConfig, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		config: cfg,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Register adds a task. Tasks run in the order they were registered.
+func (s *Scheduler) Register(name string, fn TaskFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tasks = append(s.tasks, &task{name: name, fn: fn, status: TaskStatus{Name: name}})
+}
+
+// Run checks for due tasks every minute until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	s.RunDue(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.RunDue(ctx)
+		}
+	}
+}
+
+// RunDue runs every task that has not yet completed in the current window
+// and returns how many ran. Outside the window it does nothing. Each task's
+// context is cancelled when the window closes; tasks that have not started
+// by then are left for the next window.
+func (s *Scheduler) RunDue(ctx context.Context) int {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	now := s.now()
+	if !s.config.IsActive(now) {
+		return 0
+	}
+
+	// Identify this window occurrence by its end; without a window, tasks
+	// are due once a day
+	windowEnd := s.config.WindowEnd(now)
+	runCtx := ctx
+	if !windowEnd.IsZero() {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithDeadline(ctx, windowEnd)
+		defer cancel()
+	}
+
+	s.mu.Lock()
+	tasks := append([]*task(nil), s.tasks...)
+	s.mu.Unlock()
+
+	ran := 0
+	for _, t := range tasks {
+		if !s.due(t, now, windowEnd) {
+			continue
+		}
+		if runCtx.Err() != nil {
+			s.logger.Info("Maintenance window closed, deferring task", "task", t.name)
+			break
+		}
+
+		started := s.now()
+		s.logger.Info("Running maintenance task", "task", t.name)
+		err := t.fn(runCtx)
+		ran++
+
+		s.mu.Lock()
+		t.status.LastRun = started
+		t.status.Duration = s.now().Sub(started)
+		t.status.LastError = ""
+		if err != nil {
+			t.status.LastError = err.Error()
+		}
+		// A task cut short by the window closing is retried next window
+		if err == nil || runCtx.Err() == nil {
+			if windowEnd.IsZero() {
+				t.done = started
+			} else {
+				t.done = windowEnd
+			}
+		}
+		s.mu.Unlock()
+
+		if err != nil {
+			s.logger.Warn("Maintenance task failed", "task", t.name, "error", err)
+		}
+	}
+
+	return ran
+}
+
+// due reports whether t still has to run in the window ending at windowEnd.
+func (s *Scheduler) due(t *task, now, windowEnd time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if windowEnd.IsZero() {
+		// A clock set back past the last run would otherwise hold the task
+		// off for as long as the jump
+		if now.Before(t.done) {
+			t.done = now
+		}
+		return t.done.IsZero() || now.Sub(t.done) >= unrestrictedInterval
+	}
+
+	// Windows are identified by when they close, so a clock set back into
+	// an earlier occurrence does not repeat work already done
+	return t.done.IsZero() || windowEnd.After(t.done)
+}
+
+// Status returns the window configuration and the last run of every task.
+func (s *Scheduler) Status() *Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := &Status{
+		Enabled: s.config.Enabled,
+		Active:  s.config.Enabled && s.config.IsActive(s.now()),
+		Tasks:   make([]*TaskStatus, 0, len(s.tasks)),
+	}
+	if s.config.Enabled {
+		status.Start = s.config.Start
+		status.End = s.config.End
+	}
+	for _, t := range s.tasks {
+		taskStatus := t.status
+		status.Tasks = append(status.Tasks, &taskStatus)
+	}
+
+	return status
+}
+
+// RegisterStandardTasks registers the built-in maintenance work: cache
+// verification, routine cache cleanup (which is then deferred to the window
+// outside of emergencies), queue reconciliation for every household user,
+// viewing history and event log pruning and database compaction. The
+// predictor may be nil.
+func RegisterStandardTasks(s *Scheduler, sm *storage.Manager, cm *storage.CacheManager, predictor *downloader.Predictor) {
+	s.Register("verify-cache", func(ctx context.Context) error {
+		result, err := sm.VerifyCache(ctx)
+		if err != nil {
+			return err
+		}
+		if len(result.Errors) > 0 {
+			return fmt.Errorf("%d items could not be verified", len(result.Errors))
+		}
+		return nil
+	})
+
+	cm.SetMaintenanceWindow(s.config)
+	s.Register("cache-cleanup", func(ctx context.Context) error {
+		return cm.CleanupCache()
+	})
+
+	if predictor != nil {
+		s.Register("reconcile-queue", func(ctx context.Context) error {
+			_, err := predictor.RunHouseholdCycle(ctx)
+			return err
+		})
+	}
+
+	s.Register("prune-history", func(ctx context.Context) error {
+		_, err := sm.PruneViewingHistory(ctx)
+		return err
+	})
+
+	s.Register("prune-events", func(ctx context.Context) error {
+		_, err := sm.PruneEvents(ctx)
+		return err
+	})
+
+	s.Register("compact-database", func(ctx context.Context) error {
+		_, err := sm.CompactDatabase(ctx)
+		return err
+	})
+}
+
+// RegisterTrickplayTask registers caching the seek previews Jellyfin
+// generated since cached videos were downloaded.
+func RegisterTrickplayTask(s *Scheduler, cache *downloader.TrickplayCache) {
+	s.Register("cache-trickplay", func(ctx context.Context) error {
+		_, err := cache.CacheMissing(ctx)
+		return err
+	})
+}
+
+// RegisterNFOTask registers rewriting the .nfo files of every cached item,
+// which fills them in for items cached before cache.nfo_export was turned
+// on.
+func RegisterNFOTask(s *Scheduler, exporter *downloader.NFOExporter) {
+	s.Register("export-nfo", func(ctx context.Context) error {
+		_, err := exporter.ExportAll(ctx)
+		return err
+	})
+}
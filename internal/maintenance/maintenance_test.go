@@ -0,0 +1,152 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// windowAround returns a maintenance window spanning from..to relative to now.
+func windowAround(now time.Time, from, to time.Duration) *config.MaintenanceConfig {
+	return &config.MaintenanceConfig{
+		Enabled: true,
+		Start:   now.Add(from).Format("15:04"),
+		End:     now.Add(to).Format("15:04"),
+	}
+}
+
+func newTestScheduler(cfg *config.MaintenanceConfig) *Scheduler {
+	return New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestRunDueInsideWindow(t *testing.T) {
+	now := time.Now()
+	s := newTestScheduler(windowAround(now, -time.Hour, time.Hour))
+
+	var order []string
+	var deadline time.Time
+	s.Register("verify", func(ctx context.Context) error {
+		order = append(order, "verify")
+		deadline, _ = ctx.Deadline()
+		return nil
+	})
+	s.Register("compact", func(ctx context.Context) error {
+		order = append(order, "compact")
+		return errors.New("disk busy")
+	})
+
+	assert.Equal(t, 2, s.RunDue(context.Background()))
+	assert.Equal(t, []string{"verify", "compact"}, order)
+	assert.Equal(t, s.config.WindowEnd(now), deadline, "tasks stop when the window closes")
+
+	// Each task runs once per window, even if it failed
+	assert.Equal(t, 0, s.RunDue(context.Background()))
+
+	status := s.Status()
+	assert.True(t, status.Active)
+	require.Len(t, status.Tasks, 2)
+	assert.Empty(t, status.Tasks[0].LastError)
+	assert.Equal(t, "disk busy", status.Tasks[1].LastError)
+	assert.False(t, status.Tasks[1].LastRun.IsZero())
+}
+
+func TestRunDueOutsideWindow(t *testing.T) {
+	s := newTestScheduler(windowAround(time.Now(), 2*time.Hour, 3*time.Hour))
+
+	ran := false
+	s.Register("verify", func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+
+	assert.Equal(t, 0, s.RunDue(context.Background()))
+	assert.False(t, ran)
+	assert.False(t, s.Status().Active)
+}
+
+func TestRunDueDefersTasksAfterWindowCloses(t *testing.T) {
+	s := newTestScheduler(windowAround(time.Now(), -time.Hour, time.Hour))
+
+	// The first task runs until the window closes, here simulated by
+	// cancelling the parent context
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Register("verify", func(context.Context) error {
+		cancel()
+		return nil
+	})
+
+	calls := 0
+	s.Register("resync", func(ctx context.Context) error {
+		calls++
+		return ctx.Err()
+	})
+
+	assert.Equal(t, 1, s.RunDue(ctx))
+	assert.Equal(t, 0, calls, "tasks do not start once the window has closed")
+
+	assert.Equal(t, 1, s.RunDue(context.Background()), "the deferred task runs on the next pass")
+	assert.Equal(t, 1, calls)
+}
+
+func TestRunDueWithoutWindow(t *testing.T) {
+	s := newTestScheduler(&config.MaintenanceConfig{})
+	current := time.Now()
+	s.now = func() time.Time { return current }
+
+	calls := 0
+	s.Register("verify", func(ctx context.Context) error {
+		_, hasDeadline := ctx.Deadline()
+		assert.False(t, hasDeadline)
+		calls++
+		return nil
+	})
+
+	assert.Equal(t, 1, s.RunDue(context.Background()))
+	current = current.Add(time.Hour)
+	assert.Equal(t, 0, s.RunDue(context.Background()), "tasks run once a day without a window")
+	current = current.Add(24 * time.Hour)
+	assert.Equal(t, 1, s.RunDue(context.Background()))
+	assert.Equal(t, 2, calls)
+	assert.False(t, s.Status().Enabled)
+}
+
+func TestDueIgnoresClockSetBack(t *testing.T) {
+	cfg := &config.MaintenanceConfig{Enabled: true, Start: "03:00", End: "05:00"}
+	s := newTestScheduler(cfg)
+
+	day := time.Date(2026, time.October, 14, 4, 0, 0, 0, time.UTC)
+	tk := &task{name: "verify", done: cfg.WindowEnd(day)}
+
+	// Setting the clock back into yesterday's window does not repeat the run
+	yesterday := day.AddDate(0, 0, -1)
+	assert.False(t, s.due(tk, yesterday, cfg.WindowEnd(yesterday)))
+	assert.False(t, s.due(tk, day, cfg.WindowEnd(day)))
+
+	tomorrow := day.AddDate(0, 0, 1)
+	assert.True(t, s.due(tk, tomorrow, cfg.WindowEnd(tomorrow)))
+}
+
+func TestRunDueUnrestrictedRecoversFromClockSetBack(t *testing.T) {
+	start := time.Date(2026, time.October, 14, 12, 0, 0, 0, time.UTC)
+	s := newTestScheduler(&config.MaintenanceConfig{})
+	s.Register("verify", func(ctx context.Context) error { return nil })
+
+	s.now = func() time.Time { return start }
+	assert.Equal(t, 1, s.RunDue(context.Background()))
+
+	// A week's jump back must not hold the task off for a week
+	back := start.AddDate(0, 0, -7)
+	s.now = func() time.Time { return back }
+	assert.Equal(t, 0, s.RunDue(context.Background()))
+
+	s.now = func() time.Time { return back.Add(unrestrictedInterval) }
+	assert.Equal(t, 1, s.RunDue(context.Background()))
+}
@@ -0,0 +1,47 @@
+// Package media defines a backend-neutral media identity model for go-jf-watch.
+// A media ID names the provider that owns an item together with the item's
+// provider-specific ID, so the cache, queue, prediction and streaming layers
+// can work with items from any backend.
+//
+// Design Philosophy:
+// - Jellyfin IDs stay bare ("abc123") so existing cache and queue keys remain valid
+// - Other providers use a "provider:item" prefix ("local:Zm9vLm1rdg")
+// - Providers expose where an item's bytes live, not how to cache them
+package media
+
+import "strings"
+
+// Built-in provider names.
+const (
+	ProviderJellyfin = "jellyfin"
+	ProviderLocal    = "local"
+)
+
+// ID identifies a media item across providers.
+type ID struct {
+	Provider string
+	ItemID   string
+}
+
+// ParseID parses a media ID string. IDs without a provider prefix are
+// treated as Jellyfin IDs, which never contain a colon.
+func ParseID(s string) ID {
+	if provider, itemID, ok := strings.Cut(s, ":"); ok && provider != "" {
+		return ID{Provider: provider, ItemID: itemID}
+	}
+	return ID{Provider: ProviderJellyfin, ItemID: s}
+}
+
+// String returns the canonical string form of the ID. Jellyfin IDs are
+// returned bare for compatibility with existing storage keys.
+func (id ID) String() string {
+	if id.Provider == ProviderJellyfin || id.Provider == "" {
+		return id.ItemID
+	}
+	return id.Provider + ":" + id.ItemID
+}
+
+// IsJellyfin reports whether the ID belongs to the Jellyfin provider.
+func (id ID) IsJellyfin() bool {
+	return id.Provider == ProviderJellyfin || id.Provider == ""
+}
@@ -0,0 +1,32 @@
+package media
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseID(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ID
+	}{
+		{"a1b2c3d4", ID{Provider: ProviderJellyfin, ItemID: "a1b2c3d4"}},
+		{"local:Zm9vLm1rdg", ID{Provider: ProviderLocal, ItemID: "Zm9vLm1rdg"}},
+		{"plex:12345", ID{Provider: "plex", ItemID: "12345"}},
+		{":missing-provider", ID{Provider: ProviderJellyfin, ItemID: ":missing-provider"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			id := ParseID(tt.input)
+			assert.Equal(t, tt.expected, id)
+			assert.Equal(t, tt.input, id.String(), "String() round-trips")
+		})
+	}
+}
+
+func TestIDIsJellyfin(t *testing.T) {
+	assert.True(t, ParseID("a1b2c3d4").IsJellyfin())
+	assert.False(t, ParseID("local:abc").IsJellyfin())
+}
@@ -0,0 +1,38 @@
+package media
+
+import (
+	"context"
+	"errors"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+)
+
+// JellyfinProvider adapts the Jellyfin client to the Provider interface.
+type JellyfinProvider struct {
+	client *jellyfin.Client
+}
+
+// NewJellyfinProvider wraps a Jellyfin client as a media provider.
+func NewJellyfinProvider(client *jellyfin.Client) *JellyfinProvider {
+	return &JellyfinProvider{client: client}
+}
+
+// Name returns the provider name.
+func (p *JellyfinProvider) Name() string {
+	return ProviderJellyfin
+}
+
+// ListItems is not yet supported; Jellyfin items are discovered through
+// library sync rather than provider enumeration.
+func (p *JellyfinProvider) ListItems(ctx context.Context) ([]*Item, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// Source returns the direct stream URL for a Jellyfin item.
+func (p *JellyfinProvider) Source(ctx context.Context, itemID string) (*Source, error) {
+	url, err := p.client.GetStreamURL(itemID)
+	if err != nil {
+		return nil, err
+	}
+	return &Source{URL: url}, nil
+}
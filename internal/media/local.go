@@ -0,0 +1,143 @@
+package media
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// videoExtensions lists file extensions the local provider treats as media.
+var videoExtensions = map[string]bool{
+	".mkv":  true,
+	".mp4":  true,
+	".m4v":  true,
+	".avi":  true,
+	".mov":  true,
+	".webm": true,
+	".ts":   true,
+}
+
+// IsVideoFile reports whether name has the extension of a video file the
+// local provider would list.
+func IsVideoFile(name string) bool {
+	return videoExtensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// LocalProvider exposes video files already present in a local folder.
+// It is read-only: files are served in place and never downloaded, moved
+// or evicted. Item IDs are the URL-safe base64 encoding of the file's path
+// relative to the root, so IDs are stable across restarts.
+type LocalProvider struct {
+	root   string
+	logger *slog.Logger
+}
+
+// NewLocalProvider creates a provider for the video files under root.
+func NewLocalProvider(root string, logger *slog.Logger) (*LocalProvider, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve local library directory %s: %w", root, err)
+	}
+
+	info, err := os.Stat(absRoot)
+	if err != nil {
+		return nil, fmt.Errorf("local library directory %s is not accessible: %w", absRoot, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("local library path %s is not a directory", absRoot)
+	}
+
+	return &LocalProvider{root: absRoot, logger: logger}, nil
+}
+
+// Name returns the provider name.
+func (p *LocalProvider) Name() string {
+	return ProviderLocal
+}
+
+// ListItems walks the root directory and returns every video file found.
+// Unreadable subdirectories are skipped rather than failing the listing.
+func (p *LocalProvider) ListItems(ctx context.Context) ([]*Item, error) {
+	var items []*Item
+
+	err := filepath.WalkDir(p.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			p.logger.Debug("Skipping unreadable path in local library", "path", path, "error", err)
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if d.IsDir() || !IsVideoFile(path) {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		rel, err := filepath.Rel(p.root, path)
+		if err != nil {
+			return nil
+		}
+
+		items = append(items, &Item{
+			ID:        ID{Provider: ProviderLocal, ItemID: encodeLocalID(rel)},
+			Name:      strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+			Type:      "Movie",
+			Container: strings.TrimPrefix(ext, "."),
+			Size:      info.Size(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan local library: %w", err)
+	}
+
+	return items, nil
+}
+
+// Source returns the on-disk path of the item. IDs that decode to a path
+// outside the root are rejected.
+func (p *LocalProvider) Source(ctx context.Context, itemID string) (*Source, error) {
+	rel, err := decodeLocalID(itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(p.root, rel)
+	if check, err := filepath.Rel(p.root, path); err != nil || check == ".." || strings.HasPrefix(check, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("local item %s is outside the library directory", itemID)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("local item %s not found: %w", itemID, err)
+	}
+
+	return &Source{LocalPath: path}, nil
+}
+
+// encodeLocalID converts a root-relative path into an item ID.
+func encodeLocalID(rel string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(filepath.ToSlash(rel)))
+}
+
+// decodeLocalID converts an item ID back into a root-relative path.
+func decodeLocalID(itemID string) (string, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(itemID)
+	if err != nil || len(decoded) == 0 {
+		return "", fmt.Errorf("invalid local item ID %q", itemID)
+	}
+	return filepath.FromSlash(string(decoded)), nil
+}
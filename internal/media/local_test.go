@@ -0,0 +1,87 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLocalProvider(t *testing.T) (*LocalProvider, string) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "Movies"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "Movies", "Heat (1995).mkv"), []byte("video"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "clip.MP4"), []byte("clip!!"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "notes.txt"), []byte("not video"), 0644))
+
+	provider, err := NewLocalProvider(root, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	return provider, root
+}
+
+func TestLocalProviderListItems(t *testing.T) {
+	provider, _ := newTestLocalProvider(t)
+
+	items, err := provider.ListItems(context.Background())
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+
+	byName := make(map[string]*Item)
+	for _, item := range items {
+		byName[item.Name] = item
+		assert.Equal(t, ProviderLocal, item.ID.Provider)
+	}
+
+	require.Contains(t, byName, "Heat (1995)")
+	assert.Equal(t, "mkv", byName["Heat (1995)"].Container)
+	assert.Equal(t, int64(5), byName["Heat (1995)"].Size)
+	assert.Equal(t, "mp4", byName["clip"].Container)
+}
+
+func TestLocalProviderSource(t *testing.T) {
+	provider, root := newTestLocalProvider(t)
+
+	items, err := provider.ListItems(context.Background())
+	require.NoError(t, err)
+
+	registry := NewRegistry(provider)
+	for _, item := range items {
+		source, err := registry.Source(context.Background(), ParseID(item.ID.String()))
+		require.NoError(t, err)
+		assert.Empty(t, source.URL)
+		assert.True(t, filepath.IsAbs(source.LocalPath))
+		assert.FileExists(t, source.LocalPath)
+	}
+
+	// IDs that escape the root are rejected
+	escape := encodeLocalID(filepath.Join("..", filepath.Base(root)+"-other", "x.mkv"))
+	_, err = provider.Source(context.Background(), escape)
+	assert.Error(t, err)
+
+	_, err = provider.Source(context.Background(), "!!not-base64")
+	assert.Error(t, err)
+
+	_, err = provider.Source(context.Background(), encodeLocalID("missing.mkv"))
+	assert.Error(t, err)
+}
+
+func TestRegistryUnknownProvider(t *testing.T) {
+	registry := NewRegistry()
+
+	_, err := registry.Source(context.Background(), ID{Provider: "plex", ItemID: "1"})
+	assert.True(t, errors.Is(err, ErrUnknownProvider))
+}
+
+func TestNewLocalProviderRejectsFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file.mkv")
+	require.NoError(t, os.WriteFile(file, []byte("x"), 0644))
+
+	_, err := NewLocalProvider(file, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.Error(t, err)
+}
@@ -0,0 +1,87 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrUnknownProvider is returned when an ID names a provider that is not registered.
+var ErrUnknownProvider = errors.New("unknown media provider")
+
+// Item describes a media item exposed by a provider.
+type Item struct {
+	ID            ID       `json:"-"`
+	Name          string   `json:"name"`
+	Type          string   `json:"type"` // Movie, Episode, etc.
+	Container     string   `json:"container,omitempty"`
+	Size          int64    `json:"size"`
+	Genres        []string `json:"genres,omitempty"`
+	SeriesName    string   `json:"series_name,omitempty"`
+	SeasonNumber  int      `json:"season_number,omitempty"`
+	EpisodeNumber int      `json:"episode_number,omitempty"`
+}
+
+// Source describes where an item's media bytes can be read from. Exactly one
+// field is set: URL for remote items that must be streamed or downloaded,
+// LocalPath for items already on local disk that need no caching.
+type Source struct {
+	URL       string
+	LocalPath string
+}
+
+// Provider is a media backend that can enumerate items and locate their media.
+type Provider interface {
+	// Name returns the provider name used as the ID prefix.
+	Name() string
+
+	// ListItems returns all items the provider exposes.
+	ListItems(ctx context.Context) ([]*Item, error)
+
+	// Source returns where the media for itemID can be read from.
+	Source(ctx context.Context, itemID string) (*Source, error)
+}
+
+// Registry looks up providers by name.
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]Provider
+}
+
+// NewRegistry creates a registry containing the given providers.
+func NewRegistry(providers ...Provider) *Registry {
+	r := &Registry{providers: make(map[string]Provider)}
+	for _, p := range providers {
+		r.Register(p)
+	}
+	return r
+}
+
+// Register adds a provider, replacing any existing provider with the same name.
+func (r *Registry) Register(p Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[p.Name()] = p
+}
+
+// Get returns the provider with the given name.
+func (r *Registry) Get(name string) (Provider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return p, nil
+}
+
+// Source resolves the media source for an ID via its provider.
+func (r *Registry) Source(ctx context.Context, id ID) (*Source, error) {
+	p, err := r.Get(id.Provider)
+	if err != nil {
+		return nil, err
+	}
+	return p.Source(ctx, id.ItemID)
+}
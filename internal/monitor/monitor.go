@@ -0,0 +1,274 @@
+// Package monitor implements "jf-watch top", a terminal dashboard for a
+// running go-jf-watch server. It polls the REST API for the queue and cache
+// fill and follows the progress WebSocket for live download progress, so a
+// headless server can be watched over SSH without a browser.
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/opd-ai/go-jf-watch/internal/server"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// maxEvents is how many recent events the monitor keeps.
+const maxEvents = 50
+
+// Reconnect backoff bounds for the progress WebSocket.
+const (
+	minReconnectDelay = 2 * time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// Snapshot is everything the monitor knows about the server at one moment.
+type Snapshot struct {
+	BaseURL   string
+	Summary   *server.WidgetSummary
+	Queue     []server.QueueItem
+	Live      map[string]server.ProgressUpdate // latest update per media ID
+	Events    []server.ProgressUpdate          // oldest first
+	Connected bool                             // progress WebSocket is up
+	Err       error                            // last REST error, nil once a poll succeeds
+	UpdatedAt time.Time
+}
+
+// Monitor tracks a go-jf-watch server through its API.
+type Monitor struct {
+	baseURL  string
+	client   *http.Client
+	interval time.Duration
+	logger   *slog.Logger
+
+	// changed is signalled when a WebSocket update arrives
+	changed chan struct{}
+
+	mu        sync.Mutex
+	summary   *server.WidgetSummary
+	queue     []server.QueueItem
+	live      map[string]server.ProgressUpdate
+	events    []server.ProgressUpdate
+	connected bool
+	err       error
+	updatedAt time.Time
+}
+
+// New creates a monitor for the server at baseURL (e.g.
+// "http://127.0.0.1:8080") that polls the API every interval.
+func New(baseURL string, interval time.Duration, logger *slog.Logger) *Monitor {
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	return &Monitor{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		interval: interval,
+		logger:   logger,
+		changed:  make(chan struct{}, 1),
+		live:     make(map[string]server.ProgressUpdate),
+	}
+}
+
+// URLFromConfig returns the base URL of the server configured by cfg. A
+// wildcard listen address is reached over loopback.
+func URLFromConfig(cfg *config.ServerConfig) string {
+	host := cfg.Host
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(cfg.Port))
+}
+
+// Refresh polls the widget summary and the download queue.
+func (m *Monitor) Refresh(ctx context.Context) error {
+	var summary server.WidgetSummary
+	err := m.getJSON(ctx, "/api/widgets/summary", &summary)
+
+	var queue []server.QueueItem
+	if err == nil {
+		resp := server.APIResponse{Data: &queue}
+		err = m.getJSON(ctx, "/api/queue", &resp)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.err = err
+	if err != nil {
+		return err
+	}
+	m.summary = &summary
+	m.queue = queue
+	m.updatedAt = time.Now()
+
+	// Drop live progress for downloads no longer in flight
+	downloading := make(map[string]bool)
+	for _, item := range queue {
+		if item.Status == "downloading" {
+			downloading[item.MediaID] = true
+		}
+	}
+	for mediaID := range m.live {
+		if !downloading[mediaID] {
+			delete(m.live, mediaID)
+		}
+	}
+	return nil
+}
+
+// getJSON fetches path and decodes the JSON body into v.
+func (m *Monitor) getJSON(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", path, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("GET %s: failed to decode response: %w", path, err)
+	}
+	return nil
+}
+
+// Follow subscribes to the progress WebSocket until ctx is cancelled,
+// reconnecting with backoff when the connection drops.
+func (m *Monitor) Follow(ctx context.Context) {
+	wsURL, err := url.Parse(m.baseURL + "/ws/progress")
+	if err != nil {
+		m.logger.Error("Invalid server URL", "url", m.baseURL, "error", err)
+		return
+	}
+	if wsURL.Scheme == "https" {
+		wsURL.Scheme = "wss"
+	} else {
+		wsURL.Scheme = "ws"
+	}
+
+	delay := minReconnectDelay
+	for ctx.Err() == nil {
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL.String(), nil)
+		if err == nil {
+			delay = minReconnectDelay
+			m.setConnected(true)
+			m.readUpdates(ctx, conn)
+			m.setConnected(false)
+		} else {
+			m.logger.Debug("Progress WebSocket unavailable", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+// readUpdates applies updates from conn until it fails or ctx is cancelled.
+func (m *Monitor) readUpdates(ctx context.Context, conn *websocket.Conn) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	defer conn.Close()
+
+	for {
+		var update server.ProgressUpdate
+		if err := conn.ReadJSON(&update); err != nil {
+			return
+		}
+		m.apply(update)
+	}
+}
+
+// apply records a WebSocket update. Progress ticks only move the live
+// progress bars; anything else is also logged as an event.
+func (m *Monitor) apply(update server.ProgressUpdate) {
+	if update.Timestamp.IsZero() {
+		update.Timestamp = time.Now()
+	}
+
+	m.mu.Lock()
+	if update.MediaID != "" {
+		if update.Status == "downloading" {
+			m.live[update.MediaID] = update
+		} else {
+			delete(m.live, update.MediaID)
+		}
+	}
+	if update.Status != "downloading" {
+		m.events = append(m.events, update)
+		if len(m.events) > maxEvents {
+			m.events = m.events[len(m.events)-maxEvents:]
+		}
+	}
+	m.mu.Unlock()
+
+	select {
+	case m.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (m *Monitor) setConnected(connected bool) {
+	m.mu.Lock()
+	m.connected = connected
+	m.mu.Unlock()
+
+	select {
+	case m.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Snapshot returns a copy of the monitor's current view of the server.
+func (m *Monitor) Snapshot() Snapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snap := Snapshot{
+		BaseURL:   m.baseURL,
+		Queue:     append([]server.QueueItem(nil), m.queue...),
+		Live:      make(map[string]server.ProgressUpdate, len(m.live)),
+		Events:    append([]server.ProgressUpdate(nil), m.events...),
+		Connected: m.connected,
+		Err:       m.err,
+		UpdatedAt: m.updatedAt,
+	}
+	if m.summary != nil {
+		summary := *m.summary
+		snap.Summary = &summary
+	}
+	for mediaID, update := range m.live {
+		snap.Live[mediaID] = update
+	}
+	return snap
+}
@@ -0,0 +1,147 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/server"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func newTestServer(t *testing.T, updates []server.ProgressUpdate) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/widgets/summary", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(server.WidgetSummary{
+			Version:     1,
+			CachedItems: 12,
+			Cache:       server.WidgetCache{UsedBytes: 5 << 30, MaxBytes: 10 << 30, FillPercent: 50},
+		})
+	})
+	mux.HandleFunc("/api/queue", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(server.APIResponse{Success: true, Data: []server.QueueItem{
+			{ID: "j1", MediaID: "m1", Title: "Pilot", Priority: 1, Status: "downloading", Progress: 0.25},
+			{ID: "j2", MediaID: "m2", Title: "Later", Priority: 4, Status: "queued"},
+			{ID: "j3", MediaID: "m3", Title: "Urgent", Priority: 0, Status: "queued"},
+		}})
+	})
+	upgrader := websocket.Upgrader{}
+	mux.HandleFunc("/ws/progress", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for _, update := range updates {
+			conn.WriteJSON(update)
+		}
+		conn.ReadMessage() // hold the connection open until the client leaves
+	})
+
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestMonitorRefreshAndFollow(t *testing.T) {
+	ts := newTestServer(t, []server.ProgressUpdate{
+		{Type: "download", MediaID: "m1", Status: "downloading", Progress: 60, Speed: 2 << 20, ETA: "1m"},
+		{Type: "download", MediaID: "m4", Status: "completed", Progress: 100, Message: "done"},
+	})
+
+	m := New(ts.URL, time.Second, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, m.Refresh(ctx))
+	go m.Follow(ctx)
+
+	require.Eventually(t, func() bool {
+		snap := m.Snapshot()
+		return snap.Connected && len(snap.Events) == 1 && len(snap.Live) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	snap := m.Snapshot()
+	require.NotNil(t, snap.Summary)
+	assert.Equal(t, 12, snap.Summary.CachedItems)
+	assert.Len(t, snap.Queue, 3)
+	assert.Equal(t, "m4", snap.Events[0].MediaID, "progress ticks are not events")
+	assert.Equal(t, 60.0, snap.Live["m1"].Progress)
+
+	frame := Render(snap, 100)
+	assert.Contains(t, frame, "[live]")
+	assert.Contains(t, frame, "50.0%")
+	assert.Contains(t, frame, " 60.0%", "live progress overrides the polled value")
+	assert.Contains(t, frame, "2.0 MiB/s")
+	assert.Contains(t, frame, "completed")
+	assert.Less(t, strings.Index(frame, "P0  Urgent"), strings.Index(frame, "P4  Later"), "queue is ordered by priority")
+}
+
+func TestMonitorRefreshError(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	m := New(ts.URL, time.Second, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	err := m.Refresh(context.Background())
+	require.Error(t, err)
+
+	frame := Render(m.Snapshot(), 80)
+	assert.Contains(t, frame, "error:")
+	assert.Contains(t, frame, "[offline]")
+}
+
+func TestRenderTruncatesQueue(t *testing.T) {
+	var queue []server.QueueItem
+	for i := 0; i < maxQueueLines+3; i++ {
+		queue = append(queue, server.QueueItem{MediaID: "m", Title: strings.Repeat("x", 200), Status: "queued"})
+	}
+
+	frame := Render(Snapshot{Queue: queue}, 60)
+	assert.Contains(t, frame, "… and 3 more")
+	for _, line := range strings.Split(frame, "\n") {
+		assert.LessOrEqual(t, len([]rune(line)), 60)
+	}
+}
+
+func TestModelKeysAndResize(t *testing.T) {
+	ts := newTestServer(t, nil)
+	m := New(ts.URL, time.Second, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, m.Refresh(context.Background()))
+
+	var mdl tea.Model = newModel(context.Background(), m)
+	mdl, _ = mdl.Update(tea.WindowSizeMsg{Width: 50, Height: 20})
+	for _, line := range strings.Split(mdl.View(), "\n") {
+		assert.LessOrEqual(t, len([]rune(line)), 50, "frames fit the terminal width")
+	}
+
+	_, cmd := mdl.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	require.NotNil(t, cmd)
+	assert.IsType(t, refreshedMsg{}, cmd(), "r polls the server")
+
+	_, cmd = mdl.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	require.NotNil(t, cmd)
+	assert.IsType(t, tea.QuitMsg{}, cmd(), "q quits")
+
+	_, cmd = mdl.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	require.NotNil(t, cmd)
+	assert.IsType(t, tea.QuitMsg{}, cmd(), "Ctrl-C quits")
+}
+
+func TestURLFromConfig(t *testing.T) {
+	assert.Equal(t, "http://127.0.0.1:8080", URLFromConfig(&config.ServerConfig{Host: "0.0.0.0", Port: 8080}))
+	assert.Equal(t, "http://media.lan:9000", URLFromConfig(&config.ServerConfig{Host: "media.lan", Port: 9000}))
+	assert.Equal(t, "http://[::1]:8080", URLFromConfig(&config.ServerConfig{Host: "::1", Port: 8080}))
+}
@@ -0,0 +1,190 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/opd-ai/go-jf-watch/internal/server"
+)
+
+// Layout limits; sections are truncated with a "… and N more" line.
+const (
+	maxQueueLines = 10
+	maxEventLines = 8
+)
+
+// Render draws snap as a frame of at most width columns. It is a pure
+// function of its input so the layout can be tested without a terminal.
+func Render(snap Snapshot, width int) string {
+	if width < 40 {
+		width = 40
+	}
+
+	var b strings.Builder
+	line := func(format string, args ...interface{}) {
+		b.WriteString(truncate(fmt.Sprintf(format, args...), width))
+		b.WriteByte('\n')
+	}
+
+	live := "offline"
+	if snap.Connected {
+		live = "live"
+	}
+	updated := "never"
+	if !snap.UpdatedAt.IsZero() {
+		updated = snap.UpdatedAt.Format("15:04:05")
+	}
+	line("jf-watch top  %s  [%s]  updated %s", snap.BaseURL, live, updated)
+	if snap.Err != nil {
+		line("error: %v", snap.Err)
+	}
+	b.WriteByte('\n')
+
+	if snap.Summary != nil {
+		cache := snap.Summary.Cache
+		line("Cache  %s %5.1f%%  %s / %s  %d items",
+			bar(cache.FillPercent/100, 20),
+			cache.FillPercent,
+			formatBytes(cache.UsedBytes),
+			formatBytes(cache.MaxBytes),
+			snap.Summary.CachedItems)
+		b.WriteByte('\n')
+	}
+
+	titles := make(map[string]string, len(snap.Queue))
+	var downloading, queued []server.QueueItem
+	for _, item := range snap.Queue {
+		titles[item.MediaID] = item.Title
+		switch item.Status {
+		case "downloading":
+			downloading = append(downloading, item)
+		case "queued":
+			queued = append(queued, item)
+		}
+	}
+
+	// Title, bar, percent and speed/ETA share the line
+	titleWidth := width - 48
+	if titleWidth < 12 {
+		titleWidth = 12
+	}
+
+	line("Downloading (%d)", len(downloading))
+	if len(downloading) == 0 {
+		line("  nothing in flight")
+	}
+	for _, item := range downloading {
+		fraction := item.Progress
+		detail := ""
+		if update, ok := snap.Live[item.MediaID]; ok {
+			fraction = update.Progress / 100
+			if update.Speed > 0 {
+				detail = formatBytes(update.Speed) + "/s"
+			}
+			if update.ETA != "" {
+				detail += "  ETA " + update.ETA
+			}
+		}
+		line("  %-*s %s %5.1f%%  %s",
+			titleWidth, truncate(displayTitle(item.Title, item.MediaID), titleWidth),
+			bar(fraction, 20), fraction*100, detail)
+	}
+	b.WriteByte('\n')
+
+	// Most urgent first, oldest first within a priority
+	sort.SliceStable(queued, func(i, j int) bool {
+		if queued[i].Priority != queued[j].Priority {
+			return queued[i].Priority < queued[j].Priority
+		}
+		return queued[i].AddedAt.Before(queued[j].AddedAt)
+	})
+
+	line("Queue (%d)", len(queued))
+	if len(queued) == 0 {
+		line("  empty")
+	}
+	for i, item := range queued {
+		if i == maxQueueLines {
+			line("  … and %d more", len(queued)-maxQueueLines)
+			break
+		}
+		size := ""
+		if item.Size > 0 {
+			size = formatBytes(item.Size)
+		}
+		line("  P%d  %-*s %s", item.Priority, titleWidth, truncate(displayTitle(item.Title, item.MediaID), titleWidth), size)
+	}
+	b.WriteByte('\n')
+
+	line("Recent events")
+	if len(snap.Events) == 0 {
+		line("  none yet")
+	}
+	events := snap.Events
+	if len(events) > maxEventLines {
+		events = events[len(events)-maxEventLines:]
+	}
+	for i := len(events) - 1; i >= 0; i-- {
+		event := events[i]
+		title := event.Title
+		if title == "" {
+			title = titles[event.MediaID]
+		}
+		if title == "" {
+			title = event.MediaID
+		}
+		text := strings.TrimSpace(title + "  " + event.Message)
+		line("  %s  %-10s %s", event.Timestamp.Format("15:04:05"), event.Status, text)
+	}
+	b.WriteByte('\n')
+
+	line("q quit  r refresh")
+	return b.String()
+}
+
+// bar draws a progress bar of width cells for fraction (0 to 1).
+func bar(fraction float64, width int) string {
+	if fraction < 0 {
+		fraction = 0
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	filled := int(fraction*float64(width) + 0.5)
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", width-filled) + "]"
+}
+
+// displayTitle falls back to the media ID for items without a title.
+func displayTitle(title, mediaID string) string {
+	if title == "" {
+		return mediaID
+	}
+	return title
+}
+
+// truncate shortens s to at most width runes, marking the cut with "…".
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	if width <= 1 {
+		return string(runes[:width])
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// formatBytes renders a byte count with a binary unit.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/term"
+)
+
+// defaultWidth is the frame width until the terminal reports its size.
+const defaultWidth = 80
+
+// Messages driving the dashboard model.
+type (
+	tickMsg      struct{} // time to poll the API again
+	refreshedMsg struct{} // a poll finished
+	changedMsg   struct{} // a WebSocket update arrived
+)
+
+// model is the bubbletea model of the dashboard. It holds no state of its
+// own beyond the terminal width; every frame is rendered from the monitor's
+// latest snapshot.
+type model struct {
+	ctx     context.Context
+	monitor *Monitor
+	width   int
+}
+
+func newModel(ctx context.Context, m *Monitor) model {
+	return model{ctx: ctx, monitor: m, width: defaultWidth}
+}
+
+func (mdl model) Init() tea.Cmd {
+	return tea.Batch(mdl.refresh(), mdl.tick(), mdl.waitForChange())
+}
+
+func (mdl model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "Q", "ctrl+c":
+			return mdl, tea.Quit
+		case "r", "R":
+			return mdl, mdl.refresh()
+		}
+	case tea.WindowSizeMsg:
+		mdl.width = msg.Width
+	case tickMsg:
+		return mdl, tea.Batch(mdl.refresh(), mdl.tick())
+	case changedMsg:
+		return mdl, mdl.waitForChange()
+	}
+	return mdl, nil
+}
+
+func (mdl model) View() string {
+	return Render(mdl.monitor.Snapshot(), mdl.width)
+}
+
+// refresh polls the API in the background.
+func (mdl model) refresh() tea.Cmd {
+	return func() tea.Msg {
+		if err := mdl.monitor.Refresh(mdl.ctx); err != nil && mdl.ctx.Err() == nil {
+			mdl.monitor.logger.Debug("Failed to poll server", "error", err)
+		}
+		return refreshedMsg{}
+	}
+}
+
+// tick schedules the next poll.
+func (mdl model) tick() tea.Cmd {
+	return tea.Tick(mdl.monitor.interval, func(time.Time) tea.Msg {
+		return tickMsg{}
+	})
+}
+
+// waitForChange redraws once the next WebSocket update arrives. The
+// renderer caps the frame rate, so progress ticks need no throttling here.
+func (mdl model) waitForChange() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case <-mdl.monitor.changed:
+			return changedMsg{}
+		case <-mdl.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Run draws the dashboard to out until ctx is cancelled or, when in is a
+// terminal, the user presses q or Ctrl-C. r forces an immediate refresh.
+func (m *Monitor) Run(ctx context.Context, in *os.File, out io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go m.Follow(ctx)
+
+	opts := []tea.ProgramOption{tea.WithContext(ctx), tea.WithOutput(out), tea.WithAltScreen()}
+	if in != nil && term.IsTerminal(int(in.Fd())) {
+		opts = append(opts, tea.WithInput(in))
+	} else {
+		opts = append(opts, tea.WithInput(nil))
+	}
+
+	_, err := tea.NewProgram(newModel(ctx, m), opts...).Run()
+	if ctx.Err() != nil {
+		// Cancelled from outside rather than quit by the user
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// EmailSink delivers notifications over SMTP. Notifications with an HTML
+// rendering are sent as multipart/alternative with a plain text fallback.
+type EmailSink struct {
+	config   *config.EmailConfig
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewEmailSink creates an SMTP sink.
+func NewEmailSink(cfg *config.EmailConfig) *EmailSink {
+	return &EmailSink{config: cfg, sendMail: smtp.SendMail}
+}
+
+// Name returns the sink name.
+func (e *EmailSink) Name() string {
+	return "email"
+}
+
+// Send delivers the notification to all configured recipients.
+func (e *EmailSink) Send(ctx context.Context, n *Notification) error {
+	msg, err := e.buildMessage(n)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if e.config.Username != "" {
+		auth = smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.SMTPHost)
+	}
+
+	addr := fmt.Sprintf("%s:%d", e.config.SMTPHost, e.config.SMTPPort)
+	if err := e.sendMail(addr, auth, e.config.From, e.config.To, msg); err != nil {
+		return fmt.Errorf("failed to send email via %s: %w", addr, err)
+	}
+	return nil
+}
+
+// buildMessage renders the notification as an RFC 5322 message.
+func (e *EmailSink) buildMessage(n *Notification) ([]byte, error) {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "From: %s\r\n", e.config.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(e.config.To, ", "))
+	// Titles may name media in any language; RFC 2047 keeps them valid
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", n.Title))
+	fmt.Fprintf(&buf, "Date: %s\r\n", n.CreatedAt.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	if n.HTML == "" {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&buf, n.Body); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", writer.Boundary())
+
+	parts := []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", n.Body},
+		{"text/html; charset=utf-8", n.HTML},
+	}
+	for _, p := range parts {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create email part: %w", err)
+		}
+		if err := writeQuotedPrintable(part, p.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish email body: %w", err)
+	}
+
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+// writeQuotedPrintable writes content to w using quoted-printable encoding.
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return fmt.Errorf("failed to encode email body: %w", err)
+	}
+	return qp.Close()
+}
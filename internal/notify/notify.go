@@ -0,0 +1,114 @@
+// Package notify delivers notifications such as disk warnings and scheduled
+// reports to the sinks configured by the user (email, webhooks).
+//
+// Design Philosophy:
+// - Sinks are independent; one failing sink never blocks the others
+// - A Dispatcher with no sinks configured is valid and discards notifications
+// - Notifications carry a plain text body and an optional HTML rendering
+// - Non-critical notifications wait out quiet hours and arrive as a digest
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// Severity classifies how urgent a notification is.
+type Severity string
+
+// Notification severities.
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Notification is a message delivered to every configured sink.
+type Notification struct {
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`           // Plain text body
+	HTML      string    `json:"html,omitempty"` // Optional HTML rendering of Body
+	Severity  Severity  `json:"severity"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Sink delivers notifications to a single destination.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, n *Notification) error
+}
+
+// Dispatcher fans notifications out to all configured sinks.
+type Dispatcher struct {
+	sinks  []Sink
+	logger *slog.Logger
+
+	// Notifications held back during quiet hours
+	quietHours *config.QuietHoursConfig
+	pending    []*Notification
+	dropped    int
+	mu         sync.Mutex
+	now        func() time.Time
+}
+
+// NewDispatcher creates a dispatcher with the sinks enabled in cfg.
+func NewDispatcher(cfg *config.NotificationsConfig, logger *slog.Logger) *Dispatcher {
+	var sinks []Sink
+	if cfg.Email.Enabled {
+		sinks = append(sinks, NewEmailSink(&cfg.Email))
+	}
+	if cfg.Webhook.Enabled {
+		sinks = append(sinks, NewWebhookSink(&cfg.Webhook))
+	}
+	d := NewDispatcherWithSinks(logger, sinks...)
+	d.quietHours = &cfg.QuietHours
+	return d
+}
+
+// NewDispatcherWithSinks creates a dispatcher with explicit sinks.
+func NewDispatcherWithSinks(logger *slog.Logger, sinks ...Sink) *Dispatcher {
+	return &Dispatcher{sinks: sinks, logger: logger, now: time.Now}
+}
+
+// Notify sends n to every sink. Delivery continues past failing sinks and
+// the returned error joins all sink failures. During quiet hours non-critical
+// notifications are held and later delivered by FlushDigest.
+func (d *Dispatcher) Notify(ctx context.Context, n *Notification) error {
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = d.now()
+	}
+	if n.Severity == "" {
+		n.Severity = SeverityInfo
+	}
+
+	if d.hold(n) {
+		d.logger.Debug("Holding notification until quiet hours end", "title", n.Title)
+		return nil
+	}
+
+	// Anything held back is delivered before newer notifications
+	flushErr := d.FlushDigest(ctx)
+	return errors.Join(flushErr, d.send(ctx, n))
+}
+
+// send delivers n to every sink.
+func (d *Dispatcher) send(ctx context.Context, n *Notification) error {
+	var errs []error
+	for _, sink := range d.sinks {
+		if err := sink.Send(ctx, n); err != nil {
+			d.logger.Warn("Failed to deliver notification",
+				"sink", sink.Name(), "title", n.Title, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+			continue
+		}
+		d.logger.Debug("Delivered notification", "sink", sink.Name(), "title", n.Title)
+	}
+
+	return errors.Join(errs...)
+}
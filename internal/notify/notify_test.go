@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+type recordingSink struct {
+	name string
+	err  error
+	sent []*Notification
+}
+
+func (r *recordingSink) Name() string { return r.name }
+
+func (r *recordingSink) Send(ctx context.Context, n *Notification) error {
+	r.sent = append(r.sent, n)
+	return r.err
+}
+
+func TestDispatcherContinuesPastFailingSink(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	failing := &recordingSink{name: "failing", err: errors.New("boom")}
+	working := &recordingSink{name: "working"}
+
+	dispatcher := NewDispatcherWithSinks(logger, failing, working)
+	err := dispatcher.Notify(context.Background(), &Notification{Title: "Disk nearly full"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failing")
+	require.Len(t, working.sent, 1)
+	assert.Equal(t, SeverityInfo, working.sent[0].Severity, "severity defaults to info")
+	assert.False(t, working.sent[0].CreatedAt.IsZero())
+}
+
+func TestWebhookSink(t *testing.T) {
+	var received Notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(&config.WebhookConfig{Enabled: true, URL: server.URL})
+	err := sink.Send(context.Background(), &Notification{Title: "Weekly report", Severity: SeverityInfo})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Weekly report", received.Title)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	sink = NewWebhookSink(&config.WebhookConfig{Enabled: true, URL: failing.URL})
+	assert.Error(t, sink.Send(context.Background(), &Notification{Title: "x"}))
+}
+
+func TestEmailSink(t *testing.T) {
+	cfg := &config.EmailConfig{
+		Enabled:  true,
+		SMTPHost: "smtp.example.com",
+		SMTPPort: 587,
+		From:     "watch@example.com",
+		To:       []string{"me@example.com"},
+	}
+	sink := NewEmailSink(cfg)
+
+	var gotAddr string
+	var gotMsg []byte
+	sink.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotMsg = addr, msg
+		return nil
+	}
+
+	err := sink.Send(context.Background(), &Notification{
+		Title: "Weekly report",
+		Body:  "plain body",
+		HTML:  "<p>html body</p>",
+	})
+	require.NoError(t, err)
+
+	msg := string(gotMsg)
+	assert.Equal(t, "smtp.example.com:587", gotAddr)
+	assert.Contains(t, msg, "Subject: Weekly report\r\n")
+	assert.Contains(t, msg, "multipart/alternative")
+	assert.Contains(t, msg, "plain body")
+	assert.Contains(t, msg, "<p>html body</p>")
+
+	// Non-ASCII titles are encoded
+	require.NoError(t, sink.Send(context.Background(), &Notification{Title: "Cached: Amélie", Body: "x"}))
+	assert.Contains(t, string(gotMsg), "Subject: =?utf-8?q?Cached:_Am=C3=A9lie?=\r\n")
+
+	// Plain text only
+	require.NoError(t, sink.Send(context.Background(), &Notification{Title: "t", Body: "only text"}))
+	assert.True(t, strings.Contains(string(gotMsg), "Content-Type: text/plain"))
+	assert.False(t, strings.Contains(string(gotMsg), "multipart"))
+}
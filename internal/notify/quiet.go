@@ -0,0 +1,114 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxPendingNotifications bounds the notifications held during quiet hours;
+// the oldest are dropped first.
+const maxPendingNotifications = 100
+
+// digestCheckInterval is how often Run checks whether quiet hours have ended.
+const digestCheckInterval = time.Minute
+
+// quiet reports whether quiet hours are currently in effect.
+func (d *Dispatcher) quiet() bool {
+	return d.quietHours != nil && d.quietHours.IsActive(d.now())
+}
+
+// hold queues n for the digest if quiet hours are in effect. Critical
+// notifications are never held.
+func (d *Dispatcher) hold(n *Notification) bool {
+	if n.Severity == SeverityCritical || !d.quiet() {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.pending = append(d.pending, n)
+	if len(d.pending) > maxPendingNotifications {
+		d.pending = d.pending[1:]
+		d.dropped++
+	}
+	return true
+}
+
+// FlushDigest delivers notifications held during quiet hours once the quiet
+// period is over. A single held notification is delivered unchanged; several
+// are combined into one digest.
+func (d *Dispatcher) FlushDigest(ctx context.Context) error {
+	if d.quiet() {
+		return nil
+	}
+
+	d.mu.Lock()
+	pending, dropped := d.pending, d.dropped
+	d.pending, d.dropped = nil, 0
+	d.mu.Unlock()
+
+	switch {
+	case len(pending) == 0:
+		return nil
+	case len(pending) == 1 && dropped == 0:
+		return d.send(ctx, pending[0])
+	}
+
+	d.logger.Info("Delivering quiet hours digest", "notifications", len(pending)+dropped)
+	return d.send(ctx, buildDigest(pending, dropped, d.now()))
+}
+
+// Run delivers the quiet hours digest shortly after each quiet period ends,
+// until ctx is cancelled. It is a no-op when quiet hours are disabled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	if d.quietHours == nil || !d.quietHours.Enabled {
+		return
+	}
+
+	ticker := time.NewTicker(digestCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.FlushDigest(ctx); err != nil {
+				d.logger.Warn("Failed to deliver quiet hours digest", "error", err)
+			}
+		}
+	}
+}
+
+// buildDigest combines held notifications into a single plain text message
+// carrying the highest severity among them.
+func buildDigest(pending []*Notification, dropped int, now time.Time) *Notification {
+	var body strings.Builder
+	severity := SeverityInfo
+
+	for _, n := range pending {
+		if n.Severity == SeverityWarning {
+			severity = SeverityWarning
+		}
+		fmt.Fprintf(&body, "[%s] %s\n", n.CreatedAt.Format("Mon 15:04"), n.Title)
+		if n.Body != "" {
+			for _, line := range strings.Split(strings.TrimRight(n.Body, "\n"), "\n") {
+				fmt.Fprintf(&body, "    %s\n", line)
+			}
+		}
+		body.WriteString("\n")
+	}
+	if dropped > 0 {
+		fmt.Fprintf(&body, "%d older notifications were omitted.\n", dropped)
+	}
+
+	return &Notification{
+		Title:     fmt.Sprintf("go-jf-watch: %d notifications during quiet hours", len(pending)+dropped),
+		Body:      body.String(),
+		Severity:  severity,
+		CreatedAt: now,
+	}
+}
@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestQuietHoursDigest(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sink := &recordingSink{name: "sink"}
+	dispatcher := NewDispatcherWithSinks(logger, sink)
+	dispatcher.quietHours = &config.QuietHoursConfig{Enabled: true, Start: "22:00", End: "07:00"}
+
+	now := time.Date(2026, time.October, 14, 23, 30, 0, 0, time.UTC)
+	dispatcher.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	require.NoError(t, dispatcher.Notify(ctx, &Notification{Title: "Weekly report", Body: "line one\nline two"}))
+	require.NoError(t, dispatcher.Notify(ctx, &Notification{Title: "Disk slow", Severity: SeverityWarning}))
+	assert.Empty(t, sink.sent, "non-critical notifications are held")
+
+	require.NoError(t, dispatcher.Notify(ctx, &Notification{Title: "Disk failing", Severity: SeverityCritical}))
+	require.Len(t, sink.sent, 1, "critical notifications are delivered immediately")
+	assert.Equal(t, "Disk failing", sink.sent[0].Title)
+
+	require.NoError(t, dispatcher.FlushDigest(ctx))
+	assert.Len(t, sink.sent, 1, "digest waits for quiet hours to end")
+
+	now = time.Date(2026, time.October, 15, 7, 1, 0, 0, time.UTC)
+	require.NoError(t, dispatcher.FlushDigest(ctx))
+	require.Len(t, sink.sent, 2)
+
+	digest := sink.sent[1]
+	assert.Equal(t, "go-jf-watch: 2 notifications during quiet hours", digest.Title)
+	assert.Equal(t, SeverityWarning, digest.Severity)
+	assert.Contains(t, digest.Body, "Weekly report")
+	assert.Contains(t, digest.Body, "    line two")
+	assert.Contains(t, digest.Body, "Disk slow")
+
+	require.NoError(t, dispatcher.FlushDigest(ctx))
+	assert.Len(t, sink.sent, 2, "digest is delivered once")
+}
+
+func TestQuietHoursSingleHeldNotification(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sink := &recordingSink{name: "sink"}
+	dispatcher := NewDispatcherWithSinks(logger, sink)
+	dispatcher.quietHours = &config.QuietHoursConfig{Enabled: true, Start: "22:00", End: "07:00"}
+
+	now := time.Date(2026, time.October, 14, 23, 0, 0, 0, time.UTC)
+	dispatcher.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	require.NoError(t, dispatcher.Notify(ctx, &Notification{Title: "Weekly report", HTML: "<p>report</p>"}))
+
+	// The next notification after quiet hours flushes the held one first
+	now = now.Add(9 * time.Hour)
+	require.NoError(t, dispatcher.Notify(ctx, &Notification{Title: "Later"}))
+
+	require.Len(t, sink.sent, 2)
+	assert.Equal(t, "Weekly report", sink.sent[0].Title, "a single held notification is sent unchanged")
+	assert.Equal(t, "<p>report</p>", sink.sent[0].HTML)
+	assert.Equal(t, "Later", sink.sent[1].Title)
+}
@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// WebhookSink delivers notifications as JSON POST requests, suitable for
+// ntfy, Gotify bridges, Home Assistant and similar services.
+type WebhookSink struct {
+	config *config.WebhookConfig
+	client *http.Client
+}
+
+// NewWebhookSink creates a webhook sink.
+func NewWebhookSink(cfg *config.WebhookConfig) *WebhookSink {
+	return &WebhookSink{
+		config: cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name returns the sink name.
+func (h *WebhookSink) Name() string {
+	return "webhook"
+}
+
+// Send posts the notification as JSON. Non-2xx responses are errors.
+func (h *WebhookSink) Send(ctx context.Context, n *Notification) error {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.config.URL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
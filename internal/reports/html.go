@@ -0,0 +1,43 @@
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+)
+
+// reportTemplate renders a report as a self-contained HTML page that reads
+// well both in a browser and in email clients (inline styles only).
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"bytes":   formatBytes,
+	"percent": func(f float64) string { return fmt.Sprintf("%.0f%%", f*100) },
+	"date": func(r *Report) string {
+		return r.PeriodStart.Format("Jan 2") + " – " + r.PeriodEnd.Format("Jan 2, 2006")
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>go-jf-watch weekly report</title></head>
+<body style="font-family: sans-serif; max-width: 640px; margin: 0 auto; color: #222;">
+<h2 style="margin-bottom: 0;">go-jf-watch weekly report</h2>
+<p style="color: #666; margin-top: 4px;">{{date .}}</p>
+<table style="border-collapse: collapse; width: 100%;">
+<tr><td style="padding: 4px 0;">New items cached</td><td style="text-align: right;"><strong>{{.ItemsCached}}</strong> ({{bytes .BytesDownloaded}})</td></tr>
+<tr><td style="padding: 4px 0;">Cache hit rate</td><td style="text-align: right;"><strong>{{percent .CacheHitRate}}</strong> ({{.CacheHits}} of {{.Plays}} plays)</td></tr>
+<tr><td style="padding: 4px 0;">Evicted</td><td style="text-align: right;"><strong>{{.ItemsEvicted}}</strong> ({{bytes .BytesEvicted}})</td></tr>
+</table>
+{{if .NewItems}}<h3>Recently cached</h3>
+<ul>{{range .NewItems}}<li>{{.Title}} <span style="color: #666;">({{bytes .Size}})</span></li>{{end}}</ul>{{end}}
+{{if .UpcomingDownloads}}<h3>Upcoming downloads</h3>
+<ul>{{range .UpcomingDownloads}}<li>{{.Title}} <span style="color: #666;">(priority {{.Priority}})</span></li>{{end}}</ul>{{end}}
+</body>
+</html>
+`))
+
+// RenderHTML renders the report as an HTML document.
+func RenderHTML(r *Report) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := reportTemplate.Execute(&buf, r); err != nil {
+		return nil, fmt.Errorf("failed to render report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
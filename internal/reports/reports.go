@@ -0,0 +1,372 @@
+// Package reports generates periodic summaries of cache activity for
+// go-jf-watch and delivers them through the notification sinks.
+//
+// Design Philosophy:
+// - Reports are built from the daily stats recorded by the storage layer
+// - Generated reports are stored so they can be fetched later via the API
+// - Each report renders as JSON, plain text (email fallback) and HTML
+package reports
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/notify"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// reportPeriod is the span covered by a weekly report.
+const reportPeriod = 7 * 24 * time.Hour
+
+// maxListedItems caps the item lists included in a report.
+const maxListedItems = 20
+
+// scheduleCheckInterval is the longest Run sleeps before checking the wall
+// clock against the next report time.
+const scheduleCheckInterval = time.Minute
+
+// Notifier delivers rendered reports.
+type Notifier interface {
+	Notify(ctx context.Context, n *notify.Notification) error
+}
+
+// Report summarizes cache activity over a period.
+type Report struct {
+	ID          string    `json:"id"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	GeneratedAt time.Time `json:"generated_at"`
+
+	NewItems        []ReportItem `json:"new_items"`
+	ItemsCached     int          `json:"items_cached"`
+	BytesDownloaded int64        `json:"bytes_downloaded"`
+
+	CacheHits    int     `json:"cache_hits"`
+	CacheMisses  int     `json:"cache_misses"`
+	CacheHitRate float64 `json:"cache_hit_rate"` // 0.0-1.0, zero when nothing was played
+
+	UpcomingDownloads []ReportItem `json:"upcoming_downloads"`
+
+	ItemsEvicted int   `json:"items_evicted"`
+	BytesEvicted int64 `json:"bytes_evicted"`
+}
+
+// Plays returns the number of playback starts counted in the report.
+func (r *Report) Plays() int {
+	return r.CacheHits + r.CacheMisses
+}
+
+// ReportItem is a media item listed in a report.
+type ReportItem struct {
+	MediaID  string `json:"media_id"`
+	Title    string `json:"title"`
+	Size     int64  `json:"size_bytes,omitempty"`
+	Priority int    `json:"priority,omitempty"`
+}
+
+// Service generates, stores and delivers activity reports.
+type Service struct {
+	config   *config.ReportsConfig
+	storage  *storage.Manager
+	notifier Notifier
+	logger   *slog.Logger
+}
+
+// New creates a report service. notifier may be nil, in which case reports
+// are generated and stored but not delivered.
+func New(cfg *config.ReportsConfig, storage *storage.Manager, notifier Notifier, logger *slog.Logger) *Service {
+	return &Service{
+		config:   cfg,
+		storage:  storage,
+		notifier: notifier,
+		logger:   logger,
+	}
+}
+
+// Generate builds and stores a report for the week ending at end.
+func (s *Service) Generate(ctx context.Context, end time.Time) (*Report, error) {
+	start := end.Add(-reportPeriod)
+	report := &Report{
+		ID:          end.UTC().Format("20060102T150405Z"),
+		PeriodStart: start,
+		PeriodEnd:   end,
+		GeneratedAt: time.Now(),
+	}
+
+	daily, err := s.storage.GetDailyStats(start, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, day := range daily {
+		report.ItemsCached += day.ItemsCached
+		report.BytesDownloaded += day.BytesDownloaded
+		report.CacheHits += day.CacheHits
+		report.CacheMisses += day.CacheMisses
+		report.ItemsEvicted += day.ItemsEvicted
+		report.BytesEvicted += day.BytesEvicted
+	}
+	if plays := report.Plays(); plays > 0 {
+		report.CacheHitRate = float64(report.CacheHits) / float64(plays)
+	}
+
+	report.NewItems, err = s.newItems(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	report.UpcomingDownloads, err = s.upcomingDownloads()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal report: %w", err)
+	}
+	if err := s.storage.SaveReport(report.ID, data); err != nil {
+		return nil, fmt.Errorf("failed to save report: %w", err)
+	}
+
+	s.logger.Info("Generated activity report",
+		"report_id", report.ID,
+		"items_cached", report.ItemsCached,
+		"cache_hit_rate", report.CacheHitRate)
+
+	return report, nil
+}
+
+// Deliver sends a report through the notifier.
+func (s *Service) Deliver(ctx context.Context, report *Report) error {
+	if s.notifier == nil {
+		return nil
+	}
+
+	html, err := RenderHTML(report)
+	if err != nil {
+		return err
+	}
+
+	return s.notifier.Notify(ctx, &notify.Notification{
+		Title:    fmt.Sprintf("go-jf-watch weekly report: %s", report.PeriodEnd.Format("Jan 2, 2006")),
+		Body:     RenderText(report),
+		HTML:     string(html),
+		Severity: notify.SeverityInfo,
+	})
+}
+
+// Recent returns up to limit stored reports, newest first.
+func (s *Service) Recent(limit int) ([]*Report, error) {
+	stored, err := s.storage.ListReports(limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reports: %w", err)
+	}
+
+	reports := make([]*Report, 0, len(stored))
+	for _, data := range stored {
+		var report Report
+		if err := json.Unmarshal(data, &report); err != nil {
+			s.logger.Warn("Failed to unmarshal stored report", "error", err)
+			continue
+		}
+		reports = append(reports, &report)
+	}
+	return reports, nil
+}
+
+// Get returns a stored report by ID, or nil if it does not exist.
+func (s *Service) Get(id string) (*Report, error) {
+	data, err := s.storage.GetReport(id)
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal report: %w", err)
+	}
+	return &report, nil
+}
+
+// newItems lists items downloaded during the period, newest first.
+func (s *Service) newItems(start, end time.Time) ([]ReportItem, error) {
+	records, err := s.storage.ListDownloadRecords("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list downloads: %w", err)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].DownloadedAt.After(records[j].DownloadedAt)
+	})
+
+	var items []ReportItem
+	for _, record := range records {
+		if record.DownloadedAt.Before(start) || record.DownloadedAt.After(end) {
+			continue
+		}
+		items = append(items, ReportItem{
+			MediaID: record.JellyfinID,
+			Title:   s.title(record.JellyfinID, record.Title),
+			Size:    record.Size,
+		})
+		if len(items) == maxListedItems {
+			break
+		}
+	}
+	return items, nil
+}
+
+// upcomingDownloads lists queued downloads, most urgent first.
+func (s *Service) upcomingDownloads() ([]ReportItem, error) {
+	queued, err := s.storage.GetQueueItems("queued")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queue: %w", err)
+	}
+
+	var items []ReportItem
+	for _, item := range queued {
+		items = append(items, ReportItem{
+			MediaID:  item.MediaID,
+			Title:    s.title(item.MediaID, ""),
+			Size:     item.Size,
+			Priority: item.Priority,
+		})
+		if len(items) == maxListedItems {
+			break
+		}
+	}
+	return items, nil
+}
+
+// title returns a display title for a media item, preferring metadata.
+func (s *Service) title(mediaID, fallback string) string {
+	if metadata, err := s.storage.GetMediaMetadata(mediaID); err == nil && metadata.Name != "" {
+		return metadata.Name
+	}
+	if fallback != "" {
+		return fallback
+	}
+	return mediaID
+}
+
+// Run generates and delivers a report every week at the configured day and
+// time until ctx is cancelled. It is a no-op when weekly reports are disabled.
+func (s *Service) Run(ctx context.Context) {
+	if !s.config.WeeklyEnabled {
+		return
+	}
+
+	next, err := NextRun(s.config, time.Now())
+	if err != nil {
+		s.logger.Error("Invalid weekly report schedule", "error", err)
+		return
+	}
+	s.logger.Debug("Next weekly report scheduled", "at", next)
+
+	for {
+		// Wait in short steps against the wall clock: timers follow the
+		// monotonic clock, so one long timer fires at the wrong time after
+		// the system clock is stepped or the host resumes from suspend
+		if wait := time.Until(next); wait > 0 {
+			if wait > scheduleCheckInterval {
+				wait = scheduleCheckInterval
+			}
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			continue
+		}
+
+		report, err := s.Generate(ctx, time.Now())
+		if err != nil {
+			s.logger.Error("Failed to generate weekly report", "error", err)
+		} else if err := s.Deliver(ctx, report); err != nil {
+			s.logger.Warn("Failed to deliver weekly report", "report_id", report.ID, "error", err)
+		}
+
+		// Schedule from the run just made as well as the clock, so a clock
+		// set back past it does not repeat this week's report
+		from := time.Now()
+		if from.Before(next) {
+			from = next
+		}
+		if next, err = NextRun(s.config, from); err != nil {
+			s.logger.Error("Invalid weekly report schedule", "error", err)
+			return
+		}
+		s.logger.Debug("Next weekly report scheduled", "at", next)
+	}
+}
+
+// NextRun returns the first scheduled report time strictly after now, in
+// the configured time zone. A report time skipped by a DST transition still
+// runs once that day.
+func NextRun(cfg *config.ReportsConfig, now time.Time) (time.Time, error) {
+	weekday, err := config.ParseWeekday(cfg.Weekday)
+	if err != nil {
+		return time.Time{}, err
+	}
+	at, err := time.Parse("15:04", cfg.Time)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid report time %q: %w", cfg.Time, err)
+	}
+
+	now = cfg.InZone(now)
+	days := (int(weekday) - int(now.Weekday()) + 7) % 7
+	next := time.Date(now.Year(), now.Month(), now.Day()+days, at.Hour(), at.Minute(), 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next, nil
+}
+
+// formatBytes renders a byte count in human-readable units.
+func formatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+// RenderText renders a plain text version of the report.
+func RenderText(r *Report) string {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "go-jf-watch weekly report\n%s - %s\n\n",
+		r.PeriodStart.Format("Jan 2, 2006"), r.PeriodEnd.Format("Jan 2, 2006"))
+	fmt.Fprintf(&buf, "New items cached: %d (%s downloaded)\n", r.ItemsCached, formatBytes(r.BytesDownloaded))
+	fmt.Fprintf(&buf, "Cache hit rate:   %.0f%% (%d of %d plays)\n",
+		r.CacheHitRate*100, r.CacheHits, r.Plays())
+	fmt.Fprintf(&buf, "Evicted:          %d items (%s)\n", r.ItemsEvicted, formatBytes(r.BytesEvicted))
+
+	if len(r.NewItems) > 0 {
+		buf.WriteString("\nRecently cached:\n")
+		for _, item := range r.NewItems {
+			fmt.Fprintf(&buf, "  - %s (%s)\n", item.Title, formatBytes(item.Size))
+		}
+	}
+
+	if len(r.UpcomingDownloads) > 0 {
+		buf.WriteString("\nUpcoming downloads:\n")
+		for _, item := range r.UpcomingDownloads {
+			fmt.Fprintf(&buf, "  - %s (priority %d)\n", item.Title, item.Priority)
+		}
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,133 @@
+package reports
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opd-ai/go-jf-watch/internal/notify"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+type capturingNotifier struct {
+	sent []*notify.Notification
+}
+
+func (c *capturingNotifier) Notify(ctx context.Context, n *notify.Notification) error {
+	c.sent = append(c.sent, n)
+	return nil
+}
+
+func newTestService(t *testing.T) (*Service, *storage.Manager, *capturingNotifier) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sm, err := storage.NewManager(&config.CacheConfig{Directory: t.TempDir(), MaxSizeGB: 1, MetadataStore: "boltdb"}, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { sm.Close() })
+
+	notifier := &capturingNotifier{}
+	cfg := &config.ReportsConfig{WeeklyEnabled: true, Weekday: "sunday", Time: "09:00"}
+	return New(cfg, sm, notifier, logger), sm, notifier
+}
+
+func TestGenerateReport(t *testing.T) {
+	svc, sm, notifier := newTestService(t)
+
+	require.NoError(t, sm.AddDownloadRecord(&storage.DownloadRecord{
+		ID: "m1", MediaType: "movie", JellyfinID: "m1", Title: "Heat <1995>",
+		Size: 2048, Status: "completed", DownloadedAt: time.Now().Add(-time.Hour),
+	}))
+	require.NoError(t, sm.AddDownloadRecord(&storage.DownloadRecord{
+		ID: "old", MediaType: "movie", JellyfinID: "old", Title: "Old",
+		Size: 1, Status: "completed", DownloadedAt: time.Now().AddDate(0, 0, -30),
+	}))
+	require.NoError(t, sm.AddQueueItem(&storage.QueueItem{
+		ID: "q1", MediaID: "e2", Priority: 1, Status: "queued", CreatedAt: time.Now(),
+	}))
+	require.NoError(t, sm.AddMediaMetadata(&storage.MediaMetadata{ID: "e2", JellyfinID: "e2", Name: "Episode 2"}))
+
+	sm.RecordDownloadCompleted(2048)
+	sm.RecordStreamRequest(true)
+	sm.RecordStreamRequest(true)
+	sm.RecordStreamRequest(true)
+	sm.RecordStreamRequest(false)
+	sm.RecordEviction(512)
+
+	report, err := svc.Generate(context.Background(), time.Now())
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, report.ItemsCached)
+	assert.Equal(t, int64(2048), report.BytesDownloaded)
+	assert.InDelta(t, 0.75, report.CacheHitRate, 0.001)
+	assert.Equal(t, 1, report.ItemsEvicted)
+	require.Len(t, report.NewItems, 1)
+	assert.Equal(t, "Heat <1995>", report.NewItems[0].Title)
+	require.Len(t, report.UpcomingDownloads, 1)
+	assert.Equal(t, "Episode 2", report.UpcomingDownloads[0].Title)
+
+	// Stored reports can be fetched back
+	stored, err := svc.Get(report.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, report.ItemsCached, stored.ItemsCached)
+
+	recent, err := svc.Recent(5)
+	require.NoError(t, err)
+	assert.Len(t, recent, 1)
+
+	require.NoError(t, svc.Deliver(context.Background(), report))
+	require.Len(t, notifier.sent, 1)
+	assert.Contains(t, notifier.sent[0].Body, "Cache hit rate:   75%")
+	assert.Contains(t, notifier.sent[0].HTML, "Heat &lt;1995&gt;", "HTML output is escaped")
+}
+
+func TestNextRun(t *testing.T) {
+	cfg := &config.ReportsConfig{Weekday: "sunday", Time: "09:00"}
+
+	// Wednesday Oct 14 2026
+	wednesday := time.Date(2026, time.October, 14, 12, 0, 0, 0, time.UTC)
+	next, err := NextRun(cfg, wednesday)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, time.October, 18, 9, 0, 0, 0, time.UTC), next)
+
+	// Sunday after the scheduled time rolls over to next week
+	sundayEvening := time.Date(2026, time.October, 18, 20, 0, 0, 0, time.UTC)
+	next, err = NextRun(cfg, sundayEvening)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, time.October, 25, 9, 0, 0, 0, time.UTC), next)
+
+	_, err = NextRun(&config.ReportsConfig{Weekday: "someday", Time: "09:00"}, wednesday)
+	assert.Error(t, err)
+}
+
+func TestNextRunAcrossDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	cfg := &config.ReportsConfig{Weekday: "sunday", Time: "09:00"}
+
+	// Clocks go back on Sunday November 1 2026; the report still runs at
+	// 09:00 local time, 25 hours of elapsed time after Saturday 08:00
+	saturday := time.Date(2026, time.October, 31, 8, 0, 0, 0, ny)
+	next, err := NextRun(cfg, saturday)
+	require.NoError(t, err)
+	assert.Equal(t, 9, next.In(ny).Hour())
+	assert.Equal(t, 26*time.Hour, next.Sub(saturday))
+}
+
+func TestRenderText(t *testing.T) {
+	text := RenderText(&Report{
+		PeriodStart:     time.Date(2026, 10, 11, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:       time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+		ItemsCached:     3,
+		BytesDownloaded: 3 * 1024 * 1024 * 1024,
+	})
+	assert.True(t, strings.Contains(text, "New items cached: 3 (3.0 GB downloaded)"), text)
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+)
+
+// AdoptRequest names a directory of already downloaded media to import
+// into the cache.
+type AdoptRequest struct {
+	Directory string `json:"directory"`
+	Mode      string `json:"mode"` // link (default), move or copy
+	DryRun    bool   `json:"dry_run"`
+}
+
+// SetAdopter sets the adopter behind /api/adopt.
+func (s *Server) SetAdopter(adopter *downloader.Adopter) {
+	s.adopter = adopter
+}
+
+// handleAdopt matches the media files in a directory to library items and
+// brings them into the cache, or reports what it would do on a dry run.
+func (s *Server) handleAdopt(w http.ResponseWriter, r *http.Request) {
+	if s.adopter == nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "Adopting media is not enabled", nil)
+		return
+	}
+
+	var req AdoptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if req.Directory == "" {
+		s.writeErrorResponse(w, http.StatusBadRequest, "directory is required", nil)
+		return
+	}
+	if !downloader.ValidAdoptMode(req.Mode) {
+		s.writeErrorResponse(w, http.StatusBadRequest, "mode must be link, move or copy", nil)
+		return
+	}
+	if info, err := os.Stat(req.Directory); err != nil || !info.IsDir() {
+		s.writeErrorResponse(w, http.StatusBadRequest, "directory does not exist", err)
+		return
+	}
+
+	report, err := s.adopter.Adopt(r.Context(), req.Directory, downloader.AdoptOptions{
+		Mode:   req.Mode,
+		DryRun: req.DryRun,
+	})
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to adopt media", err)
+		return
+	}
+
+	message := "Media adopted"
+	if req.DryRun {
+		message = "Dry run; nothing was changed"
+	}
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: message,
+		Data:    report,
+	})
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestHandleAdopt(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	server := newTestServer(t, &Server{logger: logger})
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/adopt", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		server.handleAdopt(w, req)
+		return w
+	}
+
+	if w := post(`{}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without an adopter, got %d", w.Code)
+	}
+
+	downloads := t.TempDir()
+	if err := os.WriteFile(filepath.Join(downloads, "Heat.1995.mkv"), []byte("heat"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	store := storagetest.New()
+	if err := store.AddMediaMetadata(&storage.MediaMetadata{ID: "m1", JellyfinID: "m1", Name: "Heat", Type: "movie"}); err != nil {
+		t.Fatalf("Failed to add metadata: %v", err)
+	}
+	source := jellyfintest.New("http://jellyfin.test")
+	source.Items = map[string]jellyfin.MediaItem{"m1": {ID: "m1", Size: 4}}
+	cfg := &config.CacheConfig{Directory: t.TempDir(), TempDirectory: t.TempDir()}
+	server.SetAdopter(downloader.NewAdopter(source, store, storage.NewCacheManager(cfg, nil, logger), cfg, logger))
+
+	for _, body := range []string{
+		`{}`,
+		`{"directory": "` + downloads + `", "mode": "symlink"}`,
+		`{"directory": "` + filepath.Join(downloads, "missing") + `"}`,
+	} {
+		if w := post(body); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+
+	w := post(`{"directory": "` + downloads + `", "dry_run": true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data downloader.AdoptReport `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Data.Adopted) != 1 || resp.Data.Adopted[0].MediaID != "m1" || !resp.Data.DryRun {
+		t.Errorf("Expected a dry run adopting m1, got %+v", resp.Data)
+	}
+	if _, err := store.GetDownload("m1"); err == nil {
+		t.Error("Expected a dry run to record nothing")
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// handleLibraryImage serves the cached poster ("primary") or backdrop of a
+// library item. Images are only ever served from the cache, so browsing the
+// library makes no requests to Jellyfin; items without cached artwork get
+// a 404 and the UI falls back to its placeholder.
+func (s *Server) handleLibraryImage(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	imageType := chi.URLParam(r, "type")
+	if !slices.Contains(storage.ArtworkTypes, imageType) {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Image type must be primary or backdrop", nil)
+		return
+	}
+
+	record, err := s.library.GetDownload(id)
+	if err != nil || record.LocalPath == "" {
+		s.writeErrorResponse(w, http.StatusNotFound, "Item not cached", nil)
+		return
+	}
+
+	file, err := os.Open(storage.ArtworkPath(record.LocalPath, imageType))
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "No cached image", nil)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to read image", err)
+		return
+	}
+
+	// Artwork is replaced when it changes on the server, so let browsers
+	// keep it briefly but check back
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	http.ServeContent(w, r, "", info.ModTime(), file)
+}
+
+// setThumbnails points library items at their cached posters.
+func setThumbnails(items []*storage.CachedItem, libraryItems []jellyfin.LibraryItem) {
+	for i, item := range items {
+		if item.Path == "" {
+			continue
+		}
+		if _, err := os.Stat(storage.ArtworkPath(item.Path, "primary")); err == nil {
+			libraryItems[i].Thumbnail = "/api/library/" + url.PathEscape(item.ID) + "/image/primary"
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+func imageRequest(server *Server, id, imageType string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/library/"+id+"/image/"+imageType, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	rctx.URLParams.Add("type", imageType)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+	server.handleLibraryImage(w, req)
+	return w
+}
+
+func TestHandleLibraryImage(t *testing.T) {
+	server := newShareTestServer(t)
+
+	if w := imageRequest(server, "v1", "primary"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 before artwork is cached, got %d", w.Code)
+	}
+
+	record, err := server.storage.GetDownload("v1")
+	if err != nil {
+		t.Fatalf("Failed to get download: %v", err)
+	}
+	poster := []byte("\xff\xd8\xff\xe0poster")
+	if err := os.WriteFile(storage.ArtworkPath(record.LocalPath, "primary"), poster, 0644); err != nil {
+		t.Fatalf("Failed to write artwork: %v", err)
+	}
+
+	w := imageRequest(server, "v1", "primary")
+	if w.Code != http.StatusOK || w.Body.String() != string(poster) {
+		t.Fatalf("Expected the cached poster, got %d %q", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "image/jpeg" {
+		t.Errorf("Expected image/jpeg, got %q", ct)
+	}
+
+	if w := imageRequest(server, "v1", "logo"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown image type, got %d", w.Code)
+	}
+	if w := imageRequest(server, "missing", "primary"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an uncached item, got %d", w.Code)
+	}
+
+	// Library listings point at the cached poster
+	w = httptest.NewRecorder()
+	server.handleLibrary(w, httptest.NewRequest(http.MethodGet, "/api/library", nil))
+	var resp struct {
+		Data struct {
+			Items []struct {
+				ID        string `json:"id"`
+				Thumbnail string `json:"thumbnail"`
+			} `json:"items"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Data.Items) != 1 || resp.Data.Items[0].Thumbnail != "/api/library/v1/image/primary" {
+		t.Errorf("Expected the listing to link the poster, got %+v", resp.Data.Items)
+	}
+}
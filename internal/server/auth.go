@@ -0,0 +1,465 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+)
+
+// sessionCookie holds a signed-in user's session.
+const sessionCookie = "jfw_session"
+
+// passwordUser is who a session is for in auth mode password, where there
+// is only the one shared password.
+const passwordUser = "admin"
+
+// CredentialVerifier checks a username and password against the Jellyfin
+// server and returns the user's ID, for auth mode jellyfin.
+// *jellyfin.Client implements it.
+type CredentialVerifier interface {
+	VerifyCredentials(ctx context.Context, username, password string) (string, error)
+}
+
+var _ CredentialVerifier = (*jellyfin.Client)(nil)
+
+// LoginRequest is the body of POST /api/auth/login. The username is only
+// used in auth mode jellyfin.
+type LoginRequest struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password"`
+}
+
+// AuthSession describes who the caller is signed in as.
+type AuthSession struct {
+	Mode         string    `json:"mode"` // Empty when sign-in is off
+	ProtectReads bool      `json:"protect_reads"`
+	SignedIn     bool      `json:"signed_in"`
+	User         string    `json:"user,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"` // Zero for proxy sign-ins
+}
+
+// loginPage is the sign-in form for browsers.
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Sign in</title></head>
+<body style="font-family: sans-serif; max-width: 360px; margin: 80px auto;">
+<h3>Sign in to go-jf-watch</h3>
+{{if .Message}}<p style="color: #b00;">{{.Message}}</p>{{end}}
+<form method="post" action="/login">
+<input type="hidden" name="next" value="{{.Next}}">
+{{if .AskUsername}}<p><input type="text" name="username" placeholder="Jellyfin username" autocomplete="username" autofocus required></p>{{end}}
+<p><input type="password" name="password" placeholder="Password" autocomplete="current-password"{{if not .AskUsername}} autofocus{{end}} required></p>
+<button type="submit">Sign in</button>
+</form>
+</body>
+</html>
+`))
+
+// SetCredentialVerifier sets what checks Jellyfin usernames and passwords
+// in auth mode jellyfin.
+func (s *Server) SetCredentialVerifier(verifier CredentialVerifier) {
+	s.credentials = verifier
+}
+
+// peerAddrKey is the request context key of the address the connection
+// came from.
+type peerAddrKey struct{}
+
+// rememberPeer records the address the connection came from before RealIP
+// replaces it with what the forwarding headers claim, so proxy sign-ins are
+// only trusted from the proxy itself.
+func rememberPeer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), peerAddrKey{}, r.RemoteAddr)))
+	})
+}
+
+// authMiddleware turns away requests that need a sign-in and do not have
+// one: API calls get a 401 and browsers are sent to the sign-in page.
+// Requests with an API token are let in by the token's scope instead.
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := bearerToken(r); ok {
+			s.serveWithToken(w, r, next, token)
+			return
+		}
+		if !s.authRequired(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, _, ok := s.authUser(r); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if strings.HasPrefix(r.URL.Path, "/api/") || r.Method != http.MethodGet || s.config.Auth.Mode == "proxy" {
+			s.writeErrorResponse(w, http.StatusUnauthorized, "Sign-in required", nil)
+			return
+		}
+		http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+	})
+}
+
+// authRequired reports whether a request needs a sign-in. Without
+// protect_reads only requests that change something through the API,
+// database backups, which hold saved credentials, and managing API tokens
+// do. Sign-in itself, health checks, metrics, share links and the WebDAV export,
+// which has its own password, never do, and neither does the Jellyfin
+// webhook when a webhook token guards it.
+func (s *Server) authRequired(r *http.Request) bool {
+	path := r.URL.Path
+	switch {
+	case path == "/login", path == "/logout", path == "/health", path == "/metrics",
+		path == "/api/auth/session", path == "/api/auth/login", path == "/api/auth/logout",
+		strings.HasPrefix(path, "/share/"):
+		return false
+	case path == "/api/backup", path == "/api/auth/tokens", strings.HasPrefix(path, "/api/auth/tokens/"):
+		return true
+	case path == "/api/webhooks/jellyfin" && s.config.WebhookToken != "":
+		return false
+	case s.config.WebDAV.Enabled && s.config.WebDAV.Port == 0 &&
+		(path == s.config.WebDAV.Path || strings.HasPrefix(path, s.config.WebDAV.Path+"/")):
+		return false
+	}
+
+	if s.config.Auth.ProtectReads {
+		return true
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return strings.HasPrefix(path, "/api/")
+}
+
+// authUser returns who the request is signed in as and, for a session
+// cookie, when the session ends. Behind a trusted proxy that is the user
+// the proxy names, otherwise the one in a valid session cookie.
+func (s *Server) authUser(r *http.Request) (string, time.Time, bool) {
+	if s.config.Auth.Mode == "proxy" {
+		user := r.Header.Get(s.config.Auth.ProxyHeader)
+		return user, time.Time{}, user != "" && s.trustedProxy(r)
+	}
+
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return s.verifySession(cookie.Value, time.Now())
+}
+
+// trustedProxy reports whether the connection comes from one of the
+// configured trusted proxies.
+func (s *Server) trustedProxy(r *http.Request) bool {
+	ip := net.ParseIP(addrHost(peerAddr(r)))
+	if ip == nil {
+		return false
+	}
+
+	for _, proxy := range s.config.Auth.TrustedProxies {
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+		} else if trusted := net.ParseIP(proxy); trusted != nil && trusted.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientAddr returns the address failed sign-ins are counted against: the
+// one the connection came from or, behind a trusted proxy, the one the
+// proxy forwarded it for, so one client guessing passwords through the
+// proxy does not lock everyone else out.
+func (s *Server) clientAddr(r *http.Request) string {
+	if s.trustedProxy(r) {
+		return addrHost(r.RemoteAddr)
+	}
+	return addrHost(peerAddr(r))
+}
+
+// peerAddr returns the address the connection came from, before RealIP.
+func peerAddr(r *http.Request) string {
+	if addr, ok := r.Context().Value(peerAddrKey{}).(string); ok {
+		return addr
+	}
+	return r.RemoteAddr
+}
+
+// addrHost strips the port from addr, if it has one.
+func addrHost(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// registerAuthRoutes adds the browser sign-in page and sign-out action.
+func (s *Server) registerAuthRoutes() {
+	s.router.Get("/login", s.handleLoginPage)
+	s.router.Post("/login", s.handleLoginForm)
+	s.router.Post("/logout", s.handleLogoutForm)
+}
+
+// handleLoginPage shows the sign-in form.
+func (s *Server) handleLoginPage(w http.ResponseWriter, r *http.Request) {
+	s.renderLogin(w, r, http.StatusOK, "")
+}
+
+func (s *Server) renderLogin(w http.ResponseWriter, r *http.Request, status int, message string) {
+	switch s.config.Auth.Mode {
+	case "password", "jellyfin":
+	default:
+		http.Error(w, "Sign-in is not handled here", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	loginPage.Execute(w, map[string]interface{}{
+		"AskUsername": s.config.Auth.Mode == "jellyfin",
+		"Message":     message,
+		"Next":        localRedirect(r.FormValue("next")),
+	})
+}
+
+// handleLoginForm signs a browser in from the sign-in form and returns it
+// to the page it came from.
+func (s *Server) handleLoginForm(w http.ResponseWriter, r *http.Request) {
+	if mode := s.config.Auth.Mode; mode != "password" && mode != "jellyfin" {
+		http.Error(w, "Sign-in is not handled here", http.StatusNotFound)
+		return
+	}
+
+	user, status, message := s.signIn(r, r.PostFormValue("username"), r.PostFormValue("password"))
+	if status != http.StatusOK {
+		s.renderLogin(w, r, status, message)
+		return
+	}
+	s.setSessionCookie(w, r, user)
+	http.Redirect(w, r, localRedirect(r.PostFormValue("next")), http.StatusSeeOther)
+}
+
+// handleLogoutForm signs a browser out.
+func (s *Server) handleLogoutForm(w http.ResponseWriter, r *http.Request) {
+	s.clearSessionCookie(w, r)
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// handleAuthLogin signs in through the API, setting the session cookie.
+func (s *Server) handleAuthLogin(w http.ResponseWriter, r *http.Request) {
+	switch s.config.Auth.Mode {
+	case "password", "jellyfin":
+	case "":
+		s.writeErrorResponse(w, http.StatusNotFound, "Sign-in is not enabled", nil)
+		return
+	default:
+		s.writeErrorResponse(w, http.StatusNotFound, "Sign-in is handled by the proxy", nil)
+		return
+	}
+
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	user, status, message := s.signIn(r, req.Username, req.Password)
+	if status != http.StatusOK {
+		s.writeErrorResponse(w, status, message, nil)
+		return
+	}
+	expires := s.setSessionCookie(w, r, user)
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: AuthSession{
+			Mode:         s.config.Auth.Mode,
+			ProtectReads: s.config.Auth.ProtectReads,
+			SignedIn:     true,
+			User:         user,
+			ExpiresAt:    expires,
+		},
+	})
+}
+
+// handleAuthLogout signs out through the API.
+func (s *Server) handleAuthLogout(w http.ResponseWriter, r *http.Request) {
+	s.clearSessionCookie(w, r)
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "Signed out",
+	})
+}
+
+// handleAuthSession tells the UI whether sign-in is on and who, if anyone,
+// the caller is signed in as.
+func (s *Server) handleAuthSession(w http.ResponseWriter, r *http.Request) {
+	session := AuthSession{
+		Mode:         s.config.Auth.Mode,
+		ProtectReads: s.config.Auth.ProtectReads,
+	}
+	if session.Mode != "" {
+		session.User, session.ExpiresAt, session.SignedIn = s.authUser(r)
+	}
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    session,
+	})
+}
+
+// signIn checks a username and password and returns who they sign in as.
+// On failure it returns the status to answer with and a message fit to
+// show the user.
+func (s *Server) signIn(r *http.Request, username, password string) (string, int, string) {
+	now := time.Now()
+	client := s.clientAddr(r)
+	if wait := guardFor(&s.signIns, client, now).blocked(now); wait > 0 {
+		return "", http.StatusTooManyRequests,
+			fmt.Sprintf("Too many failed sign-ins. Try again in %d seconds.", int(wait.Seconds())+1)
+	}
+
+	var user string
+	switch s.config.Auth.Mode {
+	case "password":
+		if !hmac.Equal([]byte(password), []byte(s.config.Auth.Password)) {
+			return s.signInFailed(r, now, client, username)
+		}
+		user = passwordUser
+	case "jellyfin":
+		if s.credentials == nil {
+			return "", http.StatusServiceUnavailable, "Jellyfin sign-in is not available."
+		}
+		if username == "" || password == "" {
+			return s.signInFailed(r, now, client, username)
+		}
+		_, err := s.credentials.VerifyCredentials(r.Context(), username, password)
+		if errors.Is(err, jellyfin.ErrInvalidCredentials) {
+			return s.signInFailed(r, now, client, username)
+		}
+		if err != nil {
+			s.logger.Error("Failed to verify Jellyfin credentials", "username", username, "error", err)
+			return "", http.StatusBadGateway, "Could not reach Jellyfin to check the password."
+		}
+		user = username
+	}
+
+	s.signIns.Delete(client)
+	s.logger.Info("Signed in", "user", user, "remote_addr", r.RemoteAddr)
+	return user, http.StatusOK, ""
+}
+
+func (s *Server) signInFailed(r *http.Request, now time.Time, client, username string) (string, int, string) {
+	guardFor(&s.signIns, client, now).failed(now)
+	s.logger.Warn("Failed sign-in", "username", username, "remote_addr", r.RemoteAddr)
+	return "", http.StatusUnauthorized, "Incorrect username or password."
+}
+
+// setSessionCookie starts a session for user and returns when it ends.
+func (s *Server) setSessionCookie(w http.ResponseWriter, r *http.Request, user string) time.Time {
+	expires := time.Now().Add(s.config.Auth.SessionTTL).Truncate(time.Second)
+	value, ok := s.signSession(user, expires)
+	if !ok {
+		return time.Time{}
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode, // Other sites cannot make signed-in writes
+	})
+	return expires
+}
+
+func (s *Server) clearSessionCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// signSession returns the session cookie value for user until expires. It
+// is keyed by the session secret and by the password, so changing either
+// signs everyone out. It reports false if no secret could be made.
+func (s *Server) signSession(user string, expires time.Time) (string, bool) {
+	key, ok := s.sessionKey()
+	if !ok {
+		return "", false
+	}
+	expiry := strconv.FormatInt(expires.Unix(), 10)
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(user))
+	return encoded + "." + expiry + "." + signShare(key, "session", user, expiry, s.config.Auth.Mode, s.config.Auth.Password), true
+}
+
+// verifySession returns the user and expiry of a session cookie value that
+// was signed by this server and has not expired.
+func (s *Server) verifySession(value string, now time.Time) (string, time.Time, bool) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 {
+		return "", time.Time{}, false
+	}
+	user, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	expires := time.Unix(expiry, 0)
+
+	expected, ok := s.signSession(string(user), expires)
+	if !ok || !hmac.Equal([]byte(value), []byte(expected)) || !now.Before(expires) {
+		return "", time.Time{}, false
+	}
+	return string(user), expires, true
+}
+
+// sessionKey returns the key sessions are signed with: the configured
+// session secret, or one made at startup.
+func (s *Server) sessionKey() ([]byte, bool) {
+	if s.config.Auth.SessionSecret != "" {
+		return []byte(s.config.Auth.SessionSecret), true
+	}
+	s.auth.once.Do(func() {
+		key, err := randomHex(32)
+		if err != nil {
+			s.logger.Error("Failed to create session secret", "error", err)
+			return
+		}
+		s.auth.key = []byte(key)
+	})
+	return s.auth.key, s.auth.key != nil
+}
+
+// localRedirect returns next if it is a path on this server, so the
+// sign-in form cannot be used to send people elsewhere, or "/".
+func localRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
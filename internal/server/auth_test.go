@@ -0,0 +1,310 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+type verifierFunc func(ctx context.Context, username, password string) (string, error)
+
+func (f verifierFunc) VerifyCredentials(ctx context.Context, username, password string) (string, error) {
+	return f(ctx, username, password)
+}
+
+func newAuthTestRouter(t *testing.T, auth config.AuthConfig) (*Server, http.Handler) {
+	server := newShareTestServer(t)
+	if auth.SessionTTL == 0 {
+		auth.SessionTTL = time.Hour
+	}
+	if auth.ProxyHeader == "" {
+		auth.ProxyHeader = "Remote-User"
+	}
+	server.config = &config.ServerConfig{Auth: auth}
+
+	ok := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(body)) }
+	}
+	server.router = chi.NewRouter()
+	server.router.Use(rememberPeer)
+	server.router.Use(middleware.RealIP)
+	server.router.Use(server.authMiddleware)
+	server.registerAuthRoutes()
+	server.router.Get("/", ok("full interface"))
+	server.router.Get("/api/status", ok("status"))
+	server.router.Post("/api/queue/add", ok("queued"))
+	server.router.Get("/api/auth/session", server.handleAuthSession)
+	server.router.Post("/api/auth/login", server.handleAuthLogin)
+	server.router.Post("/api/auth/logout", server.handleAuthLogout)
+	return server, server.router
+}
+
+func apiLogin(handler http.Handler, username, password string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(LoginRequest{Username: username, Password: password})
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(string(body)))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func sessionCookieFrom(w *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == sessionCookie {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestAuthPasswordProtectsWrites(t *testing.T) {
+	_, handler := newAuthTestRouter(t, config.AuthConfig{Mode: "password", Password: "correct horse"})
+
+	if w := kioskRequest(handler, http.MethodGet, "/api/status", nil); w.Code != http.StatusOK {
+		t.Errorf("Expected reads to stay open, got %d", w.Code)
+	}
+	if w := kioskRequest(handler, http.MethodPost, "/api/queue/add", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected writes to need a sign-in, got %d", w.Code)
+	}
+
+	if w := apiLogin(handler, "", "wrong"); w.Code != http.StatusUnauthorized || sessionCookieFrom(w) != nil {
+		t.Errorf("Expected a wrong password to be rejected, got %d", w.Code)
+	}
+
+	w := apiLogin(handler, "", "correct horse")
+	cookie := sessionCookieFrom(w)
+	if w.Code != http.StatusOK || cookie == nil || !cookie.HttpOnly {
+		t.Fatalf("Expected a session cookie, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data AuthSession `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if !resp.Data.SignedIn || resp.Data.User != passwordUser || resp.Data.ExpiresAt.IsZero() {
+		t.Errorf("Expected the new session to be described, got %+v", resp.Data)
+	}
+
+	if w := kioskRequest(handler, http.MethodPost, "/api/queue/add", nil, cookie); w.Code != http.StatusOK {
+		t.Errorf("Expected the session to allow writes, got %d", w.Code)
+	}
+
+	w = kioskRequest(handler, http.MethodGet, "/api/auth/session", nil, cookie)
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if !resp.Data.SignedIn || resp.Data.Mode != "password" {
+		t.Errorf("Expected to be signed in, got %+v", resp.Data)
+	}
+
+	// Tampered or foreign cookies are refused
+	for _, value := range []string{cookie.Value + "0", "YWRtaW4.9999999999.0123456789abcdef0123456789abcdef", "garbage"} {
+		forged := &http.Cookie{Name: sessionCookie, Value: value}
+		if w := kioskRequest(handler, http.MethodPost, "/api/queue/add", nil, forged); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected %q to be refused, got %d", value, w.Code)
+		}
+	}
+
+	w = kioskRequest(handler, http.MethodPost, "/api/auth/logout", nil, cookie)
+	if cleared := sessionCookieFrom(w); cleared == nil || cleared.MaxAge >= 0 {
+		t.Errorf("Expected signing out to clear the cookie, got %+v", cleared)
+	}
+}
+
+func TestAuthSessionExpiryAndSecret(t *testing.T) {
+	server, _ := newAuthTestRouter(t, config.AuthConfig{Mode: "password", Password: "correct horse", SessionSecret: "0123456789abcdef"})
+
+	now := time.Now()
+	value, ok := server.signSession("admin", now.Add(time.Minute))
+	if !ok {
+		t.Fatal("Expected a session to be signed")
+	}
+	if user, _, ok := server.verifySession(value, now); !ok || user != "admin" {
+		t.Errorf("Expected a valid session for admin, got %q %v", user, ok)
+	}
+	if _, _, ok := server.verifySession(value, now.Add(2*time.Minute)); ok {
+		t.Error("Expected an expired session to be refused")
+	}
+
+	// Sessions survive a restart with the same secret, but not a new password
+	restarted, _ := newAuthTestRouter(t, config.AuthConfig{Mode: "password", Password: "correct horse", SessionSecret: "0123456789abcdef"})
+	if _, _, ok := restarted.verifySession(value, now); !ok {
+		t.Error("Expected the configured secret to keep sessions across restarts")
+	}
+	restarted.config.Auth.Password = "battery staple"
+	if _, _, ok := restarted.verifySession(value, now); ok {
+		t.Error("Expected a password change to sign everyone out")
+	}
+}
+
+func TestAuthLockout(t *testing.T) {
+	_, handler := newAuthTestRouter(t, config.AuthConfig{Mode: "password", Password: "correct horse"})
+
+	for i := 0; i < loginMaxAttempts; i++ {
+		apiLogin(handler, "", "wrong")
+	}
+	if w := apiLogin(handler, "", "correct horse"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected sign-in to be refused after too many failures, got %d", w.Code)
+	}
+}
+
+func TestAuthLockoutPerClient(t *testing.T) {
+	_, handler := newAuthTestRouter(t, config.AuthConfig{Mode: "password", Password: "correct horse", TrustedProxies: []string{"10.0.0.1"}})
+
+	login := func(remoteAddr, realIP, password string) int {
+		body, _ := json.Marshal(LoginRequest{Password: password})
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(string(body)))
+		req.RemoteAddr = remoteAddr
+		if realIP != "" {
+			req.Header.Set("X-Real-IP", realIP)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for i := 0; i < loginMaxAttempts; i++ {
+		login("192.168.1.5:5000", "", "wrong")
+	}
+	if code := login("192.168.1.5:6000", "", "correct horse"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected the guessing client to be locked out, got %d", code)
+	}
+	if code := login("192.168.1.5:6000", "192.168.1.9", "correct horse"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected forwarding headers from a client not to escape the lockout, got %d", code)
+	}
+	if code := login("192.168.1.6:5000", "", "correct horse"); code != http.StatusOK {
+		t.Errorf("Expected another client to sign in, got %d", code)
+	}
+
+	// Behind the proxy, clients are told apart by the address it forwards
+	for i := 0; i < loginMaxAttempts; i++ {
+		login("10.0.0.1:5000", "192.168.2.5", "wrong")
+	}
+	if code := login("10.0.0.1:5000", "192.168.2.5", "correct horse"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected the proxied client to be locked out, got %d", code)
+	}
+	if code := login("10.0.0.1:5000", "192.168.2.6", "correct horse"); code != http.StatusOK {
+		t.Errorf("Expected another client behind the proxy to sign in, got %d", code)
+	}
+}
+
+func TestLoginGuardsForgetIdleClients(t *testing.T) {
+	var guards sync.Map
+	now := time.Now()
+
+	guardFor(&guards, "192.168.1.5", now).failed(now)
+	for i := 0; i < loginMaxAttempts; i++ {
+		guardFor(&guards, "192.168.1.6", now).failed(now)
+	}
+
+	later := now.Add(loginLockout / 2)
+	guardFor(&guards, "192.168.1.7", later)
+	if _, ok := guards.Load("192.168.1.5"); !ok {
+		t.Error("Expected a recent failure to be remembered")
+	}
+	if _, ok := guards.Load("192.168.1.6"); !ok {
+		t.Error("Expected a locked out client to be remembered")
+	}
+
+	later = now.Add(loginLockout)
+	guardFor(&guards, "192.168.1.8", later)
+	for _, addr := range []string{"192.168.1.5", "192.168.1.6", "192.168.1.7"} {
+		if _, ok := guards.Load(addr); ok {
+			t.Errorf("Expected idle client %s to be forgotten", addr)
+		}
+	}
+}
+
+func TestAuthJellyfin(t *testing.T) {
+	server, handler := newAuthTestRouter(t, config.AuthConfig{Mode: "jellyfin", ProtectReads: true})
+
+	if w := apiLogin(handler, "alice", "secret"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected sign-in to be unavailable without a verifier, got %d", w.Code)
+	}
+
+	offline := false
+	server.SetCredentialVerifier(verifierFunc(func(ctx context.Context, username, password string) (string, error) {
+		if offline {
+			return "", jellyfin.ErrOffline
+		}
+		if username != "alice" || password != "secret" {
+			return "", jellyfin.ErrInvalidCredentials
+		}
+		return "user-alice", nil
+	}))
+
+	// Reads need a sign-in too: browsers are sent to the form, the API refused
+	w := kioskRequest(handler, http.MethodGet, "/?tab=queue", nil)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login?next=%2F%3Ftab%3Dqueue" {
+		t.Errorf("Expected a redirect to the sign-in page, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := kioskRequest(handler, http.MethodGet, "/api/status", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected reads to need a sign-in, got %d", w.Code)
+	}
+
+	w = kioskRequest(handler, http.MethodGet, "/login?next=/%3Ftab%3Dqueue", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `name="username"`) || !strings.Contains(w.Body.String(), `value="/?tab=queue"`) {
+		t.Errorf("Expected a sign-in form asking for a username, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = kioskRequest(handler, http.MethodPost, "/login", url.Values{"username": {"alice"}, "password": {"wrong"}})
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "Incorrect") {
+		t.Errorf("Expected the form to report a wrong password, got %d", w.Code)
+	}
+
+	// The form only returns to pages on this server
+	w = kioskRequest(handler, http.MethodPost, "/login", url.Values{"username": {"alice"}, "password": {"secret"}, "next": {"//evil.example"}})
+	cookie := sessionCookieFrom(w)
+	if w.Code != http.StatusSeeOther || cookie == nil || w.Header().Get("Location") != "/" {
+		t.Fatalf("Expected to be signed in and sent home, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := kioskRequest(handler, http.MethodGet, "/", nil, cookie); w.Body.String() != "full interface" {
+		t.Errorf("Expected the session to open the UI, got %d", w.Code)
+	}
+
+	offline = true
+	if w := apiLogin(handler, "alice", "secret"); w.Code != http.StatusBadGateway {
+		t.Errorf("Expected an unreachable Jellyfin to be reported, got %d", w.Code)
+	}
+}
+
+func TestAuthProxy(t *testing.T) {
+	_, handler := newAuthTestRouter(t, config.AuthConfig{Mode: "proxy", TrustedProxies: []string{"10.0.0.0/8"}})
+
+	request := func(remoteAddr string, headers map[string]string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/queue/add", nil)
+		req.RemoteAddr = remoteAddr
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := request("10.1.2.3:5000", map[string]string{"Remote-User": "alice"}); code != http.StatusOK {
+		t.Errorf("Expected the proxy's user to be trusted, got %d", code)
+	}
+	if code := request("10.1.2.3:5000", nil); code != http.StatusUnauthorized {
+		t.Errorf("Expected a request without a user to be refused, got %d", code)
+	}
+	if code := request("192.168.1.5:5000", map[string]string{"Remote-User": "alice"}); code != http.StatusUnauthorized {
+		t.Errorf("Expected the header to be ignored from other addresses, got %d", code)
+	}
+	// Forwarding headers do not make a client look like the proxy
+	if code := request("192.168.1.5:5000", map[string]string{"Remote-User": "alice", "X-Real-IP": "10.0.0.1"}); code != http.StatusUnauthorized {
+		t.Errorf("Expected a spoofed proxy address to be refused, got %d", code)
+	}
+
+	if w := apiLogin(handler, "alice", "secret"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected no password sign-in behind a proxy, got %d", w.Code)
+	}
+}
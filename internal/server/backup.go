@@ -0,0 +1,62 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// backupPath serves the database backup. Large databases take longer than
+// the request timeout to send, so the path is exempt from it.
+const backupPath = "/api/backup"
+
+// handleBackup streams a consistent snapshot of the metadata database, so
+// state can be backed up without stopping the service. The snapshot is a
+// regular bolt file that can replace go-jf-watch.db while stopped. It holds
+// the saved Jellyfin session and signing secrets, so it is only served to
+// signed-in or admin-scoped callers and not at all without sign-in.
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
+	if s.config.Auth.Mode == "" {
+		s.writeErrorResponse(w, http.StatusForbidden, "Backups require sign-in to be enabled", nil)
+		return
+	}
+
+	// The server's write timeout would cut off large databases
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		s.logger.Debug("Failed to clear backup write deadline", "error", err)
+	}
+
+	filename := fmt.Sprintf("go-jf-watch-%s.db", time.Now().Format("20060102-150405"))
+
+	written, err := s.storage.Backup(w, func(size int64) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		w.WriteHeader(http.StatusOK)
+	})
+	if err != nil {
+		// The status line is already sent; the client sees a short body
+		s.logger.Error("Database backup failed", "bytes_written", written, "error", err)
+		return
+	}
+
+	s.logger.Info("Database backup downloaded", "bytes", written, "remote_addr", r.RemoteAddr)
+}
+
+// handleCompact compacts the metadata database now rather than waiting for
+// the maintenance window.
+func (s *Server) handleCompact(w http.ResponseWriter, r *http.Request) {
+	result, err := s.storage.CompactDatabase(r.Context())
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to compact database", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "Database compacted",
+		Data:    result,
+	})
+}
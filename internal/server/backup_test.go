@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestHandleBackup(t *testing.T) {
+	server := newShareTestServer(t)
+
+	// Without sign-in there is nobody to serve the credentials it holds to
+	w := httptest.NewRecorder()
+	server.handleBackup(w, httptest.NewRequest(http.MethodGet, "/api/backup", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 without sign-in, got %d", w.Code)
+	}
+
+	server.config.Auth.Mode = "password"
+	w = httptest.NewRecorder()
+	server.handleBackup(w, httptest.NewRequest(http.MethodGet, "/api/backup", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if disposition := w.Header().Get("Content-Disposition"); !strings.HasPrefix(disposition, `attachment; filename="go-jf-watch-`) {
+		t.Errorf("Unexpected Content-Disposition %q", disposition)
+	}
+	length, err := strconv.Atoi(w.Header().Get("Content-Length"))
+	if err != nil || length == 0 || length != w.Body.Len() {
+		t.Errorf("Expected Content-Length to match the %d byte body, got %q", w.Body.Len(), w.Header().Get("Content-Length"))
+	}
+}
+
+func TestBackupRequiresSignIn(t *testing.T) {
+	server, handler := newAuthTestRouter(t, config.AuthConfig{Mode: "password", Password: "correct horse"})
+	server.router.Get("/api/backup", server.handleBackup)
+
+	// Reads are open, but not the backup
+	if w := kioskRequest(handler, http.MethodGet, "/api/backup", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected an anonymous backup to get 401, got %d", w.Code)
+	}
+
+	cookie := sessionCookieFrom(apiLogin(handler, "", "correct horse"))
+	if w := kioskRequest(handler, http.MethodGet, "/api/backup", nil, cookie); w.Code != http.StatusOK {
+		t.Errorf("Expected a signed-in backup to succeed, got %d", w.Code)
+	}
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+)
+
+// CollectionCacheRequest is the optional body of
+// POST /api/collections/{id}/cache. Priority 0 uses the default of 3.
+type CollectionCacheRequest struct {
+	Priority int `json:"priority,omitempty"`
+}
+
+// SetCollections sets the synced Jellyfin collections and playlists.
+func (s *Server) SetCollections(collections *downloader.Collections) {
+	s.collections = collections
+}
+
+// handleListCollections returns every collection and playlist with how
+// much of it is cached.
+func (s *Server) handleListCollections(w http.ResponseWriter, r *http.Request) {
+	if !s.collectionsEnabled(w) {
+		return
+	}
+
+	collections, err := s.collections.List()
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to list collections", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    collections,
+	})
+}
+
+// handleGetCollection returns the progress of one collection, item by
+// item in collection or playlist order.
+func (s *Server) handleGetCollection(w http.ResponseWriter, r *http.Request) {
+	if !s.collectionsEnabled(w) {
+		return
+	}
+
+	progress, err := s.collections.Progress(chi.URLParam(r, "id"))
+	if errors.Is(err, downloader.ErrCollectionNotFound) {
+		s.writeErrorResponse(w, http.StatusNotFound, "Collection not found", nil)
+		return
+	}
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to get collection", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    progress,
+	})
+}
+
+// handleCacheCollection queues a whole collection or playlist, in order.
+// Items added to it later are queued as collections are synced.
+func (s *Server) handleCacheCollection(w http.ResponseWriter, r *http.Request) {
+	if !s.collectionsEnabled(w) {
+		return
+	}
+
+	var req CollectionCacheRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if !validSubscriptionPriority(req.Priority) {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Priority must be between 1 and 4", nil)
+		return
+	}
+
+	result, err := s.collections.Cache(r.Context(), chi.URLParam(r, "id"), req.Priority)
+	if errors.Is(err, downloader.ErrCollectionNotFound) {
+		s.writeErrorResponse(w, http.StatusNotFound, "Collection not found", nil)
+		return
+	}
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to cache collection", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "Collection queued for caching",
+		Data:    result,
+	})
+}
+
+// handleUncacheCollection stops queueing items added to a collection.
+// Items already cached or queued are kept.
+func (s *Server) handleUncacheCollection(w http.ResponseWriter, r *http.Request) {
+	if !s.collectionsEnabled(w) {
+		return
+	}
+
+	err := s.collections.Uncache(chi.URLParam(r, "id"))
+	if errors.Is(err, downloader.ErrCollectionNotFound) {
+		s.writeErrorResponse(w, http.StatusNotFound, "Collection not found", nil)
+		return
+	}
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to update collection", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "Collection no longer cached",
+	})
+}
+
+// handleSyncCollections syncs collections and playlists from Jellyfin now
+// rather than at the next interval.
+func (s *Server) handleSyncCollections(w http.ResponseWriter, r *http.Request) {
+	if !s.collectionsEnabled(w) {
+		return
+	}
+
+	if err := s.collections.Sync(r.Context()); err != nil {
+		s.writeErrorResponse(w, http.StatusBadGateway, "Failed to sync collections", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "Collections synced",
+	})
+}
+
+// collectionsEnabled writes a 503 and returns false when no collections
+// are set.
+func (s *Server) collectionsEnabled(w http.ResponseWriter) bool {
+	if s.collections == nil {
+		s.writeErrorResponse(w, http.StatusServiceUnavailable, "Collections are not enabled", nil)
+		return false
+	}
+	return true
+}
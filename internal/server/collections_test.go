@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestHandleCollections(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	server := newTestServer(t, &Server{logger: logger})
+
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := call(http.MethodGet, "/api/collections", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without collections, got %d", w.Code)
+	}
+
+	store, err := storage.NewManager(&config.CacheConfig{Directory: t.TempDir(), MaxSizeGB: 1, MetadataStore: "boltdb"}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	source := jellyfintest.New("http://jellyfin.test")
+	source.Collections = []jellyfin.MediaItem{{ID: "p1", Name: "Road Trip", Type: "Playlist"}}
+	source.CollectionItems = map[string][]jellyfin.MediaItem{
+		"p1": {{ID: "t2", Name: "Second", Type: "Audio"}, {ID: "t1", Name: "First", Type: "Audio"}},
+	}
+	queue := downloader.New(&config.DownloadConfig{Workers: 1}, store, logger)
+	server.SetCollections(downloader.NewCollections(source, store, queue, 0, logger))
+
+	if w := call(http.MethodPost, "/api/collections/sync", ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 syncing, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := call(http.MethodPost, "/api/collections/p1/cache", `{"priority": 9}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid priority, got %d", w.Code)
+	}
+	if w := call(http.MethodPost, "/api/collections/gone/cache", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown collection, got %d", w.Code)
+	}
+	if w := call(http.MethodPost, "/api/collections/p1/cache", ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 caching, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := call(http.MethodGet, "/api/collections/p1", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data downloader.CollectionProgress `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !resp.Data.Cached || resp.Data.Total != 2 || len(resp.Data.Items) != 2 || resp.Data.Items[0].MediaID != "t2" {
+		t.Errorf("Expected the cached playlist in playlist order, got %+v", resp.Data)
+	}
+
+	if w := call(http.MethodDelete, "/api/collections/p1/cache", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected 200 uncaching, got %d", w.Code)
+	}
+	w = call(http.MethodGet, "/api/collections", "")
+	var list struct {
+		Data []downloader.CollectionProgress `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(list.Data) != 1 || list.Data[0].Cached {
+		t.Errorf("Expected one uncached playlist, got %+v", list.Data)
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// DeviceStatsResponse reports playback starts per device class and the
+// variant predicted items are currently cached in.
+type DeviceStatsResponse struct {
+	Devices          []*storage.DeviceUsage `json:"devices"`
+	PredictedDevice  string                 `json:"predicted_device,omitempty"`
+	PredictedQuality string                 `json:"predicted_quality"`
+}
+
+// recordDevicePlayback counts the start of a playback against the client's
+// device class. Seeks arrive as further range requests and are not counted.
+func (s *Server) recordDevicePlayback(r *http.Request) {
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" && rangeHeader != "bytes=0-" {
+		return
+	}
+
+	device := downloader.ClassifyUserAgent(r.UserAgent())
+	if device == "" {
+		return
+	}
+	if err := s.history.RecordDevicePlayback(device, time.Now()); err != nil {
+		s.logger.Debug("Failed to record device playback", "device", device, "error", err)
+	}
+}
+
+// handleDeviceStats returns per-device playback statistics.
+func (s *Server) handleDeviceStats(w http.ResponseWriter, r *http.Request) {
+	devices, err := s.history.GetDeviceUsage()
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to load device statistics", err)
+		return
+	}
+	if devices == nil {
+		devices = []*storage.DeviceUsage{}
+	}
+
+	resp := DeviceStatsResponse{
+		Devices:          devices,
+		PredictedQuality: "original",
+	}
+	if s.predictor != nil {
+		quality, device := s.predictor.PredictedQuality(time.Now())
+		resp.PredictedDevice = device
+		if quality != "" {
+			resp.PredictedQuality = quality
+		}
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    resp,
+	})
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestDeviceStats(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	store := storagetest.New()
+	predictor := downloader.NewPredictor(store, &config.PredictionConfig{
+		AdaptiveQuality: true,
+		DeviceQuality:   map[string]string{"phone": "720p"},
+	}, logger)
+
+	server := newTestServer(t, &Server{logger: logger, history: store, predictor: predictor})
+
+	phoneUA := "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148"
+	for _, rangeHeader := range []string{"", "bytes=0-", "bytes=1000-", ""} {
+		req := httptest.NewRequest(http.MethodGet, "/stream/m1", nil)
+		req.Header.Set("User-Agent", phoneUA)
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		server.recordDevicePlayback(req)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/devices", nil)
+	w := httptest.NewRecorder()
+	server.handleDeviceStats(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Data DeviceStatsResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(resp.Data.Devices) != 1 || resp.Data.Devices[0].Plays != 3 {
+		t.Fatalf("Expected 3 phone playbacks (seeks excluded), got %+v", resp.Data.Devices)
+	}
+	if resp.Data.PredictedDevice != "phone" || resp.Data.PredictedQuality != "720p" {
+		t.Errorf("Expected phone/720p prediction, got %s/%s", resp.Data.PredictedDevice, resp.Data.PredictedQuality)
+	}
+}
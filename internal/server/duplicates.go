@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// ResolveDuplicatesRequest selects the copy to keep and how to deduplicate
+// the others.
+type ResolveDuplicatesRequest struct {
+	Keep       string   `json:"keep"`
+	Duplicates []string `json:"duplicates"`
+	Mode       string   `json:"mode"` // hardlink (default) or remove
+}
+
+// handleListDuplicates returns groups of cached items holding the same content.
+func (s *Server) handleListDuplicates(w http.ResponseWriter, r *http.Request) {
+	groups, err := s.storage.FindDuplicates(r.Context())
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to scan for duplicates", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    groups,
+	})
+}
+
+// handleResolveDuplicates keeps one cached copy and hardlinks or removes the rest.
+func (s *Server) handleResolveDuplicates(w http.ResponseWriter, r *http.Request) {
+	var req ResolveDuplicatesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if req.Keep == "" || len(req.Duplicates) == 0 {
+		s.writeErrorResponse(w, http.StatusBadRequest, "keep and duplicates are required", nil)
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = storage.DedupHardlink
+	}
+	if req.Mode != storage.DedupHardlink && req.Mode != storage.DedupRemove {
+		s.writeErrorResponse(w, http.StatusBadRequest, "mode must be hardlink or remove", nil)
+		return
+	}
+
+	result, err := s.storage.ResolveDuplicates(req.Keep, req.Duplicates, req.Mode)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Failed to resolve duplicates", err)
+		return
+	}
+
+	message := "Duplicates resolved"
+	if len(result.Errors) > 0 {
+		message = "Some duplicates could not be resolved"
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: len(result.Errors) == 0,
+		Message: message,
+		Data:    result,
+	})
+}
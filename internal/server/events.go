@@ -0,0 +1,78 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// EventInfo is an event log entry with the display title of its media.
+type EventInfo struct {
+	*storage.Event
+	Title string `json:"title,omitempty"`
+}
+
+// handleEvents returns the event log, newest first. It can be filtered by
+// ?kind (comma separated), ?media_id, and ?since and ?until (RFC 3339,
+// until exclusive); ?limit caps the number of events, 100 by default.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if s.events == nil {
+		s.writeErrorResponse(w, http.StatusServiceUnavailable, "Event log not available", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := storage.EventFilter{MediaID: query.Get("media_id")}
+	if kinds := query.Get("kind"); kinds != "" {
+		filter.Kinds = strings.Split(kinds, ",")
+	}
+	for param, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			s.writeErrorResponse(w, http.StatusBadRequest, "Invalid "+param+" time, expected RFC 3339", err)
+			return
+		}
+		*t = parsed
+	}
+	filter.Limit, _ = strconv.Atoi(query.Get("limit"))
+	if filter.Limit < 1 || filter.Limit > 1000 {
+		filter.Limit = 100
+	}
+
+	events, err := s.events.GetEvents(filter)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to read event log", err)
+		return
+	}
+
+	infos := make([]EventInfo, 0, len(events))
+	for _, event := range events {
+		info := EventInfo{Event: event}
+		if event.MediaID != "" && s.library != nil {
+			info.Title = s.widgetTitle(event.MediaID)
+		}
+		infos = append(infos, info)
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    infos,
+	})
+}
+
+// recordEvent adds event to the event log, if there is one.
+func (s *Server) recordEvent(event *storage.Event) {
+	if s.events == nil {
+		return
+	}
+	if err := s.events.RecordEvent(event); err != nil {
+		s.logger.Debug("Failed to record event", "kind", event.Kind, "error", err)
+	}
+}
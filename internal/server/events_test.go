@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+func TestHandleEvents(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sm := newTestStorage(t, t.TempDir())
+
+	if err := sm.AddMediaMetadata(&storage.MediaMetadata{ID: "m1", JellyfinID: "m1", Name: "Heat", Type: "movie"}); err != nil {
+		t.Fatalf("Failed to add metadata: %v", err)
+	}
+	base := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for _, event := range []*storage.Event{
+		{Time: base, Kind: storage.EventDownloadCompleted, MediaID: "m1", Message: "Downloaded at priority 3"},
+		{Time: base.Add(time.Hour), Kind: storage.EventSync, Message: "Prediction cycle"},
+		{Time: base.Add(2 * time.Hour), Kind: storage.EventEvicted, MediaID: "m1", Message: "Evicted by the lru policy to free space"},
+	} {
+		if err := sm.RecordEvent(event); err != nil {
+			t.Fatalf("Failed to record event: %v", err)
+		}
+	}
+
+	server := newTestServer(t, &Server{logger: logger, storage: sm})
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantKinds  []string
+	}{
+		{"all", "", http.StatusOK, []string{"evicted", "sync", "download_completed"}},
+		{"by media and kind", "?media_id=m1&kind=evicted,download_completed", http.StatusOK, []string{"evicted", "download_completed"}},
+		{"since", "?since=2026-10-01T13:00:00Z", http.StatusOK, []string{"evicted", "sync"}},
+		{"until", "?until=2026-10-01T13:00:00Z&limit=5", http.StatusOK, []string{"download_completed"}},
+		{"bad time", "?since=yesterday", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			server.handleEvents(w, httptest.NewRequest(http.MethodGet, "/api/events"+tt.query, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Data []EventInfo `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			var kinds []string
+			for _, event := range response.Data {
+				kinds = append(kinds, event.Kind)
+				if event.MediaID == "m1" && event.Title != "Heat" {
+					t.Errorf("Expected the title of m1, got %q", event.Title)
+				}
+			}
+			if strings.Join(kinds, ",") != strings.Join(tt.wantKinds, ",") {
+				t.Errorf("Expected %v, got %v", tt.wantKinds, kinds)
+			}
+		})
+	}
+}
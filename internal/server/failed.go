@@ -0,0 +1,91 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// FailedItem is a download that gave up, with why each of its latest
+// attempts failed.
+type FailedItem struct {
+	ID       string                 `json:"id"`
+	MediaID  string                 `json:"media_id"`
+	Title    string                 `json:"title"`
+	Priority int                    `json:"priority"`
+	Status   string                 `json:"status"` // dead_letter when out of retries, failed when retrying would not help
+	Attempts int                    `json:"attempts"`
+	Error    string                 `json:"error"`
+	FailedAt time.Time              `json:"failed_at,omitempty"`
+	Errors   []storage.AttemptError `json:"errors"`
+}
+
+// handleQueueFailed lists the downloads that gave up, most urgent first.
+func (s *Server) handleQueueFailed(w http.ResponseWriter, r *http.Request) {
+	items, err := s.downloadManager.FailedJobs()
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to get failed downloads", err)
+		return
+	}
+
+	failed := make([]FailedItem, 0, len(items))
+	for _, item := range items {
+		history := item.ErrorHistory
+		if history == nil {
+			history = []storage.AttemptError{}
+		}
+		failed = append(failed, FailedItem{
+			ID:       item.ID,
+			MediaID:  item.MediaID,
+			Title:    s.widgetTitle(item.MediaID),
+			Priority: item.Priority,
+			Status:   item.Status,
+			Attempts: item.RetryCount + 1,
+			Error:    item.ErrorMessage,
+			FailedAt: item.CompletedAt,
+			Errors:   history,
+		})
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    failed,
+	})
+}
+
+// handleQueueFailedRetry returns a failed download to the queue with a
+// fresh set of retries.
+func (s *Server) handleQueueFailedRetry(w http.ResponseWriter, r *http.Request) {
+	queueID := chi.URLParam(r, "id")
+	if err := s.downloadManager.RetryFailedJob(queueID); err != nil {
+		s.writeQueueControlError(w, queueID, "Failed to retry download", err)
+		return
+	}
+
+	s.logger.Info("Retrying failed download", "queue_id", queueID)
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "Download queued for retry",
+	})
+}
+
+// handleQueueFailedRetryAll returns every failed download to the queue.
+func (s *Server) handleQueueFailedRetryAll(w http.ResponseWriter, r *http.Request) {
+	retried, err := s.downloadManager.RetryFailedJobs()
+	if err != nil {
+		s.logger.Error("Failed to retry failed downloads", "retried", retried, "error", err)
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retry downloads", err)
+		return
+	}
+
+	s.logger.Info("Retrying failed downloads", "count", retried)
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    map[string]int{"retried": retried},
+	})
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestHandleQueueFailed(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sm := newTestStorage(t, t.TempDir())
+
+	failedAt := time.Date(2026, 10, 14, 3, 12, 0, 0, time.UTC)
+	for _, item := range []*storage.QueueItem{
+		{ID: "job1", MediaID: "m1", Priority: 3, Status: "dead_letter", RetryCount: 6, CreatedAt: time.Now(),
+			CompletedAt: failedAt, ErrorMessage: "HTTP error: 503",
+			ErrorHistory: []storage.AttemptError{
+				{At: failedAt.Add(-time.Minute), Message: "connection reset by peer"},
+				{At: failedAt, Message: "HTTP error: 503", HTTPStatus: 503},
+			}},
+		{ID: "job2", MediaID: "m2", Priority: 2, Status: "failed", CreatedAt: time.Now(), ErrorMessage: "HTTP error: 404"},
+		{ID: "job3", MediaID: "m3", Priority: 3, Status: "queued", CreatedAt: time.Now()},
+	} {
+		if err := sm.AddQueueItem(item); err != nil {
+			t.Fatalf("Failed to add queue item: %v", err)
+		}
+	}
+
+	manager := downloader.New(&config.DownloadConfig{Workers: 1, RateLimitMbps: 10}, sm, logger)
+	server := newTestServer(t, &Server{logger: logger, storage: sm, downloadManager: manager})
+
+	listFailed := func() []FailedItem {
+		t.Helper()
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/queue/failed", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response struct {
+			Data []FailedItem `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response.Data
+	}
+
+	failed := listFailed()
+	if len(failed) != 2 {
+		t.Fatalf("Expected 2 failed downloads, got %+v", failed)
+	}
+	var dead FailedItem
+	for _, item := range failed {
+		if item.ID == "job1" {
+			dead = item
+		}
+	}
+	if dead.Status != "dead_letter" || dead.Attempts != 7 || dead.Error != "HTTP error: 503" || !dead.FailedAt.Equal(failedAt) {
+		t.Errorf("Unexpected dead letter %+v", dead)
+	}
+	if len(dead.Errors) != 2 || dead.Errors[1].HTTPStatus != 503 {
+		t.Errorf("Expected the error history, got %+v", dead.Errors)
+	}
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/api/queue/failed/job3/retry", http.StatusConflict},
+		{"/api/queue/failed/missing/retry", http.StatusNotFound},
+		{"/api/queue/failed/job1/retry", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, nil))
+		if w.Code != tt.wantStatus {
+			t.Errorf("POST %s: expected status %d, got %d: %s", tt.path, tt.wantStatus, w.Code, w.Body.String())
+		}
+	}
+	if failed := listFailed(); len(failed) != 1 || failed[0].ID != "job2" {
+		t.Errorf("Expected only job2 to be left, got %+v", failed)
+	}
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/queue/failed/retry", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if failed := listFailed(); len(failed) != 0 {
+		t.Errorf("Expected no failed downloads after retrying all, got %+v", failed)
+	}
+	item, err := sm.FindActiveQueueItem("m2")
+	if err != nil || item == nil || item.Status != "queued" {
+		t.Errorf("Expected job2 to be queued again, got %+v (%v)", item, err)
+	}
+}
@@ -2,13 +2,18 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
 	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/media"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
 )
 
 // APIResponse represents a standard API response structure.
@@ -21,14 +26,25 @@ type APIResponse struct {
 
 // SystemStatus represents the current system status.
 type SystemStatus struct {
-	Status      string    `json:"status"`
-	Version     string    `json:"version"`
-	Uptime      string    `json:"uptime"`
-	CacheSize   int64     `json:"cache_size_bytes"`
-	CacheItems  int       `json:"cache_items"`
-	QueueLength int       `json:"queue_length"`
-	ActiveJobs  int       `json:"active_jobs"`
-	LastSync    time.Time `json:"last_sync,omitempty"`
+	Status      string              `json:"status"`
+	Version     string              `json:"version"`
+	Uptime      string              `json:"uptime"`
+	CacheSize   int64               `json:"cache_size_bytes"`
+	CacheItems  int                 `json:"cache_items"`
+	QueueLength int                 `json:"queue_length"`
+	ActiveJobs  int                 `json:"active_jobs"`
+	LastSync    time.Time           `json:"last_sync,omitempty"`
+	DiskHealth  *storage.DiskHealth `json:"disk_health,omitempty"`
+	// Download attempts since startup that resumed or started from scratch
+	ResumedDownloads int `json:"resumed_downloads"`
+	ColdStarts       int `json:"cold_starts"`
+	// Bytes downloaded today and this hour, against download.max_daily_gb
+	Bandwidth downloader.BandwidthStatus `json:"bandwidth"`
+	// How predicted downloads turned out at each priority
+	PredictionAccuracy []downloader.PriorityAccuracy `json:"prediction_accuracy"`
+	// Whether the Jellyfin server is reachable; Status is "offline" when
+	// it is not
+	Jellyfin *jellyfin.ConnectivityStatus `json:"jellyfin,omitempty"`
 }
 
 // QueueItem represents an item in the download queue.
@@ -49,11 +65,19 @@ type AddToQueueRequest struct {
 	Priority *int   `json:"priority,omitempty"` // Pointer to distinguish unset from zero
 }
 
+// Health reports whether the server can still serve the cache, by
+// checking that storage is accessible. The systemd watchdog is only pinged
+// while it returns nil.
+func (s *Server) Health() error {
+	// Check storage health by attempting to get cache stats
+	_, err := s.storage.GetCacheStats()
+	return err
+}
+
 // handleHealth provides a simple health check endpoint.
 // Returns 200 OK if the server is running and storage is accessible.
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	// Check storage health by attempting to get cache stats
-	if _, err := s.storage.GetCacheStats(); err != nil {
+	if err := s.Health(); err != nil {
 		s.writeErrorResponse(w, http.StatusServiceUnavailable, "Storage unavailable", err)
 		return
 	}
@@ -89,6 +113,20 @@ func (s *Server) handleAPIStatus(w http.ResponseWriter, r *http.Request) {
 		QueueLength: queueStats.QueueSize,
 		ActiveJobs:  queueStats.ActiveDownloads,
 		LastSync:    s.predictor.GetLastSyncTime(),
+		DiskHealth:  s.downloadManager.DiskHealth(),
+
+		ResumedDownloads: queueStats.ResumedDownloads,
+		ColdStarts:       queueStats.ColdStarts,
+		Bandwidth:        s.downloadManager.BandwidthStatus(),
+
+		PredictionAccuracy: s.predictor.Accuracy(),
+	}
+	if s.connectivity != nil {
+		connectivity := s.connectivity.Status()
+		status.Jellyfin = &connectivity
+		if !connectivity.Online {
+			status.Status = "offline"
+		}
 	}
 
 	s.writeJSONResponse(w, http.StatusOK, APIResponse{
@@ -97,8 +135,18 @@ func (s *Server) handleAPIStatus(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// libraryTypes maps the plural media types the UI filters by to the media
+// types of download records.
+var libraryTypes = map[string]string{
+	"movies":   "movie",
+	"episodes": "episode",
+}
+
 // handleLibrary returns the list of cached media items.
-// Supports pagination and filtering parameters for large libraries.
+// Supports pagination and filtering parameters for large libraries: page
+// and limit, or the next_cursor of the previous page as cursor, which
+// stays stable while downloads complete; sort (added, size, accessed or
+// name) and order (asc or desc).
 func (s *Server) handleLibrary(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
@@ -112,15 +160,83 @@ func (s *Server) handleLibrary(w http.ResponseWriter, r *http.Request) {
 	}
 
 	mediaType := r.URL.Query().Get("type") // movies, series, episodes
+	if recordType, ok := libraryTypes[mediaType]; ok {
+		mediaType = recordType
+	}
+
+	if provider := r.URL.Query().Get("provider"); provider != "" && provider != media.ProviderJellyfin {
+		s.handleProviderLibrary(w, r, provider, page, limit)
+		return
+	}
 
 	// Get cached items from storage
-	items, err := s.storage.GetCachedItems(mediaType, page, limit)
+	result, err := s.library.QueryCachedItems(storage.LibraryQuery{
+		MediaType: mediaType,
+		Sort:      r.URL.Query().Get("sort"),
+		Order:     r.URL.Query().Get("order"),
+		Limit:     limit,
+		Offset:    (page - 1) * limit,
+		Cursor:    r.URL.Query().Get("cursor"),
+	})
+	if errors.Is(err, storage.ErrInvalidLibraryQuery) {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid library query", err)
+		return
+	}
 	if err != nil {
 		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to get cached items", err)
 		return
 	}
+	libraryItems := s.libraryItems(result.Items)
+
+	response := map[string]interface{}{
+		"items":       libraryItems,
+		"page":        page,
+		"limit":       limit,
+		"total_items": result.Total,
+		"total_pages": (result.Total + limit - 1) / limit,
+		"next_cursor": result.NextCursor,
+		// Listings always come from the metadata store; offline means it
+		// cannot be refreshed until Jellyfin is reachable again
+		"offline": s.jellyfinOffline(),
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    response,
+	})
+}
+
+// handleLibrarySearch searches the names, genres and overviews of cached
+// items for every word of ?q, best matches first, without asking Jellyfin.
+func (s *Server) handleLibrarySearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if strings.TrimSpace(query) == "" {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Search query is required", nil)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	items, err := s.storage.SearchCachedItems(query, limit)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to search library", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"query": query,
+			"items": s.libraryItems(items),
+		},
+	})
+}
 
-	// Convert to API format
+// libraryItems converts cached items to the library API format.
+func (s *Server) libraryItems(items []*storage.CachedItem) []jellyfin.LibraryItem {
 	libraryItems := make([]jellyfin.LibraryItem, len(items))
 	for i, item := range items {
 		libraryItems[i] = jellyfin.LibraryItem{
@@ -136,26 +252,69 @@ func (s *Server) handleLibrary(w http.ResponseWriter, r *http.Request) {
 				SeasonNumber:  item.SeasonNumber,
 				EpisodeNumber: item.EpisodeNumber,
 			},
+			Pinned: item.Pinned,
 		}
 	}
+	s.markStale(items, libraryItems)
+	setThumbnails(items, libraryItems)
+	return libraryItems
+}
 
-	// Get total count for pagination
-	totalCount, err := s.storage.GetCachedItemsCount(mediaType)
+// handleProviderLibrary lists items exposed by a non-Jellyfin provider,
+// using the same pagination and response shape as handleLibrary.
+func (s *Server) handleProviderLibrary(w http.ResponseWriter, r *http.Request, provider string, page, limit int) {
+	if s.providers == nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "Media provider not configured", nil)
+		return
+	}
+
+	p, err := s.providers.Get(provider)
 	if err != nil {
-		s.logger.Warn("Failed to get total items count", "error", err)
-		totalCount = len(libraryItems) // Fallback to current page count
+		s.writeErrorResponse(w, http.StatusNotFound, "Unknown media provider", err)
+		return
 	}
 
-	response := map[string]interface{}{
-		"items":       libraryItems,
-		"page":        page,
-		"limit":       limit,
-		"total_items": totalCount,
+	items, err := p.ListItems(r.Context())
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to list provider items", err)
+		return
+	}
+
+	start := (page - 1) * limit
+	if start > len(items) {
+		start = len(items)
+	}
+	end := start + limit
+	if end > len(items) {
+		end = len(items)
+	}
+
+	libraryItems := make([]jellyfin.LibraryItem, 0, end-start)
+	for _, item := range items[start:end] {
+		libraryItems = append(libraryItems, jellyfin.LibraryItem{
+			MediaItem: jellyfin.MediaItem{
+				ID:            item.ID.String(),
+				Name:          item.Name,
+				Type:          item.Type,
+				Container:     item.Container,
+				Size:          item.Size,
+				Genres:        item.Genres,
+				SeriesName:    item.SeriesName,
+				SeasonNumber:  item.SeasonNumber,
+				EpisodeNumber: item.EpisodeNumber,
+			},
+		})
 	}
 
 	s.writeJSONResponse(w, http.StatusOK, APIResponse{
 		Success: true,
-		Data:    response,
+		Data: map[string]interface{}{
+			"items":       libraryItems,
+			"page":        page,
+			"limit":       limit,
+			"total_items": len(items),
+			"total_pages": (len(items) + limit - 1) / limit,
+		},
 	})
 }
 
@@ -227,6 +386,18 @@ func (s *Server) handleQueueAdd(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	// Get actual job ID from download manager
 	jobID, err := s.downloadManager.QueueDownload(ctx, req.MediaID, priority)
+	if errors.Is(err, downloader.ErrLibraryExcluded) {
+		s.writeErrorResponse(w, http.StatusForbidden, "Item belongs to a library excluded from caching", err)
+		return
+	}
+	if errors.Is(err, downloader.ErrQueueFull) {
+		s.writeErrorResponse(w, http.StatusTooManyRequests, "Download queue is full for this priority", err)
+		return
+	}
+	if errors.Is(err, downloader.ErrInsufficientSpace) {
+		s.writeErrorResponse(w, http.StatusInsufficientStorage, "Not enough cache space for this item", err)
+		return
+	}
 	if err != nil {
 		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to add item to queue", err)
 		return
@@ -263,8 +434,7 @@ func (s *Server) handleQueueRemove(w http.ResponseWriter, r *http.Request) {
 	// Remove from download manager queue
 	ctx := r.Context()
 	if err := s.downloadManager.RemoveFromQueue(ctx, queueID); err != nil {
-		s.logger.Error("Failed to remove item from queue", "queue_id", queueID, "error", err)
-		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to remove item from queue", err)
+		s.writeQueueControlError(w, queueID, "Failed to remove item from queue", err)
 		return
 	}
 
@@ -276,26 +446,98 @@ func (s *Server) handleQueueRemove(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleQueuePause pauses a queue item. An in-flight download stops at once
+// and keeps what it has fetched.
+func (s *Server) handleQueuePause(w http.ResponseWriter, r *http.Request) {
+	queueID := chi.URLParam(r, "id")
+	if err := s.downloadManager.PauseJob(queueID); err != nil {
+		s.writeQueueControlError(w, queueID, "Failed to pause download", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "Download paused",
+	})
+}
+
+// handleQueueResume returns a paused queue item to the queue.
+func (s *Server) handleQueueResume(w http.ResponseWriter, r *http.Request) {
+	queueID := chi.URLParam(r, "id")
+	if err := s.downloadManager.ResumeJob(queueID); err != nil {
+		s.writeQueueControlError(w, queueID, "Failed to resume download", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "Download resumed",
+	})
+}
+
+// writeQueueControlError reports a failed pause, resume or removal of a
+// queue item.
+func (s *Server) writeQueueControlError(w http.ResponseWriter, queueID, message string, err error) {
+	switch {
+	case errors.Is(err, downloader.ErrQueueItemNotFound):
+		s.writeErrorResponse(w, http.StatusNotFound, "Queue item not found", err)
+	case errors.Is(err, downloader.ErrQueueItemState):
+		s.writeErrorResponse(w, http.StatusConflict, message, err)
+	default:
+		s.logger.Error(message, "queue_id", queueID, "error", err)
+		s.writeErrorResponse(w, http.StatusInternalServerError, message, err)
+	}
+}
+
+// SetPriorityRequest changes the priority of a queued or in-flight download.
+type SetPriorityRequest struct {
+	Priority *int `json:"priority"`
+}
+
+// handleQueuePriority changes the priority of a queue item. In-flight
+// downloads have their bandwidth share rebalanced immediately.
+func (s *Server) handleQueuePriority(w http.ResponseWriter, r *http.Request) {
+	queueID := chi.URLParam(r, "id")
+	if queueID == "" {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Queue ID is required", nil)
+		return
+	}
+
+	var req SetPriorityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if req.Priority == nil || *req.Priority < 0 || *req.Priority > 4 {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Priority must be between 0 and 4", nil)
+		return
+	}
+
+	if err := s.downloadManager.SetJobPriority(queueID, *req.Priority); err != nil {
+		s.logger.Error("Failed to change queue item priority", "queue_id", queueID, "error", err)
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to change priority", err)
+		return
+	}
+
+	s.logger.Info("Changed queue item priority", "queue_id", queueID, "priority", *req.Priority)
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "Priority updated",
+	})
+}
+
 // handleGetSettings returns the current application settings.
 // Used by the web UI to populate configuration forms.
 func (s *Server) handleGetSettings(w http.ResponseWriter, r *http.Request) {
-	// Return a subset of configuration that's safe to expose to UI
-	settings := map[string]interface{}{
-		"cache.max_size_gb":              500, // Would come from actual config
-		"cache.eviction_threshold":       0.85,
-		"download.workers":               3,
-		"download.rate_limit_mbps":       10,
-		"download.auto_download_current": true,
-		"download.auto_download_next":    true,
-		"download.auto_download_count":   2,
-		"server.port":                    s.config.Port,
-		"server.host":                    s.config.Host,
-		"server.enable_compression":      s.config.EnableCompression,
-		"prediction.enabled":             true,
-		"prediction.sync_interval":       "4h",
-		"prediction.history_days":        30,
-		"ui.theme":                       "auto",
-		"ui.language":                    "en",
+	// Only settings the UI can change, never secrets
+	settings := s.currentSettings().Settings()
+
+	// Preferred audio languages let the player pick a default audio track
+	if s.predictor != nil {
+		settings["prediction.preferred_languages"] = s.predictor.PreferredLanguages()
+		// Household users the player can report watching together
+		settings["prediction.household_users"] = s.predictor.HouseholdUsers()
 	}
 
 	s.writeJSONResponse(w, http.StatusOK, APIResponse{
@@ -304,23 +546,69 @@ func (s *Server) handleGetSettings(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handlePostSettings updates application settings.
-// In a full implementation, this would persist changes to configuration.
+// handlePostSettings validates and saves settings changed in the web UI.
+// Rate limit, workers and cache limits take effect at once; the rest on
+// the next restart.
 func (s *Server) handlePostSettings(w http.ResponseWriter, r *http.Request) {
-	var settings map[string]interface{}
+	var values map[string]interface{}
 
-	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&values); err != nil {
 		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload", err)
 		return
 	}
 
-	// In a full implementation, validate and persist settings
-	// For Phase 4, we'll just acknowledge the request
-	s.logger.Info("Settings update requested", "settings", settings)
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
 
+	if s.settings == nil {
+		s.writeErrorResponse(w, http.StatusServiceUnavailable, "Settings cannot be changed from the web UI", nil)
+		return
+	}
+	updated, changed, err := s.settings.ApplySettings(values)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid settings", err)
+		return
+	}
+	if len(changed) == 0 {
+		s.writeJSONResponse(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    SettingsUpdate{Applied: []string{}, RestartRequired: []string{}},
+			Message: "No settings changed",
+		})
+		return
+	}
+
+	if err := s.saveSettings(values, changed); err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to save settings", err)
+		return
+	}
+	update := s.applyLiveSettings(updated, changed)
+	s.settings = updated
+
+	shown := updated.Settings()
+	details := make(map[string]interface{}, len(changed))
+	for _, key := range changed {
+		if value, ok := shown[key]; ok {
+			details[key] = value
+		} else {
+			details[key] = "(changed)" // Secrets stay out of the event log
+		}
+	}
+	s.logger.Info("Settings updated", "applied", update.Applied, "restart_required", update.RestartRequired)
+	s.recordEvent(&storage.Event{
+		Kind:    storage.EventConfigChanged,
+		Message: "Settings updated from the web UI",
+		Details: details,
+	})
+
+	message := "Settings saved and applied"
+	if len(update.RestartRequired) > 0 {
+		message = "Settings saved; some take effect after a restart"
+	}
 	s.writeJSONResponse(w, http.StatusOK, APIResponse{
 		Success: true,
-		Message: "Settings saved successfully (changes require restart)",
+		Data:    update,
+		Message: message,
 	})
 }
 
@@ -358,7 +646,7 @@ func (s *Server) writeErrorResponse(w http.ResponseWriter, statusCode int, messa
 // getMediaTitle retrieves the title for a media ID from storage or jellyfin
 func (s *Server) getMediaTitle(mediaID string) string {
 	// Try to get title from cached metadata first
-	if item, err := s.storage.GetDownload(mediaID); err == nil {
+	if item, err := s.library.GetDownload(mediaID); err == nil {
 		if item.Title != "" {
 			return item.Title
 		}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/opd-ai/go-jf-watch/internal/hls"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// defaultHLSBandwidth is advertised for items whose bitrate cannot be
+// worked out, in bits per second.
+const defaultHLSBandwidth = 8_000_000
+
+// hlsContentTypes are the files a repackaging is made of.
+var hlsContentTypes = map[string]string{
+	".m3u8": "application/vnd.apple.mpegurl",
+	".mp4":  "video/mp4",
+	".m4s":  "video/mp4",
+}
+
+// handleHLSMaster serves the master playlist of a cached item, repackaging
+// it as HLS first if that has not been done. Only cached items can be
+// repackaged; anything else is streamed from /stream/{id} as usual.
+func (s *Server) handleHLSMaster(w http.ResponseWriter, r *http.Request) {
+	if s.hls == nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "HLS is not enabled", nil)
+		return
+	}
+	// Segments are written in the clear, which would defeat encryption
+	if s.cipher() != nil {
+		s.writeErrorResponse(w, http.StatusNotImplemented, "HLS is not available for an encrypted cache", nil)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	record, err := s.library.GetDownload(id)
+	if err != nil || record.LocalPath == "" || record.Status != "completed" {
+		s.writeErrorResponse(w, http.StatusNotFound, "Item not cached", nil)
+		return
+	}
+
+	if err := s.hls.Prepare(r.Context(), record.LocalPath); err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to repackage as HLS", err)
+		return
+	}
+	s.recordCacheAccess(r, id)
+
+	var playlist strings.Builder
+	playlist.WriteString("#EXTM3U\n#EXT-X-VERSION:7\n#EXT-X-INDEPENDENT-SEGMENTS\n")
+	fmt.Fprintf(&playlist, "#EXT-X-STREAM-INF:BANDWIDTH=%d\n", s.hlsBandwidth(record))
+	playlist.WriteString("hls/" + hls.PlaylistName + "\n")
+
+	w.Header().Set("Content-Type", hlsContentTypes[".m3u8"])
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write([]byte(playlist.String()))
+}
+
+// handleHLSFile serves the media playlist or a segment of a repackaged
+// item. The media playlist grows while repackaging is still running, so
+// it is only cached by clients once complete.
+func (s *Server) handleHLSFile(w http.ResponseWriter, r *http.Request) {
+	if s.hls == nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "HLS is not enabled", nil)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	name := chi.URLParam(r, "file")
+	contentType, ok := hlsContentTypes[filepath.Ext(name)]
+	if name != filepath.Base(name) || !ok {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid HLS file name", nil)
+		return
+	}
+
+	record, err := s.library.GetDownload(id)
+	if err != nil || record.LocalPath == "" {
+		s.writeErrorResponse(w, http.StatusNotFound, "Item not cached", nil)
+		return
+	}
+
+	file, err := os.Open(filepath.Join(storage.HLSDir(record.LocalPath), name))
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "No such HLS file", nil)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to read HLS file", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if name == hls.PlaylistName && !storage.HLSComplete(record.LocalPath) {
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		w.Header().Set("Cache-Control", "private, max-age=86400")
+	}
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
+
+// hlsBandwidth returns the peak bitrate to advertise for a cached item:
+// that of its variant when known, else its average over its runtime.
+func (s *Server) hlsBandwidth(record *storage.DownloadRecord) int64 {
+	if record.Bitrate > 0 {
+		return int64(record.Bitrate)
+	}
+	if runtime := s.runtime(record.JellyfinID); runtime > 0 && record.Size > 0 {
+		return int64(float64(record.Size*8) / runtime.Seconds())
+	}
+	return defaultHLSBandwidth
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/opd-ai/go-jf-watch/internal/hls"
+)
+
+// remuxer stands in for ffmpeg, writing a finished one-segment
+// repackaging.
+type remuxer struct{}
+
+func (remuxer) Package(ctx context.Context, input, outDir string) error {
+	for name, content := range map[string]string{
+		"init.mp4":       "init",
+		"seg-00000.m4s":  "segment",
+		hls.PlaylistName: "#EXTM3U\n#EXT-X-MAP:URI=\"init.mp4\"\n#EXTINF:6.0,\nseg-00000.m4s\n#EXT-X-ENDLIST\n",
+	} {
+		if err := os.WriteFile(filepath.Join(outDir, name), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hlsRequest(server *Server, id, file string) *httptest.ResponseRecorder {
+	path := "/stream/" + id + "/master.m3u8"
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	if file != "" {
+		path = "/stream/" + id + "/hls/" + file
+		rctx.URLParams.Add("file", file)
+	}
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+	if file != "" {
+		server.handleHLSFile(w, req)
+	} else {
+		server.handleHLSMaster(w, req)
+	}
+	return w
+}
+
+func TestHandleHLS(t *testing.T) {
+	server := newShareTestServer(t)
+
+	if w := hlsRequest(server, "v1", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 with HLS disabled, got %d", w.Code)
+	}
+
+	server.hls = hls.New(remuxer{}, server.logger)
+	defer server.hls.Stop()
+
+	w := hlsRequest(server, "v1", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/vnd.apple.mpegurl" {
+		t.Fatalf("Expected a master playlist, got %d %v: %s", w.Code, w.Header(), w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "#EXT-X-STREAM-INF:BANDWIDTH=8000000\nhls/index.m3u8\n") {
+		t.Errorf("Expected the media playlist to be referenced, got %s", w.Body.String())
+	}
+	if record, err := server.storage.GetDownload("v1"); err != nil || record.AccessCount != 1 {
+		t.Errorf("Expected playback to be recorded, got %+v (%v)", record, err)
+	}
+
+	// The packager runs in the background; the master playlist waits only
+	// for the first segment
+	var media *httptest.ResponseRecorder
+	for range 50 {
+		if media = hlsRequest(server, "v1", hls.PlaylistName); strings.Contains(media.Body.String(), "#EXT-X-ENDLIST") &&
+			media.Header().Get("Cache-Control") != "no-cache" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(media.Body.String(), "seg-00000.m4s") || media.Header().Get("Cache-Control") != "private, max-age=86400" {
+		t.Errorf("Expected the finished media playlist, got %v: %s", media.Header(), media.Body.String())
+	}
+
+	if w := hlsRequest(server, "v1", "seg-00000.m4s"); w.Code != http.StatusOK || w.Body.String() != "segment" || w.Header().Get("Content-Type") != "video/mp4" {
+		t.Errorf("Expected the segment, got %d %v: %s", w.Code, w.Header(), w.Body.String())
+	}
+	if w := hlsRequest(server, "v1", "seg-00001.m4s"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a missing segment, got %d", w.Code)
+	}
+	for _, name := range []string{"../home-video.mp4", ".complete", "notes.txt"} {
+		if w := hlsRequest(server, "v1", name); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q, got %d", name, w.Code)
+		}
+	}
+
+	if w := hlsRequest(server, "missing", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an uncached item, got %d", w.Code)
+	}
+}
@@ -0,0 +1,250 @@
+package server
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// hubFlushInterval is how long the hub collects download progress before
+// sending it, so a client gets at most one progress update per media item
+// in that time however often downloads report.
+const hubFlushInterval = 250 * time.Millisecond
+
+// clientBufferSize is how many updates may wait for a client.
+const clientBufferSize = 256
+
+// maxDroppedUpdates is how many updates in a row a client may miss because
+// its buffer is full before the hub disconnects it. A client that far
+// behind is better off reconnecting and starting from a fresh status.
+const maxDroppedUpdates = 32
+
+// progressClient is a connection that broadcast progress updates go to,
+// over WebSocket or Server-Sent Events.
+type progressClient interface {
+	wants(update ProgressUpdate) bool
+	// queue hands an update to the client without blocking and reports
+	// whether there was room for it.
+	queue(update ProgressUpdate) bool
+	// close tells the client no more updates will come.
+	close()
+}
+
+// clientQueue is the buffered channel of updates a client sends on. It is
+// closed once, by whoever disconnects the client first, and queueing to a
+// closed client is a no-op.
+type clientQueue struct {
+	send chan ProgressUpdate
+
+	closeMu sync.Mutex
+	closed  bool
+}
+
+func newClientQueue(size int) clientQueue {
+	return clientQueue{send: make(chan ProgressUpdate, size)}
+}
+
+// queue implements progressClient.
+func (q *clientQueue) queue(update ProgressUpdate) bool {
+	q.closeMu.Lock()
+	defer q.closeMu.Unlock()
+
+	if q.closed {
+		return false
+	}
+	select {
+	case q.send <- update:
+		return true
+	default:
+		return false
+	}
+}
+
+// close implements progressClient.
+func (q *clientQueue) close() {
+	q.closeMu.Lock()
+	defer q.closeMu.Unlock()
+
+	if !q.closed {
+		q.closed = true
+		close(q.send)
+	}
+}
+
+// progressHub fans broadcast updates out to the connected clients from a
+// goroutine of its own, so the downloads reporting them never wait on a
+// client. Progress of a download is coalesced per media item, anything else
+// flushes what is pending and goes out at once, in order. Clients that stop
+// keeping up are disconnected.
+type progressHub struct {
+	logger   *slog.Logger
+	interval time.Duration
+
+	broadcast chan ProgressUpdate
+	done      chan struct{}
+	stopped   chan struct{}
+	stopOnce  sync.Once
+
+	mu      sync.RWMutex
+	clients map[progressClient]int // Updates dropped in a row
+
+	dropped      atomic.Int64
+	disconnected atomic.Int64
+}
+
+// newProgressHub creates a hub and starts its goroutine. Stop it with stop.
+func newProgressHub(logger *slog.Logger) *progressHub {
+	h := &progressHub{
+		logger:    logger,
+		interval:  hubFlushInterval,
+		broadcast: make(chan ProgressUpdate, clientBufferSize),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+		clients:   make(map[progressClient]int),
+	}
+	go h.run()
+	return h
+}
+
+// register starts sending broadcast updates to client.
+func (h *progressHub) register(client progressClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	select {
+	case <-h.done:
+		client.close()
+		return
+	default:
+	}
+	h.clients[client] = 0
+	h.logger.Debug("Progress client registered", "client_count", len(h.clients))
+}
+
+// unregister stops sending updates to client and closes it.
+func (h *progressHub) unregister(client progressClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[client]; ok {
+		delete(h.clients, client)
+		h.logger.Debug("Progress client unregistered", "client_count", len(h.clients))
+	}
+	client.close()
+}
+
+// clientCount returns how many clients are connected.
+func (h *progressHub) clientCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// send queues update for the connected clients. Updates sent after stop
+// are dropped.
+func (h *progressHub) send(update ProgressUpdate) {
+	select {
+	case h.broadcast <- update:
+	case <-h.done:
+	}
+}
+
+// stop disconnects every client and ends the hub's goroutine.
+func (h *progressHub) stop() {
+	h.stopOnce.Do(func() {
+		close(h.done)
+		<-h.stopped
+	})
+}
+
+func (h *progressHub) run() {
+	defer close(h.stopped)
+
+	var (
+		pending []ProgressUpdate
+		index   = make(map[string]int) // Media ID to its pending progress
+		timer   *time.Timer
+		flushC  <-chan time.Time
+	)
+	flush := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, flushC = nil, nil
+		}
+		for _, update := range pending {
+			h.deliver(update)
+		}
+		pending = pending[:0]
+		clear(index)
+	}
+
+	for {
+		select {
+		case update := <-h.broadcast:
+			if !coalescable(update) {
+				pending = append(pending, update)
+				flush()
+				continue
+			}
+			if i, ok := index[update.MediaID]; ok {
+				pending[i] = update
+				continue
+			}
+			index[update.MediaID] = len(pending)
+			pending = append(pending, update)
+			if timer == nil {
+				timer = time.NewTimer(h.interval)
+				flushC = timer.C
+			}
+
+		case <-flushC:
+			timer, flushC = nil, nil
+			flush()
+
+		case <-h.done:
+			flush()
+			h.mu.Lock()
+			for client := range h.clients {
+				client.close()
+			}
+			clear(h.clients)
+			h.mu.Unlock()
+			return
+		}
+	}
+}
+
+// deliver queues update for every client that wants it, disconnecting those
+// that have missed too many in a row.
+func (h *progressHub) deliver(update ProgressUpdate) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for client, dropped := range h.clients {
+		if !client.wants(update) {
+			continue
+		}
+		if client.queue(update) {
+			h.clients[client] = 0
+			continue
+		}
+
+		h.dropped.Add(1)
+		dropped++
+		if dropped < maxDroppedUpdates {
+			h.clients[client] = dropped
+			continue
+		}
+		h.logger.Warn("Disconnecting slow progress client", "dropped_updates", dropped)
+		delete(h.clients, client)
+		client.close()
+		h.disconnected.Add(1)
+	}
+}
+
+// coalescable reports whether update is download progress that a later
+// update for the same media item makes obsolete.
+func coalescable(update ProgressUpdate) bool {
+	return update.Type == "download" && update.Status == "downloading" && update.MediaID != ""
+}
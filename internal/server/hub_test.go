@@ -0,0 +1,101 @@
+package server
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestProgressHubCoalescesProgress(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	hub := newProgressHub(logger)
+	defer hub.stop()
+
+	client := &sseClient{clientQueue: newClientQueue(clientBufferSize)}
+	hub.register(client)
+
+	for progress := 10.0; progress <= 50; progress += 10 {
+		hub.send(ProgressUpdate{Type: "download", MediaID: "m1", Status: "downloading", Progress: progress})
+		hub.send(ProgressUpdate{Type: "download", MediaID: "m2", Status: "downloading", Progress: progress / 2})
+	}
+	// Anything other than progress goes out at once, after what is pending
+	hub.send(ProgressUpdate{Type: "download", MediaID: "m1", Status: "completed", Progress: 100})
+
+	want := []ProgressUpdate{
+		{Type: "download", MediaID: "m1", Status: "downloading", Progress: 50},
+		{Type: "download", MediaID: "m2", Status: "downloading", Progress: 25},
+		{Type: "download", MediaID: "m1", Status: "completed", Progress: 100},
+	}
+	for i, expected := range want {
+		select {
+		case update := <-client.send:
+			if update.MediaID != expected.MediaID || update.Status != expected.Status || update.Progress != expected.Progress {
+				t.Errorf("Update %d: expected %+v, got %+v", i, expected, update)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for update %d", i)
+		}
+	}
+
+	// Progress on its own waits for the flush interval
+	hub.send(ProgressUpdate{Type: "download", MediaID: "m3", Status: "downloading", Progress: 5})
+	select {
+	case update := <-client.send:
+		if update.MediaID != "m3" {
+			t.Errorf("Expected m3 progress, got %+v", update)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected pending progress to be flushed")
+	}
+}
+
+func TestProgressHubDisconnectsSlowClients(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	hub := newProgressHub(logger)
+
+	slow := &sseClient{clientQueue: newClientQueue(1)}
+	fast := &sseClient{clientQueue: newClientQueue(clientBufferSize)}
+	hub.register(slow)
+	hub.register(fast)
+
+	// The slow client never reads: one update fills it, the rest are dropped
+	for i := 0; i <= maxDroppedUpdates; i++ {
+		hub.send(ProgressUpdate{Type: "status", Status: "tick"})
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for hub.clientCount() > 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the slow client to be disconnected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := hub.disconnected.Load(); got != 1 {
+		t.Errorf("Expected 1 slow disconnect, got %d", got)
+	}
+	if got := hub.dropped.Load(); got != maxDroppedUpdates {
+		t.Errorf("Expected %d dropped updates, got %d", maxDroppedUpdates, got)
+	}
+
+	<-slow.send
+	if _, ok := <-slow.send; ok {
+		t.Error("Expected the slow client to be closed")
+	}
+	if slow.queue(ProgressUpdate{Type: "status"}) {
+		t.Error("Expected queueing to a closed client to fail")
+	}
+	if len(fast.send) != maxDroppedUpdates+1 {
+		t.Errorf("Expected the fast client to get every update, got %d", len(fast.send))
+	}
+
+	// Stopping closes the remaining clients and drops later updates
+	hub.stop()
+	hub.send(ProgressUpdate{Type: "status"})
+	for range fast.send {
+	}
+	hub.register(slow)
+	if hub.clientCount() != 0 {
+		t.Error("Expected no clients to be registered after stop")
+	}
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+)
+
+// SetIntegrityScanner sets the scanner behind /api/integrity.
+func (s *Server) SetIntegrityScanner(scanner *downloader.IntegrityScanner) {
+	s.integrity = scanner
+}
+
+// handleIntegrityReport returns the report of the last cache integrity
+// scan. Data is null until a scan has run.
+func (s *Server) handleIntegrityReport(w http.ResponseWriter, r *http.Request) {
+	if s.integrity == nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "Integrity scanner is not enabled", nil)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    s.integrity.LastReport(),
+	})
+}
+
+// handleIntegrityScan runs a cache integrity scan and returns its report.
+func (s *Server) handleIntegrityScan(w http.ResponseWriter, r *http.Request) {
+	if s.integrity == nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "Integrity scanner is not enabled", nil)
+		return
+	}
+
+	report, err := s.integrity.Scan(r.Context())
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Integrity scan failed", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    report,
+	})
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestHandleIntegrity(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	dir := t.TempDir()
+	sm := newTestStorage(t, dir)
+
+	// A record whose file is gone
+	if err := sm.AddDownloadRecord(&storage.DownloadRecord{
+		ID: "m1", JellyfinID: "m1", MediaType: "movie", Title: "Heat",
+		LocalPath: filepath.Join(dir, "movies", "m1", "video.mp4"),
+		Size:      100, Status: "completed", DownloadedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to add download record: %v", err)
+	}
+
+	dm := downloader.New(&config.DownloadConfig{Workers: 1, RateLimitMbps: 10}, sm, logger)
+
+	server := newTestServer(t, &Server{logger: logger, storage: sm, downloadManager: dm})
+
+	w := httptest.NewRecorder()
+	server.handleIntegrityReport(w, httptest.NewRequest(http.MethodGet, "/api/integrity", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without a scanner, got %d", w.Code)
+	}
+
+	server.SetIntegrityScanner(downloader.NewIntegrityScanner(sm, dm, 0, logger))
+
+	w = httptest.NewRecorder()
+	server.handleIntegrityScan(w, httptest.NewRequest(http.MethodPost, "/api/integrity/scan", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	server.handleIntegrityReport(w, httptest.NewRequest(http.MethodGet, "/api/integrity", nil))
+	var resp struct {
+		Data downloader.IntegrityReport `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Data.Checked != 1 || resp.Data.Missing != 1 {
+		t.Errorf("Expected the missing file to be reported, got %+v", resp.Data)
+	}
+	// The download manager is not running, so the re-queue fails and is reported
+	if resp.Data.Requeued != 0 || len(resp.Data.Errors) != 1 {
+		t.Errorf("Expected the failed re-queue to be reported, got %+v", resp.Data)
+	}
+}
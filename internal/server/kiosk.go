@@ -0,0 +1,298 @@
+package server
+
+import (
+	"crypto/hmac"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// kioskCookie holds proof that the kiosk PIN was entered in this browser.
+const kioskCookie = "jfw_kiosk"
+
+// kioskListLimit caps how many cached items the kiosk page lists.
+const kioskListLimit = 500
+
+// Wrong PINs or passwords allowed in a row before unlocking or signing in
+// is refused for a while.
+const (
+	loginMaxAttempts = 5
+	loginLockout     = time.Minute
+)
+
+// loginGuard holds the secret a cookie is signed with and counts failed
+// attempts at getting one, for the kiosk PIN and for signing in.
+type loginGuard struct {
+	once sync.Once
+	key  []byte
+
+	mu           sync.Mutex
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+// kioskPage is the locked-down player: a list of cached items and, once one
+// is picked, a video element streaming it.
+var kioskPage = template.Must(template.New("kiosk").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>{{if .Playing}}{{.Playing.Title}}{{else}}Watch{{end}}</title></head>
+<body style="font-family: sans-serif; max-width: 960px; margin: 24px auto; padding: 0 16px;">
+{{if .Playing}}
+<p><a href="/kiosk">&larr; Back</a></p>
+<h2>{{.Playing.Title}}</h2>
+<video src="/stream/{{.Playing.ID}}" controls autoplay style="width: 100%; background: #000;"></video>
+{{else}}
+<h2>Watch</h2>
+{{if .Items}}<ul style="list-style: none; padding: 0; font-size: 1.2em; line-height: 2;">
+{{range .Items}}<li><a href="/kiosk?play={{.ID}}">{{.Title}}</a></li>
+{{end}}</ul>{{else}}<p>Nothing has been downloaded yet.</p>{{end}}
+{{end}}
+<hr style="margin-top: 48px;">
+{{if .Unlocked}}<form method="post" action="/kiosk/lock"><button type="submit">Lock this browser</button> <a href="/">Full interface</a></form>
+{{else}}<details{{if .Message}} open{{end}}><summary>Exit kiosk</summary>
+{{if .Message}}<p style="color: #b00;">{{.Message}}</p>{{end}}
+<form method="post" action="/kiosk/unlock">
+<input type="password" name="pin" inputmode="numeric" autocomplete="off" placeholder="PIN" required>
+<button type="submit">Unlock</button>
+</form>
+</details>{{end}}
+</body>
+</html>
+`))
+
+// kioskItem is a cached item as listed on the kiosk page.
+type kioskItem struct {
+	ID    string
+	Title string
+}
+
+// kioskMiddleware confines browsers that have not entered the kiosk PIN to
+// the kiosk page and streams of cached items. Everything else, including
+// the API and the full UI, is refused.
+func (s *Server) kioskMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.kioskUnlocked(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		path := r.URL.Path
+		switch {
+		case path == "/":
+			s.handleKiosk(w, r)
+		case path == "/kiosk", path == "/kiosk/unlock", path == "/kiosk/lock", path == "/health", path == "/metrics",
+			strings.HasPrefix(path, "/share/"):
+			next.ServeHTTP(w, r)
+		case strings.HasPrefix(path, "/stream/"):
+			// Only what is already cached; a stream would otherwise fetch
+			// from Jellyfin and queue downloads
+			id, _, _ := strings.Cut(strings.TrimPrefix(path, "/stream/"), "/")
+			if cached, err := s.library.IsMediaCached(id); err != nil || !cached {
+				http.Error(w, "Not available offline", http.StatusNotFound)
+				return
+			}
+			next.ServeHTTP(w, r)
+		case path == "/api/webhooks/jellyfin" && s.config.WebhookToken != "":
+			// The Jellyfin server cannot enter a PIN; the token guards it
+			next.ServeHTTP(w, r)
+		case strings.HasPrefix(path, "/api/") && tokenAuthenticated(r):
+			// Scripts cannot enter a PIN either; their API token got them in
+			next.ServeHTTP(w, r)
+		case strings.HasPrefix(path, "/api/"):
+			s.writeErrorResponse(w, http.StatusForbidden, "Locked by kiosk mode", nil)
+		default:
+			http.Error(w, "Locked by kiosk mode", http.StatusForbidden)
+		}
+	})
+}
+
+// registerKioskRoutes adds the kiosk page and its unlock and lock actions.
+func (s *Server) registerKioskRoutes() {
+	s.router.Get("/kiosk", s.handleKiosk)
+	s.router.Post("/kiosk/unlock", s.handleKioskUnlock)
+	s.router.Post("/kiosk/lock", s.handleKioskLock)
+}
+
+// handleKiosk renders the kiosk page, playing the item named by ?play.
+func (s *Server) handleKiosk(w http.ResponseWriter, r *http.Request) {
+	s.renderKiosk(w, r, http.StatusOK, "")
+}
+
+func (s *Server) renderKiosk(w http.ResponseWriter, r *http.Request, status int, message string) {
+	data := map[string]interface{}{
+		"Unlocked": s.kioskUnlocked(r),
+		"Message":  message,
+	}
+
+	items, err := s.library.GetCachedItems("", 1, kioskListLimit)
+	if err != nil {
+		s.logger.Error("Failed to list cached items for kiosk", "error", err)
+		http.Error(w, "Failed to list downloads", http.StatusInternalServerError)
+		return
+	}
+
+	listed := make([]kioskItem, 0, len(items))
+	for _, item := range items {
+		entry := kioskItem{ID: item.ID, Title: kioskTitle(item)}
+		if entry.ID == r.URL.Query().Get("play") {
+			data["Playing"] = entry
+		}
+		listed = append(listed, entry)
+	}
+	data["Items"] = listed
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	kioskPage.Execute(w, data)
+}
+
+// handleKioskUnlock checks the submitted PIN and, if it matches, marks this
+// browser as unlocked until it is closed.
+func (s *Server) handleKioskUnlock(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	if wait := s.kiosk.blocked(now); wait > 0 {
+		s.renderKiosk(w, r, http.StatusTooManyRequests,
+			fmt.Sprintf("Too many wrong PINs. Try again in %d seconds.", int(wait.Seconds())+1))
+		return
+	}
+
+	pin := r.PostFormValue("pin")
+	if !hmac.Equal([]byte(pin), []byte(s.config.Kiosk.PIN)) {
+		s.kiosk.failed(now)
+		s.logger.Warn("Incorrect kiosk PIN", "remote_addr", r.RemoteAddr)
+		s.renderKiosk(w, r, http.StatusUnauthorized, "Incorrect PIN.")
+		return
+	}
+	s.kiosk.succeeded()
+
+	token, ok := s.kioskToken()
+	if !ok {
+		http.Error(w, "Kiosk cannot be unlocked", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Info("Kiosk unlocked", "remote_addr", r.RemoteAddr)
+	http.SetCookie(w, &http.Cookie{
+		Name:     kioskCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// handleKioskLock returns this browser to the kiosk page.
+func (s *Server) handleKioskLock(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     kioskCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// kioskUnlocked reports whether the request comes from a browser that
+// entered the PIN.
+func (s *Server) kioskUnlocked(r *http.Request) bool {
+	cookie, err := r.Cookie(kioskCookie)
+	if err != nil {
+		return false
+	}
+	token, ok := s.kioskToken()
+	return ok && hmac.Equal([]byte(cookie.Value), []byte(token))
+}
+
+// kioskToken returns the unlock cookie value. It is keyed by a secret made
+// at startup and by the PIN, so a restart or a PIN change locks every
+// browser again. It reports false if no secret could be made, in which
+// case nothing can be unlocked.
+func (s *Server) kioskToken() (string, bool) {
+	s.kiosk.once.Do(func() {
+		key, err := randomHex(32)
+		if err != nil {
+			s.logger.Error("Failed to create kiosk secret", "error", err)
+			return
+		}
+		s.kiosk.key = []byte(key)
+	})
+	if s.kiosk.key == nil {
+		return "", false
+	}
+	return signShare(s.kiosk.key, "kiosk", s.config.Kiosk.PIN), true
+}
+
+// blocked returns how long unlocking is still refused after too many wrong
+// PINs or passwords.
+func (g *loginGuard) blocked(now time.Time) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.blockedUntil.Sub(now)
+}
+
+// failed counts a wrong PIN or password, refusing further attempts for a while once
+// there have been too many.
+func (g *loginGuard) failed(now time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.failures++
+	g.lastFailure = now
+	if g.failures >= loginMaxAttempts {
+		g.failures = 0
+		g.blockedUntil = now.Add(loginLockout)
+	}
+}
+
+func (g *loginGuard) succeeded() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.failures = 0
+}
+
+// idle reports whether g has nothing left to remember: it is not locked out
+// and has seen no failure for a lockout period.
+func (g *loginGuard) idle(now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return !now.Before(g.blockedUntil) && now.Sub(g.lastFailure) >= loginLockout
+}
+
+// guardFor returns the guard stored under key in guards, adding one if
+// there is none. Adding one first drops the idle guards, so the map only
+// holds keys that failed recently.
+func guardFor(guards *sync.Map, key string, now time.Time) *loginGuard {
+	if guard, ok := guards.Load(key); ok {
+		return guard.(*loginGuard)
+	}
+	guards.Range(func(key, guard interface{}) bool {
+		if guard.(*loginGuard).idle(now) {
+			guards.Delete(key)
+		}
+		return true
+	})
+	guard, _ := guards.LoadOrStore(key, &loginGuard{})
+	return guard.(*loginGuard)
+}
+
+// kioskTitle names a cached item for the kiosk list.
+func kioskTitle(item *storage.CachedItem) string {
+	if item.SeriesName != "" && item.EpisodeNumber > 0 {
+		return fmt.Sprintf("%s S%02dE%02d - %s", item.SeriesName, item.SeasonNumber, item.EpisodeNumber, item.Name)
+	}
+	if item.Name == "" {
+		return item.ID
+	}
+	return item.Name
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func newKioskTestRouter(t *testing.T) (*Server, http.Handler) {
+	server := newShareTestServer(t)
+	server.config = &config.ServerConfig{Kiosk: config.KioskConfig{Enabled: true, PIN: "2468"}}
+
+	ok := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(body)) }
+	}
+	server.router = chi.NewRouter()
+	server.router.Use(server.kioskMiddleware)
+	server.registerKioskRoutes()
+	server.router.Get("/", ok("full interface"))
+	server.router.Get("/api/status", ok("status"))
+	server.router.Get("/stream/{id}", ok("video"))
+	return server, server.router
+}
+
+func kioskRequest(handler http.Handler, method, path string, form url.Values, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	var req *http.Request
+	if form != nil {
+		req = httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req = httptest.NewRequest(method, path, nil)
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestKioskLocked(t *testing.T) {
+	_, handler := newKioskTestRouter(t)
+
+	w := kioskRequest(handler, http.MethodGet, "/", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `href="/kiosk?play=v1"`) {
+		t.Fatalf("Expected the kiosk page listing the cached item, got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "full interface") {
+		t.Error("Expected the full interface to be hidden")
+	}
+
+	w = kioskRequest(handler, http.MethodGet, "/kiosk?play=v1", nil)
+	if !strings.Contains(w.Body.String(), `<video src="/stream/v1"`) {
+		t.Errorf("Expected a player for the picked item, got %s", w.Body.String())
+	}
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/api/status", http.StatusForbidden},
+		{"/stream/v1", http.StatusOK},
+		{"/stream/not-cached", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := kioskRequest(handler, http.MethodGet, tt.path, nil); w.Code != tt.status {
+			t.Errorf("GET %s: expected status %d, got %d", tt.path, tt.status, w.Code)
+		}
+	}
+}
+
+func TestKioskUnlock(t *testing.T) {
+	_, handler := newKioskTestRouter(t)
+
+	w := kioskRequest(handler, http.MethodPost, "/kiosk/unlock", url.Values{"pin": {"1111"}})
+	if w.Code != http.StatusUnauthorized || len(w.Result().Cookies()) != 0 {
+		t.Fatalf("Expected a wrong PIN to be refused, got %d", w.Code)
+	}
+
+	w = kioskRequest(handler, http.MethodPost, "/kiosk/unlock", url.Values{"pin": {"2468"}})
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("Expected a redirect after unlocking, got %d: %s", w.Code, w.Body.String())
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != kioskCookie {
+		t.Fatalf("Expected the unlock cookie, got %v", cookies)
+	}
+	unlock := cookies[0]
+
+	if w := kioskRequest(handler, http.MethodGet, "/", nil, unlock); w.Body.String() != "full interface" {
+		t.Errorf("Expected the full interface once unlocked, got %s", w.Body.String())
+	}
+	if w := kioskRequest(handler, http.MethodGet, "/api/status", nil, unlock); w.Code != http.StatusOK {
+		t.Errorf("Expected the API once unlocked, got %d", w.Code)
+	}
+
+	forged := &http.Cookie{Name: kioskCookie, Value: "0123456789abcdef"}
+	if w := kioskRequest(handler, http.MethodGet, "/api/status", nil, forged); w.Code != http.StatusForbidden {
+		t.Errorf("Expected a forged cookie to be refused, got %d", w.Code)
+	}
+
+	w = kioskRequest(handler, http.MethodPost, "/kiosk/lock", nil, unlock)
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("Expected locking to clear the cookie, got %v", cookies)
+	}
+}
+
+func TestKioskUnlockLockout(t *testing.T) {
+	_, handler := newKioskTestRouter(t)
+
+	for i := 0; i < loginMaxAttempts; i++ {
+		kioskRequest(handler, http.MethodPost, "/kiosk/unlock", url.Values{"pin": {"0000"}})
+	}
+
+	w := kioskRequest(handler, http.MethodPost, "/kiosk/unlock", url.Values{"pin": {"2468"}})
+	if w.Code != http.StatusTooManyRequests || len(w.Result().Cookies()) != 0 {
+		t.Errorf("Expected unlocking to be refused after repeated wrong PINs, got %d", w.Code)
+	}
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/opd-ai/go-jf-watch/internal/maintenance"
+)
+
+// SetMaintenance sets the scheduler reported by /api/maintenance.
+func (s *Server) SetMaintenance(scheduler *maintenance.Scheduler) {
+	s.maintenance = scheduler
+}
+
+// handleMaintenanceStatus returns the maintenance window and the last run
+// of each maintenance task.
+func (s *Server) handleMaintenanceStatus(w http.ResponseWriter, r *http.Request) {
+	if s.maintenance == nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "Maintenance scheduler is not enabled", nil)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    s.maintenance.Status(),
+	})
+}
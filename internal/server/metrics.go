@@ -0,0 +1,168 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// metricsContentType is the Prometheus text exposition format, version 0.0.4.
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// maxQueuePriority is the least urgent download priority. Queue depth is
+// reported for every priority up to it, so idle priorities show as zero
+// rather than disappearing from dashboards.
+const maxQueuePriority = 4
+
+// metricsWriter writes metrics in the Prometheus text format. The first
+// write error is kept and later writes are skipped.
+type metricsWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// header writes the HELP and TYPE lines of a metric family.
+func (m *metricsWriter) header(name, kind, help string) {
+	if m.err != nil {
+		return
+	}
+	_, m.err = fmt.Fprintf(m.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// sample writes one sample. labels alternate between names and values.
+func (m *metricsWriter) sample(name string, value float64, labels ...string) {
+	if m.err != nil {
+		return
+	}
+	line := name
+	if len(labels) > 0 {
+		line += "{"
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				line += ","
+			}
+			line += labels[i] + "=" + strconv.Quote(labels[i+1])
+		}
+		line += "}"
+	}
+	_, m.err = fmt.Fprintf(m.w, "%s %s\n", line, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// single writes a metric family with one unlabelled sample.
+func (m *metricsWriter) single(name, kind, help string, value float64) {
+	m.header(name, kind, help)
+	m.sample(name, value)
+}
+
+// handleMetrics serves queue, download, cache and client metrics for
+// Prometheus. Counters that survive restarts (evictions, stream requests)
+// come from the daily stats in storage; bytes downloaded counts from
+// startup, which Prometheus treats as a counter reset.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	queued, err := s.queue.GetQueueItems("queued")
+	if err != nil {
+		http.Error(w, "Failed to get queue status", http.StatusInternalServerError)
+		return
+	}
+
+	cacheStats, err := s.storage.GetCacheStats()
+	if err != nil {
+		http.Error(w, "Failed to get cache stats", http.StatusInternalServerError)
+		return
+	}
+
+	storageStats, err := s.storage.GetStorageStats()
+	if err != nil {
+		http.Error(w, "Failed to get storage stats", http.StatusInternalServerError)
+		return
+	}
+
+	days, err := s.storage.GetDailyStats(time.Time{}, time.Now())
+	if err != nil {
+		http.Error(w, "Failed to get daily stats", http.StatusInternalServerError)
+		return
+	}
+
+	depth := make(map[int]int)
+	for _, item := range queued {
+		depth[item.Priority]++
+	}
+
+	var itemsEvicted, hits, misses int
+	var bytesEvicted int64
+	for _, day := range days {
+		itemsEvicted += day.ItemsEvicted
+		bytesEvicted += day.BytesEvicted
+		hits += day.CacheHits
+		misses += day.CacheMisses
+	}
+
+	var utilization float64
+	if storageStats.MaxSize > 0 {
+		utilization = float64(cacheStats.TotalSizeBytes) / float64(storageStats.MaxSize)
+	}
+
+	var hitRatio float64
+	if hits+misses > 0 {
+		hitRatio = float64(hits) / float64(hits+misses)
+	}
+
+	wsClients := s.hub.clientCount()
+
+	w.Header().Set("Content-Type", metricsContentType)
+	w.Header().Set("Cache-Control", "no-store")
+
+	m := &metricsWriter{w: bufio.NewWriter(w)}
+
+	m.header("jfwatch_queue_depth", "gauge", "Downloads waiting in the queue, by priority (0 is most urgent).")
+	for priority := 0; priority <= maxQueuePriority; priority++ {
+		m.sample("jfwatch_queue_depth", float64(depth[priority]), "priority", strconv.Itoa(priority))
+	}
+
+	m.single("jfwatch_active_workers", "gauge",
+		"Download workers currently transferring a file.",
+		float64(len(s.downloadManager.ActiveDownloads())))
+	m.single("jfwatch_downloaded_bytes_total", "counter",
+		"Bytes downloaded since startup, including failed and resumed attempts.",
+		float64(s.downloadManager.DownloadedBytes()))
+
+	m.single("jfwatch_cache_items", "gauge", "Items in the cache.", float64(cacheStats.TotalItems))
+	m.single("jfwatch_cache_used_bytes", "gauge", "Bytes used by cached media.", float64(cacheStats.TotalSizeBytes))
+	m.single("jfwatch_cache_max_bytes", "gauge", "Configured cache size limit in bytes.", float64(storageStats.MaxSize))
+	m.single("jfwatch_cache_utilization_ratio", "gauge", "Fraction of the cache size limit in use.", utilization)
+
+	m.single("jfwatch_evictions_total", "counter", "Items evicted from the cache.", float64(itemsEvicted))
+	m.single("jfwatch_evicted_bytes_total", "counter", "Bytes freed by cache eviction.", float64(bytesEvicted))
+
+	m.header("jfwatch_stream_requests_total", "counter", "Playback requests, by whether they were served from the cache.")
+	m.sample("jfwatch_stream_requests_total", float64(hits), "result", "hit")
+	m.sample("jfwatch_stream_requests_total", float64(misses), "result", "miss")
+	m.single("jfwatch_prediction_hit_ratio", "gauge",
+		"Fraction of playback requests served from content cached ahead of time.", hitRatio)
+
+	if s.predictor != nil {
+		m.header("jfwatch_prediction_downloads", "gauge",
+			"Predicted downloads finished within the history window, by priority and outcome.")
+		for _, acc := range s.predictor.Accuracy() {
+			priority := strconv.Itoa(acc.Priority)
+			m.sample("jfwatch_prediction_downloads", float64(acc.Hits), "priority", priority, "outcome", "hit")
+			m.sample("jfwatch_prediction_downloads", float64(acc.Wasted), "priority", priority, "outcome", "wasted")
+			m.sample("jfwatch_prediction_downloads", float64(acc.Pending), "priority", priority, "outcome", "pending")
+		}
+	}
+
+	m.single("jfwatch_websocket_clients", "gauge", "Connected WebSocket and event stream clients.", float64(wsClients))
+	m.single("jfwatch_websocket_dropped_updates_total", "counter",
+		"Updates not sent to a client because it was not keeping up.", float64(s.hub.dropped.Load()))
+	m.single("jfwatch_websocket_slow_disconnects_total", "counter",
+		"Clients disconnected for falling too far behind.", float64(s.hub.disconnected.Load()))
+
+	if m.err == nil {
+		m.err = m.w.Flush()
+	}
+	if m.err != nil {
+		s.logger.Debug("Failed to write metrics", "error", m.err)
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestHandleMetrics(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sm := newTestStorage(t, t.TempDir())
+
+	if err := sm.AddDownloadRecord(&storage.DownloadRecord{
+		ID: "m1", JellyfinID: "m1", MediaType: "movie", Title: "Heat",
+		Size: 256 * 1024 * 1024, Status: "completed", DownloadedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to add download record: %v", err)
+	}
+	for _, item := range []*storage.QueueItem{
+		{ID: "q1", MediaID: "e1", Priority: 1, Status: "queued", CreatedAt: time.Now()},
+		{ID: "q2", MediaID: "e2", Priority: 3, Status: "queued", CreatedAt: time.Now()},
+		{ID: "q3", MediaID: "e3", Priority: 3, Status: "queued", CreatedAt: time.Now()},
+		{ID: "q4", MediaID: "e4", Priority: 3, Status: "failed", CreatedAt: time.Now()},
+	} {
+		if err := sm.AddQueueItem(item); err != nil {
+			t.Fatalf("Failed to add queue item: %v", err)
+		}
+	}
+	for _, hit := range []bool{true, true, true, false} {
+		if err := sm.RecordStreamRequest(hit); err != nil {
+			t.Fatalf("Failed to record stream request: %v", err)
+		}
+	}
+	if err := sm.RecordEviction(1024); err != nil {
+		t.Fatalf("Failed to record eviction: %v", err)
+	}
+
+	dm := downloader.New(&config.DownloadConfig{Workers: 1, RateLimitMbps: 10}, sm, logger)
+
+	server := newTestServer(t, &Server{
+		logger: logger, storage: sm, downloadManager: dm,
+	})
+	for i := 0; i < 2; i++ {
+		server.hub.register(&sseClient{clientQueue: newClientQueue(clientBufferSize)})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	server.handleMetrics(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Expected Prometheus text format, got %q", ct)
+	}
+
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE jfwatch_queue_depth gauge",
+		`jfwatch_queue_depth{priority="0"} 0`,
+		`jfwatch_queue_depth{priority="1"} 1`,
+		`jfwatch_queue_depth{priority="3"} 2`,
+		"jfwatch_active_workers 0",
+		"# TYPE jfwatch_downloaded_bytes_total counter",
+		"jfwatch_cache_items 1",
+		"jfwatch_cache_utilization_ratio 0.25",
+		"jfwatch_evictions_total 1",
+		"jfwatch_evicted_bytes_total 1024",
+		`jfwatch_stream_requests_total{result="hit"} 3`,
+		`jfwatch_stream_requests_total{result="miss"} 1`,
+		"jfwatch_prediction_hit_ratio 0.75",
+		"jfwatch_websocket_clients 2",
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected line %q in metrics:\n%s", line, body)
+		}
+	}
+}
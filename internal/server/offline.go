@@ -0,0 +1,20 @@
+package server
+
+import (
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+)
+
+// SetConnectivity sets the tracker of whether the Jellyfin server is
+// reachable. While it is not, /api/status reports the system offline,
+// library listings say they come from metadata cached before the outage,
+// and streams of uncached items fail at once instead of waiting on the
+// proxy.
+func (s *Server) SetConnectivity(connectivity *jellyfin.Connectivity) {
+	s.connectivity = connectivity
+}
+
+// jellyfinOffline reports whether the Jellyfin server was last found
+// unreachable.
+func (s *Server) jellyfinOffline() bool {
+	return s.connectivity != nil && !s.connectivity.Online()
+}
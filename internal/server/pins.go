@@ -0,0 +1,57 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// PinResponse reports the pinned state of a library item.
+type PinResponse struct {
+	MediaID string `json:"media_id"`
+	Pinned  bool   `json:"pinned"`
+}
+
+// handlePinItem protects a movie, episode, series or album from cache
+// eviction. Pinning a series or album covers every episode or track of it.
+func (s *Server) handlePinItem(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	metadata, err := s.storage.FindMediaMetadata(id)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to look up item", err)
+		return
+	}
+	if metadata == nil {
+		if cached, err := s.library.IsMediaCached(id); err != nil || !cached {
+			s.writeErrorResponse(w, http.StatusNotFound, "Item not found", nil)
+			return
+		}
+	}
+
+	if err := s.storage.PinMedia(id); err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to pin item", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "Item pinned",
+		Data:    PinResponse{MediaID: id, Pinned: true},
+	})
+}
+
+// handleUnpinItem lets a pinned item be evicted again.
+func (s *Server) handleUnpinItem(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := s.storage.UnpinMedia(id); err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to unpin item", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "Item unpinned",
+		Data:    PinResponse{MediaID: id},
+	})
+}
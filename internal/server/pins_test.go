@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func pinRequest(t *testing.T, server *Server, method, id string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/library/"+id+"/pin", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+
+	if method == http.MethodDelete {
+		server.handleUnpinItem(w, req)
+	} else {
+		server.handlePinItem(w, req)
+	}
+	return w
+}
+
+func TestHandlePinItem(t *testing.T) {
+	server := newShareTestServer(t)
+
+	if w := pinRequest(t, server, http.MethodPost, "v1"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if pinned, err := server.storage.IsPinned("v1"); err != nil || !pinned {
+		t.Fatalf("Expected v1 to be pinned, got %v (%v)", pinned, err)
+	}
+
+	if w := pinRequest(t, server, http.MethodPost, "missing"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown item, got %d", w.Code)
+	}
+
+	if w := pinRequest(t, server, http.MethodDelete, "v1"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if pinned, err := server.storage.IsPinned("v1"); err != nil || pinned {
+		t.Errorf("Expected v1 to be unpinned, got %v (%v)", pinned, err)
+	}
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// PlaybackProgressRequest reports how far the local player got through an
+// item for everyone watching together.
+type PlaybackProgressRequest struct {
+	MediaID   string    `json:"media_id"`
+	Users     []string  `json:"users"`
+	StartedAt time.Time `json:"started_at"`
+	Position  float64   `json:"position_seconds"`
+	Duration  float64   `json:"duration_seconds"`
+}
+
+// handlePlaybackProgress records local playback progress for each user
+// watching, so every viewer's predictions advance together.
+func (s *Server) handlePlaybackProgress(w http.ResponseWriter, r *http.Request) {
+	var req PlaybackProgressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if req.MediaID == "" || len(req.Users) == 0 {
+		s.writeErrorResponse(w, http.StatusBadRequest, "media_id and users are required", nil)
+		return
+	}
+	if req.Position < 0 || req.Duration < 0 {
+		s.writeErrorResponse(w, http.StatusBadRequest, "position and duration cannot be negative", nil)
+		return
+	}
+	if req.StartedAt.IsZero() {
+		req.StartedAt = time.Now()
+	}
+
+	if s.predictor == nil {
+		s.writeErrorResponse(w, http.StatusServiceUnavailable, "Prediction is not enabled", nil)
+		return
+	}
+
+	position := time.Duration(req.Position * float64(time.Second))
+	duration := time.Duration(req.Duration * float64(time.Second))
+	if err := s.predictor.RecordWatchTogether(r.Context(), req.MediaID, req.Users, req.StartedAt, position, duration); err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to record playback progress", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "Playback progress recorded",
+	})
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestHandlePlaybackProgress(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sm := newTestStorage(t, t.TempDir())
+
+	predictor := downloader.NewPredictor(sm, &config.PredictionConfig{HouseholdUsers: []string{"alice", "bob"}}, logger)
+
+	server := newTestServer(t, &Server{logger: logger, storage: sm, predictor: predictor})
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"watched together", `{"media_id":"ep1","users":["alice","bob"],"started_at":"2024-03-01T20:00:00Z","position_seconds":2500,"duration_seconds":2700}`, http.StatusOK},
+		{"no users", `{"media_id":"ep1","users":[],"position_seconds":10,"duration_seconds":2700}`, http.StatusBadRequest},
+		{"negative position", `{"media_id":"ep1","users":["alice"],"position_seconds":-1}`, http.StatusBadRequest},
+		{"invalid json", `{`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/playback/progress", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			server.handlePlaybackProgress(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	for _, user := range []string{"alice", "bob"} {
+		history, err := sm.GetViewingHistory(user, 365*10)
+		if err != nil {
+			t.Fatalf("Failed to get history: %v", err)
+		}
+		if len(history) != 1 || !history[0].Completed {
+			t.Errorf("Expected one completed session for %s, got %+v", user, history)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestHandleQueuePriority(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sm := newTestStorage(t, t.TempDir())
+
+	manager := downloader.New(&config.DownloadConfig{Workers: 1, RateLimitMbps: 10}, sm, logger)
+	if err := manager.AddJob(&downloader.DownloadJob{ID: "job1", MediaID: "m1", Priority: 3, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to add job: %v", err)
+	}
+
+	server := newTestServer(t, &Server{logger: logger, storage: sm, downloadManager: manager})
+
+	tests := []struct {
+		name       string
+		id         string
+		body       string
+		wantStatus int
+	}{
+		{"bump to playing", "job1", `{"priority":0}`, http.StatusOK},
+		{"out of range", "job1", `{"priority":5}`, http.StatusBadRequest},
+		{"missing priority", "job1", `{}`, http.StatusBadRequest},
+		{"invalid json", "job1", `{`, http.StatusBadRequest},
+		{"unknown item", "missing", `{"priority":1}`, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/api/queue/"+tt.id+"/priority", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	item, err := sm.FindActiveQueueItem("m1")
+	if err != nil {
+		t.Fatalf("Failed to find queue item: %v", err)
+	}
+	if item.Priority != 0 {
+		t.Errorf("Expected priority 0, got %d", item.Priority)
+	}
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// deviceProfileHeader names the device profile of a player that cannot add
+// the device query parameter to its stream URLs.
+const deviceProfileHeader = "X-Device-Profile"
+
+// qualityRanks orders the quality levels from lowest to highest.
+var qualityRanks = map[string]int{
+	"480p":     1,
+	"720p":     2,
+	"1080p":    3,
+	"original": 4,
+}
+
+// SetDeviceProfiles sets the quality caps streams are served under, keyed
+// by device profile name. Without profiles every device gets the cached
+// file, or the original from Jellyfin.
+func (s *Server) SetDeviceProfiles(profiles map[string]config.DeviceProfileConfig) {
+	s.deviceProfiles = profiles
+}
+
+// deviceProfile returns the profile a stream request is served under: the
+// one named by the device query parameter or the X-Device-Profile header,
+// else the one for the device class of its User-Agent.
+func (s *Server) deviceProfile(r *http.Request) (config.DeviceProfileConfig, bool) {
+	name := r.URL.Query().Get("device")
+	if name == "" {
+		name = r.Header.Get(deviceProfileHeader)
+	}
+	if name == "" {
+		name = downloader.ClassifyUserAgent(r.UserAgent())
+	}
+	if name == "" {
+		return config.DeviceProfileConfig{}, false
+	}
+
+	if profile, ok := s.deviceProfiles[name]; ok {
+		return profile, true
+	}
+	for profileName, profile := range s.deviceProfiles {
+		if strings.EqualFold(profileName, name) {
+			return profile, true
+		}
+	}
+	return config.DeviceProfileConfig{}, false
+}
+
+// exceedsProfile reports whether a cached variant is above what a device
+// profile allows. A variant of unknown bitrate is only judged by quality.
+func exceedsProfile(record *storage.DownloadRecord, profile config.DeviceProfileConfig) bool {
+	if qualityRank(record.Quality) > qualityRank(profile.MaxQuality) {
+		return true
+	}
+	return profile.MaxBitrateMbps > 0 && record.Bitrate > 0 &&
+		float64(record.Bitrate) > profile.MaxBitrateMbps*1_000_000
+}
+
+// qualityRank returns the rank of a quality level; an empty or unknown
+// quality is the original.
+func qualityRank(quality string) int {
+	if rank, ok := qualityRanks[quality]; ok {
+		return rank
+	}
+	return qualityRanks["original"]
+}
+
+// streamURL returns the Jellyfin URL a stream request is proxied to: a
+// transcode within the request's device profile, or the original stream.
+func (s *Server) streamURL(r *http.Request, mediaID string) (string, error) {
+	profile, ok := s.deviceProfile(r)
+	if !ok {
+		return s.jellyfinClient.GetStreamURL(mediaID)
+	}
+	return s.jellyfinClient.GetTranscodeURL(mediaID, profile.MaxQuality, int(profile.MaxBitrateMbps*1_000_000))
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+const tabletUserAgent = "Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X)"
+
+func TestDeviceProfile(t *testing.T) {
+	server := newTestServer(t, &Server{})
+	server.SetDeviceProfiles(map[string]config.DeviceProfileConfig{
+		"tablet":      {MaxQuality: "720p", MaxBitrateMbps: 2},
+		"tv":          {MaxQuality: "original"},
+		"Living-Room": {MaxQuality: "1080p"},
+	})
+
+	tests := []struct {
+		name      string
+		url       string
+		header    string
+		userAgent string
+		want      string
+		found     bool
+	}{
+		{"query parameter", "/stream/m1?device=tv", "", tabletUserAgent, "original", true},
+		{"header", "/stream/m1", "living-room", "", "1080p", true},
+		{"user agent", "/stream/m1", "", tabletUserAgent, "720p", true},
+		{"unknown profile", "/stream/m1?device=watch", "", "", "", false},
+		{"unidentified", "/stream/m1", "", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.header != "" {
+				req.Header.Set(deviceProfileHeader, tt.header)
+			}
+			req.Header.Set("User-Agent", tt.userAgent)
+
+			profile, ok := server.deviceProfile(req)
+			if ok != tt.found || profile.MaxQuality != tt.want {
+				t.Errorf("Expected %q (%v), got %q (%v)", tt.want, tt.found, profile.MaxQuality, ok)
+			}
+		})
+	}
+}
+
+func TestExceedsProfile(t *testing.T) {
+	tablet := config.DeviceProfileConfig{MaxQuality: "720p", MaxBitrateMbps: 2}
+	tests := []struct {
+		name   string
+		record storage.DownloadRecord
+		want   bool
+	}{
+		{"original", storage.DownloadRecord{}, true},
+		{"higher quality", storage.DownloadRecord{Quality: "1080p"}, true},
+		{"within", storage.DownloadRecord{Quality: "480p", Bitrate: 1_500_000}, false},
+		{"unknown bitrate", storage.DownloadRecord{Quality: "720p"}, false},
+		{"over bitrate", storage.DownloadRecord{Quality: "720p", Bitrate: 4_000_000}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exceedsProfile(&tt.record, tablet); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if exceedsProfile(&storage.DownloadRecord{}, config.DeviceProfileConfig{MaxQuality: "original"}) {
+		t.Error("Expected the original to be within an uncapped profile")
+	}
+}
+
+func TestHandleFallbackStreamDeviceProfile(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	var requested string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.RequestURI()
+	}))
+	defer upstream.Close()
+
+	server := newTestServer(t, &Server{logger: logger, jellyfinClient: jellyfintest.New(upstream.URL)})
+	server.SetDeviceProfiles(map[string]config.DeviceProfileConfig{
+		"tablet": {MaxQuality: "720p", MaxBitrateMbps: 2},
+		"tv":     {MaxQuality: "original"},
+	})
+
+	for _, tt := range []struct {
+		url  string
+		want string
+	}{
+		{"/stream/m1?device=tablet", "/Videos/m1/stream.mp4?Quality=720p&MaxBitrate=2000000"},
+		{"/stream/m1?device=tv", "/Videos/m1/stream?Static=true"},
+		{"/stream/m1", "/Videos/m1/stream?Static=true"},
+	} {
+		w := httptest.NewRecorder()
+		server.handleFallbackStream(w, httptest.NewRequest(http.MethodGet, tt.url, nil), "m1")
+		if requested != tt.want {
+			t.Errorf("%s: expected Jellyfin request %s, got %s", tt.url, tt.want, requested)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestHandleQueuePauseResume(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sm := newTestStorage(t, t.TempDir())
+
+	manager := downloader.New(&config.DownloadConfig{Workers: 1, RateLimitMbps: 10}, sm, logger)
+	if err := manager.AddJob(&downloader.DownloadJob{ID: "job1", MediaID: "m1", Priority: 3, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to add job: %v", err)
+	}
+	if err := sm.AddQueueItem(&storage.QueueItem{ID: "job2", MediaID: "m2", Status: "failed", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to add queue item: %v", err)
+	}
+
+	server := newTestServer(t, &Server{logger: logger, storage: sm, downloadManager: manager})
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantState  string
+	}{
+		{"pause", "/api/queue/job1/pause", http.StatusOK, "paused"},
+		{"pause again", "/api/queue/job1/pause", http.StatusOK, "paused"},
+		{"resume", "/api/queue/job1/resume", http.StatusOK, "queued"},
+		{"pause failed item", "/api/queue/job2/pause", http.StatusConflict, "queued"},
+		{"unknown item", "/api/queue/missing/resume", http.StatusNotFound, "queued"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+
+			item, err := sm.FindActiveQueueItem("m1")
+			if err != nil || item == nil {
+				t.Fatalf("Failed to find queue item: %v", err)
+			}
+			if item.Status != tt.wantState {
+				t.Errorf("Expected job1 to be %s, got %s", tt.wantState, item.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// SetMetadataRefresher sets the refresher that flags stale library items
+// and re-fetches their metadata when they are listed.
+func (s *Server) SetMetadataRefresher(refresher *downloader.MetadataRefresher) {
+	s.refresher = refresher
+}
+
+// markStale flags listed items whose metadata is older than
+// cache.metadata_max_age_days and asks for them to be refreshed in the
+// background. The listing itself is served from what is stored.
+func (s *Server) markStale(items []*storage.CachedItem, libraryItems []jellyfin.LibraryItem) {
+	if s.refresher == nil {
+		return
+	}
+
+	var stale []string
+	for i, item := range items {
+		// Items without metadata have nothing to refresh
+		if item.LastSynced.IsZero() || !s.refresher.Stale(item.LastSynced) {
+			continue
+		}
+		libraryItems[i].Stale = true
+		stale = append(stale, item.ID)
+	}
+	s.refresher.RequestRefresh(stale)
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestLibraryFlagsStaleMetadata(t *testing.T) {
+	server := newShareTestServer(t)
+	if err := server.storage.AddMediaMetadata(&storage.MediaMetadata{
+		ID: "v1", JellyfinID: "v1", Name: "Birthday", Type: "movie", LastSynced: time.Now().AddDate(0, 0, -60),
+	}); err != nil {
+		t.Fatalf("Failed to add metadata: %v", err)
+	}
+
+	library := func() bool {
+		w := httptest.NewRecorder()
+		server.handleLibrary(w, httptest.NewRequest(http.MethodGet, "/api/library", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		var resp struct {
+			Data struct {
+				Items []struct {
+					ID    string `json:"id"`
+					Stale bool   `json:"stale"`
+				} `json:"items"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(resp.Data.Items) != 1 {
+			t.Fatalf("Expected one item, got %+v", resp.Data.Items)
+		}
+		return resp.Data.Items[0].Stale
+	}
+
+	// Without a refresher nothing is ever stale
+	if library() {
+		t.Error("Expected no stale flag without a metadata refresher")
+	}
+
+	cfg := &config.CacheConfig{MetadataMaxAgeDays: 30}
+	server.SetMetadataRefresher(downloader.NewMetadataRefresher(jellyfintest.New("http://jellyfin.local"), server.storage, cfg, server.logger))
+	if !library() {
+		t.Error("Expected metadata synced 60 days ago to be flagged stale")
+	}
+
+	cfg.MetadataMaxAgeDays = 90
+	if library() {
+		t.Error("Expected metadata within the maximum age not to be stale")
+	}
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/opd-ai/go-jf-watch/internal/reports"
+)
+
+// SetReports sets the report service backing the /api/reports endpoints.
+func (s *Server) SetReports(svc *reports.Service) {
+	s.reports = svc
+}
+
+// handleListReports returns recent activity reports as JSON, newest first.
+func (s *Server) handleListReports(w http.ResponseWriter, r *http.Request) {
+	if s.reports == nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "Reports are not enabled", nil)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 26 {
+		limit = 10
+	}
+
+	recent, err := s.reports.Recent(limit)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to list reports", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    recent,
+	})
+}
+
+// handleGenerateReport generates a report for the past week immediately.
+// Pass deliver=true to also send it through the notification sinks.
+func (s *Server) handleGenerateReport(w http.ResponseWriter, r *http.Request) {
+	if s.reports == nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "Reports are not enabled", nil)
+		return
+	}
+
+	report, err := s.reports.Generate(r.Context(), time.Now())
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to generate report", err)
+		return
+	}
+
+	if r.URL.Query().Get("deliver") == "true" {
+		if err := s.reports.Deliver(r.Context(), report); err != nil {
+			s.writeErrorResponse(w, http.StatusBadGateway, "Report generated but delivery failed", err)
+			return
+		}
+	}
+
+	s.writeJSONResponse(w, http.StatusCreated, APIResponse{
+		Success: true,
+		Data:    report,
+	})
+}
+
+// handleGetReport returns a single report as JSON, or as an HTML page
+// when requested with format=html.
+func (s *Server) handleGetReport(w http.ResponseWriter, r *http.Request) {
+	if s.reports == nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "Reports are not enabled", nil)
+		return
+	}
+
+	report, err := s.reports.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to load report", err)
+		return
+	}
+	if report == nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "Report not found", nil)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "html" {
+		html, err := reports.RenderHTML(report)
+		if err != nil {
+			s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to render report", err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(html)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    report,
+	})
+}
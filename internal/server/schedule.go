@@ -0,0 +1,18 @@
+package server
+
+import "net/http"
+
+// handleSchedule returns the download schedule: the peak hours, the
+// configured download windows and, for every priority, whether its
+// downloads may start now and when they next may if not.
+func (s *Server) handleSchedule(w http.ResponseWriter, r *http.Request) {
+	if s.downloadManager == nil {
+		s.writeErrorResponse(w, http.StatusServiceUnavailable, "Download manager not available", nil)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    s.downloadManager.Schedule(),
+	})
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestHandleSchedule(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	dm := downloader.New(&config.DownloadConfig{
+		Workers:       1,
+		RateLimitMbps: 10,
+		RateLimitSchedule: config.RateLimitScheduleConfig{
+			PeakHours:        "06:00-23:00",
+			PeakLimitPercent: 25,
+			Windows: []config.DownloadWindowConfig{
+				{Priorities: []int{0, 1}, Hours: "always"},
+				{Priorities: []int{3, 4}, Hours: "01:00-06:00"},
+			},
+		},
+	}, storagetest.New(), logger)
+
+	server := newTestServer(t, &Server{logger: logger, downloadManager: dm})
+
+	w := httptest.NewRecorder()
+	server.handleSchedule(w, httptest.NewRequest(http.MethodGet, "/api/schedule", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Data downloader.ScheduleStatus `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if resp.Data.PeakHours != "06:00-23:00" || len(resp.Data.Windows) != 2 {
+		t.Errorf("Expected peak hours and both windows, got %+v", resp.Data)
+	}
+	if len(resp.Data.Priorities) != 5 {
+		t.Fatalf("Expected all five priorities, got %d", len(resp.Data.Priorities))
+	}
+	if p := resp.Data.Priorities[1]; !p.Allowed || !p.Windowed {
+		t.Errorf("Expected Priority 1 always allowed, got %+v", p)
+	}
+	if p := resp.Data.Priorities[4]; !p.Windowed || p.Allowed == (p.NextStart != nil) {
+		t.Errorf("Expected Priority 4 to report its next window only while outside it, got %+v", p)
+	}
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+)
+
+// SeedRequest asks for the cache to be filled up to a size budget.
+type SeedRequest struct {
+	MaxSizeGB float64 `json:"max_size_gb"`
+	DryRun    bool    `json:"dry_run"`
+}
+
+// SetSeeder sets the seeder behind /api/seed.
+func (s *Server) SetSeeder(seeder *downloader.Seeder) {
+	s.seeder = seeder
+}
+
+// handleSeed fills the cache with what is in progress and the best
+// unwatched picks up to a size budget, or reports what it would queue on a
+// dry run.
+func (s *Server) handleSeed(w http.ResponseWriter, r *http.Request) {
+	if s.seeder == nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "Seeding the cache is not enabled", nil)
+		return
+	}
+
+	var req SeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if req.MaxSizeGB <= 0 {
+		s.writeErrorResponse(w, http.StatusBadRequest, "max_size_gb must be positive", nil)
+		return
+	}
+
+	report, err := s.seeder.Seed(r.Context(), downloader.SeedOptions{
+		BudgetBytes: int64(req.MaxSizeGB * 1024 * 1024 * 1024),
+		DryRun:      req.DryRun,
+	})
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusBadGateway, "Failed to seed the cache", err)
+		return
+	}
+
+	message := "Cache seeding queued"
+	if req.DryRun {
+		message = "Dry run; nothing was queued"
+	}
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: message,
+		Data:    report,
+	})
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestHandleSeed(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	server := newTestServer(t, &Server{logger: logger})
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/seed", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		server.handleSeed(w, req)
+		return w
+	}
+
+	if w := post(`{"max_size_gb": 10}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without a seeder, got %d", w.Code)
+	}
+
+	store := storagetest.New()
+	source := jellyfintest.New("http://jellyfin.test")
+	source.NextUp = []jellyfin.MediaItem{{ID: "e1", Name: "Pilot", Type: "Episode", Size: 1 << 30}}
+	source.Popular = []jellyfin.MediaItem{{ID: "m1", Name: "Heat", Type: "Movie", Size: 4 << 30}}
+	queue := downloader.New(&config.DownloadConfig{Workers: 1}, store, logger)
+	server.SetSeeder(downloader.NewSeeder(source, store, queue, "user", logger))
+
+	for _, body := range []string{`{}`, `{"max_size_gb": -1}`, `not json`} {
+		if w := post(body); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+
+	w := post(`{"max_size_gb": 2, "dry_run": true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data downloader.SeedReport `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Data.Queued) != 1 || resp.Data.Queued[0].MediaID != "e1" || resp.Data.TotalBytes != 1<<30 {
+		t.Errorf("Expected only the next episode to fit 2GB, got %+v", resp.Data)
+	}
+	if len(resp.Data.Skipped) != 1 || resp.Data.Skipped[0].Reason != "over budget" {
+		t.Errorf("Expected the movie to be over budget, got %+v", resp.Data.Skipped)
+	}
+}
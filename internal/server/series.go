@@ -0,0 +1,346 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// seriesHistoryDays bounds the viewing history series statistics read.
+const seriesHistoryDays = 365
+
+// seriesCachePriority is the priority "cache remaining episodes" queues at,
+// the documented default for manual requests.
+const seriesCachePriority = 3
+
+// SeriesStatsResponse describes how much of a series is cached, how far
+// the household is through it and what is about to be downloaded.
+type SeriesStatsResponse struct {
+	SeriesID          string            `json:"series_id"`
+	SeriesName        string            `json:"series_name,omitempty"`
+	TotalEpisodes     int               `json:"total_episodes"`
+	CachedEpisodes    int               `json:"cached_episodes"`
+	CachedBytes       int64             `json:"cached_bytes"`
+	RemainingEpisodes int               `json:"remaining_episodes"` // Neither cached nor queued
+	RemainingBytes    int64             `json:"remaining_bytes"`    // Size reported by Jellyfin; 0 when unknown
+	Seasons           []SeasonStats     `json:"seasons"`
+	Upcoming          []SeriesQueueItem `json:"upcoming_downloads"`
+	Pace              WatchPace         `json:"pace"`
+}
+
+// SeasonStats summarizes one season of a series.
+type SeasonStats struct {
+	Season   int     `json:"season"`
+	Episodes int     `json:"episodes"`
+	Cached   int     `json:"cached"`
+	Watched  int     `json:"watched"`
+	Progress float64 `json:"progress"` // Share of episodes watched, 0.0 to 1.0
+}
+
+// SeriesQueueItem is a queued or in-flight download of an episode.
+type SeriesQueueItem struct {
+	JobID    string `json:"job_id"`
+	MediaID  string `json:"media_id"`
+	Name     string `json:"name,omitempty"`
+	Season   int    `json:"season"`
+	Episode  int    `json:"episode"`
+	Priority int    `json:"priority"`
+	Status   string `json:"status"`
+	Source   string `json:"source,omitempty"`
+}
+
+// WatchPace describes how quickly a series has been watched.
+type WatchPace struct {
+	Watched         int        `json:"episodes_watched"`
+	FirstWatched    *time.Time `json:"first_watched,omitempty"`
+	LastWatched     *time.Time `json:"last_watched,omitempty"`
+	EpisodesPerWeek float64    `json:"episodes_per_week"`
+	EstimatedFinish *time.Time `json:"estimated_finish,omitempty"` // At the current pace
+}
+
+// handleSeriesStats returns statistics for one series. Watch progress is
+// taken from the users named by repeated ?user= parameters, or from every
+// household user when none are given.
+func (s *Server) handleSeriesStats(w http.ResponseWriter, r *http.Request) {
+	seriesID := chi.URLParam(r, "id")
+
+	episodes, err := s.library.GetSeriesMetadata(seriesID)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to load series", err)
+		return
+	}
+	if len(episodes) == 0 {
+		s.writeErrorResponse(w, http.StatusNotFound, "Series not found", nil)
+		return
+	}
+
+	queued, err := s.seriesQueue(episodes)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to load download queue", err)
+		return
+	}
+
+	watched, err := s.seriesWatched(episodes, s.seriesUsers(r))
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to load viewing history", err)
+		return
+	}
+
+	resp := SeriesStatsResponse{
+		SeriesID:      seriesID,
+		TotalEpisodes: len(episodes),
+		Upcoming:      []SeriesQueueItem{},
+	}
+
+	if series, err := s.library.GetMediaMetadata(seriesID); err == nil {
+		resp.SeriesName = series.Name
+	}
+
+	seasons := make(map[int]*SeasonStats)
+	var order []int
+	for _, episode := range episodes {
+		season, ok := seasons[episode.SeasonNumber]
+		if !ok {
+			season = &SeasonStats{Season: episode.SeasonNumber}
+			seasons[episode.SeasonNumber] = season
+			order = append(order, episode.SeasonNumber)
+		}
+		season.Episodes++
+
+		if _, ok := watched[episode.ID]; ok {
+			season.Watched++
+		}
+
+		if record, err := s.library.GetDownload(episode.ID); err == nil {
+			season.Cached++
+			resp.CachedEpisodes++
+			resp.CachedBytes += record.Size
+			continue
+		}
+
+		if item, ok := queued[episode.ID]; ok {
+			resp.Upcoming = append(resp.Upcoming, SeriesQueueItem{
+				JobID:    item.ID,
+				MediaID:  episode.ID,
+				Name:     episode.Name,
+				Season:   episode.SeasonNumber,
+				Episode:  episode.EpisodeNumber,
+				Priority: item.Priority,
+				Status:   item.Status,
+				Source:   item.Source,
+			})
+			continue
+		}
+
+		resp.RemainingEpisodes++
+		resp.RemainingBytes += episode.Size
+	}
+
+	for _, number := range order {
+		season := seasons[number]
+		season.Progress = float64(season.Watched) / float64(season.Episodes)
+		resp.Seasons = append(resp.Seasons, *season)
+	}
+
+	// Most urgent downloads first, then in viewing order
+	sort.SliceStable(resp.Upcoming, func(i, j int) bool {
+		return resp.Upcoming[i].Priority < resp.Upcoming[j].Priority
+	})
+
+	resp.Pace = watchPace(watched, len(episodes), time.Now())
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    resp,
+	})
+}
+
+// handleSeriesCache queues every episode of a series that is neither cached
+// nor already queued, backing the "cache remaining episodes" button. When
+// the queue limit is reached part way, the episodes queued so far are kept
+// and queue_full is set; when nothing fits it responds 429.
+func (s *Server) handleSeriesCache(w http.ResponseWriter, r *http.Request) {
+	seriesID := chi.URLParam(r, "id")
+
+	if s.downloadManager == nil {
+		s.writeErrorResponse(w, http.StatusServiceUnavailable, "Download manager not available", nil)
+		return
+	}
+
+	episodes, err := s.library.GetSeriesMetadata(seriesID)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to load series", err)
+		return
+	}
+	if len(episodes) == 0 {
+		s.writeErrorResponse(w, http.StatusNotFound, "Series not found", nil)
+		return
+	}
+
+	queued, err := s.seriesQueue(episodes)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to load download queue", err)
+		return
+	}
+
+	var added, excluded int
+	var bytes int64
+	var trimmed, full bool
+	for _, episode := range episodes {
+		if _, ok := queued[episode.ID]; ok {
+			continue
+		}
+		if cached, err := s.library.IsMediaCached(episode.ID); err == nil && cached {
+			continue
+		}
+
+		_, err := s.downloadManager.QueueDownload(r.Context(), episode.ID, seriesCachePriority)
+		if errors.Is(err, downloader.ErrLibraryExcluded) {
+			excluded++
+			continue
+		}
+		if errors.Is(err, downloader.ErrQueueFull) {
+			if added == 0 {
+				s.writeErrorResponse(w, http.StatusTooManyRequests, "Download queue is full for this priority", err)
+				return
+			}
+			// Queue what fits; the rest can be requested again later
+			trimmed = true
+			break
+		}
+		if errors.Is(err, downloader.ErrInsufficientSpace) {
+			if added == 0 {
+				s.writeErrorResponse(w, http.StatusInsufficientStorage, "Not enough cache space for this series", err)
+				return
+			}
+			// Later episodes are as large, so stop at the first that does not fit
+			full = true
+			break
+		}
+		if err != nil {
+			s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to queue episode", err)
+			return
+		}
+		added++
+		bytes += episode.Size
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"queued":         added,
+			"queued_bytes":   bytes,
+			"excluded":       excluded,
+			"queue_full":     trimmed,
+			"cache_full":     full,
+			"series_id":      seriesID,
+			"priority":       seriesCachePriority,
+			"total_episodes": len(episodes),
+		},
+	})
+}
+
+// seriesQueue returns the active queue items for the episodes, keyed by
+// media ID.
+func (s *Server) seriesQueue(episodes []*storage.MediaMetadata) (map[string]*storage.QueueItem, error) {
+	inSeries := make(map[string]bool, len(episodes))
+	for _, episode := range episodes {
+		inSeries[episode.ID] = true
+	}
+
+	items, err := s.queue.GetQueueItems("")
+	if err != nil {
+		return nil, err
+	}
+
+	queued := make(map[string]*storage.QueueItem)
+	for _, item := range items {
+		if inSeries[item.MediaID] && (item.Status == "queued" || item.Status == "downloading") {
+			queued[item.MediaID] = item
+		}
+	}
+	return queued, nil
+}
+
+// seriesUsers returns the users whose history series statistics use.
+func (s *Server) seriesUsers(r *http.Request) []string {
+	if users := r.URL.Query()["user"]; len(users) > 0 {
+		return users
+	}
+	if s.predictor != nil {
+		return s.predictor.HouseholdUsers()
+	}
+	return nil
+}
+
+// seriesWatched returns when each episode of the series was first watched
+// to completion by any of the users.
+func (s *Server) seriesWatched(episodes []*storage.MediaMetadata, users []string) (map[string]time.Time, error) {
+	inSeries := make(map[string]bool, len(episodes))
+	for _, episode := range episodes {
+		inSeries[episode.ID] = true
+	}
+
+	watched := make(map[string]time.Time)
+	for _, user := range users {
+		sessions, err := s.history.GetViewingHistory(user, seriesHistoryDays)
+		if err != nil {
+			return nil, err
+		}
+		for _, session := range sessions {
+			if !session.Completed || !inSeries[session.MediaID] {
+				continue
+			}
+			at := session.EndTime
+			if at.IsZero() {
+				at = session.StartTime
+			}
+			if first, ok := watched[session.MediaID]; !ok || at.Before(first) {
+				watched[session.MediaID] = at
+			}
+		}
+	}
+	return watched, nil
+}
+
+// watchPace computes the watch rate from when episodes were finished and
+// projects when the rest of the series would be finished at that rate.
+// Spans shorter than a week count as a week, so a single evening's binge
+// does not project an absurd pace.
+func watchPace(watched map[string]time.Time, total int, now time.Time) WatchPace {
+	pace := WatchPace{Watched: len(watched)}
+	if len(watched) == 0 {
+		return pace
+	}
+
+	var first, last time.Time
+	for _, at := range watched {
+		if first.IsZero() || at.Before(first) {
+			first = at
+		}
+		if at.After(last) {
+			last = at
+		}
+	}
+	pace.FirstWatched = &first
+	pace.LastWatched = &last
+
+	week := 7 * 24 * time.Hour
+	weeks := float64(last.Sub(first)) / float64(week)
+	if weeks < 1 {
+		weeks = 1
+	}
+	pace.EpisodesPerWeek = float64(len(watched)) / weeks
+
+	if remaining := total - len(watched); remaining > 0 {
+		finish := now.Add(time.Duration(float64(remaining) / pace.EpisodesPerWeek * float64(week)))
+		pace.EstimatedFinish = &finish
+	}
+	return pace
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+)
+
+func TestSeriesStats(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	store := storagetest.New()
+
+	store.AddMediaMetadata(&storage.MediaMetadata{ID: "s1", JellyfinID: "s1", Name: "The Show", Type: "series"})
+	for _, ep := range []struct {
+		id              string
+		season, episode int
+	}{{"e1", 1, 1}, {"e2", 1, 2}, {"e3", 2, 1}, {"e4", 2, 2}} {
+		store.AddMediaMetadata(&storage.MediaMetadata{
+			ID: ep.id, JellyfinID: ep.id, Name: ep.id, Type: "episode", SeriesID: "s1",
+			SeasonNumber: ep.season, EpisodeNumber: ep.episode, Size: 1000,
+		})
+	}
+	store.AddMediaMetadata(&storage.MediaMetadata{ID: "other", JellyfinID: "other", Type: "episode", SeriesID: "s2", SeasonNumber: 1, EpisodeNumber: 1})
+
+	store.AddDownloadRecord(&storage.DownloadRecord{ID: "e1", JellyfinID: "e1", Status: "completed", Size: 900})
+	store.AddDownloadRecord(&storage.DownloadRecord{ID: "e2", JellyfinID: "e2", Status: "completed", Size: 800})
+	store.AddQueueItem(&storage.QueueItem{ID: "q1", MediaID: "e3", Priority: 1, Status: "queued", Source: "prediction"})
+
+	start := time.Now().Add(-14 * 24 * time.Hour)
+	for i, session := range []storage.ViewingSession{
+		{MediaID: "e1", Completed: true},
+		{MediaID: "e2", Completed: true},
+		{MediaID: "e3", Completed: false},
+	} {
+		session.StartTime = start.Add(time.Duration(i) * 7 * 24 * time.Hour)
+		session.EndTime = session.StartTime.Add(time.Hour)
+		store.StoreViewingSession("alice", session)
+	}
+
+	server := newTestServer(t, &Server{logger: logger, queue: store, library: store, history: store})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/series/s1/stats?user=alice", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "s1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+	server.handleSeriesStats(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Data SeriesStatsResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	stats := resp.Data
+
+	if stats.SeriesName != "The Show" || stats.TotalEpisodes != 4 {
+		t.Errorf("Expected The Show with 4 episodes, got %q with %d", stats.SeriesName, stats.TotalEpisodes)
+	}
+	if stats.CachedEpisodes != 2 || stats.CachedBytes != 1700 {
+		t.Errorf("Expected 2 cached episodes totalling 1700 bytes, got %d/%d", stats.CachedEpisodes, stats.CachedBytes)
+	}
+	if stats.RemainingEpisodes != 1 || stats.RemainingBytes != 1000 {
+		t.Errorf("Expected e4 alone remaining at 1000 bytes, got %d/%d", stats.RemainingEpisodes, stats.RemainingBytes)
+	}
+	if len(stats.Upcoming) != 1 || stats.Upcoming[0].MediaID != "e3" {
+		t.Errorf("Expected e3 as the only upcoming download, got %+v", stats.Upcoming)
+	}
+
+	if len(stats.Seasons) != 2 {
+		t.Fatalf("Expected 2 seasons, got %+v", stats.Seasons)
+	}
+	if s1 := stats.Seasons[0]; s1.Season != 1 || s1.Watched != 2 || s1.Cached != 2 || s1.Progress != 1 {
+		t.Errorf("Expected season 1 fully watched and cached, got %+v", s1)
+	}
+	if s2 := stats.Seasons[1]; s2.Season != 2 || s2.Watched != 0 || s2.Progress != 0 {
+		t.Errorf("Expected season 2 unwatched (e3 not completed), got %+v", s2)
+	}
+
+	if stats.Pace.Watched != 2 || stats.Pace.EpisodesPerWeek != 2 {
+		t.Errorf("Expected 2 episodes at 2/week, got %+v", stats.Pace)
+	}
+	if stats.Pace.EstimatedFinish == nil {
+		t.Error("Expected an estimated finish with episodes left")
+	}
+}
+
+func TestSeriesStatsUnknownSeries(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	store := storagetest.New()
+	server := newTestServer(t, &Server{logger: logger, queue: store, library: store, history: store})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/series/missing/stats", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "missing")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+	server.handleSeriesStats(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestWatchPace(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	if pace := watchPace(nil, 10, now); pace.EpisodesPerWeek != 0 || pace.EstimatedFinish != nil {
+		t.Errorf("Expected no pace without history, got %+v", pace)
+	}
+
+	// Three episodes in one evening count as three a week, not hundreds
+	binge := map[string]time.Time{
+		"a": now.Add(-3 * time.Hour),
+		"b": now.Add(-2 * time.Hour),
+		"c": now.Add(-time.Hour),
+	}
+	pace := watchPace(binge, 6, now)
+	if pace.EpisodesPerWeek != 3 {
+		t.Errorf("Expected 3 episodes/week, got %v", pace.EpisodesPerWeek)
+	}
+	if pace.EstimatedFinish == nil || !pace.EstimatedFinish.Equal(now.Add(7*24*time.Hour)) {
+		t.Errorf("Expected finish a week out, got %v", pace.EstimatedFinish)
+	}
+
+	if pace := watchPace(binge, 3, now); pace.EstimatedFinish != nil {
+		t.Errorf("Expected no estimate for a finished series, got %v", pace.EstimatedFinish)
+	}
+}
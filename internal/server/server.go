@@ -16,8 +16,13 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 
+	"github.com/opd-ai/go-jf-watch/internal/dlna"
 	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/hls"
 	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/maintenance"
+	"github.com/opd-ai/go-jf-watch/internal/media"
+	"github.com/opd-ai/go-jf-watch/internal/reports"
 	"github.com/opd-ai/go-jf-watch/internal/storage"
 	"github.com/opd-ai/go-jf-watch/internal/ui"
 	"github.com/opd-ai/go-jf-watch/pkg/config"
@@ -30,21 +35,64 @@ type Server struct {
 	config          *config.ServerConfig
 	logger          *slog.Logger
 	storage         *storage.Manager
+	// Narrow views of storage, so handlers can be tested against an
+	// in-memory store
+	queue           storage.QueueStore
+	library         storage.MediaStore
+	history         storage.HistoryStore
+	events          storage.EventStore
 	downloadManager *downloader.Manager
-	jellyfinClient  *jellyfin.Client
+	jellyfinClient  JellyfinAPI
 	predictor       *downloader.Predictor
+	readAhead       *downloader.ReadAhead
+	providers       *media.Registry
+	reports         *reports.Service
+	maintenance     *maintenance.Scheduler
+	integrity       *downloader.IntegrityScanner
+	sessions        *downloader.SessionSyncer
+	playback        *downloader.PlaybackTracker
+	subscriptions   *downloader.Subscriptions
+	collections     *downloader.Collections
+	refresher       *downloader.MetadataRefresher
+	adopter         *downloader.Adopter
+	seeder          *downloader.Seeder
+	trip            *downloader.TripMode
+	deviceProfiles  map[string]config.DeviceProfileConfig
+	connectivity    *jellyfin.Connectivity
 	ui              *ui.UI
 	httpServer      *http.Server
+	webdavServer    *http.Server
+	shareServer     *http.Server
+	dlna            *dlna.Server
+	hls             *hls.Repackager
+	kiosk           loginGuard
+	auth            loginGuard
+	signIns         sync.Map // client address -> *loginGuard
+	shareUnlocks    sync.Map // share ID -> *loginGuard
+	credentials     CredentialVerifier
+	settings        *config.Config         // nil until SetSettings; guarded by settingsMu
+	settingsMu      sync.Mutex
+	cache           *storage.CacheManager
 	router          chi.Router
 	startTime       time.Time
-	version         string	// WebSocket client management
-	wsClients map[interface{}]bool
-	wsMutex   sync.RWMutex
+	version         string
+	hub             *progressHub // Fans updates out to WebSocket and event stream clients
 }
 
+// JellyfinAPI is the part of the Jellyfin client the server relies on to
+// resolve stream URLs for media that is not cached. *jellyfin.Client
+// implements it; jellyfintest.Mock stands in for it in tests.
+type JellyfinAPI interface {
+	GetStreamURL(mediaID string) (string, error)
+	GetVariantURL(mediaID, quality string) (string, error)
+	GetTranscodeURL(mediaID, quality string, maxBitrate int) (string, error)
+}
+
+var _ JellyfinAPI = (*jellyfin.Client)(nil)
+
 // New creates a new HTTP server instance with the provided configuration.
 // The server is configured with middleware for logging, CORS, and request recovery.
-func New(cfg *config.ServerConfig, storage *storage.Manager, downloadManager *downloader.Manager, jellyfinClient *jellyfin.Client, predictor *downloader.Predictor, logger *slog.Logger, version string) (*Server, error) {
+func New(cfg *config.ServerConfig, storage *storage.Manager, downloadManager *downloader.Manager, jellyfinClient JellyfinAPI, predictor *downloader.Predictor, logger *slog.Logger, version string) (*Server, error) {
 	// Initialize embedded UI
 	uiHandler, err := ui.New(version)
 	if err != nil {
@@ -55,13 +103,17 @@ func New(cfg *config.ServerConfig, storage *storage.Manager, downloadManager *do
 		config:          cfg,
 		logger:          logger,
 		storage:         storage,
+		queue:           storage,
+		library:         storage,
+		history:         storage,
+		events:          storage,
 		downloadManager: downloadManager,
 		jellyfinClient:  jellyfinClient,
 		predictor:       predictor,
 		ui:              uiHandler,
 		startTime:       time.Now(),
 		version:         version,
-		wsClients:       make(map[interface{}]bool),
+		hub:             newProgressHub(logger),
 	}
 
 	// Create router with middleware
@@ -79,6 +131,32 @@ func New(cfg *config.ServerConfig, storage *storage.Manager, downloadManager *do
 		IdleTimeout:  60 * time.Second,
 	}
 
+	if cfg.WebDAV.Enabled && cfg.WebDAV.Port != 0 {
+		s.webdavServer = &http.Server{
+			Addr:        fmt.Sprintf("%s:%d", cfg.Host, cfg.WebDAV.Port),
+			Handler:     s.newWebDAVHandler(),
+			ReadTimeout: cfg.ReadTimeout,
+			IdleTimeout: 60 * time.Second,
+		}
+	}
+
+	if cfg.Sharing.Enabled && cfg.Sharing.Port != 0 {
+		s.shareServer = &http.Server{
+			Addr:        fmt.Sprintf("%s:%d", cfg.Host, cfg.Sharing.Port),
+			Handler:     s.newShareHandler(),
+			ReadTimeout: cfg.ReadTimeout,
+			IdleTimeout: 60 * time.Second,
+		}
+	}
+
+	if cfg.DLNA.Enabled {
+		s.dlna = dlna.New(&cfg.DLNA, cfg.Host, storage, storage.Cipher(), logger)
+	}
+
+	if cfg.HLS.Enabled {
+		s.hls = hls.New(hls.NewFFmpeg(cfg.HLS.FFmpegPath, cfg.HLS.SegmentDuration), logger)
+	}
+
 	return s, nil
 }
 
@@ -87,6 +165,9 @@ func New(cfg *config.ServerConfig, storage *storage.Manager, downloadManager *do
 func (s *Server) setupMiddleware() {
 	// Basic middleware
 	s.router.Use(middleware.RequestID)
+	if s.config.Auth.Mode == "proxy" {
+		s.router.Use(rememberPeer)
+	}
 	s.router.Use(middleware.RealIP)
 	s.router.Use(s.loggingMiddleware())
 	s.router.Use(middleware.Recoverer)
@@ -106,8 +187,29 @@ func (s *Server) setupMiddleware() {
 		MaxAge:           300,
 	}))
 
-	// Set timeout for requests
-	s.router.Use(middleware.Timeout(30 * time.Second))
+	// Set timeout for requests, except the long-lived event stream and
+	// database backups
+	timeout := middleware.Timeout(30 * time.Second)
+	s.router.Use(func(next http.Handler) http.Handler {
+		limited := timeout(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == eventStreamPath || r.URL.Path == backupPath {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limited.ServeHTTP(w, r)
+		})
+	})
+
+	// Sign-in for the API, and for everything with protect_reads
+	if s.config.Auth.Mode != "" {
+		s.router.Use(s.authMiddleware)
+	}
+
+	// Browsers without the kiosk PIN only get the kiosk player
+	if s.config.Kiosk.Enabled {
+		s.router.Use(s.kioskMiddleware)
+	}
 }
 
 // setupRoutes configures all HTTP routes for the server.
@@ -116,28 +218,142 @@ func (s *Server) setupRoutes() {
 	// Health check endpoint
 	s.router.Get("/health", s.handleHealth)
 
+	// Prometheus scrape endpoint
+	if s.config.EnableMetrics {
+		s.router.Get("/metrics", s.handleMetrics)
+	}
+
 	// API routes
 	s.router.Route("/api", func(r chi.Router) {
 		r.Get("/status", s.handleAPIStatus)
+		r.Route("/auth", func(r chi.Router) {
+			r.Get("/session", s.handleAuthSession)
+			r.Post("/login", s.handleAuthLogin)
+			r.Post("/logout", s.handleAuthLogout)
+			r.Get("/tokens", s.handleListAPITokens)
+			r.Post("/tokens", s.handleCreateAPIToken)
+			r.Delete("/tokens/{id}", s.handleDeleteAPIToken)
+		})
 		r.Get("/library", s.handleLibrary)
+		r.Get("/library/search", s.handleLibrarySearch)
+		r.Post("/library/{id}/pin", s.handlePinItem)
+		r.Delete("/library/{id}/pin", s.handleUnpinItem)
+		r.Get("/library/{id}/image/{type}", s.handleLibraryImage)
 		r.Route("/queue", func(r chi.Router) {
 			r.Get("/", s.handleQueueStatus)
 			r.Post("/add", s.handleQueueAdd)
+			r.Get("/failed", s.handleQueueFailed)
+			r.Post("/failed/retry", s.handleQueueFailedRetryAll)
+			r.Post("/failed/{id}/retry", s.handleQueueFailedRetry)
 			r.Delete("/{id}", s.handleQueueRemove)
+			r.Put("/{id}/priority", s.handleQueuePriority)
+			r.Post("/{id}/pause", s.handleQueuePause)
+			r.Post("/{id}/resume", s.handleQueueResume)
 		})
 		// Settings endpoints for UI configuration
 		r.Get("/settings", s.handleGetSettings)
 		r.Post("/settings", s.handlePostSettings)
+		// Event log of notable actions
+		r.Get("/events", s.handleEvents)
+		// Progress updates as Server-Sent Events
+		r.Get("/events/stream", s.handleEventStream)
+		// Weekly activity reports
+		r.Route("/reports", func(r chi.Router) {
+			r.Get("/", s.handleListReports)
+			r.Post("/", s.handleGenerateReport)
+			r.Get("/{id}", s.handleGetReport)
+		})
+		// Duplicate content in the cache
+		r.Route("/duplicates", func(r chi.Router) {
+			r.Get("/", s.handleListDuplicates)
+			r.Post("/resolve", s.handleResolveDuplicates)
+		})
+		r.Post("/adopt", s.handleAdopt)
+		r.Post("/seed", s.handleSeed)
+		// Trip mode: extra caching until a date
+		r.Route("/trip", func(r chi.Router) {
+			r.Get("/", s.handleGetTrip)
+			r.Post("/", s.handleStartTrip)
+			r.Delete("/", s.handleEndTrip)
+		})
+		// Time-limited public share links
+		r.Route("/shares", func(r chi.Router) {
+			r.Get("/", s.handleListShares)
+			r.Post("/", s.handleCreateShare)
+			r.Delete("/{id}", s.handleDeleteShare)
+		})
+		// Series whose new episodes are always cached
+		r.Route("/subscriptions", func(r chi.Router) {
+			r.Get("/", s.handleListSubscriptions)
+			r.Post("/", s.handleCreateSubscription)
+			r.Get("/{id}", s.handleGetSubscription)
+			r.Put("/{id}", s.handleUpdateSubscription)
+			r.Delete("/{id}", s.handleDeleteSubscription)
+		})
+		// Jellyfin collections and playlists, cached in order
+		r.Route("/collections", func(r chi.Router) {
+			r.Get("/", s.handleListCollections)
+			r.Post("/sync", s.handleSyncCollections)
+			r.Get("/{id}", s.handleGetCollection)
+			r.Post("/{id}/cache", s.handleCacheCollection)
+			r.Delete("/{id}/cache", s.handleUncacheCollection)
+		})
+		// Compact summary for external dashboard widgets
+		r.Route("/series", func(r chi.Router) {
+			r.Get("/{id}/stats", s.handleSeriesStats)
+			r.Post("/{id}/cache", s.handleSeriesCache)
+		})
+
+		r.Get("/widgets/summary", s.handleWidgetSummary)
+		r.Get("/transfers", s.handleTransfers)
+		r.Get("/devices", s.handleDeviceStats)
+		r.Post("/playback/progress", s.handlePlaybackProgress)
+		r.Post("/webhooks/jellyfin", s.handleJellyfinWebhook)
+		r.Get("/maintenance", s.handleMaintenanceStatus)
+		r.Post("/maintenance/compact", s.handleCompact)
+		r.Get("/backup", s.handleBackup)
+		r.Get("/schedule", s.handleSchedule)
+		r.Get("/integrity", s.handleIntegrityReport)
+		r.Post("/integrity/scan", s.handleIntegrityScan)
 	})
 
 	// Video streaming endpoint with Range support
 	s.router.Get("/stream/{id}", s.handleVideoStream)
+	s.router.Get("/stream/{id}/trickplay", s.handleTrickplay)
+	s.router.Get("/stream/{id}/trickplay/{file}", s.handleTrickplayImage)
+	s.router.Get("/stream/{id}/master.m3u8", s.handleHLSMaster)
+	s.router.Get("/stream/{id}/hls/{file}", s.handleHLSFile)
 
 	// WebSocket endpoint for real-time updates
 	s.router.Get("/ws/progress", s.handleWebSocket)
 
-	// Register embedded UI routes (static files and main interface)
-	s.ui.RegisterRoutes(s.router)
+	// Read-only WebDAV export of the cache on the main port
+	if s.config.WebDAV.Enabled && s.config.WebDAV.Port == 0 {
+		davHandler := s.newWebDAVHandler()
+		s.router.Handle(s.config.WebDAV.Path, davHandler)
+		s.router.Handle(s.config.WebDAV.Path+"/*", davHandler)
+	}
+
+	// Public share links on the main port
+	if s.config.Sharing.Enabled && s.config.Sharing.Port == 0 {
+		s.registerShareRoutes(s.router)
+	}
+
+	// Locked-down player of cached content
+	if s.config.Kiosk.Enabled {
+		s.registerKioskRoutes()
+	}
+
+	// Sign-in form for browsers
+	if s.config.Auth.Mode != "" {
+		s.registerAuthRoutes()
+	}
+
+	// Register embedded UI routes (static files and main interface). Test
+	// servers are built without it
+	if s.ui != nil {
+		s.ui.RegisterRoutes(s.router)
+	}
 }
 
 // Start starts the HTTP server in a goroutine.
@@ -155,6 +371,35 @@ func (s *Server) Start(ctx context.Context) error {
 		}
 	}()
 
+	// Start the WebDAV export on its own port if configured
+	if s.webdavServer != nil {
+		s.logger.Info("Starting WebDAV server",
+			"address", s.webdavServer.Addr,
+			"path", s.config.WebDAV.Path)
+		go func() {
+			if err := s.webdavServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				s.logger.Error("WebDAV server error", "error", err)
+			}
+		}()
+	}
+
+	// Start the share link server on its own port if configured
+	if s.shareServer != nil {
+		s.logger.Info("Starting share link server", "address", s.shareServer.Addr)
+		go func() {
+			if err := s.shareServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				s.logger.Error("Share link server error", "error", err)
+			}
+		}()
+	}
+
+	// Start the DLNA media server if configured
+	if s.dlna != nil {
+		if err := s.dlna.Start(ctx); err != nil {
+			s.logger.Error("DLNA server error", "error", err)
+		}
+	}
+
 	// Wait for context cancellation
 	<-ctx.Done()
 	return s.Stop()
@@ -168,6 +413,30 @@ func (s *Server) Stop() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	if s.webdavServer != nil {
+		if err := s.webdavServer.Shutdown(ctx); err != nil {
+			s.logger.Error("Error shutting down WebDAV server", "error", err)
+		}
+	}
+
+	if s.shareServer != nil {
+		if err := s.shareServer.Shutdown(ctx); err != nil {
+			s.logger.Error("Error shutting down share link server", "error", err)
+		}
+	}
+
+	if s.dlna != nil {
+		if err := s.dlna.Stop(ctx); err != nil {
+			s.logger.Error("Error shutting down DLNA server", "error", err)
+		}
+	}
+
+	if s.hls != nil {
+		s.hls.Stop()
+	}
+
+	s.hub.stop()
+
 	if err := s.httpServer.Shutdown(ctx); err != nil {
 		s.logger.Error("Error shutting down HTTP server", "error", err)
 		return err
@@ -177,26 +446,16 @@ func (s *Server) Stop() error {
 	return nil
 }
 
-// WebSocket client management methods
-
-// registerWSClient adds a client to the registry
-func (s *Server) registerWSClient(client interface{}) {
-	s.wsMutex.Lock()
-	defer s.wsMutex.Unlock()
-	
-	s.wsClients[client] = true
-	s.logger.Debug("WebSocket client registered", "client_count", len(s.wsClients))
+// SetProviders sets the media provider registry used to serve items from
+// backends other than Jellyfin, such as the local folder provider.
+func (s *Server) SetProviders(providers *media.Registry) {
+	s.providers = providers
 }
 
-// unregisterWSClient removes a client from the registry
-func (s *Server) unregisterWSClient(client interface{}) {
-	s.wsMutex.Lock()
-	defer s.wsMutex.Unlock()
-	
-	if _, ok := s.wsClients[client]; ok {
-		delete(s.wsClients, client)
-		s.logger.Debug("WebSocket client unregistered", "client_count", len(s.wsClients))
-	}
+// SetReadAhead sets the coordinator that fetches the opening segment of
+// the next episode while a cached one streams.
+func (s *Server) SetReadAhead(readAhead *downloader.ReadAhead) {
+	s.readAhead = readAhead
 }
 
 // BroadcastProgress wrapper method to match ProgressReporter interface
@@ -212,6 +471,34 @@ func (s *Server) BroadcastProgress(mediaID, status, message string, progress flo
 	s.BroadcastProgressUpdate(update)
 }
 
+// BroadcastTransfer sends the speed and ETA of an in-flight download, to
+// match the ProgressReporter interface.
+func (s *Server) BroadcastTransfer(mediaID string, progress float64, speed int64, eta time.Duration) {
+	update := ProgressUpdate{
+		Type:     "download",
+		MediaID:  mediaID,
+		Progress: progress,
+		Speed:    speed,
+		Status:   "downloading",
+	}
+	if eta > 0 {
+		update.ETA = formatETA(eta)
+	}
+	s.BroadcastProgressUpdate(update)
+}
+
+// formatETA formats a time remaining briefly, e.g. "45s", "3m" or "1h05m".
+func formatETA(d time.Duration) string {
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	default:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+}
+
 // loggingMiddleware creates a structured logging middleware for HTTP requests.
 // Logs request method, path, status code, duration, and client IP.
 func (s *Server) loggingMiddleware() func(next http.Handler) http.Handler {
@@ -11,6 +11,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+
 	"github.com/opd-ai/go-jf-watch/internal/storage"
 	"github.com/opd-ai/go-jf-watch/pkg/config"
 )
@@ -152,6 +154,96 @@ func TestLibraryEndpoint(t *testing.T) {
 	}
 }
 
+func TestLibraryPagination(t *testing.T) {
+	server := newShareTestServer(t)
+	for _, record := range []*storage.DownloadRecord{
+		{ID: "small", JellyfinID: "small", MediaType: "movie", Title: "Small", Size: 1, Status: "completed"},
+		{ID: "large", JellyfinID: "large", MediaType: "movie", Title: "Large", Size: 1 << 30, Status: "completed"},
+	} {
+		if err := server.storage.AddDownloadRecord(record); err != nil {
+			t.Fatalf("Failed to add download record: %v", err)
+		}
+	}
+
+	type libraryPage struct {
+		Items []struct {
+			ID string `json:"id"`
+		} `json:"items"`
+		TotalItems int    `json:"total_items"`
+		TotalPages int    `json:"total_pages"`
+		NextCursor string `json:"next_cursor"`
+	}
+	library := func(query string) (int, libraryPage) {
+		w := httptest.NewRecorder()
+		server.handleLibrary(w, httptest.NewRequest(http.MethodGet, "/api/library?"+query, nil))
+		var resp struct {
+			Data libraryPage `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Data
+	}
+
+	code, page := library("type=movies&sort=size&limit=2")
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if page.TotalItems != 3 || page.TotalPages != 2 || len(page.Items) != 2 || page.Items[0].ID != "large" {
+		t.Fatalf("Expected the two largest of 3 movies, got %+v", page)
+	}
+
+	// v1 from newShareTestServer has no recorded size, so it sorts last
+	code, page = library("type=movies&sort=size&limit=2&cursor=" + page.NextCursor)
+	if code != http.StatusOK || len(page.Items) != 1 || page.Items[0].ID != "v1" || page.NextCursor != "" {
+		t.Errorf("Expected the last page to hold v1, got %d %+v", code, page)
+	}
+
+	if code, _ := library("sort=rating"); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown sort, got %d", code)
+	}
+}
+
+func TestLibrarySearch(t *testing.T) {
+	server := newShareTestServer(t)
+	if err := server.storage.AddMediaMetadata(&storage.MediaMetadata{
+		ID: "heat", JellyfinID: "heat", Name: "Heat", Type: "movie", Genres: []string{"Crime"},
+	}); err != nil {
+		t.Fatalf("Failed to add metadata: %v", err)
+	}
+	if err := server.storage.AddDownloadRecord(&storage.DownloadRecord{
+		ID: "heat", JellyfinID: "heat", MediaType: "movie", Title: "Heat", Status: "completed",
+	}); err != nil {
+		t.Fatalf("Failed to add download record: %v", err)
+	}
+
+	search := func(query string) (int, []string) {
+		w := httptest.NewRecorder()
+		server.handleLibrarySearch(w, httptest.NewRequest(http.MethodGet, "/api/library/search?"+query, nil))
+		var resp struct {
+			Data struct {
+				Items []struct {
+					ID string `json:"id"`
+				} `json:"items"`
+			} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		var ids []string
+		for _, item := range resp.Data.Items {
+			ids = append(ids, item.ID)
+		}
+		return w.Code, ids
+	}
+
+	if code, ids := search("q=cri"); code != http.StatusOK || len(ids) != 1 || ids[0] != "heat" {
+		t.Errorf("Expected heat for cri, got %d %v", code, ids)
+	}
+	if code, ids := search("q=drama"); code != http.StatusOK || len(ids) != 0 {
+		t.Errorf("Expected no results for drama, got %d %v", code, ids)
+	}
+	if code, _ := search("q="); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without a query, got %d", code)
+	}
+}
+
 func TestQueueEndpoints(t *testing.T) {
 	server := createTestServer(t)
 
@@ -387,3 +479,56 @@ func createTestServer(t *testing.T) *Server {
 	}
 	return server
 }
+
+// newTestServer finishes s the way New would, except for the embedded UI,
+// which is not built into test binaries: a quiet logger and an empty
+// config unless set, the narrow storage views pointing at s.storage, a
+// progress hub, and the full middleware and routes on s.router.
+func newTestServer(t *testing.T, s *Server) *Server {
+	t.Helper()
+
+	if s.logger == nil {
+		s.logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	}
+	if s.config == nil {
+		s.config = &config.ServerConfig{}
+	}
+	if s.storage != nil {
+		if s.queue == nil {
+			s.queue = s.storage
+		}
+		if s.library == nil {
+			s.library = s.storage
+		}
+		if s.history == nil {
+			s.history = s.storage
+		}
+		if s.events == nil {
+			s.events = s.storage
+		}
+	}
+	if s.hub == nil {
+		s.hub = newProgressHub(s.logger)
+		t.Cleanup(s.hub.stop)
+	}
+	s.startTime = time.Now()
+
+	s.router = chi.NewRouter()
+	s.setupMiddleware()
+	s.setupRoutes()
+	return s
+}
+
+// newTestStorage returns a bolt-backed storage manager in dir, closed when
+// the test ends.
+func newTestStorage(t *testing.T, dir string) *storage.Manager {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	sm, err := storage.NewManager(&config.CacheConfig{Directory: dir, MaxSizeGB: 1, MetadataStore: "boltdb"}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create storage manager: %v", err)
+	}
+	t.Cleanup(func() { sm.Close() })
+	return sm
+}
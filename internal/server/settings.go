@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// settingsStateKey is the state key the settings changed from the web UI
+// are saved under, as the values that were posted keyed by their path in
+// the config file.
+const settingsStateKey = "settings"
+
+// SettingsUpdate lists which changed settings took effect at once and
+// which wait for a restart.
+type SettingsUpdate struct {
+	Applied         []string `json:"applied"`
+	RestartRequired []string `json:"restart_required"`
+}
+
+// SetSettings gives the settings API the whole configuration, with any
+// saved settings already applied, and the cache manager whose limits it
+// changes while running. Without it settings can be viewed but not saved.
+func (s *Server) SetSettings(cfg *config.Config, cache *storage.CacheManager) {
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+	s.settings = cfg
+	s.cache = cache
+}
+
+// ApplySavedSettings returns cfg with the settings saved from the web UI
+// applied. Call it once the metadata database is open and before creating
+// what cfg configures. If the saved settings no longer validate against
+// the config file, cfg is returned as is along with the error.
+func ApplySavedSettings(cfg *config.Config, store storage.StateStore) (*config.Config, error) {
+	var saved map[string]interface{}
+	found, err := store.LoadState(settingsStateKey, &saved)
+	if err != nil || !found {
+		return cfg, err
+	}
+
+	updated, _, err := cfg.ApplySettings(saved)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to apply saved settings: %w", err)
+	}
+	return updated, nil
+}
+
+// currentSettings returns the configuration the settings API shows.
+func (s *Server) currentSettings() *config.Config {
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+	return s.currentSettingsLocked()
+}
+
+// currentSettingsLocked is currentSettings for callers holding settingsMu.
+func (s *Server) currentSettingsLocked() *config.Config {
+	if s.settings != nil {
+		return s.settings
+	}
+	cfg := config.Default()
+	cfg.Server = *s.config
+	return cfg
+}
+
+// saveSettings adds the changed keys of values to the saved settings.
+func (s *Server) saveSettings(values map[string]interface{}, changed []string) error {
+	saved := make(map[string]interface{})
+	if _, err := s.storage.LoadState(settingsStateKey, &saved); err != nil {
+		return err
+	}
+	for _, key := range changed {
+		saved[key] = values[key]
+	}
+	return s.storage.SaveState(settingsStateKey, saved)
+}
+
+// applyLiveSettings puts the changed settings that can take effect without
+// a restart into effect, and reports which did and which did not.
+func (s *Server) applyLiveSettings(cfg *config.Config, changed []string) SettingsUpdate {
+	update := SettingsUpdate{Applied: []string{}, RestartRequired: []string{}}
+	limitsChanged := false
+
+	for _, key := range changed {
+		applied := false
+		if config.LiveSetting(key) {
+			switch key {
+			case "download.workers":
+				applied = s.downloadManager != nil && s.downloadManager.SetWorkers(cfg.Download.Workers) == nil
+			case "download.rate_limit_mbps":
+				applied = s.downloadManager != nil && s.downloadManager.SetRateLimit(cfg.Download.RateLimitMbps) == nil
+			case "cache.max_size_gb", "cache.eviction_threshold":
+				applied = s.cache != nil
+				limitsChanged = applied
+			case "prediction.enabled":
+				applied = s.predictor != nil
+				if applied {
+					s.predictor.SetEnabled(cfg.Prediction.Enabled)
+				}
+			default:
+				applied = true // Only read from the settings themselves
+			}
+		}
+
+		if applied {
+			update.Applied = append(update.Applied, key)
+		} else {
+			update.RestartRequired = append(update.RestartRequired, key)
+		}
+	}
+
+	if limitsChanged {
+		s.cache.SetLimits(cfg.Cache.MaxSizeGB, cfg.Cache.EvictionThreshold)
+	}
+	return update
+}
+
+// WatchConfig applies the configurations a config.Watcher sends on updates
+// until it is closed or ctx is cancelled. Settings saved from the web UI
+// still win over the config file. Changed settings that can take effect
+// while running do; anything else is logged as waiting for a restart.
+func (s *Server) WatchConfig(ctx context.Context, updates <-chan config.ConfigUpdated) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			s.applyConfigUpdate(update)
+		}
+	}
+}
+
+// applyConfigUpdate puts a reloaded config file into effect.
+func (s *Server) applyConfigUpdate(update config.ConfigUpdated) {
+	updated, err := ApplySavedSettings(update.Config, s.storage)
+	if err != nil {
+		s.logger.Warn("Ignoring saved settings for the reloaded config file", "error", err)
+	}
+
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+
+	before, after := s.currentSettingsLocked().Settings(), updated.Settings()
+	var changed []string
+	for key, value := range after {
+		if !reflect.DeepEqual(before[key], value) {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+
+	result := s.applyLiveSettings(updated, changed)
+	if s.settings != nil {
+		s.settings = updated
+	}
+
+	details := make(map[string]interface{}, len(result.Applied))
+	for _, key := range result.Applied {
+		details[key] = after[key]
+	}
+	s.logger.Info("Config file changes applied", "sections", update.Changed,
+		"applied", result.Applied, "restart_required", result.RestartRequired)
+	s.recordEvent(&storage.Event{
+		Kind:    storage.EventConfigChanged,
+		Message: "Config file reloaded",
+		Details: details,
+	})
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func postSettings(server *Server, body string) (*httptest.ResponseRecorder, SettingsUpdate) {
+	req := httptest.NewRequest(http.MethodPost, "/api/settings", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	server.handlePostSettings(w, req)
+
+	var resp struct {
+		Data SettingsUpdate `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp.Data
+}
+
+func TestSettings(t *testing.T) {
+	server := newShareTestServer(t)
+	server.events = server.storage
+
+	if w, _ := postSettings(server, `{"download.workers": 4}`); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected saving to need SetSettings, got %d", w.Code)
+	}
+
+	cfg := config.Default()
+	cfg.Cache.Directory = t.TempDir()
+	cfg.Jellyfin.ServerURL = "http://localhost:8096"
+	cfg.Jellyfin.APIKey = "secret-key"
+	cfg.Jellyfin.UserID = "user"
+	server.downloadManager = downloader.New(&cfg.Download, server.storage, server.logger)
+	cache := storage.NewCacheManager(&cfg.Cache, server.storage, server.logger)
+	server.SetSettings(cfg, cache)
+
+	w := httptest.NewRecorder()
+	server.handleGetSettings(w, httptest.NewRequest(http.MethodGet, "/api/settings", nil))
+	if strings.Contains(w.Body.String(), "secret-key") || !strings.Contains(w.Body.String(), `"download.workers":3`) {
+		t.Errorf("Expected the settings without the API key, got %s", w.Body.String())
+	}
+
+	if w, _ := postSettings(server, `{"download.workers": 40}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an invalid value to be rejected, got %d", w.Code)
+	}
+
+	w, update := postSettings(server, `{"download.workers": 4, "download.rate_limit_mbps": "25", "cache.max_size_gb": 200, "server.port": 9090, "ui.theme": "auto"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Failed to save settings: %d %s", w.Code, w.Body.String())
+	}
+	if want := []string{"cache.max_size_gb", "download.rate_limit_mbps", "download.workers"}; !reflect.DeepEqual(update.Applied, want) {
+		t.Errorf("Expected %v applied, got %v", want, update.Applied)
+	}
+	if want := []string{"server.port"}; !reflect.DeepEqual(update.RestartRequired, want) {
+		t.Errorf("Expected %v to wait for a restart, got %v", want, update.RestartRequired)
+	}
+
+	// Live settings reach what they configure
+	status, _ := server.downloadManager.GetStatus()
+	if status["workers"] != 4 || status["rate_limit"] != 25 {
+		t.Errorf("Expected 4 workers at 25 Mbps, got %v", status)
+	}
+	if cache.MaxSize() != 200*1024*1024*1024 {
+		t.Errorf("Expected a 200GB cache, got %d", cache.MaxSize())
+	}
+	if cfg.Download.Workers != 3 {
+		t.Error("Expected the loaded config to be left as it was")
+	}
+
+	events, _ := server.storage.GetEvents(storage.EventFilter{Kinds: []string{storage.EventConfigChanged}})
+	if len(events) != 1 {
+		t.Fatalf("Expected the change to be logged, got %d events", len(events))
+	}
+
+	// Saved settings are applied over the config file at the next start,
+	// which a changed API key is one of
+	postSettings(server, `{"jellyfin.api_key": "new-key"}`)
+	restarted, err := ApplySavedSettings(cfg, server.storage)
+	if err != nil {
+		t.Fatalf("Failed to apply saved settings: %v", err)
+	}
+	if restarted.Download.Workers != 4 || restarted.Download.RateLimitMbps != 25 || restarted.Server.Port != 9090 ||
+		restarted.Jellyfin.APIKey != "new-key" {
+		t.Errorf("Expected the saved settings to be applied, got %+v", restarted)
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	server := newShareTestServer(t)
+	server.events = server.storage
+
+	cfg := config.Default()
+	cfg.Cache.Directory = t.TempDir()
+	cfg.Jellyfin.ServerURL = "http://localhost:8096"
+	cfg.Jellyfin.APIKey = "secret-key"
+	cfg.Jellyfin.UserID = "user"
+	cfg.Prediction.Enabled = true
+	server.downloadManager = downloader.New(&cfg.Download, server.storage, server.logger)
+	server.predictor = downloader.NewPredictor(server.storage, &cfg.Prediction, server.logger)
+	cache := storage.NewCacheManager(&cfg.Cache, server.storage, server.logger)
+	server.SetSettings(cfg, cache)
+
+	if w, _ := postSettings(server, `{"download.workers": 4}`); w.Code != http.StatusOK {
+		t.Fatalf("Failed to save settings: %d", w.Code)
+	}
+
+	// The config file changes under the running server
+	reloaded := *cfg
+	reloaded.Download.Workers = 2
+	reloaded.Download.RateLimitMbps = 10
+	reloaded.Cache.EvictionThreshold = 0.7
+	reloaded.Prediction.Enabled = false
+	reloaded.Server.Port = 9090
+
+	updates := make(chan config.ConfigUpdated, 1)
+	updates <- config.ConfigUpdated{Previous: cfg, Config: &reloaded, Changed: []string{"cache", "download", "server", "prediction"}}
+	close(updates)
+	server.WatchConfig(context.Background(), updates)
+
+	status, _ := server.downloadManager.GetStatus()
+	if status["workers"] != 4 || status["rate_limit"] != 10 {
+		t.Errorf("Expected the saved 4 workers at the reloaded 10 Mbps, got %v", status)
+	}
+	if cache.EvictionThreshold() != 0.7 {
+		t.Errorf("Expected the reloaded eviction threshold, got %v", cache.EvictionThreshold())
+	}
+	if server.predictor.Enabled() {
+		t.Error("Expected predictions to be turned off")
+	}
+	if current := server.currentSettings(); current.Server.Port != 9090 || current.Download.Workers != 4 {
+		t.Errorf("Expected the settings API to show the reloaded config, got port %d and %d workers",
+			current.Server.Port, current.Download.Workers)
+	}
+
+	events, _ := server.storage.GetEvents(storage.EventFilter{Kinds: []string{storage.EventConfigChanged}})
+	if len(events) != 2 {
+		t.Errorf("Expected the reload to be logged, got %d events", len(events))
+	}
+}
@@ -0,0 +1,383 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// defaultShareTTL is used when a share request does not specify a lifetime.
+const defaultShareTTL = 24 * time.Hour
+
+// shareCookie holds proof that the password for a share was entered.
+const shareCookie = "jfw_share"
+
+// CreateShareRequest is the body of POST /api/shares.
+type CreateShareRequest struct {
+	MediaID   string `json:"media_id"`
+	ExpiresIn string `json:"expires_in,omitempty"` // Go duration, e.g. "48h"; defaults to 24h
+	Password  string `json:"password,omitempty"`
+}
+
+// ShareResponse describes a share link.
+type ShareResponse struct {
+	ID                string    `json:"id"`
+	MediaID           string    `json:"media_id"`
+	Title             string    `json:"title"`
+	URL               string    `json:"url"`
+	PasswordProtected bool      `json:"password_protected"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// sharePasswordForm is shown when a password protected link is opened.
+var sharePasswordForm = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>{{.Title}}</title></head>
+<body style="font-family: sans-serif; max-width: 360px; margin: 80px auto;">
+<h3>{{.Title}}</h3>
+{{if .Message}}<p style="color: #b00;">{{.Message}}</p>{{end}}
+<form method="post">
+<input type="password" name="password" placeholder="Password" autofocus required>
+<button type="submit">Open</button>
+</form>
+</body>
+</html>
+`))
+
+// newShareHandler returns the public share link handler. It serves only
+// /share/{token}, so it can be exposed without exposing the API.
+func (s *Server) newShareHandler() http.Handler {
+	r := chi.NewRouter()
+	r.Use(middleware.Recoverer)
+	s.registerShareRoutes(r)
+	return r
+}
+
+// registerShareRoutes adds the public share link routes to r.
+func (s *Server) registerShareRoutes(r chi.Router) {
+	r.Get("/share/{token}", s.handleShare)
+	r.Head("/share/{token}", s.handleShare)
+	r.Post("/share/{token}", s.handleShare)
+}
+
+// handleCreateShare creates a time-limited share link for a cached item.
+func (s *Server) handleCreateShare(w http.ResponseWriter, r *http.Request) {
+	if !s.config.Sharing.Enabled {
+		s.writeErrorResponse(w, http.StatusNotFound, "Sharing is not enabled", nil)
+		return
+	}
+
+	var req CreateShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if req.MediaID == "" {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Media ID is required", nil)
+		return
+	}
+
+	ttl := defaultShareTTL
+	if req.ExpiresIn != "" {
+		parsed, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || parsed <= 0 {
+			s.writeErrorResponse(w, http.StatusBadRequest, "expires_in must be a positive duration such as 48h", nil)
+			return
+		}
+		ttl = parsed
+	}
+	if ttl > s.config.Sharing.MaxTTL {
+		s.writeErrorResponse(w, http.StatusBadRequest,
+			fmt.Sprintf("expires_in cannot exceed %s", s.config.Sharing.MaxTTL), nil)
+		return
+	}
+
+	// Only cached items can be shared; the link never reaches Jellyfin
+	record, err := s.library.GetDownload(req.MediaID)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "Media is not cached", nil)
+		return
+	}
+
+	id, err := randomHex(16)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to create share", err)
+		return
+	}
+
+	now := time.Now()
+	if pruned, err := s.storage.PruneExpiredShares(now); err == nil && pruned > 0 {
+		s.logger.Debug("Pruned expired share links", "count", pruned)
+	}
+
+	share := &storage.Share{
+		ID:        id,
+		MediaID:   record.JellyfinID,
+		Title:     record.Title,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl).Truncate(time.Second),
+	}
+	if req.Password != "" {
+		salt, err := randomHex(16)
+		if err != nil {
+			s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to create share", err)
+			return
+		}
+		hash, err := hashSharePassword(salt, req.Password)
+		if err != nil {
+			s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to create share", err)
+			return
+		}
+		share.PasswordSalt = salt
+		share.PasswordHash = hash
+	}
+
+	if err := s.storage.AddShare(share); err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to create share", err)
+		return
+	}
+
+	response, err := s.shareResponse(share)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to sign share link", err)
+		return
+	}
+
+	s.logger.Info("Created share link",
+		"share_id", share.ID,
+		"media_id", share.MediaID,
+		"expires_at", share.ExpiresAt,
+		"password_protected", share.HasPassword())
+
+	s.writeJSONResponse(w, http.StatusCreated, APIResponse{
+		Success: true,
+		Data:    response,
+	})
+}
+
+// handleListShares returns the active share links.
+func (s *Server) handleListShares(w http.ResponseWriter, r *http.Request) {
+	shares, err := s.storage.ListShares()
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to list shares", err)
+		return
+	}
+
+	responses := make([]*ShareResponse, 0, len(shares))
+	for _, share := range shares {
+		response, err := s.shareResponse(share)
+		if err != nil {
+			s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to sign share link", err)
+			return
+		}
+		responses = append(responses, response)
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    responses,
+	})
+}
+
+// handleDeleteShare revokes a share link.
+func (s *Server) handleDeleteShare(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := s.storage.RemoveShare(id); err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to revoke share", err)
+		return
+	}
+	s.shareUnlocks.Delete(id)
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "Share revoked",
+	})
+}
+
+// handleShare serves the file behind a share link with Range support.
+// Invalid, revoked and expired links are indistinguishable to the caller.
+func (s *Server) handleShare(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	token := chi.URLParam(r, "token")
+	share, secret := s.resolveShare(token)
+	if share == nil {
+		http.Error(w, "Share link not found or expired", http.StatusNotFound)
+		return
+	}
+
+	if share.HasPassword() && !s.shareUnlocked(r, share, secret) {
+		if r.Method != http.MethodPost {
+			s.renderSharePasswordForm(w, share, http.StatusUnauthorized, "")
+			return
+		}
+
+		now := time.Now()
+		guard := s.shareGuard(share.ID, now)
+		if wait := guard.blocked(now); wait > 0 {
+			s.renderSharePasswordForm(w, share, http.StatusTooManyRequests,
+				fmt.Sprintf("Too many wrong passwords. Try again in %d seconds.", int(wait.Seconds())+1))
+			return
+		}
+
+		password := r.PostFormValue("password")
+		hash, err := hashSharePassword(share.PasswordSalt, password)
+		if err != nil || subtle.ConstantTimeCompare([]byte(hash), []byte(share.PasswordHash)) != 1 {
+			guard.failed(now)
+			s.logger.Warn("Incorrect share link password", "share_id", share.ID, "remote_addr", r.RemoteAddr)
+			s.renderSharePasswordForm(w, share, http.StatusUnauthorized, "Incorrect password.")
+			return
+		}
+		s.shareUnlocks.Delete(share.ID)
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     shareCookie,
+			Value:    signShare(secret, "unlock", share.ID),
+			Path:     "/share/" + token,
+			Expires:  share.ExpiresAt,
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, r.URL.Path, http.StatusSeeOther)
+		return
+	}
+
+	record, err := s.library.GetDownload(share.MediaID)
+	if err != nil {
+		http.Error(w, "Shared item is no longer available", http.StatusGone)
+		return
+	}
+	if _, err := os.Stat(record.LocalPath); err != nil {
+		http.Error(w, "Shared item is no longer available", http.StatusGone)
+		return
+	}
+
+	s.logger.Debug("Serving share link",
+		"share_id", share.ID,
+		"media_id", share.MediaID,
+		"range", r.Header.Get("Range"))
+
+	s.serveVideoFile(w, r, record.LocalPath, record.ContentType, nil)
+}
+
+// resolveShare verifies a share token and returns the unexpired share it
+// names, along with the signing secret.
+func (s *Server) resolveShare(token string) (*storage.Share, []byte) {
+	id, signature, ok := strings.Cut(token, ".")
+	if !ok || id == "" {
+		return nil, nil
+	}
+
+	share, err := s.storage.GetShare(id)
+	if err != nil || share == nil || share.Expired(time.Now()) {
+		return nil, nil
+	}
+
+	secret, err := s.storage.ShareSecret()
+	if err != nil {
+		s.logger.Error("Failed to load share secret", "error", err)
+		return nil, nil
+	}
+
+	expected := signShare(secret, share.ID, fmt.Sprint(share.ExpiresAt.Unix()))
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return nil, nil
+	}
+
+	return share, secret
+}
+
+// shareUnlocked reports whether the request carries proof that the share's
+// password was entered.
+func (s *Server) shareUnlocked(r *http.Request, share *storage.Share, secret []byte) bool {
+	cookie, err := r.Cookie(shareCookie)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(cookie.Value), []byte(signShare(secret, "unlock", share.ID)))
+}
+
+// shareGuard returns the failed-password counter for a share, so guessing
+// one link's password does not lock out the others. Counters of revoked
+// and expired shares go with the other idle ones.
+func (s *Server) shareGuard(id string, now time.Time) *loginGuard {
+	return guardFor(&s.shareUnlocks, id, now)
+}
+
+func (s *Server) renderSharePasswordForm(w http.ResponseWriter, share *storage.Share, status int, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	sharePasswordForm.Execute(w, map[string]interface{}{
+		"Title":   share.Title,
+		"Message": message,
+	})
+}
+
+// shareResponse builds the API representation of a share, including its
+// signed URL.
+func (s *Server) shareResponse(share *storage.Share) (*ShareResponse, error) {
+	secret, err := s.storage.ShareSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	token := share.ID + "." + signShare(secret, share.ID, fmt.Sprint(share.ExpiresAt.Unix()))
+	return &ShareResponse{
+		ID:                share.ID,
+		MediaID:           share.MediaID,
+		Title:             share.Title,
+		URL:               strings.TrimSuffix(s.config.Sharing.BaseURL, "/") + "/share/" + token,
+		PasswordProtected: share.HasPassword(),
+		ExpiresAt:         share.ExpiresAt,
+	}, nil
+}
+
+// signShare returns a hex HMAC-SHA256 of the parts, truncated to 128 bits.
+func signShare(secret []byte, parts ...string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Cost parameters for hashing share passwords with scrypt.
+const (
+	shareScryptN      = 1 << 15
+	shareScryptR      = 8
+	shareScryptP      = 1
+	shareScryptKeyLen = 32
+)
+
+// hashSharePassword derives the stored hash of a share password. scrypt is
+// deliberately slow so a leaked database cannot be cheaply brute-forced.
+func hashSharePassword(salt, password string) (string, error) {
+	key, err := scrypt.Key([]byte(password), []byte(salt), shareScryptN, shareScryptR, shareScryptP, shareScryptKeyLen)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
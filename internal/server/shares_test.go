@@ -0,0 +1,249 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func newShareTestServer(t *testing.T) *Server {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	dir := t.TempDir()
+	sm := newTestStorage(t, dir)
+
+	path := filepath.Join(dir, "home-video.mp4")
+	if err := os.WriteFile(path, []byte("birthday party video"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := sm.AddDownloadRecord(&storage.DownloadRecord{
+		ID: "v1", JellyfinID: "v1", MediaType: "movie", Title: "Birthday",
+		LocalPath: path, ContentType: "video/mp4", Status: "completed", DownloadedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to add download record: %v", err)
+	}
+
+	return newTestServer(t, &Server{
+		config: &config.ServerConfig{Sharing: config.SharingConfig{
+			Enabled: true,
+			MaxTTL:  7 * 24 * time.Hour,
+			BaseURL: "https://share.example.com/",
+		}},
+		logger:  logger,
+		storage: sm,
+	})
+}
+
+func createShare(t *testing.T, server *Server, body string) *ShareResponse {
+	req := httptest.NewRequest(http.MethodPost, "/api/shares", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	server.handleCreateShare(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Data ShareResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return &resp.Data
+}
+
+func TestShareLinks(t *testing.T) {
+	server := newShareTestServer(t)
+	handler := server.newShareHandler()
+
+	t.Run("serves shared file with range support", func(t *testing.T) {
+		share := createShare(t, server, `{"media_id": "v1", "expires_in": "2h"}`)
+		if !strings.HasPrefix(share.URL, "https://share.example.com/share/") {
+			t.Fatalf("Unexpected share URL %q", share.URL)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, strings.TrimPrefix(share.URL, "https://share.example.com"), nil)
+		req.Header.Set("Range", "bytes=0-7")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusPartialContent {
+			t.Fatalf("Expected status 206, got %d", w.Code)
+		}
+		if w.Body.String() != "birthday" {
+			t.Errorf("Expected ranged body, got %q", w.Body.String())
+		}
+	})
+
+	t.Run("rejects tampered signature", func(t *testing.T) {
+		share := createShare(t, server, `{"media_id": "v1"}`)
+		id := strings.SplitN(strings.TrimPrefix(share.URL, "https://share.example.com/share/"), ".", 2)[0]
+
+		req := httptest.NewRequest(http.MethodGet, "/share/"+id+".00000000000000000000000000000000", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("exposes no API", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/status", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("revoked link stops working", func(t *testing.T) {
+		share := createShare(t, server, `{"media_id": "v1"}`)
+		if err := server.storage.RemoveShare(share.ID); err != nil {
+			t.Fatalf("RemoveShare failed: %v", err)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, strings.TrimPrefix(share.URL, "https://share.example.com"), nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("password protected link", func(t *testing.T) {
+		share := createShare(t, server, `{"media_id": "v1", "password": "grandma"}`)
+		path := strings.TrimPrefix(share.URL, "https://share.example.com")
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "<form") {
+			t.Fatalf("Expected password form, got %d", w.Code)
+		}
+
+		post := func(password string) *httptest.ResponseRecorder {
+			form := url.Values{"password": {password}}
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			return w
+		}
+
+		if w := post("wrong"); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 for wrong password, got %d", w.Code)
+		}
+
+		w = post("grandma")
+		if w.Code != http.StatusSeeOther {
+			t.Fatalf("Expected redirect after correct password, got %d", w.Code)
+		}
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 {
+			t.Fatalf("Expected an unlock cookie, got %d cookies", len(cookies))
+		}
+
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.AddCookie(cookies[0])
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Body.String() != "birthday party video" {
+			t.Errorf("Expected file after unlocking, got %d %q", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("locks out repeated wrong passwords", func(t *testing.T) {
+		share := createShare(t, server, `{"media_id": "v1", "password": "grandma"}`)
+		other := createShare(t, server, `{"media_id": "v1", "password": "grandpa"}`)
+
+		post := func(share *ShareResponse, password string) int {
+			form := url.Values{"password": {password}}
+			req := httptest.NewRequest(http.MethodPost, strings.TrimPrefix(share.URL, "https://share.example.com"), strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		for i := 0; i < loginMaxAttempts; i++ {
+			if code := post(share, "wrong"); code != http.StatusUnauthorized {
+				t.Fatalf("Attempt %d: expected status 401, got %d", i+1, code)
+			}
+		}
+		if code := post(share, "grandma"); code != http.StatusTooManyRequests {
+			t.Errorf("Expected status 429 once locked out, got %d", code)
+		}
+		if code := post(other, "grandpa"); code != http.StatusSeeOther {
+			t.Errorf("Expected other shares to stay unlockable, got %d", code)
+		}
+	})
+
+	t.Run("forgets the password guard of a revoked share", func(t *testing.T) {
+		share := createShare(t, server, `{"media_id": "v1", "password": "grandma"}`)
+
+		form := url.Values{"password": {"wrong"}}
+		req := httptest.NewRequest(http.MethodPost, strings.TrimPrefix(share.URL, "https://share.example.com"), strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if _, ok := server.shareUnlocks.Load(share.ID); !ok {
+			t.Fatal("Expected a wrong password to be counted")
+		}
+
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/shares/"+share.ID, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		if _, ok := server.shareUnlocks.Load(share.ID); ok {
+			t.Error("Expected revoking the share to drop its guard")
+		}
+	})
+
+	t.Run("stores a slow password hash", func(t *testing.T) {
+		created := createShare(t, server, `{"media_id": "v1", "password": "grandma"}`)
+		share, err := server.storage.GetShare(created.ID)
+		if err != nil {
+			t.Fatalf("GetShare failed: %v", err)
+		}
+
+		sum := sha256.Sum256([]byte(share.PasswordSalt + "grandma"))
+		if share.PasswordHash == "" || share.PasswordHash == hex.EncodeToString(sum[:]) {
+			t.Errorf("Expected an scrypt hash, got %q", share.PasswordHash)
+		}
+	})
+
+	t.Run("rejects lifetime beyond max_ttl", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/shares", bytes.NewBufferString(`{"media_id": "v1", "expires_in": "720h"}`))
+		w := httptest.NewRecorder()
+		server.handleCreateShare(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("rejects uncached media", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/shares", bytes.NewBufferString(`{"media_id": "missing"}`))
+		w := httptest.NewRecorder()
+		server.handleCreateShare(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// eventStreamPath is where progress updates are served as Server-Sent
+// Events. Streams stay open indefinitely, so the path is exempt from the
+// request timeout.
+const eventStreamPath = "/api/events/stream"
+
+// sseKeepAlive is how often an idle event stream gets a comment line, so
+// proxies do not close it and dead clients are noticed.
+const sseKeepAlive = 30 * time.Second
+
+// sseClient is a connected Server-Sent Events client. Its filter is fixed
+// by the query string when it connects.
+type sseClient struct {
+	clientQueue
+	updateFilter
+}
+
+// handleEventStream streams the same progress updates as the WebSocket
+// endpoint as Server-Sent Events, for proxies and dashboards that handle
+// those better. Each update is a message whose data is the JSON
+// ProgressUpdate. ?media_ids= and ?types= take comma-separated lists and
+// narrow the stream like the subscribe and filter WebSocket commands.
+func (s *Server) handleEventStream(w http.ResponseWriter, r *http.Request) {
+	client := &sseClient{clientQueue: newClientQueue(clientBufferSize)}
+	if mediaIDs := splitList(r.URL.Query().Get("media_ids")); len(mediaIDs) > 0 {
+		if _, err := client.subscribe(mediaIDs); err != nil {
+			s.writeErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+	}
+	client.setTypes(splitList(r.URL.Query().Get("types")))
+
+	// The server's write timeout would end the stream
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		s.logger.Debug("Failed to clear event stream write deadline", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Stop nginx buffering the stream
+	w.WriteHeader(http.StatusOK)
+
+	s.logger.Info("Event stream client connected", "remote_addr", r.RemoteAddr)
+	s.hub.register(client)
+	defer func() {
+		s.hub.unregister(client)
+		s.logger.Debug("Event stream client disconnected", "remote_addr", r.RemoteAddr)
+	}()
+
+	if update, err := s.initialStatus("Event stream connected successfully"); err != nil {
+		s.logger.Error("Failed to get cache stats", "error", err)
+	} else {
+		client.queue(update)
+	}
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case update, ok := <-client.send:
+			if !ok {
+				// Disconnected by the hub
+				return
+			}
+			err = writeSSEMessage(w, update)
+		case <-ticker.C:
+			_, err = fmt.Fprint(w, ": keepalive\n\n")
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			s.logger.Debug("Event stream write error", "error", err)
+			return
+		}
+	}
+}
+
+// writeSSEMessage writes update as one Server-Sent Events message.
+func writeSSEMessage(w http.ResponseWriter, update ProgressUpdate) error {
+	data, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
+
+// splitList splits a comma-separated query parameter, dropping empty
+// entries.
+func splitList(value string) []string {
+	var list []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleEventStream(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sm := newTestStorage(t, t.TempDir())
+
+	server := newTestServer(t, &Server{
+		logger:  logger,
+		storage: sm,
+	})
+	ts := httptest.NewServer(http.HandlerFunc(server.handleEventStream))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "?media_ids=m1,m2&types=download")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %q", ct)
+	}
+
+	updates := make(chan ProgressUpdate)
+	go func() {
+		defer close(updates)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var update ProgressUpdate
+			if err := json.Unmarshal([]byte(data), &update); err != nil {
+				t.Errorf("Failed to decode %q: %v", data, err)
+				return
+			}
+			updates <- update
+		}
+	}()
+	read := func() ProgressUpdate {
+		t.Helper()
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				t.Fatal("Stream closed")
+			}
+			return update
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for an update")
+		}
+		return ProgressUpdate{}
+	}
+
+	if update := read(); update.Type != "status" || update.Status != "connected" {
+		t.Fatalf("Expected initial status, got %+v", update)
+	}
+
+	// Only the last update matches both the media IDs and the types
+	server.BroadcastProgressUpdate(ProgressUpdate{Type: "download", MediaID: "m3", Status: "downloading"})
+	server.BroadcastProgressUpdate(ProgressUpdate{Type: "cache", MediaID: "m1", Status: "completed"})
+	server.BroadcastProgressUpdate(ProgressUpdate{Type: "download", MediaID: "m2", Status: "downloading", Progress: 50})
+	if update := read(); update.MediaID != "m2" || update.Type != "download" || update.Progress != 50 {
+		t.Errorf("Expected only the m2 download update, got %+v", update)
+	}
+
+	resp.Body.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for server.hub.clientCount() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the client to be unregistered after disconnecting")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ids := make([]string, maxWSSubscriptions+1)
+	for i := range ids {
+		ids[i] = string(rune('a'+i%26)) + strings.Repeat("x", i/26)
+	}
+	resp, err = http.Get(ts.URL + "?media_ids=" + strings.Join(ids, ","))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected too many media IDs to be rejected, got %d", resp.StatusCode)
+	}
+}
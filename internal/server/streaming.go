@@ -4,13 +4,18 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+
+	"github.com/opd-ai/go-jf-watch/internal/media"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
 )
 
 // handleVideoStream serves video files with HTTP Range support for seeking.
@@ -28,6 +33,14 @@ func (s *Server) handleVideoStream(w http.ResponseWriter, r *http.Request) {
 		"range", r.Header.Get("Range"),
 		"user_agent", r.UserAgent())
 
+	// Items from other providers bypass the Jellyfin cache and prediction
+	if id := media.ParseID(mediaID); !id.IsJellyfin() {
+		s.handleProviderStream(w, r, id)
+		return
+	}
+
+	s.recordDevicePlayback(r)
+
 	// Trigger playback prediction for Priority 0 download and next episode queuing
 	// Only trigger on initial request (not range requests for seeking)
 	if r.Header.Get("Range") == "" {
@@ -42,9 +55,10 @@ func (s *Server) handleVideoStream(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if file exists in cache
-	cachedItem, err := s.storage.GetDownload(mediaID)
+	cachedItem, err := s.library.GetDownload(mediaID)
 	if err != nil {
 		s.logger.Warn("Media not found in cache", "media_id", mediaID, "error", err)
+		s.recordStreamRequest(r, false)
 		s.handleFallbackStream(w, r, mediaID)
 		return
 	}
@@ -52,19 +66,93 @@ func (s *Server) handleVideoStream(w http.ResponseWriter, r *http.Request) {
 	// Verify file exists on disk
 	if _, err := os.Stat(cachedItem.LocalPath); os.IsNotExist(err) {
 		s.logger.Warn("Cached file not found on disk", "media_id", mediaID, "path", cachedItem.LocalPath)
+		s.recordStreamRequest(r, false)
+		s.handleFallbackStream(w, r, mediaID)
+		return
+	}
+
+	// A device whose profile the cached variant exceeds gets a transcode
+	// instead, unless Jellyfin is unreachable and the cache is all there is
+	if profile, ok := s.deviceProfile(r); ok && exceedsProfile(cachedItem, profile) &&
+		s.jellyfinClient != nil && !s.jellyfinOffline() {
+		s.logger.Debug("Cached variant exceeds device profile, transcoding",
+			"media_id", mediaID, "quality", cachedItem.Quality, "max_quality", profile.MaxQuality)
+		s.recordStreamRequest(r, false)
 		s.handleFallbackStream(w, r, mediaID)
 		return
 	}
 
-	// Serve the cached file with range support
-	s.serveVideoFile(w, r, cachedItem.LocalPath, cachedItem.ContentType)
+	s.recordStreamRequest(r, true)
+	s.recordCacheAccess(r, mediaID)
+
+	// Binge watchers move on as soon as this ends, so make sure the next
+	// episode can start even if it is still downloading
+	if rangeHeader := r.Header.Get("Range"); (rangeHeader == "" || rangeHeader == "bytes=0-") && s.readAhead.Enabled() {
+		go func() {
+			if err := s.readAhead.OnCachedPlayback(context.Background(), mediaID); err != nil {
+				s.logger.Warn("Failed to read ahead into next item",
+					"media_id", mediaID,
+					"error", err)
+			}
+		}()
+	}
+
+	// Serve the cached file with range support, following how much of it
+	// the player fetches as playback progress
+	var track func(start, end, size int64)
+	if s.playback != nil {
+		client, userAgent := clientAddress(r), r.UserAgent()
+		track = func(start, end, size int64) {
+			s.playback.StreamServed(mediaID, client, userAgent, start, end, size)
+		}
+	}
+	s.serveVideoFile(w, r, cachedItem.LocalPath, cachedItem.ContentType, track)
+}
+
+// clientAddress returns the IP address a request came from.
+func clientAddress(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// recordStreamRequest counts a cache hit or miss for the start of a playback.
+// Seeks arrive as further range requests and are not counted.
+func (s *Server) recordStreamRequest(r *http.Request, hit bool) {
+	if !isPlaybackStart(r) {
+		return
+	}
+	if err := s.storage.RecordStreamRequest(hit); err != nil {
+		s.logger.Debug("Failed to record stream stats", "error", err)
+	}
+}
+
+// recordCacheAccess marks a cached item as played, which the eviction
+// policies use to keep popular and recently played items.
+func (s *Server) recordCacheAccess(r *http.Request, mediaID string) {
+	if !isPlaybackStart(r) {
+		return
+	}
+	if err := s.library.RecordAccess(mediaID, time.Now()); err != nil {
+		s.logger.Debug("Failed to record cache access", "media_id", mediaID, "error", err)
+	}
+}
+
+// isPlaybackStart reports whether a stream request starts playback rather
+// than seeking within it.
+func isPlaybackStart(r *http.Request) bool {
+	rangeHeader := r.Header.Get("Range")
+	return rangeHeader == "" || rangeHeader == "bytes=0-"
 }
 
 // serveVideoFile serves a video file with HTTP Range support.
 // Uses http.ServeContent for robust range handling including multipart ranges.
-func (s *Server) serveVideoFile(w http.ResponseWriter, r *http.Request, filePath, contentType string) {
-	// Open file
-	file, err := os.Open(filePath)
+// track, if set, is called with each byte range of the file as it is sent.
+func (s *Server) serveVideoFile(w http.ResponseWriter, r *http.Request, filePath, contentType string, track func(start, end, size int64)) {
+	// Open file, decrypting it as it is read when the cache is encrypted.
+	// Ranges are served from the content, not from the file on disk.
+	file, err := storage.OpenCached(filePath, s.cipher())
 	if err != nil {
 		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to open video file", err)
 		return
@@ -86,18 +174,94 @@ func (s *Server) serveVideoFile(w http.ResponseWriter, r *http.Request, filePath
 	}
 	w.Header().Set("Content-Type", contentType)
 
+	var content io.ReadSeeker = file
+	if track != nil {
+		size := fileInfo.Size()
+		content = &servedReader{ReadSeeker: file, report: func(start, end int64) { track(start, end, size) }}
+	}
+
 	// Use http.ServeContent for robust Range request handling
 	// This handles single ranges, multipart ranges, and proper caching headers
-	http.ServeContent(w, r, filepath.Base(filePath), fileInfo.ModTime(), file)
+	http.ServeContent(w, r, filepath.Base(filePath), fileInfo.ModTime(), content)
+}
+
+// cipher returns the cipher of the cache, nil when it is not encrypted.
+func (s *Server) cipher() *storage.Cipher {
+	if s.storage == nil {
+		return nil
+	}
+	return s.storage.Cipher()
+}
+
+// servedReader reports the byte ranges read from a file while it is
+// served.
+type servedReader struct {
+	io.ReadSeeker
+	pos    int64
+	report func(start, end int64)
+}
+
+func (r *servedReader) Read(p []byte) (int, error) {
+	n, err := r.ReadSeeker.Read(p)
+	if n > 0 {
+		r.report(r.pos, r.pos+int64(n))
+		r.pos += int64(n)
+	}
+	return n, err
+}
+
+func (r *servedReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := r.ReadSeeker.Seek(offset, whence)
+	if err == nil {
+		r.pos = pos
+	}
+	return pos, err
+}
+
+// handleProviderStream serves an item from a non-Jellyfin provider.
+// Local sources are served in place with Range support.
+func (s *Server) handleProviderStream(w http.ResponseWriter, r *http.Request, id media.ID) {
+	if s.providers == nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "Media provider not configured", nil)
+		return
+	}
+
+	source, err := s.providers.Source(r.Context(), id)
+	if err != nil {
+		s.logger.Warn("Failed to resolve media source",
+			"provider", id.Provider, "item_id", id.ItemID, "error", err)
+		s.writeErrorResponse(w, http.StatusNotFound, "Media not found", err)
+		return
+	}
+
+	if source.LocalPath == "" {
+		s.writeErrorResponse(w, http.StatusNotImplemented, "Remote sources are not supported for this provider", nil)
+		return
+	}
+
+	s.serveVideoFile(w, r, source.LocalPath, "", nil)
 }
 
 // handleFallbackStream handles streaming from Jellyfin server when file is not cached.
 // Proxies the request to the original Jellyfin server while preserving headers.
+// Devices with a quality profile get a Jellyfin transcode within it.
 func (s *Server) handleFallbackStream(w http.ResponseWriter, r *http.Request, mediaID string) {
 	s.logger.Info("Streaming uncached media from Jellyfin server", "media_id", mediaID)
 
-	// Get stream URL from Jellyfin client
-	streamURL, err := s.jellyfinClient.GetStreamURL(mediaID)
+	if s.jellyfinClient == nil {
+		s.writeErrorResponse(w, http.StatusServiceUnavailable,
+			"Jellyfin server not configured", nil)
+		return
+	}
+
+	if s.jellyfinOffline() {
+		s.writeErrorResponse(w, http.StatusServiceUnavailable,
+			"Jellyfin server is unreachable and the item is not cached", nil)
+		return
+	}
+
+	// Get stream URL from Jellyfin client, transcoded for the device
+	streamURL, err := s.streamURL(r, mediaID)
 	if err != nil {
 		s.logger.Error("Failed to get stream URL from Jellyfin",
 			"media_id", mediaID, "error", err)
@@ -161,9 +325,9 @@ func (s *Server) handleFallbackStream(w http.ResponseWriter, r *http.Request, me
 		"media_id", mediaID, "status", resp.StatusCode)
 }
 
-// detectContentType detects the MIME type of a video file.
+// detectContentType detects the MIME type of a video or audio file.
 // Uses file extension and content sniffing for accurate detection.
-func (s *Server) detectContentType(filePath string, file *os.File) string {
+func (s *Server) detectContentType(filePath string, file io.ReadSeeker) string {
 	// Try to detect from file extension first
 	ext := strings.ToLower(filepath.Ext(filePath))
 	switch ext {
@@ -183,6 +347,20 @@ func (s *Server) detectContentType(filePath string, file *os.File) string {
 		return "video/webm"
 	case ".m4v":
 		return "video/x-m4v"
+	case ".mp3":
+		return "audio/mpeg"
+	case ".flac":
+		return "audio/flac"
+	case ".m4a", ".m4b":
+		return "audio/mp4"
+	case ".aac":
+		return "audio/aac"
+	case ".ogg", ".oga":
+		return "audio/ogg"
+	case ".opus":
+		return "audio/opus"
+	case ".wav":
+		return "audio/wav"
 	}
 
 	// Fall back to content sniffing
@@ -196,7 +374,7 @@ func (s *Server) detectContentType(filePath string, file *os.File) string {
 	file.Seek(0, io.SeekStart)
 
 	contentType := http.DetectContentType(buffer[:n])
-	if strings.HasPrefix(contentType, "video/") {
+	if strings.HasPrefix(contentType, "video/") || strings.HasPrefix(contentType, "audio/") {
 		return contentType
 	}
 
@@ -1,13 +1,21 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/internal/media"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
 )
 
 func TestParseRangeHeader(t *testing.T) {
@@ -110,6 +118,10 @@ func TestDetectContentType(t *testing.T) {
 		{"stream.flv", "video/x-flv"},
 		{"web.webm", "video/webm"},
 		{"mobile.m4v", "video/x-m4v"},
+		{"track.mp3", "audio/mpeg"},
+		{"track.flac", "audio/flac"},
+		{"book.m4b", "audio/mp4"},
+		{"track.opus", "audio/opus"},
 		{"unknown.xyz", "application/octet-stream"},
 	}
 
@@ -201,7 +213,7 @@ func TestServeVideoFile(t *testing.T) {
 
 			w := httptest.NewRecorder()
 
-			server.serveVideoFile(w, req, testFile, "video/mp4")
+			server.serveVideoFile(w, req, testFile, "video/mp4", nil)
 
 			if w.Code != tt.expectedStatus {
 				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
@@ -300,7 +312,7 @@ func TestVideoStreamingWithRangeRequests(t *testing.T) {
 
 			w := httptest.NewRecorder()
 
-			server.serveVideoFile(w, req, testFile, "video/mp4")
+			server.serveVideoFile(w, req, testFile, "video/mp4", nil)
 
 			if w.Code != http.StatusPartialContent {
 				t.Errorf("Expected status 206, got %d", w.Code)
@@ -346,7 +358,7 @@ func TestHEADRequestSupport(t *testing.T) {
 	req := httptest.NewRequest("HEAD", "/test", nil)
 	w := httptest.NewRecorder()
 
-	server.serveVideoFile(w, req, testFile, "video/mp4")
+	server.serveVideoFile(w, req, testFile, "video/mp4", nil)
 
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status 200 for HEAD request, got %d", w.Code)
@@ -368,7 +380,7 @@ func TestHEADRequestSupport(t *testing.T) {
 	req.Header.Set("Range", "bytes=0-499")
 	w = httptest.NewRecorder()
 
-	server.serveVideoFile(w, req, testFile, "video/mp4")
+	server.serveVideoFile(w, req, testFile, "video/mp4", nil)
 
 	if w.Code != http.StatusPartialContent {
 		t.Errorf("Expected status 206 for HEAD range request, got %d", w.Code)
@@ -383,3 +395,210 @@ func TestHEADRequestSupport(t *testing.T) {
 		t.Errorf("Expected proper Content-Range header, got %s", contentRange)
 	}
 }
+
+func TestHandleProviderStream(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	root := t.TempDir()
+	content := "local video content"
+	if err := os.WriteFile(filepath.Join(root, "home movie.mp4"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	provider, err := media.NewLocalProvider(root, logger)
+	if err != nil {
+		t.Fatalf("Failed to create local provider: %v", err)
+	}
+	items, err := provider.ListItems(context.Background())
+	if err != nil || len(items) != 1 {
+		t.Fatalf("Expected one local item, got %d (err: %v)", len(items), err)
+	}
+
+	server := newTestServer(t, &Server{logger: logger})
+	server.SetProviders(media.NewRegistry(provider))
+
+	t.Run("serves local item with range support", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/stream/"+items[0].ID.String(), nil)
+		req.Header.Set("Range", "bytes=0-4")
+		w := httptest.NewRecorder()
+
+		server.handleProviderStream(w, req, items[0].ID)
+
+		if w.Code != http.StatusPartialContent {
+			t.Fatalf("Expected status 206, got %d", w.Code)
+		}
+		if w.Body.String() != content[:5] {
+			t.Errorf("Expected body %q, got %q", content[:5], w.Body.String())
+		}
+	})
+
+	t.Run("unknown provider returns not found", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/stream/plex:1", nil)
+		w := httptest.NewRecorder()
+
+		server.handleProviderStream(w, req, media.ParseID("plex:1"))
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", w.Code)
+		}
+	})
+}
+
+func TestHandleFallbackStream(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/Videos/m1/stream" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Range", "bytes 0-4/10")
+		w.WriteHeader(http.StatusPartialContent)
+		fmt.Fprint(w, r.Header.Get("Range"))
+	}))
+	defer upstream.Close()
+
+	t.Run("proxies range requests to jellyfin", func(t *testing.T) {
+		client := jellyfintest.New(upstream.URL)
+		server := newTestServer(t, &Server{logger: logger, jellyfinClient: client})
+
+		req := httptest.NewRequest(http.MethodGet, "/stream/m1", nil)
+		req.Header.Set("Range", "bytes=0-4")
+		w := httptest.NewRecorder()
+
+		server.handleFallbackStream(w, req, "m1")
+
+		if w.Code != http.StatusPartialContent {
+			t.Fatalf("Expected status 206, got %d", w.Code)
+		}
+		if w.Body.String() != "bytes=0-4" {
+			t.Errorf("Expected Range header to be forwarded, got %q", w.Body.String())
+		}
+		if w.Header().Get("Content-Range") != "bytes 0-4/10" {
+			t.Errorf("Expected upstream headers to be copied, got %q", w.Header().Get("Content-Range"))
+		}
+		if got := client.Requests(); len(got) != 1 || got[0] != "m1" {
+			t.Errorf("Expected one URL lookup for m1, got %v", got)
+		}
+	})
+
+	t.Run("url resolution failure", func(t *testing.T) {
+		client := jellyfintest.New(upstream.URL)
+		client.Err = fmt.Errorf("API key not configured")
+		server := newTestServer(t, &Server{logger: logger, jellyfinClient: client})
+
+		w := httptest.NewRecorder()
+		server.handleFallbackStream(w, httptest.NewRequest(http.MethodGet, "/stream/m1", nil), "m1")
+
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status 500, got %d", w.Code)
+		}
+	})
+
+	t.Run("jellyfin offline", func(t *testing.T) {
+		down := httptest.NewServer(http.NotFoundHandler())
+		down.Close()
+		offline := jellyfin.New(&config.JellyfinConfig{ServerURL: down.URL, APIKey: "key", UserID: "u1"}, logger)
+		offline.TestConnection(context.Background())
+
+		client := jellyfintest.New(upstream.URL)
+		server := newTestServer(t, &Server{logger: logger, jellyfinClient: client})
+		server.SetConnectivity(offline.Connectivity())
+
+		w := httptest.NewRecorder()
+		server.handleFallbackStream(w, httptest.NewRequest(http.MethodGet, "/stream/m1", nil), "m1")
+
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status 503, got %d", w.Code)
+		}
+		if got := client.Requests(); len(got) != 0 {
+			t.Errorf("Expected no stream URL lookups while offline, got %v", got)
+		}
+	})
+
+	t.Run("no jellyfin client", func(t *testing.T) {
+		server := newTestServer(t, &Server{logger: logger})
+
+		w := httptest.NewRecorder()
+		server.handleFallbackStream(w, httptest.NewRequest(http.MethodGet, "/stream/m1", nil), "m1")
+
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status 503, got %d", w.Code)
+		}
+	})
+}
+
+func TestServeVideoFileTracksServedRanges(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	server := newTestServer(t, &Server{logger: logger})
+
+	testFile := filepath.Join(t.TempDir(), "video.mp4")
+	if err := os.WriteFile(testFile, make([]byte, 10000), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	served := make(map[int64]int64)
+	track := func(start, end, size int64) {
+		if size != 10000 {
+			t.Errorf("Expected size 10000, got %d", size)
+		}
+		served[start] = end
+	}
+
+	for _, rangeHeader := range []string{"bytes=0-999", "bytes=9000-"} {
+		req := httptest.NewRequest("GET", "/stream/item", nil)
+		req.Header.Set("Range", rangeHeader)
+		w := httptest.NewRecorder()
+		server.serveVideoFile(w, req, testFile, "video/mp4", track)
+		if w.Code != http.StatusPartialContent {
+			t.Fatalf("Expected status 206, got %d", w.Code)
+		}
+	}
+
+	if served[0] != 1000 || served[9000] != 10000 || len(served) != 2 {
+		t.Errorf("Expected the two requested ranges to be reported, got %v", served)
+	}
+}
+
+func TestServeVideoFileDecryptsEncryptedCache(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	dir := t.TempDir()
+	sm, err := storage.NewManager(&config.CacheConfig{
+		Directory: dir, MaxSizeGB: 1, MetadataStore: "boltdb",
+		Encryption: config.CacheEncryptionConfig{
+			Enabled: true,
+			Key:     "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		},
+	}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer sm.Close()
+
+	content := make([]byte, 200000)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	plain := filepath.Join(dir, "download.partial")
+	os.WriteFile(plain, content, 0644)
+	path := filepath.Join(dir, "movie.mp4")
+	if err := sm.Cipher().EncryptFile(plain, path); err != nil {
+		t.Fatalf("EncryptFile failed: %v", err)
+	}
+
+	server := newTestServer(t, &Server{logger: logger, storage: sm})
+	req := httptest.NewRequest(http.MethodGet, "/stream/m1", nil)
+	req.Header.Set("Range", "bytes=65530-65549")
+	w := httptest.NewRecorder()
+	server.serveVideoFile(w, req, path, "video/mp4", nil)
+
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("Expected status 206, got %d", w.Code)
+	}
+	if got := w.Header().Get("Content-Range"); got != "bytes 65530-65549/200000" {
+		t.Errorf("Expected the range of the decrypted content, got %q", got)
+	}
+	if w.Body.String() != string(content[65530:65550]) {
+		t.Errorf("Expected decrypted bytes, got %x", w.Body.Bytes())
+	}
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+)
+
+// SubscriptionRequest is the body of POST /api/subscriptions and
+// PUT /api/subscriptions/{id}. Priority 0 uses
+// download.subscription_priority.
+type SubscriptionRequest struct {
+	SeriesID string `json:"series_id"`
+	Priority int    `json:"priority,omitempty"`
+}
+
+// SetSubscriptions sets the subscriptions whose new episodes are queued as
+// library sync finds them.
+func (s *Server) SetSubscriptions(subs *downloader.Subscriptions) {
+	s.subscriptions = subs
+}
+
+// handleListSubscriptions returns every series subscription.
+func (s *Server) handleListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	if !s.subscriptionsEnabled(w) {
+		return
+	}
+
+	subs, err := s.subscriptions.List()
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to list subscriptions", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    subs,
+	})
+}
+
+// handleCreateSubscription subscribes to a series in the library, so
+// every episode added to it from now on is cached.
+func (s *Server) handleCreateSubscription(w http.ResponseWriter, r *http.Request) {
+	if !s.subscriptionsEnabled(w) {
+		return
+	}
+
+	var req SubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if req.SeriesID == "" {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Series ID is required", nil)
+		return
+	}
+	if !validSubscriptionPriority(req.Priority) {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Priority must be between 1 and 4", nil)
+		return
+	}
+
+	// A series may have no episodes yet, but it must be in the library
+	var name string
+	if series, err := s.library.GetMediaMetadata(req.SeriesID); err == nil {
+		name = series.Name
+	} else if episodes, err := s.library.GetSeriesMetadata(req.SeriesID); err != nil || len(episodes) == 0 {
+		s.writeErrorResponse(w, http.StatusNotFound, "Series not found", nil)
+		return
+	}
+
+	sub, err := s.subscriptions.Subscribe(req.SeriesID, name, req.Priority)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to subscribe", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusCreated, APIResponse{
+		Success: true,
+		Message: "Subscribed",
+		Data:    sub,
+	})
+}
+
+// handleGetSubscription returns the subscription to one series.
+func (s *Server) handleGetSubscription(w http.ResponseWriter, r *http.Request) {
+	if !s.subscriptionsEnabled(w) {
+		return
+	}
+
+	sub, err := s.subscriptions.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to get subscription", err)
+		return
+	}
+	if sub == nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "Subscription not found", nil)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    sub,
+	})
+}
+
+// handleUpdateSubscription changes the priority new episodes of a
+// subscribed series are queued at.
+func (s *Server) handleUpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	if !s.subscriptionsEnabled(w) {
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	var req SubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if !validSubscriptionPriority(req.Priority) {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Priority must be between 1 and 4", nil)
+		return
+	}
+
+	existing, err := s.subscriptions.Get(id)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to get subscription", err)
+		return
+	}
+	if existing == nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "Subscription not found", nil)
+		return
+	}
+
+	sub, err := s.subscriptions.Subscribe(id, "", req.Priority)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to update subscription", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "Subscription updated",
+		Data:    sub,
+	})
+}
+
+// handleDeleteSubscription unsubscribes from a series. Episodes already
+// cached or queued are kept.
+func (s *Server) handleDeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	if !s.subscriptionsEnabled(w) {
+		return
+	}
+
+	if err := s.subscriptions.Unsubscribe(chi.URLParam(r, "id")); err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to unsubscribe", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "Unsubscribed",
+	})
+}
+
+// subscriptionsEnabled writes a 503 and returns false when no
+// subscriptions are set.
+func (s *Server) subscriptionsEnabled(w http.ResponseWriter) bool {
+	if s.subscriptions == nil {
+		s.writeErrorResponse(w, http.StatusServiceUnavailable, "Subscriptions are not enabled", nil)
+		return false
+	}
+	return true
+}
+
+// validSubscriptionPriority reports whether priority may be requested for
+// a subscription; 0 means the default.
+func validSubscriptionPriority(priority int) bool {
+	return priority >= 0 && priority <= 4
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+func TestSubscriptionEndpoints(t *testing.T) {
+	server := newShareTestServer(t)
+	if err := server.storage.AddMediaMetadata(&storage.MediaMetadata{
+		ID: "bluey", JellyfinID: "bluey", Name: "Bluey", Type: "series",
+	}); err != nil {
+		t.Fatalf("Failed to add metadata: %v", err)
+	}
+
+	// Unconfigured subscriptions are reported as unavailable
+	w := httptest.NewRecorder()
+	server.handleListSubscriptions(w, httptest.NewRequest(http.MethodGet, "/api/subscriptions", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503 without subscriptions, got %d", w.Code)
+	}
+
+	server.SetSubscriptions(downloader.NewSubscriptions(server.storage, nil, 2, server.logger))
+
+	create := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.handleCreateSubscription(w, httptest.NewRequest(http.MethodPost, "/api/subscriptions", strings.NewReader(body)))
+		return w
+	}
+	if w := create(`{"series_id":"missing"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown series, got %d", w.Code)
+	}
+	if w := create(`{"series_id":"bluey","priority":7}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid priority, got %d", w.Code)
+	}
+	w = create(`{"series_id":"bluey"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Data storage.Subscription `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Data.SeriesName != "Bluey" || resp.Data.Priority != 2 {
+		t.Errorf("Expected Bluey at default priority 2, got %+v", resp.Data)
+	}
+
+	withID := func(req *http.Request, id string) *http.Request {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+
+	w = httptest.NewRecorder()
+	server.handleUpdateSubscription(w, withID(httptest.NewRequest(http.MethodPut, "/api/subscriptions/bluey", strings.NewReader(`{"priority":1}`)), "bluey"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if sub, _ := server.storage.GetSubscription("bluey"); sub == nil || sub.Priority != 1 {
+		t.Errorf("Expected priority updated to 1, got %+v", sub)
+	}
+
+	w = httptest.NewRecorder()
+	server.handleDeleteSubscription(w, withID(httptest.NewRequest(http.MethodDelete, "/api/subscriptions/bluey", nil), "bluey"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	server.handleGetSubscription(w, withID(httptest.NewRequest(http.MethodGet, "/api/subscriptions/bluey", nil), "bluey"))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after unsubscribing, got %d", w.Code)
+	}
+}
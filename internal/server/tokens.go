@@ -0,0 +1,229 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// tokenPrefix starts every API token, so they are easy to spot in
+// scripts and secret scanners.
+const tokenPrefix = "jfw_"
+
+// tokenTouchInterval is how stale a token's last use may get before a
+// request records it again, so busy scripts do not write on every call.
+const tokenTouchInterval = time.Minute
+
+// tokenScopeRank orders the API token scopes; each allows what the ones
+// ranked below it do.
+var tokenScopeRank = map[string]int{
+	storage.TokenScopeRead:  1,
+	storage.TokenScopeQueue: 2,
+	storage.TokenScopeAdmin: 3,
+}
+
+// CreateAPITokenRequest is the body of POST /api/auth/tokens.
+type CreateAPITokenRequest struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope"` // read, queue or admin
+}
+
+// APITokenResponse describes an API token. Token is the secret to send as
+// "Authorization: Bearer <token>" and is only set when it is created.
+type APITokenResponse struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Scope      string    `json:"scope"`
+	Token      string    `json:"token,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at,omitempty"`
+}
+
+// apiTokenKey is the request context key of the API token a request was
+// authenticated with.
+type apiTokenKey struct{}
+
+// bearerToken returns the API token in the request's Authorization header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || !strings.HasPrefix(token, tokenPrefix) {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// authenticateToken returns the stored token that value is the secret of,
+// recording when it was used.
+func (s *Server) authenticateToken(value string) (*storage.APIToken, bool) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(value, tokenPrefix), "_")
+	if !ok || id == "" || secret == "" {
+		return nil, false
+	}
+	token, err := s.storage.GetAPIToken(id)
+	if err != nil || token == nil {
+		return nil, false
+	}
+	sum := sha256.Sum256([]byte(secret))
+	if subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(token.SecretHash)) != 1 {
+		return nil, false
+	}
+
+	if now := time.Now(); now.Sub(token.LastUsedAt) >= tokenTouchInterval {
+		token.LastUsedAt = now
+		if err := s.storage.AddAPIToken(token); err != nil {
+			s.logger.Warn("Failed to record API token use", "token_id", token.ID, "error", err)
+		}
+	}
+	return token, true
+}
+
+// requiredScope returns the API token scope a request needs. Reads need
+// read, except backups and the tokens themselves; changes to what is
+// downloaded need queue; anything else needs admin.
+func requiredScope(r *http.Request) string {
+	path := r.URL.Path
+	switch {
+	case path == "/api/backup", path == "/api/auth/tokens", strings.HasPrefix(path, "/api/auth/tokens/"):
+		return storage.TokenScopeAdmin
+	case r.Method == http.MethodGet, r.Method == http.MethodHead, r.Method == http.MethodOptions:
+		return storage.TokenScopeRead
+	case strings.HasPrefix(path, "/api/queue/"), strings.HasPrefix(path, "/api/library/"),
+		strings.HasPrefix(path, "/api/subscriptions"), strings.HasPrefix(path, "/api/series/"), path == "/api/seed",
+		strings.HasPrefix(path, "/api/collections/"):
+		return storage.TokenScopeQueue
+	}
+	return storage.TokenScopeAdmin
+}
+
+// tokenAuthenticated reports whether the request was let in by an API token.
+func tokenAuthenticated(r *http.Request) bool {
+	_, ok := r.Context().Value(apiTokenKey{}).(*storage.APIToken)
+	return ok
+}
+
+// serveWithToken answers a request that carries an API token: it is served
+// if the token is valid and its scope allows the request.
+func (s *Server) serveWithToken(w http.ResponseWriter, r *http.Request, next http.Handler, value string) {
+	token, ok := s.authenticateToken(value)
+	if !ok {
+		s.writeErrorResponse(w, http.StatusUnauthorized, "Invalid API token", nil)
+		return
+	}
+	if tokenScopeRank[token.Scope] < tokenScopeRank[requiredScope(r)] {
+		s.writeErrorResponse(w, http.StatusForbidden, "API token scope does not allow this request", nil)
+		return
+	}
+	next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiTokenKey{}, token)))
+}
+
+// handleListAPITokens lists the API tokens, without their secrets.
+func (s *Server) handleListAPITokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := s.storage.ListAPITokens()
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to list API tokens", err)
+		return
+	}
+
+	responses := make([]APITokenResponse, 0, len(tokens))
+	for _, token := range tokens {
+		responses = append(responses, apiTokenResponse(token, ""))
+	}
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    responses,
+	})
+}
+
+// handleCreateAPIToken creates an API token and returns its secret, which
+// cannot be retrieved again.
+func (s *Server) handleCreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	if s.config.Auth.Mode == "" {
+		s.writeErrorResponse(w, http.StatusNotFound, "Sign-in is not enabled", nil)
+		return
+	}
+
+	var req CreateAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Name is required", nil)
+		return
+	}
+	if _, ok := tokenScopeRank[req.Scope]; !ok {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Scope must be read, queue or admin", nil)
+		return
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to create API token", err)
+		return
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to create API token", err)
+		return
+	}
+	sum := sha256.Sum256([]byte(secret))
+
+	token := &storage.APIToken{
+		ID:         id,
+		Name:       req.Name,
+		Scope:      req.Scope,
+		SecretHash: hex.EncodeToString(sum[:]),
+		CreatedAt:  time.Now(),
+	}
+	if err := s.storage.AddAPIToken(token); err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to create API token", err)
+		return
+	}
+
+	s.logger.Info("Created API token", "token_id", id, "name", token.Name, "scope", token.Scope)
+	s.writeJSONResponse(w, http.StatusCreated, APIResponse{
+		Success: true,
+		Data:    apiTokenResponse(token, tokenPrefix+id+"_"+secret),
+	})
+}
+
+// handleDeleteAPIToken revokes an API token.
+func (s *Server) handleDeleteAPIToken(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	token, err := s.storage.GetAPIToken(id)
+	if err != nil || token == nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "API token not found", err)
+		return
+	}
+	if err := s.storage.RemoveAPIToken(id); err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to revoke API token", err)
+		return
+	}
+
+	s.logger.Info("Revoked API token", "token_id", id, "name", token.Name)
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "API token revoked",
+	})
+}
+
+func apiTokenResponse(token *storage.APIToken, secret string) APITokenResponse {
+	return APITokenResponse{
+		ID:         token.ID,
+		Name:       token.Name,
+		Scope:      token.Scope,
+		Token:      secret,
+		CreatedAt:  token.CreatedAt,
+		LastUsedAt: token.LastUsedAt,
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func tokenRequest(handler http.Handler, method, path, token string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func createToken(t *testing.T, handler http.Handler, cookie *http.Cookie, name, scope string) APITokenResponse {
+	t.Helper()
+	body, _ := json.Marshal(CreateAPITokenRequest{Name: name, Scope: scope})
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/tokens", strings.NewReader(string(body)))
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create %s token: %d %s", scope, w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data APITokenResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp.Data
+}
+
+func TestAPITokens(t *testing.T) {
+	server, _ := newAuthTestRouter(t, config.AuthConfig{Mode: "password", Password: "correct horse", ProtectReads: true})
+	ok := func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) }
+	server.router.Get("/api/auth/tokens", server.handleListAPITokens)
+	server.router.Post("/api/auth/tokens", server.handleCreateAPIToken)
+	server.router.Delete("/api/auth/tokens/{id}", server.handleDeleteAPIToken)
+	server.router.Post("/api/settings", ok)
+	handler := server.router
+
+	// Managing tokens needs a sign-in, even just to list them
+	if w := tokenRequest(handler, http.MethodGet, "/api/auth/tokens", "", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected listing tokens to need a sign-in, got %d", w.Code)
+	}
+	cookie := sessionCookieFrom(apiLogin(handler, "", "correct horse"))
+
+	read := createToken(t, handler, cookie, "Dashboard", "read")
+	queue := createToken(t, handler, cookie, "Scripts", "queue")
+	admin := createToken(t, handler, cookie, "Backups", "admin")
+	if !strings.HasPrefix(read.Token, tokenPrefix) || read.ID == "" {
+		t.Fatalf("Expected the new token's secret, got %+v", read)
+	}
+
+	// Each scope allows what the ones below it do
+	cases := []struct {
+		token  string
+		method string
+		path   string
+		want   int
+	}{
+		{read.Token, http.MethodGet, "/api/status", http.StatusOK},
+		{read.Token, http.MethodPost, "/api/queue/add", http.StatusForbidden},
+		{queue.Token, http.MethodPost, "/api/queue/add", http.StatusOK},
+		{queue.Token, http.MethodPost, "/api/settings", http.StatusForbidden},
+		{queue.Token, http.MethodGet, "/api/auth/tokens", http.StatusForbidden},
+		{admin.Token, http.MethodPost, "/api/settings", http.StatusOK},
+		{admin.Token, http.MethodGet, "/api/auth/tokens", http.StatusOK},
+		{read.Token + "0", http.MethodGet, "/api/status", http.StatusUnauthorized},
+		{tokenPrefix + "unknown_secret", http.MethodGet, "/api/status", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		if w := tokenRequest(handler, tc.method, tc.path, tc.token, ""); w.Code != tc.want {
+			t.Errorf("%s %s with %.12s...: expected %d, got %d", tc.method, tc.path, tc.token, tc.want, w.Code)
+		}
+	}
+
+	// Secrets are never listed again, but use is recorded
+	w := tokenRequest(handler, http.MethodGet, "/api/auth/tokens", admin.Token, "")
+	var listed struct {
+		Data []APITokenResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &listed)
+	if len(listed.Data) != 3 {
+		t.Fatalf("Expected 3 tokens, got %d", len(listed.Data))
+	}
+	for _, token := range listed.Data {
+		if token.Token != "" {
+			t.Errorf("Expected %s to be listed without its secret", token.Name)
+		}
+		if token.LastUsedAt.IsZero() {
+			t.Errorf("Expected %s to have been used", token.Name)
+		}
+	}
+
+	if w := tokenRequest(handler, http.MethodPost, "/api/auth/tokens", admin.Token, `{"name":"Bad","scope":"root"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown scope to be rejected, got %d", w.Code)
+	}
+
+	// Revoked tokens stop working at once
+	if w := tokenRequest(handler, http.MethodDelete, "/api/auth/tokens/"+read.ID, admin.Token, ""); w.Code != http.StatusOK {
+		t.Fatalf("Failed to revoke token: %d", w.Code)
+	}
+	if w := tokenRequest(handler, http.MethodGet, "/api/status", read.Token, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a revoked token to be refused, got %d", w.Code)
+	}
+	if w := tokenRequest(handler, http.MethodDelete, "/api/auth/tokens/"+read.ID, admin.Token, ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected revoking twice to find nothing, got %d", w.Code)
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+)
+
+// TransfersResponse describes what is downloading now and how much was
+// downloaded over the last day, for a downloads page.
+type TransfersResponse struct {
+	Active        []TransferInfo                `json:"active"`
+	Speed         int64                         `json:"speed"` // Combined bytes per second of the active downloads
+	History       []downloader.ThroughputBucket `json:"history"`
+	BucketSeconds int                           `json:"bucket_seconds"`
+}
+
+// TransferInfo is an active download with its display title and derived
+// figures.
+type TransferInfo struct {
+	downloader.ActiveDownload
+	Title      string  `json:"title"`
+	Percent    float64 `json:"percent"`
+	ETASeconds int64   `json:"eta_seconds,omitempty"` // Omitted when not known
+}
+
+// handleTransfers returns the active downloads, most urgent first, with
+// their progress, speed and retries, and the throughput of the last 24
+// hours in ThroughputBucketSize intervals, oldest first.
+func (s *Server) handleTransfers(w http.ResponseWriter, r *http.Request) {
+	if s.downloadManager == nil {
+		s.writeErrorResponse(w, http.StatusServiceUnavailable, "Download manager not available", nil)
+		return
+	}
+
+	response := TransfersResponse{
+		Active:        []TransferInfo{},
+		History:       s.downloadManager.ThroughputHistory(),
+		BucketSeconds: int(downloader.ThroughputBucketSize.Seconds()),
+	}
+	for _, download := range s.downloadManager.ActiveDownloads() {
+		response.Active = append(response.Active, TransferInfo{
+			ActiveDownload: download,
+			Title:          s.widgetTitle(download.MediaID),
+			Percent:        roundTenth(download.Percent()),
+			ETASeconds:     int64(download.ETA().Seconds()),
+		})
+		response.Speed += download.Speed
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    response,
+	})
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestHandleTransfers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	w := httptest.NewRecorder()
+	newTestServer(t, &Server{logger: logger}).handleTransfers(w, httptest.NewRequest(http.MethodGet, "/api/transfers", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 without a download manager, got %d", w.Code)
+	}
+
+	sm := newTestStorage(t, t.TempDir())
+	dm := downloader.New(&config.DownloadConfig{Workers: 1, RateLimitMbps: 10}, sm, logger)
+	server := newTestServer(t, &Server{logger: logger, storage: sm, downloadManager: dm})
+
+	w = httptest.NewRecorder()
+	server.handleTransfers(w, httptest.NewRequest(http.MethodGet, "/api/transfers", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if string(resp.Data["active"]) != "[]" {
+		t.Errorf("Expected an empty list when idle, got %s", resp.Data["active"])
+	}
+	var history []downloader.ThroughputBucket
+	if err := json.Unmarshal(resp.Data["history"], &history); err != nil || len(history) != 288 {
+		t.Errorf("Expected 288 five-minute intervals, got %d (%v)", len(history), err)
+	}
+	if string(resp.Data["bucket_seconds"]) != "300" {
+		t.Errorf("Expected 300 second intervals, got %s", resp.Data["bucket_seconds"])
+	}
+}
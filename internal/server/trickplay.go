@@ -0,0 +1,128 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// handleTrickplay serves the seek previews of a cached video as a WebVTT
+// track whose cues point at thumbnail images: regions of trickplay sprite
+// sheets (#xywh=x,y,w,h) or whole chapter images. Items without cached
+// previews get a 404, so the player shows none rather than asking Jellyfin.
+func (s *Server) handleTrickplay(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	record, err := s.library.GetDownload(id)
+	if err != nil || record.LocalPath == "" {
+		s.writeErrorResponse(w, http.StatusNotFound, "Item not cached", nil)
+		return
+	}
+	manifest, err := storage.ReadTrickplayManifest(record.LocalPath)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to read seek previews", err)
+		return
+	}
+	if manifest == nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "No cached seek previews", nil)
+		return
+	}
+
+	base := "/stream/" + url.PathEscape(id) + "/trickplay/"
+	var vtt strings.Builder
+	vtt.WriteString("WEBVTT\n")
+
+	if tiles := manifest.Tiles; tiles != nil {
+		perSheet := tiles.Columns * tiles.Rows
+		for i := 0; i < tiles.ThumbnailCount && perSheet > 0; i++ {
+			pos := i % perSheet
+			fmt.Fprintf(&vtt, "\n%s --> %s\n%s%s#xywh=%d,%d,%d,%d\n",
+				vttTimestamp(time.Duration(i)*tiles.Interval),
+				vttTimestamp(time.Duration(i+1)*tiles.Interval),
+				base, storage.TrickplayTileName(i/perSheet),
+				pos%tiles.Columns*tiles.Width, pos/tiles.Columns*tiles.Height,
+				tiles.Width, tiles.Height)
+		}
+	} else {
+		end := s.runtime(id)
+		for i, chapter := range manifest.Chapters {
+			// The last chapter runs to the end, or an hour when that is
+			// not known
+			until := end
+			if i+1 < len(manifest.Chapters) {
+				until = manifest.Chapters[i+1].Start
+			} else if until <= chapter.Start {
+				until = chapter.Start + time.Hour
+			}
+			fmt.Fprintf(&vtt, "\n%s --> %s\n%s%s\n",
+				vttTimestamp(chapter.Start), vttTimestamp(until), base, chapter.Image)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.Write([]byte(vtt.String()))
+}
+
+// handleTrickplayImage serves one cached seek preview image.
+func (s *Server) handleTrickplayImage(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	name := chi.URLParam(r, "file")
+	if name != filepath.Base(name) || !strings.HasSuffix(name, ".jpg") {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid image name", nil)
+		return
+	}
+
+	record, err := s.library.GetDownload(id)
+	if err != nil || record.LocalPath == "" {
+		s.writeErrorResponse(w, http.StatusNotFound, "Item not cached", nil)
+		return
+	}
+
+	file, err := os.Open(filepath.Join(storage.TrickplayDir(record.LocalPath), name))
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "No such seek preview", nil)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to read image", err)
+		return
+	}
+
+	// Previews of a cached file never change
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
+
+// runtime returns the length of an item from its stored metadata, or 0 if
+// it is not known. Jellyfin's runtime is preferred; the inspected length
+// of the cached file covers items Jellyfin has none for.
+func (s *Server) runtime(id string) time.Duration {
+	metadata, err := s.library.GetMediaMetadata(id)
+	if err != nil || metadata == nil {
+		return 0
+	}
+	if metadata.RunTimeTicks == 0 {
+		if info := metadata.MediaInfo(); info != nil {
+			return info.Duration()
+		}
+	}
+	return time.Duration(metadata.RunTimeTicks) * 100
+}
+
+// vttTimestamp formats d as a WebVTT timestamp, HH:MM:SS.mmm.
+func vttTimestamp(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+func trickplayRequest(server *Server, id, file string) *httptest.ResponseRecorder {
+	path := "/stream/" + id + "/trickplay"
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	if file != "" {
+		path += "/" + file
+		rctx.URLParams.Add("file", file)
+	}
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+	if file != "" {
+		server.handleTrickplayImage(w, req)
+	} else {
+		server.handleTrickplay(w, req)
+	}
+	return w
+}
+
+func TestHandleTrickplay(t *testing.T) {
+	server := newShareTestServer(t)
+
+	if w := trickplayRequest(server, "v1", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 without cached previews, got %d", w.Code)
+	}
+
+	record, err := server.storage.GetDownload("v1")
+	if err != nil {
+		t.Fatalf("Failed to get download: %v", err)
+	}
+	files := storage.NewFileManager(t.TempDir(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := files.WriteFileAtomic(filepath.Join(storage.TrickplayDir(record.LocalPath), storage.TrickplayTileName(1)), []byte("sheet")); err != nil {
+		t.Fatalf("Failed to write tile: %v", err)
+	}
+	if err := files.WriteTrickplayManifest(record.LocalPath, &storage.TrickplayManifest{Tiles: &storage.TrickplayTiles{
+		Width: 320, Height: 180, Columns: 2, Rows: 2, ThumbnailCount: 5, Interval: 10 * time.Second, Sheets: 2,
+	}}); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	w := trickplayRequest(server, "v1", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/vtt; charset=utf-8" {
+		t.Fatalf("Expected a WebVTT track, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	vtt := w.Body.String()
+	for _, want := range []string{
+		"WEBVTT\n",
+		"00:00:30.000 --> 00:00:40.000\n/stream/v1/trickplay/tile-0.jpg#xywh=320,180,320,180\n",
+		"00:00:40.000 --> 00:00:50.000\n/stream/v1/trickplay/tile-1.jpg#xywh=0,0,320,180\n",
+	} {
+		if !strings.Contains(vtt, want) {
+			t.Errorf("Expected the track to contain %q, got:\n%s", want, vtt)
+		}
+	}
+
+	if w := trickplayRequest(server, "v1", "tile-1.jpg"); w.Code != http.StatusOK || w.Body.String() != "sheet" {
+		t.Errorf("Expected the cached tile, got %d %q", w.Code, w.Body.String())
+	}
+	if w := trickplayRequest(server, "v1", "manifest.json"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for the manifest, got %d", w.Code)
+	}
+}
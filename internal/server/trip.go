@@ -0,0 +1,104 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+)
+
+// TripRequest starts trip mode. Favorites defaults to true.
+type TripRequest struct {
+	Until     time.Time `json:"until"`
+	Episodes  int       `json:"episodes,omitempty"`
+	Favorites *bool     `json:"favorites,omitempty"`
+	MaxSizeGB int       `json:"max_size_gb,omitempty"`
+}
+
+// SetTripMode sets the trip mode behind /api/trip.
+func (s *Server) SetTripMode(trip *downloader.TripMode) {
+	s.trip = trip
+}
+
+// handleGetTrip returns the trip under way, or null data if there is none.
+func (s *Server) handleGetTrip(w http.ResponseWriter, r *http.Request) {
+	if s.trip == nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "Trip mode is not enabled", nil)
+		return
+	}
+
+	trip, err := s.trip.Current()
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to get trip", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    trip,
+	})
+}
+
+// handleStartTrip starts a trip, replacing any under way, and queues the
+// next episodes of active series and favorite movies.
+func (s *Server) handleStartTrip(w http.ResponseWriter, r *http.Request) {
+	if s.trip == nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "Trip mode is not enabled", nil)
+		return
+	}
+
+	var req TripRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if !req.Until.After(time.Now()) {
+		s.writeErrorResponse(w, http.StatusBadRequest, "until must be in the future", nil)
+		return
+	}
+	if req.Episodes < 0 || req.MaxSizeGB < 0 {
+		s.writeErrorResponse(w, http.StatusBadRequest, "episodes and max_size_gb must not be negative", nil)
+		return
+	}
+
+	favorites := true
+	if req.Favorites != nil {
+		favorites = *req.Favorites
+	}
+
+	trip, err := s.trip.Start(r.Context(), downloader.TripOptions{
+		Until:     req.Until,
+		Episodes:  req.Episodes,
+		Favorites: favorites,
+		MaxSizeGB: req.MaxSizeGB,
+	})
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusBadGateway, "Failed to start trip", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "Trip started",
+		Data:    trip,
+	})
+}
+
+// handleEndTrip ends the trip early, restoring the normal cache limits.
+func (s *Server) handleEndTrip(w http.ResponseWriter, r *http.Request) {
+	if s.trip == nil {
+		s.writeErrorResponse(w, http.StatusNotFound, "Trip mode is not enabled", nil)
+		return
+	}
+
+	if err := s.trip.End("ended early"); err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to end trip", err)
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "Trip ended",
+	})
+}
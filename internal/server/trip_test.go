@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestHandleTrip(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	server := newTestServer(t, &Server{logger: logger})
+
+	call := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/trip", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		switch method {
+		case http.MethodGet:
+			server.handleGetTrip(w, req)
+		case http.MethodPost:
+			server.handleStartTrip(w, req)
+		case http.MethodDelete:
+			server.handleEndTrip(w, req)
+		}
+		return w
+	}
+
+	if w := call(http.MethodGet, ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without trip mode, got %d", w.Code)
+	}
+
+	store, err := storage.NewManager(&config.CacheConfig{Directory: t.TempDir(), MaxSizeGB: 1, MetadataStore: "boltdb"}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	source := jellyfintest.New("http://jellyfin.test")
+	source.Favorites = []jellyfin.MediaItem{{ID: "m1", Name: "Heat", Type: "Movie"}}
+	queue := downloader.New(&config.DownloadConfig{Workers: 1}, store, logger)
+	predictor := downloader.NewPredictor(store, &config.PredictionConfig{HistoryDays: 30}, logger)
+	server.SetTripMode(downloader.NewTripMode(predictor, source, store, queue, nil, "user", logger))
+
+	until := time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)
+	for _, body := range []string{`{}`, `{"until": "2001-01-01T00:00:00Z"}`, `{"until": "` + until + `", "episodes": -1}`, `not json`} {
+		if w := call(http.MethodPost, body); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+
+	w := call(http.MethodPost, `{"until": "`+until+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data *storage.Trip `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Data == nil || !resp.Data.Favorites || len(resp.Data.MediaIDs) != 1 || resp.Data.MediaIDs[0] != "m1" {
+		t.Errorf("Expected a trip caching the favorite movie, got %+v", resp.Data)
+	}
+
+	if w := call(http.MethodDelete, ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 ending the trip, got %d", w.Code)
+	}
+	w = call(http.MethodGet, "")
+	resp.Data = nil
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if w.Code != http.StatusOK || resp.Data != nil {
+		t.Errorf("Expected no trip after ending it, got %d %+v", w.Code, resp.Data)
+	}
+}
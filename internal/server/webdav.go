@@ -0,0 +1,359 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/net/webdav"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// webdavTreeTTL bounds how stale the virtual WebDAV tree may be. Clients
+// issue bursts of PROPFIND requests, so rebuilding per request is wasteful.
+const webdavTreeTTL = 10 * time.Second
+
+func init() {
+	// chi rejects methods it does not know about with 405
+	for _, method := range []string{"PROPFIND", "PROPPATCH", "MKCOL", "COPY", "MOVE", "LOCK", "UNLOCK"} {
+		chi.RegisterMethod(method)
+	}
+}
+
+// newWebDAVHandler returns the read-only WebDAV handler for the cache,
+// wrapped in HTTP basic authentication.
+func (s *Server) newWebDAVHandler() http.Handler {
+	cfg := s.config.WebDAV
+
+	handler := &webdav.Handler{
+		Prefix:     cfg.Path,
+		FileSystem: &cacheFS{storage: s.storage},
+		LockSystem: webdav.NewMemLS(),
+		Logger: func(r *http.Request, err error) {
+			if err != nil {
+				s.logger.Debug("WebDAV request failed",
+					"method", r.Method, "path", r.URL.Path, "error", err)
+			}
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(username), []byte(cfg.Username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(password), []byte(cfg.Password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="go-jf-watch cache"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// cacheFS is a read-only webdav.FileSystem presenting cached downloads in a
+// browsable layout built from their metadata:
+//
+//	/Movies/{title}.{ext}
+//	/Shows/{series}/Season {NN}/{title}.{ext}
+//	/Other/{title}.{ext}
+type cacheFS struct {
+	storage *storage.Manager
+
+	mu      sync.Mutex
+	tree    *davNode
+	builtAt time.Time
+}
+
+// davNode is a file or directory in the virtual WebDAV tree.
+type davNode struct {
+	name      string
+	localPath string // empty for directories
+	size      int64
+	modTime   time.Time
+	children  map[string]*davNode
+}
+
+// Mkdir is not permitted on the read-only cache share.
+func (c *cacheFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	return os.ErrPermission
+}
+
+// RemoveAll is not permitted on the read-only cache share.
+func (c *cacheFS) RemoveAll(ctx context.Context, name string) error {
+	return os.ErrPermission
+}
+
+// Rename is not permitted on the read-only cache share.
+func (c *cacheFS) Rename(ctx context.Context, oldName, newName string) error {
+	return os.ErrPermission
+}
+
+// OpenFile opens a virtual directory or the cached file behind a virtual path.
+func (c *cacheFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, os.ErrPermission
+	}
+
+	node, err := c.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.children != nil {
+		return &davDir{node: node}, nil
+	}
+
+	file, err := storage.OpenCached(node.localPath, c.storage.Cipher())
+	if err != nil {
+		return nil, err
+	}
+	return &davFile{CachedFile: file, node: node}, nil
+}
+
+// Stat returns file info for a virtual path.
+func (c *cacheFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	node, err := c.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return node.info(), nil
+}
+
+// lookup resolves a slash-separated virtual path against the current tree.
+func (c *cacheFS) lookup(name string) (*davNode, error) {
+	root, err := c.root()
+	if err != nil {
+		return nil, err
+	}
+
+	node := root
+	for _, part := range strings.Split(strings.Trim(path.Clean("/"+name), "/"), "/") {
+		if part == "" {
+			continue
+		}
+		child, ok := node.children[part]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		node = child
+	}
+	return node, nil
+}
+
+// root returns the virtual tree, rebuilding it from download records when stale.
+func (c *cacheFS) root() (*davNode, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.tree != nil && time.Since(c.builtAt) < webdavTreeTTL {
+		return c.tree, nil
+	}
+
+	records, err := c.storage.ListDownloadRecords("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cached downloads: %w", err)
+	}
+
+	root := newDavDir("/")
+	for _, record := range records {
+		if record.Status != "" && record.Status != "completed" {
+			continue
+		}
+
+		dir := root
+		for _, part := range c.directoryFor(record) {
+			dir = dir.subdir(part)
+		}
+
+		name := sanitizeDavName(record.Title)
+		if name == "" {
+			name = record.JellyfinID
+		}
+		name += filepath.Ext(record.LocalPath)
+		if _, exists := dir.children[name]; exists {
+			// Disambiguate identically titled items
+			name = strings.TrimSuffix(name, filepath.Ext(name)) + " (" + record.JellyfinID + ")" + filepath.Ext(name)
+		}
+
+		dir.children[name] = &davNode{
+			name:      name,
+			localPath: record.LocalPath,
+			size:      record.Size,
+			modTime:   record.DownloadedAt,
+		}
+	}
+
+	c.tree = root
+	c.builtAt = time.Now()
+	return root, nil
+}
+
+// directoryFor returns the virtual directory path for a download record,
+// using series or album metadata for episodes, music and audiobooks when
+// available.
+func (c *cacheFS) directoryFor(record *storage.DownloadRecord) []string {
+	switch record.MediaType {
+	case "movie":
+		return []string{"Movies"}
+	case "episode":
+		series := "Unknown Series"
+		season := 0
+		if metadata, err := c.storage.GetMediaMetadata(record.JellyfinID); err == nil {
+			season = metadata.SeasonNumber
+			if seriesMeta, err := c.storage.GetMediaMetadata(metadata.SeriesID); err == nil && seriesMeta.Name != "" {
+				series = sanitizeDavName(seriesMeta.Name)
+			} else if metadata.SeriesID != "" {
+				series = metadata.SeriesID
+			}
+		}
+		return []string{"Shows", series, fmt.Sprintf("Season %02d", season)}
+	case "audio", "audiobook":
+		root := "Music"
+		if record.MediaType == "audiobook" {
+			root = "Audiobooks"
+		}
+		album := "Unknown Album"
+		if metadata, err := c.storage.GetMediaMetadata(record.JellyfinID); err == nil && metadata.AlbumID != "" {
+			album = metadata.AlbumID
+			if albumMeta, err := c.storage.GetMediaMetadata(metadata.AlbumID); err == nil && albumMeta.Name != "" {
+				album = sanitizeDavName(albumMeta.Name)
+			}
+		}
+		return []string{root, album}
+	default:
+		return []string{"Other"}
+	}
+}
+
+// sanitizeDavName strips path separators so a title cannot create
+// unintended directory levels.
+func sanitizeDavName(name string) string {
+	return strings.TrimSpace(strings.NewReplacer("/", "-", "\\", "-").Replace(name))
+}
+
+func newDavDir(name string) *davNode {
+	return &davNode{name: name, modTime: time.Now(), children: make(map[string]*davNode)}
+}
+
+// subdir returns the named child directory, creating it if needed.
+func (n *davNode) subdir(name string) *davNode {
+	child, ok := n.children[name]
+	if !ok {
+		child = newDavDir(name)
+		n.children[name] = child
+	}
+	return child
+}
+
+func (n *davNode) info() os.FileInfo {
+	return davFileInfo{node: n}
+}
+
+// davFileInfo implements os.FileInfo for virtual nodes.
+type davFileInfo struct {
+	node *davNode
+}
+
+func (i davFileInfo) Name() string       { return i.node.name }
+func (i davFileInfo) Size() int64        { return i.node.size }
+func (i davFileInfo) ModTime() time.Time { return i.node.modTime }
+func (i davFileInfo) IsDir() bool        { return i.node.children != nil }
+func (i davFileInfo) Sys() interface{}   { return nil }
+
+func (i davFileInfo) Mode() fs.FileMode {
+	if i.IsDir() {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// davFile is a cached media file exposed under its virtual name.
+// Reads and seeks go to the underlying file, so GET supports Range requests.
+type davFile struct {
+	storage.CachedFile
+	node *davNode
+}
+
+func (f *davFile) Stat() (os.FileInfo, error) {
+	info, err := f.CachedFile.Stat()
+	if err != nil {
+		return nil, err
+	}
+	// Report the on-disk size without mutating the shared tree
+	node := *f.node
+	node.size = info.Size()
+	return node.info(), nil
+}
+
+func (f *davFile) Write(p []byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+func (f *davFile) Readdir(count int) ([]fs.FileInfo, error) {
+	return nil, os.ErrInvalid
+}
+
+// davDir is an open virtual directory.
+type davDir struct {
+	node   *davNode
+	offset int
+}
+
+func (d *davDir) Close() error { return nil }
+
+func (d *davDir) Read(p []byte) (int, error) {
+	return 0, os.ErrInvalid
+}
+
+func (d *davDir) Seek(offset int64, whence int) (int64, error) {
+	return 0, os.ErrInvalid
+}
+
+func (d *davDir) Write(p []byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+func (d *davDir) Stat() (os.FileInfo, error) {
+	return d.node.info(), nil
+}
+
+// Readdir returns directory entries sorted by name, following os.File
+// semantics for count.
+func (d *davDir) Readdir(count int) ([]fs.FileInfo, error) {
+	names := make([]string, 0, len(d.node.children))
+	for name := range d.node.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if d.offset >= len(names) {
+		if count > 0 {
+			return nil, io.EOF
+		}
+		return nil, nil
+	}
+
+	names = names[d.offset:]
+	if count > 0 && count < len(names) {
+		names = names[:count]
+	}
+	d.offset += len(names)
+
+	infos := make([]fs.FileInfo, len(names))
+	for i, name := range names {
+		infos[i] = d.node.children[name].info()
+	}
+	return infos, nil
+}
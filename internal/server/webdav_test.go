@@ -0,0 +1,115 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func createWebDAVTestServer(t *testing.T) *Server {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	tmpDir := t.TempDir()
+	storageManager := newTestStorage(t, tmpDir)
+
+	moviePath := filepath.Join(tmpDir, "movies", "m1", "m1.mkv")
+	episodePath := filepath.Join(tmpDir, "series", "e1", "e1.mp4")
+	for _, p := range []string{moviePath, episodePath} {
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(p, []byte("0123456789"), 0644); err != nil {
+			t.Fatalf("Failed to create media file: %v", err)
+		}
+	}
+
+	records := []*storage.DownloadRecord{
+		{ID: "m1", MediaType: "movie", JellyfinID: "m1", Title: "Heat", LocalPath: moviePath, Size: 10, Status: "completed", DownloadedAt: time.Now()},
+		{ID: "e1", MediaType: "episode", JellyfinID: "e1", Title: "Pilot", LocalPath: episodePath, Size: 10, Status: "completed", DownloadedAt: time.Now()},
+	}
+	for _, record := range records {
+		if err := storageManager.AddDownloadRecord(record); err != nil {
+			t.Fatalf("Failed to add download record: %v", err)
+		}
+	}
+	storageManager.AddMediaMetadata(&storage.MediaMetadata{ID: "s1", JellyfinID: "s1", Name: "Lost", Type: "Series"})
+	storageManager.AddMediaMetadata(&storage.MediaMetadata{ID: "e1", JellyfinID: "e1", Name: "Pilot", Type: "Episode", SeriesID: "s1", SeasonNumber: 1})
+
+	return newTestServer(t, &Server{
+		config: &config.ServerConfig{
+			WebDAV: config.WebDAVConfig{Enabled: true, Path: "/dav", Username: "kodi", Password: "secret"},
+		},
+		storage: storageManager,
+		logger:  logger,
+	})
+}
+
+func TestWebDAVExport(t *testing.T) {
+	server := createWebDAVTestServer(t)
+	handler := server.newWebDAVHandler()
+
+	do := func(method, target string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.SetBasicAuth("kodi", "secret")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("requires authentication", func(t *testing.T) {
+		req := httptest.NewRequest("PROPFIND", "/dav/", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401, got %d", w.Code)
+		}
+	})
+
+	t.Run("lists metadata-driven layout", func(t *testing.T) {
+		w := do("PROPFIND", "/dav/Movies/", map[string]string{"Depth": "1"})
+		if w.Code != http.StatusMultiStatus {
+			t.Fatalf("Expected status 207, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "/dav/Movies/Heat.mkv") {
+			t.Errorf("Expected Heat.mkv in listing, got %s", w.Body.String())
+		}
+
+		w = do("PROPFIND", "/dav/Shows/Lost/Season%2001/", map[string]string{"Depth": "1"})
+		if w.Code != http.StatusMultiStatus {
+			t.Fatalf("Expected status 207, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "Pilot.mp4") {
+			t.Errorf("Expected Pilot.mp4 in listing, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("serves range requests", func(t *testing.T) {
+		w := do(http.MethodGet, "/dav/Movies/Heat.mkv", map[string]string{"Range": "bytes=2-5"})
+		if w.Code != http.StatusPartialContent {
+			t.Fatalf("Expected status 206, got %d", w.Code)
+		}
+		if w.Body.String() != "2345" {
+			t.Errorf("Expected body %q, got %q", "2345", w.Body.String())
+		}
+	})
+
+	t.Run("rejects writes", func(t *testing.T) {
+		for _, method := range []string{http.MethodPut, http.MethodDelete, "MKCOL"} {
+			w := do(method, "/dav/Movies/New.mkv", nil)
+			if w.Code < 400 {
+				t.Errorf("%s: expected an error status, got %d", method, w.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+)
+
+// webhookTokenHeader carries server.webhook_token on webhook requests.
+const webhookTokenHeader = "X-Webhook-Token"
+
+// SetSessionSyncer sets the syncer that records playback pushed by the
+// Jellyfin webhook plugin.
+func (s *Server) SetSessionSyncer(syncer *downloader.SessionSyncer) {
+	s.sessions = syncer
+}
+
+// SetPlaybackTracker sets the tracker that records how far streams of
+// cached items get as viewing history.
+func (s *Server) SetPlaybackTracker(tracker *downloader.PlaybackTracker) {
+	s.playback = tracker
+}
+
+// handleJellyfinWebhook accepts playback notifications from the Jellyfin
+// webhook plugin, so starts, progress and stops on any client reach
+// prediction and viewing history as they happen rather than at the next
+// session sync. Other notification types are acknowledged and ignored.
+func (s *Server) handleJellyfinWebhook(w http.ResponseWriter, r *http.Request) {
+	if token := s.config.WebhookToken; token != "" &&
+		subtle.ConstantTimeCompare([]byte(r.Header.Get(webhookTokenHeader)), []byte(token)) != 1 {
+		s.writeErrorResponse(w, http.StatusUnauthorized, "Invalid webhook token", nil)
+		return
+	}
+
+	if s.sessions == nil {
+		s.writeErrorResponse(w, http.StatusServiceUnavailable, "Playback tracking is not enabled", nil)
+		return
+	}
+
+	event, err := jellyfin.ParseWebhookEvent(r.Body)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid webhook", err)
+		return
+	}
+
+	recorded, err := s.sessions.HandleWebhook(r.Context(), *event)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to record playback", err)
+		return
+	}
+
+	message := "Event ignored"
+	if recorded {
+		message = "Playback recorded"
+	}
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: message,
+	})
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin/jellyfintest"
+	"github.com/opd-ai/go-jf-watch/internal/storage/storagetest"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestHandleJellyfinWebhook(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := storagetest.New()
+	cfg := &config.PredictionConfig{HistoryDays: 30}
+	syncer := downloader.NewSessionSyncer(jellyfintest.New("http://jellyfin.local"), store, nil, []string{"alice"}, cfg, logger)
+
+	server := newTestServer(t, &Server{config: &config.ServerConfig{WebhookToken: "s3cret"}, logger: logger})
+
+	post := func(token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/webhooks/jellyfin", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set(webhookTokenHeader, token)
+		}
+		w := httptest.NewRecorder()
+		server.handleJellyfinWebhook(w, req)
+		return w
+	}
+	start := `{"NotificationType":"PlaybackStart","UserId":"alice","ItemId":"m1","ItemType":"Movie","RunTimeTicks":72000000000,"PlaybackPositionTicks":0}`
+
+	if w := post("s3cret", start); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a session syncer, got %d", w.Code)
+	}
+
+	server.SetSessionSyncer(syncer)
+	tests := []struct {
+		name       string
+		token      string
+		body       string
+		wantStatus int
+	}{
+		{"missing token", "", start, http.StatusUnauthorized},
+		{"wrong token", "guess", start, http.StatusUnauthorized},
+		{"invalid body", "s3cret", `{"NotificationType":`, http.StatusBadRequest},
+		{"ignored type", "s3cret", `{"NotificationType":"ItemAdded","ItemId":"m2"}`, http.StatusOK},
+		{"playback start", "s3cret", start, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := post(tt.token, tt.body); w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	history, err := store.GetViewingHistory("alice", 30)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 1 || history[0].MediaID != "m1" || history[0].MediaType != "movie" {
+		t.Errorf("Expected one movie session, got %+v", history)
+	}
+}
@@ -1,8 +1,11 @@
 package server
 
 import (
+	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -19,25 +22,64 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// wsReadLimit is the largest command a client may send, enough to
+// subscribe to a few hundred media IDs at once.
+const wsReadLimit = 16 * 1024
+
+// maxWSSubscriptions caps how many media IDs one client may subscribe to.
+const maxWSSubscriptions = 500
+
 // ProgressUpdate represents a real-time progress update message.
 type ProgressUpdate struct {
-	Type      string    `json:"type"` // download, cache, error
-	MediaID   string    `json:"media_id"`
-	Title     string    `json:"title,omitempty"`
-	Progress  float64   `json:"progress"`        // 0-100
-	Speed     int64     `json:"speed,omitempty"` // bytes per second
-	ETA       string    `json:"eta,omitempty"`   // estimated time remaining
-	Status    string    `json:"status"`          // queued, downloading, completed, failed
-	Message   string    `json:"message,omitempty"`
-	Timestamp time.Time `json:"timestamp"`
+	Type      string      `json:"type"` // download, cache, status, queue, command, error
+	MediaID   string      `json:"media_id"`
+	Title     string      `json:"title,omitempty"`
+	Progress  float64     `json:"progress"`        // 0-100
+	Speed     int64       `json:"speed,omitempty"` // bytes per second
+	ETA       string      `json:"eta,omitempty"`   // estimated time remaining
+	Status    string      `json:"status"`          // queued, downloading, completed, failed
+	Message   string      `json:"message,omitempty"`
+	Queue     []QueueItem `json:"queue,omitempty"` // set on queue snapshots
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// WebSocketCommand is a JSON command sent by a WebSocket client:
+//
+//	{"action": "subscribe", "media_ids": ["abc", "def"]}
+//	{"action": "unsubscribe", "media_ids": ["abc"]}
+//	{"action": "filter", "types": ["download", "error"]}
+//	{"action": "snapshot"}
+//
+// Until a client subscribes it receives updates for every media item, and
+// until it sets a filter it receives every update type. Unsubscribing from
+// the last media ID, or sending an empty filter, goes back to everything.
+// Each command is answered with a "command" update, or an "error" update
+// if it was rejected.
+type WebSocketCommand struct {
+	Action   string   `json:"action"` // subscribe, unsubscribe, filter, snapshot
+	MediaIDs []string `json:"media_ids,omitempty"`
+	Types    []string `json:"types,omitempty"`
 }
 
 // WebSocketClient represents a connected WebSocket client.
 type WebSocketClient struct {
 	conn   *websocket.Conn
-	send   chan ProgressUpdate
 	server *Server
 	logger *slog.Logger
+
+	// Updates waiting to be written
+	clientQueue
+
+	// Subscription state set by client commands
+	updateFilter
+}
+
+// updateFilter is the media items and update types a client receives;
+// empty means everything.
+type updateFilter struct {
+	mu       sync.Mutex
+	mediaIDs map[string]bool
+	types    map[string]bool
 }
 
 // handleWebSocket handles WebSocket connections for real-time progress updates.
@@ -52,16 +94,16 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 
 	// Create client
 	client := &WebSocketClient{
-		conn:   conn,
-		send:   make(chan ProgressUpdate, 256),
-		server: s,
-		logger: s.logger,
+		conn:        conn,
+		server:      s,
+		logger:      s.logger,
+		clientQueue: newClientQueue(clientBufferSize),
 	}
 
 	s.logger.Info("WebSocket client connected", "remote_addr", r.RemoteAddr)
 
 	// Register client
-	s.registerWSClient(client)
+	s.hub.register(client)
 
 	// Start client goroutines
 	go client.writePump()
@@ -72,13 +114,13 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 }
 
 // writePump handles sending messages to the WebSocket client.
-// Runs in a goroutine and manages connection cleanup on error.
+// Runs in a goroutine and closes the connection when the hub disconnects
+// the client or a write fails, which in turn stops readPump.
 func (c *WebSocketClient) writePump() {
 	ticker := time.NewTicker(54 * time.Second)
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
-		c.server.unregisterWSClient(c)
 		c.logger.Debug("WebSocket write pump stopped")
 	}()
 
@@ -89,7 +131,7 @@ func (c *WebSocketClient) writePump() {
 			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 
 			if !ok {
-				// Channel closed
+				// Disconnected by the hub
 				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
@@ -116,12 +158,12 @@ func (c *WebSocketClient) writePump() {
 func (c *WebSocketClient) readPump() {
 	defer func() {
 		c.conn.Close()
-		close(c.send)
+		c.server.hub.unregister(c)
 		c.logger.Debug("WebSocket read pump stopped")
 	}()
 
 	// Set connection limits
-	c.conn.SetReadLimit(512)
+	c.conn.SetReadLimit(wsReadLimit)
 	c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
@@ -152,74 +194,218 @@ func (c *WebSocketClient) readPump() {
 }
 
 // handleTextMessage processes text messages from WebSocket clients.
-// Supports commands like subscribe/unsubscribe from specific media updates.
+// Messages are WebSocketCommands that subscribe to specific media items,
+// filter update types or request a queue snapshot.
 func (c *WebSocketClient) handleTextMessage(message []byte) {
 	c.logger.Debug("WebSocket message received", "message", string(message))
 
-	// TODO: Implement client commands when needed
-	// Examples: subscribe to specific media ID, request status update, etc.
+	var cmd WebSocketCommand
+	if err := json.Unmarshal(message, &cmd); err != nil {
+		c.sendCommandError("invalid command: " + err.Error())
+		return
+	}
+
+	switch cmd.Action {
+	case "subscribe":
+		if len(cmd.MediaIDs) == 0 {
+			c.sendCommandError("subscribe requires media_ids")
+			return
+		}
+		count, err := c.subscribe(cmd.MediaIDs)
+		if err != nil {
+			c.sendCommandError(err.Error())
+			return
+		}
+		c.sendCommandResult(cmd.Action, fmt.Sprintf("Subscribed to %d media items", count))
+	case "unsubscribe":
+		count := c.unsubscribe(cmd.MediaIDs)
+		if count == 0 {
+			c.sendCommandResult(cmd.Action, "Receiving updates for all media items")
+			return
+		}
+		c.sendCommandResult(cmd.Action, fmt.Sprintf("Subscribed to %d media items", count))
+	case "filter":
+		c.setTypes(cmd.Types)
+		if len(cmd.Types) == 0 {
+			c.sendCommandResult(cmd.Action, "Receiving all update types")
+			return
+		}
+		c.sendCommandResult(cmd.Action, fmt.Sprintf("Receiving %d update types", len(cmd.Types)))
+	case "snapshot":
+		c.sendQueueSnapshot()
+	default:
+		c.sendCommandError(fmt.Sprintf("unknown action %q", cmd.Action))
+	}
+}
+
+// subscribe adds media IDs to the filter's subscriptions and returns how
+// many it is subscribed to.
+func (c *updateFilter) subscribe(mediaIDs []string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.mediaIDs == nil {
+		c.mediaIDs = make(map[string]bool)
+	}
+	added := 0
+	for _, id := range mediaIDs {
+		if id != "" && !c.mediaIDs[id] {
+			added++
+		}
+	}
+	if len(c.mediaIDs)+added > maxWSSubscriptions {
+		return len(c.mediaIDs), fmt.Errorf("at most %d media items may be subscribed to", maxWSSubscriptions)
+	}
+	for _, id := range mediaIDs {
+		if id != "" {
+			c.mediaIDs[id] = true
+		}
+	}
+	return len(c.mediaIDs), nil
+}
+
+// unsubscribe removes media IDs from the filter's subscriptions, or all of
+// them when none are given, and returns how many remain.
+func (c *updateFilter) unsubscribe(mediaIDs []string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(mediaIDs) == 0 {
+		c.mediaIDs = nil
+		return 0
+	}
+	for _, id := range mediaIDs {
+		delete(c.mediaIDs, id)
+	}
+	return len(c.mediaIDs)
+}
+
+// setTypes limits the filter to the given update types, or lifts the limit
+// when none are given.
+func (c *updateFilter) setTypes(types []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.types = nil
+	for _, t := range types {
+		if c.types == nil {
+			c.types = make(map[string]bool)
+		}
+		c.types[t] = true
+	}
+}
+
+// wants reports whether a broadcast update matches the filter's
+// subscriptions. Updates not about a media item, such as status updates,
+// only go through the type filter.
+func (c *updateFilter) wants(update ProgressUpdate) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.types) > 0 && !c.types[update.Type] {
+		return false
+	}
+	if len(c.mediaIDs) > 0 && update.MediaID != "" && !c.mediaIDs[update.MediaID] {
+		return false
+	}
+	return true
+}
+
+// sendQueueSnapshot sends the whole download queue to the client.
+func (c *WebSocketClient) sendQueueSnapshot() {
+	if c.server.downloadManager == nil {
+		c.sendCommandError("download queue is not available")
+		return
+	}
+	queueData, err := c.server.downloadManager.GetQueueItems()
+	if err != nil {
+		c.logger.Error("Failed to get queue for WebSocket snapshot", "error", err)
+		c.sendCommandError("failed to get queue")
+		return
+	}
+
+	queueItems := make([]QueueItem, 0, len(queueData))
+	for _, item := range queueData {
+		queueItems = append(queueItems, QueueItem{
+			ID:       item.ID,
+			MediaID:  item.MediaID,
+			Title:    c.server.getMediaTitle(item.MediaID),
+			Priority: item.Priority,
+			Status:   item.Status,
+			Progress: item.Progress,
+			AddedAt:  item.CreatedAt,
+			Size:     item.Size,
+		})
+	}
+
+	c.SendProgress(ProgressUpdate{
+		Type:    "queue",
+		Status:  "snapshot",
+		Message: fmt.Sprintf("%d queue items", len(queueItems)),
+		Queue:   queueItems,
+	})
+}
+
+// sendCommandResult acknowledges a client command.
+func (c *WebSocketClient) sendCommandResult(action, message string) {
+	c.SendProgress(ProgressUpdate{Type: "command", Status: action, Message: message})
+}
+
+// sendCommandError tells the client its command was rejected.
+func (c *WebSocketClient) sendCommandError(message string) {
+	c.SendProgress(ProgressUpdate{Type: "error", Status: "failed", Message: message})
 }
 
 // sendInitialStatus sends the current system status to a newly connected client.
 func (c *WebSocketClient) sendInitialStatus() {
-	// Get current cache stats
-	cacheStats, err := c.server.storage.GetCacheStats()
+	update, err := c.server.initialStatus("WebSocket connected successfully")
 	if err != nil {
 		c.logger.Error("Failed to get cache stats", "error", err)
 		return
 	}
 
+	if !c.queue(update) {
+		c.logger.Warn("Failed to send initial status - channel full")
+	}
+}
+
+// initialStatus returns the status update a newly connected client
+// starts with: how full the cache is.
+func (s *Server) initialStatus(message string) (ProgressUpdate, error) {
+	// Get current cache stats
+	cacheStats, err := s.storage.GetCacheStats()
+	if err != nil {
+		return ProgressUpdate{}, err
+	}
+
 	// Calculate max size from storage stats (StorageStats has MaxSize field)
-	storageStats, err := c.server.storage.GetStorageStats()
+	storageStats, err := s.storage.GetStorageStats()
 	maxSize := int64(10 * 1024 * 1024 * 1024) // Default 10GB if storage stats unavailable
 	if err == nil && storageStats.MaxSize > 0 {
 		maxSize = storageStats.MaxSize
 	}
 
-	// Send initial status update
-	update := ProgressUpdate{
+	return ProgressUpdate{
 		Type:      "status",
 		Progress:  float64(cacheStats.TotalSizeBytes) / float64(maxSize) * 100,
 		Status:    "connected",
-		Message:   "WebSocket connected successfully",
+		Message:   message,
 		Timestamp: time.Now(),
-	}
-
-	select {
-	case c.send <- update:
-	default:
-		c.logger.Warn("Failed to send initial status - channel full")
-	}
+	}, nil
 }
 
-// BroadcastProgressUpdate sends a progress update to all connected WebSocket clients.
+// BroadcastProgressUpdate sends a progress update to the connected WebSocket
+// and event stream clients whose subscriptions match it, by way of the hub.
 // This method will be called by the download manager to notify clients of updates.
 func (s *Server) BroadcastProgressUpdate(update ProgressUpdate) {
 	update.Timestamp = time.Now()
 
-	s.wsMutex.RLock()
-	clients := make([]*WebSocketClient, 0, len(s.wsClients))
-	for client := range s.wsClients {
-		if wsClient, ok := client.(*WebSocketClient); ok {
-			clients = append(clients, wsClient)
-		}
-	}
-	s.wsMutex.RUnlock()
-
 	s.logger.Debug("Broadcasting progress update",
 		"type", update.Type,
 		"media_id", update.MediaID,
-		"progress", update.Progress,
-		"client_count", len(clients))
+		"progress", update.Progress)
 
-	// Send to all clients
-	for _, client := range clients {
-		select {
-		case client.send <- update:
-		default:
-			client.logger.Warn("Failed to send broadcast - client channel full")
-		}
-	}
+	s.hub.send(update)
 }
 
 // SendProgressToClient sends a progress update to a specific client.
@@ -227,9 +413,7 @@ func (s *Server) BroadcastProgressUpdate(update ProgressUpdate) {
 func (c *WebSocketClient) SendProgress(update ProgressUpdate) {
 	update.Timestamp = time.Now()
 
-	select {
-	case c.send <- update:
-	default:
+	if !c.queue(update) {
 		c.logger.Warn("Failed to send progress update - channel full", "media_id", update.MediaID)
 	}
 }
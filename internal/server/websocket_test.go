@@ -0,0 +1,108 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestWebSocketCommands(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sm := newTestStorage(t, t.TempDir())
+
+	manager := downloader.New(&config.DownloadConfig{Workers: 1, RateLimitMbps: 10}, sm, logger)
+	if err := manager.AddJob(&downloader.DownloadJob{ID: "job1", MediaID: "m1", Priority: 2, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to add job: %v", err)
+	}
+
+	server := newTestServer(t, &Server{
+		logger:          logger,
+		storage:         sm,
+		downloadManager: manager,
+	})
+	ts := httptest.NewServer(http.HandlerFunc(server.handleWebSocket))
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	read := func() ProgressUpdate {
+		t.Helper()
+		var update ProgressUpdate
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if err := conn.ReadJSON(&update); err != nil {
+			t.Fatalf("Failed to read update: %v", err)
+		}
+		return update
+	}
+	command := func(cmd string) ProgressUpdate {
+		t.Helper()
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(cmd)); err != nil {
+			t.Fatalf("Failed to send command: %v", err)
+		}
+		return read()
+	}
+
+	if update := read(); update.Type != "status" {
+		t.Fatalf("Expected initial status, got %+v", update)
+	}
+
+	if reply := command(`{"action":"subscribe","media_ids":["m1","m2"]}`); reply.Type != "command" || reply.Status != "subscribe" {
+		t.Errorf("Expected subscribe acknowledgement, got %+v", reply)
+	}
+	if reply := command(`{"action":"unsubscribe","media_ids":["m2"]}`); reply.Message != "Subscribed to 1 media items" {
+		t.Errorf("Expected one remaining subscription, got %+v", reply)
+	}
+	if reply := command(`{"action":"filter","types":["download"]}`); reply.Type != "command" {
+		t.Errorf("Expected filter acknowledgement, got %+v", reply)
+	}
+
+	// Only the last update matches both the subscription and the filter
+	server.BroadcastProgressUpdate(ProgressUpdate{Type: "download", MediaID: "m2", Status: "downloading"})
+	server.BroadcastProgressUpdate(ProgressUpdate{Type: "cache", MediaID: "m1", Status: "completed"})
+	server.BroadcastProgressUpdate(ProgressUpdate{Type: "download", MediaID: "m1", Status: "downloading", Progress: 50})
+	if update := read(); update.MediaID != "m1" || update.Type != "download" || update.Progress != 50 {
+		t.Errorf("Expected only the m1 download update, got %+v", update)
+	}
+
+	snapshot := command(`{"action":"snapshot"}`)
+	if snapshot.Type != "queue" || len(snapshot.Queue) != 1 || snapshot.Queue[0].MediaID != "m1" || snapshot.Queue[0].Priority != 2 {
+		t.Errorf("Expected a queue snapshot with m1, got %+v", snapshot)
+	}
+
+	for _, cmd := range []string{`not json`, `{"action":"dance"}`, `{"action":"subscribe"}`} {
+		if reply := command(cmd); reply.Type != "error" {
+			t.Errorf("Expected %s to be rejected, got %+v", cmd, reply)
+		}
+	}
+}
+
+func TestFormatETA(t *testing.T) {
+	tests := []struct {
+		eta  time.Duration
+		want string
+	}{
+		{45 * time.Second, "45s"},
+		{3*time.Minute + 20*time.Second, "3m"},
+		{time.Hour + 5*time.Minute, "1h05m"},
+		{26 * time.Hour, "26h00m"},
+	}
+	for _, tt := range tests {
+		if got := formatETA(tt.eta); got != tt.want {
+			t.Errorf("formatETA(%v) = %q, want %q", tt.eta, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"time"
+)
+
+// widgetSummaryVersion is the schema version of WidgetSummary. Fields may be
+// added without bumping it; renaming or removing a field requires a new version.
+const widgetSummaryVersion = 1
+
+// WidgetSummary is a compact status document for dashboard widgets such as
+// Homarr or Organizr. Unlike other API responses it is not wrapped in
+// APIResponse, so widgets can map its fields directly.
+type WidgetSummary struct {
+	Version     int             `json:"version"`
+	Queued      int             `json:"queued"`
+	Downloading int             `json:"downloading"`
+	CachedItems int             `json:"cached_items"`
+	Current     *WidgetDownload `json:"current"` // null when nothing is downloading
+	Cache       WidgetCache     `json:"cache"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// WidgetDownload describes the most urgent in-flight download.
+type WidgetDownload struct {
+	MediaID         string  `json:"media_id"`
+	Title           string  `json:"title"`
+	Percent         float64 `json:"percent"` // 0-100, one decimal place; 0 if the size is unknown
+	BytesDownloaded int64   `json:"bytes_downloaded"`
+	BytesTotal      int64   `json:"bytes_total"`
+}
+
+// WidgetCache describes how full the cache is.
+type WidgetCache struct {
+	UsedBytes   int64   `json:"used_bytes"`
+	MaxBytes    int64   `json:"max_bytes"`
+	FillPercent float64 `json:"fill_percent"` // 0-100, one decimal place
+}
+
+// handleWidgetSummary returns a WidgetSummary for external dashboards.
+func (s *Server) handleWidgetSummary(w http.ResponseWriter, r *http.Request) {
+	cacheStats, err := s.storage.GetCacheStats()
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to get cache stats", err)
+		return
+	}
+
+	storageStats, err := s.storage.GetStorageStats()
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to get storage stats", err)
+		return
+	}
+
+	queued, err := s.queue.GetQueueItems("queued")
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to get queue status", err)
+		return
+	}
+
+	summary := WidgetSummary{
+		Version:     widgetSummaryVersion,
+		Queued:      len(queued),
+		CachedItems: cacheStats.TotalItems,
+		Cache: WidgetCache{
+			UsedBytes: cacheStats.TotalSizeBytes,
+			MaxBytes:  storageStats.MaxSize,
+		},
+		UpdatedAt: time.Now().UTC(),
+	}
+	if storageStats.MaxSize > 0 {
+		summary.Cache.FillPercent = roundTenth(float64(cacheStats.TotalSizeBytes) / float64(storageStats.MaxSize) * 100)
+	}
+
+	active := s.downloadManager.ActiveDownloads()
+	summary.Downloading = len(active)
+	if len(active) > 0 {
+		current := active[0]
+		summary.Current = &WidgetDownload{
+			MediaID:         current.MediaID,
+			Title:           s.widgetTitle(current.MediaID),
+			Percent:         roundTenth(current.Percent()),
+			BytesDownloaded: current.Downloaded,
+			BytesTotal:      current.Total,
+		}
+	}
+
+	// Dashboards poll frequently; never serve a stale summary from a proxy
+	w.Header().Set("Cache-Control", "no-store")
+	s.writeJSONResponse(w, http.StatusOK, summary)
+}
+
+// widgetTitle returns a display title for media that may not be cached yet.
+func (s *Server) widgetTitle(mediaID string) string {
+	if metadata, err := s.library.GetMediaMetadata(mediaID); err == nil && metadata.Name != "" {
+		return metadata.Name
+	}
+	return s.getMediaTitle(mediaID)
+}
+
+func roundTenth(f float64) float64 {
+	return math.Round(f*10) / 10
+}
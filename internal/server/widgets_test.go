@@ -0,0 +1,71 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestHandleWidgetSummary(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	sm := newTestStorage(t, t.TempDir())
+
+	if err := sm.AddDownloadRecord(&storage.DownloadRecord{
+		ID: "m1", JellyfinID: "m1", MediaType: "movie", Title: "Heat",
+		Size: 256 * 1024 * 1024, Status: "completed", DownloadedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to add download record: %v", err)
+	}
+	if err := sm.AddQueueItem(&storage.QueueItem{
+		ID: "q1", MediaID: "e1", Priority: 3, Status: "queued", CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to add queue item: %v", err)
+	}
+
+	dm := downloader.New(&config.DownloadConfig{Workers: 1, RateLimitMbps: 10}, sm, logger)
+
+	server := newTestServer(t, &Server{logger: logger, storage: sm, downloadManager: dm})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/widgets/summary", nil)
+	w := httptest.NewRecorder()
+	server.handleWidgetSummary(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Decode generically to pin the wire format dashboards depend on
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	for _, key := range []string{"version", "queued", "downloading", "cached_items", "current", "cache", "updated_at"} {
+		if _, ok := body[key]; !ok {
+			t.Errorf("Expected key %q in summary", key)
+		}
+	}
+	if body["version"] != float64(1) {
+		t.Errorf("Expected version 1, got %v", body["version"])
+	}
+	if body["queued"] != float64(1) {
+		t.Errorf("Expected 1 queued item, got %v", body["queued"])
+	}
+	if body["current"] != nil {
+		t.Errorf("Expected null current download when idle, got %v", body["current"])
+	}
+
+	cache, _ := body["cache"].(map[string]interface{})
+	if cache["fill_percent"] != 25.0 {
+		t.Errorf("Expected cache fill 25%%, got %v", cache["fill_percent"])
+	}
+}
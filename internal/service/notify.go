@@ -0,0 +1,63 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// States sent to systemd with Notify.
+const (
+	NotifyReady    = "READY=1"    // Startup finished; Type=notify units become active
+	NotifyStopping = "STOPPING=1" // Shutdown began
+	NotifyWatchdog = "WATCHDOG=1" // Still alive, resetting WatchdogSec
+)
+
+// NotifyStatus returns the state that shows status as the unit's status
+// line in systemctl status.
+func NotifyStatus(status string) string {
+	return "STATUS=" + status
+}
+
+// Notify sends states, one per line, to systemd over the socket named by
+// $NOTIFY_SOCKET. It reports false without error when the process was not
+// started by systemd with Type=notify, so it is safe to call everywhere.
+func Notify(states ...string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+
+	addr := &net.UnixAddr{Name: socket, Net: "unixgram"}
+	if strings.HasPrefix(socket, "@") {
+		// An abstract socket, which has no path
+		addr.Name = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, addr)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to systemd notify socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(strings.Join(states, "\n"))); err != nil {
+		return false, fmt.Errorf("failed to notify systemd: %w", err)
+	}
+	return true, nil
+}
+
+// WatchdogInterval returns how often systemd expects a watchdog ping, from
+// the unit's WatchdogSec, or 0 when the watchdog is not enabled for this
+// process.
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0 // Meant for another process
+	}
+	return time.Duration(usec) * time.Microsecond
+}
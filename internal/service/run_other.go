@@ -0,0 +1,12 @@
+//go:build !windows
+
+package service
+
+import (
+	"context"
+	"log/slog"
+)
+
+func run(ctx context.Context, name string, svc Service, logger *slog.Logger) error {
+	return runForeground(ctx, svc, logger)
+}
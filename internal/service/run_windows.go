@@ -0,0 +1,81 @@
+//go:build windows
+
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+// stopWaitHint is how long the service control manager is told stopping
+// may take, enough to drain the download workers.
+const stopWaitHint = 30 * time.Second
+
+// Exit codes reported to the service control manager.
+const (
+	exitStartFailed = 1
+	exitStopFailed  = 2
+)
+
+func run(ctx context.Context, name string, service Service, logger *slog.Logger) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return runForeground(ctx, service, logger)
+	}
+
+	h := &handler{ctx: ctx, service: service, logger: logger}
+	if err := svc.Run(name, h); err != nil {
+		return err
+	}
+	return h.err
+}
+
+// handler runs a Service for the Windows service control manager.
+type handler struct {
+	ctx     context.Context
+	service Service
+	logger  *slog.Logger
+	err     error
+}
+
+// Execute implements svc.Handler.
+func (h *handler) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+
+	ctx, cancel := context.WithCancel(h.ctx)
+	defer cancel()
+
+	if err := h.service.Start(ctx); err != nil {
+		h.err = err
+		return false, exitStartFailed
+	}
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	h.logger.Info("Windows service started")
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				break loop
+			}
+		}
+	}
+
+	h.logger.Info("Windows service stopping")
+	status <- svc.Status{State: svc.StopPending, WaitHint: uint32(stopWaitHint / time.Millisecond)}
+	cancel()
+	if err := h.service.Stop(); err != nil {
+		h.err = err
+		return false, exitStopFailed
+	}
+	return false, 0
+}
@@ -0,0 +1,137 @@
+// Package service runs go-jf-watch under a service supervisor. Under
+// systemd it reports readiness, status and shutdown with sd_notify and
+// pings the watchdog while the daemon is healthy; on Windows it runs as a
+// service of the service control manager. Run anywhere else, it simply
+// stops on SIGINT or SIGTERM.
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Service is the daemon Run supervises: the server or an embedded engine.
+type Service interface {
+	// Start starts the daemon; it runs until ctx is cancelled or Stop is
+	// called.
+	Start(ctx context.Context) error
+	// Stop shuts the daemon down, see StopInOrder.
+	Stop() error
+}
+
+// HealthChecker is implemented by services that can tell whether they
+// still work. The systemd watchdog is only pinged while Health returns
+// nil, so a daemon that is alive but broken gets restarted.
+type HealthChecker interface {
+	Health() error
+}
+
+// Stage is one part of a daemon to stop.
+type Stage struct {
+	Name string
+	Stop func() error
+}
+
+// StopInOrder stops stages one after another, in the order given, so
+// nothing is stopped before what depends on it: stop accepting requests
+// and jobs first, then drain the download workers, then close the
+// database. Every stage is stopped even if one before it fails; the first
+// error is returned.
+func StopInOrder(logger *slog.Logger, stages ...Stage) error {
+	var firstErr error
+	for _, stage := range stages {
+		started := time.Now()
+		if err := stage.Stop(); err != nil {
+			logger.Error("Failed to stop cleanly", "stage", stage.Name, "error", err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", stage.Name, err)
+			}
+			continue
+		}
+		logger.Debug("Stopped", "stage", stage.Name, "duration", time.Since(started))
+	}
+	return firstErr
+}
+
+// Run starts svc and supervises it until ctx is cancelled, the process
+// receives SIGINT or SIGTERM, or, when run as a Windows service, the
+// service control manager stops it; then svc is stopped. name is the
+// Windows service name.
+func Run(ctx context.Context, name string, svc Service, logger *slog.Logger) error {
+	return run(ctx, name, svc, logger)
+}
+
+// runForeground runs svc as a regular process, which is also how systemd
+// runs it.
+func runForeground(ctx context.Context, svc Service, logger *slog.Logger) error {
+	ctx, stopSignals := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	if err := svc.Start(ctx); err != nil {
+		notify(logger, NotifyStatus("Failed to start: "+err.Error()))
+		return err
+	}
+	notify(logger, NotifyReady, NotifyStatus("Running"))
+	logger.Info("Service started")
+
+	var wg sync.WaitGroup
+	if interval := WatchdogInterval(); interval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runWatchdog(ctx, svc, interval, logger)
+		}()
+	}
+
+	<-ctx.Done()
+	logger.Info("Service stopping")
+	notify(logger, NotifyStopping, NotifyStatus("Stopping"))
+
+	err := svc.Stop()
+	wg.Wait()
+	if err != nil {
+		return fmt.Errorf("failed to stop: %w", err)
+	}
+	logger.Info("Service stopped")
+	return nil
+}
+
+// runWatchdog pings the systemd watchdog at half its interval, as systemd
+// recommends, while svc is healthy, until ctx is cancelled.
+func runWatchdog(ctx context.Context, svc Service, interval time.Duration, logger *slog.Logger) {
+	checker, _ := svc.(HealthChecker)
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	logger.Info("Pinging systemd watchdog", "interval", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if checker != nil {
+			if err := checker.Health(); err != nil {
+				logger.Warn("Skipping watchdog ping, service unhealthy", "error", err)
+				notify(logger, NotifyStatus("Unhealthy: "+err.Error()))
+				continue
+			}
+		}
+		notify(logger, NotifyWatchdog)
+	}
+}
+
+// notify sends states to systemd, logging rather than returning failures
+// since the daemon runs the same without them.
+func notify(logger *slog.Logger, states ...string) {
+	if _, err := Notify(states...); err != nil {
+		logger.Warn("Failed to notify systemd", "error", err)
+	}
+}
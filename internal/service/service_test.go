@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// listenNotify stands in for systemd's notify socket, returning the
+// messages sent to it.
+func listenNotify(t *testing.T) <-chan string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets unavailable: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	messages := make(chan string, 32)
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			messages <- string(buf[:n])
+		}
+	}()
+	return messages
+}
+
+func waitFor(t *testing.T, messages <-chan string, want string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case message := <-messages:
+			if strings.Contains(message, want) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("expected %q to be sent to systemd", want)
+		}
+	}
+}
+
+type fakeService struct {
+	mu      sync.Mutex
+	healthy bool
+	stopped bool
+}
+
+func (s *fakeService) Start(ctx context.Context) error { return nil }
+
+func (s *fakeService) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+	return nil
+}
+
+func (s *fakeService) Health() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.healthy {
+		return errors.New("storage unavailable")
+	}
+	return nil
+}
+
+func TestNotifyWithoutSystemd(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if sent, err := Notify(NotifyReady); sent || err != nil {
+		t.Errorf("Notify() = %v, %v; want nothing sent outside systemd", sent, err)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "")
+	if got := WatchdogInterval(); got != 0 {
+		t.Errorf("expected no watchdog, got %v", got)
+	}
+
+	t.Setenv("WATCHDOG_USEC", "30000000")
+	t.Setenv("WATCHDOG_PID", "")
+	if got := WatchdogInterval(); got != 30*time.Second {
+		t.Errorf("expected a 30s watchdog, got %v", got)
+	}
+
+	t.Setenv("WATCHDOG_PID", "1")
+	if got := WatchdogInterval(); got != 0 {
+		t.Errorf("expected another process's watchdog to be ignored, got %v", got)
+	}
+}
+
+func TestRunNotifiesSystemd(t *testing.T) {
+	messages := listenNotify(t)
+	t.Setenv("WATCHDOG_USEC", "100000")
+	t.Setenv("WATCHDOG_PID", "")
+
+	svc := &fakeService{healthy: true}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Run(ctx, "go-jf-watch", svc, testLogger()) }()
+
+	waitFor(t, messages, NotifyReady)
+	waitFor(t, messages, NotifyWatchdog)
+
+	// An unhealthy service stops pinging so systemd restarts it
+	svc.mu.Lock()
+	svc.healthy = false
+	svc.mu.Unlock()
+	waitFor(t, messages, "STATUS=Unhealthy")
+	drain := time.After(150 * time.Millisecond)
+	for draining := true; draining; {
+		select {
+		case message := <-messages:
+			if message == NotifyWatchdog {
+				t.Error("expected no watchdog pings while unhealthy")
+			}
+		case <-drain:
+			draining = false
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	waitFor(t, messages, NotifyStopping)
+	if !svc.stopped {
+		t.Error("expected the service to be stopped")
+	}
+}
+
+func TestStopInOrder(t *testing.T) {
+	var order []string
+	stage := func(name string, err error) Stage {
+		return Stage{Name: name, Stop: func() error {
+			order = append(order, name)
+			return err
+		}}
+	}
+
+	err := StopInOrder(testLogger(),
+		stage("http", nil),
+		stage("downloads", errors.New("timed out")),
+		stage("storage", nil),
+	)
+	if err == nil || err.Error() != "downloads: timed out" {
+		t.Errorf("expected the downloads error, got %v", err)
+	}
+	if strings.Join(order, ",") != "http,downloads,storage" {
+		t.Errorf("expected every stage stopped in order, got %v", order)
+	}
+}
@@ -0,0 +1,85 @@
+package storage
+
+import "os"
+
+// fileID identifies a file on disk independently of the paths linking to it.
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// usageCounter sums file sizes, counting a hardlinked file once no matter
+// how many cached items link to it.
+type usageCounter struct {
+	seen  map[fileID]bool
+	total int64
+}
+
+func newUsageCounter() *usageCounter {
+	return &usageCounter{seen: make(map[fileID]bool)}
+}
+
+// add counts size for info's file unless another link to the same file was
+// already counted. Returns the bytes added to the total.
+func (u *usageCounter) add(info os.FileInfo, size int64) int64 {
+	if id, _, ok := fileIdentity(info); ok {
+		if u.seen[id] {
+			return 0
+		}
+		u.seen[id] = true
+	}
+	u.total += size
+	return size
+}
+
+// addPath is like add for a path, counting size as-is if the file cannot be
+// inspected (e.g. it is missing).
+func (u *usageCounter) addPath(path string, size int64) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		u.total += size
+		return size
+	}
+	return u.add(info, size)
+}
+
+// linkTracker works out how much space is actually freed as cached paths are
+// removed: a hardlinked file only frees space once its last link is gone,
+// and never if some links live outside the cache.
+type linkTracker struct {
+	released map[fileID]uint64
+}
+
+func newLinkTracker() *linkTracker {
+	return &linkTracker{released: make(map[fileID]uint64)}
+}
+
+// release records the removal of path and returns the bytes it frees.
+func (l *linkTracker) release(path string, size int64) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return size
+	}
+	id, links, ok := fileIdentity(info)
+	if !ok || links <= 1 {
+		return size
+	}
+
+	l.released[id]++
+	if l.released[id] >= links {
+		return size
+	}
+	return 0
+}
+
+// linkCount returns the number of hardlinks to path, or 1 if unknown.
+func linkCount(path string) uint64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 1
+	}
+	if _, links, ok := fileIdentity(info); ok && links > 0 {
+		return links
+	}
+	return 1
+}
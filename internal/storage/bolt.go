@@ -15,6 +15,8 @@ import (
 	"log/slog"
 	"path/filepath"
 	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/opd-ai/go-jf-watch/pkg/config"
@@ -23,11 +25,27 @@ import (
 
 // Bucket names following the design specified in PLAN.md
 var (
-	bucketDownloads = []byte("downloads") // Downloaded items index
-	bucketQueue     = []byte("queue")     // Active download queue
-	bucketMetadata  = []byte("metadata")  // Media metadata cache
-	bucketConfig    = []byte("config")    // Runtime configuration
-	bucketStats     = []byte("stats")     // Usage statistics
+	bucketDownloads     = []byte("downloads")     // Downloaded items index
+	bucketQueue         = []byte("queue")         // Active download queue
+	bucketMetadata      = []byte("metadata")      // Media metadata cache
+	bucketConfig        = []byte("config")        // Runtime configuration
+	bucketStats         = []byte("stats")         // Usage statistics
+	bucketReports       = []byte("reports")       // Generated activity reports
+	bucketShares        = []byte("shares")        // Public share links
+	bucketPins          = []byte("pins")          // Items protected from eviction
+	bucketSubscriptions = []byte("subscriptions") // Series whose new episodes are always cached
+	bucketCollections   = []byte("collections")   // Jellyfin collections and playlists
+	bucketHistory       = []byte("history")       // Viewing sessions, keyed by user and start time
+	bucketEvents        = []byte("events")        // Event log, keyed by time
+
+	// Secondary indexes, kept in step with the buckets they index and
+	// rebuilt by the schema migration
+	bucketQueueIndex  = []byte("queue_index")  // Queue item ID -> queue key
+	bucketSeriesIndex = []byte("series_index") // {series-id}:{item-id} -> nil
+	// Media type -> number of cached items of that type
+	bucketLibraryCounts = []byte("library_counts")
+	// {term}\x00{item-id} -> weight of the field the term is from
+	bucketSearchIndex = []byte("search_index")
 )
 
 // Manager handles all BoltDB operations with proper error handling and logging.
@@ -37,6 +55,20 @@ type Manager struct {
 	db     *bbolt.DB
 	logger *slog.Logger
 	config *config.CacheConfig
+	// cipher encrypts and decrypts cached media; nil unless
+	// cache.encryption is enabled
+	cipher *Cipher
+
+	// dbMu guards swapping db for a compacted copy; transactions hold it
+	// for reading via view and update
+	dbMu sync.RWMutex
+
+	// Free space samples for disk trend tracking
+	diskMu      sync.Mutex
+	diskSamples []diskSample
+
+	// Cache size limit set while running, 0 for the configured one
+	maxSizeGB atomic.Int64
 }
 
 // DownloadRecord represents a completed download entry in the database.
@@ -49,11 +81,22 @@ type DownloadRecord struct {
 	LocalPath    string    `json:"local_path"`
 	Size         int64     `json:"size"`
 	ContentType  string    `json:"content_type"` // MIME type for HTTP serving
-	Status       string    `json:"status"`       // completed, failed, partial
+	Status       string    `json:"status"`       // completed, failed, partial, evicted
 	DownloadedAt time.Time `json:"downloaded_at"`
 	LastAccessed time.Time `json:"last_accessed"`
 	Priority     int       `json:"priority"`
+	AccessCount  int       `json:"access_count,omitempty"` // Times playback started from the cache
 	Checksum     string    `json:"checksum,omitempty"`
+	Source       string    `json:"source,omitempty"` // What queued the download: manual, playback, prediction
+
+	// Quality, Container and Bitrate describe the variant that was cached:
+	// the original (empty quality) or a server-side transcode
+	Quality   string `json:"quality,omitempty"`
+	Container string `json:"container,omitempty"`
+	Bitrate   int    `json:"bitrate,omitempty"`
+
+	// ChecksumAlgorithm is what Checksum was made with; empty means sha256
+	ChecksumAlgorithm string `json:"checksum_algorithm,omitempty"`
 }
 
 // QueueItem represents an active download queue entry.
@@ -65,31 +108,67 @@ type QueueItem struct {
 	URL          string    `json:"url"`
 	LocalPath    string    `json:"local_path"`
 	Size         int64     `json:"size"`
-	Status       string    `json:"status"`   // pending, downloading, completed, failed
+	Status       string    `json:"status"`   // queued, downloading, paused, completed, failed, dead_letter (out of retries)
 	Progress     float64   `json:"progress"` // 0.0 to 1.0
 	CreatedAt    time.Time `json:"created_at"`
 	StartedAt    time.Time `json:"started_at,omitempty"`
 	CompletedAt  time.Time `json:"completed_at,omitempty"`
 	ErrorMessage string    `json:"error_message,omitempty"`
 	RetryCount   int       `json:"retry_count"`
+	Source       string    `json:"source,omitempty"`    // manual, playback, prediction
+	Quality      string    `json:"quality,omitempty"`   // cached variant; empty for the original
+	Container    string    `json:"container,omitempty"` // container of the variant, when known
+	Bitrate      int       `json:"bitrate,omitempty"`   // bitrate of the variant in bits per second, when known
+	Users        []string  `json:"users,omitempty"`     // household users whose predictions want this item
+	// NextAttemptAt is when a failed download may be retried; the queue
+	// skips the item until then
+	NextAttemptAt time.Time `json:"next_attempt_at,omitempty"`
+	// ErrorHistory holds the errors of the latest failed attempts, oldest
+	// first
+	ErrorHistory []AttemptError `json:"error_history,omitempty"`
+	// BytesDownloaded is how much of the partial file earlier attempts
+	// left behind; the next attempt resumes from there
+	BytesDownloaded int64 `json:"bytes_downloaded,omitempty"`
+	// PartialPath is the temporary file holding those bytes, recorded when
+	// a download is interrupted by shutdown or a crash
+	PartialPath string `json:"partial_path,omitempty"`
+	// Checksum is what the finished file is expected to hash to with
+	// ChecksumAlgorithm; empty when unknown
+	Checksum          string `json:"checksum,omitempty"`
+	ChecksumAlgorithm string `json:"checksum_algorithm,omitempty"`
+}
+
+// AttemptError is why one download attempt of a queue item failed.
+type AttemptError struct {
+	At         time.Time `json:"at"`
+	Message    string    `json:"message"`
+	HTTPStatus int       `json:"http_status,omitempty"` // 0 when no response was received
 }
 
 // MediaMetadata represents cached Jellyfin media metadata.
 // Key pattern: meta:{jellyfin-id}
 type MediaMetadata struct {
-	ID            string                 `json:"id"`
-	JellyfinID    string                 `json:"jellyfin_id"`
-	Name          string                 `json:"name"`
-	Type          string                 `json:"type"`
-	SeriesID      string                 `json:"series_id,omitempty"`
-	SeasonNumber  int                    `json:"season_number,omitempty"`
-	EpisodeNumber int                    `json:"episode_number,omitempty"`
-	Overview      string                 `json:"overview,omitempty"`
-	Genres        []string               `json:"genres,omitempty"`
-	Size          int64                  `json:"size"`
-	Container     string                 `json:"container"`
-	LastSynced    time.Time              `json:"last_synced"`
-	ExtraData     map[string]interface{} `json:"extra_data,omitempty"`
+	ID                string                 `json:"id"`
+	JellyfinID        string                 `json:"jellyfin_id"`
+	Name              string                 `json:"name"`
+	Type              string                 `json:"type"`
+	Library           string                 `json:"library,omitempty"` // Name of the Jellyfin library the item belongs to
+	SeriesID          string                 `json:"series_id,omitempty"`
+	SeasonNumber      int                    `json:"season_number,omitempty"`
+	EpisodeNumber     int                    `json:"episode_number,omitempty"`
+	AlbumID           string                 `json:"album_id,omitempty"`     // Album of a music track, or book of an audiobook chapter
+	DiscNumber        int                    `json:"disc_number,omitempty"`  // Disc (or audiobook part) number
+	TrackNumber       int                    `json:"track_number,omitempty"` // Track (or chapter) number within the disc
+	Overview          string                 `json:"overview,omitempty"`
+	Genres            []string               `json:"genres,omitempty"`
+	AudioLanguages    []string               `json:"audio_languages,omitempty"`
+	SubtitleLanguages []string               `json:"subtitle_languages,omitempty"`
+	Size              int64                  `json:"size"`
+	RunTimeTicks      int64                  `json:"run_time_ticks,omitempty"` // Duration in 100ns ticks
+	Container         string                 `json:"container"`
+	DateCreated       time.Time              `json:"date_created,omitempty"` // When the item was added to the Jellyfin library
+	LastSynced        time.Time              `json:"last_synced"`
+	ExtraData         map[string]interface{} `json:"extra_data,omitempty"`
 }
 
 // StorageStats represents usage statistics for monitoring and capacity management.
@@ -121,6 +200,14 @@ type EpisodeInfo struct {
 	Name    string `json:"name"`
 }
 
+// TrackInfo represents a music track or audiobook chapter within its album.
+type TrackInfo struct {
+	ID    string `json:"id"`
+	Disc  int    `json:"disc"`
+	Track int    `json:"track"`
+	Name  string `json:"name"`
+}
+
 // ViewingSession represents a media viewing session for prediction analysis.
 // This would be populated by syncing with Jellyfin playback activity.
 type ViewingSession struct {
@@ -139,7 +226,7 @@ type ViewingSession struct {
 }
 
 // CachedItem represents a cached media item for API responses.
-// Used by GetCachedItems for library listing with pagination.
+// Used by QueryCachedItems for library listing with pagination.
 type CachedItem struct {
 	ID            string    `json:"id"`
 	Name          string    `json:"name"`
@@ -151,6 +238,11 @@ type CachedItem struct {
 	SeriesName    string    `json:"series_name,omitempty"`
 	SeasonNumber  int       `json:"season_number,omitempty"`
 	EpisodeNumber int       `json:"episode_number,omitempty"`
+	Pinned        bool      `json:"pinned,omitempty"`
+	LastAccessed  time.Time `json:"last_accessed,omitempty"`
+	// LastSynced is when the item's metadata was last fetched from
+	// Jellyfin; zero when there is no metadata
+	LastSynced time.Time `json:"last_synced,omitempty"`
 }
 
 // NewManager creates a new storage manager with the given configuration.
@@ -165,10 +257,17 @@ func NewManager(cfg *config.CacheConfig, logger *slog.Logger) (*Manager, error)
 		return nil, fmt.Errorf("failed to open database at %s: %w", dbPath, err)
 	}
 
+	cipher, err := NewCipher(&cfg.Encryption, cfg.Directory)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to set up cache encryption: %w", err)
+	}
+
 	manager := &Manager{
 		db:     db,
 		logger: logger,
 		config: cfg,
+		cipher: cipher,
 	}
 
 	// Initialize buckets
@@ -177,6 +276,11 @@ func NewManager(cfg *config.CacheConfig, logger *slog.Logger) (*Manager, error)
 		return nil, fmt.Errorf("failed to initialize buckets: %w", err)
 	}
 
+	if err := manager.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
 	logger.Info("Storage manager initialized",
 		"db_path", dbPath,
 		"metadata_store", cfg.MetadataStore)
@@ -186,13 +290,24 @@ func NewManager(cfg *config.CacheConfig, logger *slog.Logger) (*Manager, error)
 
 // initializeBuckets creates all required buckets if they don't exist.
 func (m *Manager) initializeBuckets() error {
-	return m.db.Update(func(tx *bbolt.Tx) error {
+	return m.update(func(tx *bbolt.Tx) error {
 		buckets := [][]byte{
 			bucketDownloads,
 			bucketQueue,
 			bucketMetadata,
 			bucketConfig,
 			bucketStats,
+			bucketReports,
+			bucketShares,
+			bucketPins,
+			bucketSubscriptions,
+			bucketCollections,
+			bucketHistory,
+			bucketEvents,
+			bucketQueueIndex,
+			bucketSeriesIndex,
+			bucketLibraryCounts,
+			bucketSearchIndex,
 		}
 
 		for _, bucket := range buckets {
@@ -208,9 +323,26 @@ func (m *Manager) initializeBuckets() error {
 // Close closes the database connection gracefully.
 func (m *Manager) Close() error {
 	m.logger.Info("Closing storage manager")
+
+	m.dbMu.Lock()
+	defer m.dbMu.Unlock()
 	return m.db.Close()
 }
 
+// view runs fn in a read-only transaction.
+func (m *Manager) view(fn func(*bbolt.Tx) error) error {
+	m.dbMu.RLock()
+	defer m.dbMu.RUnlock()
+	return m.db.View(fn)
+}
+
+// update runs fn in a read-write transaction.
+func (m *Manager) update(fn func(*bbolt.Tx) error) error {
+	m.dbMu.RLock()
+	defer m.dbMu.RUnlock()
+	return m.db.Update(fn)
+}
+
 // AddDownloadRecord adds a completed download to the downloads bucket.
 func (m *Manager) AddDownloadRecord(record *DownloadRecord) error {
 	if record.ID == "" || record.JellyfinID == "" {
@@ -219,8 +351,9 @@ func (m *Manager) AddDownloadRecord(record *DownloadRecord) error {
 
 	key := fmt.Sprintf("%s:%s", record.MediaType, record.JellyfinID)
 
-	return m.db.Update(func(tx *bbolt.Tx) error {
+	return m.update(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket(bucketDownloads)
+		old := storedDownloadRecord(bucket, []byte(key))
 
 		data, err := json.Marshal(record)
 		if err != nil {
@@ -230,6 +363,9 @@ func (m *Manager) AddDownloadRecord(record *DownloadRecord) error {
 		if err := bucket.Put([]byte(key), data); err != nil {
 			return fmt.Errorf("failed to store download record: %w", err)
 		}
+		if err := updateLibraryCounts(tx, old, record); err != nil {
+			return fmt.Errorf("failed to update library counts: %w", err)
+		}
 
 		m.logger.Debug("Download record added",
 			"key", key,
@@ -245,7 +381,7 @@ func (m *Manager) GetDownloadRecord(mediaType, jellyfinID string) (*DownloadReco
 	key := fmt.Sprintf("%s:%s", mediaType, jellyfinID)
 
 	var record DownloadRecord
-	err := m.db.View(func(tx *bbolt.Tx) error {
+	err := m.view(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket(bucketDownloads)
 		data := bucket.Get([]byte(key))
 
@@ -263,12 +399,57 @@ func (m *Manager) GetDownloadRecord(mediaType, jellyfinID string) (*DownloadReco
 	return &record, nil
 }
 
+// RemoveDownloadRecord deletes a download record by media type and Jellyfin ID.
+// Removing a record that does not exist is not an error.
+func (m *Manager) RemoveDownloadRecord(mediaType, jellyfinID string) error {
+	key := fmt.Sprintf("%s:%s", mediaType, jellyfinID)
+
+	return m.update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketDownloads)
+		old := storedDownloadRecord(bucket, []byte(key))
+		if err := bucket.Delete([]byte(key)); err != nil {
+			return fmt.Errorf("failed to delete download record: %w", err)
+		}
+		return updateLibraryCounts(tx, old, nil)
+	})
+}
+
+// MarkEvicted records that a cached item's file was evicted. The record is
+// kept as download history but no longer counts as cached.
+func (m *Manager) MarkEvicted(mediaType, jellyfinID string) error {
+	key := []byte(fmt.Sprintf("%s:%s", mediaType, jellyfinID))
+
+	return m.update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketDownloads)
+		data := bucket.Get(key)
+		if data == nil {
+			return fmt.Errorf("download record not found: %s", key)
+		}
+
+		var record DownloadRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return fmt.Errorf("failed to unmarshal download record: %w", err)
+		}
+		old := record
+		record.Status = "evicted"
+
+		data, err := json.Marshal(&record)
+		if err != nil {
+			return fmt.Errorf("failed to marshal download record: %w", err)
+		}
+		if err := bucket.Put(key, data); err != nil {
+			return err
+		}
+		return updateLibraryCounts(tx, &old, &record)
+	})
+}
+
 // GetDownload retrieves a download record by media ID (Jellyfin ID).
 // Searches across all media types to find the matching record.
 func (m *Manager) GetDownload(mediaID string) (*DownloadRecord, error) {
 	var record *DownloadRecord
 
-	err := m.db.View(func(tx *bbolt.Tx) error {
+	err := m.view(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket(bucketDownloads)
 
 		// Search through all records to find matching JellyfinID
@@ -302,7 +483,7 @@ func (m *Manager) GetDownload(mediaID string) (*DownloadRecord, error) {
 func (m *Manager) ListDownloadRecords(mediaType string) ([]*DownloadRecord, error) {
 	var records []*DownloadRecord
 
-	err := m.db.View(func(tx *bbolt.Tx) error {
+	err := m.view(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket(bucketDownloads)
 
 		return bucket.ForEach(func(k, v []byte) error {
@@ -337,22 +518,24 @@ func (m *Manager) AddQueueItem(item *QueueItem) error {
 	}
 
 	// Key pattern: {priority}:{timestamp}:{id} for efficient priority ordering
-	key := fmt.Sprintf("%03d:%d:%s", item.Priority, item.CreatedAt.Unix(), item.ID)
-
-	return m.db.Update(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket(bucketQueue)
+	key := queueKey(item)
 
-		data, err := json.Marshal(item)
-		if err != nil {
-			return fmt.Errorf("failed to marshal queue item: %w", err)
+	return m.update(func(tx *bbolt.Tx) error {
+		// Re-adding an item replaces it rather than queueing it twice
+		if oldKey, _, err := getQueueItem(tx, item.ID); err != nil {
+			return err
+		} else if oldKey != nil && !bytes.Equal(oldKey, key) {
+			if err := tx.Bucket(bucketQueue).Delete(oldKey); err != nil {
+				return fmt.Errorf("failed to remove old queue key: %w", err)
+			}
 		}
 
-		if err := bucket.Put([]byte(key), data); err != nil {
+		if err := putQueueItem(tx, key, item); err != nil {
 			return fmt.Errorf("failed to store queue item: %w", err)
 		}
 
 		m.logger.Debug("Queue item added",
-			"key", key,
+			"key", string(key),
 			"priority", item.Priority,
 			"status", item.Status)
 
@@ -364,7 +547,7 @@ func (m *Manager) AddQueueItem(item *QueueItem) error {
 func (m *Manager) GetQueueItems(status string) ([]*QueueItem, error) {
 	var items []*QueueItem
 
-	err := m.db.View(func(tx *bbolt.Tx) error {
+	err := m.view(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket(bucketQueue)
 
 		cursor := bucket.Cursor()
@@ -393,62 +576,42 @@ func (m *Manager) GetQueueItems(status string) ([]*QueueItem, error) {
 
 // UpdateQueueItemStatus updates the status and progress of a queue item.
 func (m *Manager) UpdateQueueItemStatus(itemID string, status string, progress float64, errorMsg string) error {
-	return m.db.Update(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket(bucketQueue)
-
-		// Find the item by scanning for the ID in the key
-		cursor := bucket.Cursor()
-		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-			var item QueueItem
-			if err := json.Unmarshal(v, &item); err != nil {
-				continue
-			}
-
-			if item.ID == itemID {
-				// Update the item
-				item.Status = status
-				item.Progress = progress
-				item.ErrorMessage = errorMsg
-
-				if status == "downloading" && item.StartedAt.IsZero() {
-					item.StartedAt = time.Now()
-				}
-				if status == "completed" || status == "failed" {
-					item.CompletedAt = time.Now()
-				}
+	return m.update(func(tx *bbolt.Tx) error {
+		key, item, err := getQueueItem(tx, itemID)
+		if err != nil {
+			return err
+		}
+		if key == nil {
+			return fmt.Errorf("queue item with ID %s not found", itemID)
+		}
 
-				data, err := json.Marshal(&item)
-				if err != nil {
-					return fmt.Errorf("failed to marshal updated queue item: %w", err)
-				}
+		item.Status = status
+		item.Progress = progress
+		item.ErrorMessage = errorMsg
 
-				return bucket.Put(k, data)
-			}
+		if status == "downloading" && item.StartedAt.IsZero() {
+			item.StartedAt = time.Now()
+		}
+		if status == "completed" || status == "failed" || status == "dead_letter" {
+			item.CompletedAt = time.Now()
 		}
 
-		return fmt.Errorf("queue item with ID %s not found", itemID)
+		return putQueueItem(tx, key, item)
 	})
 }
 
 // RemoveQueueItem removes an item from the download queue.
 func (m *Manager) RemoveQueueItem(itemID string) error {
-	return m.db.Update(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket(bucketQueue)
-
-		// Find and delete the item by scanning for the ID in the key
-		cursor := bucket.Cursor()
-		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-			var item QueueItem
-			if err := json.Unmarshal(v, &item); err != nil {
-				continue
-			}
-
-			if item.ID == itemID {
-				return bucket.Delete(k)
-			}
+	return m.update(func(tx *bbolt.Tx) error {
+		key := tx.Bucket(bucketQueueIndex).Get([]byte(itemID))
+		if key == nil {
+			return fmt.Errorf("queue item with ID %s not found", itemID)
 		}
 
-		return fmt.Errorf("queue item with ID %s not found", itemID)
+		if err := tx.Bucket(bucketQueue).Delete(key); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketQueueIndex).Delete([]byte(itemID))
 	})
 }
 
@@ -460,27 +623,51 @@ func (m *Manager) AddMediaMetadata(metadata *MediaMetadata) error {
 
 	key := fmt.Sprintf("meta:%s", metadata.JellyfinID)
 
-	return m.db.Update(func(tx *bbolt.Tx) error {
+	return m.update(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket(bucketMetadata)
 
+		var previous *MediaMetadata
+		if existing := bucket.Get([]byte(key)); existing != nil {
+			previous = &MediaMetadata{}
+			if err := json.Unmarshal(existing, previous); err != nil {
+				previous = nil
+			}
+		}
+
 		data, err := json.Marshal(metadata)
 		if err != nil {
 			return fmt.Errorf("failed to marshal media metadata: %w", err)
 		}
 
-		return bucket.Put([]byte(key), data)
+		if err := bucket.Put([]byte(key), data); err != nil {
+			return err
+		}
+		if err := indexSearchTerms(tx, previous, metadata); err != nil {
+			return fmt.Errorf("failed to index metadata for search: %w", err)
+		}
+		return indexSeriesEpisode(tx, previous, metadata)
 	})
 }
 
+// cacheMaxSizeGB returns the cache size limit in GB, as last set by
+// CacheManager.SetLimits or else as configured.
+func (m *Manager) cacheMaxSizeGB() int64 {
+	if gb := m.maxSizeGB.Load(); gb > 0 {
+		return gb
+	}
+	return int64(m.config.MaxSizeGB)
+}
+
 // GetStorageStats calculates and returns current storage statistics.
 func (m *Manager) GetStorageStats() (*StorageStats, error) {
 	stats := &StorageStats{
 		DownloadsByType: make(map[string]int),
 		LastUpdated:     time.Now(),
-		MaxSize:         int64(m.config.MaxSizeGB) * 1024 * 1024 * 1024, // Convert GB to bytes
+		MaxSize:         m.cacheMaxSizeGB() * 1024 * 1024 * 1024, // Convert GB to bytes
 	}
+	usage := newUsageCounter()
 
-	err := m.db.View(func(tx *bbolt.Tx) error {
+	err := m.view(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket(bucketDownloads)
 
 		return bucket.ForEach(func(k, v []byte) error {
@@ -490,7 +677,7 @@ func (m *Manager) GetStorageStats() (*StorageStats, error) {
 			}
 
 			stats.TotalDownloads++
-			stats.TotalSize += record.Size
+			stats.TotalSize += usage.addPath(record.LocalPath, record.Size) // Hardlinked files count once
 			stats.DownloadsByType[record.MediaType]++
 
 			if stats.OldestDownload.IsZero() || record.DownloadedAt.Before(stats.OldestDownload) {
@@ -512,7 +699,7 @@ func (m *Manager) GetStorageStats() (*StorageStats, error) {
 func (m *Manager) GetMediaMetadata(mediaID string) (*MediaMetadata, error) {
 	var metadata MediaMetadata
 
-	err := m.db.View(func(tx *bbolt.Tx) error {
+	err := m.view(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket(bucketMetadata)
 		if bucket == nil {
 			return fmt.Errorf("metadata bucket not found")
@@ -535,34 +722,46 @@ func (m *Manager) GetMediaMetadata(mediaID string) (*MediaMetadata, error) {
 	return &metadata, nil
 }
 
+// FindMediaMetadata returns the stored metadata of a media item, or nil if
+// there is none.
+func (m *Manager) FindMediaMetadata(mediaID string) (*MediaMetadata, error) {
+	var found *MediaMetadata
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketMetadata)
+		if bucket == nil {
+			return fmt.Errorf("metadata bucket not found")
+		}
+
+		data := bucket.Get([]byte(fmt.Sprintf("meta:%s", mediaID)))
+		if data == nil {
+			return nil
+		}
+
+		found = &MediaMetadata{}
+		return json.Unmarshal(data, found)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to find media metadata: %w", err)
+	}
+
+	return found, nil
+}
+
 // GetSeriesEpisodes returns all episodes for a series and season.
 // Used by predictor to find next episodes in sequence.
 func (m *Manager) GetSeriesEpisodes(seriesID string, season int) ([]EpisodeInfo, error) {
 	var episodes []EpisodeInfo
 
-	err := m.db.View(func(tx *bbolt.Tx) error {
+	err := m.view(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket(bucketMetadata)
 		if bucket == nil {
 			return fmt.Errorf("metadata bucket not found")
 		}
 
-		// Iterate through metadata to find episodes of this series/season
-		cursor := bucket.Cursor()
-		prefix := []byte("meta:")
-
-		for k, v := cursor.Seek(prefix); k != nil && len(k) >= len(prefix); k, v = cursor.Next() {
-			if !bytes.HasPrefix(k, prefix) {
-				break
-			}
-
-			var metadata MediaMetadata
-			if err := json.Unmarshal(v, &metadata); err != nil {
-				continue // Skip invalid metadata
-			}
-
-			if metadata.Type == "episode" &&
-				metadata.SeriesID == seriesID &&
-				metadata.SeasonNumber == season {
+		forEachSeriesItem(tx, seriesID, func(metadata *MediaMetadata) {
+			if metadata.Type == "episode" && metadata.SeasonNumber == season {
 				episodes = append(episodes, EpisodeInfo{
 					ID:      metadata.ID,
 					Season:  metadata.SeasonNumber,
@@ -570,7 +769,7 @@ func (m *Manager) GetSeriesEpisodes(seriesID string, season int) ([]EpisodeInfo,
 					Name:    metadata.Name,
 				})
 			}
-		}
+		})
 
 		return nil
 	})
@@ -589,66 +788,107 @@ func (m *Manager) GetSeriesEpisodes(seriesID string, season int) ([]EpisodeInfo,
 	return episodes, nil
 }
 
-// IsMediaCached checks if a media item is already downloaded and cached.
-// Used by predictor to avoid queuing already cached content.
-func (m *Manager) IsMediaCached(mediaID string) (bool, error) {
-	var exists bool
+// GetAlbumTracks returns the tracks of a music album, or the chapters of an
+// audiobook, in playback order (by disc, then track).
+// Used by predictor to find the next track in sequence.
+func (m *Manager) GetAlbumTracks(albumID string) ([]TrackInfo, error) {
+	var tracks []TrackInfo
 
-	err := m.db.View(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket(bucketDownloads)
+	err := m.view(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketMetadata)
 		if bucket == nil {
-			return nil // No downloads yet
+			return fmt.Errorf("metadata bucket not found")
 		}
 
-		// Check for any download record with this media ID
 		cursor := bucket.Cursor()
+		prefix := []byte("meta:")
 
-		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-			var record DownloadRecord
-			if err := json.Unmarshal(v, &record); err != nil {
-				continue
+		for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+			var metadata MediaMetadata
+			if err := json.Unmarshal(v, &metadata); err != nil {
+				continue // Skip invalid metadata
 			}
 
-			if record.ID == mediaID || record.JellyfinID == mediaID {
-				exists = true
-				break
+			if (metadata.Type == "audio" || metadata.Type == "audiobook") && metadata.AlbumID == albumID {
+				tracks = append(tracks, TrackInfo{
+					ID:    metadata.ID,
+					Disc:  metadata.DiscNumber,
+					Track: metadata.TrackNumber,
+					Name:  metadata.Name,
+				})
 			}
 		}
 
 		return nil
 	})
 
-	return exists, err
+	if err != nil {
+		m.logger.Error("Failed to get album tracks", "album_id", albumID, "error", err)
+		return nil, err
+	}
+
+	SortTracks(tracks)
+	return tracks, nil
 }
 
-// GetViewingHistory returns user viewing history for prediction analysis.
-// This would be populated by syncing with Jellyfin viewing activity.
-func (m *Manager) GetViewingHistory(userID string, days int) ([]ViewingSession, error) {
-	var sessions []ViewingSession
+// GetSeriesMetadata returns the metadata of every episode of a series, in
+// season and episode order. Used for per-series statistics.
+func (m *Manager) GetSeriesMetadata(seriesID string) ([]*MediaMetadata, error) {
+	var episodes []*MediaMetadata
 
-	err := m.db.View(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket(bucketStats)
+	err := m.view(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketMetadata)
 		if bucket == nil {
-			return nil // No viewing history yet
+			return fmt.Errorf("metadata bucket not found")
 		}
 
-		// Get viewing history for user (this would be stored by Jellyfin sync)
-		key := fmt.Sprintf("history:%s", userID)
-		data := bucket.Get([]byte(key))
-		if data == nil {
-			return nil // No history for this user
-		}
+		forEachSeriesItem(tx, seriesID, func(metadata *MediaMetadata) {
+			if metadata.Type == "episode" {
+				episodes = append(episodes, metadata)
+			}
+		})
+
+		return nil
+	})
+
+	if err != nil {
+		m.logger.Error("Failed to get series metadata", "series_id", seriesID, "error", err)
+		return nil, err
+	}
 
-		var allSessions []ViewingSession
-		if err := json.Unmarshal(data, &allSessions); err != nil {
+	SortEpisodes(episodes)
+	return episodes, nil
+}
+
+// GetStaleMetadata returns up to limit metadata entries of cached items last
+// synced before the given time, least recently synced first. Used by the
+// background metadata refresher.
+func (m *Manager) GetStaleMetadata(before time.Time, limit int) ([]*MediaMetadata, error) {
+	var stale []*MediaMetadata
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		cached := make(map[string]bool)
+		if err := tx.Bucket(bucketDownloads).ForEach(func(k, v []byte) error {
+			var record DownloadRecord
+			if err := json.Unmarshal(v, &record); err == nil {
+				cached[record.JellyfinID] = true
+			}
+			return nil
+		}); err != nil {
 			return err
 		}
 
-		// Filter to last N days
-		cutoff := time.Now().AddDate(0, 0, -days)
-		for _, session := range allSessions {
-			if session.StartTime.After(cutoff) {
-				sessions = append(sessions, session)
+		cursor := tx.Bucket(bucketMetadata).Cursor()
+		prefix := []byte("meta:")
+
+		for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+			var metadata MediaMetadata
+			if err := json.Unmarshal(v, &metadata); err != nil {
+				continue // Skip invalid metadata
+			}
+
+			if cached[metadata.JellyfinID] && metadata.LastSynced.Before(before) {
+				stale = append(stale, &metadata)
 			}
 		}
 
@@ -656,49 +896,161 @@ func (m *Manager) GetViewingHistory(userID string, days int) ([]ViewingSession,
 	})
 
 	if err != nil {
-		m.logger.Error("Failed to get viewing history",
-			"user_id", userID, "days", days, "error", err)
+		m.logger.Error("Failed to get stale metadata", "error", err)
 		return nil, err
 	}
 
-	return sessions, nil
+	SortStaleMetadata(stale)
+	if limit > 0 && len(stale) > limit {
+		stale = stale[:limit]
+	}
+	return stale, nil
 }
 
-// StoreViewingSession adds a viewing session to the history.
-// Called when user starts/completes watching content.
-func (m *Manager) StoreViewingSession(userID string, session ViewingSession) error {
-	return m.db.Update(func(tx *bbolt.Tx) error {
-		bucket, err := tx.CreateBucketIfNotExists(bucketStats)
-		if err != nil {
-			return fmt.Errorf("failed to create stats bucket: %w", err)
-		}
+// GetRecentMetadata returns up to limit metadata entries of items added
+// to the library after since, newest first. An item's addition time is
+// its Jellyfin DateCreated, or when it was last synced if that is unknown.
+// Used to predict recently added content.
+func (m *Manager) GetRecentMetadata(since time.Time, limit int) ([]*MediaMetadata, error) {
+	var recent []*MediaMetadata
 
-		// Get existing history
-		key := fmt.Sprintf("history:%s", userID)
-		var sessions []ViewingSession
+	err := m.view(func(tx *bbolt.Tx) error {
+		cursor := tx.Bucket(bucketMetadata).Cursor()
+		prefix := []byte("meta:")
 
-		if data := bucket.Get([]byte(key)); data != nil {
-			if err := json.Unmarshal(data, &sessions); err != nil {
-				m.logger.Warn("Failed to unmarshal existing history", "error", err)
-				sessions = []ViewingSession{} // Start fresh on error
+		for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+			var metadata MediaMetadata
+			if err := json.Unmarshal(v, &metadata); err != nil {
+				continue // Skip invalid metadata
+			}
+
+			if metadata.AddedAt().After(since) {
+				recent = append(recent, &metadata)
 			}
 		}
 
-		// Add new session
-		sessions = append(sessions, session)
+		return nil
+	})
+
+	if err != nil {
+		m.logger.Error("Failed to get recent metadata", "error", err)
+		return nil, err
+	}
 
-		// Keep only last 1000 sessions to prevent unbounded growth
-		if len(sessions) > 1000 {
-			sessions = sessions[len(sessions)-1000:]
+	SortRecentMetadata(recent)
+	if limit > 0 && len(recent) > limit {
+		recent = recent[:limit]
+	}
+	return recent, nil
+}
+
+// AddedAt returns when the item was added to the Jellyfin library, falling
+// back to when it was last synced if the server did not say.
+func (m *MediaMetadata) AddedAt() time.Time {
+	if !m.DateCreated.IsZero() {
+		return m.DateCreated
+	}
+	return m.LastSynced
+}
+
+// SortRecentMetadata orders metadata by addition time, newest first,
+// breaking ties by Jellyfin ID.
+func SortRecentMetadata(metadata []*MediaMetadata) {
+	sort.Slice(metadata, func(i, j int) bool {
+		if !metadata[i].AddedAt().Equal(metadata[j].AddedAt()) {
+			return metadata[i].AddedAt().After(metadata[j].AddedAt())
 		}
+		return metadata[i].JellyfinID < metadata[j].JellyfinID
+	})
+}
 
-		// Store updated history
-		data, err := json.Marshal(sessions)
-		if err != nil {
-			return fmt.Errorf("failed to marshal viewing history: %w", err)
+// SortStaleMetadata orders metadata by last sync, oldest first, breaking
+// ties by Jellyfin ID.
+func SortStaleMetadata(metadata []*MediaMetadata) {
+	sort.Slice(metadata, func(i, j int) bool {
+		if !metadata[i].LastSynced.Equal(metadata[j].LastSynced) {
+			return metadata[i].LastSynced.Before(metadata[j].LastSynced)
+		}
+		return metadata[i].JellyfinID < metadata[j].JellyfinID
+	})
+}
+
+// SortEpisodes orders episode metadata by season, then episode number.
+func SortEpisodes(episodes []*MediaMetadata) {
+	sort.Slice(episodes, func(i, j int) bool {
+		if episodes[i].SeasonNumber != episodes[j].SeasonNumber {
+			return episodes[i].SeasonNumber < episodes[j].SeasonNumber
+		}
+		return episodes[i].EpisodeNumber < episodes[j].EpisodeNumber
+	})
+}
+
+// SortTracks orders tracks by disc, then track number.
+func SortTracks(tracks []TrackInfo) {
+	sort.Slice(tracks, func(i, j int) bool {
+		if tracks[i].Disc != tracks[j].Disc {
+			return tracks[i].Disc < tracks[j].Disc
+		}
+		return tracks[i].Track < tracks[j].Track
+	})
+}
+
+// IsMediaCached checks if a media item is already downloaded and cached.
+// Used by predictor to avoid queuing already cached content.
+func (m *Manager) IsMediaCached(mediaID string) (bool, error) {
+	var exists bool
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketDownloads)
+		if bucket == nil {
+			return nil // No downloads yet
+		}
+
+		// Check for any download record with this media ID
+		cursor := bucket.Cursor()
+
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var record DownloadRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				continue
+			}
+
+			// Evicted records are kept as history only
+			if (record.ID == mediaID || record.JellyfinID == mediaID) && record.Status != "evicted" {
+				exists = true
+				break
+			}
 		}
 
-		return bucket.Put([]byte(key), data)
+		return nil
+	})
+
+	return exists, err
+}
+
+// RecordAccess marks a cached item as played at the given time, updating
+// its last access time and access count for eviction.
+func (m *Manager) RecordAccess(mediaID string, at time.Time) error {
+	return m.update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketDownloads)
+
+		c := bucket.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var rec DownloadRecord
+			if err := json.Unmarshal(v, &rec); err != nil || rec.JellyfinID != mediaID {
+				continue
+			}
+
+			rec.LastAccessed = at
+			rec.AccessCount++
+			data, err := json.Marshal(&rec)
+			if err != nil {
+				return fmt.Errorf("failed to marshal download record: %w", err)
+			}
+			return bucket.Put(k, data)
+		}
+
+		return fmt.Errorf("download record not found for media ID: %s", mediaID)
 	})
 }
 
@@ -706,7 +1058,7 @@ func (m *Manager) StoreViewingSession(userID string, session ViewingSession) err
 func (m *Manager) GetCacheStats() (*CacheStats, error) {
 	var stats CacheStats
 
-	err := m.db.View(func(tx *bbolt.Tx) error {
+	err := m.view(func(tx *bbolt.Tx) error {
 		// Get downloads bucket to calculate statistics
 		bucket := tx.Bucket(bucketDownloads)
 		if bucket == nil {
@@ -714,7 +1066,7 @@ func (m *Manager) GetCacheStats() (*CacheStats, error) {
 			return nil
 		}
 
-		var totalSize int64
+		usage := newUsageCounter()
 		var itemCount int
 
 		// Iterate through all download records
@@ -725,11 +1077,12 @@ func (m *Manager) GetCacheStats() (*CacheStats, error) {
 				return nil // Continue iteration
 			}
 
-			totalSize += record.Size
+			usage.addPath(record.LocalPath, record.Size) // Hardlinked files count once
 			itemCount++
 			return nil
 		})
 
+		totalSize := usage.total
 		stats.TotalSizeBytes = totalSize
 		stats.TotalItems = itemCount
 		stats.Size = totalSize      // Alias
@@ -746,48 +1099,8 @@ func (m *Manager) GetCacheStats() (*CacheStats, error) {
 	return &stats, nil
 }
 
-// GetCachedItemsCount returns the total count of cached items, optionally filtered by media type.
-// Used for pagination to provide accurate total counts.
-func (m *Manager) GetCachedItemsCount(mediaType string) (int, error) {
-	var count int
-
-	err := m.db.View(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket(bucketDownloads)
-		if bucket == nil {
-			return nil // No downloads bucket means 0 items
-		}
-
-		if mediaType == "" {
-			// Count all items
-			count = bucket.Stats().KeyN
-			return nil
-		}
-
-		// Count items matching media type
-		c := bucket.Cursor()
-		for k, v := c.First(); k != nil; k, v = c.Next() {
-			var record DownloadRecord
-			if err := json.Unmarshal(v, &record); err != nil {
-				continue // Skip invalid records
-			}
-
-			if record.MediaType == mediaType {
-				count++
-			}
-		}
-
-		return nil
-	})
-
-	if err != nil {
-		return 0, fmt.Errorf("failed to get cached items count: %w", err)
-	}
-
-	return count, nil
-}
-
-// GetCachedItems returns cached items with pagination support.
-// Used by /api/library endpoint to list downloaded media.
+// GetCachedItems returns a page of the cached library, newest first. Page
+// numbers start at 1.
 func (m *Manager) GetCachedItems(mediaType string, page, limit int) ([]*CachedItem, error) {
 	if page < 1 {
 		page = 1
@@ -796,80 +1109,11 @@ func (m *Manager) GetCachedItems(mediaType string, page, limit int) ([]*CachedIt
 		limit = 50
 	}
 
-	var items []*CachedItem
-	skip := (page - 1) * limit
-
-	err := m.db.View(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket(bucketDownloads)
-		if bucket == nil {
-			return nil // No downloads bucket means empty list
-		}
-
-		metaBucket := tx.Bucket(bucketMetadata)
-
-		c := bucket.Cursor()
-		itemCount := 0
-
-		for k, v := c.First(); k != nil; k, v = c.Next() {
-			var record DownloadRecord
-			if err := json.Unmarshal(v, &record); err != nil {
-				m.logger.Warn("Failed to unmarshal download record",
-					"key", string(k), "error", err)
-				continue
-			}
-
-			// Filter by media type if specified
-			if mediaType != "" && record.MediaType != mediaType {
-				continue
-			}
-
-			// Skip items before current page
-			if itemCount < skip {
-				itemCount++
-				continue
-			}
-
-			// Stop if we've collected enough items
-			if len(items) >= limit {
-				break
-			}
-
-			// Create cached item from download record
-			item := &CachedItem{
-				ID:        record.JellyfinID,
-				Name:      record.Title,
-				Type:      record.MediaType,
-				Path:      record.LocalPath,
-				Size:      record.Size,
-				DateAdded: record.DownloadedAt,
-			}
-
-			// Try to get additional metadata if available
-			if metaBucket != nil {
-				metaKey := []byte("meta:" + record.JellyfinID)
-				if metaData := metaBucket.Get(metaKey); metaData != nil {
-					var metadata MediaMetadata
-					if err := json.Unmarshal(metaData, &metadata); err == nil {
-						item.SeriesID = metadata.SeriesID
-						item.SeasonNumber = metadata.SeasonNumber
-						item.EpisodeNumber = metadata.EpisodeNumber
-						// SeriesName would need to be looked up separately
-					}
-				}
-			}
-
-			items = append(items, item)
-			itemCount++
-		}
-
-		return nil
-	})
-
+	result, err := m.QueryCachedItems(LibraryQuery{MediaType: mediaType, Limit: limit, Offset: (page - 1) * limit})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get cached items: %w", err)
+		return nil, err
 	}
-
-	return items, nil
+	return result.Items, nil
 }
 
 // GetNextQueueItem retrieves the next queued item (lowest priority number, oldest timestamp).
@@ -877,7 +1121,7 @@ func (m *Manager) GetCachedItems(mediaType string, page, limit int) ([]*CachedIt
 func (m *Manager) GetNextQueueItem() (*QueueItem, error) {
 	var nextItem *QueueItem
 
-	err := m.db.View(func(tx *bbolt.Tx) error {
+	err := m.view(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket(bucketQueue)
 		if bucket == nil {
 			return nil
@@ -913,32 +1157,18 @@ func (m *Manager) UpdateQueueItem(item *QueueItem) error {
 		return fmt.Errorf("invalid queue item: item or ID is empty")
 	}
 
-	return m.db.Update(func(tx *bbolt.Tx) error {
+	return m.update(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket(bucketQueue)
 		if bucket == nil {
 			return fmt.Errorf("queue bucket not found")
 		}
 
-		// Find the item by ID
-		cursor := bucket.Cursor()
-		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-			var existing QueueItem
-			if err := json.Unmarshal(v, &existing); err != nil {
-				continue
-			}
-
-			if existing.ID == item.ID {
-				// Update the item
-				data, err := json.Marshal(item)
-				if err != nil {
-					return fmt.Errorf("failed to marshal queue item: %w", err)
-				}
-
-				return bucket.Put(k, data)
-			}
+		key := tx.Bucket(bucketQueueIndex).Get([]byte(item.ID))
+		if key == nil || bucket.Get(key) == nil {
+			return fmt.Errorf("queue item with ID %s not found", item.ID)
 		}
 
-		return fmt.Errorf("queue item with ID %s not found", item.ID)
+		return putQueueItem(tx, append([]byte(nil), key...), item)
 	})
 }
 
@@ -946,7 +1176,7 @@ func (m *Manager) UpdateQueueItem(item *QueueItem) error {
 func (m *Manager) GetQueueSize() (map[int]int, error) {
 	sizes := make(map[int]int)
 
-	err := m.db.View(func(tx *bbolt.Tx) error {
+	err := m.view(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket(bucketQueue)
 		if bucket == nil {
 			return nil
@@ -971,3 +1201,90 @@ func (m *Manager) GetQueueSize() (map[int]int, error) {
 
 	return sizes, nil
 }
+
+// FindActiveQueueItem returns the queued, downloading or paused item for a
+// media ID.
+// Returns nil if the media item has no active queue entry.
+func (m *Manager) FindActiveQueueItem(mediaID string) (*QueueItem, error) {
+	var found *QueueItem
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketQueue)
+		if bucket == nil {
+			return nil
+		}
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var item QueueItem
+			if err := json.Unmarshal(v, &item); err != nil {
+				continue
+			}
+
+			if item.MediaID == mediaID && (item.Status == "queued" || item.Status == "downloading" || item.Status == "paused") {
+				found = &item
+				return nil
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to find active queue item: %w", err)
+	}
+
+	return found, nil
+}
+
+// UpdateQueueItemPriority changes the priority of a queue item.
+// The item is re-keyed so that priority ordering of the queue bucket stays correct.
+func (m *Manager) UpdateQueueItemPriority(itemID string, priority int) error {
+	return m.update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketQueue)
+		if bucket == nil {
+			return fmt.Errorf("queue bucket not found")
+		}
+
+		key, item, err := getQueueItem(tx, itemID)
+		if err != nil {
+			return err
+		}
+		if key == nil {
+			return fmt.Errorf("queue item with ID %s not found", itemID)
+		}
+
+		if item.Priority == priority {
+			return nil
+		}
+
+		if err := bucket.Delete(key); err != nil {
+			return fmt.Errorf("failed to remove old queue key: %w", err)
+		}
+
+		item.Priority = priority
+		return putQueueItem(tx, queueKey(item), item)
+	})
+}
+
+// SetQueueItemUsers records which household users' predictions want a
+// queue item. The item keeps its place in the queue.
+func (m *Manager) SetQueueItemUsers(itemID string, users []string) error {
+	return m.update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketQueue)
+		if bucket == nil {
+			return fmt.Errorf("queue bucket not found")
+		}
+
+		key, item, err := getQueueItem(tx, itemID)
+		if err != nil {
+			return err
+		}
+		if key == nil {
+			return fmt.Errorf("queue item with ID %s not found", itemID)
+		}
+
+		item.Users = users
+		return putQueueItem(tx, key, item)
+	})
+}
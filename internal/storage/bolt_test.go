@@ -483,3 +483,78 @@ func createTestManager(t *testing.T, tempDir string) *Manager {
 
 	return manager
 }
+
+func TestUpdateQueueItemPriority(t *testing.T) {
+	manager := createTestManager(t, t.TempDir())
+	defer manager.Close()
+
+	now := time.Now()
+	if err := manager.AddQueueItem(&QueueItem{ID: "low", MediaID: "media-low", Priority: 4, Status: "queued", CreatedAt: now}); err != nil {
+		t.Fatalf("Failed to add queue item: %v", err)
+	}
+	if err := manager.AddQueueItem(&QueueItem{ID: "mid", MediaID: "media-mid", Priority: 2, Status: "queued", CreatedAt: now}); err != nil {
+		t.Fatalf("Failed to add queue item: %v", err)
+	}
+
+	if err := manager.UpdateQueueItemPriority("low", 1); err != nil {
+		t.Fatalf("Failed to update priority: %v", err)
+	}
+
+	next, err := manager.GetNextQueueItem()
+	if err != nil {
+		t.Fatalf("Failed to get next queue item: %v", err)
+	}
+	if next == nil || next.ID != "low" || next.Priority != 1 {
+		t.Errorf("Expected re-prioritized item to be next, got %+v", next)
+	}
+
+	items, _ := manager.GetQueueItems("")
+	if len(items) != 2 {
+		t.Errorf("Expected 2 queue items after re-keying, got %d", len(items))
+	}
+
+	if err := manager.UpdateQueueItemPriority("missing", 1); err == nil {
+		t.Error("Expected error for unknown queue item")
+	}
+}
+
+func TestFindActiveQueueItem(t *testing.T) {
+	manager := createTestManager(t, t.TempDir())
+	defer manager.Close()
+
+	if err := manager.AddQueueItem(&QueueItem{ID: "done", MediaID: "media-1", Status: "failed", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to add queue item: %v", err)
+	}
+
+	item, err := manager.FindActiveQueueItem("media-1")
+	if err != nil {
+		t.Fatalf("FindActiveQueueItem failed: %v", err)
+	}
+	if item != nil {
+		t.Errorf("Expected failed item to be ignored, got %+v", item)
+	}
+
+	if err := manager.AddQueueItem(&QueueItem{ID: "live", MediaID: "media-1", Status: "queued", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to add queue item: %v", err)
+	}
+
+	item, err = manager.FindActiveQueueItem("media-1")
+	if err != nil {
+		t.Fatalf("FindActiveQueueItem failed: %v", err)
+	}
+	if item == nil || item.ID != "live" {
+		t.Errorf("Expected active item 'live', got %+v", item)
+	}
+
+	if err := manager.AddQueueItem(&QueueItem{ID: "held", MediaID: "media-2", Status: "paused", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to add queue item: %v", err)
+	}
+
+	item, err = manager.FindActiveQueueItem("media-2")
+	if err != nil {
+		t.Fatalf("FindActiveQueueItem failed: %v", err)
+	}
+	if item == nil || item.ID != "held" {
+		t.Errorf("Expected paused item 'held' to count as active, got %+v", item)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/opd-ai/go-jf-watch/pkg/config"
@@ -15,7 +16,7 @@ import (
 // capacity management, eviction policies, and directory organization.
 //
 // Design principles:
-// - LRU eviction with protection for active downloads
+// - Pluggable eviction policy (LRU by default) with protection for active downloads
 // - Atomic operations to prevent corruption
 // - Predictable directory structure
 // - Graceful degradation on filesystem errors
@@ -23,6 +24,15 @@ type CacheManager struct {
 	config  *config.CacheConfig
 	storage *Manager
 	logger  *slog.Logger
+	policy  EvictionPolicy
+
+	// maintenance, when set, confines non-emergency cleanup to its window
+	maintenance *config.MaintenanceConfig
+
+	// Size limit and eviction threshold, which can change while running
+	limitsMu          sync.RWMutex
+	maxSizeGB         int
+	evictionThreshold float64
 }
 
 // CacheEntry represents a cached media file with its metadata.
@@ -32,7 +42,12 @@ type CacheEntry struct {
 	LastAccessed time.Time
 	MediaType    string
 	JellyfinID   string
-	Protected    bool // Protected from eviction (currently downloading/playing)
+	Protected    bool   // Protected from eviction (currently downloading/playing, or picked for a trip)
+	Pinned       bool   // Pinned by the user, never evicted
+	Links        uint64 // Hardlinks to the file, including ones outside the cache
+	AccessCount  int    // Times playback started from the cache
+	Watched      bool   // Watched to completion by any user
+	Height       int    // Video height in pixels, 0 when not inspected
 }
 
 // EvictionCandidate represents an item that can be evicted, sorted by priority.
@@ -42,23 +57,48 @@ type EvictionCandidate struct {
 }
 
 // NewCacheManager creates a new cache manager with the given storage manager.
+// Eviction uses the policy named by cfg.EvictionPolicy, falling back to LRU
+// if the name is unknown.
 func NewCacheManager(cfg *config.CacheConfig, storage *Manager, logger *slog.Logger) *CacheManager {
+	policy, err := NewEvictionPolicy(cfg.EvictionPolicy)
+	if err != nil {
+		logger.Warn("Falling back to LRU eviction", "error", err)
+		policy = LRUPolicy{}
+	}
+
 	return &CacheManager{
-		config:  cfg,
-		storage: storage,
-		logger:  logger,
+		config:            cfg,
+		storage:           storage,
+		logger:            logger,
+		policy:            policy,
+		maxSizeGB:         cfg.MaxSizeGB,
+		evictionThreshold: cfg.EvictionThreshold,
 	}
 }
 
+// SetEvictionPolicy replaces the policy that orders eviction candidates.
+func (c *CacheManager) SetEvictionPolicy(policy EvictionPolicy) {
+	c.policy = policy
+}
+
+// SetMaintenanceWindow defers routine cleanup to the maintenance window.
+// Emergency cleanup still runs whenever it is needed.
+func (c *CacheManager) SetMaintenanceWindow(cfg *config.MaintenanceConfig) {
+	c.maintenance = cfg
+}
+
 // GetCacheSize calculates the current total size of cached media.
 // It scans the filesystem and cross-references with database records.
+// Hardlinked files are counted once, so the result reflects actual disk usage.
 func (c *CacheManager) GetCacheSize() (int64, error) {
-	var totalSize int64
+	usage := newUsageCounter()
 
 	// Walk through cache directories
 	mediaDirs := []string{
 		filepath.Join(c.config.Directory, "movies"),
 		filepath.Join(c.config.Directory, "series"),
+		filepath.Join(c.config.Directory, "music"),
+		filepath.Join(c.config.Directory, "audiobooks"),
 	}
 
 	for _, mediaDir := range mediaDirs {
@@ -74,8 +114,12 @@ func (c *CacheManager) GetCacheSize() (int64, error) {
 				return nil // Continue walking
 			}
 
-			if !info.IsDir() && info.Name() != ".meta.json" {
-				totalSize += info.Size()
+			// HLS segments are a second copy of the media, so they count
+			if info.IsDir() && IsSidecar(info.Name()) && info.Name() != hlsDirName {
+				return filepath.SkipDir
+			}
+			if !info.IsDir() && !IsSidecar(info.Name()) {
+				usage.add(info, info.Size())
 			}
 
 			return nil
@@ -86,7 +130,34 @@ func (c *CacheManager) GetCacheSize() (int64, error) {
 		}
 	}
 
-	return totalSize, nil
+	return usage.total, nil
+}
+
+// SetLimits changes the cache size limit and the utilization at which
+// eviction starts. The next cleanup uses them.
+func (c *CacheManager) SetLimits(maxSizeGB int, evictionThreshold float64) {
+	c.limitsMu.Lock()
+	c.maxSizeGB = maxSizeGB
+	c.evictionThreshold = evictionThreshold
+	c.limitsMu.Unlock()
+
+	if c.storage != nil {
+		c.storage.maxSizeGB.Store(int64(maxSizeGB))
+	}
+}
+
+// MaxSize returns the cache size limit in bytes.
+func (c *CacheManager) MaxSize() int64 {
+	c.limitsMu.RLock()
+	defer c.limitsMu.RUnlock()
+	return int64(c.maxSizeGB) * 1024 * 1024 * 1024
+}
+
+// EvictionThreshold returns the utilization at which eviction starts.
+func (c *CacheManager) EvictionThreshold() float64 {
+	c.limitsMu.RLock()
+	defer c.limitsMu.RUnlock()
+	return c.evictionThreshold
 }
 
 // GetCacheUtilization returns the current cache utilization as a percentage.
@@ -96,8 +167,7 @@ func (c *CacheManager) GetCacheUtilization() (float64, error) {
 		return 0, err
 	}
 
-	maxSizeBytes := int64(c.config.MaxSizeGB) * 1024 * 1024 * 1024
-	return float64(currentSize) / float64(maxSizeBytes), nil
+	return float64(currentSize) / float64(c.MaxSize()), nil
 }
 
 // NeedsCleanup returns true if cache cleanup should be triggered.
@@ -107,11 +177,13 @@ func (c *CacheManager) NeedsCleanup() (bool, error) {
 		return false, err
 	}
 
-	return utilization >= c.config.EvictionThreshold, nil
+	return utilization >= c.EvictionThreshold(), nil
 }
 
 // GetMediaPath returns the expected filesystem path for a media item.
-// Follows the directory structure specified in PLAN.md.
+// Follows the directory structure specified in PLAN.md. For music tracks and
+// audiobook chapters jellyfinID is the album (or book) ID, and seasonNum and
+// episodeNum are the disc and track (or chapter) numbers.
 func (c *CacheManager) GetMediaPath(mediaType, jellyfinID string, seasonNum, episodeNum int, filename string) string {
 	switch mediaType {
 	case "movie":
@@ -119,6 +191,12 @@ func (c *CacheManager) GetMediaPath(mediaType, jellyfinID string, seasonNum, epi
 	case "episode":
 		return filepath.Join(c.config.Directory, "series", jellyfinID,
 			fmt.Sprintf("S%02dE%02d", seasonNum, episodeNum), filename)
+	case "audio":
+		return filepath.Join(c.config.Directory, "music", jellyfinID,
+			fmt.Sprintf("D%02dT%02d", seasonNum, episodeNum), filename)
+	case "audiobook":
+		return filepath.Join(c.config.Directory, "audiobooks", jellyfinID,
+			fmt.Sprintf("P%02dC%03d", seasonNum, episodeNum), filename)
 	default:
 		return filepath.Join(c.config.Directory, mediaType, jellyfinID, filename)
 	}
@@ -167,6 +245,12 @@ func (c *CacheManager) GetCacheEntries() ([]*CacheEntry, error) {
 		return nil, fmt.Errorf("failed to list download records: %w", err)
 	}
 
+	watched, err := c.storage.WatchedMediaIDs()
+	if err != nil {
+		c.logger.Warn("Failed to read watched items for eviction", "error", err)
+	}
+	trip := c.storage.tripMedia(time.Now())
+
 	var entries []*CacheEntry
 
 	for _, record := range records {
@@ -185,13 +269,34 @@ func (c *CacheManager) GetCacheEntries() ([]*CacheEntry, error) {
 			continue
 		}
 
+		pinned, err := c.storage.IsPinned(record.JellyfinID)
+		if err != nil {
+			// Err on the side of keeping the file
+			c.logger.Warn("Failed to check pin, protecting item",
+				"jellyfin_id", record.JellyfinID,
+				"error", err)
+			pinned = true
+		}
+
 		entry := &CacheEntry{
 			Path:         record.LocalPath,
 			Size:         info.Size(),
 			LastAccessed: record.LastAccessed,
 			MediaType:    record.MediaType,
 			JellyfinID:   record.JellyfinID,
-			Protected:    c.isProtectedFromEviction(record.JellyfinID),
+			Protected:    trip[record.JellyfinID] || c.isProtectedFromEviction(record.JellyfinID),
+			Pinned:       pinned,
+			Links:        1,
+			AccessCount:  record.AccessCount,
+			Watched:      watched[record.JellyfinID],
+		}
+		if _, links, ok := fileIdentity(info); ok && links > 0 {
+			entry.Links = links
+		}
+		if metadata, err := c.storage.FindMediaMetadata(record.JellyfinID); err == nil && metadata != nil {
+			if mediaInfo := metadata.MediaInfo(); mediaInfo != nil {
+				entry.Height = mediaInfo.Height
+			}
 		}
 
 		entries = append(entries, entry)
@@ -234,7 +339,8 @@ func (c *CacheManager) isProtectedFromEviction(jellyfinID string) bool {
 }
 
 // GetEvictionCandidates returns items that can be evicted, sorted by priority.
-// Uses LRU with protection as specified in PLAN.md.
+// Protected and pinned items are skipped and the rest are ordered by the
+// eviction policy.
 func (c *CacheManager) GetEvictionCandidates(targetSize int64) ([]*EvictionCandidate, error) {
 	entries, err := c.GetCacheEntries()
 	if err != nil {
@@ -242,36 +348,15 @@ func (c *CacheManager) GetEvictionCandidates(targetSize int64) ([]*EvictionCandi
 	}
 
 	var candidates []*EvictionCandidate
-	now := time.Now()
 
 	for _, entry := range entries {
-		if entry.Protected {
-			continue // Skip protected items
-		}
-
-		// Calculate eviction score based on:
-		// 1. Age since last access (higher = more evictable)
-		// 2. Size (larger files get slight preference for removal)
-		// 3. Media type preferences
-
-		daysSinceAccess := now.Sub(entry.LastAccessed).Hours() / 24
-		sizeMB := float64(entry.Size) / (1024 * 1024)
-
-		score := daysSinceAccess * 1.0 // Base score from age
-
-		// Slight preference for removing larger files when space is tight
-		if sizeMB > 1000 { // Files > 1GB
-			score += 0.5
-		}
-
-		// Media type scoring (movies slightly more evictable than episodes)
-		if entry.MediaType == "movie" {
-			score += 0.1
+		if entry.Protected || entry.Pinned {
+			continue // Skip protected and pinned items
 		}
 
 		candidates = append(candidates, &EvictionCandidate{
 			CacheEntry: *entry,
-			Score:      score,
+			Score:      c.policy.Score(entry),
 		})
 	}
 
@@ -280,13 +365,15 @@ func (c *CacheManager) GetEvictionCandidates(targetSize int64) ([]*EvictionCandi
 		return candidates[i].Score > candidates[j].Score
 	})
 
-	// Return only enough candidates to reach target size
+	// Return only enough candidates to reach target size, counting the
+	// space actually freed rather than the apparent file sizes
 	var totalSize int64
 	var result []*EvictionCandidate
+	links := newLinkTracker()
 
 	for _, candidate := range candidates {
 		result = append(result, candidate)
-		totalSize += candidate.Size
+		totalSize += links.release(candidate.Path, candidate.Size)
 
 		if totalSize >= targetSize {
 			break
@@ -302,6 +389,12 @@ func (c *CacheManager) EvictItems(candidates []*EvictionCandidate) error {
 	var evictedCount int
 
 	for _, candidate := range candidates {
+		// Removing one link to a hardlinked file frees nothing
+		freed := int64(0)
+		if linkCount(candidate.Path) <= 1 {
+			freed = candidate.Size
+		}
+
 		if err := c.evictSingleItem(candidate); err != nil {
 			c.logger.Error("Failed to evict item",
 				"path", candidate.Path,
@@ -310,13 +403,18 @@ func (c *CacheManager) EvictItems(candidates []*EvictionCandidate) error {
 			continue
 		}
 
-		totalEvicted += candidate.Size
+		totalEvicted += freed
 		evictedCount++
 
+		if err := c.storage.RecordEviction(freed); err != nil {
+			c.logger.Debug("Failed to record eviction stats", "error", err)
+		}
+
 		c.logger.Info("Evicted cached item",
 			"jellyfin_id", candidate.JellyfinID,
 			"size_mb", candidate.Size/(1024*1024),
 			"last_accessed", candidate.LastAccessed.Format(time.RFC3339))
+		c.recordEviction(candidate)
 	}
 
 	c.logger.Info("Cache eviction completed",
@@ -326,6 +424,31 @@ func (c *CacheManager) EvictItems(candidates []*EvictionCandidate) error {
 	return nil
 }
 
+// recordEviction adds an eviction to the event log with what the eviction
+// policy weighed.
+func (c *CacheManager) recordEviction(candidate *EvictionCandidate) {
+	policy := c.config.EvictionPolicy
+	if policy == "" {
+		policy = EvictionLRU
+	}
+	event := &Event{
+		Kind:    EventEvicted,
+		MediaID: candidate.JellyfinID,
+		Message: fmt.Sprintf("Evicted by the %s policy to free space", policy),
+		Details: map[string]interface{}{
+			"size_bytes":    candidate.Size,
+			"score":         candidate.Score,
+			"policy":        policy,
+			"last_accessed": candidate.LastAccessed,
+			"access_count":  candidate.AccessCount,
+			"watched":       candidate.Watched,
+		},
+	}
+	if err := c.storage.RecordEvent(event); err != nil {
+		c.logger.Debug("Failed to record eviction event", "jellyfin_id", candidate.JellyfinID, "error", err)
+	}
+}
+
 // evictSingleItem removes a single item from both filesystem and database.
 func (c *CacheManager) evictSingleItem(candidate *EvictionCandidate) error {
 	// Remove from filesystem
@@ -333,12 +456,19 @@ func (c *CacheManager) evictSingleItem(candidate *EvictionCandidate) error {
 		return fmt.Errorf("failed to remove file %s: %w", candidate.Path, err)
 	}
 
-	// Remove metadata file if it exists
-	metadataPath := filepath.Join(filepath.Dir(candidate.Path), ".meta.json")
-	if err := os.Remove(metadataPath); err != nil && !os.IsNotExist(err) {
-		c.logger.Debug("Failed to remove metadata file",
-			"path", metadataPath,
-			"error", err)
+	// Remove metadata file, artwork, seek previews, HLS segments and .nfo
+	// if they exist
+	sidecars := []string{filepath.Join(filepath.Dir(candidate.Path), ".meta.json")}
+	for _, imageType := range ArtworkTypes {
+		sidecars = append(sidecars, ArtworkPath(candidate.Path, imageType))
+	}
+	sidecars = append(sidecars, TrickplayDir(candidate.Path), HLSDir(candidate.Path), NFOPath(candidate.Path))
+	for _, path := range sidecars {
+		if err := os.RemoveAll(path); err != nil {
+			c.logger.Debug("Failed to remove sidecar file",
+				"path", path,
+				"error", err)
+		}
 	}
 
 	// Remove empty directory if this was the last file
@@ -347,13 +477,18 @@ func (c *CacheManager) evictSingleItem(candidate *EvictionCandidate) error {
 		os.Remove(mediaDir)
 	}
 
-	// Note: We don't remove from database to maintain download history
-	// The record will show that it was downloaded but is no longer cached
+	// Keep the record as download history, marked as no longer cached so
+	// integrity scans do not mistake the file for a lost one
+	if err := c.storage.MarkEvicted(candidate.MediaType, candidate.JellyfinID); err != nil {
+		c.logger.Warn("Failed to mark download record evicted",
+			"jellyfin_id", candidate.JellyfinID,
+			"error", err)
+	}
 
 	return nil
 }
 
-// isDirEmpty checks if a directory is empty or contains only .meta.json.
+// isDirEmpty checks if a directory is empty or contains only sidecar files.
 func (c *CacheManager) isDirEmpty(dir string) (bool, error) {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
@@ -361,7 +496,7 @@ func (c *CacheManager) isDirEmpty(dir string) (bool, error) {
 	}
 
 	for _, entry := range entries {
-		if entry.Name() != ".meta.json" {
+		if !IsSidecar(entry.Name()) {
 			return false, nil
 		}
 	}
@@ -373,6 +508,8 @@ func (c *CacheManager) isDirEmpty(dir string) (bool, error) {
 // Implements two-tier cleanup:
 // - Normal cleanup at eviction threshold (default 85%) targets 70% utilization
 // - Emergency cleanup at 95% capacity targets 60% utilization with more aggressive eviction
+//
+// With a maintenance window set, normal cleanup only runs inside it.
 func (c *CacheManager) CleanupCache() error {
 	utilization, err := c.GetCacheUtilization()
 	if err != nil {
@@ -382,12 +519,20 @@ func (c *CacheManager) CleanupCache() error {
 	const emergencyThreshold = 0.95
 	isEmergency := utilization >= emergencyThreshold
 
-	if utilization < c.config.EvictionThreshold {
+	threshold := c.EvictionThreshold()
+	if utilization < threshold {
 		c.logger.Debug("Cache cleanup not needed",
 			"utilization", fmt.Sprintf("%.1f%%", utilization*100))
 		return nil
 	}
 
+	if !isEmergency && c.maintenance != nil && !c.maintenance.IsActive(time.Now()) {
+		c.logger.Info("Deferring cache cleanup to maintenance window",
+			"utilization", fmt.Sprintf("%.1f%%", utilization*100),
+			"window_start", c.maintenance.Start)
+		return nil
+	}
+
 	if isEmergency {
 		c.logger.Warn("Emergency cache cleanup triggered",
 			"utilization", fmt.Sprintf("%.1f%%", utilization*100),
@@ -395,7 +540,7 @@ func (c *CacheManager) CleanupCache() error {
 	} else {
 		c.logger.Info("Starting cache cleanup",
 			"utilization", fmt.Sprintf("%.1f%%", utilization*100),
-			"threshold", fmt.Sprintf("%.1f%%", c.config.EvictionThreshold*100))
+			"threshold", fmt.Sprintf("%.1f%%", threshold*100))
 	}
 
 	// Calculate target reduction
@@ -404,7 +549,7 @@ func (c *CacheManager) CleanupCache() error {
 	if isEmergency {
 		targetUtilization = 0.60
 	}
-	maxSizeBytes := int64(c.config.MaxSizeGB) * 1024 * 1024 * 1024
+	maxSizeBytes := c.MaxSize()
 	targetReduction := int64(float64(maxSizeBytes) * (utilization - targetUtilization))
 
 	candidates, err := c.GetEvictionCandidates(targetReduction)
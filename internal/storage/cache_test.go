@@ -90,6 +90,30 @@ func TestCacheManagerGetCacheUtilization(t *testing.T) {
 	}
 }
 
+func TestCacheManagerSetLimits(t *testing.T) {
+	tempDir := t.TempDir()
+	storage := createTestManager(t, tempDir)
+	defer storage.Close()
+	cacheManager := createTestCacheManagerWithStorage(t, tempDir, storage)
+
+	cacheManager.SetLimits(20, 0.9)
+	if got := cacheManager.MaxSize(); got != 20*1024*1024*1024 {
+		t.Errorf("Expected a 20GB limit, got %d", got)
+	}
+	if got := cacheManager.EvictionThreshold(); got != 0.9 {
+		t.Errorf("Expected threshold 0.9, got %v", got)
+	}
+
+	// Storage statistics report the new limit too
+	stats, err := storage.GetStorageStats()
+	if err != nil {
+		t.Fatalf("Failed to get storage stats: %v", err)
+	}
+	if stats.MaxSize != 20*1024*1024*1024 {
+		t.Errorf("Expected stats to report the 20GB limit, got %d", stats.MaxSize)
+	}
+}
+
 func TestCacheManagerNeedsCleanup(t *testing.T) {
 	tempDir := t.TempDir()
 	cfg := &config.CacheConfig{
@@ -165,6 +189,24 @@ func TestGetMediaPath(t *testing.T) {
 			filename:   "episode.mkv",
 			expected:   filepath.Join(tempDir, "series", "series-456", "S01E05", "episode.mkv"),
 		},
+		{
+			name:       "music track path",
+			mediaType:  "audio",
+			jellyfinID: "album-321",
+			seasonNum:  2,
+			episodeNum: 7,
+			filename:   "track.flac",
+			expected:   filepath.Join(tempDir, "music", "album-321", "D02T07", "track.flac"),
+		},
+		{
+			name:       "audiobook chapter path",
+			mediaType:  "audiobook",
+			jellyfinID: "book-654",
+			seasonNum:  1,
+			episodeNum: 12,
+			filename:   "chapter.m4b",
+			expected:   filepath.Join(tempDir, "audiobooks", "book-654", "P01C012", "chapter.m4b"),
+		},
 		{
 			name:       "other media type",
 			mediaType:  "music",
@@ -332,6 +374,69 @@ func TestGetEvictionCandidates(t *testing.T) {
 	}
 }
 
+func TestGetEvictionCandidatesSkipsPinned(t *testing.T) {
+	tempDir := t.TempDir()
+	storage := createTestManager(t, tempDir)
+	defer storage.Close()
+
+	cacheManager := createTestCacheManagerWithStorage(t, tempDir, storage)
+
+	old := time.Now().Add(-48 * time.Hour)
+	for _, id := range []string{"favorite", "episode", "other"} {
+		mediaPath := filepath.Join(tempDir, "movies", id, "video.mkv")
+		os.MkdirAll(filepath.Dir(mediaPath), 0755)
+		if err := os.WriteFile(mediaPath, make([]byte, 1000), 0644); err != nil {
+			t.Fatalf("Failed to create test file %s: %v", mediaPath, err)
+		}
+		if err := storage.AddDownloadRecord(&DownloadRecord{
+			ID: id, MediaType: "movie", JellyfinID: id,
+			LocalPath: mediaPath, Size: 1000, LastAccessed: old,
+		}); err != nil {
+			t.Fatalf("Failed to add download record: %v", err)
+		}
+	}
+	if err := storage.AddMediaMetadata(&MediaMetadata{ID: "episode", JellyfinID: "episode", Type: "episode", SeriesID: "kids-show"}); err != nil {
+		t.Fatalf("Failed to add metadata: %v", err)
+	}
+
+	// One item pinned directly, one through its series
+	if err := storage.PinMedia("favorite"); err != nil {
+		t.Fatalf("Failed to pin item: %v", err)
+	}
+	if err := storage.PinMedia("kids-show"); err != nil {
+		t.Fatalf("Failed to pin series: %v", err)
+	}
+
+	candidates, err := cacheManager.GetEvictionCandidates(1 << 30)
+	if err != nil {
+		t.Fatalf("Failed to get eviction candidates: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].JellyfinID != "other" {
+		t.Fatalf("Expected only the unpinned item as a candidate, got %+v", candidates)
+	}
+
+	if err := storage.UnpinMedia("favorite"); err != nil {
+		t.Fatalf("Failed to unpin item: %v", err)
+	}
+	candidates, err = cacheManager.GetEvictionCandidates(1 << 30)
+	if err != nil {
+		t.Fatalf("Failed to get eviction candidates: %v", err)
+	}
+	if len(candidates) != 2 {
+		t.Errorf("Expected unpinned item to be evictable again, got %d candidates", len(candidates))
+	}
+
+	items, err := storage.GetCachedItems("", 1, 10)
+	if err != nil {
+		t.Fatalf("Failed to get cached items: %v", err)
+	}
+	for _, item := range items {
+		if want := item.ID == "episode"; item.Pinned != want {
+			t.Errorf("Expected %s pinned=%v in library listing, got %v", item.ID, want, item.Pinned)
+		}
+	}
+}
+
 func TestCleanupCache(t *testing.T) {
 	tempDir := t.TempDir()
 	cfg := &config.CacheConfig{
@@ -400,3 +505,67 @@ func createTestCacheManagerWithStorage(t *testing.T, tempDir string, storage *Ma
 
 	return NewCacheManager(cfg, storage, logger)
 }
+
+// TestHardlinkAwareAccounting tests that hardlinked files are counted once
+func TestHardlinkAwareAccounting(t *testing.T) {
+	manager := newStatsTestManager(t)
+
+	heat := addCachedFile(t, manager, "m1", "movie", "Heat", "0123456789")
+	linked := addCachedFile(t, manager, "m2", "movie", "Heat (Collection)", "0123456789")
+	addCachedFile(t, manager, "m3", "movie", "Alien", "abcde")
+
+	if err := os.Remove(linked.LocalPath); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	if err := os.Link(heat.LocalPath, linked.LocalPath); err != nil {
+		t.Skipf("hardlinks not supported: %v", err)
+	}
+	info, _ := os.Stat(heat.LocalPath)
+	if _, _, ok := fileIdentity(info); !ok {
+		t.Skip("file identity not supported on this platform")
+	}
+
+	stats, err := manager.GetCacheStats()
+	if err != nil {
+		t.Fatalf("GetCacheStats failed: %v", err)
+	}
+	if stats.TotalSizeBytes != 15 {
+		t.Errorf("Expected 15 bytes in cache stats, got %d", stats.TotalSizeBytes)
+	}
+	if stats.TotalItems != 3 {
+		t.Errorf("Expected 3 items, got %d", stats.TotalItems)
+	}
+
+	storageStats, err := manager.GetStorageStats()
+	if err != nil {
+		t.Fatalf("GetStorageStats failed: %v", err)
+	}
+	if storageStats.TotalSize != 15 {
+		t.Errorf("Expected 15 bytes in storage stats, got %d", storageStats.TotalSize)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	cacheManager := NewCacheManager(manager.config, manager, logger)
+
+	size, err := cacheManager.GetCacheSize()
+	if err != nil {
+		t.Fatalf("GetCacheSize failed: %v", err)
+	}
+	if size != 15 {
+		t.Errorf("Expected cache size 15, got %d", size)
+	}
+
+	// Evicting one link frees nothing, so both links are needed to free 10 bytes
+	candidates, err := cacheManager.GetEvictionCandidates(11)
+	if err != nil {
+		t.Fatalf("GetEvictionCandidates failed: %v", err)
+	}
+	if len(candidates) != 3 {
+		t.Errorf("Expected all 3 items to be needed to free 11 bytes, got %d", len(candidates))
+	}
+	for _, candidate := range candidates {
+		if candidate.JellyfinID != "m3" && candidate.Links != 2 {
+			t.Errorf("Expected hardlinked entry %s to report 2 links, got %d", candidate.JellyfinID, candidate.Links)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// Kinds of collection.
+const (
+	CollectionKindCollection = "collection" // A Jellyfin collection (box set)
+	CollectionKindPlaylist   = "playlist"
+)
+
+// Collection is a Jellyfin collection or playlist as last synced, with its
+// cacheable items in collection or playlist order.
+// Key pattern: {collection-id} in the collections bucket
+type Collection struct {
+	ID       string           `json:"id"`
+	Name     string           `json:"name"`
+	Kind     string           `json:"kind"`  // One of the CollectionKind constants
+	Items    []CollectionItem `json:"items"` // In collection or playlist order
+	SyncedAt time.Time        `json:"synced_at"`
+	// Cached is set once the collection was queued for caching; items
+	// added to it later are queued too, at Priority
+	Cached   bool      `json:"cached"`
+	Priority int       `json:"priority,omitempty"`
+	CachedAt time.Time `json:"cached_at,omitempty"`
+}
+
+// CollectionItem is an item of a collection.
+type CollectionItem struct {
+	MediaID string `json:"media_id"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`           // Cache media type: movie, episode, audio or audiobook
+	Size    int64  `json:"size,omitempty"` // Size reported by Jellyfin; 0 when unknown
+}
+
+// SaveCollection stores a collection, replacing any existing one with the
+// same ID.
+func (m *Manager) SaveCollection(collection *Collection) error {
+	if collection.ID == "" {
+		return fmt.Errorf("collection must have an ID")
+	}
+
+	data, err := json.Marshal(collection)
+	if err != nil {
+		return fmt.Errorf("failed to marshal collection: %w", err)
+	}
+
+	return m.update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketCollections).Put([]byte(collection.ID), data)
+	})
+}
+
+// GetCollection returns the collection with the given ID, or nil if there
+// is none.
+func (m *Manager) GetCollection(id string) (*Collection, error) {
+	var collection *Collection
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(bucketCollections).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		collection = &Collection{}
+		return json.Unmarshal(data, collection)
+	})
+
+	return collection, err
+}
+
+// ListCollections returns every collection, sorted by name.
+func (m *Manager) ListCollections() ([]*Collection, error) {
+	var collections []*Collection
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketCollections).ForEach(func(k, v []byte) error {
+			var collection Collection
+			if err := json.Unmarshal(v, &collection); err != nil {
+				return nil // Continue on marshal errors
+			}
+			collections = append(collections, &collection)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+
+	sort.Slice(collections, func(i, j int) bool {
+		if collections[i].Name != collections[j].Name {
+			return collections[i].Name < collections[j].Name
+		}
+		return collections[i].ID < collections[j].ID
+	})
+	return collections, nil
+}
+
+// RemoveCollection deletes a collection. It is a no-op if there is none.
+func (m *Manager) RemoveCollection(id string) error {
+	return m.update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketCollections).Delete([]byte(id))
+	})
+}
@@ -0,0 +1,342 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Duplicate match kinds.
+const (
+	// DuplicateMatchChecksum means the files are byte-for-byte identical.
+	DuplicateMatchChecksum = "checksum"
+	// DuplicateMatchMetadata means the items describe the same content but the
+	// files differ, e.g. two quality variants of one movie.
+	DuplicateMatchMetadata = "metadata"
+)
+
+// Dedup modes.
+const (
+	// DedupHardlink replaces duplicates with hardlinks to the kept file so every
+	// ID stays playable from a single copy. Only valid for identical files.
+	DedupHardlink = "hardlink"
+	// DedupRemove deletes duplicate files and their download records.
+	DedupRemove = "remove"
+)
+
+// DuplicateGroup is a set of cached items holding the same content.
+type DuplicateGroup struct {
+	Key   string            `json:"key"`
+	Match string            `json:"match"` // checksum or metadata
+	Items []*DownloadRecord `json:"items"`
+	Bytes int64             `json:"reclaimable_bytes"` // Space freed by keeping only the largest item
+}
+
+// DedupResult reports the outcome of ResolveDuplicates.
+type DedupResult struct {
+	Linked     []string          `json:"linked,omitempty"`
+	Removed    []string          `json:"removed,omitempty"`
+	BytesFreed int64             `json:"bytes_freed"`
+	Errors     map[string]string `json:"errors,omitempty"` // Keyed by media ID
+}
+
+// FindDuplicates scans completed downloads for content cached more than once
+// under different IDs. Files are first grouped by size so only candidates are
+// hashed; computed checksums are saved on the download record for later scans.
+// Items that are already hardlinked to each other are not reported.
+func (m *Manager) FindDuplicates(ctx context.Context) ([]*DuplicateGroup, error) {
+	records, err := m.ListDownloadRecords("")
+	if err != nil {
+		return nil, err
+	}
+
+	bySize := make(map[int64][]*DownloadRecord)
+	var cached []*DownloadRecord
+	for _, record := range records {
+		if record.Status != "" && record.Status != "completed" {
+			continue
+		}
+		size, err := CachedSize(record.LocalPath)
+		if err != nil {
+			continue // No longer on disk
+		}
+		record.Size = size
+		cached = append(cached, record)
+		bySize[record.Size] = append(bySize[record.Size], record)
+	}
+
+	var groups []*DuplicateGroup
+	grouped := make(map[string]bool) // media IDs already in a checksum group
+
+	for _, candidates := range bySize {
+		if len(candidates) < 2 {
+			continue
+		}
+
+		algorithm := m.dedupAlgorithm()
+		byChecksum := make(map[string][]*DownloadRecord)
+		for _, record := range candidates {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			checksum, err := m.recordChecksum(record, algorithm)
+			if err != nil {
+				m.logger.Warn("Failed to checksum cached file",
+					"media_id", record.JellyfinID, "error", err)
+				continue
+			}
+			byChecksum[checksum] = append(byChecksum[checksum], record)
+		}
+
+		for checksum, items := range byChecksum {
+			if len(items) < 2 || allSameFile(items) {
+				continue
+			}
+			for _, item := range items {
+				grouped[item.JellyfinID] = true
+			}
+			groups = append(groups, newDuplicateGroup(algorithm+":"+checksum, DuplicateMatchChecksum, items))
+		}
+	}
+
+	byContent := make(map[string][]*DownloadRecord)
+	for _, record := range cached {
+		if grouped[record.JellyfinID] {
+			continue
+		}
+		if key := m.contentKey(record); key != "" {
+			byContent[key] = append(byContent[key], record)
+		}
+	}
+	for key, items := range byContent {
+		if len(items) < 2 || allSameFile(items) {
+			continue
+		}
+		groups = append(groups, newDuplicateGroup(key, DuplicateMatchMetadata, items))
+	}
+
+	// Largest savings first
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Bytes != groups[j].Bytes {
+			return groups[i].Bytes > groups[j].Bytes
+		}
+		return groups[i].Key < groups[j].Key
+	})
+
+	return groups, nil
+}
+
+// ResolveDuplicates keeps keepID and deduplicates each of duplicateIDs using
+// mode. Hardlinking requires the files to be identical. Failures for one item
+// are reported in the result and do not stop the others.
+func (m *Manager) ResolveDuplicates(keepID string, duplicateIDs []string, mode string) (*DedupResult, error) {
+	if mode != DedupHardlink && mode != DedupRemove {
+		return nil, fmt.Errorf("mode must be %q or %q", DedupHardlink, DedupRemove)
+	}
+
+	keep, err := m.GetDownload(keepID)
+	if err != nil {
+		return nil, fmt.Errorf("item to keep is not cached: %w", err)
+	}
+	if _, err := os.Stat(keep.LocalPath); err != nil {
+		return nil, fmt.Errorf("item to keep is missing from disk: %w", err)
+	}
+
+	result := &DedupResult{Errors: make(map[string]string)}
+	for _, id := range duplicateIDs {
+		if id == keepID {
+			continue
+		}
+
+		dup, err := m.GetDownload(id)
+		if err != nil {
+			result.Errors[id] = err.Error()
+			continue
+		}
+
+		var freed int64
+		if mode == DedupHardlink {
+			freed, err = m.hardlinkDuplicate(keep, dup)
+		} else {
+			freed, err = m.removeDuplicate(keep, dup)
+		}
+		if err != nil {
+			result.Errors[id] = err.Error()
+			continue
+		}
+
+		result.BytesFreed += freed
+		if mode == DedupHardlink {
+			result.Linked = append(result.Linked, id)
+		} else {
+			result.Removed = append(result.Removed, id)
+		}
+
+		m.logger.Info("Deduplicated cached item",
+			"mode", mode,
+			"kept", keepID,
+			"duplicate", id,
+			"bytes_freed", freed)
+	}
+
+	if len(result.Errors) == 0 {
+		result.Errors = nil
+	}
+	return result, nil
+}
+
+// hardlinkDuplicate replaces dup's file with a hardlink to keep's file.
+func (m *Manager) hardlinkDuplicate(keep, dup *DownloadRecord) (int64, error) {
+	keepInfo, err := os.Stat(keep.LocalPath)
+	if err != nil {
+		return 0, err
+	}
+	dupInfo, err := os.Stat(dup.LocalPath)
+	if err != nil {
+		return 0, err
+	}
+	if os.SameFile(keepInfo, dupInfo) {
+		return 0, nil // Already linked
+	}
+
+	algorithm := m.dedupAlgorithm()
+	keepSum, err := m.recordChecksum(keep, algorithm)
+	if err != nil {
+		return 0, err
+	}
+	dupSum, err := m.recordChecksum(dup, algorithm)
+	if err != nil {
+		return 0, err
+	}
+	if keepSum != dupSum {
+		return 0, fmt.Errorf("files differ; only identical files can be hardlinked")
+	}
+
+	// Link beside the duplicate, then atomically replace it
+	tempPath := dup.LocalPath + ".dedup"
+	os.Remove(tempPath)
+	if err := os.Link(keep.LocalPath, tempPath); err != nil {
+		return 0, fmt.Errorf("failed to create hardlink: %w", err)
+	}
+	if err := os.Rename(tempPath, dup.LocalPath); err != nil {
+		os.Remove(tempPath)
+		return 0, fmt.Errorf("failed to replace duplicate: %w", err)
+	}
+
+	return dupInfo.Size(), nil
+}
+
+// removeDuplicate deletes dup's file and download record.
+func (m *Manager) removeDuplicate(keep, dup *DownloadRecord) (int64, error) {
+	info, err := os.Stat(dup.LocalPath)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+
+	var freed int64
+	if info != nil {
+		// Removing one name of a hardlinked pair frees nothing
+		if keepInfo, err := os.Stat(keep.LocalPath); err == nil && !os.SameFile(keepInfo, info) {
+			freed = info.Size()
+		}
+		if err := os.Remove(dup.LocalPath); err != nil {
+			return 0, fmt.Errorf("failed to remove duplicate: %w", err)
+		}
+	}
+
+	if err := m.RemoveDownloadRecord(dup.MediaType, dup.JellyfinID); err != nil {
+		return 0, err
+	}
+	return freed, nil
+}
+
+// recordChecksum returns the checksum of a record's file using algorithm,
+// computing it if the record does not already hold one made with algorithm.
+// A newly computed checksum is saved on records that had none.
+func (m *Manager) recordChecksum(record *DownloadRecord, algorithm string) (string, error) {
+	if record.Checksum != "" && record.checksumAlgorithm() == algorithm {
+		return record.Checksum, nil
+	}
+
+	checksum, err := m.files().CalculateChecksumWith(record.LocalPath, algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	if record.Checksum == "" {
+		record.Checksum = checksum
+		record.ChecksumAlgorithm = algorithm
+		if err := m.AddDownloadRecord(record); err != nil {
+			m.logger.Debug("Failed to save checksum", "media_id", record.JellyfinID, "error", err)
+		}
+	}
+	return checksum, nil
+}
+
+// dedupAlgorithm returns the algorithm duplicate detection compares files
+// with. Finding duplicates needs some checksum even when routine checksums
+// are turned off.
+func (m *Manager) dedupAlgorithm() string {
+	if algorithm := m.ChecksumAlgorithm(); algorithm != ChecksumOff {
+		return algorithm
+	}
+	return ChecksumSHA256
+}
+
+// contentKey identifies the content a record describes independently of its
+// ID: series, season and episode for episodes with metadata, album, disc and
+// track for music and audiobooks, otherwise the media type and normalized
+// title. Returns "" if there is nothing to match on.
+func (m *Manager) contentKey(record *DownloadRecord) string {
+	if record.MediaType == "episode" {
+		if metadata, err := m.GetMediaMetadata(record.JellyfinID); err == nil && metadata.SeriesID != "" && metadata.EpisodeNumber > 0 {
+			return fmt.Sprintf("episode:%s:s%02de%02d", metadata.SeriesID, metadata.SeasonNumber, metadata.EpisodeNumber)
+		}
+	}
+	if record.MediaType == "audio" || record.MediaType == "audiobook" {
+		if metadata, err := m.GetMediaMetadata(record.JellyfinID); err == nil && metadata.AlbumID != "" && metadata.TrackNumber > 0 {
+			return fmt.Sprintf("%s:%s:d%02dt%03d", record.MediaType, metadata.AlbumID, metadata.DiscNumber, metadata.TrackNumber)
+		}
+	}
+
+	title := strings.Join(strings.Fields(strings.ToLower(record.Title)), " ")
+	if title == "" {
+		return ""
+	}
+	return record.MediaType + ":" + title
+}
+
+// newDuplicateGroup builds a group, keeping the largest item first since it
+// is usually the best quality copy.
+func newDuplicateGroup(key, match string, items []*DownloadRecord) *DuplicateGroup {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Size != items[j].Size {
+			return items[i].Size > items[j].Size
+		}
+		return items[i].JellyfinID < items[j].JellyfinID
+	})
+
+	group := &DuplicateGroup{Key: key, Match: match, Items: items}
+	for _, item := range items[1:] {
+		group.Bytes += item.Size
+	}
+	return group
+}
+
+// allSameFile reports whether every record points at the same file on disk,
+// e.g. because they were already hardlinked.
+func allSameFile(records []*DownloadRecord) bool {
+	first, err := os.Stat(records[0].LocalPath)
+	if err != nil {
+		return false
+	}
+	for _, record := range records[1:] {
+		info, err := os.Stat(record.LocalPath)
+		if err != nil || !os.SameFile(first, info) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func addCachedFile(t *testing.T, manager *Manager, id, mediaType, title, content string) *DownloadRecord {
+	path := filepath.Join(manager.config.Directory, "movies", id, "video.mp4")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	record := &DownloadRecord{
+		ID: id, JellyfinID: id, MediaType: mediaType, Title: title,
+		LocalPath: path, Size: int64(len(content)), Status: "completed", DownloadedAt: time.Now(),
+	}
+	if err := manager.AddDownloadRecord(record); err != nil {
+		t.Fatalf("Failed to add download record: %v", err)
+	}
+	return record
+}
+
+func TestFindAndResolveDuplicates(t *testing.T) {
+	manager := newStatsTestManager(t)
+	ctx := context.Background()
+
+	standalone := addCachedFile(t, manager, "m1", "movie", "Heat", "identical movie bytes")
+	collection := addCachedFile(t, manager, "m2", "movie", "Heat (Collection)", "identical movie bytes")
+	addCachedFile(t, manager, "m3", "movie", "Dune", "dune 1080p")
+	addCachedFile(t, manager, "m4", "movie", "  dune ", "dune 4k remux, larger")
+	addCachedFile(t, manager, "m5", "movie", "Alien", "unique")
+
+	groups, err := manager.FindDuplicates(ctx)
+	if err != nil {
+		t.Fatalf("FindDuplicates failed: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 duplicate groups, got %d", len(groups))
+	}
+
+	var checksumGroup, metadataGroup *DuplicateGroup
+	for _, group := range groups {
+		switch group.Match {
+		case DuplicateMatchChecksum:
+			checksumGroup = group
+		case DuplicateMatchMetadata:
+			metadataGroup = group
+		}
+	}
+	if checksumGroup == nil || len(checksumGroup.Items) != 2 {
+		t.Fatalf("Expected a checksum group of 2 items, got %+v", checksumGroup)
+	}
+	if metadataGroup == nil || metadataGroup.Items[0].JellyfinID != "m4" {
+		t.Fatalf("Expected a metadata group keeping the larger m4 first, got %+v", metadataGroup)
+	}
+
+	t.Run("hardlink refuses different files", func(t *testing.T) {
+		result, err := manager.ResolveDuplicates("m4", []string{"m3"}, DedupHardlink)
+		if err != nil {
+			t.Fatalf("ResolveDuplicates failed: %v", err)
+		}
+		if result.Errors["m3"] == "" {
+			t.Error("Expected an error hardlinking files with different content")
+		}
+	})
+
+	t.Run("hardlink identical files", func(t *testing.T) {
+		result, err := manager.ResolveDuplicates("m1", []string{"m2"}, DedupHardlink)
+		if err != nil {
+			t.Fatalf("ResolveDuplicates failed: %v", err)
+		}
+		if len(result.Linked) != 1 || result.BytesFreed == 0 {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+
+		a, _ := os.Stat(standalone.LocalPath)
+		b, _ := os.Stat(collection.LocalPath)
+		if !os.SameFile(a, b) {
+			t.Error("Expected duplicate to be hardlinked to the kept file")
+		}
+	})
+
+	t.Run("remove variant", func(t *testing.T) {
+		result, err := manager.ResolveDuplicates("m4", []string{"m3"}, DedupRemove)
+		if err != nil {
+			t.Fatalf("ResolveDuplicates failed: %v", err)
+		}
+		if len(result.Removed) != 1 {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+		if cached, _ := manager.IsMediaCached("m3"); cached {
+			t.Error("Expected removed duplicate to no longer be cached")
+		}
+	})
+
+	groups, err = manager.FindDuplicates(ctx)
+	if err != nil {
+		t.Fatalf("FindDuplicates failed: %v", err)
+	}
+	if len(groups) != 0 {
+		t.Errorf("Expected no duplicates after resolving, got %d", len(groups))
+	}
+
+	if _, err := manager.ResolveDuplicates("m1", []string{"m2"}, "copy"); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// deviceUsagePrefix prefixes per-device usage keys in the stats bucket.
+const deviceUsagePrefix = "device:"
+
+// DeviceUsage records how often a class of client device started playback,
+// broken down by local hour of day.
+// Key pattern: device:{class}
+type DeviceUsage struct {
+	Class    string    `json:"class"`
+	Plays    int       `json:"plays"`
+	Hours    [24]int   `json:"hours"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// RecordDevicePlayback counts a playback start on a device of the given
+// class at time at.
+func (m *Manager) RecordDevicePlayback(class string, at time.Time) error {
+	if class == "" {
+		return fmt.Errorf("device class is required")
+	}
+
+	return m.update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(bucketStats)
+		if err != nil {
+			return fmt.Errorf("failed to create stats bucket: %w", err)
+		}
+
+		key := []byte(deviceUsagePrefix + class)
+		usage := DeviceUsage{Class: class}
+		if data := bucket.Get(key); data != nil {
+			if err := json.Unmarshal(data, &usage); err != nil {
+				m.logger.Warn("Failed to unmarshal device usage", "key", string(key), "error", err)
+			}
+		}
+
+		usage.Plays++
+		usage.Hours[at.Hour()]++
+		usage.LastSeen = at
+
+		data, err := json.Marshal(&usage)
+		if err != nil {
+			return fmt.Errorf("failed to marshal device usage: %w", err)
+		}
+		return bucket.Put(key, data)
+	})
+}
+
+// GetDeviceUsage returns the recorded usage of every device class, most
+// used first.
+func (m *Manager) GetDeviceUsage() ([]*DeviceUsage, error) {
+	var usage []*DeviceUsage
+	prefix := []byte(deviceUsagePrefix)
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketStats)
+		if bucket == nil {
+			return nil
+		}
+
+		c := bucket.Cursor()
+		for k, v := c.Seek(prefix); k != nil && strings.HasPrefix(string(k), deviceUsagePrefix); k, v = c.Next() {
+			var device DeviceUsage
+			if err := json.Unmarshal(v, &device); err != nil {
+				m.logger.Warn("Failed to unmarshal device usage", "key", string(k), "error", err)
+				continue
+			}
+			usage = append(usage, &device)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Plays != usage[j].Plays {
+			return usage[i].Plays > usage[j].Plays
+		}
+		return usage[i].Class < usage[j].Class
+	})
+
+	return usage, nil
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Disk health states reported by CheckDiskHealth.
+const (
+	DiskStatusOK       = "ok"
+	DiskStatusLowSpace = "low_space"
+	DiskStatusFailing  = "failing"
+)
+
+// SMART health results. SMARTUnknown covers a missing smartctl binary,
+// an unreadable device, or output that could not be interpreted.
+const (
+	SMARTDisabled = "disabled"
+	SMARTPassed   = "passed"
+	SMARTFailing  = "failing"
+	SMARTUnknown  = "unknown"
+)
+
+// Trend tracking keeps at most this many samples within this window.
+const (
+	maxDiskSamples   = 48
+	diskSampleWindow = 24 * time.Hour
+)
+
+// errDiskUsageUnsupported is returned by diskUsage on platforms without statfs.
+var errDiskUsageUnsupported = errors.New("disk usage not supported on this platform")
+
+// DiskHealth summarizes the capacity, free space trend and SMART status
+// of the disk holding the cache directory.
+type DiskHealth struct {
+	Path        string  `json:"path"`
+	Status      string  `json:"status"` // ok, low_space, failing
+	Message     string  `json:"message,omitempty"`
+	TotalBytes  uint64  `json:"total_bytes"`
+	FreeBytes   uint64  `json:"free_bytes"`
+	UsedPercent float64 `json:"used_percent"`
+	// FreeBytesPerHour is the recent change in free space; negative while
+	// the disk is filling up. Zero until enough samples are collected.
+	FreeBytesPerHour float64   `json:"free_bytes_per_hour"`
+	SMARTStatus      string    `json:"smart_status"`
+	CheckedAt        time.Time `json:"checked_at"`
+}
+
+// diskSample is a point-in-time free space measurement.
+type diskSample struct {
+	at   time.Time
+	free uint64
+}
+
+// CheckDiskHealth measures free space on the cache disk, updates the free
+// space trend and, when a SMART device is configured, queries smartctl.
+// The disk is reported failing if SMART says so, and low on space when free
+// space drops below the configured minimum, unless that is negative.
+func (m *Manager) CheckDiskHealth(ctx context.Context) (*DiskHealth, error) {
+	total, free, err := diskUsage(m.config.Directory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disk usage for %s: %w", m.config.Directory, err)
+	}
+
+	now := time.Now()
+	health := &DiskHealth{
+		Path:             m.config.Directory,
+		Status:           DiskStatusOK,
+		TotalBytes:       total,
+		FreeBytes:        free,
+		FreeBytesPerHour: m.recordDiskSample(now, free),
+		SMARTStatus:      SMARTDisabled,
+		CheckedAt:        now,
+	}
+	if total > 0 {
+		health.UsedPercent = float64(total-free) / float64(total) * 100
+	}
+
+	if m.config.SmartDevice != "" {
+		health.SMARTStatus = checkSMART(ctx, m.config.SmartDevice)
+	}
+
+	switch {
+	case health.SMARTStatus == SMARTFailing:
+		health.Status = DiskStatusFailing
+		health.Message = fmt.Sprintf("SMART reports %s is failing", m.config.SmartDevice)
+	case m.config.MinFreeGB > 0 && free < uint64(m.config.MinFreeGB)*1024*1024*1024:
+		health.Status = DiskStatusLowSpace
+		health.Message = fmt.Sprintf("only %.1f GB free, minimum is %d GB",
+			float64(free)/(1024*1024*1024), m.config.MinFreeGB)
+	}
+
+	return health, nil
+}
+
+// recordDiskSample stores a free space sample and returns the change in
+// free bytes per hour across the retained samples.
+func (m *Manager) recordDiskSample(at time.Time, free uint64) float64 {
+	m.diskMu.Lock()
+	defer m.diskMu.Unlock()
+
+	m.diskSamples = append(m.diskSamples, diskSample{at: at, free: free})
+
+	// Drop samples outside the trend window, and the oldest beyond the cap
+	cutoff := at.Add(-diskSampleWindow)
+	start := 0
+	for start < len(m.diskSamples)-1 && m.diskSamples[start].at.Before(cutoff) {
+		start++
+	}
+	if len(m.diskSamples)-start > maxDiskSamples {
+		start = len(m.diskSamples) - maxDiskSamples
+	}
+	m.diskSamples = m.diskSamples[start:]
+
+	first := m.diskSamples[0]
+	hours := at.Sub(first.at).Hours()
+	if hours <= 0 {
+		return 0
+	}
+	return (float64(free) - float64(first.free)) / hours
+}
+
+// checkSMART runs "smartctl -H" against device and interprets the result.
+func checkSMART(ctx context.Context, device string) string {
+	output, err := exec.CommandContext(ctx, "smartctl", "-H", device).CombinedOutput()
+
+	exitCode := 0
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			// smartctl not installed or could not be started
+			return SMARTUnknown
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	return parseSMARTHealth(string(output), exitCode)
+}
+
+// parseSMARTHealth interprets smartctl -H output. smartctl's exit status is
+// a bitmask: bits 0-1 mean the command or device open failed, and bit 3
+// means the disk's own health self-assessment reports failure.
+func parseSMARTHealth(output string, exitCode int) string {
+	if exitCode&0x08 != 0 {
+		return SMARTFailing
+	}
+	if exitCode&0x03 != 0 {
+		return SMARTUnknown
+	}
+
+	switch {
+	case strings.Contains(output, "FAILED"):
+		return SMARTFailing
+	case strings.Contains(output, "PASSED"), strings.Contains(output, "SMART Health Status: OK"):
+		return SMARTPassed
+	default:
+		return SMARTUnknown
+	}
+}
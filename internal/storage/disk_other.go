@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !freebsd
+
+package storage
+
+import "os"
+
+// diskUsage is not implemented on this platform.
+func diskUsage(path string) (total, free uint64, err error) {
+	return 0, 0, errDiskUsageUnsupported
+}
+
+// fileIdentity is not implemented on this platform, so hardlinked files are
+// counted once per link.
+func fileIdentity(info os.FileInfo) (id fileID, links uint64, ok bool) {
+	return fileID{}, 0, false
+}
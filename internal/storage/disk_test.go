@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestCheckDiskHealth(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.CacheConfig{
+		Directory:     t.TempDir(),
+		MaxSizeGB:     1,
+		MetadataStore: "boltdb",
+	}
+
+	manager, err := NewManager(cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	defer manager.Close()
+
+	health, err := manager.CheckDiskHealth(context.Background())
+	if err == errDiskUsageUnsupported {
+		t.Skip("disk usage not supported on this platform")
+	}
+	if err != nil {
+		t.Fatalf("CheckDiskHealth failed: %v", err)
+	}
+
+	if health.TotalBytes == 0 || health.FreeBytes > health.TotalBytes {
+		t.Errorf("Unexpected disk usage: total=%d free=%d", health.TotalBytes, health.FreeBytes)
+	}
+	if health.Status != DiskStatusOK {
+		t.Errorf("Expected status %s, got %s (%s)", DiskStatusOK, health.Status, health.Message)
+	}
+	if health.SMARTStatus != SMARTDisabled {
+		t.Errorf("Expected SMART status %s, got %s", SMARTDisabled, health.SMARTStatus)
+	}
+
+	// No disk has this much free space
+	cfg.MinFreeGB = 1 << 30
+	health, err = manager.CheckDiskHealth(context.Background())
+	if err != nil {
+		t.Fatalf("CheckDiskHealth failed: %v", err)
+	}
+	if health.Status != DiskStatusLowSpace {
+		t.Errorf("Expected status %s, got %s", DiskStatusLowSpace, health.Status)
+	}
+
+	// A negative minimum turns the check off
+	cfg.MinFreeGB = -1
+	health, err = manager.CheckDiskHealth(context.Background())
+	if err != nil {
+		t.Fatalf("CheckDiskHealth failed: %v", err)
+	}
+	if health.Status != DiskStatusOK {
+		t.Errorf("Expected status %s with no minimum, got %s", DiskStatusOK, health.Status)
+	}
+}
+
+func TestRecordDiskSampleTrend(t *testing.T) {
+	manager := &Manager{}
+	start := time.Now()
+
+	if rate := manager.recordDiskSample(start, 10000); rate != 0 {
+		t.Errorf("Expected no trend from a single sample, got %f", rate)
+	}
+
+	rate := manager.recordDiskSample(start.Add(2*time.Hour), 8000)
+	if rate != -1000 {
+		t.Errorf("Expected -1000 bytes/hour, got %f", rate)
+	}
+
+	// Samples older than the window are dropped from the trend
+	rate = manager.recordDiskSample(start.Add(26*time.Hour+time.Minute), 8000)
+	if rate != 0 {
+		t.Errorf("Expected flat trend after old samples expire, got %f", rate)
+	}
+}
+
+func TestParseSMARTHealth(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		exitCode int
+		want     string
+	}{
+		{"ata passed", "SMART overall-health self-assessment test result: PASSED", 0, SMARTPassed},
+		{"scsi ok", "SMART Health Status: OK", 0, SMARTPassed},
+		{"ata failed", "SMART overall-health self-assessment test result: FAILED!", 8, SMARTFailing},
+		{"failing exit bit only", "", 8, SMARTFailing},
+		{"device open failed", "Smartctl open device: /dev/sdz failed", 2, SMARTUnknown},
+		{"unrecognized output", "something else", 0, SMARTUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSMARTHealth(tt.output, tt.exitCode); got != tt.want {
+				t.Errorf("parseSMARTHealth() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+//go:build linux || darwin || freebsd
+
+package storage
+
+import (
+	"os"
+	"syscall"
+)
+
+// diskUsage returns the total and available bytes of the filesystem
+// containing path.
+func diskUsage(path string) (total, free uint64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, 0, err
+	}
+
+	total = uint64(stat.Blocks) * uint64(stat.Bsize)
+	free = uint64(stat.Bavail) * uint64(stat.Bsize)
+	return total, free, nil
+}
+
+// fileIdentity returns the device and inode identifying info's file, and its
+// hardlink count.
+func fileIdentity(info os.FileInfo) (id fileID, links uint64, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, 0, false
+	}
+	return fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, uint64(stat.Nlink), true
+}
@@ -0,0 +1,450 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// ErrEncrypted is returned when opening an encrypted cached file without
+// a key.
+var ErrEncrypted = errors.New("cached file is encrypted and no cache key is configured")
+
+// errCorrupted is returned for an encrypted file that is truncated or
+// fails to decrypt with the right key.
+var errCorrupted = errors.New("encrypted file is corrupted")
+
+// Encrypted cache files start with encryptedMagic and a random file ID,
+// followed by the content in chunks of encryptedChunkSize bytes, each
+// sealed with AES-256-GCM on its own so any byte range can be read without
+// decrypting what comes before it. Each file has its own key, derived from
+// the cache key and the file ID; a chunk's nonce is its index, and the last
+// chunk is marked in its additional data so a truncated file fails to
+// decrypt rather than reading as shorter.
+const (
+	encryptedChunkSize  = 64 * 1024
+	encryptedFileIDSize = 16
+	encryptedHeaderSize = len(encryptedMagic) + encryptedFileIDSize
+	encryptedTagSize    = 16
+)
+
+const encryptedMagic = "JFWENC01"
+
+// keyFileName is the file in the cache directory that records how the
+// cache key was made, so a wrong key or passphrase is reported at startup
+// instead of as unreadable files.
+const keyFileName = "encryption.json"
+
+// passphraseIterations is the PBKDF2-HMAC-SHA256 work factor for keys
+// derived from a passphrase.
+const passphraseIterations = 600000
+
+// keyCheckLabel is authenticated with the cache key to recognize it again.
+const keyCheckLabel = "go-jf-watch cache key check"
+
+// Cipher encrypts completed downloads and decrypts them as they are read.
+type Cipher struct {
+	key []byte
+}
+
+// keyFile is the content of keyFileName.
+type keyFile struct {
+	// Salt and Iterations derive the key from the passphrase; both are
+	// empty for a configured key
+	Salt       string `json:"salt,omitempty"`
+	Iterations int    `json:"iterations,omitempty"`
+	Check      string `json:"check"`
+}
+
+// NewCipher returns the cipher for the cache in dir, or nil when
+// encryption is disabled. The first time it runs it records a key check,
+// and the passphrase salt, in the cache directory; after that a key or
+// passphrase that does not match is an error.
+func NewCipher(cfg *config.CacheEncryptionConfig, dir string) (*Cipher, error) {
+	if cfg == nil || !cfg.Enabled {
+		return nil, nil
+	}
+
+	path := filepath.Join(dir, keyFileName)
+	var stored keyFile
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var key []byte
+	if cfg.Key != "" {
+		if key, err = hex.DecodeString(cfg.Key); err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("cache key must be 64 hex digits")
+		}
+	} else {
+		if stored.Salt == "" {
+			salt := make([]byte, 16)
+			if _, err := rand.Read(salt); err != nil {
+				return nil, fmt.Errorf("failed to generate salt: %w", err)
+			}
+			stored.Salt = hex.EncodeToString(salt)
+			stored.Iterations = passphraseIterations
+		}
+		salt, err := hex.DecodeString(stored.Salt)
+		if err != nil || stored.Iterations <= 0 {
+			return nil, fmt.Errorf("invalid passphrase salt in %s", path)
+		}
+		key = pbkdf2.Key([]byte(cfg.Passphrase), salt, stored.Iterations, 32, sha256.New)
+	}
+
+	c := &Cipher{key: key}
+	check := hex.EncodeToString(c.mac([]byte(keyCheckLabel)))
+	if stored.Check != "" {
+		if !hmac.Equal([]byte(stored.Check), []byte(check)) {
+			return nil, fmt.Errorf("cache key does not match the one %s was encrypted with", dir)
+		}
+		return c, nil
+	}
+
+	stored.Check = check
+	data, err = json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Cipher returns the cipher of the cache, nil when it is not encrypted.
+func (m *Manager) Cipher() *Cipher {
+	return m.cipher
+}
+
+// mac authenticates data with the cache key.
+func (c *Cipher) mac(data []byte) []byte {
+	h := hmac.New(sha256.New, c.key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// fileAEAD returns the AEAD for the file with fileID.
+func (c *Cipher) fileAEAD(fileID []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(c.mac(fileID))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptFile writes an encrypted copy of src to dst. dst is written under
+// a temporary name and renamed into place once synced, so a crash never
+// leaves half of it.
+func (c *Cipher) EncryptFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open file to encrypt: %w", err)
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file to encrypt: %w", err)
+	}
+
+	tmp := dst + ".encrypting"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create encrypted file: %w", err)
+	}
+
+	if err := c.encrypt(out, in, info.Size()); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to sync encrypted file: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to close encrypted file: %w", err)
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to move encrypted file: %w", err)
+	}
+	return syncDir(filepath.Dir(dst))
+}
+
+// encrypt writes the header and size bytes of in, sealed chunk by chunk,
+// to out.
+func (c *Cipher) encrypt(out io.Writer, in io.Reader, size int64) error {
+	fileID := make([]byte, encryptedFileIDSize)
+	if _, err := rand.Read(fileID); err != nil {
+		return fmt.Errorf("failed to generate file ID: %w", err)
+	}
+	aead, err := c.fileAEAD(fileID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(out, encryptedMagic); err != nil {
+		return fmt.Errorf("failed to write encrypted file: %w", err)
+	}
+	if _, err := out.Write(fileID); err != nil {
+		return fmt.Errorf("failed to write encrypted file: %w", err)
+	}
+
+	// An empty file is still one, empty, last chunk
+	chunks := max((size+encryptedChunkSize-1)/encryptedChunkSize, 1)
+	plain := make([]byte, encryptedChunkSize)
+	sealed := make([]byte, 0, encryptedChunkSize+encryptedTagSize)
+	for i := int64(0); i < chunks; i++ {
+		n := min(size-i*encryptedChunkSize, encryptedChunkSize)
+		if _, err := io.ReadFull(in, plain[:n]); err != nil {
+			return fmt.Errorf("failed to read file to encrypt: %w", err)
+		}
+		sealed = aead.Seal(sealed[:0], chunkNonce(aead, i), plain[:n], chunkAD(i == chunks-1))
+		if _, err := out.Write(sealed); err != nil {
+			return fmt.Errorf("failed to write encrypted file: %w", err)
+		}
+	}
+	return nil
+}
+
+// chunkNonce returns the nonce of chunk i.
+func chunkNonce(aead cipher.AEAD, i int64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], uint64(i))
+	return nonce
+}
+
+// chunkAD returns the additional data of a chunk.
+func chunkAD(last bool) []byte {
+	if last {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// encryptedPlainSize returns the size of the content of an encrypted file
+// fileSize bytes long.
+func encryptedPlainSize(fileSize int64) (int64, error) {
+	body := fileSize - int64(encryptedHeaderSize)
+	if body < encryptedTagSize {
+		return 0, errCorrupted
+	}
+	full, rest := body/(encryptedChunkSize+encryptedTagSize), body%(encryptedChunkSize+encryptedTagSize)
+	if rest == 0 {
+		return full * encryptedChunkSize, nil
+	}
+	if rest < encryptedTagSize {
+		return 0, errCorrupted
+	}
+	return full*encryptedChunkSize + rest - encryptedTagSize, nil
+}
+
+// CachedFile is a cached media file opened for reading. Stat reports the
+// size of the content, which for an encrypted file is less than it takes
+// on disk.
+type CachedFile interface {
+	io.ReadSeeker
+	io.ReaderAt
+	io.Closer
+	Stat() (os.FileInfo, error)
+}
+
+// OpenCached opens a cached file for reading, decrypting it with c when it
+// is encrypted. Files cached before encryption was enabled are read as
+// they are. Without a cipher an encrypted file fails with ErrEncrypted.
+func OpenCached(path string, c *Cipher) (CachedFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, encryptedHeaderSize)
+	n, err := file.ReadAt(header, 0)
+	if n < encryptedHeaderSize || !bytes.Equal(header[:len(encryptedMagic)], []byte(encryptedMagic)) {
+		if err != nil && err != io.EOF {
+			file.Close()
+			return nil, err
+		}
+		return file, nil
+	}
+
+	if c == nil {
+		file.Close()
+		return nil, ErrEncrypted
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	size, err := encryptedPlainSize(info.Size())
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	aead, err := c.fileAEAD(header[len(encryptedMagic):])
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &encryptedFile{
+		file:  file,
+		info:  info,
+		aead:  aead,
+		size:  size,
+		index: -1,
+	}, nil
+}
+
+// CachedSize returns the size of the content of a cached file, decrypted
+// or not. It only reads the file header.
+func CachedSize(path string) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	header := make([]byte, len(encryptedMagic))
+	if n, _ := file.ReadAt(header, 0); n < len(header) || string(header) != encryptedMagic {
+		return info.Size(), nil
+	}
+	return encryptedPlainSize(info.Size())
+}
+
+// encryptedFile decrypts an encrypted cached file as it is read, keeping
+// the last chunk it decrypted for the sequential reads streaming makes.
+type encryptedFile struct {
+	file *os.File
+	info os.FileInfo
+	aead cipher.AEAD
+	size int64
+
+	mu    sync.Mutex
+	pos   int64
+	index int64 // of chunk, -1 when empty
+	chunk []byte
+	buf   []byte
+}
+
+func (f *encryptedFile) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := 0
+	for n < len(p) {
+		if off >= f.size {
+			return n, io.EOF
+		}
+		i := off / encryptedChunkSize
+		if err := f.load(i); err != nil {
+			return n, err
+		}
+		copied := copy(p[n:], f.chunk[off-i*encryptedChunkSize:])
+		n += copied
+		off += int64(copied)
+	}
+	return n, nil
+}
+
+// load decrypts chunk i into f.chunk. The caller holds f.mu.
+func (f *encryptedFile) load(i int64) error {
+	if f.index == i {
+		return nil
+	}
+
+	last := max((f.size+encryptedChunkSize-1)/encryptedChunkSize-1, 0)
+	length := min(f.size-i*encryptedChunkSize, encryptedChunkSize) + encryptedTagSize
+	if cap(f.buf) < int(length) {
+		f.buf = make([]byte, encryptedChunkSize+encryptedTagSize)
+	}
+	sealed := f.buf[:length]
+	offset := int64(encryptedHeaderSize) + i*(encryptedChunkSize+encryptedTagSize)
+	if _, err := f.file.ReadAt(sealed, offset); err != nil {
+		return fmt.Errorf("failed to read encrypted chunk %d: %w", i, err)
+	}
+
+	chunk, err := f.aead.Open(f.chunk[:0], chunkNonce(f.aead, i), sealed, chunkAD(i == last))
+	if err != nil {
+		f.index = -1
+		return fmt.Errorf("chunk %d: %w", i, errCorrupted)
+	}
+	f.chunk = chunk
+	f.index = i
+	return nil
+}
+
+func (f *encryptedFile) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.pos)
+	f.pos += int64(n)
+	return n, err
+}
+
+func (f *encryptedFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.pos
+	case io.SeekEnd:
+		offset += f.size
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative position")
+	}
+	f.pos = offset
+	return offset, nil
+}
+
+func (f *encryptedFile) Stat() (os.FileInfo, error) {
+	return decryptedInfo{FileInfo: f.info, size: f.size}, nil
+}
+
+func (f *encryptedFile) Close() error {
+	return f.file.Close()
+}
+
+// decryptedInfo reports the size of an encrypted file's content.
+type decryptedInfo struct {
+	os.FileInfo
+	size int64
+}
+
+func (i decryptedInfo) Size() int64 { return i.size }
@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+const testCacheKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+func newTestCipher(t *testing.T) *Cipher {
+	t.Helper()
+	c, err := NewCipher(&config.CacheEncryptionConfig{Enabled: true, Key: testCacheKey}, t.TempDir())
+	if err != nil {
+		t.Fatalf("NewCipher failed: %v", err)
+	}
+	return c
+}
+
+// encryptTestFile writes content encrypted with c and returns its path.
+func encryptTestFile(t *testing.T, c *Cipher, content []byte) string {
+	t.Helper()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "plain.mkv")
+	if err := os.WriteFile(src, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(dir, "movie.mkv")
+	if err := c.EncryptFile(src, dst); err != nil {
+		t.Fatalf("EncryptFile failed: %v", err)
+	}
+	return dst
+}
+
+func TestEncryptedFileRoundTrip(t *testing.T) {
+	c := newTestCipher(t)
+
+	for _, size := range []int{0, 1, encryptedChunkSize, encryptedChunkSize + 1, 3*encryptedChunkSize + 5} {
+		content := make([]byte, size)
+		for i := range content {
+			content[i] = byte(i * 31)
+		}
+		path := encryptTestFile(t, c, content)
+
+		onDisk, _ := os.ReadFile(path)
+		// Short plaintexts can turn up in random ciphertext by chance
+		if size >= 16 && bytes.Contains(onDisk, content[:min(size, 64)]) {
+			t.Errorf("size %d: content stored in the clear", size)
+		}
+		if got, err := CachedSize(path); err != nil || got != int64(size) {
+			t.Errorf("size %d: CachedSize = %d, %v", size, got, err)
+		}
+
+		file, err := OpenCached(path, c)
+		if err != nil {
+			t.Fatalf("size %d: OpenCached failed: %v", size, err)
+		}
+		info, _ := file.Stat()
+		if info.Size() != int64(size) {
+			t.Errorf("size %d: Stat reports %d bytes", size, info.Size())
+		}
+		got, err := io.ReadAll(file)
+		if err != nil || !bytes.Equal(got, content) {
+			t.Errorf("size %d: read back %d bytes (%v)", size, len(got), err)
+		}
+
+		// Ranges across chunk boundaries read without decrypting from the start
+		if size > encryptedChunkSize+10 {
+			start := int64(encryptedChunkSize - 3)
+			file.Seek(start, io.SeekStart)
+			part := make([]byte, 10)
+			if _, err := io.ReadFull(file, part); err != nil || !bytes.Equal(part, content[start:start+10]) {
+				t.Errorf("size %d: range across chunks read %x (%v)", size, part, err)
+			}
+			if end, _ := file.Seek(0, io.SeekEnd); end != int64(size) {
+				t.Errorf("size %d: end at %d", size, end)
+			}
+		}
+		file.Close()
+	}
+}
+
+func TestEncryptedFileDetectsTampering(t *testing.T) {
+	c := newTestCipher(t)
+	content := bytes.Repeat([]byte("frame"), encryptedChunkSize)
+
+	tampered := encryptTestFile(t, c, content)
+	data, _ := os.ReadFile(tampered)
+	data[encryptedHeaderSize+encryptedChunkSize+100] ^= 1
+	os.WriteFile(tampered, data, 0644)
+
+	file, err := OpenCached(tampered, c)
+	if err != nil {
+		t.Fatalf("OpenCached failed: %v", err)
+	}
+	defer file.Close()
+	if _, err := io.ReadAll(file); !errors.Is(err, errCorrupted) {
+		t.Errorf("Expected a modified chunk to fail, got %v", err)
+	}
+
+	// Dropping whole chunks from the end is caught too
+	truncated := encryptTestFile(t, c, content)
+	os.Truncate(truncated, int64(encryptedHeaderSize+2*(encryptedChunkSize+encryptedTagSize)))
+	file, err = OpenCached(truncated, c)
+	if err != nil {
+		t.Fatalf("OpenCached failed: %v", err)
+	}
+	defer file.Close()
+	if _, err := io.ReadAll(file); !errors.Is(err, errCorrupted) {
+		t.Errorf("Expected a truncated file to fail, got %v", err)
+	}
+}
+
+func TestOpenCachedPlainFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "movie.mkv")
+	os.WriteFile(path, []byte("cached before encryption"), 0644)
+
+	// Files cached before encryption was enabled stay readable
+	file, err := OpenCached(path, newTestCipher(t))
+	if err != nil {
+		t.Fatalf("OpenCached failed: %v", err)
+	}
+	got, _ := io.ReadAll(file)
+	file.Close()
+	if string(got) != "cached before encryption" {
+		t.Errorf("Expected the plain file as is, got %q", got)
+	}
+
+	encrypted := encryptTestFile(t, newTestCipher(t), []byte("secret"))
+	if _, err := OpenCached(encrypted, nil); !errors.Is(err, ErrEncrypted) {
+		t.Errorf("Expected ErrEncrypted without a key, got %v", err)
+	}
+}
+
+func TestNewCipherChecksKey(t *testing.T) {
+	dir := t.TempDir()
+
+	if c, err := NewCipher(&config.CacheEncryptionConfig{}, dir); c != nil || err != nil {
+		t.Errorf("Expected no cipher when disabled, got %v, %v", c, err)
+	}
+
+	cfg := &config.CacheEncryptionConfig{Enabled: true, Passphrase: "correct horse battery"}
+	first, err := NewCipher(cfg, dir)
+	if err != nil {
+		t.Fatalf("NewCipher failed: %v", err)
+	}
+	path := encryptTestFile(t, first, []byte("episode"))
+
+	// The same passphrase derives the same key from the stored salt
+	again, err := NewCipher(cfg, dir)
+	if err != nil {
+		t.Fatalf("NewCipher with the same passphrase failed: %v", err)
+	}
+	file, err := OpenCached(path, again)
+	if err != nil {
+		t.Fatalf("OpenCached failed: %v", err)
+	}
+	got, _ := io.ReadAll(file)
+	file.Close()
+	if string(got) != "episode" {
+		t.Errorf("Expected the file to decrypt, got %q", got)
+	}
+
+	_, err = NewCipher(&config.CacheEncryptionConfig{Enabled: true, Passphrase: "wrong horse battery"}, dir)
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("Expected a wrong passphrase to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// Kinds of events recorded in the event log.
+const (
+	EventDownloadCompleted = "download_completed"
+	EventDownloadFailed    = "download_failed" // gave up, out of retries or not retryable
+	EventEvicted           = "evicted"
+	EventPredictionQueued  = "prediction_queued"
+	EventSync              = "sync" // a prediction cycle reconciled the queue
+	EventConfigChanged     = "config_changed"
+)
+
+// Event is an entry of the event log: a notable action the service took,
+// kept so users can find out later why the cache looks the way it does.
+// Key pattern: {time}:{sequence}
+type Event struct {
+	ID      string                 `json:"id"`
+	Time    time.Time              `json:"time"`
+	Kind    string                 `json:"kind"`
+	MediaID string                 `json:"media_id,omitempty"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// EventFilter selects events from the log. Zero fields match everything.
+type EventFilter struct {
+	Kinds   []string
+	MediaID string
+	Since   time.Time
+	Until   time.Time // exclusive
+	Limit   int       // the latest Limit matching events; 0 for all
+}
+
+// Matches reports whether event passes the filter's kind and media
+// conditions. The time range and limit are applied by the store.
+func (f *EventFilter) Matches(event *Event) bool {
+	if len(f.Kinds) > 0 && !slices.Contains(f.Kinds, event.Kind) {
+		return false
+	}
+	return f.MediaID == "" || event.MediaID == f.MediaID
+}
+
+// eventKey is the key an event is stored under. The time is formatted like
+// history keys, so keys sort by time; the sequence keeps events recorded
+// in the same instant apart.
+func eventKey(at time.Time, seq uint64) []byte {
+	return []byte(fmt.Sprintf("%s:%016x", eventTimeKey(at), seq))
+}
+
+// eventTimeKey is the prefix of the keys of events recorded at t.
+func eventTimeKey(t time.Time) string {
+	return t.UTC().Format(historyTimeLayout)
+}
+
+// RecordEvent adds an event to the log, setting its ID and, when zero, its
+// time.
+func (m *Manager) RecordEvent(event *Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	return m.update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketEvents)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := eventKey(event.Time, seq)
+		event.ID = string(key)
+
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event: %w", err)
+		}
+		return bucket.Put(key, data)
+	})
+}
+
+// GetEvents returns the events matching filter, newest first.
+func (m *Manager) GetEvents(filter EventFilter) ([]*Event, error) {
+	events := make([]*Event, 0)
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(bucketEvents).Cursor()
+
+		// Walk back from the end of the range
+		var k, v []byte
+		if filter.Until.IsZero() {
+			k, v = c.Last()
+		} else if k, _ = c.Seek([]byte(eventTimeKey(filter.Until))); k == nil {
+			k, v = c.Last()
+		} else {
+			k, v = c.Prev()
+		}
+
+		since := []byte(eventTimeKey(filter.Since))
+		for ; k != nil; k, v = c.Prev() {
+			if !filter.Since.IsZero() && bytes.Compare(k, since) < 0 {
+				break
+			}
+
+			var event Event
+			if err := json.Unmarshal(v, &event); err != nil {
+				m.logger.Warn("Skipping unreadable event", "key", string(k), "error", err)
+				continue
+			}
+			if !filter.Matches(&event) {
+				continue
+			}
+			events = append(events, &event)
+			if filter.Limit > 0 && len(events) == filter.Limit {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event log: %w", err)
+	}
+
+	return events, nil
+}
+
+// PruneEvents deletes events recorded more than cache.event_retention_days
+// ago and returns how many it deleted. It keeps everything when retention
+// is 0.
+func (m *Manager) PruneEvents(ctx context.Context) (int, error) {
+	days := m.config.EventRetentionDays
+	if days <= 0 {
+		return 0, nil
+	}
+	cutoff := []byte(eventTimeKey(time.Now().AddDate(0, 0, -days)))
+
+	var expired [][]byte
+	err := m.view(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(bucketEvents).Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k, cutoff) < 0; k, _ = c.Next() {
+			expired = append(expired, bytes.Clone(k))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan event log: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+
+	if err := m.update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketEvents)
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("failed to prune event log: %w", err)
+	}
+
+	m.logger.Info("Pruned event log", "events", len(expired), "retention_days", days)
+	return len(expired), nil
+}
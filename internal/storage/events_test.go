@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPruneEvents(t *testing.T) {
+	manager := newStatsTestManager(t)
+	manager.config.EventRetentionDays = 30
+
+	now := time.Now()
+	manager.RecordEvent(&Event{Time: now.AddDate(0, 0, -60), Kind: EventEvicted, MediaID: "expired"})
+	manager.RecordEvent(&Event{Time: now.AddDate(0, 0, -10), Kind: EventEvicted, MediaID: "kept"})
+	manager.RecordEvent(&Event{Kind: EventSync})
+
+	pruned, err := manager.PruneEvents(context.Background())
+	if err != nil {
+		t.Fatalf("PruneEvents failed: %v", err)
+	}
+	if pruned != 1 {
+		t.Errorf("Expected 1 event pruned, got %d", pruned)
+	}
+	events, _ := manager.GetEvents(EventFilter{Kinds: []string{EventEvicted}})
+	if len(events) != 1 || events[0].MediaID != "kept" {
+		t.Errorf("Expected only the recent eviction left, got %+v", events)
+	}
+}
+
+func TestEvictItemsRecordsEvent(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheManager := createTestCacheManager(t, tempDir)
+
+	path := filepath.Join(tempDir, "movies", "heat", "video.mkv")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte("video"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	candidate := &EvictionCandidate{
+		CacheEntry: CacheEntry{Path: path, Size: 5, MediaType: "movie", JellyfinID: "heat", AccessCount: 2},
+		Score:      3.5,
+	}
+	if err := cacheManager.EvictItems([]*EvictionCandidate{candidate}); err != nil {
+		t.Fatalf("EvictItems failed: %v", err)
+	}
+
+	events, err := cacheManager.storage.GetEvents(EventFilter{MediaID: "heat"})
+	if err != nil || len(events) != 1 {
+		t.Fatalf("Expected one event, got %+v (%v)", events, err)
+	}
+	event := events[0]
+	if event.Kind != EventEvicted || event.Details["policy"] != EvictionLRU || event.Details["score"] != 3.5 {
+		t.Errorf("Unexpected eviction event %+v", event)
+	}
+}
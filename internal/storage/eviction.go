@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// EvictionPolicy decides which cached items are removed first when the
+// cache needs space. Entries with higher scores are evicted first; scores
+// only need to be comparable within one policy. Protected entries are never
+// scored.
+type EvictionPolicy interface {
+	Score(entry *CacheEntry) float64
+}
+
+// Built-in eviction policy names, selected with cache.eviction_policy.
+const (
+	EvictionLRU     = "lru"     // least recently played first
+	EvictionLFU     = "lfu"     // least often played first
+	EvictionSize    = "size"    // large, stale files first
+	EvictionWatched = "watched" // watched items first, then least recently played
+)
+
+// NewEvictionPolicy returns the built-in policy with the given name. An
+// empty name selects LRU.
+func NewEvictionPolicy(name string) (EvictionPolicy, error) {
+	switch name {
+	case "", EvictionLRU:
+		return LRUPolicy{}, nil
+	case EvictionLFU:
+		return LFUPolicy{}, nil
+	case EvictionSize:
+		return SizePolicy{}, nil
+	case EvictionWatched:
+		return WatchedPolicy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown eviction policy %q", name)
+	}
+}
+
+// daysSinceAccess is how long ago an entry was last played, in days.
+func daysSinceAccess(entry *CacheEntry) float64 {
+	return time.Since(entry.LastAccessed).Hours() / 24
+}
+
+// LRUPolicy evicts the least recently played items first, with a slight
+// preference for large files, files above 1080p and movies. It is the
+// default.
+type LRUPolicy struct{}
+
+// Score implements EvictionPolicy.
+func (LRUPolicy) Score(entry *CacheEntry) float64 {
+	score := daysSinceAccess(entry) // Base score from age
+
+	// Slight preference for removing larger files when space is tight
+	if entry.Size > 1000*1024*1024 { // Files > 1GB
+		score += 0.5
+	}
+
+	// A 4K copy takes the space of several 1080p ones
+	if entry.Height > 1080 {
+		score += 0.5
+	}
+
+	// Media type scoring (movies slightly more evictable than episodes)
+	if entry.MediaType == "movie" {
+		score += 0.1
+	}
+
+	// Hardlinked files free no space until every link is removed
+	if entry.Links > 1 {
+		score -= 1.0
+	}
+
+	return score
+}
+
+// LFUPolicy evicts the least often played items first, breaking ties by
+// age. It suits large volumes where favourites should stay cached however
+// long ago they were last played.
+type LFUPolicy struct{}
+
+// Score implements EvictionPolicy.
+func (LFUPolicy) Score(entry *CacheEntry) float64 {
+	plays := float64(entry.AccessCount)
+
+	// Hardlinked files count as played once more, since evicting them
+	// frees nothing on its own
+	if entry.Links > 1 {
+		plays++
+	}
+
+	// Age adds less than one play, so it only breaks ties
+	days := daysSinceAccess(entry)
+	if days < 0 {
+		days = 0
+	}
+	return -plays + days/(days+1)
+}
+
+// SizePolicy weighs age by the space eviction frees, so a few large,
+// stale files go before many small ones. It suits small SSDs.
+type SizePolicy struct{}
+
+// Score implements EvictionPolicy.
+func (SizePolicy) Score(entry *CacheEntry) float64 {
+	freedGB := float64(entry.Size) / (1024 * 1024 * 1024)
+	if entry.Links > 1 {
+		freedGB /= float64(entry.Links)
+	}
+	days := daysSinceAccess(entry)
+	if days < 0 {
+		days = 0
+	}
+	return freedGB * (1 + days)
+}
+
+// WatchedPolicy evicts items someone has watched to completion before any
+// unwatched ones, each group least recently played first.
+type WatchedPolicy struct{}
+
+// watchedBonus lifts watched items above any unwatched item's LRU score.
+const watchedBonus = 1e6
+
+// Score implements EvictionPolicy.
+func (WatchedPolicy) Score(entry *CacheEntry) float64 {
+	score := LRUPolicy{}.Score(entry)
+	if entry.Watched {
+		score += watchedBonus
+	}
+	return score
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestEvictionPolicies(t *testing.T) {
+	day := 24 * time.Hour
+	const gb = 1024 * 1024 * 1024
+	now := time.Now()
+
+	// favourite: played often but not lately; stale: played once long ago
+	// and large; fresh: small and just played, but already watched
+	favourite := &CacheEntry{JellyfinID: "favourite", MediaType: "episode", Size: gb / 2, LastAccessed: now.Add(-20 * day), AccessCount: 12, Links: 1}
+	stale := &CacheEntry{JellyfinID: "stale", MediaType: "movie", Size: 8 * gb, LastAccessed: now.Add(-10 * day), AccessCount: 1, Links: 1}
+	fresh := &CacheEntry{JellyfinID: "fresh", MediaType: "episode", Size: gb / 4, LastAccessed: now.Add(-day), AccessCount: 2, Links: 1, Watched: true}
+
+	tests := []struct {
+		policy string
+		want   []string // Eviction order
+	}{
+		{EvictionLRU, []string{"favourite", "stale", "fresh"}},
+		{EvictionLFU, []string{"stale", "fresh", "favourite"}},
+		{EvictionSize, []string{"stale", "favourite", "fresh"}},
+		{EvictionWatched, []string{"fresh", "favourite", "stale"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			policy, err := NewEvictionPolicy(tt.policy)
+			if err != nil {
+				t.Fatalf("NewEvictionPolicy failed: %v", err)
+			}
+			for i := 1; i < len(tt.want); i++ {
+				entries := map[string]*CacheEntry{"favourite": favourite, "stale": stale, "fresh": fresh}
+				first, second := entries[tt.want[i-1]], entries[tt.want[i]]
+				if policy.Score(first) <= policy.Score(second) {
+					t.Errorf("Expected %s to be evicted before %s (%.3f <= %.3f)",
+						first.JellyfinID, second.JellyfinID, policy.Score(first), policy.Score(second))
+				}
+			}
+		})
+	}
+
+	if _, err := NewEvictionPolicy("fifo"); err == nil {
+		t.Error("Expected an error for an unknown policy")
+	}
+
+	// Of two copies played equally long ago, the 4K one goes first
+	uhd := &CacheEntry{JellyfinID: "uhd", MediaType: "movie", Size: 8 * gb, LastAccessed: now.Add(-5 * day), Links: 1, Height: 2160}
+	hd := &CacheEntry{JellyfinID: "hd", MediaType: "movie", Size: 8 * gb, LastAccessed: now.Add(-5 * day), Links: 1, Height: 1080}
+	if policy := (LRUPolicy{}); policy.Score(uhd) <= policy.Score(hd) {
+		t.Error("Expected the 4K copy to be evicted before the 1080p one")
+	}
+}
+
+func TestEvictionCandidatesUseAccessAndHistory(t *testing.T) {
+	manager := newStatsTestManager(t)
+	addCachedFile(t, manager, "m1", "movie", "Heat", "0123456789")
+	addCachedFile(t, manager, "m2", "movie", "Alien", "abcdefghij")
+
+	// Both were played long enough ago to not be protected; m1 three times
+	// and m2, which has been watched to completion, once
+	past := time.Now().Add(-time.Hour)
+	for i := 0; i < 3; i++ {
+		if err := manager.RecordAccess("m1", past); err != nil {
+			t.Fatalf("RecordAccess failed: %v", err)
+		}
+	}
+	if err := manager.RecordAccess("m2", past.Add(-time.Hour)); err != nil {
+		t.Fatalf("RecordAccess failed: %v", err)
+	}
+	if err := manager.RecordAccess("missing", past); err == nil {
+		t.Error("Expected an error recording access to an uncached item")
+	}
+	if err := manager.StoreViewingSession("alice", ViewingSession{MediaID: "m2", StartTime: past, Completed: true}); err != nil {
+		t.Fatalf("StoreViewingSession failed: %v", err)
+	}
+
+	record, err := manager.GetDownload("m1")
+	if err != nil {
+		t.Fatalf("GetDownload failed: %v", err)
+	}
+	if record.AccessCount != 3 || !record.LastAccessed.Equal(past) {
+		t.Errorf("Expected 3 accesses at %v, got %d at %v", past, record.AccessCount, record.LastAccessed)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cacheManager := NewCacheManager(manager.config, manager, logger)
+
+	entries, err := cacheManager.GetCacheEntries()
+	if err != nil {
+		t.Fatalf("GetCacheEntries failed: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.Watched != (entry.JellyfinID == "m2") {
+			t.Errorf("Unexpected watched state for %s: %v", entry.JellyfinID, entry.Watched)
+		}
+	}
+
+	// Once m2 has been played more often, LFU evicts m1 first while the
+	// watched policy still evicts m2 first
+	for i := 0; i < 3; i++ {
+		if err := manager.RecordAccess("m2", past.Add(-time.Hour)); err != nil {
+			t.Fatalf("RecordAccess failed: %v", err)
+		}
+	}
+	cacheManager.SetEvictionPolicy(LFUPolicy{})
+	candidates, err := cacheManager.GetEvictionCandidates(1)
+	if err != nil {
+		t.Fatalf("GetEvictionCandidates failed: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].JellyfinID != "m1" {
+		t.Errorf("Expected the less played m1 to be evicted first, got %+v", candidates)
+	}
+
+	cacheManager.SetEvictionPolicy(WatchedPolicy{})
+	candidates, err = cacheManager.GetEvictionCandidates(1)
+	if err != nil {
+		t.Fatalf("GetEvictionCandidates failed: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].JellyfinID != "m2" {
+		t.Errorf("Expected the watched m2 to be evicted first, got %+v", candidates)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/natefinch/atomic"
@@ -21,6 +22,8 @@ import (
 type FileManager struct {
 	tempDir string
 	logger  *slog.Logger
+	// cipher decrypts encrypted cached files for checksumming
+	cipher *Cipher
 }
 
 // FileMetadata represents metadata stored alongside media files.
@@ -32,6 +35,12 @@ type FileMetadata struct {
 	DownloadedAt time.Time `json:"downloaded_at"`
 	ContentType  string    `json:"content_type"`
 	URL          string    `json:"url,omitempty"`
+
+	// Descriptive fields kept current by the background metadata refresher
+	Name     string    `json:"name,omitempty"`
+	Overview string    `json:"overview,omitempty"`
+	Genres   []string  `json:"genres,omitempty"`
+	SyncedAt time.Time `json:"synced_at,omitempty"`
 }
 
 // NewFileManager creates a new file manager with the specified temp directory.
@@ -61,32 +70,31 @@ func (f *FileManager) WriteFileAtomic(filename string, data []byte) error {
 }
 
 // CopyFileAtomic copies a file atomically from source to destination.
-// It calculates checksums during the copy for integrity verification.
+// It streams into a temporary file beside the destination, calculating the
+// checksum during the copy, and renames it into place once synced.
 func (f *FileManager) CopyFileAtomic(src, dst string) (string, error) {
 	f.logger.Debug("Copying file atomically",
 		"src", src,
 		"dst", dst)
 
-	// Ensure destination directory exists
-	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
-		return "", fmt.Errorf("failed to create destination directory: %w", err)
-	}
-
 	srcFile, err := os.Open(src)
 	if err != nil {
 		return "", fmt.Errorf("failed to open source file: %w", err)
 	}
 	defer srcFile.Close()
 
-	// Use atomic.WriteFile with a custom writer that calculates checksum
-	hasher := sha256.New()
-
-	err = atomic.WriteFile(dst, &atomicCopyReader{
-		reader: io.TeeReader(srcFile, hasher),
-		logger: f.logger,
-	})
-
+	partial, err := CreatePartial(dst, false)
 	if err != nil {
+		return "", err
+	}
+
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(partial, hasher), srcFile); err != nil {
+		partial.Discard()
+		return "", fmt.Errorf("atomic copy failed: %w", err)
+	}
+	if err := partial.Commit(); err != nil {
+		partial.Discard()
 		return "", fmt.Errorf("atomic copy failed: %w", err)
 	}
 
@@ -99,16 +107,6 @@ func (f *FileManager) CopyFileAtomic(src, dst string) (string, error) {
 	return checksum, nil
 }
 
-// atomicCopyReader wraps an io.Reader to work with atomic.WriteFile
-type atomicCopyReader struct {
-	reader io.Reader
-	logger *slog.Logger
-}
-
-func (r *atomicCopyReader) Read(p []byte) (n int, err error) {
-	return r.reader.Read(p)
-}
-
 // MoveFileAtomic moves a file from source to destination atomically.
 // It first attempts a rename (fastest), falling back to copy+delete if needed.
 func (f *FileManager) MoveFileAtomic(src, dst string) error {
@@ -143,36 +141,12 @@ func (f *FileManager) MoveFileAtomic(src, dst string) error {
 
 // CalculateChecksum calculates SHA256 checksum of a file.
 func (f *FileManager) CalculateChecksum(filename string) (string, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return "", fmt.Errorf("failed to open file for checksum: %w", err)
-	}
-	defer file.Close()
-
-	hasher := sha256.New()
-	if _, err := io.Copy(hasher, file); err != nil {
-		return "", fmt.Errorf("failed to calculate checksum: %w", err)
-	}
-
-	return hex.EncodeToString(hasher.Sum(nil)), nil
+	return f.CalculateChecksumWith(filename, ChecksumSHA256)
 }
 
-// VerifyChecksum verifies that a file's checksum matches the expected value.
+// VerifyChecksum verifies that a file's SHA256 checksum matches the expected value.
 func (f *FileManager) VerifyChecksum(filename, expectedChecksum string) (bool, error) {
-	actualChecksum, err := f.CalculateChecksum(filename)
-	if err != nil {
-		return false, err
-	}
-
-	match := actualChecksum == expectedChecksum
-	if !match {
-		f.logger.Warn("Checksum mismatch",
-			"filename", filename,
-			"expected", expectedChecksum,
-			"actual", actualChecksum)
-	}
-
-	return match, nil
+	return f.VerifyChecksumWith(filename, ChecksumSHA256, expectedChecksum)
 }
 
 // WriteMetadata writes metadata to a .meta.json file alongside the media file.
@@ -207,6 +181,27 @@ func (f *FileManager) ReadMetadata(mediaPath string) (*FileMetadata, error) {
 	return &metadata, nil
 }
 
+// ArtworkTypes are the item images cached beside media files.
+var ArtworkTypes = []string{"primary", "backdrop"}
+
+// artworkPrefix starts the names of artwork files, which sit next to the
+// media file like the .meta.json sidecar: .image-primary, .image-backdrop.
+const artworkPrefix = ".image-"
+
+// ArtworkPath returns where the image of imageType is cached for the
+// media file at mediaPath.
+func ArtworkPath(mediaPath, imageType string) string {
+	return filepath.Join(filepath.Dir(mediaPath), artworkPrefix+imageType)
+}
+
+// IsSidecar reports whether a name in a media directory belongs to the
+// .meta.json sidecar, cached artwork, seek previews, HLS segments or .nfo
+// files rather than the media itself.
+func IsSidecar(name string) bool {
+	return name == ".meta.json" || name == trickplayDirName || name == hlsDirName ||
+		strings.HasPrefix(name, artworkPrefix) || strings.HasSuffix(name, ".nfo")
+}
+
 // GetTempFilePath generates a temporary file path for downloads.
 func (f *FileManager) GetTempFilePath(id string) string {
 	return filepath.Join(f.tempDir, fmt.Sprintf("%s.tmp", id))
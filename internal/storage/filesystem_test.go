@@ -498,3 +498,66 @@ func createTestFileManager(t *testing.T, tempDir string) *FileManager {
 
 	return NewFileManager(tempDir, logger)
 }
+
+func TestPartialFile(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "movies", "film.mkv")
+
+	partial, err := CreatePartial(dest, false)
+	if err != nil {
+		t.Fatalf("CreatePartial failed: %v", err)
+	}
+	if filepath.Dir(partial.Path()) != filepath.Dir(dest) {
+		t.Errorf("Expected partial file beside destination, got %s", partial.Path())
+	}
+	partial.Write([]byte("hello "))
+
+	// Bytes written so far are readable before the download completes
+	data, err := os.ReadFile(partial.Path())
+	if err != nil {
+		t.Fatalf("Failed to read partial file: %v", err)
+	}
+	if string(data) != "hello " || partial.Written() != 6 {
+		t.Errorf("Expected 6 readable bytes, got %q (written %d)", data, partial.Written())
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Error("Destination should not exist before commit")
+	}
+
+	// An interrupted download resumes by appending
+	partial.Close()
+	partial, err = CreatePartial(dest, true)
+	if err != nil {
+		t.Fatalf("CreatePartial resume failed: %v", err)
+	}
+	if partial.Written() != 6 {
+		t.Errorf("Expected resumed partial to report 6 bytes, got %d", partial.Written())
+	}
+	partial.Write([]byte("world"))
+
+	if err := partial.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	data, err = os.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("Failed to read destination: %v", err)
+	}
+	if string(data) != "hello world" {
+		t.Errorf("Expected %q, got %q", "hello world", data)
+	}
+	if _, err := os.Stat(PartialPath(dest)); !os.IsNotExist(err) {
+		t.Error("Partial file should be gone after commit")
+	}
+
+	// Without resume the partial file starts over
+	partial, err = CreatePartial(dest, false)
+	if err != nil {
+		t.Fatalf("CreatePartial failed: %v", err)
+	}
+	partial.Write([]byte("x"))
+	if err := partial.Discard(); err != nil {
+		t.Fatalf("Discard failed: %v", err)
+	}
+	if _, err := os.Stat(PartialPath(dest)); !os.IsNotExist(err) {
+		t.Error("Partial file should be removed by discard")
+	}
+}
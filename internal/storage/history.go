@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// historyTimeLayout formats session start times in history keys. It is
+// fixed width, so keys sort by start time within a user.
+const historyTimeLayout = "20060102T150405.000000000Z"
+
+// historyKey is the key a viewing session is stored under:
+// {user-id}:{start-time}:{media-id}. A session is identified by its media
+// and start time, so progress reports for it overwrite one entry.
+func historyKey(userID string, session *ViewingSession) []byte {
+	return []byte(fmt.Sprintf("%s:%s:%s", userID, session.StartTime.UTC().Format(historyTimeLayout), session.MediaID))
+}
+
+// historyPrefix is the key prefix of all of a user's sessions.
+func historyPrefix(userID string) []byte {
+	return []byte(userID + ":")
+}
+
+// GetViewingHistory returns a user's viewing sessions that started in the
+// last days days, oldest first.
+func (m *Manager) GetViewingHistory(userID string, days int) ([]ViewingSession, error) {
+	var sessions []ViewingSession
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		prefix := historyPrefix(userID)
+		cutoff := time.Now().AddDate(0, 0, -days).UTC().Format(historyTimeLayout)
+
+		c := tx.Bucket(bucketHistory).Cursor()
+		for k, v := c.Seek(append(prefix, cutoff...)); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var session ViewingSession
+			if err := json.Unmarshal(v, &session); err != nil {
+				m.logger.Warn("Skipping unreadable viewing session", "key", string(k), "error", err)
+				continue
+			}
+			sessions = append(sessions, session)
+		}
+		return nil
+	})
+
+	if err != nil {
+		m.logger.Error("Failed to get viewing history",
+			"user_id", userID, "days", days, "error", err)
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// StoreViewingSession adds a viewing session to a user's history. A
+// session with the same media and start time is replaced.
+func (m *Manager) StoreViewingSession(userID string, session ViewingSession) error {
+	return m.update(func(tx *bbolt.Tx) error {
+		return putViewingSession(tx, userID, &session)
+	})
+}
+
+// UpsertViewingSession records progress for a viewing session, replacing
+// the stored session for the same media and start time if there is one.
+// Players report progress repeatedly during a session, so this keeps one
+// entry per viewing instead of one per report.
+func (m *Manager) UpsertViewingSession(userID string, session ViewingSession) error {
+	return m.StoreViewingSession(userID, session)
+}
+
+// putViewingSession writes session under its history key.
+func putViewingSession(tx *bbolt.Tx, userID string, session *ViewingSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal viewing session: %w", err)
+	}
+	return tx.Bucket(bucketHistory).Put(historyKey(userID, session), data)
+}
+
+// WatchedMediaIDs returns the media any user has watched to completion,
+// according to the stored viewing history.
+func (m *Manager) WatchedMediaIDs() (map[string]bool, error) {
+	watched := make(map[string]bool)
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketHistory).ForEach(func(k, v []byte) error {
+			var session ViewingSession
+			if err := json.Unmarshal(v, &session); err != nil {
+				return fmt.Errorf("failed to unmarshal viewing session %s: %w", k, err)
+			}
+			if session.Completed {
+				watched[session.MediaID] = true
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return watched, nil
+}
+
+// PruneViewingHistory deletes viewing sessions that started more than
+// cache.history_retention_days ago and returns how many it deleted. It
+// keeps everything when retention is 0.
+func (m *Manager) PruneViewingHistory(ctx context.Context) (int, error) {
+	days := m.config.HistoryRetentionDays
+	if days <= 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().AddDate(0, 0, -days)
+
+	var expired [][]byte
+	err := m.view(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketHistory).ForEach(func(k, v []byte) error {
+			var session ViewingSession
+			if err := json.Unmarshal(v, &session); err != nil || session.StartTime.Before(cutoff) {
+				expired = append(expired, bytes.Clone(k))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan viewing history: %w", err)
+	}
+
+	// Delete in batches, so writers are not held off for the whole prune
+	const batchSize = 1000
+	pruned := 0
+	for len(expired) > 0 {
+		if err := ctx.Err(); err != nil {
+			return pruned, err
+		}
+		batch := expired[:min(batchSize, len(expired))]
+		expired = expired[len(batch):]
+
+		if err := m.update(func(tx *bbolt.Tx) error {
+			bucket := tx.Bucket(bucketHistory)
+			for _, k := range batch {
+				if err := bucket.Delete(k); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return pruned, fmt.Errorf("failed to prune viewing history: %w", err)
+		}
+		pruned += len(batch)
+	}
+
+	if pruned > 0 {
+		m.logger.Info("Pruned viewing history", "sessions", pruned, "retention_days", days)
+	}
+	return pruned, nil
+}
+
+// migrateViewingHistory moves viewing history from the per-user JSON arrays
+// formerly kept in the stats bucket under history:{user-id} into the
+// history bucket, one key per session.
+func migrateViewingHistory(tx *bbolt.Tx) error {
+	stats := tx.Bucket(bucketStats)
+	prefix := []byte("history:")
+
+	var keys [][]byte
+	c := stats.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		keys = append(keys, bytes.Clone(k))
+
+		var sessions []ViewingSession
+		if err := json.Unmarshal(v, &sessions); err != nil {
+			continue // Unreadable history was never returned either
+		}
+		userID := string(k[len(prefix):])
+		for i := range sessions {
+			if err := putViewingSession(tx, userID, &sessions[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, k := range keys {
+		if err := stats.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
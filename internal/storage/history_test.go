@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+func TestViewingHistory(t *testing.T) {
+	manager := newStatsTestManager(t)
+
+	now := time.Now()
+	for _, session := range []ViewingSession{
+		{MediaID: "recent", StartTime: now.Add(-time.Hour)},
+		{MediaID: "older", StartTime: now.Add(-48 * time.Hour)},
+		{MediaID: "stale", StartTime: now.AddDate(0, 0, -40)},
+	} {
+		if err := manager.StoreViewingSession("alice", session); err != nil {
+			t.Fatalf("StoreViewingSession failed: %v", err)
+		}
+	}
+	if err := manager.StoreViewingSession("bob", ViewingSession{MediaID: "other", StartTime: now}); err != nil {
+		t.Fatalf("StoreViewingSession failed: %v", err)
+	}
+
+	// Progress for the same viewing replaces the session
+	if err := manager.UpsertViewingSession("alice", ViewingSession{MediaID: "recent", StartTime: now.Add(-time.Hour), WatchedTime: 600}); err != nil {
+		t.Fatalf("UpsertViewingSession failed: %v", err)
+	}
+
+	sessions, err := manager.GetViewingHistory("alice", 30)
+	if err != nil {
+		t.Fatalf("GetViewingHistory failed: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].MediaID != "older" || sessions[1].MediaID != "recent" {
+		t.Fatalf("Expected older then recent, got %+v", sessions)
+	}
+	if sessions[1].WatchedTime != 600 {
+		t.Errorf("Expected the upserted session, got %+v", sessions[1])
+	}
+}
+
+func TestPruneViewingHistory(t *testing.T) {
+	manager := newStatsTestManager(t)
+	manager.config.HistoryRetentionDays = 30
+
+	now := time.Now()
+	manager.StoreViewingSession("alice", ViewingSession{MediaID: "kept", StartTime: now.AddDate(0, 0, -10)})
+	manager.StoreViewingSession("alice", ViewingSession{MediaID: "expired", StartTime: now.AddDate(0, 0, -60)})
+
+	pruned, err := manager.PruneViewingHistory(context.Background())
+	if err != nil {
+		t.Fatalf("PruneViewingHistory failed: %v", err)
+	}
+	if pruned != 1 {
+		t.Errorf("Expected 1 session pruned, got %d", pruned)
+	}
+	sessions, _ := manager.GetViewingHistory("alice", 365)
+	if len(sessions) != 1 || sessions[0].MediaID != "kept" {
+		t.Errorf("Expected only the recent session left, got %+v", sessions)
+	}
+}
+
+func TestMigrateViewingHistory(t *testing.T) {
+	dir := t.TempDir()
+	manager := createTestManager(t, dir)
+
+	// Write history the way it used to be stored, at schema version 1
+	now := time.Now()
+	legacy, _ := json.Marshal([]ViewingSession{
+		{MediaID: "m1", StartTime: now.Add(-2 * time.Hour), Completed: true},
+		{MediaID: "m2", StartTime: now.Add(-time.Hour)},
+	})
+	if err := manager.update(func(tx *bbolt.Tx) error {
+		if err := tx.Bucket(bucketStats).Put([]byte("history:alice"), legacy); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketConfig).Put(schemaVersionKey, []byte("1"))
+	}); err != nil {
+		t.Fatalf("Failed to write legacy history: %v", err)
+	}
+	manager.Close()
+
+	manager = createTestManager(t, dir)
+	defer manager.Close()
+
+	sessions, err := manager.GetViewingHistory("alice", 30)
+	if err != nil {
+		t.Fatalf("GetViewingHistory failed: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].MediaID != "m1" || sessions[1].MediaID != "m2" {
+		t.Errorf("Expected migrated sessions m1 and m2, got %+v", sessions)
+	}
+	if watched, _ := manager.WatchedMediaIDs(); !watched["m1"] || watched["m2"] {
+		t.Errorf("Expected only m1 watched, got %v", watched)
+	}
+	manager.view(func(tx *bbolt.Tx) error {
+		if tx.Bucket(bucketStats).Get([]byte("history:alice")) != nil {
+			t.Error("Expected the legacy history to be removed")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// hlsDirName is the directory next to a media file that holds its HLS
+// repackaging: a media playlist, an fMP4 init segment and media segments.
+const hlsDirName = ".hls"
+
+// hlsCompleteName is written once every segment is in place.
+const hlsCompleteName = ".complete"
+
+// HLSDir returns the directory the HLS repackaging of the media file at
+// mediaPath is cached in.
+func HLSDir(mediaPath string) string {
+	return filepath.Join(filepath.Dir(mediaPath), hlsDirName)
+}
+
+// HLSComplete reports whether the HLS repackaging of the media file at
+// mediaPath is finished.
+func HLSComplete(mediaPath string) bool {
+	_, err := os.Stat(filepath.Join(HLSDir(mediaPath), hlsCompleteName))
+	return err == nil
+}
+
+// MarkHLSComplete records that the HLS repackaging of the media file at
+// mediaPath is finished.
+func MarkHLSComplete(mediaPath string) error {
+	return os.WriteFile(filepath.Join(HLSDir(mediaPath), hlsCompleteName), nil, 0644)
+}
@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"go.etcd.io/bbolt"
+)
+
+// schemaVersionKey records in the config bucket how many migrations the
+// database has been through.
+var schemaVersionKey = []byte("schema_version")
+
+// migrations upgrade the database one schema version at a time:
+// migrations[i] takes a database at version i to version i+1. Append new
+// steps; never reorder or remove them.
+var migrations = []func(tx *bbolt.Tx) error{
+	rebuildIndexes,        // 1: backfill the queue and series indexes
+	migrateViewingHistory, // 2: one history key per viewing session
+	rebuildLibraryCounts,  // 3: count cached items per media type
+	rebuildSearchIndex,    // 4: index metadata for library search
+	rekeyQueue,            // 5: order queue items queued in the same second
+}
+
+// migrate brings the database up to the current schema version. Each step
+// runs in its own transaction together with the version bump, so an
+// interrupted migration resumes where it stopped.
+func (m *Manager) migrate() error {
+	for {
+		var version int
+		err := m.update(func(tx *bbolt.Tx) error {
+			bucket := tx.Bucket(bucketConfig)
+			if stored := bucket.Get(schemaVersionKey); stored != nil {
+				parsed, err := strconv.Atoi(string(stored))
+				if err != nil {
+					return fmt.Errorf("invalid schema version %q: %w", stored, err)
+				}
+				version = parsed
+			}
+			if version >= len(migrations) {
+				return nil
+			}
+
+			if err := migrations[version](tx); err != nil {
+				return fmt.Errorf("migration to schema version %d failed: %w", version+1, err)
+			}
+			version++
+			return bucket.Put(schemaVersionKey, []byte(strconv.Itoa(version)))
+		})
+		if err != nil {
+			return err
+		}
+		if version >= len(migrations) {
+			return nil
+		}
+		m.logger.Info("Migrated database", "schema_version", version)
+	}
+}
+
+// rebuildIndexes recreates the queue and series indexes from the queue and
+// metadata buckets.
+func rebuildIndexes(tx *bbolt.Tx) error {
+	for _, name := range [][]byte{bucketQueueIndex, bucketSeriesIndex} {
+		if err := tx.DeleteBucket(name); err != nil && err != bbolt.ErrBucketNotFound {
+			return err
+		}
+		if _, err := tx.CreateBucket(name); err != nil {
+			return err
+		}
+	}
+
+	queueIndex := tx.Bucket(bucketQueueIndex)
+	if err := tx.Bucket(bucketQueue).ForEach(func(k, v []byte) error {
+		var item QueueItem
+		if err := json.Unmarshal(v, &item); err != nil {
+			return nil // Unreadable items were never found by ID either
+		}
+		return queueIndex.Put([]byte(item.ID), k)
+	}); err != nil {
+		return err
+	}
+
+	seriesIndex := tx.Bucket(bucketSeriesIndex)
+	cursor := tx.Bucket(bucketMetadata).Cursor()
+	prefix := []byte("meta:")
+	for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+		var metadata MediaMetadata
+		if err := json.Unmarshal(v, &metadata); err != nil || metadata.SeriesID == "" {
+			continue
+		}
+		if err := seriesIndex.Put(seriesIndexKey(metadata.SeriesID, metadata.JellyfinID), nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// queueKey is the key a queue item is stored under, which orders the queue
+// bucket by priority and then age. Ages are fixed-width nanoseconds, so
+// items queued one after another, such as a playlist, keep their order.
+func queueKey(item *QueueItem) []byte {
+	return []byte(fmt.Sprintf("%03d:%020d:%s", item.Priority, item.CreatedAt.UnixNano(), item.ID))
+}
+
+// rekeyQueue moves every queue item from the key pattern with ages in
+// seconds to the current one.
+func rekeyQueue(tx *bbolt.Tx) error {
+	bucket := tx.Bucket(bucketQueue)
+
+	var items []*QueueItem
+	var oldKeys [][]byte
+	if err := bucket.ForEach(func(k, v []byte) error {
+		var item QueueItem
+		if err := json.Unmarshal(v, &item); err != nil {
+			return nil // Leave unreadable items where they are
+		}
+		items = append(items, &item)
+		oldKeys = append(oldKeys, append([]byte(nil), k...))
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for i, item := range items {
+		key := queueKey(item)
+		if bytes.Equal(key, oldKeys[i]) {
+			continue
+		}
+		if err := bucket.Delete(oldKeys[i]); err != nil {
+			return err
+		}
+		if err := putQueueItem(tx, key, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getQueueItem looks up a queue item and the key it is stored under by ID.
+// It returns a nil key when there is no such item.
+func getQueueItem(tx *bbolt.Tx, itemID string) ([]byte, *QueueItem, error) {
+	key := tx.Bucket(bucketQueueIndex).Get([]byte(itemID))
+	if key == nil {
+		return nil, nil, nil
+	}
+	data := tx.Bucket(bucketQueue).Get(key)
+	if data == nil {
+		return nil, nil, nil
+	}
+
+	var item QueueItem
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal queue item: %w", err)
+	}
+	// Keys returned by Get are only valid for the transaction's lifetime
+	return append([]byte(nil), key...), &item, nil
+}
+
+// putQueueItem stores a queue item under key and indexes it.
+func putQueueItem(tx *bbolt.Tx, key []byte, item *QueueItem) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("failed to marshal queue item: %w", err)
+	}
+	if err := tx.Bucket(bucketQueue).Put(key, data); err != nil {
+		return err
+	}
+	return tx.Bucket(bucketQueueIndex).Put([]byte(item.ID), key)
+}
+
+// seriesIndexKey is the series index entry for an episode:
+// {series-id}:{episode-id}, so one series' episodes share a prefix.
+func seriesIndexKey(seriesID, episodeID string) []byte {
+	return []byte(seriesID + ":" + episodeID)
+}
+
+// indexSeriesEpisode moves an item's series index entry from the series it
+// was stored under before, if any, to the one it belongs to now.
+func indexSeriesEpisode(tx *bbolt.Tx, previous, metadata *MediaMetadata) error {
+	index := tx.Bucket(bucketSeriesIndex)
+	if previous != nil && previous.SeriesID != "" && previous.SeriesID != metadata.SeriesID {
+		if err := index.Delete(seriesIndexKey(previous.SeriesID, previous.JellyfinID)); err != nil {
+			return err
+		}
+	}
+	if metadata.SeriesID == "" {
+		return nil
+	}
+	return index.Put(seriesIndexKey(metadata.SeriesID, metadata.JellyfinID), nil)
+}
+
+// forEachSeriesItem calls fn with the metadata of every item indexed under
+// a series. Entries whose metadata is missing or unreadable are skipped.
+func forEachSeriesItem(tx *bbolt.Tx, seriesID string, fn func(*MediaMetadata)) {
+	metaBucket := tx.Bucket(bucketMetadata)
+	prefix := []byte(seriesID + ":")
+	cursor := tx.Bucket(bucketSeriesIndex).Cursor()
+	for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+		data := metaBucket.Get([]byte("meta:" + string(k[len(prefix):])))
+		if data == nil {
+			continue
+		}
+		var metadata MediaMetadata
+		if err := json.Unmarshal(data, &metadata); err != nil {
+			continue // Skip invalid metadata
+		}
+		// Guards against a series ID that is a prefix of another ending in ":"
+		if metadata.SeriesID == seriesID {
+			fn(&metadata)
+		}
+	}
+}
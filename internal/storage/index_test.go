@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+func TestQueueIndex(t *testing.T) {
+	manager := createTestManager(t, t.TempDir())
+	defer manager.Close()
+
+	now := time.Now()
+	if err := manager.AddQueueItem(&QueueItem{ID: "a", MediaID: "media-a", Priority: 3, Status: "queued", CreatedAt: now}); err != nil {
+		t.Fatalf("Failed to add queue item: %v", err)
+	}
+	// Re-adding under another priority moves the item instead of duplicating it
+	if err := manager.AddQueueItem(&QueueItem{ID: "a", MediaID: "media-a", Priority: 1, Status: "queued", CreatedAt: now}); err != nil {
+		t.Fatalf("Failed to re-add queue item: %v", err)
+	}
+	items, _ := manager.GetQueueItems("")
+	if len(items) != 1 || items[0].Priority != 1 {
+		t.Fatalf("Expected one item at priority 1, got %+v", items)
+	}
+
+	if err := manager.UpdateQueueItemStatus("a", "downloading", 50, ""); err != nil {
+		t.Fatalf("UpdateQueueItemStatus failed: %v", err)
+	}
+	if err := manager.SetQueueItemUsers("a", []string{"alice"}); err != nil {
+		t.Fatalf("SetQueueItemUsers failed: %v", err)
+	}
+	item, _ := manager.FindActiveQueueItem("media-a")
+	if item == nil || item.Status != "downloading" || item.Progress != 50 || len(item.Users) != 1 {
+		t.Errorf("Expected updates to reach the item, got %+v", item)
+	}
+
+	if err := manager.RemoveQueueItem("a"); err != nil {
+		t.Fatalf("RemoveQueueItem failed: %v", err)
+	}
+	if err := manager.RemoveQueueItem("a"); err == nil {
+		t.Error("Expected error removing an item twice")
+	}
+	if err := manager.UpdateQueueItemStatus("a", "failed", 0, ""); err == nil {
+		t.Error("Expected error updating a removed item")
+	}
+}
+
+func TestSeriesIndex(t *testing.T) {
+	manager := createTestManager(t, t.TempDir())
+	defer manager.Close()
+
+	for _, metadata := range []*MediaMetadata{
+		{ID: "e2", JellyfinID: "e2", Type: "episode", SeriesID: "s1", SeasonNumber: 1, EpisodeNumber: 2},
+		{ID: "e1", JellyfinID: "e1", Type: "episode", SeriesID: "s1", SeasonNumber: 1, EpisodeNumber: 1},
+		{ID: "x1", JellyfinID: "x1", Type: "episode", SeriesID: "s1:x", SeasonNumber: 1, EpisodeNumber: 1},
+		{ID: "m1", JellyfinID: "m1", Type: "movie"},
+	} {
+		if err := manager.AddMediaMetadata(metadata); err != nil {
+			t.Fatalf("Failed to add metadata: %v", err)
+		}
+	}
+
+	episodes, err := manager.GetSeriesEpisodes("s1", 1)
+	if err != nil {
+		t.Fatalf("GetSeriesEpisodes failed: %v", err)
+	}
+	if len(episodes) != 2 || episodes[0].ID != "e1" || episodes[1].ID != "e2" {
+		t.Errorf("Expected e1 and e2 in order, got %+v", episodes)
+	}
+
+	// Moving an episode to another series updates the index
+	if err := manager.AddMediaMetadata(&MediaMetadata{ID: "e2", JellyfinID: "e2", Type: "episode", SeriesID: "s2", SeasonNumber: 1, EpisodeNumber: 1}); err != nil {
+		t.Fatalf("Failed to update metadata: %v", err)
+	}
+	series, _ := manager.GetSeriesMetadata("s1")
+	if len(series) != 1 || series[0].ID != "e1" {
+		t.Errorf("Expected only e1 left in s1, got %+v", series)
+	}
+	series, _ = manager.GetSeriesMetadata("s2")
+	if len(series) != 1 || series[0].ID != "e2" {
+		t.Errorf("Expected e2 in s2, got %+v", series)
+	}
+}
+
+func TestMigrateBackfillsIndexes(t *testing.T) {
+	dir := t.TempDir()
+	manager := createTestManager(t, dir)
+
+	if err := manager.AddQueueItem(&QueueItem{ID: "q1", MediaID: "media-1", Priority: 2, Status: "queued", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to add queue item: %v", err)
+	}
+	if err := manager.AddMediaMetadata(&MediaMetadata{ID: "e1", JellyfinID: "e1", Type: "episode", SeriesID: "s1", SeasonNumber: 1, EpisodeNumber: 1}); err != nil {
+		t.Fatalf("Failed to add metadata: %v", err)
+	}
+
+	// Turn the database back into one written before the indexes existed
+	if err := manager.update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{bucketQueueIndex, bucketSeriesIndex} {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(bucketConfig).Delete(schemaVersionKey)
+	}); err != nil {
+		t.Fatalf("Failed to strip indexes: %v", err)
+	}
+	manager.Close()
+
+	manager = createTestManager(t, dir)
+	defer manager.Close()
+
+	if err := manager.UpdateQueueItemStatus("q1", "downloading", 10, ""); err != nil {
+		t.Errorf("Expected queue index to be backfilled: %v", err)
+	}
+	episodes, _ := manager.GetSeriesEpisodes("s1", 1)
+	if len(episodes) != 1 {
+		t.Errorf("Expected series index to be backfilled, got %+v", episodes)
+	}
+
+	var version string
+	manager.view(func(tx *bbolt.Tx) error {
+		version = string(tx.Bucket(bucketConfig).Get(schemaVersionKey))
+		return nil
+	})
+	if version != strconv.Itoa(len(migrations)) {
+		t.Errorf("Expected schema version %d, got %q", len(migrations), version)
+	}
+}
+
+func TestMigrateRekeysQueue(t *testing.T) {
+	dir := t.TempDir()
+	manager := createTestManager(t, dir)
+
+	// Two items queued in the same second, written under the key pattern
+	// with ages in seconds, which orders them by ID
+	queued := time.Unix(1700000000, 0)
+	if err := manager.update(func(tx *bbolt.Tx) error {
+		for i, id := range []string{"b", "a"} {
+			item := &QueueItem{ID: id, MediaID: "media-" + id, Priority: 2, Status: "queued", CreatedAt: queued.Add(time.Duration(i) * time.Millisecond)}
+			key := []byte(fmt.Sprintf("%03d:%d:%s", item.Priority, item.CreatedAt.Unix(), item.ID))
+			if err := putQueueItem(tx, key, item); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(bucketConfig).Put(schemaVersionKey, []byte("4"))
+	}); err != nil {
+		t.Fatalf("Failed to write old queue keys: %v", err)
+	}
+	manager.Close()
+
+	manager = createTestManager(t, dir)
+	defer manager.Close()
+
+	items, err := manager.GetQueueItems("")
+	if err != nil {
+		t.Fatalf("GetQueueItems failed: %v", err)
+	}
+	if len(items) != 2 || items[0].ID != "b" || items[1].ID != "a" {
+		t.Fatalf("Expected the queue in the order items were queued, got %+v", items)
+	}
+	if err := manager.UpdateQueueItemStatus("a", "downloading", 10, ""); err != nil {
+		t.Errorf("Expected the queue index to follow the new keys: %v", err)
+	}
+}
@@ -0,0 +1,340 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.etcd.io/bbolt"
+)
+
+// Library sort orders accepted by QueryCachedItems.
+const (
+	LibrarySortAdded    = "added"    // When the item was downloaded; the default
+	LibrarySortSize     = "size"     // Size on disk
+	LibrarySortAccessed = "accessed" // When the item was last played from the cache
+	LibrarySortName     = "name"     // Title, case-insensitively
+)
+
+// ErrInvalidLibraryQuery is returned for an unknown sort order or a cursor
+// that was not produced by a query with the same sort order.
+var ErrInvalidLibraryQuery = errors.New("invalid library query")
+
+// librarySortTimeLayout formats times in sort keys; it is fixed width so
+// keys compare like the times they hold.
+const librarySortTimeLayout = "20060102T150405.000000000"
+
+// LibraryQuery selects a page of the cached library.
+type LibraryQuery struct {
+	MediaType string // movie, episode, ...; empty for all
+	Sort      string // One of the LibrarySort constants; empty for LibrarySortAdded
+	// Order is "asc" or "desc". Empty sorts names A to Z and everything
+	// else newest or largest first.
+	Order  string
+	Limit  int
+	Offset int // Items to skip; ignored when Cursor is set
+	// Cursor continues after the last item of a previous page, which
+	// stays stable while items are added or removed
+	Cursor string
+}
+
+// LibraryPage is one page of the cached library.
+type LibraryPage struct {
+	Items []*CachedItem `json:"items"`
+	// Total is the number of cached items of the queried media type
+	Total int `json:"total"`
+	// NextCursor fetches the following page; empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// listedInLibrary reports whether a download record is part of the cached
+// library. Evicted records are kept as download history only.
+func listedInLibrary(record *DownloadRecord) bool {
+	return record != nil && record.Status != "evicted"
+}
+
+// QueryCachedItems returns a sorted page of the cached library, with the
+// total number of cached items of the queried type.
+func (m *Manager) QueryCachedItems(query LibraryQuery) (*LibraryPage, error) {
+	var items []*CachedItem
+	var total int
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		metaBucket := tx.Bucket(bucketMetadata)
+		pinBucket := tx.Bucket(bucketPins)
+
+		if err := tx.Bucket(bucketDownloads).ForEach(func(k, v []byte) error {
+			var record DownloadRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				m.logger.Warn("Failed to unmarshal download record",
+					"key", string(k), "error", err)
+				return nil
+			}
+			if !listedInLibrary(&record) || (query.MediaType != "" && record.MediaType != query.MediaType) {
+				return nil
+			}
+
+			item := cachedItemFromRecord(&record)
+			item.Pinned = pinnedIn(pinBucket, metaBucket, record.JellyfinID)
+			if data := metaBucket.Get([]byte("meta:" + record.JellyfinID)); data != nil {
+				var metadata MediaMetadata
+				if err := json.Unmarshal(data, &metadata); err == nil {
+					ApplyCachedItemMetadata(item, &metadata)
+				}
+			}
+			items = append(items, item)
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		var err error
+		total, err = libraryCount(tx, query.MediaType)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cached items: %w", err)
+	}
+
+	page, err := PaginateCachedItems(items, query)
+	if err != nil {
+		return nil, err
+	}
+	page.Total = total
+	return page, nil
+}
+
+// GetCachedItemsCount returns the number of cached items, optionally
+// filtered by media type, from the library count index.
+func (m *Manager) GetCachedItemsCount(mediaType string) (int, error) {
+	var count int
+	err := m.view(func(tx *bbolt.Tx) error {
+		var err error
+		count, err = libraryCount(tx, mediaType)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get cached items count: %w", err)
+	}
+	return count, nil
+}
+
+// cachedItemFromRecord describes a download record as a library item.
+func cachedItemFromRecord(record *DownloadRecord) *CachedItem {
+	return &CachedItem{
+		ID:           record.JellyfinID,
+		Name:         record.Title,
+		Type:         record.MediaType,
+		Path:         record.LocalPath,
+		Size:         record.Size,
+		DateAdded:    record.DownloadedAt,
+		LastAccessed: record.LastAccessed,
+	}
+}
+
+// ApplyCachedItemMetadata fills in the fields of a library item that come
+// from its media metadata.
+func ApplyCachedItemMetadata(item *CachedItem, metadata *MediaMetadata) {
+	item.SeriesID = metadata.SeriesID
+	item.SeasonNumber = metadata.SeasonNumber
+	item.EpisodeNumber = metadata.EpisodeNumber
+	item.LastSynced = metadata.LastSynced
+}
+
+// PaginateCachedItems sorts items as query asks and cuts out the requested
+// page. Page.Total is left for the caller. It is shared with in-memory
+// stores, so both page the same way.
+func PaginateCachedItems(items []*CachedItem, query LibraryQuery) (*LibraryPage, error) {
+	sortBy := query.Sort
+	if sortBy == "" {
+		sortBy = LibrarySortAdded
+	}
+	switch sortBy {
+	case LibrarySortAdded, LibrarySortSize, LibrarySortAccessed, LibrarySortName:
+	default:
+		return nil, fmt.Errorf("%w: unknown sort order %q", ErrInvalidLibraryQuery, query.Sort)
+	}
+
+	desc := sortBy != LibrarySortName
+	switch query.Order {
+	case "":
+	case "asc":
+		desc = false
+	case "desc":
+		desc = true
+	default:
+		return nil, fmt.Errorf("%w: order must be asc or desc, not %q", ErrInvalidLibraryQuery, query.Order)
+	}
+
+	keys := make(map[*CachedItem]string, len(items))
+	for _, item := range items {
+		keys[item] = librarySortKey(item, sortBy)
+	}
+	// less orders by the sort key in the requested direction; ties are
+	// always broken by ID, so pages never overlap
+	less := func(key, id, otherKey, otherID string) bool {
+		if key != otherKey {
+			return (key < otherKey) != desc
+		}
+		return id < otherID
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return less(keys[items[i]], items[i].ID, keys[items[j]], items[j].ID)
+	})
+
+	start := min(max(query.Offset, 0), len(items))
+	// Cursors only make sense for the order they were made for
+	order := sortBy
+	if desc {
+		order += "-"
+	}
+	if query.Cursor != "" {
+		key, id, err := decodeLibraryCursor(query.Cursor, order)
+		if err != nil {
+			return nil, err
+		}
+		start = sort.Search(len(items), func(i int) bool {
+			return less(key, id, keys[items[i]], items[i].ID)
+		})
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = len(items)
+	}
+	end := min(start+limit, len(items))
+
+	page := &LibraryPage{Items: items[start:end]}
+	if end < len(items) && end > start {
+		last := items[end-1]
+		page.NextCursor = encodeLibraryCursor(keys[last], last.ID, order)
+	}
+	return page, nil
+}
+
+// librarySortKey returns a string that orders items by sortBy when compared.
+func librarySortKey(item *CachedItem, sortBy string) string {
+	switch sortBy {
+	case LibrarySortSize:
+		return fmt.Sprintf("%020d", max(item.Size, 0))
+	case LibrarySortAccessed:
+		return item.LastAccessed.UTC().Format(librarySortTimeLayout)
+	case LibrarySortName:
+		return strings.ToLower(item.Name)
+	default:
+		return item.DateAdded.UTC().Format(librarySortTimeLayout)
+	}
+}
+
+// encodeLibraryCursor encodes the position of the item with the given sort
+// key and ID in order.
+func encodeLibraryCursor(key, id, order string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(order + "\x00" + key + "\x00" + id))
+}
+
+// decodeLibraryCursor returns the sort key and ID a cursor for order
+// points after.
+func decodeLibraryCursor(cursor, order string) (string, string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: malformed cursor", ErrInvalidLibraryQuery)
+	}
+	parts := strings.SplitN(string(data), "\x00", 3)
+	if len(parts) != 3 || parts[0] != order {
+		return "", "", fmt.Errorf("%w: cursor is for another sort order", ErrInvalidLibraryQuery)
+	}
+	return parts[1], parts[2], nil
+}
+
+// libraryCount returns the number of cached items of mediaType, or of all
+// types when it is empty, from the library count index.
+func libraryCount(tx *bbolt.Tx, mediaType string) (int, error) {
+	bucket := tx.Bucket(bucketLibraryCounts)
+	if mediaType != "" {
+		return parseLibraryCount(bucket.Get([]byte(mediaType)))
+	}
+
+	total := 0
+	err := bucket.ForEach(func(k, v []byte) error {
+		count, err := parseLibraryCount(v)
+		total += count
+		return err
+	})
+	return total, err
+}
+
+func parseLibraryCount(v []byte) (int, error) {
+	if v == nil {
+		return 0, nil
+	}
+	count, err := strconv.Atoi(string(v))
+	if err != nil {
+		return 0, fmt.Errorf("invalid library count %q: %w", v, err)
+	}
+	return count, nil
+}
+
+// updateLibraryCounts keeps the library count index in step with a
+// download record changing from old to updated; either may be nil.
+func updateLibraryCounts(tx *bbolt.Tx, old, updated *DownloadRecord) error {
+	if listedInLibrary(old) {
+		if err := addLibraryCount(tx, old.MediaType, -1); err != nil {
+			return err
+		}
+	}
+	if listedInLibrary(updated) {
+		if err := addLibraryCount(tx, updated.MediaType, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addLibraryCount(tx *bbolt.Tx, mediaType string, delta int) error {
+	bucket := tx.Bucket(bucketLibraryCounts)
+	count, err := parseLibraryCount(bucket.Get([]byte(mediaType)))
+	if err != nil {
+		return err
+	}
+	if count += delta; count <= 0 {
+		return bucket.Delete([]byte(mediaType))
+	}
+	return bucket.Put([]byte(mediaType), []byte(strconv.Itoa(count)))
+}
+
+// storedDownloadRecord returns the download record stored under key, or
+// nil if there is none or it cannot be read.
+func storedDownloadRecord(bucket *bbolt.Bucket, key []byte) *DownloadRecord {
+	data := bucket.Get(key)
+	if data == nil {
+		return nil
+	}
+	var record DownloadRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil
+	}
+	return &record
+}
+
+// rebuildLibraryCounts recreates the library count index from the
+// downloads bucket.
+func rebuildLibraryCounts(tx *bbolt.Tx) error {
+	if err := tx.DeleteBucket(bucketLibraryCounts); err != nil && err != bbolt.ErrBucketNotFound {
+		return err
+	}
+	if _, err := tx.CreateBucket(bucketLibraryCounts); err != nil {
+		return err
+	}
+
+	return tx.Bucket(bucketDownloads).ForEach(func(k, v []byte) error {
+		var record DownloadRecord
+		if err := json.Unmarshal(v, &record); err != nil {
+			return nil // Unreadable records are never listed either
+		}
+		return updateLibraryCounts(tx, nil, &record)
+	})
+}
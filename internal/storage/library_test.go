@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+func TestLibraryCounts(t *testing.T) {
+	dir := t.TempDir()
+	manager := createTestManager(t, dir)
+
+	count := func(mediaType string) int {
+		t.Helper()
+		n, err := manager.GetCachedItemsCount(mediaType)
+		if err != nil {
+			t.Fatalf("GetCachedItemsCount failed: %v", err)
+		}
+		return n
+	}
+
+	for _, record := range []*DownloadRecord{
+		{ID: "m1", JellyfinID: "m1", MediaType: "movie", Status: "completed", DownloadedAt: time.Now()},
+		{ID: "m2", JellyfinID: "m2", MediaType: "movie", Status: "completed", DownloadedAt: time.Now()},
+		{ID: "e1", JellyfinID: "e1", MediaType: "episode", Status: "completed", DownloadedAt: time.Now()},
+		// Storing a record again replaces it rather than counting it twice
+		{ID: "m1", JellyfinID: "m1", MediaType: "movie", Status: "completed", DownloadedAt: time.Now()},
+	} {
+		if err := manager.AddDownloadRecord(record); err != nil {
+			t.Fatalf("AddDownloadRecord failed: %v", err)
+		}
+	}
+	if count("movie") != 2 || count("episode") != 1 || count("") != 3 {
+		t.Fatalf("Expected 2 movies and 1 episode, got %d and %d", count("movie"), count("episode"))
+	}
+
+	if err := manager.MarkEvicted("movie", "m2"); err != nil {
+		t.Fatalf("MarkEvicted failed: %v", err)
+	}
+	if err := manager.RemoveDownloadRecord("episode", "e1"); err != nil {
+		t.Fatalf("RemoveDownloadRecord failed: %v", err)
+	}
+	if err := manager.RemoveDownloadRecord("movie", "m2"); err != nil {
+		t.Fatalf("RemoveDownloadRecord failed: %v", err)
+	}
+	if count("movie") != 1 || count("episode") != 0 {
+		t.Errorf("Expected 1 movie and no episodes, got %d and %d", count("movie"), count("episode"))
+	}
+
+	// The migration rebuilds the index for databases written before it
+	if err := manager.update(func(tx *bbolt.Tx) error {
+		if err := tx.DeleteBucket(bucketLibraryCounts); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketConfig).Put(schemaVersionKey, []byte("2"))
+	}); err != nil {
+		t.Fatalf("Failed to strip library counts: %v", err)
+	}
+	manager.Close()
+
+	manager = createTestManager(t, dir)
+	defer manager.Close()
+	if count("movie") != 1 || count("") != 1 {
+		t.Errorf("Expected the rebuilt index to count 1 movie, got %d", count("movie"))
+	}
+}
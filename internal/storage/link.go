@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Ways LinkFile can bring a file into the cache without downloading it.
+const (
+	LinkAuto     = "auto"     // Reflink, else hardlink, else copy
+	LinkReflink  = "reflink"  // Share the file's extents (Btrfs, XFS); copy-on-write
+	LinkHardlink = "hardlink" // Another name for the same file; same filesystem only
+	LinkCopy     = "copy"     // A full copy; works across filesystems
+)
+
+// ErrReflinkUnsupported is returned for reflinks on platforms without them.
+var ErrReflinkUnsupported = errors.New("reflinks are not supported on this platform")
+
+// LinkFile puts the file at src at dst as mode asks and returns how it did
+// so: LinkReflink, LinkHardlink or LinkCopy. dst appears complete or not at
+// all. Under LinkAuto each way is tried in turn and the last error is
+// returned if none works.
+func LinkFile(src, dst, mode string) (string, error) {
+	var methods []string
+	switch mode {
+	case LinkAuto, "":
+		methods = []string{LinkReflink, LinkHardlink, LinkCopy}
+	case LinkReflink, LinkHardlink, LinkCopy:
+		methods = []string{mode}
+	default:
+		return "", fmt.Errorf("unknown link mode %q", mode)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Link beside the destination, then atomically move it into place
+	temp := dst + ".link"
+	var err error
+	for _, method := range methods {
+		os.Remove(temp)
+		switch method {
+		case LinkReflink:
+			err = reflinkFile(src, temp)
+		case LinkHardlink:
+			err = os.Link(src, temp)
+		case LinkCopy:
+			err = copyFile(src, temp)
+		}
+		if err != nil {
+			continue
+		}
+		if err = os.Rename(temp, dst); err != nil {
+			break
+		}
+		return method, nil
+	}
+	os.Remove(temp)
+	return "", fmt.Errorf("failed to %s %s: %w", methods[len(methods)-1], src, err)
+}
+
+// reflinkFile creates dst as a reflink of src.
+func reflinkFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if err := reflink(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
+
+// copyFile copies src to the new file dst and syncs it to disk.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
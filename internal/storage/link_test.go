@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLinkFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "library", "movie.mkv")
+	if err := os.MkdirAll(filepath.Dir(src), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(src, []byte("movie"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		t.Fatalf("Failed to stat source: %v", err)
+	}
+
+	for _, mode := range []string{LinkHardlink, LinkCopy, LinkAuto} {
+		dst := filepath.Join(dir, "cache", mode, "movie.mkv")
+		method, err := LinkFile(src, dst, mode)
+		if err != nil {
+			t.Fatalf("LinkFile(%s) failed: %v", mode, err)
+		}
+
+		data, err := os.ReadFile(dst)
+		if err != nil || string(data) != "movie" {
+			t.Errorf("%s: expected the file's content, got %q (%v)", mode, data, err)
+		}
+		if _, err := os.Stat(dst + ".link"); !os.IsNotExist(err) {
+			t.Errorf("%s: expected no temporary file to be left", mode)
+		}
+
+		dstInfo, _ := os.Stat(dst)
+		switch mode {
+		case LinkHardlink:
+			if method != LinkHardlink || !os.SameFile(srcInfo, dstInfo) {
+				t.Errorf("Expected a hardlink, got %s", method)
+			}
+		case LinkCopy:
+			if method != LinkCopy || os.SameFile(srcInfo, dstInfo) {
+				t.Errorf("Expected a copy, got %s", method)
+			}
+		case LinkAuto:
+			// Whether reflinks work depends on the filesystem
+			if method != LinkReflink && method != LinkHardlink {
+				t.Errorf("Expected a reflink or hardlink, got %s", method)
+			}
+		}
+	}
+
+	if _, err := LinkFile(src, filepath.Join(dir, "cache", "x.mkv"), "symlink"); err == nil {
+		t.Error("Expected an unknown mode to fail")
+	}
+	if _, err := LinkFile(filepath.Join(dir, "missing.mkv"), filepath.Join(dir, "cache", "y.mkv"), LinkAuto); err == nil {
+		t.Error("Expected a missing source to fail")
+	}
+}
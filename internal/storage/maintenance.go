@@ -0,0 +1,357 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// compactTxMaxSize bounds the size of each write transaction while copying
+// the database during compaction.
+const compactTxMaxSize = 64 * 1024 * 1024
+
+// VerifyResult summarizes a cache verification pass.
+type VerifyResult struct {
+	Checked int      `json:"checked"`
+	Missing int      `json:"missing"`        // Records whose file was gone; the record is removed
+	Corrupt int      `json:"corrupt"`        // Files that failed size or checksum checks; both are removed
+	Lost    []string `json:"lost,omitempty"` // Media IDs of the missing and corrupt items, which can be downloaded again
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// OrphanResult summarizes a sweep for cached files without a download
+// record.
+type OrphanResult struct {
+	Removed int      `json:"removed"`
+	Bytes   int64    `json:"bytes"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// CompactResult reports the database file size before and after compaction.
+type CompactResult struct {
+	BytesBefore int64         `json:"bytes_before"`
+	BytesAfter  int64         `json:"bytes_after"`
+	Duration    time.Duration `json:"duration"`
+}
+
+// VerifyCache checks every completed download against the file on disk.
+// Records whose file is missing are removed, and files whose size or
+// checksum no longer match are deleted with their record so they can be
+// downloaded again. Files without a stored checksum get one recorded. With
+// checksums turned off only file sizes are checked. Verification stops
+// early, keeping what it has done, when ctx is cancelled.
+func (m *Manager) VerifyCache(ctx context.Context) (*VerifyResult, error) {
+	records, err := m.ListDownloadRecords("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list downloads: %w", err)
+	}
+
+	result := &VerifyResult{}
+	files := m.files()
+	algorithm := m.ChecksumAlgorithm()
+
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if record.Status != "completed" {
+			continue
+		}
+		result.Checked++
+
+		// Encrypted files are larger on disk than their content
+		size, err := CachedSize(record.LocalPath)
+		if os.IsNotExist(err) {
+			if err := m.RemoveDownloadRecord(record.MediaType, record.JellyfinID); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", record.JellyfinID, err))
+				continue
+			}
+			m.logger.Warn("Removed download record for missing file",
+				"media_id", record.JellyfinID, "path", record.LocalPath)
+			result.Missing++
+			result.Lost = append(result.Lost, record.JellyfinID)
+			continue
+		}
+		if err != nil && !errors.Is(err, errCorrupted) {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", record.JellyfinID, err))
+			continue
+		}
+
+		corrupt := err != nil || record.Size > 0 && size != record.Size
+		if !corrupt && algorithm != ChecksumOff {
+			// An encrypted file that fails to decrypt is corrupt whether or
+			// not there is a checksum to compare with
+			if record.Checksum == "" {
+				_, err := m.recordChecksum(record, algorithm)
+				if err != nil && !errors.Is(err, errCorrupted) {
+					result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", record.JellyfinID, err))
+				}
+				if !errors.Is(err, errCorrupted) {
+					continue
+				}
+				corrupt = true
+			} else {
+				// Verify with whatever algorithm the checksum was made with
+				valid, err := files.VerifyChecksumWith(record.LocalPath, record.checksumAlgorithm(), record.Checksum)
+				if err != nil && !errors.Is(err, errCorrupted) {
+					result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", record.JellyfinID, err))
+					continue
+				}
+				corrupt = !valid
+			}
+		}
+		if !corrupt {
+			continue
+		}
+
+		if err := os.Remove(record.LocalPath); err != nil && !os.IsNotExist(err) {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", record.JellyfinID, err))
+			continue
+		}
+		if err := m.RemoveDownloadRecord(record.MediaType, record.JellyfinID); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", record.JellyfinID, err))
+			continue
+		}
+		m.logger.Warn("Removed corrupt cached file",
+			"media_id", record.JellyfinID, "path", record.LocalPath)
+		result.Corrupt++
+		result.Lost = append(result.Lost, record.JellyfinID)
+	}
+
+	m.logger.Info("Cache verification complete",
+		"checked", result.Checked,
+		"missing", result.Missing,
+		"corrupt", result.Corrupt,
+		"errors", len(result.Errors))
+
+	return result, nil
+}
+
+// RemoveOrphanedFiles deletes files in the cache's media directories that
+// no download record points to, such as files left behind when a record was
+// lost. Sidecars (metadata, artwork and seek previews), partial downloads
+// and files modified after before are left alone, so downloads finishing
+// during the sweep are not mistaken for orphans. Directories left holding
+// only sidecars are removed too.
+func (m *Manager) RemoveOrphanedFiles(ctx context.Context, before time.Time) (*OrphanResult, error) {
+	records, err := m.ListDownloadRecords("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list downloads: %w", err)
+	}
+	known := make(map[string]bool, len(records))
+	for _, record := range records {
+		known[filepath.Clean(record.LocalPath)] = true
+	}
+
+	result := &OrphanResult{}
+	for _, dir := range []string{"movies", "series", "music", "audiobooks"} {
+		root := filepath.Join(m.config.Directory, dir)
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", path, err))
+				return nil
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if d.IsDir() && IsSidecar(d.Name()) {
+				return fs.SkipDir
+			}
+			if d.IsDir() || IsSidecar(d.Name()) || strings.HasSuffix(path, PartialSuffix) || known[filepath.Clean(path)] {
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", path, err))
+				return nil
+			}
+			if info.ModTime().After(before) {
+				return nil
+			}
+
+			if err := os.Remove(path); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", path, err))
+				return nil
+			}
+			m.logger.Warn("Removed orphaned cache file", "path", path, "size_bytes", info.Size())
+			result.Removed++
+			result.Bytes += info.Size()
+			removeSidecarOnlyDir(filepath.Dir(path))
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	m.logger.Info("Orphaned file sweep complete",
+		"removed", result.Removed,
+		"bytes", result.Bytes,
+		"errors", len(result.Errors))
+
+	return result, nil
+}
+
+// removeSidecarOnlyDir removes dir if nothing but a .meta.json, cached
+// artwork and seek previews is left in it.
+func removeSidecarOnlyDir(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !IsSidecar(entry.Name()) {
+			return
+		}
+	}
+	for _, entry := range entries {
+		os.RemoveAll(filepath.Join(dir, entry.Name()))
+	}
+	os.Remove(dir)
+}
+
+// CompactDatabase rewrites the metadata database into a new file to reclaim
+// space freed by deleted records, then swaps it in. All storage access
+// blocks while it runs.
+func (m *Manager) CompactDatabase(ctx context.Context) (*CompactResult, error) {
+	m.dbMu.Lock()
+	defer m.dbMu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	path := m.db.Path()
+	compactPath := path + ".compact"
+
+	before, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat database: %w", err)
+	}
+
+	os.Remove(compactPath)
+	dst, err := bbolt.Open(compactPath, 0600, &bbolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compacted database: %w", err)
+	}
+
+	if err := bbolt.Compact(dst, m.db, compactTxMaxSize); err != nil {
+		dst.Close()
+		os.Remove(compactPath)
+		return nil, fmt.Errorf("failed to compact database: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(compactPath)
+		return nil, fmt.Errorf("failed to close compacted database: %w", err)
+	}
+
+	if err := m.db.Close(); err != nil {
+		os.Remove(compactPath)
+		return nil, fmt.Errorf("failed to close database: %w", err)
+	}
+
+	// Reopen whichever file ended up at path, so storage stays usable
+	// even if the swap fails
+	swapErr := os.Rename(compactPath, path)
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reopen database at %s: %w", path, err)
+	}
+	m.db = db
+
+	if swapErr != nil {
+		os.Remove(compactPath)
+		return nil, fmt.Errorf("failed to replace database: %w", swapErr)
+	}
+
+	after, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat compacted database: %w", err)
+	}
+
+	result := &CompactResult{
+		BytesBefore: before.Size(),
+		BytesAfter:  after.Size(),
+		Duration:    time.Since(started),
+	}
+
+	m.logger.Info("Database compacted",
+		"bytes_before", result.BytesBefore,
+		"bytes_after", result.BytesAfter,
+		"duration", result.Duration)
+
+	return result, nil
+}
+
+// Backup writes a consistent snapshot of the metadata database to w and
+// returns how many bytes were written. The snapshot is first copied to a
+// temporary file next to the database, so a slow reader does not hold up
+// compaction and, behind it, all other storage access. size, if not nil,
+// is called with the snapshot size before anything is written.
+func (m *Manager) Backup(w io.Writer, size func(int64)) (int64, error) {
+	snapshot, err := m.snapshotDatabase()
+	if err != nil {
+		return 0, fmt.Errorf("failed to write database backup: %w", err)
+	}
+	defer func() {
+		snapshot.Close()
+		os.Remove(snapshot.Name())
+	}()
+
+	info, err := snapshot.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat database backup: %w", err)
+	}
+	if size != nil {
+		size(info.Size())
+	}
+
+	written, err := io.Copy(w, snapshot)
+	if err != nil {
+		return written, fmt.Errorf("failed to write database backup: %w", err)
+	}
+	return written, nil
+}
+
+// snapshotDatabase copies the database in a read transaction to a
+// temporary file and returns the file, rewound. The caller removes it.
+func (m *Manager) snapshotDatabase() (*os.File, error) {
+	var snapshot *os.File
+	err := m.view(func(tx *bbolt.Tx) error {
+		path := tx.DB().Path()
+		file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".backup-*")
+		if err != nil {
+			return err
+		}
+		if _, err := tx.WriteTo(file); err != nil {
+			file.Close()
+			os.Remove(file.Name())
+			return err
+		}
+		snapshot = file
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := snapshot.Seek(0, io.SeekStart); err != nil {
+		snapshot.Close()
+		os.Remove(snapshot.Name())
+		return nil, err
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,344 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func TestVerifyCache(t *testing.T) {
+	manager := newStatsTestManager(t)
+
+	addCachedFile(t, manager, "ok", "movie", "Heat", "intact movie")
+	missing := addCachedFile(t, manager, "missing", "movie", "Dune", "soon gone")
+	truncated := addCachedFile(t, manager, "truncated", "movie", "Alien", "full length file")
+	tampered := addCachedFile(t, manager, "tampered", "movie", "Tron", "original bytes")
+
+	if _, err := manager.recordChecksum(tampered, ChecksumSHA256); err != nil {
+		t.Fatalf("recordChecksum failed: %v", err)
+	}
+	os.Remove(missing.LocalPath)
+	os.WriteFile(truncated.LocalPath, []byte("short"), 0644)
+	os.WriteFile(tampered.LocalPath, []byte("altered bytes!"), 0644)
+
+	result, err := manager.VerifyCache(context.Background())
+	if err != nil {
+		t.Fatalf("VerifyCache failed: %v", err)
+	}
+	if result.Checked != 4 || result.Missing != 1 || result.Corrupt != 2 || len(result.Errors) != 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	sort.Strings(result.Lost)
+	if fmt.Sprint(result.Lost) != "[missing tampered truncated]" {
+		t.Errorf("Expected lost items to be reported, got %v", result.Lost)
+	}
+
+	for _, id := range []string{"missing", "truncated", "tampered"} {
+		if record, _ := manager.GetDownloadRecord("movie", id); record != nil {
+			t.Errorf("Expected record %s to be removed", id)
+		}
+	}
+	if _, err := os.Stat(tampered.LocalPath); !os.IsNotExist(err) {
+		t.Error("Expected corrupt file to be deleted")
+	}
+
+	// The intact file gets a checksum for future passes
+	record, err := manager.GetDownloadRecord("movie", "ok")
+	if err != nil || record.Checksum == "" {
+		t.Errorf("Expected checksum to be recorded for intact file, got %+v (%v)", record, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := manager.VerifyCache(ctx); err == nil {
+		t.Error("Expected error for cancelled context")
+	}
+}
+
+func TestVerifyCacheEncrypted(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	manager, err := NewManager(&config.CacheConfig{
+		Directory: t.TempDir(), MaxSizeGB: 1, MetadataStore: "boltdb",
+		Encryption: config.CacheEncryptionConfig{Enabled: true, Key: testCacheKey},
+	}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	defer manager.Close()
+
+	// Records hold the size of the content, not of the encrypted file
+	intact := addCachedFile(t, manager, "ok", "movie", "Heat", "intact movie")
+	tampered := addCachedFile(t, manager, "tampered", "movie", "Tron", "original bytes")
+	for _, record := range []*DownloadRecord{intact, tampered} {
+		plain := record.LocalPath + PartialSuffix
+		os.Rename(record.LocalPath, plain)
+		if err := manager.Cipher().EncryptFile(plain, record.LocalPath); err != nil {
+			t.Fatalf("EncryptFile failed: %v", err)
+		}
+	}
+	data, _ := os.ReadFile(tampered.LocalPath)
+	data[len(data)-1] ^= 1
+	os.WriteFile(tampered.LocalPath, data, 0644)
+
+	if _, err := manager.recordChecksum(intact, ChecksumSHA256); err != nil {
+		t.Fatalf("recordChecksum failed: %v", err)
+	}
+	// Checksums are of the content, so they match the download's
+	if sum := sha256.Sum256([]byte("intact movie")); intact.Checksum != hex.EncodeToString(sum[:]) {
+		t.Fatalf("Expected the checksum of the decrypted content, got %q", intact.Checksum)
+	}
+
+	result, err := manager.VerifyCache(context.Background())
+	if err != nil {
+		t.Fatalf("VerifyCache failed: %v", err)
+	}
+	if result.Checked != 2 || result.Corrupt != 1 || len(result.Errors) != 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if fmt.Sprint(result.Lost) != "[tampered]" {
+		t.Errorf("Expected the tampered file to be lost, got %v", result.Lost)
+	}
+}
+
+func TestCompactDatabase(t *testing.T) {
+	manager := newStatsTestManager(t)
+
+	// Grow the database, then delete most of it
+	payload := make([]byte, 16*1024)
+	for i := 0; i < 200; i++ {
+		record := &DownloadRecord{
+			ID: fmt.Sprintf("m%d", i), JellyfinID: fmt.Sprintf("m%d", i), MediaType: "movie",
+			Title: string(payload), Status: "completed", DownloadedAt: time.Now(),
+		}
+		if err := manager.AddDownloadRecord(record); err != nil {
+			t.Fatalf("Failed to add record: %v", err)
+		}
+	}
+	for i := 1; i < 200; i++ {
+		manager.RemoveDownloadRecord("movie", fmt.Sprintf("m%d", i))
+	}
+
+	result, err := manager.CompactDatabase(context.Background())
+	if err != nil {
+		t.Fatalf("CompactDatabase failed: %v", err)
+	}
+	if result.BytesAfter >= result.BytesBefore {
+		t.Errorf("Expected database to shrink, got %d -> %d bytes", result.BytesBefore, result.BytesAfter)
+	}
+
+	// Data survives and the manager keeps working on the new file
+	if record, err := manager.GetDownloadRecord("movie", "m0"); err != nil || record == nil {
+		t.Fatalf("Expected record to survive compaction: %v", err)
+	}
+	if err := manager.RecordStreamRequest(true); err != nil {
+		t.Errorf("Expected writes to work after compaction: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(manager.config.Directory, "go-jf-watch.db.compact")); !os.IsNotExist(err) {
+		t.Error("Expected temporary compaction file to be gone")
+	}
+}
+
+func TestBackup(t *testing.T) {
+	manager := newStatsTestManager(t)
+	addCachedFile(t, manager, "heat", "movie", "Heat", "movie bytes")
+
+	path := filepath.Join(t.TempDir(), "backup.db")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create backup file: %v", err)
+	}
+	var size int64
+	written, err := manager.Backup(file, func(n int64) { size = n })
+	file.Close()
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if written == 0 || written != size {
+		t.Errorf("Expected %d bytes written, got %d", size, written)
+	}
+
+	// The backup opens as a working database with the same records
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+	if err := os.Rename(path, filepath.Join(dir, "go-jf-watch.db")); err != nil {
+		t.Fatalf("Failed to move backup: %v", err)
+	}
+	restored, err := NewManager(&config.CacheConfig{Directory: dir, MaxSizeGB: 1, MetadataStore: "boltdb"}, logger)
+	if err != nil {
+		t.Fatalf("Failed to open backup: %v", err)
+	}
+	defer restored.Close()
+	if record, err := restored.GetDownloadRecord("movie", "heat"); err != nil || record == nil {
+		t.Errorf("Expected record in backup: %v", err)
+	}
+}
+
+// blockingWriter accepts one write, then blocks until released, like a
+// slow client downloading a backup.
+type blockingWriter struct {
+	wrote   chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.wrote) })
+	<-w.release
+	return len(p), nil
+}
+
+func TestBackupDoesNotHoldUpCompaction(t *testing.T) {
+	manager := newStatsTestManager(t)
+	addCachedFile(t, manager, "heat", "movie", "Heat", "movie bytes")
+
+	w := &blockingWriter{wrote: make(chan struct{}), release: make(chan struct{})}
+	done := make(chan error, 1)
+	go func() {
+		_, err := manager.Backup(w, nil)
+		done <- err
+	}()
+	<-w.wrote
+
+	compacted := make(chan error, 1)
+	go func() {
+		_, err := manager.CompactDatabase(context.Background())
+		compacted <- err
+	}()
+	select {
+	case err := <-compacted:
+		if err != nil {
+			t.Errorf("CompactDatabase failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Compaction waited for the backup client")
+	}
+
+	close(w.release)
+	if err := <-done; err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	// The temporary snapshot is cleaned up
+	entries, err := os.ReadDir(manager.config.Directory)
+	if err != nil {
+		t.Fatalf("Failed to read cache directory: %v", err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".backup-") {
+			t.Errorf("Backup snapshot %s left behind", entry.Name())
+		}
+	}
+}
+
+func TestCleanupCacheDefersToMaintenanceWindow(t *testing.T) {
+	manager := newStatsTestManager(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.CacheConfig{Directory: manager.config.Directory, MaxSizeGB: 1, EvictionThreshold: 0.5}
+	cacheManager := NewCacheManager(cfg, manager, logger)
+
+	// 60% full: above the threshold but not an emergency
+	path := filepath.Join(cfg.Directory, "movies", "big", "video.mkv")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	size := int64(600 * 1024 * 1024)
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := os.Truncate(path, size); err != nil {
+		t.Fatalf("Failed to size file: %v", err)
+	}
+	manager.AddDownloadRecord(&DownloadRecord{
+		ID: "big", JellyfinID: "big", MediaType: "movie", LocalPath: path, Size: size,
+		Status: "completed", DownloadedAt: time.Now(), LastAccessed: time.Now().AddDate(0, 0, -30),
+	})
+
+	now := time.Now()
+	window := func(from, to time.Duration) *config.MaintenanceConfig {
+		return &config.MaintenanceConfig{Enabled: true, Start: now.Add(from).Format("15:04"), End: now.Add(to).Format("15:04")}
+	}
+
+	cacheManager.SetMaintenanceWindow(window(2*time.Hour, 3*time.Hour))
+	if err := cacheManager.CleanupCache(); err != nil {
+		t.Fatalf("Cleanup failed: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal("Expected cleanup outside the maintenance window to be deferred")
+	}
+
+	cacheManager.SetMaintenanceWindow(window(-time.Hour, time.Hour))
+	if err := cacheManager.CleanupCache(); err != nil {
+		t.Fatalf("Cleanup failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected cleanup to evict inside the maintenance window")
+	}
+}
+
+func TestRemoveOrphanedFiles(t *testing.T) {
+	manager := newStatsTestManager(t)
+	ctx := context.Background()
+
+	kept := addCachedFile(t, manager, "kept", "movie", "Heat", "recorded movie")
+	evicted := addCachedFile(t, manager, "evicted", "movie", "Dune", "evicted movie")
+	if err := manager.MarkEvicted("movie", "evicted"); err != nil {
+		t.Fatalf("MarkEvicted failed: %v", err)
+	}
+	if cached, _ := manager.IsMediaCached("evicted"); cached {
+		t.Error("Expected evicted item not to count as cached")
+	}
+
+	write := func(path, content string) {
+		t.Helper()
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	orphanDir := filepath.Join(manager.config.Directory, "series", "lost-show")
+	orphan := filepath.Join(orphanDir, "episode.mkv")
+	write(orphan, "no record")
+	write(filepath.Join(orphanDir, ".meta.json"), "{}")
+	write(ArtworkPath(orphan, "primary"), "poster")
+	keptPoster := ArtworkPath(kept.LocalPath, "primary")
+	write(keptPoster, "poster")
+	keptNFO := NFOPath(kept.LocalPath)
+	write(keptNFO, "<movie/>")
+	partial := filepath.Join(manager.config.Directory, "movies", "busy", "video.mp4"+PartialSuffix)
+	write(partial, "half")
+	fresh := filepath.Join(manager.config.Directory, "music", "new", "track.flac")
+	write(fresh, "just finished")
+
+	cutoff := time.Now().Add(time.Hour)
+	if err := os.Chtimes(fresh, cutoff.Add(time.Minute), cutoff.Add(time.Minute)); err != nil {
+		t.Fatalf("Chtimes failed: %v", err)
+	}
+
+	result, err := manager.RemoveOrphanedFiles(ctx, cutoff)
+	if err != nil {
+		t.Fatalf("RemoveOrphanedFiles failed: %v", err)
+	}
+	if result.Removed != 1 || result.Bytes != int64(len("no record")) || len(result.Errors) != 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	if _, err := os.Stat(orphanDir); !os.IsNotExist(err) {
+		t.Error("Expected directory holding only sidecars to be removed")
+	}
+	for _, path := range []string{kept.LocalPath, keptPoster, keptNFO, evicted.LocalPath, partial, fresh} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s to be kept: %v", path, err)
+		}
+	}
+}
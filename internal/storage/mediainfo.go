@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// mediaInfoKey is the MediaMetadata.ExtraData key holding the result of
+// inspecting the cached file.
+const mediaInfoKey = "media_info"
+
+// MediaInfo describes a cached file as inspected after download, which
+// can differ from what Jellyfin reports for a transcoded variant.
+type MediaInfo struct {
+	DurationSeconds float64      `json:"duration_seconds,omitempty"`
+	Container       string       `json:"container,omitempty"`
+	Bitrate         int          `json:"bitrate,omitempty"` // bits per second
+	VideoCodec      string       `json:"video_codec,omitempty"`
+	Width           int          `json:"width,omitempty"`
+	Height          int          `json:"height,omitempty"`
+	AudioTracks     []MediaTrack `json:"audio_tracks,omitempty"`
+	SubtitleTracks  []MediaTrack `json:"subtitle_tracks,omitempty"`
+	InspectedAt     time.Time    `json:"inspected_at"`
+}
+
+// MediaTrack is an audio or subtitle track of a cached file.
+type MediaTrack struct {
+	Codec    string `json:"codec"`
+	Language string `json:"language,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Channels int    `json:"channels,omitempty"` // audio only
+	Default  bool   `json:"default,omitempty"`
+}
+
+// Duration returns the length of the file.
+func (i *MediaInfo) Duration() time.Duration {
+	return time.Duration(i.DurationSeconds * float64(time.Second))
+}
+
+// MediaInfo returns what inspecting the cached file found, or nil if it
+// has not been inspected.
+func (m *MediaMetadata) MediaInfo() *MediaInfo {
+	raw, ok := m.ExtraData[mediaInfoKey]
+	if !ok {
+		return nil
+	}
+	// Stored metadata comes back from JSON as a generic map
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var info MediaInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil
+	}
+	return &info
+}
+
+// SetMediaInfo records what inspecting the cached file found.
+func (m *MediaMetadata) SetMediaInfo(info *MediaInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if m.ExtraData == nil {
+		m.ExtraData = make(map[string]interface{})
+	}
+	m.ExtraData[mediaInfoKey] = raw
+	return nil
+}
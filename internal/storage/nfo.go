@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// nfoDateLayout is how Kodi expects dates in .nfo files.
+const nfoDateLayout = "2006-01-02 15:04:05"
+
+// nfoUniqueID is the item's Jellyfin ID, which lets Kodi and Plex match
+// the item back to the server.
+type nfoUniqueID struct {
+	Type    string `xml:"type,attr"`
+	Default bool   `xml:"default,attr"`
+	ID      string `xml:",chardata"`
+}
+
+// nfoMovie is a Kodi movie .nfo.
+type nfoMovie struct {
+	XMLName   xml.Name    `xml:"movie"`
+	Title     string      `xml:"title"`
+	Plot      string      `xml:"plot,omitempty"`
+	Genres    []string    `xml:"genre"`
+	Runtime   int         `xml:"runtime,omitempty"` // Minutes
+	DateAdded string      `xml:"dateadded,omitempty"`
+	UniqueID  nfoUniqueID `xml:"uniqueid"`
+}
+
+// nfoEpisode is a Kodi episode .nfo.
+type nfoEpisode struct {
+	XMLName   xml.Name    `xml:"episodedetails"`
+	Title     string      `xml:"title"`
+	ShowTitle string      `xml:"showtitle,omitempty"`
+	Season    int         `xml:"season"`
+	Episode   int         `xml:"episode"`
+	Plot      string      `xml:"plot,omitempty"`
+	Runtime   int         `xml:"runtime,omitempty"` // Minutes
+	DateAdded string      `xml:"dateadded,omitempty"`
+	UniqueID  nfoUniqueID `xml:"uniqueid"`
+}
+
+// nfoShow is a Kodi tvshow.nfo.
+type nfoShow struct {
+	XMLName  xml.Name    `xml:"tvshow"`
+	Title    string      `xml:"title"`
+	Plot     string      `xml:"plot,omitempty"`
+	Genres   []string    `xml:"genre"`
+	UniqueID nfoUniqueID `xml:"uniqueid"`
+}
+
+// NFOPath returns the .nfo file Kodi reads for the media file at
+// mediaPath: the same name with the extension replaced.
+func NFOPath(mediaPath string) string {
+	return strings.TrimSuffix(mediaPath, filepath.Ext(mediaPath)) + ".nfo"
+}
+
+// ShowNFOPath returns the tvshow.nfo of the series an episode cached at
+// mediaPath belongs to, in the series directory above the episode's.
+func ShowNFOPath(mediaPath string) string {
+	return filepath.Join(filepath.Dir(filepath.Dir(mediaPath)), "tvshow.nfo")
+}
+
+// WriteNFO writes a Kodi-compatible .nfo describing the movie or episode
+// cached at mediaPath, and for episodes the tvshow.nfo of series when it
+// is known. Other media types are not written.
+func (f *FileManager) WriteNFO(mediaPath string, metadata, series *MediaMetadata) error {
+	id := nfoUniqueID{Type: "jellyfin", Default: true, ID: metadata.JellyfinID}
+	runtime := int(time.Duration(metadata.RunTimeTicks*100).Round(time.Minute) / time.Minute)
+	var dateAdded string
+	if !metadata.DateCreated.IsZero() {
+		dateAdded = metadata.DateCreated.UTC().Format(nfoDateLayout)
+	}
+
+	var doc interface{}
+	switch metadata.Type {
+	case "movie":
+		doc = nfoMovie{
+			Title:     metadata.Name,
+			Plot:      metadata.Overview,
+			Genres:    metadata.Genres,
+			Runtime:   runtime,
+			DateAdded: dateAdded,
+			UniqueID:  id,
+		}
+	case "episode":
+		episode := nfoEpisode{
+			Title:     metadata.Name,
+			Season:    metadata.SeasonNumber,
+			Episode:   metadata.EpisodeNumber,
+			Plot:      metadata.Overview,
+			Runtime:   runtime,
+			DateAdded: dateAdded,
+			UniqueID:  id,
+		}
+		if series != nil {
+			episode.ShowTitle = series.Name
+			show := nfoShow{
+				Title:    series.Name,
+				Plot:     series.Overview,
+				Genres:   series.Genres,
+				UniqueID: nfoUniqueID{Type: "jellyfin", Default: true, ID: series.JellyfinID},
+			}
+			if err := f.writeXML(ShowNFOPath(mediaPath), show); err != nil {
+				return err
+			}
+		}
+		doc = episode
+	default:
+		return nil
+	}
+
+	return f.writeXML(NFOPath(mediaPath), doc)
+}
+
+// writeXML writes doc as an XML document to path.
+func (f *FileManager) writeXML(path string, doc interface{}) error {
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", filepath.Base(path), err)
+	}
+	data = append([]byte(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+"\n"), data...)
+	return f.WriteFileAtomic(path, append(data, '\n'))
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// PartialSuffix is appended to a destination path to name the file a
+// download streams into until it completes.
+const PartialSuffix = ".partial"
+
+// PartialFile streams a download into a temporary file next to its
+// destination, so completing it is a rename on the same filesystem rather
+// than a copy. The bytes written so far can be read back while the
+// download is still in progress.
+type PartialFile struct {
+	file    *os.File
+	path    string
+	dest    string
+	written atomic.Int64
+}
+
+// PartialPath returns the temporary path a download to dest streams into.
+func PartialPath(dest string) string {
+	return dest + PartialSuffix
+}
+
+// CreatePartial opens the temporary file for dest. With resume set, writes
+// are appended to whatever an interrupted download left behind; otherwise
+// the file is truncated.
+func CreatePartial(dest string, resume bool) (*PartialFile, error) {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	path := PartialPath(dest)
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open partial file: %w", err)
+	}
+
+	p := &PartialFile{file: file, path: path, dest: dest}
+	if resume {
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to stat partial file: %w", err)
+		}
+		p.written.Store(info.Size())
+	}
+
+	return p, nil
+}
+
+// Write appends to the temporary file.
+func (p *PartialFile) Write(b []byte) (int, error) {
+	n, err := p.file.Write(b)
+	p.written.Add(int64(n))
+	return n, err
+}
+
+// WriteAt writes b at off without counting it towards Written, for
+// segments of a download fetched out of order. It cannot be used on a
+// resumed file.
+func (p *PartialFile) WriteAt(b []byte, off int64) (int, error) {
+	return p.file.WriteAt(b, off)
+}
+
+// Filled records that the first n bytes are on disk, once the segments
+// written with WriteAt have joined up.
+func (p *PartialFile) Filled(n int64) {
+	p.written.Store(n)
+}
+
+// Trim cuts the temporary file back to Written, dropping segments written
+// past a gap so a resumed download does not keep the hole.
+func (p *PartialFile) Trim() error {
+	if err := p.file.Truncate(p.written.Load()); err != nil {
+		return fmt.Errorf("failed to trim partial file: %w", err)
+	}
+	return nil
+}
+
+// Path returns the temporary file's path.
+func (p *PartialFile) Path() string {
+	return p.path
+}
+
+// Written returns how many bytes of the temporary file are safe to read,
+// including any resumed from an earlier attempt.
+func (p *PartialFile) Written() int64 {
+	return p.written.Load()
+}
+
+// Commit flushes the temporary file to disk and renames it over the
+// destination. The directory is synced too, so the rename survives a crash.
+func (p *PartialFile) Commit() error {
+	if err := p.file.Sync(); err != nil {
+		p.file.Close()
+		return fmt.Errorf("failed to sync partial file: %w", err)
+	}
+	if err := p.file.Close(); err != nil {
+		return fmt.Errorf("failed to close partial file: %w", err)
+	}
+	if err := os.Rename(p.path, p.dest); err != nil {
+		return fmt.Errorf("failed to move completed file: %w", err)
+	}
+
+	return syncDir(filepath.Dir(p.dest))
+}
+
+// CommitEncrypted is Commit for an encrypted cache: the destination is
+// written as an encrypted copy of the temporary file, which is removed
+// once the copy is in place.
+func (p *PartialFile) CommitEncrypted(c *Cipher) error {
+	if err := p.file.Close(); err != nil {
+		return fmt.Errorf("failed to close partial file: %w", err)
+	}
+	if err := c.EncryptFile(p.path, p.dest); err != nil {
+		return err
+	}
+	if err := os.Remove(p.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove partial file: %w", err)
+	}
+	return nil
+}
+
+// Suspend flushes the temporary file to disk and closes it without
+// committing it, so the download can resume after a restart.
+func (p *PartialFile) Suspend() error {
+	if err := p.file.Sync(); err != nil {
+		p.file.Close()
+		return fmt.Errorf("failed to sync partial file: %w", err)
+	}
+	return p.file.Close()
+}
+
+// Close closes the temporary file without committing it, leaving it in
+// place so the download can resume later.
+func (p *PartialFile) Close() error {
+	return p.file.Close()
+}
+
+// Discard closes and removes the temporary file.
+func (p *PartialFile) Discard() error {
+	p.file.Close()
+	if err := os.Remove(p.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove partial file: %w", err)
+	}
+	return nil
+}
+
+// syncDir flushes a directory's entries to disk. Not every platform
+// supports syncing directories, so failures to do so are ignored.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open directory: %w", err)
+	}
+	defer d.Close()
+
+	d.Sync()
+	return nil
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// Pin marks an item the user never wants evicted, such as a favorite movie
+// or a show the kids watch on repeat. Pinning a series or album also
+// protects every episode or track cached from it.
+// Key pattern: {media-id} in the pins bucket
+type Pin struct {
+	MediaID  string    `json:"media_id"`
+	PinnedAt time.Time `json:"pinned_at"`
+}
+
+// PinMedia protects mediaID from eviction. Pinning an item that is already
+// pinned keeps its original pin time.
+func (m *Manager) PinMedia(mediaID string) error {
+	if mediaID == "" {
+		return fmt.Errorf("pin must have a media ID")
+	}
+
+	return m.update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketPins)
+		if bucket.Get([]byte(mediaID)) != nil {
+			return nil
+		}
+
+		data, err := json.Marshal(&Pin{MediaID: mediaID, PinnedAt: time.Now()})
+		if err != nil {
+			return fmt.Errorf("failed to marshal pin: %w", err)
+		}
+		return bucket.Put([]byte(mediaID), data)
+	})
+}
+
+// UnpinMedia lets mediaID be evicted again. It is a no-op if the item is
+// not pinned.
+func (m *Manager) UnpinMedia(mediaID string) error {
+	return m.update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketPins).Delete([]byte(mediaID))
+	})
+}
+
+// IsPinned reports whether mediaID is protected from eviction, either by
+// its own pin or by one on its series or album.
+func (m *Manager) IsPinned(mediaID string) (bool, error) {
+	var pinned bool
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		pinned = pinnedIn(tx.Bucket(bucketPins), tx.Bucket(bucketMetadata), mediaID)
+		return nil
+	})
+
+	return pinned, err
+}
+
+// ListPins returns every pin, most recently pinned first.
+func (m *Manager) ListPins() ([]*Pin, error) {
+	var pins []*Pin
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketPins).ForEach(func(k, v []byte) error {
+			var pin Pin
+			if err := json.Unmarshal(v, &pin); err != nil {
+				return nil // Continue on marshal errors
+			}
+			pins = append(pins, &pin)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pins: %w", err)
+	}
+
+	sort.Slice(pins, func(i, j int) bool {
+		return pins[i].PinnedAt.After(pins[j].PinnedAt)
+	})
+	return pins, nil
+}
+
+// pinnedIn reports whether mediaID, or the series or album it belongs to,
+// has a pin. Either bucket may be nil.
+func pinnedIn(pins, metadata *bbolt.Bucket, mediaID string) bool {
+	if pins == nil {
+		return false
+	}
+	if first, _ := pins.Cursor().First(); first == nil {
+		return false // Nothing pinned, skip the metadata lookup
+	}
+	if pins.Get([]byte(mediaID)) != nil {
+		return true
+	}
+	if metadata == nil {
+		return false
+	}
+
+	data := metadata.Get([]byte("meta:" + mediaID))
+	if data == nil {
+		return false
+	}
+	var meta MediaMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return false
+	}
+	return (meta.SeriesID != "" && pins.Get([]byte(meta.SeriesID)) != nil) ||
+		(meta.AlbumID != "" && pins.Get([]byte(meta.AlbumID)) != nil)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package storage
+
+import "os"
+
+// reflink is not implemented on this platform.
+func reflink(dst, src *os.File) error {
+	return ErrReflinkUnsupported
+}
@@ -0,0 +1,301 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go.etcd.io/bbolt"
+)
+
+// Weights of the fields a search term can come from; an item matching a
+// term in its name ranks above one matching it in its overview.
+const (
+	searchWeightOverview byte = 1
+	searchWeightGenre    byte = 2
+	searchWeightName     byte = 3
+)
+
+// searchStopWords are too common in overviews to be worth indexing.
+var searchStopWords = map[string]bool{
+	"an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "has": true, "he": true, "her": true,
+	"his": true, "in": true, "is": true, "it": true, "its": true, "of": true,
+	"on": true, "or": true, "she": true, "that": true, "the": true, "their": true,
+	"they": true, "this": true, "to": true, "was": true, "who": true, "with": true,
+}
+
+// searchTerms splits text into lowercase search terms of at least two
+// letters or digits, leaving out stop words.
+func searchTerms(text string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) >= 2 && !searchStopWords[word] {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// searchIndexTerms returns the terms metadata is indexed under, each with
+// the weight of the most important field it appears in.
+func searchIndexTerms(metadata *MediaMetadata) map[string]byte {
+	terms := make(map[string]byte)
+	add := func(text string, weight byte) {
+		for _, term := range searchTerms(text) {
+			if weight > terms[term] {
+				terms[term] = weight
+			}
+		}
+	}
+	add(metadata.Name, searchWeightName)
+	for _, genre := range metadata.Genres {
+		add(genre, searchWeightGenre)
+	}
+	add(metadata.Overview, searchWeightOverview)
+	return terms
+}
+
+// searchIndexKey is the search index key of a term found in an item:
+// {term}\x00{jellyfin-id}.
+func searchIndexKey(term, jellyfinID string) []byte {
+	return []byte(term + "\x00" + jellyfinID)
+}
+
+// indexSearchTerms replaces the search index entries of an item's previous
+// metadata, if any, with those of its current metadata.
+func indexSearchTerms(tx *bbolt.Tx, previous, metadata *MediaMetadata) error {
+	index := tx.Bucket(bucketSearchIndex)
+	current := searchIndexTerms(metadata)
+	if previous != nil {
+		for term := range searchIndexTerms(previous) {
+			if _, ok := current[term]; ok {
+				continue
+			}
+			if err := index.Delete(searchIndexKey(term, previous.JellyfinID)); err != nil {
+				return err
+			}
+		}
+	}
+	for term, weight := range current {
+		if err := index.Put(searchIndexKey(term, metadata.JellyfinID), []byte{weight}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rebuildSearchIndex recreates the search index from the metadata bucket.
+func rebuildSearchIndex(tx *bbolt.Tx) error {
+	if err := tx.DeleteBucket(bucketSearchIndex); err != nil && err != bbolt.ErrBucketNotFound {
+		return err
+	}
+	if _, err := tx.CreateBucket(bucketSearchIndex); err != nil {
+		return err
+	}
+
+	cursor := tx.Bucket(bucketMetadata).Cursor()
+	prefix := []byte("meta:")
+	for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+		var metadata MediaMetadata
+		if err := json.Unmarshal(v, &metadata); err != nil || metadata.JellyfinID == "" {
+			continue
+		}
+		if err := indexSearchTerms(tx, nil, &metadata); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SearchCachedItems returns up to limit cached items whose name, genres or
+// overview contain every word of query, best matches first. Words match
+// as prefixes, so results can follow a query as it is typed. Matching a
+// series finds its cached episodes.
+func (m *Manager) SearchCachedItems(query string, limit int) ([]*CachedItem, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	var items []*CachedItem
+	err := m.view(func(tx *bbolt.Tx) error {
+		// Score items by the best field each term matched, dropping those
+		// that miss a term. Episodes match the terms of their series too,
+		// so "bluey camping" finds the episode Camping of Bluey.
+		var scores map[string]int
+		for i, term := range terms {
+			matched := searchIndexMatches(tx, term)
+			for _, id := range matchIDs(matched) {
+				weight := matched[id]
+				forEachSeriesItem(tx, id, func(episode *MediaMetadata) {
+					matched[episode.JellyfinID] = max(matched[episode.JellyfinID], weight)
+				})
+			}
+
+			if i == 0 {
+				scores = make(map[string]int, len(matched))
+				for id, weight := range matched {
+					scores[id] = int(weight)
+				}
+				continue
+			}
+			for id := range scores {
+				if weight, ok := matched[id]; ok {
+					scores[id] += int(weight)
+				} else {
+					delete(scores, id)
+				}
+			}
+		}
+		if len(scores) == 0 {
+			return nil
+		}
+
+		metaBucket := tx.Bucket(bucketMetadata)
+		pinBucket := tx.Bucket(bucketPins)
+		ranked := make(map[*CachedItem]int)
+		if err := tx.Bucket(bucketDownloads).ForEach(func(k, v []byte) error {
+			var record DownloadRecord
+			if err := json.Unmarshal(v, &record); err != nil || !listedInLibrary(&record) {
+				return nil
+			}
+			score, ok := scores[record.JellyfinID]
+			if !ok {
+				return nil
+			}
+
+			item := cachedItemFromRecord(&record)
+			item.Pinned = pinnedIn(pinBucket, metaBucket, record.JellyfinID)
+			if data := metaBucket.Get([]byte("meta:" + record.JellyfinID)); data != nil {
+				var metadata MediaMetadata
+				if err := json.Unmarshal(data, &metadata); err == nil {
+					ApplyCachedItemMetadata(item, &metadata)
+				}
+			}
+			ranked[item] = score
+			items = append(items, item)
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		sort.Slice(items, func(i, j int) bool {
+			if ranked[items[i]] != ranked[items[j]] {
+				return ranked[items[i]] > ranked[items[j]]
+			}
+			if a, b := strings.ToLower(items[i].Name), strings.ToLower(items[j].Name); a != b {
+				return a < b
+			}
+			return items[i].ID < items[j].ID
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
+
+// SearchMetadata returns up to limit items of the synced library, cached or
+// not, whose name, genres or overview contain every word of query, best
+// matches first. A non-empty mediaType keeps only items of that type.
+func (m *Manager) SearchMetadata(query, mediaType string, limit int) ([]*MediaMetadata, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	var results []*MediaMetadata
+	err := m.view(func(tx *bbolt.Tx) error {
+		var scores map[string]int
+		for i, term := range terms {
+			matched := searchIndexMatches(tx, term)
+			if i == 0 {
+				scores = make(map[string]int, len(matched))
+				for id, weight := range matched {
+					scores[id] = int(weight)
+				}
+				continue
+			}
+			for id := range scores {
+				if weight, ok := matched[id]; ok {
+					scores[id] += int(weight)
+				} else {
+					delete(scores, id)
+				}
+			}
+		}
+
+		metaBucket := tx.Bucket(bucketMetadata)
+		for id := range scores {
+			data := metaBucket.Get([]byte("meta:" + id))
+			if data == nil {
+				continue
+			}
+			var metadata MediaMetadata
+			if err := json.Unmarshal(data, &metadata); err != nil {
+				continue
+			}
+			if mediaType == "" || metadata.Type == mediaType {
+				results = append(results, &metadata)
+			}
+		}
+
+		sort.Slice(results, func(i, j int) bool {
+			a, b := results[i], results[j]
+			if scores[a.JellyfinID] != scores[b.JellyfinID] {
+				return scores[a.JellyfinID] > scores[b.JellyfinID]
+			}
+			if an, bn := strings.ToLower(a.Name), strings.ToLower(b.Name); an != bn {
+				return an < bn
+			}
+			return a.JellyfinID < b.JellyfinID
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// searchIndexMatches returns the items with an indexed term starting with
+// prefix, each with the weight of its best matching term.
+func searchIndexMatches(tx *bbolt.Tx, prefix string) map[string]byte {
+	matches := make(map[string]byte)
+	cursor := tx.Bucket(bucketSearchIndex).Cursor()
+	for k, v := cursor.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, v = cursor.Next() {
+		sep := bytes.IndexByte(k, 0)
+		if sep < 0 || len(v) == 0 {
+			continue
+		}
+		id := string(k[sep+1:])
+		if v[0] > matches[id] {
+			matches[id] = v[0]
+		}
+	}
+	return matches
+}
+
+// matchIDs returns the IDs of matches, so they can be iterated while
+// matches grows.
+func matchIDs(matches map[string]byte) []string {
+	ids := make([]string, 0, len(matches))
+	for id := range matches {
+		ids = append(ids, id)
+	}
+	return ids
+}
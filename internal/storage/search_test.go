@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSearchCachedItems(t *testing.T) {
+	manager := createTestManager(t, t.TempDir())
+	defer manager.Close()
+
+	for _, metadata := range []*MediaMetadata{
+		{ID: "heat", JellyfinID: "heat", Name: "Heat", Type: "movie", Genres: []string{"Crime"}, Overview: "A detective hunts a crew of thieves."},
+		{ID: "dune", JellyfinID: "dune", Name: "Dune", Type: "movie", Genres: []string{"Science Fiction"}, Overview: "A noble family's heir faces a desert crime syndicate."},
+		{ID: "bluey", JellyfinID: "bluey", Name: "Bluey", Type: "series"},
+		{ID: "camping", JellyfinID: "camping", Name: "Camping", Type: "episode", SeriesID: "bluey"},
+		{ID: "evicted", JellyfinID: "evicted", Name: "Crime Story", Type: "movie"},
+		{ID: "uncached", JellyfinID: "uncached", Name: "Crimewave", Type: "movie"},
+	} {
+		if err := manager.AddMediaMetadata(metadata); err != nil {
+			t.Fatalf("Failed to add metadata: %v", err)
+		}
+	}
+	for _, id := range []string{"heat", "dune", "camping", "evicted"} {
+		status := "completed"
+		if id == "evicted" {
+			status = "evicted"
+		}
+		if err := manager.AddDownloadRecord(&DownloadRecord{ID: id, JellyfinID: id, MediaType: "movie", Title: id, Status: status, DownloadedAt: time.Now()}); err != nil {
+			t.Fatalf("Failed to add download record: %v", err)
+		}
+	}
+
+	search := func(query string) string {
+		t.Helper()
+		items, err := manager.SearchCachedItems(query, 10)
+		if err != nil {
+			t.Fatalf("SearchCachedItems(%q) failed: %v", query, err)
+		}
+		ids := make([]string, len(items))
+		for i, item := range items {
+			ids[i] = item.ID
+		}
+		return fmt.Sprint(ids)
+	}
+
+	// A genre match ranks above an overview match; evicted and uncached
+	// items are left out
+	if got := search("crime"); got != "[heat dune]" {
+		t.Errorf("Expected heat then dune for crime, got %s", got)
+	}
+	if got := search("Sci fi"); got != "[dune]" {
+		t.Errorf("Expected prefixes to match dune, got %s", got)
+	}
+	if got := search("bluey camp"); got != "[camping]" {
+		t.Errorf("Expected the episode to match its series name, got %s", got)
+	}
+	if got := search("the"); got != "[]" {
+		t.Errorf("Expected stop words to match nothing, got %s", got)
+	}
+
+	// Updated metadata replaces the old index entries
+	if err := manager.AddMediaMetadata(&MediaMetadata{ID: "heat", JellyfinID: "heat", Name: "Heat (1995)", Type: "movie", Genres: []string{"Thriller"}}); err != nil {
+		t.Fatalf("Failed to update metadata: %v", err)
+	}
+	if got := search("crime"); got != "[dune]" {
+		t.Errorf("Expected heat to no longer match crime, got %s", got)
+	}
+	if got := search("1995 thrill"); got != "[heat]" {
+		t.Errorf("Expected heat to match its new metadata, got %s", got)
+	}
+}
+
+func TestSearchMetadata(t *testing.T) {
+	manager := createTestManager(t, t.TempDir())
+	defer manager.Close()
+
+	for _, metadata := range []*MediaMetadata{
+		{ID: "heat", JellyfinID: "heat", Name: "Heat", Type: "movie"},
+		{ID: "heatwave", JellyfinID: "heatwave", Name: "Heatwave", Type: "series"},
+		{ID: "dune", JellyfinID: "dune", Name: "Dune", Type: "movie", Overview: "Heat and sand."},
+	} {
+		if err := manager.AddMediaMetadata(metadata); err != nil {
+			t.Fatalf("Failed to add metadata: %v", err)
+		}
+	}
+
+	search := func(query, mediaType string) string {
+		t.Helper()
+		results, err := manager.SearchMetadata(query, mediaType, 10)
+		if err != nil {
+			t.Fatalf("SearchMetadata(%q) failed: %v", query, err)
+		}
+		ids := make([]string, len(results))
+		for i, metadata := range results {
+			ids[i] = metadata.JellyfinID
+		}
+		return fmt.Sprint(ids)
+	}
+
+	// Uncached items are found, name matches first
+	if got := search("heat", ""); got != "[heat heatwave dune]" {
+		t.Errorf("Expected heat, heatwave then dune, got %s", got)
+	}
+	if got := search("heat", "movie"); got != "[heat dune]" {
+		t.Errorf("Expected only movies, got %s", got)
+	}
+	if got := search("heat sand", ""); got != "[dune]" {
+		t.Errorf("Expected every word to have to match, got %s", got)
+	}
+}
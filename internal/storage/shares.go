@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// shareSecretKey is the config bucket key holding the share link signing key.
+var shareSecretKey = []byte("share_secret")
+
+// Share is a time-limited public link to a single cached item.
+// Key pattern: {share-id} in the shares bucket
+type Share struct {
+	ID           string    `json:"id"`
+	MediaID      string    `json:"media_id"`
+	Title        string    `json:"title"`
+	PasswordHash string    `json:"password_hash,omitempty"` // Hex scrypt key of password and salt
+	PasswordSalt string    `json:"password_salt,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// HasPassword reports whether the share requires a password.
+func (s *Share) HasPassword() bool {
+	return s.PasswordHash != ""
+}
+
+// Expired reports whether the share is no longer valid at t.
+func (s *Share) Expired(t time.Time) bool {
+	return !t.Before(s.ExpiresAt)
+}
+
+// AddShare stores a share link.
+func (m *Manager) AddShare(share *Share) error {
+	if share.ID == "" || share.MediaID == "" {
+		return fmt.Errorf("share must have ID and MediaID")
+	}
+
+	data, err := json.Marshal(share)
+	if err != nil {
+		return fmt.Errorf("failed to marshal share: %w", err)
+	}
+
+	return m.update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketShares).Put([]byte(share.ID), data)
+	})
+}
+
+// GetShare returns a share by ID, or nil if it does not exist.
+func (m *Manager) GetShare(id string) (*Share, error) {
+	var share *Share
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(bucketShares).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		share = &Share{}
+		return json.Unmarshal(data, share)
+	})
+
+	return share, err
+}
+
+// ListShares returns unexpired shares, soonest to expire first.
+func (m *Manager) ListShares() ([]*Share, error) {
+	var shares []*Share
+	now := time.Now()
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketShares).ForEach(func(k, v []byte) error {
+			var share Share
+			if err := json.Unmarshal(v, &share); err != nil {
+				return nil // Continue on marshal errors
+			}
+			if !share.Expired(now) {
+				shares = append(shares, &share)
+			}
+			return nil
+		})
+	})
+
+	sort.Slice(shares, func(i, j int) bool {
+		return shares[i].ExpiresAt.Before(shares[j].ExpiresAt)
+	})
+	return shares, err
+}
+
+// RemoveShare deletes a share, revoking its link.
+func (m *Manager) RemoveShare(id string) error {
+	return m.update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketShares).Delete([]byte(id))
+	})
+}
+
+// PruneExpiredShares deletes shares that expired before now and returns how
+// many were removed.
+func (m *Manager) PruneExpiredShares(now time.Time) (int, error) {
+	var pruned int
+
+	err := m.update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketShares)
+		var expired [][]byte
+
+		bucket.ForEach(func(k, v []byte) error {
+			var share Share
+			if err := json.Unmarshal(v, &share); err != nil || share.Expired(now) {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		pruned = len(expired)
+		return nil
+	})
+
+	return pruned, err
+}
+
+// ShareSecret returns the key used to sign share links, generating and
+// persisting a random one on first use so links survive restarts.
+func (m *Manager) ShareSecret() ([]byte, error) {
+	var secret []byte
+
+	// Fast path avoids a write transaction once the secret exists
+	m.view(func(tx *bbolt.Tx) error {
+		if existing := tx.Bucket(bucketConfig).Get(shareSecretKey); existing != nil {
+			secret = append([]byte(nil), existing...)
+		}
+		return nil
+	})
+	if secret != nil {
+		return secret, nil
+	}
+
+	err := m.update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketConfig)
+		if existing := bucket.Get(shareSecretKey); existing != nil {
+			secret = append([]byte(nil), existing...)
+			return nil
+		}
+
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return fmt.Errorf("failed to generate share secret: %w", err)
+		}
+		return bucket.Put(shareSecretKey, secret)
+	})
+
+	return secret, err
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+)
+
+// statePrefix namespaces component state within the config bucket.
+const statePrefix = "state:"
+
+// SaveState persists v as JSON under key, replacing whatever was saved
+// before. Components use it to carry in-memory state across restarts.
+func (m *Manager) SaveState(key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state %s: %w", key, err)
+	}
+
+	return m.update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketConfig).Put([]byte(statePrefix+key), data)
+	})
+}
+
+// LoadState decodes the state saved under key into v. It reports false,
+// leaving v untouched, when nothing has been saved.
+func (m *Manager) LoadState(key string, v interface{}) (bool, error) {
+	var data []byte
+	err := m.view(func(tx *bbolt.Tx) error {
+		if stored := tx.Bucket(bucketConfig).Get([]byte(statePrefix + key)); stored != nil {
+			data = append([]byte(nil), stored...)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to load state %s: %w", key, err)
+	}
+	if data == nil {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to unmarshal state %s: %w", key, err)
+	}
+	return true, nil
+}
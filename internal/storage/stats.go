@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// maxStoredReports bounds the number of generated reports kept (26 weeks).
+const maxStoredReports = 26
+
+// DailyStats aggregates cache activity for one calendar day in local time.
+// Key pattern: daily:{YYYY-MM-DD} in the stats bucket
+type DailyStats struct {
+	Date            string `json:"date"`
+	ItemsCached     int    `json:"items_cached"`
+	BytesDownloaded int64  `json:"bytes_downloaded"`
+	CacheHits       int    `json:"cache_hits"`
+	CacheMisses     int    `json:"cache_misses"`
+	ItemsEvicted    int    `json:"items_evicted"`
+	BytesEvicted    int64  `json:"bytes_evicted"`
+}
+
+// RecordDownloadCompleted counts a finished download in today's stats.
+func (m *Manager) RecordDownloadCompleted(bytes int64) error {
+	return m.updateDailyStats(time.Now(), func(s *DailyStats) {
+		s.ItemsCached++
+		s.BytesDownloaded += bytes
+	})
+}
+
+// RecordStreamRequest counts a playback request served from the cache (hit)
+// or proxied from Jellyfin (miss) in today's stats.
+func (m *Manager) RecordStreamRequest(hit bool) error {
+	return m.updateDailyStats(time.Now(), func(s *DailyStats) {
+		if hit {
+			s.CacheHits++
+		} else {
+			s.CacheMisses++
+		}
+	})
+}
+
+// RecordEviction counts an evicted item in today's stats.
+func (m *Manager) RecordEviction(bytes int64) error {
+	return m.updateDailyStats(time.Now(), func(s *DailyStats) {
+		s.ItemsEvicted++
+		s.BytesEvicted += bytes
+	})
+}
+
+// GetDailyStats returns the daily stats for each day in [from, to] that has
+// recorded activity, oldest first.
+func (m *Manager) GetDailyStats(from, to time.Time) ([]*DailyStats, error) {
+	var stats []*DailyStats
+
+	minKey := []byte(dailyStatsKey(from))
+	maxKey := []byte(dailyStatsKey(to))
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketStats)
+		if bucket == nil {
+			return nil
+		}
+
+		c := bucket.Cursor()
+		for k, v := c.Seek(minKey); k != nil && string(k) <= string(maxKey); k, v = c.Next() {
+			var day DailyStats
+			if err := json.Unmarshal(v, &day); err != nil {
+				m.logger.Warn("Failed to unmarshal daily stats", "key", string(k), "error", err)
+				continue
+			}
+			stats = append(stats, &day)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// updateDailyStats applies fn to the stats record for the day containing at.
+func (m *Manager) updateDailyStats(at time.Time, fn func(*DailyStats)) error {
+	return m.update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(bucketStats)
+		if err != nil {
+			return fmt.Errorf("failed to create stats bucket: %w", err)
+		}
+
+		key := []byte(dailyStatsKey(at))
+		day := DailyStats{Date: at.Format("2006-01-02")}
+		if data := bucket.Get(key); data != nil {
+			if err := json.Unmarshal(data, &day); err != nil {
+				m.logger.Warn("Failed to unmarshal daily stats", "key", string(key), "error", err)
+			}
+		}
+
+		fn(&day)
+
+		data, err := json.Marshal(&day)
+		if err != nil {
+			return fmt.Errorf("failed to marshal daily stats: %w", err)
+		}
+		return bucket.Put(key, data)
+	})
+}
+
+// dailyStatsKey returns the stats bucket key for the day containing t.
+func dailyStatsKey(t time.Time) string {
+	return "daily:" + t.Format("2006-01-02")
+}
+
+// BandwidthUsage is the number of bytes downloaded during one clock hour in
+// local time, counting every attempt including failed and resumed ones.
+// Key pattern: bandwidth:{YYYY-MM-DDTHH} in the stats bucket
+type BandwidthUsage struct {
+	Hour  time.Time `json:"hour"`
+	Bytes int64     `json:"bytes"`
+}
+
+// RecordBandwidth adds bytes to the usage of the hour containing at.
+func (m *Manager) RecordBandwidth(at time.Time, bytes int64) error {
+	hour := time.Date(at.Year(), at.Month(), at.Day(), at.Hour(), 0, 0, 0, at.Location())
+	key := []byte(bandwidthKey(hour))
+
+	return m.update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(bucketStats)
+		if err != nil {
+			return fmt.Errorf("failed to create stats bucket: %w", err)
+		}
+
+		usage := BandwidthUsage{Hour: hour}
+		if data := bucket.Get(key); data != nil {
+			if err := json.Unmarshal(data, &usage); err != nil {
+				m.logger.Warn("Failed to unmarshal bandwidth usage", "key", string(key), "error", err)
+			}
+		}
+		usage.Bytes += bytes
+
+		data, err := json.Marshal(&usage)
+		if err != nil {
+			return fmt.Errorf("failed to marshal bandwidth usage: %w", err)
+		}
+		return bucket.Put(key, data)
+	})
+}
+
+// GetBandwidthUsage returns the usage of each hour in [from, to] that has
+// any, oldest first.
+func (m *Manager) GetBandwidthUsage(from, to time.Time) ([]*BandwidthUsage, error) {
+	var usage []*BandwidthUsage
+
+	minKey := []byte(bandwidthKey(from))
+	maxKey := []byte(bandwidthKey(to))
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketStats)
+		if bucket == nil {
+			return nil
+		}
+
+		c := bucket.Cursor()
+		for k, v := c.Seek(minKey); k != nil && string(k) <= string(maxKey); k, v = c.Next() {
+			var hour BandwidthUsage
+			if err := json.Unmarshal(v, &hour); err != nil {
+				m.logger.Warn("Failed to unmarshal bandwidth usage", "key", string(k), "error", err)
+				continue
+			}
+			usage = append(usage, &hour)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bandwidth usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// bandwidthKey returns the stats bucket key for the hour containing t.
+func bandwidthKey(t time.Time) string {
+	return "bandwidth:" + t.Format("2006-01-02T15")
+}
+
+// SaveReport stores a generated report, keeping only the most recent
+// maxStoredReports. IDs must sort chronologically.
+// Key pattern: {report-id} in the reports bucket
+func (m *Manager) SaveReport(id string, data []byte) error {
+	return m.update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(bucketReports)
+		if err != nil {
+			return fmt.Errorf("failed to create reports bucket: %w", err)
+		}
+
+		if err := bucket.Put([]byte(id), data); err != nil {
+			return fmt.Errorf("failed to store report: %w", err)
+		}
+
+		// Prune the oldest reports beyond the retention limit
+		// Stats() does not reflect writes made in this transaction
+		excess := -maxStoredReports
+		c := bucket.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			excess++
+		}
+		for k, _ := c.First(); k != nil && excess > 0; k, _ = c.First() {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+			excess--
+		}
+		return nil
+	})
+}
+
+// GetReport returns the stored report data for id, or nil if not found.
+func (m *Manager) GetReport(id string) ([]byte, error) {
+	var data []byte
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketReports)
+		if bucket == nil {
+			return nil
+		}
+		if v := bucket.Get([]byte(id)); v != nil {
+			data = append([]byte(nil), v...)
+		}
+		return nil
+	})
+
+	return data, err
+}
+
+// ListReports returns up to limit stored reports, newest first.
+func (m *Manager) ListReports(limit int) ([][]byte, error) {
+	var reports [][]byte
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketReports)
+		if bucket == nil {
+			return nil
+		}
+
+		c := bucket.Cursor()
+		for k, v := c.Last(); k != nil && len(reports) < limit; k, v = c.Prev() {
+			reports = append(reports, append([]byte(nil), v...))
+		}
+		return nil
+	})
+
+	return reports, err
+}
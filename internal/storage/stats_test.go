@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func newStatsTestManager(t *testing.T) *Manager {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	manager, err := NewManager(&config.CacheConfig{Directory: t.TempDir(), MaxSizeGB: 1, MetadataStore: "boltdb"}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	t.Cleanup(func() { manager.Close() })
+	return manager
+}
+
+func TestDailyStats(t *testing.T) {
+	manager := newStatsTestManager(t)
+
+	if err := manager.RecordDownloadCompleted(1000); err != nil {
+		t.Fatalf("RecordDownloadCompleted failed: %v", err)
+	}
+	manager.RecordDownloadCompleted(500)
+	manager.RecordStreamRequest(true)
+	manager.RecordStreamRequest(true)
+	manager.RecordStreamRequest(false)
+	manager.RecordEviction(300)
+
+	// An older day outside the queried range
+	manager.updateDailyStats(time.Now().AddDate(0, 0, -30), func(s *DailyStats) { s.ItemsCached = 99 })
+
+	stats, err := manager.GetDailyStats(time.Now().AddDate(0, 0, -7), time.Now())
+	if err != nil {
+		t.Fatalf("GetDailyStats failed: %v", err)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("Expected 1 day of stats, got %d", len(stats))
+	}
+
+	day := stats[0]
+	if day.ItemsCached != 2 || day.BytesDownloaded != 1500 {
+		t.Errorf("Unexpected download stats: %+v", day)
+	}
+	if day.CacheHits != 2 || day.CacheMisses != 1 {
+		t.Errorf("Unexpected stream stats: %+v", day)
+	}
+	if day.ItemsEvicted != 1 || day.BytesEvicted != 300 {
+		t.Errorf("Unexpected eviction stats: %+v", day)
+	}
+}
+
+func TestSaveReportRetention(t *testing.T) {
+	manager := newStatsTestManager(t)
+
+	for i := 0; i < maxStoredReports+3; i++ {
+		id := fmt.Sprintf("2026%04d", i)
+		if err := manager.SaveReport(id, []byte(fmt.Sprintf(`{"id":%q}`, id))); err != nil {
+			t.Fatalf("SaveReport failed: %v", err)
+		}
+	}
+
+	all, err := manager.ListReports(100)
+	if err != nil {
+		t.Fatalf("ListReports failed: %v", err)
+	}
+	if len(all) != maxStoredReports {
+		t.Errorf("Expected %d retained reports, got %d", maxStoredReports, len(all))
+	}
+
+	newest := fmt.Sprintf(`{"id":"2026%04d"}`, maxStoredReports+2)
+	if string(all[0]) != newest {
+		t.Errorf("Expected newest report first, got %s", all[0])
+	}
+
+	if data, _ := manager.GetReport("20260000"); data != nil {
+		t.Error("Expected oldest report to be pruned")
+	}
+}
+
+func TestDeviceUsage(t *testing.T) {
+	manager := newStatsTestManager(t)
+
+	morning := time.Date(2024, 3, 1, 7, 15, 0, 0, time.Local)
+	evening := time.Date(2024, 3, 1, 20, 45, 0, 0, time.Local)
+	manager.RecordDevicePlayback("phone", morning)
+	manager.RecordDevicePlayback("phone", morning.Add(24*time.Hour))
+	manager.RecordDevicePlayback("tv", evening)
+	manager.RecordStreamRequest(true)
+
+	if err := manager.RecordDevicePlayback("", morning); err == nil {
+		t.Error("Expected error for empty device class")
+	}
+
+	usage, err := manager.GetDeviceUsage()
+	if err != nil {
+		t.Fatalf("GetDeviceUsage failed: %v", err)
+	}
+	if len(usage) != 2 {
+		t.Fatalf("Expected 2 device classes, got %d", len(usage))
+	}
+	if usage[0].Class != "phone" || usage[0].Plays != 2 || usage[0].Hours[7] != 2 {
+		t.Errorf("Unexpected phone usage: %+v", usage[0])
+	}
+	if usage[1].Class != "tv" || usage[1].Hours[20] != 1 || !usage[1].LastSeen.Equal(evening) {
+		t.Errorf("Unexpected tv usage: %+v", usage[1])
+	}
+
+	// Device usage shares the stats bucket without leaking into daily stats
+	stats, _ := manager.GetDailyStats(time.Now(), time.Now())
+	if len(stats) != 1 {
+		t.Errorf("Expected 1 day of stats, got %d", len(stats))
+	}
+}
+
+func TestBandwidthUsage(t *testing.T) {
+	manager := newStatsTestManager(t)
+
+	at := time.Date(2024, 5, 1, 14, 10, 0, 0, time.Local)
+	manager.RecordBandwidth(at, 100)
+	manager.RecordBandwidth(at.Add(30*time.Minute), 50)
+	manager.RecordBandwidth(at.Add(time.Hour), 25)
+	manager.RecordBandwidth(at.AddDate(0, 0, -1), 999)
+
+	usage, err := manager.GetBandwidthUsage(at.Add(-time.Hour), at.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("GetBandwidthUsage failed: %v", err)
+	}
+	if len(usage) != 2 {
+		t.Fatalf("Expected 2 hours of usage, got %d", len(usage))
+	}
+	if usage[0].Bytes != 150 || usage[0].Hour.Hour() != 14 || usage[0].Hour.Minute() != 0 {
+		t.Errorf("Expected 150 bytes in the 14:00 hour, got %+v", usage[0])
+	}
+	if usage[1].Bytes != 25 {
+		t.Errorf("Expected 25 bytes in the 15:00 hour, got %+v", usage[1])
+	}
+}
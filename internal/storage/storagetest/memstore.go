@@ -0,0 +1,765 @@
+// Package storagetest provides an in-memory implementation of the storage
+// interfaces for fast unit tests that do not need a real BoltDB.
+//
+// MemStore mirrors the observable behavior of storage.Manager: queue items
+// keep their priority ordering, records are copied in and out so callers
+// cannot mutate stored state, and lookups fail the same way.
+package storagetest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+)
+
+// MemStore is an in-memory storage.Store. It also provides the extras the
+// download manager needs. The zero value is not usable; call New.
+type MemStore struct {
+	mu        sync.Mutex
+	queue     map[string]*storage.QueueItem      // keyed like the queue bucket
+	downloads map[string]*storage.DownloadRecord // keyed {media_type}:{jellyfin_id}
+	metadata  map[string]*storage.MediaMetadata  // keyed by Jellyfin ID
+	history   map[string][]storage.ViewingSession
+	devices   map[string]*storage.DeviceUsage
+	states    map[string][]byte
+	bandwidth map[time.Time]int64 // keyed by the start of the hour
+	events    []*storage.Event    // in time order
+
+	// Checksum is returned by ChecksumAlgorithm; defaults to sha256
+	Checksum string
+	// DiskHealth is returned by CheckDiskHealth; defaults to a healthy disk
+	DiskHealth storage.DiskHealth
+	// BytesDownloaded totals the bytes passed to RecordDownloadCompleted
+	BytesDownloaded int64
+}
+
+var _ storage.Store = (*MemStore)(nil)
+
+// New returns an empty in-memory store.
+func New() *MemStore {
+	return &MemStore{
+		queue:      make(map[string]*storage.QueueItem),
+		downloads:  make(map[string]*storage.DownloadRecord),
+		metadata:   make(map[string]*storage.MediaMetadata),
+		history:    make(map[string][]storage.ViewingSession),
+		devices:    make(map[string]*storage.DeviceUsage),
+		states:     make(map[string][]byte),
+		bandwidth:  make(map[time.Time]int64),
+		Checksum:   storage.ChecksumSHA256,
+		DiskHealth: storage.DiskHealth{Status: storage.DiskStatusOK, SMARTStatus: storage.SMARTDisabled},
+	}
+}
+
+// clone deep-copies v the way a round trip through the database would.
+func clone[T any](v *T) *T {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("storagetest: failed to marshal %T: %v", v, err))
+	}
+	var out T
+	if err := json.Unmarshal(data, &out); err != nil {
+		panic(fmt.Sprintf("storagetest: failed to unmarshal %T: %v", v, err))
+	}
+	return &out
+}
+
+// queueKey matches the key pattern of the queue bucket, which determines
+// queue order.
+func queueKey(item *storage.QueueItem) string {
+	return fmt.Sprintf("%03d:%020d:%s", item.Priority, item.CreatedAt.UnixNano(), item.ID)
+}
+
+// sortedKeys returns the keys of m in byte order, like a bucket cursor.
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// findQueueKey returns the key of the queue item with the given ID.
+func (s *MemStore) findQueueKey(itemID string) (string, bool) {
+	for _, k := range sortedKeys(s.queue) {
+		if s.queue[k].ID == itemID {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// AddQueueItem adds an item to the download queue.
+func (s *MemStore) AddQueueItem(item *storage.QueueItem) error {
+	if item.ID == "" || item.MediaID == "" {
+		return fmt.Errorf("queue item must have ID and MediaID")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Re-adding an item replaces it rather than queueing it twice
+	if k, ok := s.findQueueKey(item.ID); ok {
+		delete(s.queue, k)
+	}
+	s.queue[queueKey(item)] = clone(item)
+	return nil
+}
+
+// GetQueueItems returns queue items, ordered by priority and creation time.
+func (s *MemStore) GetQueueItems(status string) ([]*storage.QueueItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []*storage.QueueItem
+	for _, k := range sortedKeys(s.queue) {
+		if status != "" && s.queue[k].Status != status {
+			continue
+		}
+		items = append(items, clone(s.queue[k]))
+	}
+	return items, nil
+}
+
+// GetNextQueueItem returns the first queued item, or nil if there is none.
+func (s *MemStore) GetNextQueueItem() (*storage.QueueItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range sortedKeys(s.queue) {
+		if s.queue[k].Status == "queued" {
+			return clone(s.queue[k]), nil
+		}
+	}
+	return nil, nil
+}
+
+// FindActiveQueueItem returns the queued, downloading or paused item for a
+// media ID.
+func (s *MemStore) FindActiveQueueItem(mediaID string) (*storage.QueueItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range sortedKeys(s.queue) {
+		item := s.queue[k]
+		if item.MediaID == mediaID && (item.Status == "queued" || item.Status == "downloading" || item.Status == "paused") {
+			return clone(item), nil
+		}
+	}
+	return nil, nil
+}
+
+// GetQueueSize returns the count of items in the queue grouped by priority.
+func (s *MemStore) GetQueueSize() (map[int]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sizes := make(map[int]int)
+	for _, item := range s.queue {
+		sizes[item.Priority]++
+	}
+	return sizes, nil
+}
+
+// UpdateQueueItem replaces a queue item in place. Like storage.Manager, it
+// keeps the item's original position even if its priority changed.
+func (s *MemStore) UpdateQueueItem(item *storage.QueueItem) error {
+	if item == nil || item.ID == "" {
+		return fmt.Errorf("invalid queue item: item or ID is empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.findQueueKey(item.ID)
+	if !ok {
+		return fmt.Errorf("queue item with ID %s not found", item.ID)
+	}
+	s.queue[k] = clone(item)
+	return nil
+}
+
+// UpdateQueueItemPriority changes the priority of a queue item and moves it
+// to its new place in the queue.
+func (s *MemStore) UpdateQueueItemPriority(itemID string, priority int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.findQueueKey(itemID)
+	if !ok {
+		return fmt.Errorf("queue item with ID %s not found", itemID)
+	}
+
+	item := s.queue[k]
+	if item.Priority == priority {
+		return nil
+	}
+	delete(s.queue, k)
+	item.Priority = priority
+	s.queue[queueKey(item)] = item
+	return nil
+}
+
+// SetQueueItemUsers records which household users want a queue item.
+func (s *MemStore) SetQueueItemUsers(itemID string, users []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.findQueueKey(itemID)
+	if !ok {
+		return fmt.Errorf("queue item with ID %s not found", itemID)
+	}
+	s.queue[k].Users = append([]string(nil), users...)
+	return nil
+}
+
+// RemoveQueueItem removes an item from the download queue.
+func (s *MemStore) RemoveQueueItem(itemID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.findQueueKey(itemID)
+	if !ok {
+		return fmt.Errorf("queue item with ID %s not found", itemID)
+	}
+	delete(s.queue, k)
+	return nil
+}
+
+// AddMediaMetadata stores metadata for a media item.
+func (s *MemStore) AddMediaMetadata(metadata *storage.MediaMetadata) error {
+	if metadata.JellyfinID == "" {
+		return fmt.Errorf("media metadata must have JellyfinID")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metadata[metadata.JellyfinID] = clone(metadata)
+	return nil
+}
+
+// GetMediaMetadata retrieves metadata for a media item.
+func (s *MemStore) GetMediaMetadata(mediaID string) (*storage.MediaMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	metadata, ok := s.metadata[mediaID]
+	if !ok {
+		return nil, fmt.Errorf("metadata not found for media ID: %s", mediaID)
+	}
+	return clone(metadata), nil
+}
+
+// FindMediaMetadata returns the metadata for a media item, or nil if there
+// is none.
+func (s *MemStore) FindMediaMetadata(mediaID string) (*storage.MediaMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	metadata, ok := s.metadata[mediaID]
+	if !ok {
+		return nil, nil
+	}
+	return clone(metadata), nil
+}
+
+// GetSeriesEpisodes returns the episodes of a series season in episode order.
+func (s *MemStore) GetSeriesEpisodes(seriesID string, season int) ([]storage.EpisodeInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var episodes []storage.EpisodeInfo
+	for _, k := range sortedKeys(s.metadata) {
+		metadata := s.metadata[k]
+		if metadata.Type == "episode" && metadata.SeriesID == seriesID && metadata.SeasonNumber == season {
+			episodes = append(episodes, storage.EpisodeInfo{
+				ID:      metadata.ID,
+				Season:  metadata.SeasonNumber,
+				Episode: metadata.EpisodeNumber,
+				Name:    metadata.Name,
+			})
+		}
+	}
+
+	sort.Slice(episodes, func(i, j int) bool {
+		return episodes[i].Episode < episodes[j].Episode
+	})
+	return episodes, nil
+}
+
+// GetAlbumTracks returns the tracks of an album or audiobook in playback
+// order.
+func (s *MemStore) GetAlbumTracks(albumID string) ([]storage.TrackInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tracks []storage.TrackInfo
+	for _, k := range sortedKeys(s.metadata) {
+		metadata := s.metadata[k]
+		if (metadata.Type == "audio" || metadata.Type == "audiobook") && metadata.AlbumID == albumID {
+			tracks = append(tracks, storage.TrackInfo{
+				ID:    metadata.ID,
+				Disc:  metadata.DiscNumber,
+				Track: metadata.TrackNumber,
+				Name:  metadata.Name,
+			})
+		}
+	}
+
+	storage.SortTracks(tracks)
+	return tracks, nil
+}
+
+// GetSeriesMetadata returns the metadata of every episode of a series in
+// season and episode order.
+func (s *MemStore) GetSeriesMetadata(seriesID string) ([]*storage.MediaMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var episodes []*storage.MediaMetadata
+	for _, k := range sortedKeys(s.metadata) {
+		metadata := s.metadata[k]
+		if metadata.Type == "episode" && metadata.SeriesID == seriesID {
+			episodes = append(episodes, clone(metadata))
+		}
+	}
+
+	storage.SortEpisodes(episodes)
+	return episodes, nil
+}
+
+// SearchMetadata returns up to limit items of mediaType, or of any type if
+// it is empty, whose name has a word starting with each word of query, in
+// name order. It does not rank matches like the search index does.
+func (s *MemStore) SearchMetadata(query, mediaType string, limit int) ([]*storage.MediaMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	words := strings.FieldsFunc(strings.ToLower(query), notWordRune)
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	var results []*storage.MediaMetadata
+	for _, k := range sortedKeys(s.metadata) {
+		metadata := s.metadata[k]
+		if mediaType != "" && metadata.Type != mediaType {
+			continue
+		}
+		if nameHasWords(metadata.Name, words) {
+			results = append(results, clone(metadata))
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return strings.ToLower(results[i].Name) < strings.ToLower(results[j].Name)
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func notWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// nameHasWords reports whether every word is a prefix of a word of name.
+func nameHasWords(name string, words []string) bool {
+	nameWords := strings.FieldsFunc(strings.ToLower(name), notWordRune)
+	for _, word := range words {
+		found := false
+		for _, nameWord := range nameWords {
+			if strings.HasPrefix(nameWord, word) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// GetStaleMetadata returns up to limit metadata entries of cached items last
+// synced before the given time, least recently synced first.
+func (s *MemStore) GetStaleMetadata(before time.Time, limit int) ([]*storage.MediaMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cached := make(map[string]bool)
+	for _, record := range s.downloads {
+		cached[record.JellyfinID] = true
+	}
+
+	var stale []*storage.MediaMetadata
+	for _, metadata := range s.metadata {
+		if cached[metadata.JellyfinID] && metadata.LastSynced.Before(before) {
+			stale = append(stale, clone(metadata))
+		}
+	}
+
+	storage.SortStaleMetadata(stale)
+	if limit > 0 && len(stale) > limit {
+		stale = stale[:limit]
+	}
+	return stale, nil
+}
+
+// GetRecentMetadata returns up to limit metadata entries of items added
+// after since, newest first.
+func (s *MemStore) GetRecentMetadata(since time.Time, limit int) ([]*storage.MediaMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var recent []*storage.MediaMetadata
+	for _, metadata := range s.metadata {
+		if metadata.AddedAt().After(since) {
+			recent = append(recent, clone(metadata))
+		}
+	}
+
+	storage.SortRecentMetadata(recent)
+	if limit > 0 && len(recent) > limit {
+		recent = recent[:limit]
+	}
+	return recent, nil
+}
+
+// IsMediaCached reports whether a media item has a download record.
+func (s *MemStore) IsMediaCached(mediaID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, record := range s.downloads {
+		if (record.ID == mediaID || record.JellyfinID == mediaID) && record.Status != "evicted" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AddDownloadRecord adds or replaces a download record.
+func (s *MemStore) AddDownloadRecord(record *storage.DownloadRecord) error {
+	if record.ID == "" || record.JellyfinID == "" {
+		return fmt.Errorf("download record must have ID and JellyfinID")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.downloads[record.MediaType+":"+record.JellyfinID] = clone(record)
+	return nil
+}
+
+// GetDownload retrieves a download record by Jellyfin ID.
+func (s *MemStore) GetDownload(mediaID string) (*storage.DownloadRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range sortedKeys(s.downloads) {
+		if s.downloads[k].JellyfinID == mediaID {
+			return clone(s.downloads[k]), nil
+		}
+	}
+	return nil, fmt.Errorf("download record not found for media ID: %s", mediaID)
+}
+
+// ListDownloadRecords returns every download record, optionally filtered by
+// media type, including evicted ones.
+func (s *MemStore) ListDownloadRecords(mediaType string) ([]*storage.DownloadRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var records []*storage.DownloadRecord
+	for _, k := range sortedKeys(s.downloads) {
+		if record := s.downloads[k]; mediaType == "" || record.MediaType == mediaType {
+			records = append(records, clone(record))
+		}
+	}
+	return records, nil
+}
+
+// RecordAccess marks a cached item as played at the given time.
+func (s *MemStore) RecordAccess(mediaID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range sortedKeys(s.downloads) {
+		if record := s.downloads[k]; record.JellyfinID == mediaID {
+			record.LastAccessed = at
+			record.AccessCount++
+			return nil
+		}
+	}
+	return fmt.Errorf("download record not found for media ID: %s", mediaID)
+}
+
+// GetCachedItems returns a page of the cached library, newest first.
+func (s *MemStore) GetCachedItems(mediaType string, page, limit int) ([]*storage.CachedItem, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 50
+	}
+
+	result, err := s.QueryCachedItems(storage.LibraryQuery{MediaType: mediaType, Limit: limit, Offset: (page - 1) * limit})
+	if err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+// QueryCachedItems returns a sorted page of the cached library, with the
+// total number of cached items of the queried type.
+func (s *MemStore) QueryCachedItems(query storage.LibraryQuery) (*storage.LibraryPage, error) {
+	s.mu.Lock()
+	var items []*storage.CachedItem
+	for _, record := range s.downloads {
+		if record.Status == "evicted" || (query.MediaType != "" && record.MediaType != query.MediaType) {
+			continue
+		}
+		item := &storage.CachedItem{
+			ID:           record.JellyfinID,
+			Name:         record.Title,
+			Type:         record.MediaType,
+			Path:         record.LocalPath,
+			Size:         record.Size,
+			DateAdded:    record.DownloadedAt,
+			LastAccessed: record.LastAccessed,
+		}
+		if metadata, ok := s.metadata[record.JellyfinID]; ok {
+			storage.ApplyCachedItemMetadata(item, metadata)
+		}
+		items = append(items, item)
+	}
+	s.mu.Unlock()
+
+	page, err := storage.PaginateCachedItems(items, query)
+	if err != nil {
+		return nil, err
+	}
+	page.Total = len(items)
+	return page, nil
+}
+
+// GetCachedItemsCount returns the number of cached items, optionally
+// filtered by media type.
+func (s *MemStore) GetCachedItemsCount(mediaType string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, record := range s.downloads {
+		if record.Status != "evicted" && (mediaType == "" || record.MediaType == mediaType) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// StoreViewingSession adds a viewing session to a user's history,
+// replacing the session with the same media and start time.
+func (s *MemStore) StoreViewingSession(userID string, session storage.ViewingSession) error {
+	return s.UpsertViewingSession(userID, session)
+}
+
+// UpsertViewingSession replaces the session with the same media and start
+// time, or appends it if there is none.
+func (s *MemStore) UpsertViewingSession(userID string, session storage.ViewingSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions := s.history[userID]
+	for i := len(sessions) - 1; i >= 0; i-- {
+		if sessions[i].MediaID == session.MediaID && sessions[i].StartTime.Equal(session.StartTime) {
+			sessions[i] = session
+			return nil
+		}
+	}
+	s.history[userID] = append(sessions, session)
+	return nil
+}
+
+// GetViewingHistory returns a user's sessions from the last days days,
+// oldest first.
+func (s *MemStore) GetViewingHistory(userID string, days int) ([]storage.ViewingSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sessions []storage.ViewingSession
+	cutoff := time.Now().AddDate(0, 0, -days)
+	for _, session := range s.history[userID] {
+		if !session.StartTime.Before(cutoff) {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].StartTime.Before(sessions[j].StartTime)
+	})
+	return sessions, nil
+}
+
+// RecordDevicePlayback counts a playback start on a device class.
+func (s *MemStore) RecordDevicePlayback(class string, at time.Time) error {
+	if class == "" {
+		return fmt.Errorf("device class is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usage, ok := s.devices[class]
+	if !ok {
+		usage = &storage.DeviceUsage{Class: class}
+		s.devices[class] = usage
+	}
+	usage.Plays++
+	usage.Hours[at.Hour()]++
+	usage.LastSeen = at
+	return nil
+}
+
+// GetDeviceUsage returns the usage of every device class, most used first.
+func (s *MemStore) GetDeviceUsage() ([]*storage.DeviceUsage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var usage []*storage.DeviceUsage
+	for _, device := range s.devices {
+		usage = append(usage, clone(device))
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Plays != usage[j].Plays {
+			return usage[i].Plays > usage[j].Plays
+		}
+		return usage[i].Class < usage[j].Class
+	})
+	return usage, nil
+}
+
+// RecordDownloadCompleted adds to BytesDownloaded.
+func (s *MemStore) RecordDownloadCompleted(bytes int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.BytesDownloaded += bytes
+	return nil
+}
+
+// RecordBandwidth adds bytes to the usage of the hour containing at.
+func (s *MemStore) RecordBandwidth(at time.Time, bytes int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hour := time.Date(at.Year(), at.Month(), at.Day(), at.Hour(), 0, 0, 0, at.Location())
+	s.bandwidth[hour] += bytes
+	return nil
+}
+
+// GetBandwidthUsage returns the usage of each hour in [from, to] that has
+// any, oldest first.
+func (s *MemStore) GetBandwidthUsage(from, to time.Time) ([]*storage.BandwidthUsage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from = time.Date(from.Year(), from.Month(), from.Day(), from.Hour(), 0, 0, 0, from.Location())
+	var usage []*storage.BandwidthUsage
+	for hour, bytes := range s.bandwidth {
+		if !hour.Before(from) && !hour.After(to) {
+			usage = append(usage, &storage.BandwidthUsage{Hour: hour, Bytes: bytes})
+		}
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Hour.Before(usage[j].Hour) })
+	return usage, nil
+}
+
+// CheckDiskHealth returns a copy of DiskHealth stamped with the current time.
+func (s *MemStore) CheckDiskHealth(ctx context.Context) (*storage.DiskHealth, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	health := s.DiskHealth
+	health.CheckedAt = time.Now()
+	return &health, nil
+}
+
+// ChecksumAlgorithm returns Checksum.
+func (s *MemStore) ChecksumAlgorithm() string {
+	return s.Checksum
+}
+
+// SaveState stores v as JSON under key.
+func (s *MemStore) SaveState(key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state %s: %w", key, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[key] = data
+	return nil
+}
+
+// LoadState decodes the state saved under key into v, reporting false when
+// nothing has been saved.
+func (s *MemStore) LoadState(key string, v interface{}) (bool, error) {
+	s.mu.Lock()
+	data, ok := s.states[key]
+	s.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to unmarshal state %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// RecordEvent adds an event to the log, setting its ID and, when zero, its
+// time.
+func (s *MemStore) RecordEvent(event *storage.Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	event.ID = fmt.Sprintf("%s:%016x", event.Time.UTC().Format(time.RFC3339Nano), len(s.events)+1)
+	stored := clone(event)
+	i := sort.Search(len(s.events), func(i int) bool { return s.events[i].Time.After(stored.Time) })
+	s.events = append(s.events[:i], append([]*storage.Event{stored}, s.events[i:]...)...)
+	return nil
+}
+
+// GetEvents returns the events matching filter, newest first.
+func (s *MemStore) GetEvents(filter storage.EventFilter) ([]*storage.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := make([]*storage.Event, 0)
+	for i := len(s.events) - 1; i >= 0; i-- {
+		event := s.events[i]
+		if !filter.Until.IsZero() && !event.Time.Before(filter.Until) {
+			continue
+		}
+		if !filter.Since.IsZero() && event.Time.Before(filter.Since) {
+			break
+		}
+		if !filter.Matches(event) {
+			continue
+		}
+		events = append(events, clone(event))
+		if filter.Limit > 0 && len(events) == filter.Limit {
+			break
+		}
+	}
+	return events, nil
+}
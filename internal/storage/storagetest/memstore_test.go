@@ -0,0 +1,440 @@
+package storagetest
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// seedableStore is a storage.Store that tests can fill with metadata and
+// history; both storage.Manager and MemStore qualify.
+type seedableStore interface {
+	storage.Store
+	AddMediaMetadata(metadata *storage.MediaMetadata) error
+	StoreViewingSession(userID string, session storage.ViewingSession) error
+}
+
+// forEachStore runs fn against a BoltDB-backed manager and a MemStore, so
+// the fake is held to the real store's behavior.
+func forEachStore(t *testing.T, fn func(t *testing.T, s seedableStore)) {
+	t.Run("bolt", func(t *testing.T) {
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+		manager, err := storage.NewManager(&config.CacheConfig{Directory: t.TempDir(), MaxSizeGB: 1, MetadataStore: "boltdb"}, logger)
+		if err != nil {
+			t.Fatalf("Failed to create manager: %v", err)
+		}
+		defer manager.Close()
+		fn(t, manager)
+	})
+	t.Run("memory", func(t *testing.T) {
+		fn(t, New())
+	})
+}
+
+func queueIDs(t *testing.T, s storage.QueueStore, status string) []string {
+	items, err := s.GetQueueItems(status)
+	if err != nil {
+		t.Fatalf("GetQueueItems failed: %v", err)
+	}
+	var ids []string
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestQueueStore(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s seedableStore) {
+		base := time.Date(2024, 3, 1, 20, 0, 0, 0, time.UTC)
+		for i, item := range []*storage.QueueItem{
+			{ID: "a", MediaID: "m1", Priority: 3, Status: "queued"},
+			{ID: "b", MediaID: "m2", Priority: 1, Status: "queued"},
+			{ID: "c", MediaID: "m3", Priority: 3, Status: "downloading"},
+			{ID: "d", MediaID: "m4", Priority: 4, Status: "failed"},
+		} {
+			item.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+			if err := s.AddQueueItem(item); err != nil {
+				t.Fatalf("AddQueueItem failed: %v", err)
+			}
+		}
+		if err := s.AddQueueItem(&storage.QueueItem{ID: "x"}); err == nil {
+			t.Error("Expected error for item without media ID")
+		}
+
+		if got := queueIDs(t, s, ""); !reflect.DeepEqual(got, []string{"b", "a", "c", "d"}) {
+			t.Errorf("Expected priority order, got %v", got)
+		}
+		if got := queueIDs(t, s, "queued"); !reflect.DeepEqual(got, []string{"b", "a"}) {
+			t.Errorf("Expected queued items, got %v", got)
+		}
+
+		next, err := s.GetNextQueueItem()
+		if err != nil || next == nil || next.ID != "b" {
+			t.Errorf("Expected next item b, got %+v (%v)", next, err)
+		}
+
+		active, err := s.FindActiveQueueItem("m3")
+		if err != nil || active == nil || active.ID != "c" {
+			t.Errorf("Expected active item c, got %+v (%v)", active, err)
+		}
+		if failed, _ := s.FindActiveQueueItem("m4"); failed != nil {
+			t.Errorf("Failed items are not active, got %+v", failed)
+		}
+
+		sizes, err := s.GetQueueSize()
+		if err != nil || !reflect.DeepEqual(sizes, map[int]int{1: 1, 3: 2, 4: 1}) {
+			t.Errorf("Unexpected queue sizes %v (%v)", sizes, err)
+		}
+
+		// Re-prioritizing moves the item; updating in place does not
+		if err := s.UpdateQueueItemPriority("c", 0); err != nil {
+			t.Fatalf("UpdateQueueItemPriority failed: %v", err)
+		}
+		next.Priority = 4
+		next.Status = "downloading"
+		if err := s.UpdateQueueItem(next); err != nil {
+			t.Fatalf("UpdateQueueItem failed: %v", err)
+		}
+		if got := queueIDs(t, s, ""); !reflect.DeepEqual(got, []string{"c", "b", "a", "d"}) {
+			t.Errorf("Unexpected order after updates, got %v", got)
+		}
+
+		if err := s.SetQueueItemUsers("a", []string{"alice", "bob"}); err != nil {
+			t.Fatalf("SetQueueItemUsers failed: %v", err)
+		}
+		items, _ := s.GetQueueItems("queued")
+		if len(items) != 1 || !reflect.DeepEqual(items[0].Users, []string{"alice", "bob"}) {
+			t.Errorf("Expected users on item a, got %+v", items)
+		}
+
+		// Returned items are copies
+		items[0].Status = "mutated"
+		if got := queueIDs(t, s, "queued"); !reflect.DeepEqual(got, []string{"a"}) {
+			t.Errorf("Mutating a returned item changed the store: %v", got)
+		}
+
+		if err := s.RemoveQueueItem("a"); err != nil {
+			t.Fatalf("RemoveQueueItem failed: %v", err)
+		}
+		for name, err := range map[string]error{
+			"remove":   s.RemoveQueueItem("a"),
+			"priority": s.UpdateQueueItemPriority("missing", 1),
+			"users":    s.SetQueueItemUsers("missing", nil),
+			"update":   s.UpdateQueueItem(&storage.QueueItem{ID: "missing"}),
+		} {
+			if err == nil {
+				t.Errorf("Expected %s of a missing item to fail", name)
+			}
+		}
+	})
+}
+
+func TestMediaStore(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s seedableStore) {
+		for _, metadata := range []*storage.MediaMetadata{
+			{ID: "e2", JellyfinID: "e2", Name: "Two", Type: "episode", SeriesID: "s1", SeasonNumber: 1, EpisodeNumber: 2},
+			{ID: "e1", JellyfinID: "e1", Name: "One", Type: "episode", SeriesID: "s1", SeasonNumber: 1, EpisodeNumber: 1},
+			{ID: "e3", JellyfinID: "e3", Name: "Three", Type: "episode", SeriesID: "s1", SeasonNumber: 2, EpisodeNumber: 1},
+		} {
+			if err := s.AddMediaMetadata(metadata); err != nil {
+				t.Fatalf("AddMediaMetadata failed: %v", err)
+			}
+		}
+
+		episodes, err := s.GetSeriesEpisodes("s1", 1)
+		if err != nil || len(episodes) != 2 || episodes[0].ID != "e1" || episodes[1].ID != "e2" {
+			t.Errorf("Expected season 1 in episode order, got %+v (%v)", episodes, err)
+		}
+
+		series, err := s.GetSeriesMetadata("s1")
+		if err != nil || len(series) != 3 || series[0].ID != "e1" || series[1].ID != "e2" || series[2].ID != "e3" {
+			t.Errorf("Expected the whole series in season and episode order, got %d items (%v)", len(series), err)
+		}
+
+		for _, metadata := range []*storage.MediaMetadata{
+			{ID: "t3", JellyfinID: "t3", Name: "Disc Two", Type: "audio", AlbumID: "a1", DiscNumber: 2, TrackNumber: 1},
+			{ID: "t2", JellyfinID: "t2", Name: "Second", Type: "audio", AlbumID: "a1", DiscNumber: 1, TrackNumber: 2},
+			{ID: "t1", JellyfinID: "t1", Name: "First", Type: "audio", AlbumID: "a1", DiscNumber: 1, TrackNumber: 1},
+			{ID: "c1", JellyfinID: "c1", Name: "Chapter", Type: "audiobook", AlbumID: "b1", TrackNumber: 1},
+		} {
+			if err := s.AddMediaMetadata(metadata); err != nil {
+				t.Fatalf("AddMediaMetadata failed: %v", err)
+			}
+		}
+
+		tracks, err := s.GetAlbumTracks("a1")
+		if err != nil || len(tracks) != 3 || tracks[0].ID != "t1" || tracks[1].ID != "t2" || tracks[2].ID != "t3" {
+			t.Errorf("Expected album tracks in disc and track order, got %+v (%v)", tracks, err)
+		}
+		if chapters, _ := s.GetAlbumTracks("b1"); len(chapters) != 1 || chapters[0].ID != "c1" {
+			t.Errorf("Expected one audiobook chapter, got %+v", chapters)
+		}
+
+		if _, err := s.GetMediaMetadata("missing"); err == nil {
+			t.Error("Expected error for missing metadata")
+		}
+
+		for _, record := range []*storage.DownloadRecord{
+			{ID: "e1", JellyfinID: "e1", MediaType: "episode", Title: "One", Size: 10, Status: "completed"},
+			{ID: "m1", JellyfinID: "m1", MediaType: "movie", Title: "Heat", Size: 20, Status: "completed"},
+		} {
+			if err := s.AddDownloadRecord(record); err != nil {
+				t.Fatalf("AddDownloadRecord failed: %v", err)
+			}
+		}
+
+		if cached, _ := s.IsMediaCached("e1"); !cached {
+			t.Error("Expected e1 to be cached")
+		}
+		if cached, _ := s.IsMediaCached("e2"); cached {
+			t.Error("Expected e2 not to be cached")
+		}
+
+		record, err := s.GetDownload("m1")
+		if err != nil || record.Title != "Heat" {
+			t.Errorf("Expected Heat, got %+v (%v)", record, err)
+		}
+		if _, err := s.GetDownload("missing"); err == nil {
+			t.Error("Expected error for missing download")
+		}
+
+		if count, _ := s.GetCachedItemsCount(""); count != 2 {
+			t.Errorf("Expected 2 cached items, got %d", count)
+		}
+		if count, _ := s.GetCachedItemsCount("movie"); count != 1 {
+			t.Errorf("Expected 1 cached movie, got %d", count)
+		}
+
+		page, err := s.GetCachedItems("", 2, 1)
+		if err != nil || len(page) != 1 || page[0].ID != "m1" {
+			t.Errorf("Expected second page to hold m1, got %+v (%v)", page, err)
+		}
+		page, _ = s.GetCachedItems("episode", 1, 10)
+		if len(page) != 1 || page[0].SeriesID != "s1" || page[0].EpisodeNumber != 1 {
+			t.Errorf("Expected episode enriched with metadata, got %+v", page)
+		}
+
+		// Only cached items are refreshed; e2 and e3 have no download record
+		synced := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		for _, metadata := range []*storage.MediaMetadata{
+			{ID: "m1", JellyfinID: "m1", Name: "Heat", Type: "movie", LastSynced: synced.Add(time.Hour)},
+			{ID: "e1", JellyfinID: "e1", Name: "One", Type: "episode", SeriesID: "s1", SeasonNumber: 1, EpisodeNumber: 1, LastSynced: synced},
+		} {
+			if err := s.AddMediaMetadata(metadata); err != nil {
+				t.Fatalf("AddMediaMetadata failed: %v", err)
+			}
+		}
+
+		stale, err := s.GetStaleMetadata(synced.Add(2*time.Hour), 0)
+		if err != nil || len(stale) != 2 || stale[0].ID != "e1" || stale[1].ID != "m1" {
+			t.Errorf("Expected e1 then m1 as stale, got %d items (%v)", len(stale), err)
+		}
+		if stale, _ := s.GetStaleMetadata(synced.Add(2*time.Hour), 1); len(stale) != 1 || stale[0].ID != "e1" {
+			t.Errorf("Expected the limit to keep the oldest, got %+v", stale)
+		}
+		if stale, _ := s.GetStaleMetadata(synced.Add(time.Minute), 0); len(stale) != 1 || stale[0].ID != "e1" {
+			t.Errorf("Expected only e1 synced before the cutoff, got %+v", stale)
+		}
+	})
+}
+
+func TestLibraryQuery(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s seedableStore) {
+		base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		for i, record := range []*storage.DownloadRecord{
+			{ID: "a", JellyfinID: "a", MediaType: "movie", Title: "alien", Size: 30, Status: "completed", DownloadedAt: base},
+			{ID: "b", JellyfinID: "b", MediaType: "movie", Title: "Brazil", Size: 10, Status: "completed", DownloadedAt: base.Add(time.Hour)},
+			{ID: "c", JellyfinID: "c", MediaType: "episode", Title: "Cold Open", Size: 20, Status: "completed", DownloadedAt: base.Add(2 * time.Hour)},
+			{ID: "d", JellyfinID: "d", MediaType: "movie", Title: "Dune", Size: 40, Status: "evicted", DownloadedAt: base.Add(3 * time.Hour)},
+		} {
+			record.LastAccessed = base.Add(time.Duration(-i) * time.Hour)
+			if err := s.AddDownloadRecord(record); err != nil {
+				t.Fatalf("AddDownloadRecord failed: %v", err)
+			}
+		}
+
+		ids := func(page *storage.LibraryPage) []string {
+			var ids []string
+			for _, item := range page.Items {
+				ids = append(ids, item.ID)
+			}
+			return ids
+		}
+
+		for _, tc := range []struct {
+			sort, order string
+			want        []string
+		}{
+			{"", "", []string{"c", "b", "a"}},
+			{storage.LibrarySortAdded, "asc", []string{"a", "b", "c"}},
+			{storage.LibrarySortSize, "", []string{"a", "c", "b"}},
+			{storage.LibrarySortAccessed, "", []string{"a", "b", "c"}},
+			{storage.LibrarySortName, "", []string{"a", "b", "c"}},
+		} {
+			page, err := s.QueryCachedItems(storage.LibraryQuery{Sort: tc.sort, Order: tc.order})
+			if err != nil {
+				t.Fatalf("QueryCachedItems(%s %s) failed: %v", tc.sort, tc.order, err)
+			}
+			if !reflect.DeepEqual(ids(page), tc.want) || page.Total != 3 {
+				t.Errorf("Sort %q %q: expected %v of 3, got %v of %d", tc.sort, tc.order, tc.want, ids(page), page.Total)
+			}
+		}
+
+		// Following cursors visits every item once, even as items are added
+		first, err := s.QueryCachedItems(storage.LibraryQuery{Sort: storage.LibrarySortSize, Limit: 2})
+		if err != nil || first.NextCursor == "" {
+			t.Fatalf("Expected a first page with a cursor, got %+v (%v)", first, err)
+		}
+		s.AddDownloadRecord(&storage.DownloadRecord{ID: "e", JellyfinID: "e", MediaType: "movie", Title: "Empire", Size: 50, Status: "completed"})
+		second, err := s.QueryCachedItems(storage.LibraryQuery{Sort: storage.LibrarySortSize, Limit: 2, Cursor: first.NextCursor})
+		if err != nil {
+			t.Fatalf("QueryCachedItems with cursor failed: %v", err)
+		}
+		if got := ids(second); !reflect.DeepEqual(got, []string{"b"}) || second.NextCursor != "" || second.Total != 4 {
+			t.Errorf("Expected the last page to hold b of 4, got %v of %d (cursor %q)", got, second.Total, second.NextCursor)
+		}
+
+		if _, err := s.QueryCachedItems(storage.LibraryQuery{Sort: storage.LibrarySortName, Cursor: first.NextCursor}); !errors.Is(err, storage.ErrInvalidLibraryQuery) {
+			t.Errorf("Expected a cursor for another order to be rejected, got %v", err)
+		}
+		if _, err := s.QueryCachedItems(storage.LibraryQuery{Sort: "rating"}); !errors.Is(err, storage.ErrInvalidLibraryQuery) {
+			t.Errorf("Expected an unknown sort to be rejected, got %v", err)
+		}
+		if count, _ := s.GetCachedItemsCount("movie"); count != 3 {
+			t.Errorf("Expected evicted movies not to be counted, got %d", count)
+		}
+	})
+}
+
+func TestHistoryStore(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s seedableStore) {
+		now := time.Now().Truncate(time.Second)
+		old := storage.ViewingSession{MediaID: "old", StartTime: now.AddDate(0, 0, -40)}
+		if err := s.StoreViewingSession("alice", old); err != nil {
+			t.Fatalf("StoreViewingSession failed: %v", err)
+		}
+
+		session := storage.ViewingSession{MediaID: "e1", StartTime: now, WatchedTime: 60}
+		s.UpsertViewingSession("alice", session)
+		session.WatchedTime = 1200
+		session.Completed = true
+		s.UpsertViewingSession("alice", session)
+
+		history, err := s.GetViewingHistory("alice", 30)
+		if err != nil || len(history) != 1 || history[0].WatchedTime != 1200 || !history[0].Completed {
+			t.Errorf("Expected one updated recent session, got %+v (%v)", history, err)
+		}
+		if history, _ := s.GetViewingHistory("bob", 30); len(history) != 0 {
+			t.Errorf("Expected no history for bob, got %+v", history)
+		}
+
+		evening := time.Date(2024, 3, 1, 21, 0, 0, 0, time.Local)
+		s.RecordDevicePlayback("tv", evening)
+		s.RecordDevicePlayback("tv", evening)
+		s.RecordDevicePlayback("phone", evening.Add(-12*time.Hour))
+		if err := s.RecordDevicePlayback("", evening); err == nil {
+			t.Error("Expected error for empty device class")
+		}
+
+		usage, err := s.GetDeviceUsage()
+		if err != nil || len(usage) != 2 || usage[0].Class != "tv" || usage[0].Plays != 2 || usage[0].Hours[21] != 2 {
+			t.Errorf("Unexpected device usage %+v (%v)", usage, err)
+		}
+	})
+}
+
+func TestStateStore(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s seedableStore) {
+		type state struct {
+			Count int       `json:"count"`
+			At    time.Time `json:"at"`
+		}
+
+		var loaded state
+		found, err := s.LoadState("component", &loaded)
+		if err != nil || found {
+			t.Fatalf("Expected no saved state, got found=%v err=%v", found, err)
+		}
+
+		saved := state{Count: 3, At: time.Date(2024, 3, 1, 20, 0, 0, 0, time.UTC)}
+		if err := s.SaveState("component", saved); err != nil {
+			t.Fatalf("SaveState failed: %v", err)
+		}
+		if err := s.SaveState("component", state{Count: 4, At: saved.At}); err != nil {
+			t.Fatalf("SaveState failed: %v", err)
+		}
+
+		found, err = s.LoadState("component", &loaded)
+		if err != nil || !found {
+			t.Fatalf("Expected saved state, got found=%v err=%v", found, err)
+		}
+		if loaded.Count != 4 || !loaded.At.Equal(saved.At) {
+			t.Errorf("Expected the latest state, got %+v", loaded)
+		}
+	})
+}
+
+func TestEventStore(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s seedableStore) {
+		base := time.Date(2024, 3, 1, 20, 0, 0, 0, time.UTC)
+		for i, event := range []*storage.Event{
+			{Time: base, Kind: storage.EventDownloadCompleted, MediaID: "m1", Message: "Downloaded"},
+			{Time: base.Add(2 * time.Hour), Kind: storage.EventEvicted, MediaID: "m1", Message: "Evicted",
+				Details: map[string]interface{}{"score": 1.5}},
+			{Time: base.Add(time.Hour), Kind: storage.EventSync, Message: "Prediction cycle"},
+			{Time: base.Add(time.Hour), Kind: storage.EventDownloadCompleted, MediaID: "m2", Message: "Downloaded"},
+		} {
+			if err := s.RecordEvent(event); err != nil {
+				t.Fatalf("RecordEvent %d failed: %v", i, err)
+			}
+			if event.ID == "" {
+				t.Errorf("Expected event %d to get an ID", i)
+			}
+		}
+
+		kinds := func(events []*storage.Event) []string {
+			var kinds []string
+			for _, event := range events {
+				kinds = append(kinds, event.Kind+":"+event.MediaID)
+			}
+			return kinds
+		}
+
+		tests := []struct {
+			name   string
+			filter storage.EventFilter
+			want   []string
+		}{
+			{"all, newest first", storage.EventFilter{}, []string{"evicted:m1", "download_completed:m2", "sync:", "download_completed:m1"}},
+			{"by media", storage.EventFilter{MediaID: "m1"}, []string{"evicted:m1", "download_completed:m1"}},
+			{"by kind", storage.EventFilter{Kinds: []string{storage.EventSync, storage.EventEvicted}}, []string{"evicted:m1", "sync:"}},
+			{"time range", storage.EventFilter{Since: base.Add(time.Hour), Until: base.Add(2 * time.Hour)}, []string{"download_completed:m2", "sync:"}},
+			{"limit", storage.EventFilter{Limit: 1}, []string{"evicted:m1"}},
+			{"nothing before", storage.EventFilter{Until: base}, nil},
+		}
+		for _, tt := range tests {
+			events, err := s.GetEvents(tt.filter)
+			if err != nil {
+				t.Fatalf("%s: GetEvents failed: %v", tt.name, err)
+			}
+			if got := kinds(events); !slices.Equal(got, tt.want) {
+				t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+			}
+		}
+
+		events, err := s.GetEvents(storage.EventFilter{Kinds: []string{storage.EventEvicted}})
+		if err != nil || len(events) != 1 {
+			t.Fatalf("Expected the eviction, got %v (%v)", events, err)
+		}
+		if events[0].Details["score"] != 1.5 || !events[0].Time.Equal(base.Add(2*time.Hour)) {
+			t.Errorf("Unexpected eviction event %+v", events[0])
+		}
+	})
+}
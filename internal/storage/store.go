@@ -0,0 +1,68 @@
+package storage
+
+import "time"
+
+// QueueStore persists the download queue. Items are returned ordered by
+// priority, then creation time.
+type QueueStore interface {
+	AddQueueItem(item *QueueItem) error
+	GetQueueItems(status string) ([]*QueueItem, error)
+	GetNextQueueItem() (*QueueItem, error)
+	FindActiveQueueItem(mediaID string) (*QueueItem, error)
+	GetQueueSize() (map[int]int, error)
+	UpdateQueueItem(item *QueueItem) error
+	UpdateQueueItemPriority(itemID string, priority int) error
+	SetQueueItemUsers(itemID string, users []string) error
+	RemoveQueueItem(itemID string) error
+}
+
+// MediaStore holds library metadata and records of cached downloads.
+type MediaStore interface {
+	AddMediaMetadata(metadata *MediaMetadata) error
+	GetMediaMetadata(mediaID string) (*MediaMetadata, error)
+	GetSeriesEpisodes(seriesID string, season int) ([]EpisodeInfo, error)
+	GetAlbumTracks(albumID string) ([]TrackInfo, error)
+	GetSeriesMetadata(seriesID string) ([]*MediaMetadata, error)
+	IsMediaCached(mediaID string) (bool, error)
+	AddDownloadRecord(record *DownloadRecord) error
+	GetDownload(mediaID string) (*DownloadRecord, error)
+	ListDownloadRecords(mediaType string) ([]*DownloadRecord, error)
+	RecordAccess(mediaID string, at time.Time) error
+	GetCachedItems(mediaType string, page, limit int) ([]*CachedItem, error)
+	QueryCachedItems(query LibraryQuery) (*LibraryPage, error)
+	GetCachedItemsCount(mediaType string) (int, error)
+	GetStaleMetadata(before time.Time, limit int) ([]*MediaMetadata, error)
+	GetRecentMetadata(since time.Time, limit int) ([]*MediaMetadata, error)
+}
+
+// HistoryStore records what users watched and on which devices.
+type HistoryStore interface {
+	GetViewingHistory(userID string, days int) ([]ViewingSession, error)
+	UpsertViewingSession(userID string, session ViewingSession) error
+	RecordDevicePlayback(class string, at time.Time) error
+	GetDeviceUsage() ([]*DeviceUsage, error)
+}
+
+// StateStore persists the in-memory state of long-running components, such
+// as the predictor's analysis, across restarts. Values are stored as JSON.
+type StateStore interface {
+	SaveState(key string, v interface{}) error
+	LoadState(key string, v interface{}) (bool, error)
+}
+
+// EventStore keeps the event log. Events are returned newest first.
+type EventStore interface {
+	RecordEvent(event *Event) error
+	GetEvents(filter EventFilter) ([]*Event, error)
+}
+
+// Store combines the queue, media, history, state and event stores.
+type Store interface {
+	QueueStore
+	MediaStore
+	HistoryStore
+	StateStore
+	EventStore
+}
+
+var _ Store = (*Manager)(nil)
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// Subscription makes every episode library sync finds for a series from
+// now on get cached, whether or not the predictor expects it to be watched.
+// Key pattern: {series-id} in the subscriptions bucket
+type Subscription struct {
+	SeriesID   string    `json:"series_id"`
+	SeriesName string    `json:"series_name,omitempty"`
+	Priority   int       `json:"priority"` // Priority new episodes are queued at
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AddSubscription stores a subscription, replacing any existing one for
+// the same series.
+func (m *Manager) AddSubscription(sub *Subscription) error {
+	if sub.SeriesID == "" {
+		return fmt.Errorf("subscription must have a series ID")
+	}
+
+	data, err := json.Marshal(sub)
+	if err != nil {
+		return fmt.Errorf("failed to marshal subscription: %w", err)
+	}
+
+	return m.update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketSubscriptions).Put([]byte(sub.SeriesID), data)
+	})
+}
+
+// GetSubscription returns the subscription to seriesID, or nil if there is
+// none.
+func (m *Manager) GetSubscription(seriesID string) (*Subscription, error) {
+	var sub *Subscription
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(bucketSubscriptions).Get([]byte(seriesID))
+		if data == nil {
+			return nil
+		}
+		sub = &Subscription{}
+		return json.Unmarshal(data, sub)
+	})
+
+	return sub, err
+}
+
+// ListSubscriptions returns every subscription, sorted by series name.
+func (m *Manager) ListSubscriptions() ([]*Subscription, error) {
+	var subs []*Subscription
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketSubscriptions).ForEach(func(k, v []byte) error {
+			var sub Subscription
+			if err := json.Unmarshal(v, &sub); err != nil {
+				return nil // Continue on marshal errors
+			}
+			subs = append(subs, &sub)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+
+	sort.Slice(subs, func(i, j int) bool {
+		if subs[i].SeriesName != subs[j].SeriesName {
+			return subs[i].SeriesName < subs[j].SeriesName
+		}
+		return subs[i].SeriesID < subs[j].SeriesID
+	})
+	return subs, nil
+}
+
+// RemoveSubscription deletes the subscription to seriesID. It is a no-op
+// if there is none.
+func (m *Manager) RemoveSubscription(seriesID string) error {
+	return m.update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketSubscriptions).Delete([]byte(seriesID))
+	})
+}
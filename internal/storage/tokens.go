@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// apiTokenPrefix namespaces API tokens within the config bucket.
+const apiTokenPrefix = "api_token:"
+
+// API token scopes, each allowing everything the ones before it do.
+const (
+	TokenScopeRead  = "read"  // Read-only access to the API
+	TokenScopeQueue = "queue" // Also manage the download queue, pins, subscriptions and collections
+	TokenScopeAdmin = "admin" // Everything a signed-in user can do
+)
+
+// APIToken lets a script or dashboard use the API without signing in.
+// Only a hash of the secret is kept; the secret is shown once, when the
+// token is created.
+// Key pattern: api_token:{token-id} in the config bucket
+type APIToken struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Scope      string    `json:"scope"`
+	SecretHash string    `json:"secret_hash"` // Hex SHA-256 of the secret
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at,omitempty"`
+}
+
+// AddAPIToken stores an API token, replacing one with the same ID.
+func (m *Manager) AddAPIToken(token *APIToken) error {
+	if token.ID == "" || token.SecretHash == "" {
+		return fmt.Errorf("API token must have ID and SecretHash")
+	}
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal API token: %w", err)
+	}
+
+	return m.update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketConfig).Put([]byte(apiTokenPrefix+token.ID), data)
+	})
+}
+
+// GetAPIToken returns an API token by ID, or nil if it does not exist.
+func (m *Manager) GetAPIToken(id string) (*APIToken, error) {
+	var token *APIToken
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(bucketConfig).Get([]byte(apiTokenPrefix + id))
+		if data == nil {
+			return nil
+		}
+		token = &APIToken{}
+		return json.Unmarshal(data, token)
+	})
+
+	return token, err
+}
+
+// ListAPITokens returns every API token, oldest first.
+func (m *Manager) ListAPITokens() ([]*APIToken, error) {
+	var tokens []*APIToken
+	prefix := []byte(apiTokenPrefix)
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(bucketConfig).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var token APIToken
+			if err := json.Unmarshal(v, &token); err != nil {
+				continue // Continue on marshal errors
+			}
+			tokens = append(tokens, &token)
+		}
+		return nil
+	})
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+	})
+	return tokens, err
+}
+
+// RemoveAPIToken deletes an API token, revoking it.
+func (m *Manager) RemoveAPIToken(id string) error {
+	return m.update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketConfig).Delete([]byte(apiTokenPrefix + id))
+	})
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// trickplayDirName is the directory next to a media file that holds its
+// seek preview images and their manifest.
+const trickplayDirName = ".trickplay"
+
+// trickplayManifestName is written last, so its presence means the images
+// it lists are all there.
+const trickplayManifestName = "manifest.json"
+
+// TrickplayManifest describes the seek preview images cached for a media
+// file: sprite sheets of evenly spaced thumbnails when Jellyfin generated
+// trickplay tiles, otherwise one image per chapter.
+type TrickplayManifest struct {
+	Tiles    *TrickplayTiles    `json:"tiles,omitempty"`
+	Chapters []TrickplayChapter `json:"chapters,omitempty"`
+	CachedAt time.Time          `json:"cached_at"`
+}
+
+// TrickplayTiles describes sprite sheets of thumbnails. Thumbnails run left
+// to right, then top to bottom, across sheets tile-0.jpg, tile-1.jpg, ...
+type TrickplayTiles struct {
+	Width          int           `json:"width"`  // Of one thumbnail, in pixels
+	Height         int           `json:"height"` // Of one thumbnail, in pixels
+	Columns        int           `json:"columns"`
+	Rows           int           `json:"rows"`
+	ThumbnailCount int           `json:"thumbnail_count"`
+	Interval       time.Duration `json:"interval"` // Between thumbnails
+	Sheets         int           `json:"sheets"`
+}
+
+// TrickplayChapter is a chapter with a cached image, chapter-{n}.jpg.
+type TrickplayChapter struct {
+	Name  string        `json:"name"`
+	Start time.Duration `json:"start"`
+	Image string        `json:"image"`
+}
+
+// TrickplayDir returns the directory seek previews of the media file at
+// mediaPath are cached in.
+func TrickplayDir(mediaPath string) string {
+	return filepath.Join(filepath.Dir(mediaPath), trickplayDirName)
+}
+
+// TrickplayTileName is the file name of sprite sheet index.
+func TrickplayTileName(index int) string {
+	return fmt.Sprintf("tile-%d.jpg", index)
+}
+
+// TrickplayChapterName is the file name of the image of chapter index.
+func TrickplayChapterName(index int) string {
+	return fmt.Sprintf("chapter-%d.jpg", index)
+}
+
+// WriteTrickplayManifest records the seek previews cached for the media
+// file at mediaPath. Write it after the images it lists.
+func (f *FileManager) WriteTrickplayManifest(mediaPath string, manifest *TrickplayManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal trickplay manifest: %w", err)
+	}
+	return f.WriteFileAtomic(filepath.Join(TrickplayDir(mediaPath), trickplayManifestName), data)
+}
+
+// ReadTrickplayManifest returns the seek previews cached for the media file
+// at mediaPath, or nil if there are none.
+func ReadTrickplayManifest(mediaPath string) (*TrickplayManifest, error) {
+	data, err := os.ReadFile(filepath.Join(TrickplayDir(mediaPath), trickplayManifestName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trickplay manifest: %w", err)
+	}
+
+	var manifest TrickplayManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal trickplay manifest: %w", err)
+	}
+	return &manifest, nil
+}
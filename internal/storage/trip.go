@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// tripKey is where the trip is kept in the config bucket.
+const tripKey = "trip"
+
+// Trip is trip mode: until Until, more episodes of active series are
+// cached, the cache may grow to MaxSizeGB and what the trip picked is
+// never evicted. Once it ends, the cache returns to NormalMaxSizeGB and
+// the extra content is evicted as usual.
+// Key pattern: trip in the config bucket
+type Trip struct {
+	StartedAt time.Time `json:"started_at"`
+	Until     time.Time `json:"until"`
+	Episodes  int       `json:"episodes"`  // Episodes ahead cached of each active series
+	Favorites bool      `json:"favorites"` // Whether every favorite movie is cached too
+	MaxSizeGB int       `json:"max_size_gb,omitempty"`
+	// NormalMaxSizeGB is the cache size limit restored when the trip ends
+	NormalMaxSizeGB int `json:"normal_max_size_gb,omitempty"`
+	// MediaIDs are the items the trip picked
+	MediaIDs []string `json:"media_ids"`
+}
+
+// Active reports whether the trip has not ended by now.
+func (t *Trip) Active(now time.Time) bool {
+	return t != nil && now.Before(t.Until)
+}
+
+// SaveTrip stores the trip, replacing any other.
+func (m *Manager) SaveTrip(trip *Trip) error {
+	data, err := json.Marshal(trip)
+	if err != nil {
+		return fmt.Errorf("failed to marshal trip: %w", err)
+	}
+
+	return m.update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketConfig).Put([]byte(tripKey), data)
+	})
+}
+
+// GetTrip returns the trip, or nil if none was started or it was ended.
+func (m *Manager) GetTrip() (*Trip, error) {
+	var trip *Trip
+
+	err := m.view(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(bucketConfig).Get([]byte(tripKey))
+		if data == nil {
+			return nil
+		}
+		trip = &Trip{}
+		return json.Unmarshal(data, trip)
+	})
+
+	return trip, err
+}
+
+// ClearTrip ends the trip.
+func (m *Manager) ClearTrip() error {
+	return m.update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketConfig).Delete([]byte(tripKey))
+	})
+}
+
+// tripMedia returns the items an active trip protects from eviction.
+func (m *Manager) tripMedia(now time.Time) map[string]bool {
+	trip, err := m.GetTrip()
+	if err != nil || !trip.Active(now) {
+		return nil
+	}
+	protected := make(map[string]bool, len(trip.MediaIDs))
+	for _, id := range trip.MediaIDs {
+		protected[id] = true
+	}
+	return protected
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// XXH64 primes.
+const (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime3 uint64 = 1609587929392839161
+	xxPrime4 uint64 = 9650029242287828579
+	xxPrime5 uint64 = 2870177450012600261
+)
+
+// xxh64 is a streaming XXH64 hash with a zero seed. It is a fast,
+// non-cryptographic checksum: good for catching corruption on disk, not for
+// detecting tampering.
+type xxh64 struct {
+	v1, v2, v3, v4 uint64
+	total          uint64
+	buf            [32]byte
+	n              int // bytes buffered in buf
+}
+
+// newXXH64 returns a new XXH64 hash.
+func newXXH64() hash.Hash64 {
+	h := &xxh64{}
+	h.Reset()
+	return h
+}
+
+func (h *xxh64) Reset() {
+	// The accumulators are meant to wrap, which constant arithmetic rejects
+	var seed uint64
+	h.v1 = seed + xxPrime1 + xxPrime2
+	h.v2 = seed + xxPrime2
+	h.v3 = seed
+	h.v4 = seed - xxPrime1
+	h.total = 0
+	h.n = 0
+}
+
+func (h *xxh64) Size() int      { return 8 }
+func (h *xxh64) BlockSize() int { return 32 }
+
+func (h *xxh64) Write(p []byte) (int, error) {
+	length := len(p)
+	h.total += uint64(length)
+
+	// Top up a partially filled stripe first
+	if h.n > 0 {
+		copied := copy(h.buf[h.n:], p)
+		h.n += copied
+		p = p[copied:]
+		if h.n < len(h.buf) {
+			return length, nil
+		}
+		h.stripe(h.buf[:])
+		h.n = 0
+	}
+
+	for len(p) >= 32 {
+		h.stripe(p[:32])
+		p = p[32:]
+	}
+
+	h.n = copy(h.buf[:], p)
+	return length, nil
+}
+
+// stripe consumes one 32-byte stripe.
+func (h *xxh64) stripe(p []byte) {
+	h.v1 = xxRound(h.v1, binary.LittleEndian.Uint64(p[0:8]))
+	h.v2 = xxRound(h.v2, binary.LittleEndian.Uint64(p[8:16]))
+	h.v3 = xxRound(h.v3, binary.LittleEndian.Uint64(p[16:24]))
+	h.v4 = xxRound(h.v4, binary.LittleEndian.Uint64(p[24:32]))
+}
+
+func (h *xxh64) Sum64() uint64 {
+	var sum uint64
+	if h.total >= 32 {
+		sum = bits.RotateLeft64(h.v1, 1) + bits.RotateLeft64(h.v2, 7) +
+			bits.RotateLeft64(h.v3, 12) + bits.RotateLeft64(h.v4, 18)
+		sum = xxMergeRound(sum, h.v1)
+		sum = xxMergeRound(sum, h.v2)
+		sum = xxMergeRound(sum, h.v3)
+		sum = xxMergeRound(sum, h.v4)
+	} else {
+		sum = xxPrime5
+	}
+	sum += h.total
+
+	p := h.buf[:h.n]
+	for ; len(p) >= 8; p = p[8:] {
+		sum ^= xxRound(0, binary.LittleEndian.Uint64(p))
+		sum = bits.RotateLeft64(sum, 27)*xxPrime1 + xxPrime4
+	}
+	if len(p) >= 4 {
+		sum ^= uint64(binary.LittleEndian.Uint32(p)) * xxPrime1
+		sum = bits.RotateLeft64(sum, 23)*xxPrime2 + xxPrime3
+		p = p[4:]
+	}
+	for _, b := range p {
+		sum ^= uint64(b) * xxPrime5
+		sum = bits.RotateLeft64(sum, 11) * xxPrime1
+	}
+
+	// Avalanche
+	sum ^= sum >> 33
+	sum *= xxPrime2
+	sum ^= sum >> 29
+	sum *= xxPrime3
+	sum ^= sum >> 32
+	return sum
+}
+
+// Sum appends the big-endian digest, XXH64's canonical representation.
+func (h *xxh64) Sum(b []byte) []byte {
+	return binary.BigEndian.AppendUint64(b, h.Sum64())
+}
+
+func xxRound(acc, input uint64) uint64 {
+	acc += input * xxPrime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * xxPrime1
+}
+
+func xxMergeRound(acc, val uint64) uint64 {
+	acc ^= xxRound(0, val)
+	return acc*xxPrime1 + xxPrime4
+}
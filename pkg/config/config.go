@@ -7,6 +7,8 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/knadh/koanf/parsers/yaml"
@@ -24,15 +26,54 @@ type Config struct {
 	Prediction PredictionConfig `koanf:"prediction"`
 	Logging    LoggingConfig    `koanf:"logging"`
 	UI         UIConfig         `koanf:"ui"`
+	// LocalLibrary exposes already-owned files alongside Jellyfin media
+	LocalLibrary  LocalLibraryConfig  `koanf:"local_library"`
+	Notifications NotificationsConfig `koanf:"notifications"`
+	Reports       ReportsConfig       `koanf:"reports"`
+	Maintenance   MaintenanceConfig   `koanf:"maintenance"`
+	// Chaos injects faults for resilience testing; only honored by
+	// binaries built with -tags chaos
+	Chaos ChaosConfig `koanf:"chaos"`
+	// Timezone is the IANA time zone (e.g. "Europe/Berlin") that peak
+	// hours, quiet hours, the maintenance window and the weekly report are
+	// evaluated in. Empty uses the host's local time zone.
+	Timezone string `koanf:"timezone"`
 }
 
 // JellyfinConfig contains Jellyfin server connection and authentication settings.
 type JellyfinConfig struct {
-	ServerURL     string        `koanf:"server_url"`
-	APIKey        string        `koanf:"api_key"`
-	UserID        string        `koanf:"user_id"`
+	ServerURL string `koanf:"server_url"`
+	APIKey    string `koanf:"api_key"`
+	// UserID is required with an API key; logging in fills it in.
+	UserID string `koanf:"user_id"`
+	// Username and Password log in instead of using an API key. The
+	// session token is kept in the cache database and renewed when the
+	// server rejects it.
+	Username string `koanf:"username"`
+	Password string `koanf:"password"`
+	// QuickConnect logs in by logging a code to approve in a signed-in
+	// Jellyfin app, when there is no username or the login is rejected.
+	QuickConnect  bool          `koanf:"quick_connect"`
 	Timeout       time.Duration `koanf:"timeout"`
 	RetryAttempts int           `koanf:"retry_attempts"`
+	// Libraries restricts which Jellyfin libraries are synced, predicted
+	// from and cached.
+	Libraries LibraryFilterConfig `koanf:"libraries"`
+	// LibrarySyncInterval is how often the movies, series and episodes of
+	// the libraries are mirrored into the metadata store, which is how the
+	// predictor learns about newly added items. 0 disables it.
+	LibrarySyncInterval time.Duration `koanf:"library_sync_interval"`
+	// ConnectivityCheckInterval is how often an unreachable server is
+	// probed to find out it is back.
+	ConnectivityCheckInterval time.Duration `koanf:"connectivity_check_interval"`
+}
+
+// LibraryFilterConfig selects Jellyfin libraries by name. When Include is
+// set only the listed libraries participate; libraries in Exclude never do.
+// Names are matched case-insensitively.
+type LibraryFilterConfig struct {
+	Include []string `koanf:"include"` // e.g. ["TV", "Movies"] (empty = all libraries)
+	Exclude []string `koanf:"exclude"` // e.g. ["Home Videos", "Music"]
 }
 
 // CacheConfig defines cache storage settings and limits.
@@ -42,25 +83,195 @@ type CacheConfig struct {
 	EvictionThreshold float64 `koanf:"eviction_threshold"`
 	MetadataStore     string  `koanf:"metadata_store"`
 	TempDirectory     string  `koanf:"temp_directory"`
+	MinFreeGB         int     `koanf:"min_free_gb"`        // Pause speculative downloads below this much free disk space; 0 means 10, negative turns it off
+	SmartDevice       string  `koanf:"smart_device"`       // Optional device for smartctl health checks, e.g. "/dev/sda"
+	ChecksumAlgorithm string  `koanf:"checksum_algorithm"` // sha256, blake3, xxhash or off
+	EvictionPolicy    string  `koanf:"eviction_policy"`    // lru, lfu, size or watched
+	// MetadataMaxAgeDays is how old the stored metadata of a cached item may
+	// get before it is re-fetched from Jellyfin in the background, picking
+	// up renames and corrected descriptions. 0 disables refreshing.
+	MetadataMaxAgeDays int `koanf:"metadata_max_age_days"`
+	// HistoryRetentionDays is how long viewing sessions are kept before
+	// maintenance deletes them.
+	HistoryRetentionDays int `koanf:"history_retention_days"`
+	// EventRetentionDays is how long the event log keeps entries before
+	// maintenance deletes them.
+	EventRetentionDays int `koanf:"event_retention_days"`
+	// NFOExport writes Kodi-compatible .nfo files next to cached movies and
+	// episodes, so the cache directory works as a standalone Kodi or Plex
+	// library while the service is down.
+	NFOExport bool `koanf:"nfo_export"`
+	// IntegrityScanInterval is how often the cache is checked against its
+	// download records: lost and corrupt files are downloaded again and
+	// files without a record deleted. 0 disables periodic scans.
+	IntegrityScanInterval time.Duration `koanf:"integrity_scan_interval"`
+	// Encryption encrypts cached media at rest.
+	Encryption CacheEncryptionConfig `koanf:"encryption"`
+	// Probe inspects completed downloads with ffprobe.
+	Probe CacheProbeConfig `koanf:"probe"`
+}
+
+// CacheEncryptionConfig encrypts completed downloads with AES-256-GCM, for
+// caches on laptops or removable drives that may be lost. The key is
+// either given as 64 hex digits or derived from a passphrase; set one.
+type CacheEncryptionConfig struct {
+	Enabled    bool   `koanf:"enabled"`
+	Key        string `koanf:"key"`
+	Passphrase string `koanf:"passphrase"`
+}
+
+// CacheProbeConfig runs ffprobe on each completed download and records its
+// duration, codecs, resolution, bitrate and tracks with the item's
+// metadata, for eviction and the UI. Encrypted caches are not inspected.
+type CacheProbeConfig struct {
+	Enabled     bool          `koanf:"enabled"`
+	FFprobePath string        `koanf:"ffprobe_path"` // ffprobe binary, looked up in PATH when not absolute
+	Timeout     time.Duration `koanf:"timeout"`      // Longest one file may take to inspect
 }
 
 // DownloadConfig controls download behavior, rate limiting, and scheduling.
 type DownloadConfig struct {
-	Workers                int                     `koanf:"workers"`
-	RateLimitMbps          int                     `koanf:"rate_limit_mbps"`
-	RateLimitSchedule      RateLimitScheduleConfig `koanf:"rate_limit_schedule"`
-	AutoDownloadCurrent    bool                    `koanf:"auto_download_current"`
-	AutoDownloadNext       bool                    `koanf:"auto_download_next"`
-	AutoDownloadCount      int                     `koanf:"auto_download_count"`
-	CurrentEpisodePriority bool                    `koanf:"current_episode_priority"`
-	RetryAttempts          int                     `koanf:"retry_attempts"`
-	RetryDelay             time.Duration           `koanf:"retry_delay"`
+	Workers                int                      `koanf:"workers"`
+	RateLimitMbps          int                      `koanf:"rate_limit_mbps"`
+	RateLimitSchedule      RateLimitScheduleConfig  `koanf:"rate_limit_schedule"`
+	RateLimitExemption     RateLimitExemptionConfig `koanf:"rate_limit_exemption"`
+	AutoDownloadCurrent    bool                     `koanf:"auto_download_current"`
+	AutoDownloadNext       bool                     `koanf:"auto_download_next"`
+	AutoDownloadCount      int                      `koanf:"auto_download_count"`
+	CurrentEpisodePriority bool                     `koanf:"current_episode_priority"`
+	RetryAttempts          int                      `koanf:"retry_attempts"`
+	RetryDelay             time.Duration            `koanf:"retry_delay"`
+	InterfaceBindings      []InterfaceBindingConfig `koanf:"interface_bindings"`
+	// ReadAheadMB is the opening segment of the next episode or track that
+	// is fetched ahead of everything else while a cached item plays, so a
+	// binge can move on before the whole file is down. 0 disables it.
+	ReadAheadMB int `koanf:"read_ahead_mb"`
+	// StallTimeout aborts a download that receives no data for this long,
+	// so a hung connection is retried (resuming where it stopped) instead
+	// of holding a worker until the request times out.
+	StallTimeout time.Duration `koanf:"stall_timeout"`
+	// MinThroughputKBps aborts and retries a download averaging less than
+	// this over a minute while its bandwidth share allows more. 0 disables.
+	MinThroughputKBps int `koanf:"min_throughput_kbps"`
+	// QueueLimits caps how much may wait in the queue at the listed
+	// priorities, so a misbehaving predictor cannot queue without bound.
+	QueueLimits []QueueLimitConfig `koanf:"queue_limits"`
+	// EvictToFit lets Priority 0-2 downloads that would overfill the cache
+	// evict cached items to make room instead of being refused.
+	EvictToFit bool `koanf:"evict_to_fit"`
+	// MaxDailyGB caps how much is downloaded per calendar day. Once it is
+	// reached only Priority 0-1 downloads start until midnight. 0 disables.
+	MaxDailyGB int `koanf:"max_daily_gb"`
+	// WatchTimeScheduling holds Priority 3-4 downloads of at least
+	// LargeDownloadMB back during the hours users usually start watching,
+	// learned from viewing history, so they finish beforehand. Once those
+	// hours are known they replace RateLimitSchedule's peak hours.
+	WatchTimeScheduling bool `koanf:"watch_time_scheduling"`
+	// LargeDownloadMB is the size from which speculative downloads wait
+	// for viewing windows to end.
+	LargeDownloadMB int `koanf:"large_download_mb"`
+	// Segments splits downloads of at least SegmentMinMB into this many
+	// byte ranges fetched in parallel, to fill fast links that a single
+	// connection cannot. 0 or 1 downloads over one connection.
+	Segments int `koanf:"segments"`
+	// SegmentMinMB is the size from which downloads are segmented.
+	SegmentMinMB int `koanf:"segment_min_mb"`
+	// SubscriptionPriority is the priority (1-4) new episodes of subscribed
+	// series are queued at, regardless of what the predictor thinks.
+	SubscriptionPriority int `koanf:"subscription_priority"`
+	// HTTP tunes the connections downloads are made over.
+	HTTP DownloadHTTPConfig `koanf:"http"`
+	// LocalSource takes files straight from Jellyfin's media folders when
+	// they are reachable from this host, instead of downloading them.
+	LocalSource LocalSourceConfig `koanf:"local_source"`
+}
+
+// LocalSourceConfig lets downloads link or copy the server's own files
+// when go-jf-watch runs on the Jellyfin host or has its media mounted.
+type LocalSourceConfig struct {
+	Enabled bool `koanf:"enabled"`
+	// Mode is "auto" to try a reflink, then a hardlink, then a copy, or
+	// one of "reflink", "hardlink" and "copy" to only try that. Files that
+	// cannot be taken are downloaded over HTTP as usual.
+	Mode string `koanf:"mode"`
+	// PathMappings translate the paths Jellyfin reports into paths on this
+	// host. The longest matching prefix wins; other paths are used as they
+	// are.
+	PathMappings []PathMappingConfig `koanf:"path_mappings"`
+}
+
+// PathMappingConfig maps a folder as Jellyfin sees it to the same folder
+// on this host.
+type PathMappingConfig struct {
+	From string `koanf:"from"`
+	To   string `koanf:"to"`
+}
+
+// DownloadHTTPConfig tunes the pooled connections downloads share.
+type DownloadHTTPConfig struct {
+	// MaxConnsPerHost caps concurrent downloads from one server; Priority
+	// 0 downloads are exempt. 0 means no cap.
+	MaxConnsPerHost int `koanf:"max_conns_per_host"`
+	// IdleConnTimeout is how long an unused pooled connection is kept.
+	IdleConnTimeout time.Duration `koanf:"idle_conn_timeout"`
+	// DisableHTTP2 keeps downloads on HTTP/1.1 even when the server
+	// offers HTTP/2.
+	DisableHTTP2 bool `koanf:"disable_http2"`
+	// MinTLSVersion is "1.2" or "1.3".
+	MinTLSVersion string `koanf:"min_tls_version"`
+	// CAFile is a PEM file of extra certificate authorities to trust, e.g.
+	// for a Jellyfin server with a self-signed certificate.
+	CAFile string `koanf:"ca_file"`
+	// InsecureSkipVerify turns off certificate verification entirely.
+	InsecureSkipVerify bool `koanf:"insecure_skip_verify"`
 }
 
 // RateLimitScheduleConfig defines peak/off-peak bandwidth scheduling.
 type RateLimitScheduleConfig struct {
 	PeakHours        string `koanf:"peak_hours"`
 	PeakLimitPercent int    `koanf:"peak_limit_percent"`
+	// Windows restrict when downloads may start. A priority listed in any
+	// window only starts during one of its windows; priorities no window
+	// lists start at any time. Downloads already running when a window
+	// closes finish.
+	Windows []DownloadWindowConfig `koanf:"windows"`
+
+	loc *time.Location
+}
+
+// DownloadWindowConfig lets downloads of the listed priorities start during
+// Hours, e.g. Priority 3-4 only between 01:00 and 06:00.
+type DownloadWindowConfig struct {
+	Priorities []int  `koanf:"priorities"` // Priority classes (1-4) this window applies to
+	Hours      string `koanf:"hours"`      // HH:MM-HH:MM, crossing midnight if need be, or "always"
+}
+
+// alwaysWindow is the Hours of a window that never closes.
+const alwaysWindow = "always"
+
+// RateLimitExemptionConfig lists networks where downloads bypass bandwidth
+// limiting, such as a Jellyfin server on the local LAN.
+type RateLimitExemptionConfig struct {
+	AutoDetectLAN bool     `koanf:"auto_detect_lan"` // Exempt private, loopback and link-local addresses
+	CIDRs         []string `koanf:"cidrs"`           // Additional exempt networks, e.g. "10.8.0.0/24"
+}
+
+// InterfaceBindingConfig routes downloads of the listed priorities through a
+// specific network interface or source IP. Exactly one of Interface and
+// SourceIP must be set.
+type InterfaceBindingConfig struct {
+	Priorities []int  `koanf:"priorities"` // Priority classes (0-4) this binding applies to
+	Interface  string `koanf:"interface"`  // Interface name, e.g. "wg0"
+	SourceIP   string `koanf:"source_ip"`  // Local address to bind, e.g. "192.168.2.10"
+}
+
+// QueueLimitConfig caps the queued (not yet downloading) items of a
+// priority class. The caps apply to the listed priorities together; 0
+// leaves a cap unset.
+type QueueLimitConfig struct {
+	Priorities []int   `koanf:"priorities"` // Priority classes (0-4) sharing these caps
+	MaxItems   int     `koanf:"max_items"`  // Most items queued at once
+	MaxGB      float64 `koanf:"max_gb"`     // Most bytes queued at once, by known item size
 }
 
 // ServerConfig contains HTTP server settings.
@@ -70,6 +281,67 @@ type ServerConfig struct {
 	ReadTimeout       time.Duration `koanf:"read_timeout"`
 	WriteTimeout      time.Duration `koanf:"write_timeout"`
 	EnableCompression bool          `koanf:"enable_compression"`
+	EnableMetrics     bool          `koanf:"enable_metrics"` // Serve Prometheus metrics on /metrics
+	WebhookToken      string        `koanf:"webhook_token"`  // Required in X-Webhook-Token by /api/webhooks/jellyfin when set
+	WebDAV            WebDAVConfig  `koanf:"webdav"`
+	DLNA              DLNAConfig    `koanf:"dlna"`
+	HLS               HLSConfig     `koanf:"hls"`
+	Sharing           SharingConfig `koanf:"sharing"`
+	Kiosk             KioskConfig   `koanf:"kiosk"`
+	Auth              AuthConfig    `koanf:"auth"`
+}
+
+// WebDAVConfig controls the read-only WebDAV export of the cache.
+type WebDAVConfig struct {
+	Enabled  bool   `koanf:"enabled"`
+	Port     int    `koanf:"port"` // Separate listener port (0 = share the main server port)
+	Path     string `koanf:"path"` // URL prefix the share is served under
+	Username string `koanf:"username"`
+	Password string `koanf:"password"`
+}
+
+// DLNAConfig controls the DLNA/UPnP media server that lets smart TVs on
+// the LAN browse and play the cache. It has no authentication.
+type DLNAConfig struct {
+	Enabled      bool   `koanf:"enabled"`
+	Port         int    `koanf:"port"`          // Listener for the device description, control and media URLs
+	FriendlyName string `koanf:"friendly_name"` // Name TVs list the server under
+}
+
+// HLSConfig controls on-demand HLS repackaging of cached files for players
+// that cannot stream them progressively, such as Safari on iOS with MKV.
+type HLSConfig struct {
+	Enabled         bool          `koanf:"enabled"`
+	FFmpegPath      string        `koanf:"ffmpeg_path"`      // ffmpeg binary, looked up in PATH when not absolute
+	SegmentDuration time.Duration `koanf:"segment_duration"` // Target length of a segment
+}
+
+// SharingConfig controls time-limited public share links for cached items.
+type SharingConfig struct {
+	Enabled bool          `koanf:"enabled"`
+	Port    int           `koanf:"port"`     // Separate listener serving only share links (0 = share the main server port)
+	MaxTTL  time.Duration `koanf:"max_ttl"`  // Longest lifetime a share link may be given
+	BaseURL string        `koanf:"base_url"` // Public URL prefix for generated links, e.g. "https://share.example.com"
+}
+
+// KioskConfig locks the web UI down to a simple player of cached content,
+// for guests and children on a travel laptop. Entering the PIN unlocks the
+// full interface in that browser.
+type KioskConfig struct {
+	Enabled bool   `koanf:"enabled"`
+	PIN     string `koanf:"pin"` // 4 to 12 digits
+}
+
+// AuthConfig puts the web UI and API behind a sign-in. Without a mode
+// everything is open to anyone who can reach the server.
+type AuthConfig struct {
+	Mode           string        `koanf:"mode"`            // "", password, proxy or jellyfin
+	Password       string        `koanf:"password"`        // Shared password, for mode password
+	ProxyHeader    string        `koanf:"proxy_header"`    // Header a trusted proxy names the signed-in user in, for mode proxy
+	TrustedProxies []string      `koanf:"trusted_proxies"` // IPs or CIDRs the proxy header is accepted from
+	SessionTTL     time.Duration `koanf:"session_ttl"`     // How long a sign-in lasts
+	SessionSecret  string        `koanf:"session_secret"`  // Key signing session cookies; random per start when empty, signing everyone out on restart
+	ProtectReads   bool          `koanf:"protect_reads"`   // Require sign-in to view anything, not just to change things
 }
 
 // PredictionConfig controls predictive download behavior.
@@ -78,6 +350,143 @@ type PredictionConfig struct {
 	SyncInterval  time.Duration `koanf:"sync_interval"`
 	HistoryDays   int           `koanf:"history_days"`
 	MinConfidence float64       `koanf:"min_confidence"`
+	// SpeculativeTTL is how long a Priority 3-4 download may sit in the queue
+	// without starting before it is dropped as no longer relevant.
+	SpeculativeTTL time.Duration `koanf:"speculative_ttl"`
+	// PreferredLanguages overrides the audio languages derived from viewing
+	// history (ISO 639 codes, most preferred first).
+	PreferredLanguages []string `koanf:"preferred_languages"`
+	// SeasonalRules adjust the confidence of Priority 4 trending predictions
+	// in matching genres during a recurring date range.
+	SeasonalRules []SeasonalRule `koanf:"seasonal_rules"`
+	// AdaptiveQuality caches predicted items in the variant suited to the
+	// device the user is expected to watch on next, instead of the original.
+	AdaptiveQuality bool `koanf:"adaptive_quality"`
+	// DeviceQuality maps a device class (phone, tablet, tv, desktop) to the
+	// quality cached for it when AdaptiveQuality is enabled.
+	DeviceQuality map[string]string `koanf:"device_quality"`
+	// HouseholdUsers lists the Jellyfin user IDs of everyone sharing this
+	// cache. Each gets their own predictions, but items wanted by several
+	// users are cached once and kept while any of them still wants them.
+	HouseholdUsers []string `koanf:"household_users"`
+	// WarmNextUp caches the user's Jellyfin Next Up list every sync,
+	// independently of the behavioral predictor.
+	WarmNextUp bool `koanf:"warm_next_up"`
+	// WarmFavorites also caches everything the user marked as a favorite.
+	WarmFavorites bool `koanf:"warm_favorites"`
+	// NextUpLimit caps how many Next Up items are warmed.
+	NextUpLimit int `koanf:"next_up_limit"`
+	// SessionSyncInterval is how often playback on the Jellyfin server is
+	// polled into the viewing history, so predictions follow what is
+	// watched on every client rather than only streams served from the
+	// cache. 0 disables it.
+	SessionSyncInterval time.Duration `koanf:"session_sync_interval"`
+	// AccuracyDays is how long after a predicted download finishes it has
+	// to be watched to count as a hit rather than wasted. Per-priority hit
+	// rates scale down the confidence of future predictions.
+	AccuracyDays int `koanf:"accuracy_days"`
+	// RecentlyAddedDays is how long after being added to Jellyfin an item
+	// is still suggested as recently added content (Priority 3).
+	RecentlyAddedDays int `koanf:"recently_added_days"`
+	// MaxSpeculativeGB caps the space speculative (Priority 3-4) predicted
+	// downloads may take up, cached and queued together. 0 means no cap
+	// beyond the cache size itself.
+	MaxSpeculativeGB int `koanf:"max_speculative_gb"`
+	// BingeRateThreshold is the average number of episodes per viewing day
+	// above which upcoming episodes are prefetched BingeEpisodes ahead.
+	BingeRateThreshold float64 `koanf:"binge_rate_threshold"`
+	// BingeEpisodes is how many episodes past the last one watched are
+	// prefetched for binge watchers, across season boundaries.
+	BingeEpisodes int `koanf:"binge_episodes"`
+	// WatchlistPriority is the priority unwatched favorites, the user's
+	// Jellyfin watchlist, are predicted at. They rank ahead of recently
+	// added content at the same priority.
+	WatchlistPriority int `koanf:"watchlist_priority"`
+	// ServerNextUp merges the user's Next Up and resume lists from
+	// Jellyfin into Priority 1 predictions, so continue watching follows
+	// the server's own algorithm rather than local guesses alone.
+	ServerNextUp bool `koanf:"server_next_up"`
+}
+
+// SeasonalRule boosts (or dampens, with a negative boost) trending content
+// in the listed genres between Start and End each year. Dates use MM-DD
+// format and ranges may wrap the year end (e.g. 12-20 to 01-02).
+type SeasonalRule struct {
+	Name   string   `koanf:"name"`
+	Start  string   `koanf:"start"`
+	End    string   `koanf:"end"`
+	Genres []string `koanf:"genres"`
+	Boost  float64  `koanf:"boost"`
+}
+
+// ChaosConfig controls fault injection. Rates are probabilities between 0
+// and 1 applied independently to each download, storage call or request.
+type ChaosConfig struct {
+	Enabled             bool          `koanf:"enabled"`
+	Seed                int64         `koanf:"seed"`                  // Random seed for reproducible runs (0 = time-based)
+	DownloadFailureRate float64       `koanf:"download_failure_rate"` // Downloads that fail part-way through
+	SlowReadDelay       time.Duration `koanf:"slow_read_delay"`       // Delay added to every download read
+	StorageErrorRate    float64       `koanf:"storage_error_rate"`    // Queue and record writes that fail
+	JellyfinErrorRate   float64       `koanf:"jellyfin_error_rate"`   // Jellyfin requests answered with HTTP 500
+}
+
+// LocalLibraryConfig configures the read-only local folder media provider.
+type LocalLibraryConfig struct {
+	Directory string `koanf:"directory"` // Folder of video files to serve in place (empty to disable)
+}
+
+// NotificationsConfig configures the sinks notifications are delivered to.
+type NotificationsConfig struct {
+	Email      EmailConfig      `koanf:"email"`
+	Webhook    WebhookConfig    `koanf:"webhook"`
+	QuietHours QuietHoursConfig `koanf:"quiet_hours"`
+}
+
+// QuietHoursConfig defines a daily window during which non-critical
+// notifications are held back and delivered afterwards as a digest.
+type QuietHoursConfig struct {
+	Enabled bool   `koanf:"enabled"`
+	Start   string `koanf:"start"` // Local time of day in HH:MM format
+	End     string `koanf:"end"`   // Local time of day in HH:MM format, may be before Start
+
+	loc *time.Location
+}
+
+// EmailConfig configures delivery of notifications over SMTP.
+type EmailConfig struct {
+	Enabled  bool     `koanf:"enabled"`
+	SMTPHost string   `koanf:"smtp_host"`
+	SMTPPort int      `koanf:"smtp_port"`
+	Username string   `koanf:"username"`
+	Password string   `koanf:"password"`
+	From     string   `koanf:"from"`
+	To       []string `koanf:"to"`
+}
+
+// WebhookConfig configures delivery of notifications as JSON POST requests.
+type WebhookConfig struct {
+	Enabled bool   `koanf:"enabled"`
+	URL     string `koanf:"url"`
+}
+
+// ReportsConfig controls the scheduled weekly activity report.
+type ReportsConfig struct {
+	WeeklyEnabled bool   `koanf:"weekly_enabled"`
+	Weekday       string `koanf:"weekday"` // Day the report is generated, e.g. "sunday"
+	Time          string `koanf:"time"`    // Local time of day in HH:MM format
+
+	loc *time.Location
+}
+
+// MaintenanceConfig defines the daily window in which expensive background
+// work (cache verification, database compaction, queue reconciliation and
+// non-emergency evictions) is allowed to run.
+type MaintenanceConfig struct {
+	Enabled bool   `koanf:"enabled"`
+	Start   string `koanf:"start"` // Local time of day in HH:MM format
+	End     string `koanf:"end"`   // Local time of day in HH:MM format, may be before Start
+
+	loc *time.Location
 }
 
 // LoggingConfig defines logging behavior and output format.
@@ -93,16 +502,44 @@ type UIConfig struct {
 	Theme                  string `koanf:"theme"`
 	Language               string `koanf:"language"`
 	VideoQualityPreference string `koanf:"video_quality_preference"`
+	// DeviceProfiles caps what is streamed to each device, keyed by the
+	// name a player sends in the device query parameter or the
+	// X-Device-Profile header, or by the class guessed from its User-Agent
+	// (phone, tablet, tv, desktop). A cached file above the cap is
+	// replaced by a Jellyfin transcode; devices without a profile get the
+	// cached file as is.
+	DeviceProfiles map[string]DeviceProfileConfig `koanf:"device_profiles"`
+}
+
+// DeviceProfileConfig limits the quality streamed to a device.
+type DeviceProfileConfig struct {
+	// MaxQuality is original, 1080p, 720p or 480p
+	MaxQuality string `koanf:"max_quality"`
+	// MaxBitrateMbps caps the video bitrate; 0 for no cap
+	MaxBitrateMbps float64 `koanf:"max_bitrate_mbps"`
 }
 
 // Load reads configuration from the specified YAML file and applies validation.
 // Returns a validated Config struct or an error if loading/validation fails.
-func Load(configPath string) (*Config, error) {
+//
+// Values are layered, each overriding the ones before: defaults, the
+// config file, JFWATCH_ environment variables (see EnvName) and flags in
+// args such as --download.workers=4. An empty configPath skips the file,
+// so a container can be configured from its environment alone.
+func Load(configPath string, args ...string) (*Config, error) {
 	k := koanf.New(".")
 
 	// Load configuration from YAML file
-	if err := k.Load(file.Provider(configPath), yaml.Parser()); err != nil {
-		return nil, fmt.Errorf("failed to load config from %s: %w", configPath, err)
+	if configPath != "" {
+		if err := k.Load(file.Provider(configPath), yaml.Parser()); err != nil {
+			return nil, fmt.Errorf("failed to load config from %s: %w", configPath, err)
+		}
+	}
+	if err := k.Load(envProvider(), nil); err != nil {
+		return nil, fmt.Errorf("failed to load config from environment: %w", err)
+	}
+	if err := loadFlags(k, args); err != nil {
+		return nil, err
 	}
 
 	var config Config
@@ -117,10 +554,53 @@ func Load(configPath string) (*Config, error) {
 	if err := validate(&config); err != nil {
 		return nil, fmt.Errorf("config validation failed: %w", err)
 	}
+	config.applyTimezone()
 
 	return &config, nil
 }
 
+// Default returns a configuration with every default applied, for programs
+// that build their configuration in code instead of loading a file. The
+// Jellyfin server URL and credentials still have to be filled in.
+func Default() *Config {
+	var config Config
+	applyDefaults(&config)
+	config.applyTimezone()
+	return &config
+}
+
+// Validate checks a configuration built in code the same way Load checks
+// one read from a file.
+func (c *Config) Validate() error {
+	if err := validate(c); err != nil {
+		return fmt.Errorf("config validation failed: %w", err)
+	}
+	c.applyTimezone()
+	return nil
+}
+
+// Location returns the time zone named by Timezone, or time.Local when it
+// is empty.
+func (c *Config) Location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(c.Timezone)
+}
+
+// applyTimezone points every daily window and schedule at the configured
+// time zone. An invalid zone is left for validation to report.
+func (c *Config) applyTimezone() {
+	loc, err := c.Location()
+	if err != nil {
+		return
+	}
+	c.Download.RateLimitSchedule.loc = loc
+	c.Notifications.QuietHours.loc = loc
+	c.Reports.loc = loc
+	c.Maintenance.loc = loc
+}
+
 // applyDefaults sets sensible defaults for configuration values that weren't specified.
 func applyDefaults(config *Config) {
 	// Jellyfin defaults
@@ -130,6 +610,9 @@ func applyDefaults(config *Config) {
 	if config.Jellyfin.RetryAttempts == 0 {
 		config.Jellyfin.RetryAttempts = 3
 	}
+	if config.Jellyfin.ConnectivityCheckInterval == 0 {
+		config.Jellyfin.ConnectivityCheckInterval = 30 * time.Second
+	}
 
 	// Cache defaults
 	if config.Cache.Directory == "" {
@@ -147,6 +630,21 @@ func applyDefaults(config *Config) {
 	if config.Cache.TempDirectory == "" {
 		config.Cache.TempDirectory = filepath.Join(config.Cache.Directory, "temp")
 	}
+	if config.Cache.MinFreeGB == 0 {
+		config.Cache.MinFreeGB = 10
+	}
+	if config.Cache.ChecksumAlgorithm == "" {
+		config.Cache.ChecksumAlgorithm = "sha256"
+	}
+	if config.Cache.EvictionPolicy == "" {
+		config.Cache.EvictionPolicy = "lru"
+	}
+	if config.Cache.Probe.FFprobePath == "" {
+		config.Cache.Probe.FFprobePath = "ffprobe"
+	}
+	if config.Cache.Probe.Timeout == 0 {
+		config.Cache.Probe.Timeout = 30 * time.Second
+	}
 
 	// Download defaults
 	if config.Download.Workers == 0 {
@@ -161,6 +659,33 @@ func applyDefaults(config *Config) {
 	if config.Download.RateLimitSchedule.PeakLimitPercent == 0 {
 		config.Download.RateLimitSchedule.PeakLimitPercent = 25
 	}
+	if config.Download.HTTP.MaxConnsPerHost == 0 {
+		config.Download.HTTP.MaxConnsPerHost = 4
+	}
+	if config.Download.HTTP.IdleConnTimeout == 0 {
+		config.Download.HTTP.IdleConnTimeout = 90 * time.Second
+	}
+	if config.Download.HTTP.MinTLSVersion == "" {
+		config.Download.HTTP.MinTLSVersion = "1.2"
+	}
+	if config.Download.LocalSource.Mode == "" {
+		config.Download.LocalSource.Mode = "auto"
+	}
+	if config.Download.LargeDownloadMB == 0 {
+		config.Download.LargeDownloadMB = 1024
+	}
+	if config.Download.SegmentMinMB == 0 {
+		config.Download.SegmentMinMB = 1024
+	}
+	if config.Cache.HistoryRetentionDays == 0 {
+		config.Cache.HistoryRetentionDays = 365
+	}
+	if config.Cache.EventRetentionDays == 0 {
+		config.Cache.EventRetentionDays = 90
+	}
+	if config.Download.SubscriptionPriority == 0 {
+		config.Download.SubscriptionPriority = 2
+	}
 	if config.Download.AutoDownloadCount == 0 {
 		config.Download.AutoDownloadCount = 2
 	}
@@ -170,6 +695,9 @@ func applyDefaults(config *Config) {
 	if config.Download.RetryDelay == 0 {
 		config.Download.RetryDelay = 1 * time.Second
 	}
+	if config.Download.StallTimeout == 0 {
+		config.Download.StallTimeout = 60 * time.Second
+	}
 
 	// Server defaults
 	if config.Server.Port == 0 {
@@ -184,6 +712,57 @@ func applyDefaults(config *Config) {
 	if config.Server.WriteTimeout == 0 {
 		config.Server.WriteTimeout = 15 * time.Second
 	}
+	if config.Server.WebDAV.Path == "" {
+		config.Server.WebDAV.Path = "/dav"
+	}
+	if config.Server.DLNA.Port == 0 {
+		config.Server.DLNA.Port = 8200
+	}
+	if config.Server.DLNA.FriendlyName == "" {
+		config.Server.DLNA.FriendlyName = "go-jf-watch"
+	}
+	if config.Server.HLS.FFmpegPath == "" {
+		config.Server.HLS.FFmpegPath = "ffmpeg"
+	}
+	if config.Server.HLS.SegmentDuration == 0 {
+		config.Server.HLS.SegmentDuration = 6 * time.Second
+	}
+	if config.Server.Sharing.MaxTTL == 0 {
+		config.Server.Sharing.MaxTTL = 7 * 24 * time.Hour
+	}
+	if config.Server.Auth.ProxyHeader == "" {
+		config.Server.Auth.ProxyHeader = "Remote-User"
+	}
+	if config.Server.Auth.SessionTTL == 0 {
+		config.Server.Auth.SessionTTL = 7 * 24 * time.Hour
+	}
+
+	// Notification defaults
+	if config.Notifications.Email.SMTPPort == 0 {
+		config.Notifications.Email.SMTPPort = 587
+	}
+
+	// Report defaults
+	if config.Notifications.QuietHours.Start == "" {
+		config.Notifications.QuietHours.Start = "22:00"
+	}
+	if config.Notifications.QuietHours.End == "" {
+		config.Notifications.QuietHours.End = "07:00"
+	}
+
+	if config.Maintenance.Start == "" {
+		config.Maintenance.Start = "03:00"
+	}
+	if config.Maintenance.End == "" {
+		config.Maintenance.End = "05:00"
+	}
+
+	if config.Reports.Weekday == "" {
+		config.Reports.Weekday = "sunday"
+	}
+	if config.Reports.Time == "" {
+		config.Reports.Time = "09:00"
+	}
 
 	// Prediction defaults
 	if config.Prediction.SyncInterval == 0 {
@@ -195,6 +774,35 @@ func applyDefaults(config *Config) {
 	if config.Prediction.MinConfidence == 0 {
 		config.Prediction.MinConfidence = 0.7
 	}
+	if config.Prediction.NextUpLimit == 0 {
+		config.Prediction.NextUpLimit = 10
+	}
+	if config.Prediction.RecentlyAddedDays == 0 {
+		config.Prediction.RecentlyAddedDays = 14
+	}
+	if config.Prediction.BingeRateThreshold == 0 {
+		config.Prediction.BingeRateThreshold = 2.0
+	}
+	if config.Prediction.BingeEpisodes == 0 {
+		config.Prediction.BingeEpisodes = 5
+	}
+	if config.Prediction.WatchlistPriority == 0 {
+		config.Prediction.WatchlistPriority = 3
+	}
+	if config.Prediction.AccuracyDays == 0 {
+		config.Prediction.AccuracyDays = 7
+	}
+	if config.Prediction.SpeculativeTTL == 0 {
+		config.Prediction.SpeculativeTTL = 7 * 24 * time.Hour
+	}
+	if config.Prediction.DeviceQuality == nil {
+		config.Prediction.DeviceQuality = map[string]string{
+			"phone":   "720p",
+			"tablet":  "1080p",
+			"tv":      "original",
+			"desktop": "original",
+		}
+	}
 
 	// Logging defaults
 	if config.Logging.Level == "" {
@@ -217,6 +825,12 @@ func applyDefaults(config *Config) {
 	if config.UI.VideoQualityPreference == "" {
 		config.UI.VideoQualityPreference = "original"
 	}
+	for name, profile := range config.UI.DeviceProfiles {
+		if profile.MaxQuality == "" {
+			profile.MaxQuality = "original"
+			config.UI.DeviceProfiles[name] = profile
+		}
+	}
 }
 
 // GetLogLevel converts the string log level to slog.Level.
@@ -236,6 +850,253 @@ func (c *LoggingConfig) GetLogLevel() slog.Level {
 	}
 }
 
+// IsActive reports whether t falls within the rule's date range, inclusive.
+// Returns false if the rule's dates cannot be parsed.
+func (r *SeasonalRule) IsActive(t time.Time) bool {
+	start, err := time.Parse("01-02", r.Start)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse("01-02", r.End)
+	if err != nil {
+		return false
+	}
+
+	day := int(t.Month())*100 + t.Day()
+	startDay := int(start.Month())*100 + start.Day()
+	endDay := int(end.Month())*100 + end.Day()
+
+	// Handle ranges that span the year end
+	if startDay > endDay {
+		return day >= startDay || day <= endDay
+	}
+
+	return day >= startDay && day <= endDay
+}
+
+// IsPeak reports whether t falls within the peak hours window. Windows that
+// cross midnight (e.g. 18:00-02:00) are supported; the end time is
+// exclusive.
+func (r *RateLimitScheduleConfig) IsPeak(t time.Time) bool {
+	start, end, ok := strings.Cut(r.PeakHours, "-")
+	if !ok {
+		return false
+	}
+	_, _, active := dailyWindow(strings.TrimSpace(start), strings.TrimSpace(end), t, r.loc)
+	return active
+}
+
+// Restricted reports whether any window lists priority, so downloads of it
+// only start during its windows.
+func (r *RateLimitScheduleConfig) Restricted(priority int) bool {
+	for _, window := range r.Windows {
+		if slices.Contains(window.Priorities, priority) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsStart reports whether a download of priority may start at t.
+func (r *RateLimitScheduleConfig) AllowsStart(priority int, t time.Time) bool {
+	restricted := false
+	for _, window := range r.Windows {
+		if !slices.Contains(window.Priorities, priority) {
+			continue
+		}
+		restricted = true
+		if window.contains(t, r.loc) {
+			return true
+		}
+	}
+	return !restricted
+}
+
+// NextStart returns when a download of priority may next start at or after
+// t: t itself when it may start now, or the zero time when no window ever
+// opens for it.
+func (r *RateLimitScheduleConfig) NextStart(priority int, t time.Time) time.Time {
+	if r.AllowsStart(priority, t) {
+		return t
+	}
+
+	var next time.Time
+	for _, window := range r.Windows {
+		if !slices.Contains(window.Priorities, priority) {
+			continue
+		}
+		if opens := window.nextOpening(t, r.loc); !opens.IsZero() && (next.IsZero() || opens.Before(next)) {
+			next = opens
+		}
+	}
+	return next
+}
+
+// contains reports whether t falls within the window, evaluated in loc.
+func (w *DownloadWindowConfig) contains(t time.Time, loc *time.Location) bool {
+	if w.Hours == alwaysWindow {
+		return true
+	}
+	start, end, ok := strings.Cut(w.Hours, "-")
+	if !ok {
+		return false
+	}
+	_, _, active := dailyWindow(strings.TrimSpace(start), strings.TrimSpace(end), t, loc)
+	return active
+}
+
+// nextOpening returns the first time after t the window opens, evaluated
+// in loc, or the zero time for a malformed window.
+func (w *DownloadWindowConfig) nextOpening(t time.Time, loc *time.Location) time.Time {
+	startTime, _, ok := strings.Cut(w.Hours, "-")
+	if !ok {
+		return time.Time{}
+	}
+	start, err := time.Parse("15:04", strings.TrimSpace(startTime))
+	if err != nil {
+		return time.Time{}
+	}
+
+	if loc != nil {
+		t = t.In(loc)
+	}
+	year, month, day := t.Date()
+	opens := wallClock(year, month, day, start, t.Location())
+	if !opens.After(t) {
+		opens = wallClock(year, month, day+1, start, t.Location())
+	}
+	return opens
+}
+
+// IsActive reports whether t falls within the quiet hours window.
+// Windows that cross midnight (e.g. 22:00-07:00) are supported; the end time
+// is exclusive.
+func (q *QuietHoursConfig) IsActive(t time.Time) bool {
+	if !q.Enabled {
+		return false
+	}
+	_, _, active := dailyWindow(q.Start, q.End, t, q.loc)
+	return active
+}
+
+// InZone returns t in the time zone the report schedule is evaluated in.
+func (r *ReportsConfig) InZone(t time.Time) time.Time {
+	if r.loc == nil {
+		return t
+	}
+	return t.In(r.loc)
+}
+
+// IsActive reports whether t falls within the maintenance window. A
+// disabled window never restricts work, so it is always active. Windows
+// that cross midnight are supported; the end time is exclusive.
+func (m *MaintenanceConfig) IsActive(t time.Time) bool {
+	if !m.Enabled {
+		return true
+	}
+	_, _, active := dailyWindow(m.Start, m.End, t, m.loc)
+	return active
+}
+
+// WindowEnd returns when the maintenance window containing t closes. It
+// returns the zero time when the window is disabled or t is outside it.
+func (m *MaintenanceConfig) WindowEnd(t time.Time) time.Time {
+	if !m.Enabled {
+		return time.Time{}
+	}
+	_, closes, active := dailyWindow(m.Start, m.End, t, m.loc)
+	if !active {
+		return time.Time{}
+	}
+	return closes
+}
+
+// dailyWindow finds the occurrence of the daily window between the HH:MM
+// times start and end that contains t, evaluated in loc (t's own location
+// when loc is nil). End may be before start for windows that cross
+// midnight, and is exclusive.
+//
+// Occurrences are compared as instants rather than as times of day, so
+// DST transitions neither skip nor repeat a window: a window opening or
+// closing in the hour skipped when clocks go forward is moved to just
+// after the jump, and one spanning the hour repeated when clocks go back
+// simply lasts an hour longer.
+func dailyWindow(startTime, endTime string, t time.Time, loc *time.Location) (opens, closes time.Time, ok bool) {
+	start, err := time.Parse("15:04", startTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	end, err := time.Parse("15:04", endTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+	if startMinute == endMinute {
+		return time.Time{}, time.Time{}, false
+	}
+
+	if loc != nil {
+		t = t.In(loc)
+	}
+	year, month, day := t.Date()
+
+	// A window crossing midnight may have opened the day before
+	for _, openDay := range []int{day - 1, day} {
+		closeDay := openDay
+		if startMinute > endMinute {
+			closeDay++
+		}
+		opens = wallClock(year, month, openDay, start, t.Location())
+		closes = wallClock(year, month, closeDay, end, t.Location())
+		if !t.Before(opens) && t.Before(closes) {
+			return opens, closes, true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// wallClock returns the instant the clock in loc reads hh:mm on the given
+// day. A time skipped by a DST transition resolves to the same distance
+// past the transition, as cron does, rather than before it as time.Date
+// would.
+func wallClock(year int, month time.Month, day int, hhmm time.Time, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, hhmm.Hour(), hhmm.Minute(), 0, 0, loc)
+
+	// Compare wall clocks as if in UTC to measure how far time.Date moved
+	// back, which also handles transitions at midnight
+	wanted := time.Date(year, month, day, hhmm.Hour(), hhmm.Minute(), 0, 0, time.UTC)
+	got := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+	if skipped := wanted.Sub(got); skipped > 0 {
+		t = t.Add(skipped)
+	}
+	return t
+}
+
+// Allows reports whether items from the named library may be synced,
+// predicted and cached. Items whose library is unknown are allowed, since
+// there is nothing to match them against.
+func (f *LibraryFilterConfig) Allows(library string) bool {
+	if f == nil || library == "" {
+		return true
+	}
+	for _, name := range f.Exclude {
+		if strings.EqualFold(name, library) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, name := range f.Include {
+		if strings.EqualFold(name, library) {
+			return true
+		}
+	}
+	return false
+}
+
 // CreateCacheDirectories ensures that cache and temp directories exist.
 // Creates directories with appropriate permissions if they don't exist.
 func (c *CacheConfig) CreateCacheDirectories() error {
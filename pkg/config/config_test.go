@@ -202,6 +202,24 @@ func TestApplyDefaults(t *testing.T) {
 	}
 }
 
+func TestDefaultNeedsJellyfinSettings(t *testing.T) {
+	config := Default()
+	if config.Download.Workers != 3 {
+		t.Errorf("Expected 3 workers, got %d", config.Download.Workers)
+	}
+
+	if err := config.Validate(); err == nil {
+		t.Error("Expected validation error without Jellyfin settings")
+	}
+
+	config.Jellyfin.ServerURL = "http://localhost:8096"
+	config.Jellyfin.APIKey = "key"
+	config.Jellyfin.UserID = "user"
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected defaults plus Jellyfin settings to validate, got %v", err)
+	}
+}
+
 func TestLoggingConfigGetLogLevel(t *testing.T) {
 	tests := []struct {
 		level    string
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/knadh/koanf/providers/env"
+	"github.com/knadh/koanf/v2"
+)
+
+// EnvPrefix starts the environment variables that override config file
+// values: JFWATCH_ followed by the key's path with dots as underscores,
+// upper-cased, such as JFWATCH_JELLYFIN_API_KEY for jellyfin.api_key.
+const EnvPrefix = "JFWATCH_"
+
+// Keys returns the path of every value that can be set in the config file,
+// environment or flags, sorted. Maps and lists of sections, such as
+// ui.device_profiles and download.rate_limit_schedule.windows, can only be
+// set in the config file.
+func Keys() []string {
+	kinds := keyKinds()
+	keys := make([]string, 0, len(kinds))
+	for key := range kinds {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// keyKinds returns the kind of value at every key Keys returns.
+func keyKinds() map[string]reflect.Kind {
+	kinds := make(map[string]reflect.Kind)
+	var collect func(t reflect.Type, prefix string)
+	collect = func(t reflect.Type, prefix string) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := field.Tag.Get("koanf")
+			if name == "" || !field.IsExported() {
+				continue
+			}
+
+			switch field.Type.Kind() {
+			case reflect.Struct:
+				collect(field.Type, prefix+name+".")
+			case reflect.Map:
+				// Keyed by names the user picks, so there is no fixed path
+			case reflect.Slice:
+				if field.Type.Elem().Kind() != reflect.Struct {
+					kinds[prefix+name] = reflect.Slice
+				}
+			default:
+				kinds[prefix+name] = field.Type.Kind()
+			}
+		}
+	}
+	collect(reflect.TypeOf(Config{}), "")
+	return kinds
+}
+
+// EnvName returns the environment variable that overrides key.
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// envProvider reads the JFWATCH_ environment variables as the keys they
+// override. Lists are comma-separated, and variables that name no key are
+// ignored.
+func envProvider() *env.Env {
+	kinds := keyKinds()
+	keys := make(map[string]string, len(kinds))
+	for key := range kinds {
+		keys[EnvName(key)] = key
+	}
+
+	return env.ProviderWithValue(EnvPrefix, ".", func(name, value string) (string, interface{}) {
+		key, ok := keys[name]
+		if !ok {
+			return "", nil
+		}
+		return key, overrideValue(kinds[key], value)
+	})
+}
+
+// loadFlags sets every key given as a flag in args, such as
+// --download.workers=4 or -jellyfin.api_key KEY, to its value. Boolean
+// keys may be given without a value to set them to true, and lists are
+// comma-separated.
+func loadFlags(k *koanf.Koanf, args []string) error {
+	fs := flag.NewFlagSet("go-jf-watch", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	for key, kind := range keyKinds() {
+		fs.Var(&overrideFlag{k: k, key: key, kind: kind}, key, "overrides "+key)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("invalid flags: %w", err)
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("invalid flags: unexpected argument %q", fs.Arg(0))
+	}
+	return nil
+}
+
+// overrideFlag is a flag that sets a config key when given.
+type overrideFlag struct {
+	k    *koanf.Koanf
+	key  string
+	kind reflect.Kind
+}
+
+func (f *overrideFlag) String() string { return "" }
+
+func (f *overrideFlag) Set(value string) error {
+	return f.k.Set(f.key, overrideValue(f.kind, value))
+}
+
+// IsBoolFlag lets boolean keys be given without a value.
+func (f *overrideFlag) IsBoolFlag() bool { return f.kind == reflect.Bool }
+
+// overrideValue returns the value an override sets a key of kind to; other
+// conversions are left to unmarshalling, as for values in the config file.
+func overrideValue(kind reflect.Kind, value string) interface{} {
+	if kind != reflect.Slice {
+		return value
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadOverrides(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	yaml := `
+jellyfin:
+  server_url: "https://jellyfin.example.com"
+  api_key: "file-key"
+  user_id: "test-user-id"
+cache:
+  directory: "` + filepath.Join(dir, "cache") + `"
+download:
+  workers: 2
+  rate_limit_mbps: 10
+`
+	if err := os.WriteFile(path, []byte(yaml), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	t.Setenv("JFWATCH_JELLYFIN_API_KEY", "env-key")
+	t.Setenv("JFWATCH_DOWNLOAD_WORKERS", "4")
+	t.Setenv("JFWATCH_JELLYFIN_LIBRARIES_INCLUDE", "TV,Movies")
+	t.Setenv("JFWATCH_JELLYFIN_TIMEOUT", "45s")
+
+	// Flags win over the environment, which wins over the file
+	cfg, err := Load(path, "--download.workers=6", "-prediction.enabled", "--server.port", "9090")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Jellyfin.APIKey != "env-key" {
+		t.Errorf("APIKey = %q, want env-key", cfg.Jellyfin.APIKey)
+	}
+	if cfg.Download.Workers != 6 || cfg.Download.RateLimitMbps != 10 || cfg.Server.Port != 9090 {
+		t.Errorf("got %d workers at %d Mbps on port %d, want 6 at 10 on 9090",
+			cfg.Download.Workers, cfg.Download.RateLimitMbps, cfg.Server.Port)
+	}
+	if !cfg.Prediction.Enabled {
+		t.Error("expected a boolean flag without a value to set true")
+	}
+	if want := []string{"TV", "Movies"}; !reflect.DeepEqual(cfg.Jellyfin.Libraries.Include, want) {
+		t.Errorf("Include = %v, want %v", cfg.Jellyfin.Libraries.Include, want)
+	}
+	if cfg.Jellyfin.Timeout != 45*time.Second {
+		t.Errorf("Timeout = %v, want 45s", cfg.Jellyfin.Timeout)
+	}
+
+	if _, err := Load(path, "--download.unknown=1"); err == nil || !strings.Contains(err.Error(), "invalid flags") {
+		t.Errorf("expected an unknown flag to be rejected, got %v", err)
+	}
+	if _, err := Load(path, "--download.workers=40"); err == nil {
+		t.Error("expected an invalid override to fail validation")
+	}
+}
+
+func TestLoadWithoutFile(t *testing.T) {
+	t.Setenv("JFWATCH_JELLYFIN_SERVER_URL", "https://jellyfin.example.com")
+	t.Setenv("JFWATCH_JELLYFIN_API_KEY", "env-key")
+	t.Setenv("JFWATCH_JELLYFIN_USER_ID", "user")
+	t.Setenv("JFWATCH_CACHE_DIRECTORY", t.TempDir())
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Jellyfin.ServerURL != "https://jellyfin.example.com" || cfg.Download.Workers != 3 {
+		t.Errorf("expected the environment over the defaults, got %+v", cfg.Jellyfin)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	keys := Keys()
+	for _, want := range []string{"jellyfin.api_key", "download.workers", "server.auth.mode", "timezone"} {
+		found := false
+		for _, key := range keys {
+			found = found || key == want
+		}
+		if !found {
+			t.Errorf("expected %s among the keys", want)
+		}
+	}
+	for _, key := range keys {
+		if strings.HasPrefix(key, "ui.device_profiles") || key == "download.rate_limit_schedule.windows" {
+			t.Errorf("expected %s to be left to the config file", key)
+		}
+	}
+
+	if got := EnvName("jellyfin.api_key"); got != "JFWATCH_JELLYFIN_API_KEY" {
+		t.Errorf("EnvName() = %s", got)
+	}
+}
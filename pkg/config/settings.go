@@ -0,0 +1,242 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting is a value the web UI can change, named by its path in the
+// config file.
+type setting struct {
+	// live settings take effect without a restart
+	live bool
+	// secret settings are never shown, and an empty value leaves them as
+	// they are
+	secret bool
+	get    func(c *Config) interface{}
+	set    func(c *Config, value interface{}) error
+}
+
+// settings are the values the web UI can change.
+var settings = map[string]setting{
+	"jellyfin.server_url": stringSetting(func(c *Config) *string { return &c.Jellyfin.ServerURL }),
+	"jellyfin.api_key":    secretSetting(func(c *Config) *string { return &c.Jellyfin.APIKey }),
+	"jellyfin.user_id":    stringSetting(func(c *Config) *string { return &c.Jellyfin.UserID }),
+
+	"cache.directory": {
+		get: func(c *Config) interface{} { return c.Cache.Directory },
+		set: func(c *Config, value interface{}) error {
+			// The metadata database lives in it, so it is read before
+			// any saved setting could be
+			if dir, err := toString(value); err != nil || dir != c.Cache.Directory {
+				return errors.New("can only be changed in the config file")
+			}
+			return nil
+		},
+	},
+	"cache.max_size_gb":        liveSetting(intSetting(func(c *Config) *int { return &c.Cache.MaxSizeGB })),
+	"cache.eviction_threshold": liveSetting(floatSetting(func(c *Config) *float64 { return &c.Cache.EvictionThreshold })),
+
+	"download.workers":               liveSetting(intSetting(func(c *Config) *int { return &c.Download.Workers })),
+	"download.rate_limit_mbps":       liveSetting(intSetting(func(c *Config) *int { return &c.Download.RateLimitMbps })),
+	"download.auto_download_current": boolSetting(func(c *Config) *bool { return &c.Download.AutoDownloadCurrent }),
+	"download.auto_download_next":    boolSetting(func(c *Config) *bool { return &c.Download.AutoDownloadNext }),
+	"download.auto_download_count":   intSetting(func(c *Config) *int { return &c.Download.AutoDownloadCount }),
+
+	"server.port":               intSetting(func(c *Config) *int { return &c.Server.Port }),
+	"server.host":               stringSetting(func(c *Config) *string { return &c.Server.Host }),
+	"server.enable_compression": boolSetting(func(c *Config) *bool { return &c.Server.EnableCompression }),
+
+	"prediction.enabled":       liveSetting(boolSetting(func(c *Config) *bool { return &c.Prediction.Enabled })),
+	"prediction.sync_interval": durationSetting(func(c *Config) *time.Duration { return &c.Prediction.SyncInterval }),
+	"prediction.history_days":  intSetting(func(c *Config) *int { return &c.Prediction.HistoryDays }),
+
+	"ui.theme":    liveSetting(stringSetting(func(c *Config) *string { return &c.UI.Theme })),
+	"ui.language": liveSetting(stringSetting(func(c *Config) *string { return &c.UI.Language })),
+}
+
+// Settings returns the values the web UI can change, keyed by their path in
+// the config file. Secrets such as the Jellyfin API key are left out.
+func (c *Config) Settings() map[string]interface{} {
+	values := make(map[string]interface{}, len(settings))
+	for key, s := range settings {
+		if !s.secret {
+			values[key] = s.get(c)
+		}
+	}
+	return values
+}
+
+// LiveSetting reports whether a setting changed from the web UI takes
+// effect without a restart.
+func LiveSetting(key string) bool {
+	return settings[key].live
+}
+
+// ApplySettings returns a copy of c with values, keyed by their path in the
+// config file, applied and validated, along with the keys whose value
+// changed. Values may be JSON numbers and booleans or their text form, as
+// posted by a form. c itself is left untouched.
+func (c *Config) ApplySettings(values map[string]interface{}) (*Config, []string, error) {
+	updated := *c
+	var changed []string
+
+	for key, value := range values {
+		s, ok := settings[key]
+		if !ok {
+			return nil, nil, fmt.Errorf("%s cannot be changed from the web UI", key)
+		}
+		if text, ok := value.(string); s.secret && ok && text == "" {
+			continue
+		}
+
+		before := s.get(&updated)
+		if err := s.set(&updated, value); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if s.get(&updated) != before {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+
+	if err := validate(&updated); err != nil {
+		return nil, nil, fmt.Errorf("config validation failed: %w", err)
+	}
+	updated.applyTimezone()
+	return &updated, changed, nil
+}
+
+func liveSetting(s setting) setting {
+	s.live = true
+	return s
+}
+
+func stringSetting(field func(c *Config) *string) setting {
+	return setting{
+		get: func(c *Config) interface{} { return *field(c) },
+		set: func(c *Config, value interface{}) error {
+			text, err := toString(value)
+			if err != nil {
+				return err
+			}
+			*field(c) = strings.TrimSpace(text)
+			return nil
+		},
+	}
+}
+
+func secretSetting(field func(c *Config) *string) setting {
+	s := stringSetting(field)
+	s.secret = true
+	return s
+}
+
+func intSetting(field func(c *Config) *int) setting {
+	return setting{
+		get: func(c *Config) interface{} { return *field(c) },
+		set: func(c *Config, value interface{}) error {
+			n, err := toFloat(value)
+			if err != nil || n != math.Trunc(n) || math.Abs(n) > math.MaxInt32 {
+				return errors.New("must be a whole number")
+			}
+			*field(c) = int(n)
+			return nil
+		},
+	}
+}
+
+func floatSetting(field func(c *Config) *float64) setting {
+	return setting{
+		get: func(c *Config) interface{} { return *field(c) },
+		set: func(c *Config, value interface{}) error {
+			n, err := toFloat(value)
+			if err != nil {
+				return errors.New("must be a number")
+			}
+			*field(c) = n
+			return nil
+		},
+	}
+}
+
+func boolSetting(field func(c *Config) *bool) setting {
+	return setting{
+		get: func(c *Config) interface{} { return *field(c) },
+		set: func(c *Config, value interface{}) error {
+			switch v := value.(type) {
+			case bool:
+				*field(c) = v
+			case string:
+				// What a form posts for a checkbox
+				switch strings.ToLower(v) {
+				case "true", "on", "1":
+					*field(c) = true
+				case "false", "off", "0":
+					*field(c) = false
+				default:
+					return errors.New("must be true or false")
+				}
+			default:
+				return errors.New("must be true or false")
+			}
+			return nil
+		},
+	}
+}
+
+func durationSetting(field func(c *Config) *time.Duration) setting {
+	return setting{
+		get: func(c *Config) interface{} { return formatDuration(*field(c)) },
+		set: func(c *Config, value interface{}) error {
+			text, err := toString(value)
+			if err != nil {
+				return errors.New("must be a duration such as 4h")
+			}
+			d, err := time.ParseDuration(text)
+			if err != nil {
+				return errors.New("must be a duration such as 4h")
+			}
+			*field(c) = d
+			return nil
+		},
+	}
+}
+
+func toString(value interface{}) (string, error) {
+	text, ok := value.(string)
+	if !ok {
+		return "", errors.New("must be text")
+	}
+	return text, nil
+}
+
+func toFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	}
+	return 0, errors.New("not a number")
+}
+
+// formatDuration formats d without the zero minutes and seconds
+// time.Duration.String adds, so 4h reads as "4h" rather than "4h0m0s".
+func formatDuration(d time.Duration) string {
+	text := d.String()
+	if strings.HasSuffix(text, "m0s") {
+		text = strings.TrimSuffix(text, "0s")
+	}
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestApplySettings(t *testing.T) {
+	cfg := Default()
+	cfg.Cache.Directory = t.TempDir()
+	cfg.Jellyfin.ServerURL = "http://localhost:8096"
+	cfg.Jellyfin.APIKey = "key"
+	cfg.Jellyfin.UserID = "user"
+
+	settings := cfg.Settings()
+	if _, ok := settings["jellyfin.api_key"]; ok {
+		t.Error("expected the API key to be left out of the settings")
+	}
+	if settings["prediction.sync_interval"] != "4h" {
+		t.Errorf("expected sync interval 4h, got %v", settings["prediction.sync_interval"])
+	}
+
+	// Form values arrive as text, JSON ones as numbers and booleans
+	updated, changed, err := cfg.ApplySettings(map[string]interface{}{
+		"download.workers":            float64(5),
+		"download.rate_limit_mbps":    "20",
+		"download.auto_download_next": "on",
+		"prediction.sync_interval":    "2h",
+		"cache.directory":             cfg.Cache.Directory,
+		"jellyfin.api_key":            "",
+		"ui.theme":                    "auto",
+	})
+	if err != nil {
+		t.Fatalf("ApplySettings() error = %v", err)
+	}
+	want := []string{"download.auto_download_next", "download.rate_limit_mbps", "download.workers", "prediction.sync_interval"}
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+	if updated.Download.Workers != 5 || updated.Download.RateLimitMbps != 20 ||
+		!updated.Download.AutoDownloadNext || updated.Prediction.SyncInterval != 2*time.Hour || updated.Jellyfin.APIKey != "key" {
+		t.Errorf("settings not applied: %+v", updated.Download)
+	}
+	if cfg.Download.Workers != 3 {
+		t.Error("expected the original config to be left untouched")
+	}
+	for _, key := range []string{"download.workers", "download.rate_limit_mbps", "cache.eviction_threshold"} {
+		if !LiveSetting(key) {
+			t.Errorf("expected %s to take effect without a restart", key)
+		}
+	}
+	if LiveSetting("server.port") {
+		t.Error("expected server.port to need a restart")
+	}
+
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+	}{
+		{"unknown setting", map[string]interface{}{"logging.level": "debug"}},
+		{"fails validation", map[string]interface{}{"download.workers": 50}},
+		{"not a whole number", map[string]interface{}{"download.workers": 2.5}},
+		{"bad duration", map[string]interface{}{"prediction.sync_interval": "soon"}},
+		{"bad boolean", map[string]interface{}{"prediction.enabled": "maybe"}},
+		{"cache directory", map[string]interface{}{"cache.directory": "/elsewhere"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := cfg.ApplySettings(tt.values); err == nil {
+				t.Errorf("expected %v to be rejected", tt.values)
+			}
+		})
+	}
+}
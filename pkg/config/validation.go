@@ -1,7 +1,9 @@
 package config
 
 import (
+	"encoding/hex"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -32,6 +34,11 @@ func validate(config *Config) error {
 		return fmt.Errorf("prediction config: %w", err)
 	}
 
+	// Predictions need the whole history window
+	if config.Cache.HistoryRetentionDays > 0 && config.Cache.HistoryRetentionDays < config.Prediction.HistoryDays {
+		return fmt.Errorf("cache config: history_retention_days cannot be shorter than prediction.history_days")
+	}
+
 	if err := validateLogging(&config.Logging); err != nil {
 		return fmt.Errorf("logging config: %w", err)
 	}
@@ -40,6 +47,47 @@ func validate(config *Config) error {
 		return fmt.Errorf("ui config: %w", err)
 	}
 
+	if err := validateLocalLibrary(&config.LocalLibrary); err != nil {
+		return fmt.Errorf("local_library config: %w", err)
+	}
+
+	if err := validateNotifications(&config.Notifications); err != nil {
+		return fmt.Errorf("notifications config: %w", err)
+	}
+
+	if err := validateReports(&config.Reports); err != nil {
+		return fmt.Errorf("reports config: %w", err)
+	}
+
+	if err := validateMaintenance(&config.Maintenance); err != nil {
+		return fmt.Errorf("maintenance config: %w", err)
+	}
+
+	if err := validateChaos(&config.Chaos); err != nil {
+		return fmt.Errorf("chaos config: %w", err)
+	}
+
+	if _, err := config.Location(); err != nil {
+		return fmt.Errorf("timezone %q is not a known IANA time zone", config.Timezone)
+	}
+
+	return nil
+}
+
+// validateLocalLibrary validates the optional local folder provider settings.
+func validateLocalLibrary(config *LocalLibraryConfig) error {
+	if config.Directory == "" {
+		return nil
+	}
+
+	info, err := os.Stat(config.Directory)
+	if err != nil {
+		return fmt.Errorf("directory %s is not accessible: %w", config.Directory, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("directory %s is not a directory", config.Directory)
+	}
+
 	return nil
 }
 
@@ -53,18 +101,55 @@ func validateJellyfin(config *JellyfinConfig) error {
 		return fmt.Errorf("server_url must start with http:// or https://")
 	}
 
-	if config.APIKey == "" {
+	// Logging in as a user replaces the API key and fills in the user ID
+	if config.APIKey == "" && config.Username == "" && !config.QuickConnect {
 		return fmt.Errorf("api_key is required")
 	}
 
-	if config.UserID == "" {
+	if config.APIKey != "" && config.UserID == "" {
 		return fmt.Errorf("user_id is required")
 	}
 
+	if config.Password != "" && config.Username == "" {
+		return fmt.Errorf("password requires username")
+	}
+
 	if config.RetryAttempts < 0 || config.RetryAttempts > 10 {
 		return fmt.Errorf("retry_attempts must be between 0 and 10")
 	}
 
+	if err := validateLibraryFilter(&config.Libraries); err != nil {
+		return fmt.Errorf("libraries: %w", err)
+	}
+
+	if config.LibrarySyncInterval != 0 && (config.LibrarySyncInterval < 5*time.Minute || config.LibrarySyncInterval > 24*time.Hour) {
+		return fmt.Errorf("library_sync_interval must be 0 (off) or between 5m and 24h")
+	}
+
+	if config.ConnectivityCheckInterval != 0 && (config.ConnectivityCheckInterval < time.Second || config.ConnectivityCheckInterval > time.Hour) {
+		return fmt.Errorf("connectivity_check_interval must be between 1s and 1h")
+	}
+
+	return nil
+}
+
+// validateLibraryFilter rejects blank library names and libraries listed as
+// both included and excluded.
+func validateLibraryFilter(config *LibraryFilterConfig) error {
+	for _, name := range append(append([]string{}, config.Include...), config.Exclude...) {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("library names must not be empty")
+		}
+	}
+
+	for _, include := range config.Include {
+		for _, exclude := range config.Exclude {
+			if strings.EqualFold(include, exclude) {
+				return fmt.Errorf("library %q is both included and excluded", include)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -94,11 +179,74 @@ func validateCache(config *CacheConfig) error {
 		return fmt.Errorf("eviction_threshold must be between 0 and 1")
 	}
 
+	if config.MetadataMaxAgeDays < 0 {
+		return fmt.Errorf("metadata_max_age_days cannot be negative")
+	}
+
+	if config.HistoryRetentionDays < 0 {
+		return fmt.Errorf("history_retention_days cannot be negative")
+	}
+
+	if config.EventRetentionDays < 0 {
+		return fmt.Errorf("event_retention_days cannot be negative")
+	}
+
+	if config.IntegrityScanInterval != 0 && config.IntegrityScanInterval < time.Hour {
+		return fmt.Errorf("integrity_scan_interval must be 0 (off) or at least 1h")
+	}
+
 	validStores := []string{"boltdb", "flatfile"}
 	if !contains(validStores, config.MetadataStore) {
 		return fmt.Errorf("metadata_store must be one of: %s", strings.Join(validStores, ", "))
 	}
 
+	// An unset algorithm falls back to sha256
+	validChecksums := []string{"sha256", "blake3", "xxhash", "off"}
+	if config.ChecksumAlgorithm != "" && !contains(validChecksums, config.ChecksumAlgorithm) {
+		return fmt.Errorf("checksum_algorithm must be one of: %s", strings.Join(validChecksums, ", "))
+	}
+
+	// An unset policy falls back to lru
+	validPolicies := []string{"lru", "lfu", "size", "watched"}
+	if config.EvictionPolicy != "" && !contains(validPolicies, config.EvictionPolicy) {
+		return fmt.Errorf("eviction_policy must be one of: %s", strings.Join(validPolicies, ", "))
+	}
+
+	if err := validateCacheEncryption(&config.Encryption); err != nil {
+		return fmt.Errorf("encryption: %w", err)
+	}
+
+	if config.Probe.Enabled {
+		if config.Probe.FFprobePath == "" {
+			return fmt.Errorf("probe: ffprobe_path is required")
+		}
+		if config.Probe.Timeout < time.Second {
+			return fmt.Errorf("probe: timeout must be at least 1s")
+		}
+	}
+
+	return nil
+}
+
+// validateCacheEncryption requires exactly one source for the key when
+// encryption is enabled.
+func validateCacheEncryption(config *CacheEncryptionConfig) error {
+	if !config.Enabled {
+		return nil
+	}
+
+	switch {
+	case config.Key == "" && config.Passphrase == "":
+		return fmt.Errorf("key or passphrase is required")
+	case config.Key != "" && config.Passphrase != "":
+		return fmt.Errorf("set either key or passphrase, not both")
+	case config.Key != "":
+		if key, err := hex.DecodeString(config.Key); err != nil || len(key) != 32 {
+			return fmt.Errorf("key must be 64 hex digits")
+		}
+	case len(config.Passphrase) < 12:
+		return fmt.Errorf("passphrase must be at least 12 characters")
+	}
 	return nil
 }
 
@@ -120,6 +268,18 @@ func validateDownload(config *DownloadConfig) error {
 		return fmt.Errorf("peak_limit_percent must be between 1 and 100")
 	}
 
+	for i, window := range config.RateLimitSchedule.Windows {
+		if err := validateDownloadWindow(&window); err != nil {
+			return fmt.Errorf("rate_limit_schedule windows[%d]: %w", i, err)
+		}
+	}
+
+	for _, cidr := range config.RateLimitExemption.CIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("rate_limit_exemption cidr %q is invalid: %w", cidr, err)
+		}
+	}
+
 	if config.AutoDownloadCount < 0 || config.AutoDownloadCount > 10 {
 		return fmt.Errorf("auto_download_count must be between 0 and 10")
 	}
@@ -132,6 +292,193 @@ func validateDownload(config *DownloadConfig) error {
 		return fmt.Errorf("retry_delay must be between 100ms and 60s")
 	}
 
+	if config.ReadAheadMB < 0 || config.ReadAheadMB > 4096 {
+		return fmt.Errorf("read_ahead_mb must be between 0 and 4096")
+	}
+
+	// An unset stall timeout falls back to 60s
+	if config.StallTimeout != 0 && (config.StallTimeout < 5*time.Second || config.StallTimeout > 30*time.Minute) {
+		return fmt.Errorf("stall_timeout must be between 5s and 30m")
+	}
+
+	if config.MinThroughputKBps < 0 {
+		return fmt.Errorf("min_throughput_kbps cannot be negative")
+	}
+
+	if config.MaxDailyGB < 0 {
+		return fmt.Errorf("max_daily_gb cannot be negative")
+	}
+
+	if config.LargeDownloadMB < 0 {
+		return fmt.Errorf("large_download_mb cannot be negative")
+	}
+
+	if config.Segments < 0 || config.Segments > 16 {
+		return fmt.Errorf("segments must be between 0 and 16")
+	}
+
+	if config.SegmentMinMB < 0 {
+		return fmt.Errorf("segment_min_mb cannot be negative")
+	}
+
+	// Priority 0 is reserved for what is playing right now; unset falls
+	// back to 2
+	if config.SubscriptionPriority < 0 || config.SubscriptionPriority > 4 {
+		return fmt.Errorf("subscription_priority must be between 1 and 4")
+	}
+
+	if err := validateDownloadHTTP(&config.HTTP); err != nil {
+		return fmt.Errorf("http: %w", err)
+	}
+
+	if err := validateLocalSource(&config.LocalSource); err != nil {
+		return fmt.Errorf("local_source: %w", err)
+	}
+
+	bound := make(map[int]bool)
+	for i, binding := range config.InterfaceBindings {
+		if err := validateInterfaceBinding(&binding, bound); err != nil {
+			return fmt.Errorf("interface_bindings[%d]: %w", i, err)
+		}
+	}
+
+	limited := make(map[int]bool)
+	for i, limit := range config.QueueLimits {
+		if err := validateQueueLimit(&limit, limited); err != nil {
+			return fmt.Errorf("queue_limits[%d]: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// validateDownloadHTTP validates download connection settings.
+func validateDownloadHTTP(config *DownloadHTTPConfig) error {
+	if config.MaxConnsPerHost < 0 || config.MaxConnsPerHost > 32 {
+		return fmt.Errorf("max_conns_per_host must be between 0 and 32")
+	}
+
+	if config.IdleConnTimeout < 0 {
+		return fmt.Errorf("idle_conn_timeout cannot be negative")
+	}
+
+	switch config.MinTLSVersion {
+	case "", "1.2", "1.3":
+	default:
+		return fmt.Errorf("min_tls_version must be 1.2 or 1.3")
+	}
+
+	if config.CAFile != "" {
+		if _, err := os.Stat(config.CAFile); err != nil {
+			return fmt.Errorf("ca_file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// validateLocalSource validates taking files from Jellyfin's media folders.
+func validateLocalSource(config *LocalSourceConfig) error {
+	switch config.Mode {
+	case "", "auto", "reflink", "hardlink", "copy":
+	default:
+		return fmt.Errorf("mode must be auto, reflink, hardlink or copy")
+	}
+
+	for i, mapping := range config.PathMappings {
+		if mapping.From == "" || mapping.To == "" {
+			return fmt.Errorf("path_mappings[%d]: from and to are required", i)
+		}
+	}
+
+	return nil
+}
+
+// validateQueueLimit validates a single queue limit. limited tracks
+// priorities capped by earlier limits.
+func validateQueueLimit(limit *QueueLimitConfig, limited map[int]bool) error {
+	if limit.MaxItems < 0 {
+		return fmt.Errorf("max_items cannot be negative")
+	}
+
+	if limit.MaxGB < 0 {
+		return fmt.Errorf("max_gb cannot be negative")
+	}
+
+	if limit.MaxItems == 0 && limit.MaxGB == 0 {
+		return fmt.Errorf("at least one of max_items or max_gb must be set")
+	}
+
+	if len(limit.Priorities) == 0 {
+		return fmt.Errorf("priorities cannot be empty")
+	}
+
+	for _, priority := range limit.Priorities {
+		if priority < 0 || priority > 4 {
+			return fmt.Errorf("priority %d must be between 0 and 4", priority)
+		}
+		if limited[priority] {
+			return fmt.Errorf("priority %d is limited more than once", priority)
+		}
+		limited[priority] = true
+	}
+
+	return nil
+}
+
+// validateDownloadWindow validates a single download scheduling window.
+func validateDownloadWindow(window *DownloadWindowConfig) error {
+	if len(window.Priorities) == 0 {
+		return fmt.Errorf("priorities cannot be empty")
+	}
+
+	if window.Hours == "" {
+		return fmt.Errorf("hours cannot be empty")
+	}
+	if window.Hours != alwaysWindow {
+		if err := validatePeakHours(window.Hours); err != nil {
+			return fmt.Errorf("hours must be in format HH:MM-HH:MM (e.g., 01:00-06:00) or \"always\"")
+		}
+	}
+
+	for _, priority := range window.Priorities {
+		if priority < 0 || priority > 4 {
+			return fmt.Errorf("priority %d must be between 0 and 4", priority)
+		}
+		// Priority 0 is what is playing right now
+		if priority == 0 && window.Hours != alwaysWindow {
+			return fmt.Errorf("priority 0 cannot be restricted to a window")
+		}
+	}
+
+	return nil
+}
+
+// validateInterfaceBinding validates a single download interface binding.
+// bound tracks priorities claimed by earlier bindings.
+func validateInterfaceBinding(binding *InterfaceBindingConfig, bound map[int]bool) error {
+	if (binding.Interface == "") == (binding.SourceIP == "") {
+		return fmt.Errorf("exactly one of interface or source_ip must be set")
+	}
+
+	if binding.SourceIP != "" && net.ParseIP(binding.SourceIP) == nil {
+		return fmt.Errorf("source_ip %q is not a valid IP address", binding.SourceIP)
+	}
+
+	if len(binding.Priorities) == 0 {
+		return fmt.Errorf("priorities cannot be empty")
+	}
+
+	for _, priority := range binding.Priorities {
+		if priority < 0 || priority > 4 {
+			return fmt.Errorf("priority %d must be between 0 and 4", priority)
+		}
+		if bound[priority] {
+			return fmt.Errorf("priority %d is bound more than once", priority)
+		}
+		bound[priority] = true
+	}
+
 	return nil
 }
 
@@ -145,6 +492,170 @@ func validateServer(config *ServerConfig) error {
 		return fmt.Errorf("host cannot be empty")
 	}
 
+	if config.WebDAV.Enabled {
+		if err := validateWebDAV(&config.WebDAV, config.Port); err != nil {
+			return fmt.Errorf("webdav: %w", err)
+		}
+	}
+
+	if config.DLNA.Enabled {
+		if err := validateDLNA(config); err != nil {
+			return fmt.Errorf("dlna: %w", err)
+		}
+	}
+
+	if config.HLS.Enabled {
+		if config.HLS.FFmpegPath == "" {
+			return fmt.Errorf("hls: ffmpeg_path cannot be empty")
+		}
+		if config.HLS.SegmentDuration < time.Second || config.HLS.SegmentDuration > time.Minute {
+			return fmt.Errorf("hls: segment_duration must be between 1s and 1m")
+		}
+	}
+
+	if config.Sharing.Enabled {
+		if err := validateSharing(config); err != nil {
+			return fmt.Errorf("sharing: %w", err)
+		}
+	}
+
+	if config.Kiosk.Enabled {
+		if err := validateKiosk(&config.Kiosk); err != nil {
+			return fmt.Errorf("kiosk: %w", err)
+		}
+	}
+
+	if config.Auth.Mode != "" {
+		if err := validateAuth(&config.Auth); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// validateAuth validates the sign-in settings.
+func validateAuth(auth *AuthConfig) error {
+	switch auth.Mode {
+	case "password":
+		if len(auth.Password) < 8 {
+			return fmt.Errorf("password must be at least 8 characters")
+		}
+	case "proxy":
+		if auth.ProxyHeader == "" {
+			return fmt.Errorf("proxy_header cannot be empty")
+		}
+		if len(auth.TrustedProxies) == 0 {
+			return fmt.Errorf("trusted_proxies must list the proxy's address")
+		}
+		for _, proxy := range auth.TrustedProxies {
+			if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+				return fmt.Errorf("trusted proxy %q is not an IP address or CIDR", proxy)
+			}
+		}
+	case "jellyfin":
+	default:
+		return fmt.Errorf("mode must be password, proxy or jellyfin, not %q", auth.Mode)
+	}
+
+	if auth.SessionTTL < time.Minute {
+		return fmt.Errorf("session_ttl must be at least 1m")
+	}
+	if auth.SessionSecret != "" && len(auth.SessionSecret) < 16 {
+		return fmt.Errorf("session_secret must be at least 16 characters")
+	}
+
+	return nil
+}
+
+// validateKiosk validates the kiosk mode settings.
+func validateKiosk(kiosk *KioskConfig) error {
+	if len(kiosk.PIN) < 4 || len(kiosk.PIN) > 12 {
+		return fmt.Errorf("pin must be 4 to 12 digits")
+	}
+
+	for _, c := range kiosk.PIN {
+		if c < '0' || c > '9' {
+			return fmt.Errorf("pin must contain only digits")
+		}
+	}
+
+	return nil
+}
+
+// validateSharing validates the public share link settings. A separate port
+// must not collide with the main server or a separate WebDAV listener.
+func validateSharing(config *ServerConfig) error {
+	sharing := config.Sharing
+
+	if sharing.Port < 0 || sharing.Port > 65535 {
+		return fmt.Errorf("port must be between 0 and 65535")
+	}
+
+	if sharing.Port != 0 {
+		if sharing.Port == config.Port {
+			return fmt.Errorf("port must differ from the main server port (use 0 to share it)")
+		}
+		if config.WebDAV.Enabled && sharing.Port == config.WebDAV.Port {
+			return fmt.Errorf("port must differ from the webdav port")
+		}
+	}
+
+	if sharing.MaxTTL <= 0 {
+		return fmt.Errorf("max_ttl must be positive")
+	}
+
+	if sharing.BaseURL != "" && !strings.HasPrefix(sharing.BaseURL, "http://") && !strings.HasPrefix(sharing.BaseURL, "https://") {
+		return fmt.Errorf("base_url must start with http:// or https://")
+	}
+
+	return nil
+}
+
+// validateDLNA validates the DLNA media server settings. It always has its
+// own listener, since UPnP clients expect the device at the root of it.
+func validateDLNA(config *ServerConfig) error {
+	dlna := config.DLNA
+
+	if dlna.Port <= 0 || dlna.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535")
+	}
+	if dlna.Port == config.Port {
+		return fmt.Errorf("port must differ from the main server port")
+	}
+	if config.WebDAV.Enabled && dlna.Port == config.WebDAV.Port {
+		return fmt.Errorf("port must differ from the webdav port")
+	}
+	if config.Sharing.Enabled && dlna.Port == config.Sharing.Port {
+		return fmt.Errorf("port must differ from the sharing port")
+	}
+
+	if strings.TrimSpace(dlna.FriendlyName) == "" {
+		return fmt.Errorf("friendly_name cannot be empty")
+	}
+
+	return nil
+}
+
+// validateWebDAV validates the WebDAV export settings. Credentials are
+// mandatory since the share exposes the whole cache.
+func validateWebDAV(config *WebDAVConfig, mainPort int) error {
+	if config.Username == "" || config.Password == "" {
+		return fmt.Errorf("username and password are required")
+	}
+
+	if config.Port < 0 || config.Port > 65535 {
+		return fmt.Errorf("port must be between 0 and 65535")
+	}
+
+	if config.Port == mainPort {
+		return fmt.Errorf("port must differ from the main server port (use 0 to share it)")
+	}
+
+	if !strings.HasPrefix(config.Path, "/") || config.Path == "/" {
+		return fmt.Errorf("path must start with / and cannot be the root")
+	}
+
 	return nil
 }
 
@@ -158,6 +669,70 @@ func validatePrediction(config *PredictionConfig) error {
 		return fmt.Errorf("min_confidence must be between 0 and 1")
 	}
 
+	if config.SpeculativeTTL < 0 {
+		return fmt.Errorf("speculative_ttl cannot be negative")
+	}
+
+	if config.AccuracyDays < 0 || config.AccuracyDays > config.HistoryDays {
+		return fmt.Errorf("accuracy_days cannot be negative or exceed history_days")
+	}
+
+	if config.RecentlyAddedDays < 0 || config.RecentlyAddedDays > 365 {
+		return fmt.Errorf("recently_added_days cannot be negative or exceed 365")
+	}
+
+	if config.MaxSpeculativeGB < 0 {
+		return fmt.Errorf("max_speculative_gb cannot be negative")
+	}
+
+	if config.BingeRateThreshold < 0 {
+		return fmt.Errorf("binge_rate_threshold cannot be negative")
+	}
+
+	if config.BingeEpisodes < 0 || config.BingeEpisodes > 10 {
+		return fmt.Errorf("binge_episodes must be between 0 and 10")
+	}
+
+	if config.WatchlistPriority < 0 || config.WatchlistPriority > 4 {
+		return fmt.Errorf("watchlist_priority must be between 1 and 4")
+	}
+
+	if config.NextUpLimit < 0 || config.NextUpLimit > 100 {
+		return fmt.Errorf("next_up_limit must be between 0 and 100")
+	}
+
+	if config.SessionSyncInterval != 0 && (config.SessionSyncInterval < 10*time.Second || config.SessionSyncInterval > time.Hour) {
+		return fmt.Errorf("session_sync_interval must be 0 (off) or between 10s and 1h")
+	}
+
+	for i, rule := range config.SeasonalRules {
+		if err := validateSeasonalRule(&rule); err != nil {
+			return fmt.Errorf("seasonal_rules[%d]: %w", i, err)
+		}
+	}
+
+	validDevices := []string{"phone", "tablet", "tv", "desktop"}
+	validQualities := []string{"original", "1080p", "720p", "480p"}
+	for device, quality := range config.DeviceQuality {
+		if !contains(validDevices, device) {
+			return fmt.Errorf("device_quality: device must be one of: %s", strings.Join(validDevices, ", "))
+		}
+		if !contains(validQualities, quality) {
+			return fmt.Errorf("device_quality.%s must be one of: %s", device, strings.Join(validQualities, ", "))
+		}
+	}
+
+	seenUsers := make(map[string]bool, len(config.HouseholdUsers))
+	for i, user := range config.HouseholdUsers {
+		if user == "" {
+			return fmt.Errorf("household_users[%d] cannot be empty", i)
+		}
+		if seenUsers[user] {
+			return fmt.Errorf("household_users contains %s more than once", user)
+		}
+		seenUsers[user] = true
+	}
+
 	return nil
 }
 
@@ -200,6 +775,18 @@ func validateUI(config *UIConfig) error {
 		return fmt.Errorf("video_quality_preference must be one of: %s", strings.Join(validQualities, ", "))
 	}
 
+	for name, profile := range config.DeviceProfiles {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("device_profiles: profile name cannot be empty")
+		}
+		if !contains(validQualities, profile.MaxQuality) {
+			return fmt.Errorf("device_profiles.%s.max_quality must be one of: %s", name, strings.Join(validQualities, ", "))
+		}
+		if profile.MaxBitrateMbps < 0 {
+			return fmt.Errorf("device_profiles.%s.max_bitrate_mbps cannot be negative", name)
+		}
+	}
+
 	return nil
 }
 
@@ -224,6 +811,27 @@ func validatePeakHours(peakHours string) error {
 	return nil
 }
 
+// validateSeasonalRule validates a single seasonal boost rule.
+func validateSeasonalRule(rule *SeasonalRule) error {
+	if _, err := time.Parse("01-02", rule.Start); err != nil {
+		return fmt.Errorf("start must be in format MM-DD")
+	}
+
+	if _, err := time.Parse("01-02", rule.End); err != nil {
+		return fmt.Errorf("end must be in format MM-DD")
+	}
+
+	if len(rule.Genres) == 0 {
+		return fmt.Errorf("genres cannot be empty")
+	}
+
+	if rule.Boost < -1 || rule.Boost > 1 {
+		return fmt.Errorf("boost must be between -1 and 1")
+	}
+
+	return nil
+}
+
 // contains checks if a slice contains a specific string.
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
@@ -233,3 +841,107 @@ func contains(slice []string, item string) bool {
 	}
 	return false
 }
+
+// validateNotifications validates the configured notification sinks.
+func validateNotifications(config *NotificationsConfig) error {
+	if config.Email.Enabled {
+		if config.Email.SMTPHost == "" {
+			return fmt.Errorf("email smtp_host is required")
+		}
+		if config.Email.SMTPPort <= 0 || config.Email.SMTPPort > 65535 {
+			return fmt.Errorf("email smtp_port must be between 1 and 65535")
+		}
+		if config.Email.From == "" || len(config.Email.To) == 0 {
+			return fmt.Errorf("email from and to addresses are required")
+		}
+	}
+
+	if config.Webhook.Enabled {
+		if !strings.HasPrefix(config.Webhook.URL, "http://") && !strings.HasPrefix(config.Webhook.URL, "https://") {
+			return fmt.Errorf("webhook url must start with http:// or https://")
+		}
+	}
+
+	if config.QuietHours.Enabled {
+		start, err := time.Parse("15:04", config.QuietHours.Start)
+		if err != nil {
+			return fmt.Errorf("quiet_hours start must be in HH:MM format")
+		}
+		end, err := time.Parse("15:04", config.QuietHours.End)
+		if err != nil {
+			return fmt.Errorf("quiet_hours end must be in HH:MM format")
+		}
+		if start.Equal(end) {
+			return fmt.Errorf("quiet_hours start and end must differ")
+		}
+	}
+
+	return nil
+}
+
+// validateReports validates the weekly report schedule.
+func validateReports(config *ReportsConfig) error {
+	if _, err := ParseWeekday(config.Weekday); err != nil {
+		return err
+	}
+
+	if _, err := time.Parse("15:04", config.Time); err != nil {
+		return fmt.Errorf("time must be in HH:MM format")
+	}
+
+	return nil
+}
+
+// validateMaintenance validates the maintenance window.
+func validateMaintenance(config *MaintenanceConfig) error {
+	if !config.Enabled {
+		return nil
+	}
+
+	start, err := time.Parse("15:04", config.Start)
+	if err != nil {
+		return fmt.Errorf("start must be in HH:MM format")
+	}
+	end, err := time.Parse("15:04", config.End)
+	if err != nil {
+		return fmt.Errorf("end must be in HH:MM format")
+	}
+	if start.Equal(end) {
+		return fmt.Errorf("start and end must differ")
+	}
+
+	return nil
+}
+
+// ParseWeekday converts a case-insensitive day name such as "sunday" to a time.Weekday.
+func ParseWeekday(name string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), name) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("weekday %q is not a valid day name", name)
+}
+
+// validateChaos validates fault injection rates and delays.
+func validateChaos(config *ChaosConfig) error {
+	rates := []struct {
+		name string
+		rate float64
+	}{
+		{"download_failure_rate", config.DownloadFailureRate},
+		{"storage_error_rate", config.StorageErrorRate},
+		{"jellyfin_error_rate", config.JellyfinErrorRate},
+	}
+	for _, r := range rates {
+		if r.rate < 0 || r.rate > 1 {
+			return fmt.Errorf("%s must be between 0 and 1", r.name)
+		}
+	}
+
+	if config.SlowReadDelay < 0 {
+		return fmt.Errorf("slow_read_delay cannot be negative")
+	}
+
+	return nil
+}
@@ -0,0 +1,1232 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Jellyfin settings
+
+func TestValidateJellyfinLogin(t *testing.T) {
+	valid := []JellyfinConfig{
+		{ServerURL: "https://jellyfin.example.com", Username: "alice", Password: "secret"},
+		{ServerURL: "https://jellyfin.example.com", Username: "alice"},
+		{ServerURL: "https://jellyfin.example.com", QuickConnect: true},
+	}
+	for _, cfg := range valid {
+		if err := validateJellyfin(&cfg); err != nil {
+			t.Errorf("unexpected error for %+v: %v", cfg, err)
+		}
+	}
+
+	cfg := &JellyfinConfig{ServerURL: "https://jellyfin.example.com", Password: "secret"}
+	if err := validateJellyfin(cfg); err == nil || !strings.Contains(err.Error(), "api_key is required") {
+		t.Errorf("expected api_key error, got %v", err)
+	}
+
+	cfg = &JellyfinConfig{ServerURL: "https://jellyfin.example.com", Password: "secret", QuickConnect: true}
+	if err := validateJellyfin(cfg); err == nil || !strings.Contains(err.Error(), "password requires username") {
+		t.Errorf("expected password error, got %v", err)
+	}
+}
+
+// TestLibraryFilterAllows tests library inclusion and exclusion matching
+func TestLibraryFilterAllows(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  LibraryFilterConfig
+		library string
+		want    bool
+	}{
+		{"no filter", LibraryFilterConfig{}, "Music", true},
+		{"excluded", LibraryFilterConfig{Exclude: []string{"Home Videos"}}, "Home Videos", false},
+		{"excluded ignores case", LibraryFilterConfig{Exclude: []string{"music"}}, "Music", false},
+		{"not excluded", LibraryFilterConfig{Exclude: []string{"Music"}}, "Movies", true},
+		{"included", LibraryFilterConfig{Include: []string{"TV", "Movies"}}, "TV", true},
+		{"not included", LibraryFilterConfig{Include: []string{"TV", "Movies"}}, "Music", false},
+		{"unknown library", LibraryFilterConfig{Include: []string{"TV"}}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Allows(tt.library); got != tt.want {
+				t.Errorf("Allows(%q) = %v, want %v", tt.library, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateLibraryFilter tests library filter validation
+func TestValidateLibraryFilter(t *testing.T) {
+	tests := []struct {
+		name       string
+		filter     LibraryFilterConfig
+		errorMatch string
+	}{
+		{"empty", LibraryFilterConfig{}, ""},
+		{"include and exclude", LibraryFilterConfig{Include: []string{"TV"}, Exclude: []string{"Music"}}, ""},
+		{"blank name", LibraryFilterConfig{Exclude: []string{" "}}, "must not be empty"},
+		{"conflict", LibraryFilterConfig{Include: []string{"TV"}, Exclude: []string{"tv"}}, "both included and excluded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateLibraryFilter(&tt.filter)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// TestValidateLibrarySyncInterval tests Jellyfin library sync interval validation
+func TestValidateLibrarySyncInterval(t *testing.T) {
+	tests := []struct {
+		name       string
+		interval   time.Duration
+		errorMatch string
+	}{
+		{"off", 0, ""},
+		{"valid", 6 * time.Hour, ""},
+		{"too frequent", time.Minute, "library_sync_interval"},
+		{"too rare", 48 * time.Hour, "library_sync_interval"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &JellyfinConfig{
+				ServerURL:           "http://localhost:8096",
+				APIKey:              "key",
+				UserID:              "user",
+				LibrarySyncInterval: tt.interval,
+			}
+			err := validateJellyfin(cfg)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// Cache settings
+
+// TestValidateChecksumAlgorithm tests cache checksum algorithm validation
+func TestValidateChecksumAlgorithm(t *testing.T) {
+	tests := []struct {
+		name       string
+		algorithm  string
+		errorMatch string
+	}{
+		{"unset", "", ""},
+		{"sha256", "sha256", ""},
+		{"blake3", "blake3", ""},
+		{"xxhash", "xxhash", ""},
+		{"off", "off", ""},
+		{"unsupported", "md5", "checksum_algorithm must be one of"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &CacheConfig{
+				Directory:         t.TempDir(),
+				MaxSizeGB:         1,
+				EvictionThreshold: 0.85,
+				MetadataStore:     "boltdb",
+				ChecksumAlgorithm: tt.algorithm,
+			}
+			err := validateCache(cfg)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// TestValidateMetadataMaxAge tests metadata refresh age validation
+func TestValidateMetadataMaxAge(t *testing.T) {
+	for _, days := range []int{0, 30} {
+		cfg := &CacheConfig{Directory: t.TempDir(), MaxSizeGB: 1, EvictionThreshold: 0.85, MetadataStore: "boltdb", MetadataMaxAgeDays: days}
+		if err := validateCache(cfg); err != nil {
+			t.Errorf("unexpected error for %d days: %v", days, err)
+		}
+	}
+
+	cfg := &CacheConfig{Directory: t.TempDir(), MaxSizeGB: 1, EvictionThreshold: 0.85, MetadataStore: "boltdb", MetadataMaxAgeDays: -1}
+	if err := validateCache(cfg); err == nil || !strings.Contains(err.Error(), "metadata_max_age_days") {
+		t.Errorf("expected metadata_max_age_days error, got %v", err)
+	}
+}
+
+func TestValidateEvictionPolicy(t *testing.T) {
+	for _, policy := range []string{"", "lru", "lfu", "size", "watched"} {
+		cfg := &CacheConfig{Directory: t.TempDir(), MaxSizeGB: 1, EvictionThreshold: 0.85, MetadataStore: "boltdb", EvictionPolicy: policy}
+		if err := validateCache(cfg); err != nil {
+			t.Errorf("unexpected error for policy %q: %v", policy, err)
+		}
+	}
+
+	cfg := &CacheConfig{Directory: t.TempDir(), MaxSizeGB: 1, EvictionThreshold: 0.85, MetadataStore: "boltdb", EvictionPolicy: "fifo"}
+	if err := validateCache(cfg); err == nil || !strings.Contains(err.Error(), "eviction_policy") {
+		t.Errorf("expected eviction_policy error, got %v", err)
+	}
+}
+
+func TestValidateIntegrityScanInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, time.Hour, 24 * time.Hour} {
+		cfg := &CacheConfig{Directory: t.TempDir(), MaxSizeGB: 1, EvictionThreshold: 0.85, MetadataStore: "boltdb", IntegrityScanInterval: interval}
+		if err := validateCache(cfg); err != nil {
+			t.Errorf("unexpected error for interval %v: %v", interval, err)
+		}
+	}
+
+	cfg := &CacheConfig{Directory: t.TempDir(), MaxSizeGB: 1, EvictionThreshold: 0.85, MetadataStore: "boltdb", IntegrityScanInterval: 10 * time.Minute}
+	if err := validateCache(cfg); err == nil || !strings.Contains(err.Error(), "integrity_scan_interval") {
+		t.Errorf("expected integrity_scan_interval error, got %v", err)
+	}
+}
+
+func TestValidateCacheProbe(t *testing.T) {
+	tests := []struct {
+		name       string
+		probe      CacheProbeConfig
+		errorMatch string
+	}{
+		{"disabled", CacheProbeConfig{}, ""},
+		{"valid", CacheProbeConfig{Enabled: true, FFprobePath: "ffprobe", Timeout: 30 * time.Second}, ""},
+		{"no binary", CacheProbeConfig{Enabled: true, Timeout: 30 * time.Second}, "ffprobe_path is required"},
+		{"short timeout", CacheProbeConfig{Enabled: true, FFprobePath: "ffprobe", Timeout: time.Millisecond}, "timeout must be at least 1s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &CacheConfig{Directory: t.TempDir(), MaxSizeGB: 1, EvictionThreshold: 0.85, MetadataStore: "boltdb", Probe: tt.probe}
+			err := validateCache(cfg)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// Download settings
+
+// TestValidateInterfaceBindings tests download interface binding validation
+func TestValidateInterfaceBindings(t *testing.T) {
+	tests := []struct {
+		name       string
+		bindings   []InterfaceBindingConfig
+		errorMatch string
+	}{
+		{"no bindings", nil, ""},
+		{"valid", []InterfaceBindingConfig{{Priorities: []int{3, 4}, Interface: "wg0"}, {Priorities: []int{0}, SourceIP: "192.168.1.5"}}, ""},
+		{"both interface and ip", []InterfaceBindingConfig{{Priorities: []int{3}, Interface: "wg0", SourceIP: "10.0.0.2"}}, "exactly one"},
+		{"neither interface nor ip", []InterfaceBindingConfig{{Priorities: []int{3}}}, "exactly one"},
+		{"bad ip", []InterfaceBindingConfig{{Priorities: []int{3}, SourceIP: "not-an-ip"}}, "not a valid IP"},
+		{"no priorities", []InterfaceBindingConfig{{Interface: "wg0"}}, "priorities cannot be empty"},
+		{"priority out of range", []InterfaceBindingConfig{{Priorities: []int{5}, Interface: "wg0"}}, "between 0 and 4"},
+		{"duplicate priority", []InterfaceBindingConfig{{Priorities: []int{3}, Interface: "wg0"}, {Priorities: []int{3}, Interface: "eth1"}}, "bound more than once"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &DownloadConfig{
+				Workers:           3,
+				RateLimitMbps:     10,
+				RateLimitSchedule: RateLimitScheduleConfig{PeakLimitPercent: 25},
+				RetryAttempts:     3,
+				RetryDelay:        time.Second,
+				InterfaceBindings: tt.bindings,
+			}
+			err := validateDownload(cfg)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// TestValidateReadAhead tests read-ahead segment size validation
+func TestValidateReadAhead(t *testing.T) {
+	tests := []struct {
+		name       string
+		readAhead  int
+		errorMatch string
+	}{
+		{"off", 0, ""},
+		{"valid", 64, ""},
+		{"negative", -1, "read_ahead_mb"},
+		{"too large", 4097, "read_ahead_mb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &DownloadConfig{
+				Workers:           3,
+				RateLimitMbps:     10,
+				RateLimitSchedule: RateLimitScheduleConfig{PeakLimitPercent: 25},
+				RetryAttempts:     3,
+				RetryDelay:        time.Second,
+				ReadAheadMB:       tt.readAhead,
+			}
+			err := validateDownload(cfg)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// TestValidateStallDetection tests stall timeout and minimum throughput validation
+func TestValidateStallDetection(t *testing.T) {
+	tests := []struct {
+		name          string
+		stallTimeout  time.Duration
+		minThroughput int
+		errorMatch    string
+	}{
+		{"unset", 0, 0, ""},
+		{"valid", time.Minute, 256, ""},
+		{"too short", time.Second, 0, "stall_timeout"},
+		{"too long", time.Hour, 0, "stall_timeout"},
+		{"negative throughput", time.Minute, -1, "min_throughput_kbps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &DownloadConfig{
+				Workers:           3,
+				RateLimitMbps:     10,
+				RateLimitSchedule: RateLimitScheduleConfig{PeakLimitPercent: 25},
+				RetryAttempts:     3,
+				RetryDelay:        time.Second,
+				StallTimeout:      tt.stallTimeout,
+				MinThroughputKBps: tt.minThroughput,
+			}
+			err := validateDownload(cfg)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// TestValidateQueueLimits tests queue limit validation
+func TestValidateQueueLimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		limits     []QueueLimitConfig
+		errorMatch string
+	}{
+		{"unset", nil, ""},
+		{"valid", []QueueLimitConfig{{Priorities: []int{3, 4}, MaxItems: 50, MaxGB: 100}, {Priorities: []int{2}, MaxItems: 20}}, ""},
+		{"no caps", []QueueLimitConfig{{Priorities: []int{4}}}, "max_items or max_gb"},
+		{"negative items", []QueueLimitConfig{{Priorities: []int{4}, MaxItems: -1}}, "max_items"},
+		{"negative size", []QueueLimitConfig{{Priorities: []int{4}, MaxGB: -1}}, "max_gb"},
+		{"no priorities", []QueueLimitConfig{{MaxItems: 10}}, "priorities"},
+		{"priority out of range", []QueueLimitConfig{{Priorities: []int{5}, MaxItems: 10}}, "between 0 and 4"},
+		{"priority limited twice", []QueueLimitConfig{{Priorities: []int{4}, MaxItems: 10}, {Priorities: []int{3, 4}, MaxGB: 5}}, "queue_limits[1]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &DownloadConfig{
+				Workers:           3,
+				RateLimitMbps:     10,
+				RateLimitSchedule: RateLimitScheduleConfig{PeakLimitPercent: 25},
+				RetryAttempts:     3,
+				RetryDelay:        time.Second,
+				QueueLimits:       tt.limits,
+			}
+			err := validateDownload(cfg)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// TestPeakHours tests peak hours window evaluation
+func TestPeakHours(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, time.October, 14, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name      string
+		peakHours string
+		time      time.Time
+		peak      bool
+	}{
+		{"inside", "06:00-23:00", at(12, 0), true},
+		{"end is exclusive", "06:00-23:00", at(23, 0), false},
+		{"before", "06:00-23:00", at(5, 59), false},
+		{"overnight", "18:00-02:00", at(1, 0), true},
+		{"disabled", "", at(12, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule := RateLimitScheduleConfig{PeakHours: tt.peakHours}
+			if got := schedule.IsPeak(tt.time); got != tt.peak {
+				t.Errorf("IsPeak() = %v, want %v", got, tt.peak)
+			}
+		})
+	}
+}
+
+// TestDownloadWindows tests per-priority download window evaluation
+func TestDownloadWindows(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, time.October, 14, hour, minute, 0, 0, time.UTC)
+	}
+
+	schedule := RateLimitScheduleConfig{Windows: []DownloadWindowConfig{
+		{Priorities: []int{0, 1}, Hours: "always"},
+		{Priorities: []int{3, 4}, Hours: "01:00-06:00"},
+		{Priorities: []int{4}, Hours: "13:00-14:00"},
+	}}
+
+	tests := []struct {
+		name     string
+		priority int
+		time     time.Time
+		allowed  bool
+		next     time.Time
+	}{
+		{"always", 1, at(12, 0), true, at(12, 0)},
+		{"unlisted priority", 2, at(12, 0), true, at(12, 0)},
+		{"inside window", 3, at(2, 0), true, at(2, 0)},
+		{"end is exclusive", 3, at(6, 0), false, at(1, 0).Add(24 * time.Hour)},
+		{"second window", 4, at(13, 30), true, at(13, 30)},
+		{"nearest window opens next", 4, at(7, 0), false, at(13, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := schedule.AllowsStart(tt.priority, tt.time); got != tt.allowed {
+				t.Errorf("AllowsStart() = %v, want %v", got, tt.allowed)
+			}
+			if got := schedule.NextStart(tt.priority, tt.time); !got.Equal(tt.next) {
+				t.Errorf("NextStart() = %v, want %v", got, tt.next)
+			}
+		})
+	}
+
+	if schedule.Restricted(2) || !schedule.Restricted(3) {
+		t.Error("expected only listed priorities to be restricted")
+	}
+}
+
+// TestValidateDownloadWindows tests download window validation
+func TestValidateDownloadWindows(t *testing.T) {
+	tests := []struct {
+		name       string
+		windows    []DownloadWindowConfig
+		errorMatch string
+	}{
+		{"valid", []DownloadWindowConfig{{Priorities: []int{3, 4}, Hours: "01:00-06:00"}, {Priorities: []int{0, 1}, Hours: "always"}}, ""},
+		{"no priorities", []DownloadWindowConfig{{Hours: "01:00-06:00"}}, "priorities"},
+		{"no hours", []DownloadWindowConfig{{Priorities: []int{3}}}, "hours"},
+		{"bad hours", []DownloadWindowConfig{{Priorities: []int{3}, Hours: "1am-6am"}}, "HH:MM-HH:MM"},
+		{"priority out of range", []DownloadWindowConfig{{Priorities: []int{5}, Hours: "always"}}, "between 0 and 4"},
+		{"playback restricted", []DownloadWindowConfig{{Priorities: []int{0}, Hours: "01:00-06:00"}}, "priority 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &DownloadConfig{
+				Workers:           3,
+				RateLimitMbps:     10,
+				RateLimitSchedule: RateLimitScheduleConfig{PeakLimitPercent: 25, Windows: tt.windows},
+				RetryAttempts:     3,
+				RetryDelay:        time.Second,
+			}
+			err := validateDownload(cfg)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+func TestValidateMaxDailyGB(t *testing.T) {
+	cfg := &DownloadConfig{
+		Workers:           3,
+		RateLimitMbps:     10,
+		RateLimitSchedule: RateLimitScheduleConfig{PeakLimitPercent: 25},
+		RetryAttempts:     3,
+		RetryDelay:        time.Second,
+		MaxDailyGB:        50,
+	}
+	if err := validateDownload(cfg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	cfg.MaxDailyGB = -1
+	if err := validateDownload(cfg); err == nil || !strings.Contains(err.Error(), "max_daily_gb") {
+		t.Errorf("expected max_daily_gb error, got %v", err)
+	}
+}
+
+func TestValidateLocalSource(t *testing.T) {
+	tests := []struct {
+		name       string
+		local      LocalSourceConfig
+		errorMatch string
+	}{
+		{"unset", LocalSourceConfig{}, ""},
+		{"valid", LocalSourceConfig{Enabled: true, Mode: "auto", PathMappings: []PathMappingConfig{{From: "/media", To: "/mnt/media"}}}, ""},
+		{"unknown mode", LocalSourceConfig{Enabled: true, Mode: "symlink"}, "mode"},
+		{"mapping without target", LocalSourceConfig{Enabled: true, PathMappings: []PathMappingConfig{{From: "/media"}}}, "path_mappings[0]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &DownloadConfig{
+				Workers:           3,
+				RateLimitMbps:     10,
+				RateLimitSchedule: RateLimitScheduleConfig{PeakLimitPercent: 25},
+				RetryAttempts:     3,
+				RetryDelay:        time.Second,
+				LocalSource:       tt.local,
+			}
+			err := validateDownload(cfg)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// Server settings
+
+// TestValidateWebDAV tests WebDAV export validation
+func TestValidateWebDAV(t *testing.T) {
+	tests := []struct {
+		name       string
+		webdav     WebDAVConfig
+		errorMatch string
+	}{
+		{"valid shared port", WebDAVConfig{Path: "/dav", Username: "u", Password: "p"}, ""},
+		{"valid separate port", WebDAVConfig{Port: 8081, Path: "/dav", Username: "u", Password: "p"}, ""},
+		{"missing credentials", WebDAVConfig{Path: "/dav", Username: "u"}, "username and password are required"},
+		{"same port as server", WebDAVConfig{Port: 8080, Path: "/dav", Username: "u", Password: "p"}, "must differ"},
+		{"root path", WebDAVConfig{Path: "/", Username: "u", Password: "p"}, "cannot be the root"},
+		{"relative path", WebDAVConfig{Path: "dav", Username: "u", Password: "p"}, "must start with /"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateWebDAV(&tt.webdav, 8080)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// TestValidateKiosk tests kiosk PIN validation
+func TestValidateKiosk(t *testing.T) {
+	tests := []struct {
+		name       string
+		pin        string
+		errorMatch string
+	}{
+		{"valid", "2468", ""},
+		{"long", "123456789012", ""},
+		{"missing", "", "4 to 12 digits"},
+		{"too short", "123", "4 to 12 digits"},
+		{"too long", "1234567890123", "4 to 12 digits"},
+		{"not digits", "12ab", "only digits"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateKiosk(&KioskConfig{Enabled: true, PIN: tt.pin})
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// TestValidateAuth tests sign-in mode validation
+func TestValidateAuth(t *testing.T) {
+	tests := []struct {
+		name       string
+		auth       AuthConfig
+		errorMatch string
+	}{
+		{"password", AuthConfig{Mode: "password", Password: "correct horse"}, ""},
+		{"short password", AuthConfig{Mode: "password", Password: "hunter2"}, "at least 8"},
+		{"proxy", AuthConfig{Mode: "proxy", ProxyHeader: "Remote-User", TrustedProxies: []string{"127.0.0.1", "10.0.0.0/8", "::1"}}, ""},
+		{"proxy without proxies", AuthConfig{Mode: "proxy", ProxyHeader: "Remote-User"}, "trusted_proxies"},
+		{"bad proxy", AuthConfig{Mode: "proxy", ProxyHeader: "Remote-User", TrustedProxies: []string{"proxy.lan"}}, "not an IP"},
+		{"jellyfin", AuthConfig{Mode: "jellyfin"}, ""},
+		{"unknown mode", AuthConfig{Mode: "ldap"}, "mode must be"},
+		{"short session", AuthConfig{Mode: "jellyfin", SessionTTL: time.Second}, "session_ttl"},
+		{"short secret", AuthConfig{Mode: "jellyfin", SessionSecret: "abc"}, "session_secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.auth.SessionTTL == 0 {
+				tt.auth.SessionTTL = time.Hour
+			}
+			err := validateAuth(&tt.auth)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// TestValidateSharing tests public share link validation
+func TestValidateSharing(t *testing.T) {
+	tests := []struct {
+		name       string
+		server     ServerConfig
+		errorMatch string
+	}{
+		{"valid shared port", ServerConfig{Port: 8080, Sharing: SharingConfig{Enabled: true, MaxTTL: time.Hour}}, ""},
+		{"valid separate port", ServerConfig{Port: 8080, Sharing: SharingConfig{Enabled: true, Port: 8082, MaxTTL: time.Hour, BaseURL: "https://share.example.com"}}, ""},
+		{"same port as server", ServerConfig{Port: 8080, Sharing: SharingConfig{Enabled: true, Port: 8080, MaxTTL: time.Hour}}, "must differ from the main"},
+		{"same port as webdav", ServerConfig{Port: 8080, WebDAV: WebDAVConfig{Enabled: true, Port: 8081}, Sharing: SharingConfig{Enabled: true, Port: 8081, MaxTTL: time.Hour}}, "webdav port"},
+		{"zero max ttl", ServerConfig{Port: 8080, Sharing: SharingConfig{Enabled: true}}, "max_ttl"},
+		{"bad base url", ServerConfig{Port: 8080, Sharing: SharingConfig{Enabled: true, MaxTTL: time.Hour, BaseURL: "share.example.com"}}, "base_url"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSharing(&tt.server)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+func TestValidateDeviceProfiles(t *testing.T) {
+	tests := []struct {
+		name       string
+		profiles   map[string]DeviceProfileConfig
+		errorMatch string
+	}{
+		{"unset", nil, ""},
+		{"valid", map[string]DeviceProfileConfig{"tablet": {MaxQuality: "720p", MaxBitrateMbps: 2}, "tv": {MaxQuality: "original"}}, ""},
+		{"unknown quality", map[string]DeviceProfileConfig{"tablet": {MaxQuality: "4k"}}, "device_profiles.tablet.max_quality"},
+		{"negative bitrate", map[string]DeviceProfileConfig{"phone": {MaxQuality: "480p", MaxBitrateMbps: -1}}, "max_bitrate_mbps"},
+		{"empty name", map[string]DeviceProfileConfig{" ": {MaxQuality: "720p"}}, "profile name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &UIConfig{Theme: "auto", VideoQualityPreference: "original", DeviceProfiles: tt.profiles}
+			err := validateUI(cfg)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+func TestValidateDLNA(t *testing.T) {
+	tests := []struct {
+		name       string
+		dlna       DLNAConfig
+		webdav     WebDAVConfig
+		errorMatch string
+	}{
+		{"valid", DLNAConfig{Enabled: true, Port: 8200, FriendlyName: "Cache"}, WebDAVConfig{}, ""},
+		{"no port", DLNAConfig{Enabled: true, FriendlyName: "Cache"}, WebDAVConfig{}, "port must be between"},
+		{"same port as server", DLNAConfig{Enabled: true, Port: 8080, FriendlyName: "Cache"}, WebDAVConfig{}, "main server port"},
+		{"same port as webdav", DLNAConfig{Enabled: true, Port: 8081, FriendlyName: "Cache"}, WebDAVConfig{Enabled: true, Port: 8081}, "webdav port"},
+		{"no name", DLNAConfig{Enabled: true, Port: 8200, FriendlyName: " "}, WebDAVConfig{}, "friendly_name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDLNA(&ServerConfig{Port: 8080, DLNA: tt.dlna, WebDAV: tt.webdav})
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// Prediction settings
+
+// TestSeasonalRuleIsActive tests date range matching, including year-end wrap
+func TestSeasonalRuleIsActive(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   SeasonalRule
+		date   time.Time
+		active bool
+	}{
+		{"inside range", SeasonalRule{Start: "10-20", End: "10-31"}, time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC), true},
+		{"range boundary", SeasonalRule{Start: "10-20", End: "10-31"}, time.Date(2026, 10, 31, 23, 0, 0, 0, time.UTC), true},
+		{"outside range", SeasonalRule{Start: "10-20", End: "10-31"}, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), false},
+		{"wrapped range after start", SeasonalRule{Start: "12-20", End: "01-02"}, time.Date(2026, 12, 28, 0, 0, 0, 0, time.UTC), true},
+		{"wrapped range before end", SeasonalRule{Start: "12-20", End: "01-02"}, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), true},
+		{"wrapped range outside", SeasonalRule{Start: "12-20", End: "01-02"}, time.Date(2027, 1, 3, 0, 0, 0, 0, time.UTC), false},
+		{"invalid dates", SeasonalRule{Start: "bad", End: "01-02"}, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.IsActive(tt.date); got != tt.active {
+				t.Errorf("IsActive() = %v, want %v", got, tt.active)
+			}
+		})
+	}
+}
+
+// TestValidateSeasonalRule tests seasonal rule validation
+func TestValidateSeasonalRule(t *testing.T) {
+	tests := []struct {
+		name       string
+		rule       SeasonalRule
+		errorMatch string
+	}{
+		{"valid", SeasonalRule{Start: "12-01", End: "12-31", Genres: []string{"Holiday"}, Boost: 0.2}, ""},
+		{"bad start", SeasonalRule{Start: "2026-12-01", End: "12-31", Genres: []string{"Holiday"}}, "start must be"},
+		{"bad end", SeasonalRule{Start: "12-01", End: "13-01", Genres: []string{"Holiday"}}, "end must be"},
+		{"no genres", SeasonalRule{Start: "12-01", End: "12-31"}, "genres cannot be empty"},
+		{"boost too large", SeasonalRule{Start: "12-01", End: "12-31", Genres: []string{"Holiday"}, Boost: 2}, "boost must be"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSeasonalRule(&tt.rule)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// TestValidateDeviceQuality tests per-device quality validation
+func TestValidateDeviceQuality(t *testing.T) {
+	tests := []struct {
+		name          string
+		deviceQuality map[string]string
+		errorMatch    string
+	}{
+		{"valid", map[string]string{"phone": "720p", "tv": "original"}, ""},
+		{"empty", nil, ""},
+		{"unknown device", map[string]string{"watch": "480p"}, "device must be one of"},
+		{"unknown quality", map[string]string{"phone": "4k"}, "device_quality.phone"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &PredictionConfig{HistoryDays: 30, MinConfidence: 0.5, DeviceQuality: tt.deviceQuality}
+			err := validatePrediction(cfg)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// TestValidateHouseholdUsers tests household user list validation
+func TestValidateHouseholdUsers(t *testing.T) {
+	tests := []struct {
+		name       string
+		users      []string
+		errorMatch string
+	}{
+		{"valid", []string{"alice", "bob"}, ""},
+		{"empty list", nil, ""},
+		{"empty id", []string{"alice", ""}, "household_users[1]"},
+		{"duplicate", []string{"alice", "alice"}, "more than once"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &PredictionConfig{HistoryDays: 30, MinConfidence: 0.5, HouseholdUsers: tt.users}
+			err := validatePrediction(cfg)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// TestValidateSessionSyncInterval tests Jellyfin session polling interval validation
+func TestValidateSessionSyncInterval(t *testing.T) {
+	tests := []struct {
+		name       string
+		interval   time.Duration
+		errorMatch string
+	}{
+		{"off", 0, ""},
+		{"valid", time.Minute, ""},
+		{"too frequent", time.Second, "session_sync_interval"},
+		{"too rare", 2 * time.Hour, "session_sync_interval"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &PredictionConfig{HistoryDays: 30, MinConfidence: 0.5, SessionSyncInterval: tt.interval}
+			err := validatePrediction(cfg)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// TestValidateNextUpLimit tests Next Up warmer limit validation
+func TestValidateNextUpLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		limit      int
+		errorMatch string
+	}{
+		{"unset", 0, ""},
+		{"valid", 25, ""},
+		{"negative", -1, "next_up_limit"},
+		{"too large", 101, "next_up_limit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &PredictionConfig{HistoryDays: 30, MinConfidence: 0.5, WarmNextUp: true, NextUpLimit: tt.limit}
+			err := validatePrediction(cfg)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// Notifications settings
+
+// TestValidateNotifications tests email and webhook sink validation
+func TestValidateNotifications(t *testing.T) {
+	validEmail := EmailConfig{Enabled: true, SMTPHost: "smtp.example.com", SMTPPort: 587, From: "a@example.com", To: []string{"b@example.com"}}
+
+	tests := []struct {
+		name       string
+		config     NotificationsConfig
+		errorMatch string
+	}{
+		{"disabled", NotificationsConfig{}, ""},
+		{"valid email", NotificationsConfig{Email: validEmail}, ""},
+		{"missing host", NotificationsConfig{Email: EmailConfig{Enabled: true, SMTPPort: 587}}, "smtp_host is required"},
+		{"missing recipients", NotificationsConfig{Email: EmailConfig{Enabled: true, SMTPHost: "h", SMTPPort: 587, From: "a@example.com"}}, "from and to"},
+		{"valid webhook", NotificationsConfig{Webhook: WebhookConfig{Enabled: true, URL: "https://hooks.example.com/x"}}, ""},
+		{"bad webhook url", NotificationsConfig{Webhook: WebhookConfig{Enabled: true, URL: "hooks.example.com"}}, "must start with http"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateNotifications(&tt.config)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// TestQuietHoursIsActive tests quiet hours windows, including ones crossing midnight
+func TestQuietHoursIsActive(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, time.October, 14, hour, minute, 0, 0, time.UTC)
+	}
+
+	overnight := QuietHoursConfig{Enabled: true, Start: "22:00", End: "07:00"}
+	daytime := QuietHoursConfig{Enabled: true, Start: "09:00", End: "17:30"}
+
+	tests := []struct {
+		name   string
+		config QuietHoursConfig
+		time   time.Time
+		active bool
+	}{
+		{"overnight late evening", overnight, at(23, 15), true},
+		{"overnight early morning", overnight, at(6, 59), true},
+		{"overnight end is exclusive", overnight, at(7, 0), false},
+		{"overnight afternoon", overnight, at(15, 0), false},
+		{"daytime inside", daytime, at(17, 29), true},
+		{"daytime outside", daytime, at(18, 0), false},
+		{"disabled", QuietHoursConfig{Start: "00:00", End: "23:59"}, at(12, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.IsActive(tt.time); got != tt.active {
+				t.Errorf("IsActive() = %v, want %v", got, tt.active)
+			}
+		})
+	}
+}
+
+// TestValidateQuietHours tests quiet hours validation
+func TestValidateQuietHours(t *testing.T) {
+	tests := []struct {
+		name       string
+		quiet      QuietHoursConfig
+		errorMatch string
+	}{
+		{"valid", QuietHoursConfig{Enabled: true, Start: "22:00", End: "07:00"}, ""},
+		{"disabled ignores format", QuietHoursConfig{Start: "late"}, ""},
+		{"bad start", QuietHoursConfig{Enabled: true, Start: "10pm", End: "07:00"}, "start must be in HH:MM"},
+		{"empty window", QuietHoursConfig{Enabled: true, Start: "07:00", End: "07:00"}, "must differ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateNotifications(&NotificationsConfig{QuietHours: tt.quiet})
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// Reports settings
+
+// TestValidateReports tests weekly report schedule validation
+func TestValidateReports(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     ReportsConfig
+		errorMatch string
+	}{
+		{"valid", ReportsConfig{Weekday: "Sunday", Time: "09:00"}, ""},
+		{"bad weekday", ReportsConfig{Weekday: "sun", Time: "09:00"}, "not a valid day name"},
+		{"bad time", ReportsConfig{Weekday: "monday", Time: "9am"}, "HH:MM"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateReports(&tt.config)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// Maintenance settings
+
+// TestMaintenanceWindow tests maintenance window activity and closing times
+func TestMaintenanceWindow(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.October, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	early := MaintenanceConfig{Enabled: true, Start: "03:00", End: "05:00"}
+	overnight := MaintenanceConfig{Enabled: true, Start: "23:00", End: "02:00"}
+
+	tests := []struct {
+		name   string
+		config MaintenanceConfig
+		time   time.Time
+		active bool
+		end    time.Time
+	}{
+		{"inside", early, at(14, 4, 30), true, at(14, 5, 0)},
+		{"end is exclusive", early, at(14, 5, 0), false, time.Time{}},
+		{"outside", early, at(14, 12, 0), false, time.Time{}},
+		{"overnight before midnight", overnight, at(14, 23, 30), true, at(15, 2, 0)},
+		{"overnight after midnight", overnight, at(15, 1, 0), true, at(15, 2, 0)},
+		{"disabled never restricts", MaintenanceConfig{Start: "03:00", End: "05:00"}, at(14, 12, 0), true, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.IsActive(tt.time); got != tt.active {
+				t.Errorf("IsActive() = %v, want %v", got, tt.active)
+			}
+			if got := tt.config.WindowEnd(tt.time); !got.Equal(tt.end) {
+				t.Errorf("WindowEnd() = %v, want %v", got, tt.end)
+			}
+		})
+	}
+}
+
+// TestValidateMaintenance tests maintenance window validation
+func TestValidateMaintenance(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     MaintenanceConfig
+		errorMatch string
+	}{
+		{"valid", MaintenanceConfig{Enabled: true, Start: "03:00", End: "05:00"}, ""},
+		{"disabled ignores format", MaintenanceConfig{Start: "night"}, ""},
+		{"bad start", MaintenanceConfig{Enabled: true, Start: "3am", End: "05:00"}, "start must be"},
+		{"bad end", MaintenanceConfig{Enabled: true, Start: "03:00", End: "25:00"}, "end must be"},
+		{"empty window", MaintenanceConfig{Enabled: true, Start: "03:00", End: "03:00"}, "must differ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMaintenance(&tt.config)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// Chaos settings
+
+// TestValidateChaos tests fault injection rate validation
+func TestValidateChaos(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     ChaosConfig
+		errorMatch string
+	}{
+		{"disabled", ChaosConfig{}, ""},
+		{"valid", ChaosConfig{Enabled: true, DownloadFailureRate: 0.2, JellyfinErrorRate: 1, SlowReadDelay: time.Millisecond}, ""},
+		{"rate above one", ChaosConfig{StorageErrorRate: 1.5}, "storage_error_rate"},
+		{"negative rate", ChaosConfig{DownloadFailureRate: -0.1}, "download_failure_rate"},
+		{"negative delay", ChaosConfig{SlowReadDelay: -time.Second}, "slow_read_delay"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateChaos(&tt.config)
+			if tt.errorMatch == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMatch, err)
+			}
+		})
+	}
+}
+
+// Timezone settings
+
+// TestDailyWindowDST tests that DST transitions neither skip nor repeat a window
+func TestDailyWindowDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	utc := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	// Clocks go forward at 02:00 EST on March 8 2026 (07:00 UTC) and back
+	// at 02:00 EDT on November 1 2026 (06:00 UTC)
+	tests := []struct {
+		name       string
+		start, end string
+		time       time.Time
+		active     bool
+		closes     time.Time
+	}{
+		{"window in skipped hour moves after the jump", "02:00", "02:30", utc(time.March, 8, 7, 15), true, utc(time.March, 8, 7, 30)},
+		{"window in skipped hour is not early", "02:00", "02:30", utc(time.March, 8, 6, 15), false, time.Time{}},
+		{"window closing in skipped hour", "01:30", "02:30", utc(time.March, 8, 7, 15), true, utc(time.March, 8, 7, 30)},
+		{"repeated hour, first pass", "01:00", "02:00", utc(time.November, 1, 5, 30), true, utc(time.November, 1, 7, 0)},
+		{"repeated hour, second pass", "01:00", "02:00", utc(time.November, 1, 6, 30), true, utc(time.November, 1, 7, 0)},
+		{"window ending in repeated hour runs once", "00:30", "01:30", utc(time.November, 1, 6, 15), false, time.Time{}},
+		{"overnight across fall back", "23:00", "03:00", utc(time.November, 1, 7, 30), true, utc(time.November, 1, 8, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, closes, active := dailyWindow(tt.start, tt.end, tt.time, ny)
+			if active != tt.active {
+				t.Errorf("active = %v, want %v", active, tt.active)
+			}
+			if !closes.Equal(tt.closes) {
+				t.Errorf("closes = %v, want %v", closes, tt.closes)
+			}
+		})
+	}
+}
+
+// TestTimezone tests that windows are evaluated in the configured time zone
+func TestTimezone(t *testing.T) {
+	if _, err := time.LoadLocation("Asia/Tokyo"); err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	cfg := Default()
+	cfg.Timezone = "Asia/Tokyo"
+	cfg.Download.RateLimitSchedule.PeakHours = "18:00-23:00"
+	cfg.Maintenance = MaintenanceConfig{Enabled: true, Start: "03:00", End: "05:00"}
+	cfg.applyTimezone()
+
+	// 19:00 UTC is 04:00 the next day in Tokyo
+	at := time.Date(2026, time.October, 14, 19, 0, 0, 0, time.UTC)
+	if !cfg.Maintenance.IsActive(at) {
+		t.Error("expected the maintenance window to be open at 04:00 Tokyo time")
+	}
+	if cfg.Download.RateLimitSchedule.IsPeak(at) {
+		t.Error("expected 04:00 Tokyo time to be off-peak")
+	}
+	if got := cfg.Reports.InZone(at); got.Hour() != 4 {
+		t.Errorf("InZone() hour = %d, want 4", got.Hour())
+	}
+
+	if _, err := (&Config{Timezone: "Mars/Olympus_Mons"}).Location(); err == nil {
+		t.Error("expected an unknown time zone to be rejected")
+	}
+	if loc, err := (&Config{}).Location(); err != nil || loc != time.Local {
+		t.Errorf("expected an empty time zone to mean local time, got %v, %v", loc, err)
+	}
+}
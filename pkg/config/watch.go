@@ -0,0 +1,182 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/knadh/koanf/providers/file"
+)
+
+// watchDebounce is how long the watcher waits after the config file changes
+// before reloading it, so an editor's several writes reload it once.
+const watchDebounce = 500 * time.Millisecond
+
+// subscriberBuffer is how many updates a subscriber may fall behind by
+// before new ones are dropped for it.
+const subscriberBuffer = 4
+
+// ConfigUpdated is sent to subscribers when the config file was reloaded
+// and its configuration changed.
+type ConfigUpdated struct {
+	Previous *Config
+	Config   *Config
+	// Changed names the sections of the config file that differ, such as
+	// "download" or "cache"
+	Changed []string
+}
+
+// Watcher reloads the config file when it changes on disk or the process
+// receives SIGHUP. A reloaded configuration that fails validation is
+// logged and ignored, leaving the current one in effect.
+type Watcher struct {
+	path   string
+	args   []string
+	logger *slog.Logger
+
+	mu          sync.Mutex
+	current     *Config
+	subscribers []chan ConfigUpdated
+}
+
+// NewWatcher creates a watcher for the config file at path, which current
+// was loaded from along with the flags in args. Every reload applies the
+// environment and those flags over the file again.
+func NewWatcher(path string, current *Config, logger *slog.Logger, args ...string) *Watcher {
+	return &Watcher{
+		path:    path,
+		args:    args,
+		logger:  logger,
+		current: current,
+	}
+}
+
+// Subscribe returns a channel every configuration change is sent on. A
+// subscriber that falls behind misses updates rather than blocking the
+// reload; the Config of the latest one it receives is always current.
+func (w *Watcher) Subscribe() <-chan ConfigUpdated {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ch := make(chan ConfigUpdated, subscriberBuffer)
+	w.subscribers = append(w.subscribers, ch)
+	return ch
+}
+
+// Current returns the configuration in effect.
+func (w *Watcher) Current() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// Reload reads and validates the config file and, if it changed, notifies
+// subscribers. reason is logged, e.g. "SIGHUP".
+func (w *Watcher) Reload(reason string) error {
+	updated, err := Load(w.path, w.args...)
+	if err != nil {
+		w.logger.Error("Ignoring config file change", "reason", reason, "error", err)
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	changed := changedSections(w.current, updated)
+	if len(changed) == 0 {
+		w.logger.Debug("Config file reloaded without changes", "reason", reason)
+		return nil
+	}
+
+	update := ConfigUpdated{Previous: w.current, Config: updated, Changed: changed}
+	w.current = updated
+	w.logger.Info("Config file reloaded", "reason", reason, "changed", changed)
+
+	for _, ch := range w.subscribers {
+		select {
+		case ch <- update:
+		default:
+			w.logger.Warn("Dropping config update for a slow subscriber")
+		}
+	}
+	return nil
+}
+
+// Run reloads the config file whenever it is written or the process
+// receives SIGHUP, until ctx is cancelled. Subscriber channels are closed
+// when it returns.
+func (w *Watcher) Run(ctx context.Context) error {
+	defer w.closeSubscribers()
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	// The provider watches the file's directory, so editors that replace
+	// the file rather than write to it are noticed too
+	written := make(chan struct{}, 1)
+	provider := file.Provider(w.path)
+	if err := provider.Watch(func(_ interface{}, err error) {
+		if err != nil {
+			w.logger.Warn("Stopped watching config file", "path", w.path, "error", err)
+			return
+		}
+		select {
+		case written <- struct{}{}:
+		default:
+		}
+	}); err != nil {
+		return fmt.Errorf("failed to watch config file %s: %w", w.path, err)
+	}
+	defer provider.Unwatch()
+
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	w.logger.Info("Watching config file for changes", "path", w.path)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hangup:
+			w.Reload("SIGHUP")
+		case <-written:
+			debounce.Reset(watchDebounce)
+		case <-debounce.C:
+			w.Reload("file changed")
+		}
+	}
+}
+
+func (w *Watcher) closeSubscribers() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, ch := range w.subscribers {
+		close(ch)
+	}
+	w.subscribers = nil
+}
+
+// changedSections returns the koanf names of the top-level sections that
+// differ between previous and updated, in the order Config declares them.
+func changedSections(previous, updated *Config) []string {
+	before := reflect.ValueOf(previous).Elem()
+	after := reflect.ValueOf(updated).Elem()
+	fields := before.Type()
+
+	var changed []string
+	for i := 0; i < fields.NumField(); i++ {
+		if !reflect.DeepEqual(before.Field(i).Interface(), after.Field(i).Interface()) {
+			changed = append(changed, fields.Field(i).Tag.Get("koanf"))
+		}
+	}
+	return changed
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func writeWatchedConfig(t *testing.T, path, cacheDir string, rateLimit int) {
+	t.Helper()
+	yaml := `
+jellyfin:
+  server_url: "https://jellyfin.example.com"
+  api_key: "test-api-key"
+  user_id: "test-user-id"
+cache:
+  directory: "` + cacheDir + `"
+download:
+  rate_limit_mbps: ` + strconv.Itoa(rateLimit) + `
+`
+	if err := os.WriteFile(path, []byte(yaml), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+}
+
+func TestWatcherReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	writeWatchedConfig(t, path, filepath.Join(dir, "cache"), 50)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	watcher := NewWatcher(path, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	updates := watcher.Subscribe()
+
+	// Nothing is sent when the file did not change
+	if err := watcher.Reload("test"); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	select {
+	case update := <-updates:
+		t.Fatalf("expected no update, got %v", update.Changed)
+	default:
+	}
+
+	writeWatchedConfig(t, path, filepath.Join(dir, "cache"), 20)
+	if err := watcher.Reload("test"); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	update := <-updates
+	if !reflect.DeepEqual(update.Changed, []string{"download"}) {
+		t.Errorf("Changed = %v, want [download]", update.Changed)
+	}
+	if update.Previous != cfg || update.Config.Download.RateLimitMbps != 20 || watcher.Current() != update.Config {
+		t.Errorf("expected the reloaded config to be current, got rate limit %d", update.Config.Download.RateLimitMbps)
+	}
+
+	// An invalid file leaves the current configuration in effect
+	if err := os.WriteFile(path, []byte("jellyfin:\n  server_url: \"\"\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if err := watcher.Reload("test"); err == nil {
+		t.Error("expected an invalid config file to be rejected")
+	}
+	if watcher.Current() != update.Config {
+		t.Error("expected the invalid config file to be ignored")
+	}
+}
+
+func TestWatcherRun(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	writeWatchedConfig(t, path, filepath.Join(dir, "cache"), 50)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	watcher := NewWatcher(path, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	updates := watcher.Subscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- watcher.Run(ctx) }()
+
+	// Give the watcher a moment to start before writing
+	time.Sleep(100 * time.Millisecond)
+	writeWatchedConfig(t, path, filepath.Join(dir, "cache"), 10)
+
+	select {
+	case update := <-updates:
+		if update.Config.Download.RateLimitMbps != 10 {
+			t.Errorf("expected rate limit 10, got %d", update.Config.Download.RateLimitMbps)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the file change to be noticed")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+	if _, ok := <-updates; ok {
+		t.Error("expected the updates channel to be closed")
+	}
+}
@@ -0,0 +1,507 @@
+// Package jfwatch embeds the go-jf-watch caching engine in another Go
+// program: a media center frontend, a home automation service or a test
+// harness can queue downloads, report playback and follow progress without
+// running the web server or touching the internal packages.
+//
+// A minimal embedding:
+//
+//	cfg := config.Default()
+//	cfg.Jellyfin.ServerURL = "http://jellyfin.local:8096"
+//	cfg.Jellyfin.APIKey = apiKey
+//	cfg.Jellyfin.UserID = userID
+//	cfg.Cache.Directory = "/var/cache/jfwatch"
+//
+//	engine, err := jfwatch.New(cfg, jfwatch.WithLogger(logger))
+//	if err != nil {
+//		return err
+//	}
+//	if err := engine.Start(ctx); err != nil {
+//		return err
+//	}
+//	defer engine.Stop()
+//
+//	go func() {
+//		for event := range engine.Events() {
+//			log.Printf("%s %s %.0f%%", event.MediaID, event.Status, event.Progress)
+//		}
+//	}()
+//
+//	engine.Queue(ctx, itemID, 1)
+//
+// The engine owns its cache directory; do not run it alongside a
+// go-jf-watch server pointed at the same directory.
+package jfwatch
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/chaos"
+	"github.com/opd-ai/go-jf-watch/internal/downloader"
+	"github.com/opd-ai/go-jf-watch/internal/jellyfin"
+	"github.com/opd-ai/go-jf-watch/internal/media"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+// defaultEventBuffer is how many events Events buffers before new ones are
+// dropped.
+const defaultEventBuffer = 64
+
+// ErrLibraryExcluded is returned by Queue for items in a library the
+// configured library filter leaves out.
+var ErrLibraryExcluded = downloader.ErrLibraryExcluded
+
+// ErrQueueFull is returned by Queue when the priority's queue limit is
+// reached. The error is a *downloader.QueueFullError wrapping it.
+var ErrQueueFull = downloader.ErrQueueFull
+
+// ErrInsufficientSpace is returned by Queue when the item does not fit in
+// the cache. The error is a *downloader.InsufficientSpaceError wrapping it.
+var ErrInsufficientSpace = downloader.ErrInsufficientSpace
+
+// Event is a download progress update.
+type Event struct {
+	MediaID string
+	// Status is one of "queued", "downloading", "completed" or "failed".
+	Status   string
+	Message  string
+	Progress float64 // percent, 0-100
+	// Speed, in bytes per second, and ETA are set on the updates sent
+	// every second while an item downloads; ETA is zero when not known.
+	Speed int64
+	ETA   time.Duration
+	Time  time.Time
+}
+
+// Status summarizes the download queue.
+type Status struct {
+	QueueSize       int
+	ActiveDownloads int
+	CompletedToday  int
+	FailedToday     int
+	// Download attempts since the engine was created that resumed a partial
+	// file, and those that started from byte 0
+	ResumedDownloads int
+	ColdStarts       int
+	// Offline is set while the Jellyfin server is unreachable; cached
+	// items stay playable and syncs are replayed when it returns
+	Offline bool
+}
+
+// Option customizes an Engine.
+type Option func(*Engine)
+
+// WithLogger sets the logger the engine and its components write to,
+// slog.Default() by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(e *Engine) {
+		e.logger = logger
+	}
+}
+
+// WithEventBuffer sets how many events Events buffers for a slow reader
+// before new events are dropped.
+func WithEventBuffer(n int) Option {
+	return func(e *Engine) {
+		e.eventBuffer = n
+	}
+}
+
+// Engine is a running cache: storage, the Jellyfin client, the download
+// manager, the predictor, the cache warmers, the metadata refresher, the
+// integrity scanner, the session syncer and library sync, wired as the
+// server wires them.
+type Engine struct {
+	config      *config.Config
+	logger      *slog.Logger
+	eventBuffer int
+
+	storage   *storage.Manager
+	jellyfin  *jellyfin.Client
+	downloads *downloader.Manager
+	predictor *downloader.Predictor
+	warmer    *downloader.Warmer
+	refresher *downloader.MetadataRefresher
+	integrity *downloader.IntegrityScanner
+	sessions  *downloader.SessionSyncer
+	library   *jellyfin.LibrarySync
+	readAhead *downloader.ReadAhead
+	providers *media.Registry
+
+	subscriptions *downloader.Subscriptions
+	collections   *downloader.Collections
+
+	// eventsMu guards events separately from mu so workers reporting
+	// progress never wait on a Stop that is waiting for them
+	eventsMu     sync.Mutex
+	events       chan Event
+	eventsClosed bool
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	running bool
+	closed  bool
+}
+
+// New validates cfg and builds an engine from it. Nothing runs and nothing
+// is contacted until Start is called, but the cache database is opened, so
+// Stop must be called to release it even if Start never is.
+func New(cfg *config.Config, opts ...Option) (*Engine, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config is nil")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	e := &Engine{
+		config:      cfg,
+		logger:      slog.Default(),
+		eventBuffer: defaultEventBuffer,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if e.eventBuffer < 0 {
+		e.eventBuffer = 0
+	}
+	e.events = make(chan Event, e.eventBuffer)
+
+	sm, err := storage.NewManager(&cfg.Cache, e.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache: %w", err)
+	}
+	e.storage = sm
+
+	faults := chaos.New(&cfg.Chaos, e.logger)
+
+	e.jellyfin = jellyfin.New(&cfg.Jellyfin, e.logger)
+	e.jellyfin.SetFaultInjector(faults)
+	e.jellyfin.SetTokenStore(sm)
+
+	e.providers = media.NewRegistry(media.NewJellyfinProvider(e.jellyfin))
+	if dir := cfg.LocalLibrary.Directory; dir != "" {
+		local, err := media.NewLocalProvider(dir, e.logger)
+		if err != nil {
+			sm.Close()
+			return nil, err
+		}
+		e.providers.Register(local)
+	}
+
+	e.downloads = downloader.New(&cfg.Download, sm, e.logger)
+	e.downloads.SetLibraryFilter(&cfg.Jellyfin.Libraries)
+	e.downloads.SetProgressReporter(e)
+	e.downloads.SetFaultInjector(faults)
+	e.downloads.SetCapacityManager(storage.NewCacheManager(&cfg.Cache, sm, e.logger))
+	e.downloads.SetCipher(sm.Cipher())
+	e.downloads.SetVariantSelector(e.jellyfin, cfg.UI.VideoQualityPreference)
+
+	e.predictor = downloader.NewPredictor(sm, &cfg.Prediction, e.logger)
+	e.predictor.SetDownloadManager(e.downloads)
+	e.predictor.SetLibraryFilter(&cfg.Jellyfin.Libraries)
+	e.predictor.SetMetadataSource(e.jellyfin)
+	if err := e.predictor.RestoreState(); err != nil {
+		e.logger.Warn("Starting predictor without saved state", "error", err)
+	}
+
+	e.warmer = downloader.NewWarmer(e.jellyfin, sm, e.downloads, &cfg.Prediction, e.logger)
+	e.refresher = downloader.NewMetadataRefresher(e.jellyfin, sm, &cfg.Cache, e.logger)
+	e.integrity = downloader.NewIntegrityScanner(sm, e.downloads, cfg.Cache.IntegrityScanInterval, e.logger)
+
+	e.readAhead = downloader.NewReadAhead(e.downloads, e.logger)
+
+	e.library = jellyfin.NewLibrarySync(e.jellyfin, sm, cfg.Jellyfin.LibrarySyncInterval, e.logger)
+	e.library.OnChange(e.predictor.LibraryChanged)
+
+	e.subscriptions = downloader.NewSubscriptions(sm, e.downloads, cfg.Download.SubscriptionPriority, e.logger)
+	e.library.OnChange(e.subscriptions.LibraryChanged)
+
+	e.collections = downloader.NewCollections(e.jellyfin, sm, e.downloads, cfg.Jellyfin.LibrarySyncInterval, e.logger)
+
+	return e, nil
+}
+
+// Start connects to Jellyfin and starts the download workers, the
+// prediction loop, the cache warmers, the metadata refresher, the integrity
+// scanner, the session syncer, library and collection sync and the
+// connectivity monitor. They run until Stop is called or ctx is cancelled.
+func (e *Engine) Start(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return fmt.Errorf("engine is stopped")
+	}
+	if e.running {
+		return fmt.Errorf("engine is already running")
+	}
+
+	if err := e.jellyfin.Connect(ctx); err != nil {
+		return err
+	}
+
+	// Built after connecting, since logging in fills in the user ID
+	users := e.config.Prediction.HouseholdUsers
+	if len(users) == 0 {
+		users = []string{e.config.Jellyfin.UserID}
+	}
+	e.sessions = downloader.NewSessionSyncer(e.jellyfin, e.storage, e.predictor, users, &e.config.Prediction, e.logger)
+	e.sessions.SetConnectivity(e.jellyfin.Connectivity())
+
+	ctx, cancel := context.WithCancel(ctx)
+	if err := e.downloads.Start(ctx); err != nil {
+		cancel()
+		return err
+	}
+	e.cancel = cancel
+
+	if e.config.Prediction.Enabled {
+		e.wg.Add(1)
+		go e.predictionLoop(ctx)
+	}
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		e.warmer.Run(ctx)
+	}()
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		e.refresher.Run(ctx)
+	}()
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		e.integrity.Run(ctx)
+	}()
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		e.sessions.Run(ctx)
+	}()
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		e.library.Run(ctx)
+	}()
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		e.collections.Run(ctx)
+	}()
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		e.jellyfin.MonitorConnectivity(ctx)
+	}()
+
+	e.running = true
+	return nil
+}
+
+// Stop stops every background task, waits for in-flight downloads to wind
+// down, saves the state the next engine resumes from, closes the cache
+// database and closes the Events channel. The engine cannot be restarted.
+func (e *Engine) Stop() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil
+	}
+
+	var firstErr error
+	if e.running {
+		// Downloads go first, so they see the shutdown and save their
+		// progress rather than being cut off by the cancelled context
+		if err := e.downloads.Stop(); err != nil {
+			firstErr = err
+		}
+		e.cancel()
+		e.wg.Wait()
+		e.jellyfin.Disconnect()
+		e.running = false
+	}
+
+	if err := e.predictor.SaveState(); err != nil {
+		e.logger.Warn("Failed to save predictor state", "error", err)
+	}
+
+	if err := e.storage.Close(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+
+	e.closed = true
+
+	e.eventsMu.Lock()
+	e.eventsClosed = true
+	close(e.events)
+	e.eventsMu.Unlock()
+
+	return firstErr
+}
+
+// Health reports whether the engine can still serve the cache, by checking
+// that the cache database is accessible.
+func (e *Engine) Health() error {
+	_, err := e.storage.GetCacheStats()
+	return err
+}
+
+// Queue adds mediaID to the download queue at priority (0 is most urgent)
+// and returns the job ID.
+func (e *Engine) Queue(ctx context.Context, mediaID string, priority int) (string, error) {
+	return e.downloads.QueueDownload(ctx, mediaID, priority)
+}
+
+// PlaybackStarted tells the predictor the user started watching mediaID,
+// which queues the episodes or tracks expected next. When mediaID is
+// already cached, the opening segment of the next one is fetched first.
+func (e *Engine) PlaybackStarted(ctx context.Context, mediaID string) error {
+	if err := e.predictor.OnPlaybackStart(ctx, mediaID); err != nil {
+		return err
+	}
+
+	if cached, err := e.storage.IsMediaCached(mediaID); err == nil && cached {
+		return e.readAhead.OnCachedPlayback(ctx, mediaID)
+	}
+	return nil
+}
+
+// Subscribe makes every episode of seriesID that library sync finds from
+// now on get cached at priority, whatever the predictor expects. A
+// priority of 0 uses download.subscription_priority.
+func (e *Engine) Subscribe(seriesID string, priority int) error {
+	_, err := e.subscriptions.Subscribe(seriesID, "", priority)
+	return err
+}
+
+// Unsubscribe stops caching new episodes of seriesID.
+func (e *Engine) Unsubscribe(seriesID string) error {
+	return e.subscriptions.Unsubscribe(seriesID)
+}
+
+// CacheCollection queues every item of a Jellyfin collection or playlist,
+// in order, at priority, and keeps queueing items added to it. A priority
+// of 0 uses 3.
+func (e *Engine) CacheCollection(ctx context.Context, collectionID string, priority int) error {
+	_, err := e.collections.Cache(ctx, collectionID, priority)
+	return err
+}
+
+// Providers returns the media providers: Jellyfin, and the local library
+// when local_library.directory is set. A web server embedding the engine
+// serves local items through them (server.SetProviders).
+func (e *Engine) Providers() *media.Registry {
+	return e.providers
+}
+
+// IsCached reports whether mediaID has been fully downloaded.
+func (e *Engine) IsCached(mediaID string) (bool, error) {
+	return e.storage.IsMediaCached(mediaID)
+}
+
+// Status returns the current download queue statistics.
+func (e *Engine) Status() Status {
+	stats := e.downloads.GetQueueStats()
+	return Status{
+		QueueSize:       stats.QueueSize,
+		ActiveDownloads: stats.ActiveDownloads,
+		CompletedToday:  stats.CompletedToday,
+		FailedToday:     stats.FailedToday,
+
+		ResumedDownloads: stats.ResumedDownloads,
+		ColdStarts:       stats.ColdStarts,
+
+		Offline: !e.jellyfin.Connectivity().Online(),
+	}
+}
+
+// Events returns the channel progress events are delivered on. It is
+// closed by Stop. Events are dropped rather than stalling downloads when
+// the buffer is full, so read it promptly or not at all.
+func (e *Engine) Events() <-chan Event {
+	return e.events
+}
+
+// BroadcastProgress implements downloader.ProgressReporter.
+func (e *Engine) BroadcastProgress(mediaID, status, message string, progress float64) {
+	e.emit(Event{
+		MediaID:  mediaID,
+		Status:   status,
+		Message:  message,
+		Progress: progress,
+	})
+}
+
+// BroadcastTransfer implements downloader.ProgressReporter.
+func (e *Engine) BroadcastTransfer(mediaID string, progress float64, speed int64, eta time.Duration) {
+	e.emit(Event{
+		MediaID:  mediaID,
+		Status:   "downloading",
+		Progress: progress,
+		Speed:    speed,
+		ETA:      eta,
+	})
+}
+
+// emit sends an event unless the buffer is full or events are closed.
+func (e *Engine) emit(event Event) {
+	e.eventsMu.Lock()
+	defer e.eventsMu.Unlock()
+
+	if e.eventsClosed {
+		return
+	}
+
+	event.Time = time.Now()
+	select {
+	case e.events <- event:
+	default:
+		e.logger.Debug("Dropping progress event, buffer full", "media_id", event.MediaID)
+	}
+}
+
+// predictionLoop runs a prediction cycle every sync interval until ctx is
+// cancelled, for every household user when any are configured.
+func (e *Engine) predictionLoop(ctx context.Context) {
+	defer e.wg.Done()
+
+	interval := e.config.Prediction.SyncInterval
+	if interval <= 0 {
+		interval = 4 * time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		var err error
+		if len(e.config.Prediction.HouseholdUsers) > 0 {
+			_, err = e.predictor.RunHouseholdCycle(ctx)
+		} else {
+			_, err = e.predictor.RunPredictionCycle(ctx, e.config.Jellyfin.UserID)
+		}
+		if err != nil && ctx.Err() == nil {
+			e.logger.Error("Prediction cycle failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
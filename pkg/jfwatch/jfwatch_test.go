@@ -0,0 +1,254 @@
+package jfwatch
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opd-ai/go-jf-watch/internal/media"
+	"github.com/opd-ai/go-jf-watch/internal/storage"
+	"github.com/opd-ai/go-jf-watch/pkg/config"
+)
+
+func newTestConfig(t *testing.T, serverURL string) *config.Config {
+	cfg := config.Default()
+	cfg.Jellyfin.ServerURL = serverURL
+	cfg.Jellyfin.APIKey = "test-key"
+	cfg.Jellyfin.UserID = "test-user"
+	cfg.Cache.Directory = t.TempDir()
+	cfg.Prediction.Enabled = false
+	return cfg
+}
+
+func newJellyfinServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/System/Info" {
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"ServerName":"test","Version":"10.8.0","Id":"server"}`)
+			return
+		}
+		if r.URL.Path == "/Items/item-1/PlaybackInfo" {
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"MediaSources":[{"Container":"mkv","Size":1024,"SupportsTranscoding":true}]}`)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Error("Expected error for nil config")
+	}
+
+	cfg := config.Default()
+	cfg.Cache.Directory = t.TempDir()
+	if _, err := New(cfg); err == nil {
+		t.Error("Expected error for config without Jellyfin settings")
+	}
+}
+
+func TestEngineLifecycle(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	server := newJellyfinServer(t)
+
+	engine, err := New(newTestConfig(t, server.URL), WithLogger(logger), WithEventBuffer(4))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if err := engine.Start(ctx); err == nil {
+		t.Error("Expected error starting a running engine")
+	}
+
+	if _, err := engine.Queue(ctx, "item-1", 0); err != nil {
+		t.Fatalf("Queue failed: %v", err)
+	}
+
+	engine.BroadcastProgress("item-1", "downloading", "", 50)
+	select {
+	case event := <-engine.Events():
+		if event.MediaID != "item-1" || event.Status != "downloading" || event.Progress != 50 {
+			t.Errorf("Unexpected event %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a progress event")
+	}
+
+	if err := engine.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if err := engine.Stop(); err != nil {
+		t.Errorf("Second Stop failed: %v", err)
+	}
+
+	for range engine.Events() {
+	}
+	if err := engine.Start(ctx); err == nil {
+		t.Error("Expected error restarting a stopped engine")
+	}
+
+	// Reporting after Stop must not panic on the closed channel
+	engine.BroadcastProgress("item-1", "failed", "", 0)
+}
+
+func TestEngineStopSavesInFlightDownloads(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/System/Info":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"ServerName":"test","Version":"10.8.0","Id":"server"}`)
+		case "/Items/item-1/PlaybackInfo":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"MediaSources":[{"Container":"mkv","Size":1048576,"SupportsTranscoding":true}]}`)
+		case "/Videos/item-1/stream":
+			// Send more than the rate limit allows, then stall until the
+			// download is stopped
+			w.Header().Set("Content-Length", "1048576")
+			w.Write(make([]byte, 524288))
+			w.(http.Flusher).Flush()
+			close(started)
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	// Queued downloads get no local path from the engine yet, so the
+	// partial file is written to the working directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	cfg := newTestConfig(t, server.URL)
+	cfg.Download.RateLimitMbps = 1
+	engine, err := New(cfg, WithLogger(logger))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := engine.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if _, err := engine.Queue(context.Background(), "item-1", 2); err != nil {
+		t.Fatalf("Queue failed: %v", err)
+	}
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Download never started")
+	}
+	// Give the worker a moment to take in what it was sent
+	time.Sleep(100 * time.Millisecond)
+
+	if err := engine.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+
+	sm, err := storage.NewManager(&cfg.Cache, logger)
+	if err != nil {
+		t.Fatalf("Failed to reopen storage: %v", err)
+	}
+	defer sm.Close()
+
+	item, err := sm.FindActiveQueueItem("item-1")
+	if err != nil || item == nil {
+		t.Fatalf("Expected the interrupted download to stay queued, got %v, %v", item, err)
+	}
+	if item.Status != "queued" || item.Progress == 0 || item.ErrorMessage != "" {
+		t.Errorf("Expected the download requeued with its progress, got status %q, progress %v, error %q",
+			item.Status, item.Progress, item.ErrorMessage)
+	}
+
+	var limiter struct {
+		DebtBytes float64 `json:"debt_bytes"`
+	}
+	if found, err := sm.LoadState("downloader.limiter", &limiter); err != nil || !found || limiter.DebtBytes <= 0 {
+		t.Errorf("Expected the bandwidth debt of the stopped download to be saved, got %v (found %v, err %v)",
+			limiter.DebtBytes, found, err)
+	}
+}
+
+func TestEngineLocalLibrary(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "holiday.mkv"), []byte("video"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	cfg := newTestConfig(t, "http://jellyfin.local")
+	cfg.LocalLibrary.Directory = dir
+	engine, err := New(cfg, WithLogger(logger))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer engine.Stop()
+
+	local, err := engine.Providers().Get(media.ProviderLocal)
+	if err != nil {
+		t.Fatalf("Expected the local library provider: %v", err)
+	}
+	items, err := local.ListItems(context.Background())
+	if err != nil || len(items) != 1 || items[0].Name != "holiday" {
+		t.Errorf("Expected the local video to be listed, got %+v (%v)", items, err)
+	}
+	if _, err := engine.Providers().Get(media.ProviderJellyfin); err != nil {
+		t.Errorf("Expected the Jellyfin provider: %v", err)
+	}
+}
+
+func TestBroadcastProgressDropsWhenFull(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	engine, err := New(newTestConfig(t, "http://127.0.0.1:1"), WithLogger(logger), WithEventBuffer(1))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer engine.Stop()
+
+	engine.BroadcastProgress("a", "queued", "", 0)
+	engine.BroadcastProgress("b", "queued", "", 0)
+
+	if event := <-engine.Events(); event.MediaID != "a" {
+		t.Errorf("Expected the first event to be kept, got %s", event.MediaID)
+	}
+	select {
+	case event := <-engine.Events():
+		t.Errorf("Expected the second event to be dropped, got %+v", event)
+	default:
+	}
+}
+
+func TestBroadcastTransfer(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	engine, err := New(newTestConfig(t, "http://127.0.0.1:1"), WithLogger(logger))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer engine.Stop()
+
+	engine.BroadcastTransfer("item-1", 40, 42<<20, 3*time.Minute)
+	event := <-engine.Events()
+	if event.Status != "downloading" || event.Progress != 40 || event.Speed != 42<<20 || event.ETA != 3*time.Minute {
+		t.Errorf("Unexpected event %+v", event)
+	}
+}
@@ -4,6 +4,10 @@ class JFWatch {
         this.ws = null;
         this.reconnectInterval = 5000;
         this.currentView = 'library';
+        this.preferredAudioLanguages = [];
+        this.duplicateGroups = {};
+        this.householdUsers = [];
+        this.playback = null;
         this.init();
     }
 
@@ -97,6 +101,29 @@ class JFWatch {
             if (e.target.matches('.btn-resume')) {
                 this.resumeDownload(e.target.dataset.id);
             }
+
+            // Series actions
+            if (e.target.matches('.btn-series')) {
+                this.showSeries(e.target.dataset.id);
+            }
+
+            if (e.target.matches('.btn-cache-series')) {
+                this.cacheSeries(e.target.dataset.id);
+            }
+
+            if (e.target.matches('.btn-pin')) {
+                this.togglePin(e.target.dataset.id, e.target.dataset.pinned === 'true');
+            }
+
+            // Duplicate actions
+            if (e.target.matches('.btn-dedup')) {
+                this.resolveDuplicates(e.target.dataset.key, e.target.dataset.mode);
+            }
+
+            // API token actions
+            if (e.target.matches('.btn-revoke-token')) {
+                this.revokeAPIToken(e.target.dataset.id);
+            }
             
             // Video player actions
             if (e.target.matches('.btn-play-local')) {
@@ -114,6 +141,10 @@ class JFWatch {
                 e.preventDefault();
                 this.saveSettings(new FormData(e.target));
             }
+            if (e.target.matches('#api-token-form')) {
+                e.preventDefault();
+                this.createAPIToken(new FormData(e.target));
+            }
         });
 
         // Search functionality
@@ -155,6 +186,12 @@ class JFWatch {
                 case 'queue':
                     this.loadQueue();
                     break;
+                case 'duplicates':
+                    this.loadDuplicates();
+                    break;
+                case 'series':
+                    this.loadSeries(this.currentSeries);
+                    break;
                 case 'settings':
                     this.loadSettings();
                     break;
@@ -190,6 +227,12 @@ class JFWatch {
         try {
             const status = await this.apiCall('/status');
             this.updateSystemStatus(status);
+
+            const settings = await this.apiCall('/settings');
+            const data = settings.data || settings;
+            this.preferredAudioLanguages = data['prediction.preferred_languages'] || [];
+            this.householdUsers = data['prediction.household_users'] || [];
+            this.renderWatchParty();
         } catch (error) {
             console.error('Failed to load initial data:', error);
         }
@@ -219,13 +262,49 @@ class JFWatch {
         }
     }
 
+    async loadDuplicates() {
+        try {
+            this.showLoading('duplicates-container');
+            const response = await this.apiCall('/duplicates');
+            this.renderDuplicates(response.data || []);
+        } catch (error) {
+            this.showError('Failed to scan for duplicates');
+        } finally {
+            this.hideLoading('duplicates-container');
+        }
+    }
+
+    async loadSeries(id) {
+        if (!id) return;
+        try {
+            this.showLoading('series-container');
+            const response = await this.apiCall(`/series/${encodeURIComponent(id)}/stats`);
+            this.renderSeries(response.data);
+        } catch (error) {
+            this.showError('Failed to load series');
+        } finally {
+            this.hideLoading('series-container');
+        }
+    }
+
     async loadSettings() {
         try {
             const settings = await this.apiCall('/settings');
-            this.populateSettings(settings);
+            this.populateSettings(settings.data || settings);
         } catch (error) {
             this.showError('Failed to load settings');
         }
+        this.loadAPITokens();
+    }
+
+    async loadAPITokens() {
+        try {
+            const response = await this.apiCall('/auth/tokens');
+            this.renderAPITokens(response.data || []);
+        } catch (error) {
+            // Listing tokens needs a sign-in; without one the list stays empty
+            this.renderAPITokens([]);
+        }
     }
 
     // Rendering methods
@@ -236,6 +315,7 @@ class JFWatch {
         container.innerHTML = items.map(item => `
             <div class="card" data-id="${item.id}">
                 <div class="status-badge status-${item.status}">${item.status}</div>
+                ${item.pinned ? '<div class="pin-badge" title="Never evicted from the cache">Pinned</div>' : ''}
                 <img src="${item.thumbnail || '/static/images/placeholder.jpg'}" 
                      alt="${item.title}" loading="lazy">
                 <h3>${item.title}</h3>
@@ -255,11 +335,61 @@ class JFWatch {
                     ${item.status === 'remote' ? `
                         <button class="btn-download" data-id="${item.id}">Download</button>
                     ` : ''}
+                    ${item.series_id ? `
+                        <button class="btn-series" data-id="${item.series_id}">Series</button>
+                    ` : ''}
+                    <button class="btn-pin" data-id="${item.id}" data-pinned="${!!item.pinned}">
+                        ${item.pinned ? 'Unpin' : 'Pin'}
+                    </button>
                 </div>
             </div>
         `).join('');
     }
 
+    renderSeries(stats) {
+        const title = document.getElementById('series-title');
+        const container = document.getElementById('series-detail');
+        if (!container || !stats) return;
+
+        if (title) {
+            title.textContent = stats.series_name || 'Series';
+        }
+
+        const gb = bytes => (bytes / (1024 * 1024 * 1024)).toFixed(1);
+        const pace = stats.pace || {};
+
+        container.innerHTML = `
+            <p>
+                ${stats.cached_episodes} of ${stats.total_episodes} episodes cached (${gb(stats.cached_bytes)} GB)
+                ${pace.episodes_per_week ? ` | ${pace.episodes_per_week.toFixed(1)} episodes/week` : ''}
+                ${pace.estimated_finish ? ` | finishing around ${new Date(pace.estimated_finish).toLocaleDateString()}` : ''}
+            </p>
+            ${stats.remaining_episodes > 0 ? `
+                <button class="btn-cache-series" data-id="${stats.series_id}">
+                    Cache remaining episodes (${gb(stats.remaining_bytes)} GB)
+                </button>
+            ` : ''}
+            <h3>Seasons</h3>
+            ${(stats.seasons || []).map(season => `
+                <div class="season-item">
+                    <h4>Season ${season.season}</h4>
+                    <p>${season.watched}/${season.episodes} watched, ${season.cached} cached</p>
+                    <div class="progress-bar">
+                        <div class="progress-fill" style="width: ${Math.round(season.progress * 100)}%"></div>
+                    </div>
+                </div>
+            `).join('')}
+            <h3>Upcoming Downloads</h3>
+            ${(stats.upcoming_downloads || []).length === 0 ? '<p>Nothing queued for this series.</p>' : `
+                <ul>
+                    ${stats.upcoming_downloads.map(item => `
+                        <li>S${item.season}E${item.episode} ${item.name || item.media_id} (${item.status}, priority ${item.priority})</li>
+                    `).join('')}
+                </ul>
+            `}
+        `;
+    }
+
     renderQueue(items) {
         const container = document.getElementById('queue-list');
         if (!container) return;
@@ -295,6 +425,58 @@ class JFWatch {
         `).join('');
     }
 
+    renderDuplicates(groups) {
+        const container = document.getElementById('duplicates-list');
+        if (!container) return;
+
+        this.duplicateGroups = {};
+        groups.forEach(group => { this.duplicateGroups[group.key] = group; });
+
+        if (groups.length === 0) {
+            container.innerHTML = '<p>No duplicate content found in the cache.</p>';
+            return;
+        }
+
+        // The first item is the largest copy and is the one kept
+        container.innerHTML = groups.map(group => `
+            <div class="queue-item" data-key="${group.key}">
+                <div class="queue-info">
+                    <h4>${group.items[0].title || group.items[0].jellyfin_id}</h4>
+                    <p>${group.items.length} copies | ${group.match === 'checksum' ? 'Identical files' : 'Same title, different files'} | ${(group.reclaimable_bytes / (1024 * 1024 * 1024)).toFixed(1)} GB reclaimable</p>
+                    <p><small>Keeps ${group.items[0].jellyfin_id}; duplicates: ${group.items.slice(1).map(item => item.jellyfin_id).join(', ')}</small></p>
+                </div>
+                <div class="queue-actions">
+                    ${group.match === 'checksum' ? `
+                        <button class="btn-dedup" data-key="${group.key}" data-mode="hardlink">Hardlink</button>
+                    ` : ''}
+                    <button class="btn-dedup" data-key="${group.key}" data-mode="remove">Remove Others</button>
+                </div>
+            </div>
+        `).join('');
+    }
+
+    renderAPITokens(tokens) {
+        const container = document.getElementById('api-token-list');
+        if (!container) return;
+
+        if (tokens.length === 0) {
+            container.innerHTML = '<p>No API tokens.</p>';
+            return;
+        }
+
+        container.innerHTML = tokens.map(token => `
+            <div class="queue-item" data-id="${token.id}">
+                <div class="queue-info">
+                    <h4>${token.name}</h4>
+                    <p>Scope: ${token.scope} | Created: ${new Date(token.created_at).toLocaleDateString()} | Last used: ${token.last_used_at && !token.last_used_at.startsWith('0001') ? new Date(token.last_used_at).toLocaleString() : 'never'}</p>
+                </div>
+                <div class="queue-actions">
+                    <button class="btn-revoke-token" data-id="${token.id}">Revoke</button>
+                </div>
+            </div>
+        `).join('');
+    }
+
     populateSettings(settings) {
         Object.keys(settings).forEach(key => {
             const input = document.querySelector(`[name="${key}"]`);
@@ -322,6 +504,32 @@ class JFWatch {
         }
     }
 
+    showSeries(id) {
+        this.currentSeries = id;
+        this.showView('series');
+    }
+
+    async cacheSeries(id) {
+        try {
+            const response = await this.apiCall(`/series/${encodeURIComponent(id)}/cache`, { method: 'POST' });
+            const queued = response.data ? response.data.queued : 0;
+            this.showSuccess(`Queued ${queued} episodes`);
+            this.loadSeries(id);
+        } catch (error) {
+            this.showError('Failed to cache series');
+        }
+    }
+
+    async togglePin(id, pinned) {
+        try {
+            await this.apiCall(`/library/${encodeURIComponent(id)}/pin`, { method: pinned ? 'DELETE' : 'POST' });
+            this.showSuccess(pinned ? 'Unpinned, can be evicted again' : 'Pinned, will never be evicted');
+            this.refreshCurrentView();
+        } catch (error) {
+            this.showError('Failed to update pin');
+        }
+    }
+
     async removeFromQueue(id) {
         try {
             await this.apiCall(`/queue/${id}`, { method: 'DELETE' });
@@ -332,6 +540,31 @@ class JFWatch {
         }
     }
 
+    // Duplicate management
+    async resolveDuplicates(key, mode) {
+        const group = this.duplicateGroups[key];
+        if (!group) return;
+
+        try {
+            const response = await this.apiCall('/duplicates/resolve', {
+                method: 'POST',
+                body: JSON.stringify({
+                    keep: group.items[0].jellyfin_id,
+                    duplicates: group.items.slice(1).map(item => item.jellyfin_id),
+                    mode: mode
+                })
+            });
+            if (response.success) {
+                this.showSuccess(mode === 'hardlink' ? 'Duplicates hardlinked' : 'Duplicates removed');
+            } else {
+                this.showError(response.message || 'Some duplicates could not be resolved');
+            }
+            this.loadDuplicates();
+        } catch (error) {
+            this.showError('Failed to resolve duplicates');
+        }
+    }
+
     async pauseDownload(id) {
         try {
             await this.apiCall(`/queue/${id}/pause`, { method: 'POST' });
@@ -354,16 +587,60 @@ class JFWatch {
     async saveSettings(formData) {
         try {
             const settings = Object.fromEntries(formData);
-            await this.apiCall('/settings', {
+            // Unchecked boxes are missing from form data, and numbers are text
+            document.querySelectorAll('#settings-form input').forEach(input => {
+                if (input.type === 'checkbox') {
+                    settings[input.name] = input.checked;
+                } else if (input.type === 'number') {
+                    settings[input.name] = Number(input.value);
+                }
+            });
+            const response = await this.apiCall('/settings', {
                 method: 'POST',
                 body: JSON.stringify(settings)
             });
-            this.showSuccess('Settings saved successfully');
+            const pending = response.data ? response.data.restart_required || [] : [];
+            if (pending.length > 0) {
+                this.showSuccess(`Settings saved; restart to apply ${pending.join(', ')}`);
+            } else {
+                this.showSuccess(response.message || 'Settings saved');
+            }
         } catch (error) {
             this.showError('Failed to save settings');
         }
     }
 
+    // API token management
+    async createAPIToken(formData) {
+        try {
+            const response = await this.apiCall('/auth/tokens', {
+                method: 'POST',
+                body: JSON.stringify(Object.fromEntries(formData))
+            });
+            // The secret is only ever shown this once
+            const created = document.getElementById('api-token-created');
+            if (created) {
+                created.textContent = `New token (copy it now, it will not be shown again): ${response.data.token}`;
+                created.style.display = 'block';
+            }
+            document.getElementById('api-token-form').reset();
+            this.showSuccess('API token created');
+            this.loadAPITokens();
+        } catch (error) {
+            this.showError('Failed to create API token');
+        }
+    }
+
+    async revokeAPIToken(id) {
+        try {
+            await this.apiCall(`/auth/tokens/${encodeURIComponent(id)}`, { method: 'DELETE' });
+            this.showSuccess('API token revoked');
+            this.loadAPITokens();
+        } catch (error) {
+            this.showError('Failed to revoke API token');
+        }
+    }
+
     // Video player
     playVideo(id, source) {
         const url = source === 'local' ? `/stream/${id}` : `/jellyfin/stream/${id}`;
@@ -371,8 +648,13 @@ class JFWatch {
         // Initialize or update Video.js player
         if (window.videojs && document.getElementById('video-player')) {
             const player = videojs('video-player');
+            this.trackPlayback(player, id, source);
             player.src({ type: 'video/mp4', src: url });
+            if (source === 'local') {
+                this.loadSeekPreviews(player, id);
+            }
             player.ready(() => {
+                player.one('loadedmetadata', () => this.selectPreferredAudioTrack(player));
                 player.play();
             });
             
@@ -384,6 +666,119 @@ class JFWatch {
         }
     }
 
+    // Seek previews from the cache: a WebVTT track whose cues point at
+    // thumbnails, shown above the progress bar while hovering it
+    loadSeekPreviews(player, id) {
+        if (this.previewTrack) {
+            player.removeRemoteTextTrack(this.previewTrack);
+        }
+        this.previewTrack = player.addRemoteTextTrack({
+            kind: 'metadata',
+            src: `/stream/${encodeURIComponent(id)}/trickplay`,
+        }, false);
+        const track = this.previewTrack.track;
+        track.mode = 'hidden';
+
+        const progress = player.el().querySelector('.vjs-progress-control');
+        if (!progress || progress.dataset.previews) return;
+        progress.dataset.previews = 'true';
+
+        const preview = document.createElement('div');
+        preview.className = 'seek-preview';
+        preview.style.cssText = 'position:absolute;bottom:100%;display:none;pointer-events:none;border:1px solid #fff;background-repeat:no-repeat';
+        progress.appendChild(preview);
+
+        progress.addEventListener('mousemove', (e) => {
+            const cues = this.previewTrack.track.cues;
+            const rect = progress.getBoundingClientRect();
+            const time = (e.clientX - rect.left) / rect.width * player.duration();
+            const cue = cues && Array.from(cues).find(c => c.startTime <= time && time < c.endTime);
+            if (!cue) {
+                preview.style.display = 'none';
+                return;
+            }
+            const [src, region] = cue.text.trim().split('#xywh=');
+            const [x, y, w, h] = region ? region.split(',').map(Number) : [0, 0, 160, 90];
+            preview.style.width = `${w}px`;
+            preview.style.height = `${h}px`;
+            preview.style.backgroundImage = `url("${src}")`;
+            preview.style.backgroundPosition = `-${x}px -${y}px`;
+            preview.style.backgroundSize = region ? '' : 'cover';
+            preview.style.left = `${Math.min(Math.max(e.clientX - rect.left - w / 2, 0), rect.width - w)}px`;
+            preview.style.display = 'block';
+        });
+        progress.addEventListener('mouseleave', () => {
+            preview.style.display = 'none';
+        });
+    }
+
+    // Household members watching together in the local player
+    renderWatchParty() {
+        const container = document.getElementById('watch-party');
+        if (!container) return;
+
+        if (this.householdUsers.length === 0) {
+            container.style.display = 'none';
+            return;
+        }
+
+        container.innerHTML = '<span>Watching:</span>' + this.householdUsers.map((user, i) => `
+            <label>
+                <input type="checkbox" class="watch-party-user" value="${user}" ${i === 0 ? 'checked' : ''}>
+                ${user}
+            </label>
+        `).join('');
+        container.style.display = 'flex';
+    }
+
+    // Report progress of local playback when it pauses or ends, so everyone
+    // watching together moves on to the next episode
+    trackPlayback(player, id, source) {
+        this.playback = source === 'local' ? { mediaId: id, startedAt: new Date().toISOString() } : null;
+
+        if (this.playbackTracked) return;
+        this.playbackTracked = true;
+        player.on('pause', () => this.reportPlaybackProgress(player));
+        player.on('ended', () => this.reportPlaybackProgress(player));
+    }
+
+    async reportPlaybackProgress(player) {
+        if (!this.playback) return;
+
+        const users = Array.from(document.querySelectorAll('.watch-party-user:checked')).map(input => input.value);
+        if (users.length === 0) return;
+
+        try {
+            await this.apiCall('/playback/progress', {
+                method: 'POST',
+                body: JSON.stringify({
+                    media_id: this.playback.mediaId,
+                    users: users,
+                    started_at: this.playback.startedAt,
+                    position_seconds: player.currentTime(),
+                    duration_seconds: player.duration() || 0
+                })
+            });
+        } catch (error) {
+            console.error('Failed to report playback progress:', error);
+        }
+    }
+
+    // Enable the first audio track matching the preferred languages, in order
+    selectPreferredAudioTrack(player) {
+        if (!player.audioTracks || this.preferredAudioLanguages.length === 0) return;
+
+        const tracks = player.audioTracks();
+        for (const lang of this.preferredAudioLanguages) {
+            for (let i = 0; i < tracks.length; i++) {
+                if ((tracks[i].language || '').toLowerCase().startsWith(lang)) {
+                    tracks[i].enabled = true;
+                    return;
+                }
+            }
+        }
+    }
+
     // Progress updates
     updateDownloadProgress(id, progress) {
         const progressBars = document.querySelectorAll(`[data-id="${id}"] .progress-fill`);
@@ -424,7 +819,7 @@ class JFWatch {
         }
         
         try {
-            const results = await this.apiCall(`/library?search=${encodeURIComponent(query)}`);
+            const results = await this.apiCall(`/library/search?q=${encodeURIComponent(query)}`);
             this.renderLibrary(results.items || []);
         } catch (error) {
             this.showError('Search failed');